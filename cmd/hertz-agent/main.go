@@ -0,0 +1,57 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// hertz-agent queries the diagnostics agent started by Hertz.EnableAgent or agent.Listen.
+//
+//	hertz-agent <pid|socket path|host:port> [command]
+//
+// The command defaults to "stats", run "help" to list the commands.
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"hertz-study/pkg/app/server/agent"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "usage: hertz-agent <pid|socket path|host:port> [command]")
+		os.Exit(2)
+	}
+	command := "stats"
+	if len(os.Args) > 2 {
+		command = os.Args[2]
+	}
+
+	network, addr := "unix", os.Args[1]
+	if pid, err := strconv.Atoi(addr); err == nil {
+		addr = agent.SocketPath(pid)
+	} else if !strings.ContainsRune(addr, os.PathSeparator) && strings.Contains(addr, ":") {
+		network = "tcp"
+	}
+
+	out, err := agent.Query(network, addr, command, 30*time.Second)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "query %s://%s: %v\n", network, addr, err)
+		os.Exit(1)
+	}
+	os.Stdout.Write(out) //nolint:errcheck
+}
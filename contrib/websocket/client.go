@@ -0,0 +1,222 @@
+// Copyright 2013 The Gorilla WebSocket Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+// This file may have been modified by CloudWeGo authors. All CloudWeGo
+// Modifications are Copyright 2022 CloudWeGo Authors.
+
+package websocket
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"hertz-study/pkg/app/client"
+	"hertz-study/pkg/common/config"
+	"hertz-study/pkg/protocol"
+	"hertz-study/pkg/protocol/consts"
+)
+
+// ErrBadHandshake is returned when the server response to opening handshake is
+// invalid.
+var ErrBadHandshake = errors.New("websocket: bad handshake")
+
+// Dialer contains options for connecting to WebSocket server.
+//
+// It is safe to call Dialer's methods concurrently.
+type Dialer struct {
+	// Client sends the handshake requests, e.g. with the TLS config of the wss URLs set by
+	// client.WithTLSConfig and the dial timeout. A client with the default options is used if nil.
+	Client *client.Client
+
+	// HandshakeTimeout specifies the duration for the handshake to complete.
+	// Zero means no timeout besides the deadline of the context.
+	HandshakeTimeout time.Duration
+
+	// ReadBufferSize and WriteBufferSize specify I/O buffer sizes in bytes. If a buffer
+	// size is zero, then a useful default size is used. The I/O buffer sizes
+	// do not limit the size of the messages that can be sent or received.
+	ReadBufferSize, WriteBufferSize int
+
+	// WriteBufferPool is a pool of buffers for write operations. If the value
+	// is not set, then write buffers are allocated to the connection for the
+	// lifetime of the connection.
+	WriteBufferPool BufferPool
+
+	// Subprotocols specifies the client's requested subprotocols.
+	Subprotocols []string
+
+	// EnableCompression specifies if the client should attempt to negotiate
+	// per message compression (RFC 7692). Currently only "no context
+	// takeover" modes are supported.
+	EnableCompression bool
+}
+
+// DefaultDialer is a dialer with all fields set to the default values.
+var DefaultDialer = &Dialer{
+	HandshakeTimeout: 45 * time.Second,
+}
+
+var (
+	defaultClientOnce sync.Once
+	defaultClient     *client.Client
+	defaultClientErr  error
+)
+
+func (d *Dialer) client() (*client.Client, error) {
+	if d.Client != nil {
+		return d.Client, nil
+	}
+	defaultClientOnce.Do(func() {
+		defaultClient, defaultClientErr = client.NewClient()
+	})
+	return defaultClient, defaultClientErr
+}
+
+func generateChallengeKey() (string, error) {
+	p := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, p); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(p), nil
+}
+
+// DialContext creates a new client connection. Use requestHeader to specify the
+// origin (Origin), subprotocols (Sec-WebSocket-Protocol) and cookies (Cookie).
+// Use the response.Header to get the selected subprotocol
+// (Sec-WebSocket-Protocol) and cookies (Set-Cookie).
+//
+// The handshake request is sent by Dialer.Client, and the deadline of the context bounds
+// the handshake as HandshakeTimeout does.
+//
+// If the WebSocket handshake fails, ErrBadHandshake is returned along with a
+// non-nil *protocol.Response so that callers can handle redirects, authentication,
+// etcetera.
+func (d *Dialer) DialContext(ctx context.Context, urlStr string, requestHeader *protocol.RequestHeader) (*Conn, *protocol.Response, error) {
+	if d == nil {
+		d = DefaultDialer
+	}
+	c, err := d.client()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	challengeKey, err := generateChallengeKey()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch u.Scheme {
+	case "ws":
+		u.Scheme = "http"
+	case "wss":
+		u.Scheme = "https"
+	default:
+		return nil, nil, errMalformedURL
+	}
+	if u.User != nil {
+		// User name and password are not allowed in websocket URIs.
+		return nil, nil, errMalformedURL
+	}
+
+	req := protocol.AcquireRequest()
+	defer protocol.ReleaseRequest(req)
+	if requestHeader != nil {
+		var dup string
+		requestHeader.VisitAll(func(key, value []byte) {
+			k := string(key)
+			switch {
+			case strings.EqualFold(k, consts.HeaderHost):
+				return
+			case strings.EqualFold(k, consts.HeaderUpgrade) ||
+				strings.EqualFold(k, consts.HeaderConnection) ||
+				strings.EqualFold(k, "Sec-Websocket-Key") ||
+				strings.EqualFold(k, "Sec-Websocket-Version") ||
+				strings.EqualFold(k, "Sec-Websocket-Extensions") ||
+				(strings.EqualFold(k, "Sec-Websocket-Protocol") && len(d.Subprotocols) > 0):
+				dup = k
+				return
+			}
+			req.Header.Add(k, string(value))
+		})
+		if dup != "" {
+			return nil, nil, errors.New("websocket: duplicate header not allowed: " + dup)
+		}
+	}
+	req.SetRequestURI(u.String())
+	req.Header.SetMethod(consts.MethodGet)
+	req.Header.Set(consts.HeaderUpgrade, "websocket")
+	req.Header.Set(consts.HeaderConnection, "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", challengeKey)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	if len(d.Subprotocols) > 0 {
+		req.Header.Set("Sec-WebSocket-Protocol", strings.Join(d.Subprotocols, ", "))
+	}
+	if d.EnableCompression {
+		req.Header.Set("Sec-WebSocket-Extensions", "permessage-deflate; server_no_context_takeover; client_no_context_takeover")
+	}
+
+	timeout := d.HandshakeTimeout
+	if deadline, ok := ctx.Deadline(); ok {
+		if remaining := time.Until(deadline); timeout <= 0 || remaining < timeout {
+			timeout = remaining
+		}
+		if timeout <= 0 {
+			return nil, nil, context.DeadlineExceeded
+		}
+	}
+	if timeout > 0 {
+		req.SetOptions(config.WithRequestTimeout(timeout))
+	}
+
+	resp := &protocol.Response{}
+	if err = c.Do(ctx, req, resp); err != nil {
+		return nil, nil, err
+	}
+
+	if resp.StatusCode() != consts.StatusSwitchingProtocols ||
+		!tokenContainsValue(string(resp.Header.Peek(consts.HeaderUpgrade)), "websocket") ||
+		!tokenContainsValue(string(resp.Header.Peek(consts.HeaderConnection)), "upgrade") ||
+		string(resp.Header.Peek("Sec-Websocket-Accept")) != computeAcceptKeyBytes([]byte(challengeKey)) {
+		// the connection of a 101 response is handed to resp, which nobody resets
+		if netConn, err := resp.Hijack(); err == nil {
+			netConn.Close()
+		}
+		return nil, resp, ErrBadHandshake
+	}
+
+	netConn, err := resp.Hijack()
+	if err != nil {
+		return nil, resp, err
+	}
+	conn := newConn(netConn, false, d.ReadBufferSize, d.WriteBufferSize, d.WriteBufferPool, nil, nil)
+	conn.subprotocol = string(resp.Header.Peek("Sec-Websocket-Protocol"))
+	if ext := string(resp.Header.Peek("Sec-WebSocket-Extensions")); d.EnableCompression && strings.HasPrefix(ext, "permessage-deflate") {
+		if !strings.Contains(ext, "server_no_context_takeover") || !strings.Contains(ext, "client_no_context_takeover") {
+			netConn.Close()
+			return nil, resp, fmt.Errorf("websocket: unsupported extension: %s", ext)
+		}
+		conn.newCompressionWriter = compressNoContextTakeover
+		conn.newDecompressionReader = decompressNoContextTakeover
+	}
+	return conn, resp, nil
+}
+
+// Dial creates a new client connection by calling DialContext with a background context.
+func (d *Dialer) Dial(urlStr string, requestHeader *protocol.RequestHeader) (*Conn, *protocol.Response, error) {
+	return d.DialContext(context.Background(), urlStr, requestHeader)
+}
+
+var errMalformedURL = errors.New("websocket: malformed ws or wss URL")
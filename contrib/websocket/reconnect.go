@@ -0,0 +1,204 @@
+// Copyright 2013 The Gorilla WebSocket Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+// This file may have been modified by CloudWeGo authors. All CloudWeGo
+// Modifications are Copyright 2022 CloudWeGo Authors.
+
+package websocket
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/bytedance/gopkg/lang/fastrand"
+	"hertz-study/pkg/protocol"
+)
+
+const (
+	defaultMinBackoff = 500 * time.Millisecond
+	defaultMaxBackoff = 30 * time.Second
+)
+
+// ErrNotConnected is returned by Client.WriteMessage when there is no active connection.
+var ErrNotConnected = errors.New("websocket: client is not connected")
+
+// Client is a long-lived websocket client which keeps the connection alive with
+// ping/pong and reconnects with exponential backoff when the connection is broken,
+// which is useful for consuming websocket feeds.
+type Client struct {
+	// URL is the ws or wss URL to connect to.
+	URL string
+
+	// Header is sent with every handshake request.
+	Header *protocol.RequestHeader
+
+	// Dialer is used to dial the server, DefaultDialer is used if nil.
+	Dialer *Dialer
+
+	// PingInterval is the interval of sending ping message, zero disables ping.
+	PingInterval time.Duration
+
+	// PongWait is the duration to wait for any message (including pong) from the server
+	// before the connection is considered broken, default is twice of PingInterval.
+	PongWait time.Duration
+
+	// MinBackoff and MaxBackoff bound the exponential backoff between reconnections,
+	// default is 500ms and 30s.
+	MinBackoff, MaxBackoff time.Duration
+
+	// OnConnect is called after every successful handshake, before reading messages.
+	// It is the place to (re)subscribe the feeds. The connection is closed and
+	// retried with backoff if it returns an error.
+	OnConnect func(ctx context.Context, conn *Conn) error
+
+	// OnMessage is called for every received data message.
+	OnMessage func(messageType int, data []byte)
+
+	// OnDisconnect is called when the connection is broken, err is the reason.
+	OnDisconnect func(err error)
+
+	mu   sync.Mutex
+	conn *Conn
+}
+
+// Run connects to the server and reads messages until ctx is done, the connection
+// is re-established whenever it is broken. It always returns ctx.Err().
+func (c *Client) Run(ctx context.Context) error {
+	backoff := c.minBackoff()
+	for {
+		connected, err := c.runOnce(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if c.OnDisconnect != nil {
+			c.OnDisconnect(err)
+		}
+		if connected {
+			backoff = c.minBackoff()
+		}
+
+		// full jitter to avoid reconnecting storm
+		wait := time.Duration(fastrand.Int63n(int64(backoff))) + 1
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		if backoff *= 2; backoff > c.maxBackoff() {
+			backoff = c.maxBackoff()
+		}
+	}
+}
+
+// WriteMessage writes a message to the current connection, it is safe for concurrent use.
+func (c *Client) WriteMessage(messageType int, data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return ErrNotConnected
+	}
+	return c.conn.WriteMessage(messageType, data)
+}
+
+// runOnce returns whether the connection is established, i.e. the handshake and OnConnect
+// succeeded, and the reason why the connection ends.
+func (c *Client) runOnce(ctx context.Context) (bool, error) {
+	dialer := c.Dialer
+	if dialer == nil {
+		dialer = DefaultDialer
+	}
+	conn, _, err := dialer.DialContext(ctx, c.URL, c.Header)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	// close the connection to interrupt reading when ctx is done
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.WriteControl(CloseMessage, FormatCloseMessage(CloseNormalClosure, ""), time.Now().Add(time.Second)) //nolint:errcheck
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	if c.OnConnect != nil {
+		if err = c.OnConnect(ctx, conn); err != nil {
+			// not regarded as connected to keep backing off, e.g. when the subscription is rejected
+			return false, err
+		}
+	}
+
+	if c.PingInterval > 0 {
+		conn.SetReadDeadline(time.Now().Add(c.pongWait())) //nolint:errcheck
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(c.pongWait()))
+		})
+		go c.ping(conn, done)
+	}
+
+	c.mu.Lock()
+	c.conn = conn
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		c.conn = nil
+		c.mu.Unlock()
+	}()
+
+	for {
+		messageType, data, err := conn.ReadMessage()
+		if err != nil {
+			return true, err
+		}
+		if c.PingInterval > 0 {
+			conn.SetReadDeadline(time.Now().Add(c.pongWait())) //nolint:errcheck
+		}
+		if c.OnMessage != nil {
+			c.OnMessage(messageType, data)
+		}
+	}
+}
+
+func (c *Client) ping(conn *Conn, done chan struct{}) {
+	ticker := time.NewTicker(c.PingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			// WriteControl can be called concurrently with the other methods
+			if err := conn.WriteControl(PingMessage, nil, time.Now().Add(c.PingInterval)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (c *Client) pongWait() time.Duration {
+	if c.PongWait > 0 {
+		return c.PongWait
+	}
+	return 2 * c.PingInterval
+}
+
+func (c *Client) minBackoff() time.Duration {
+	if c.MinBackoff > 0 {
+		return c.MinBackoff
+	}
+	return defaultMinBackoff
+}
+
+func (c *Client) maxBackoff() time.Duration {
+	if c.MaxBackoff > 0 {
+		return c.MaxBackoff
+	}
+	return defaultMaxBackoff
+}
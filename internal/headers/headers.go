@@ -0,0 +1,37 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package headers replays the response headers saved by the server middlewares.
+package headers
+
+import (
+	"hertz-study/pkg/protocol"
+)
+
+// Replay writes the saved headers kvs to h. The first value of a key replaces
+// the one set before, e.g. by an earlier middleware, and the following values
+// of the same key are added, so the repeated headers like Set-Cookie are kept.
+func Replay(h *protocol.ResponseHeader, kvs [][2]string) {
+	seen := make(map[string]struct{}, len(kvs))
+	for _, kv := range kvs {
+		if _, ok := seen[kv[0]]; ok {
+			h.Add(kv[0], kv[1])
+			continue
+		}
+		seen[kv[0]] = struct{}{}
+		h.Set(kv[0], kv[1])
+	}
+}
@@ -46,6 +46,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net"
 	"reflect"
 	"strings"
 	"sync"
@@ -81,7 +82,8 @@ var (
 //   - from RequestURI if it contains full url with scheme and host;
 //   - from Host header otherwise.
 //
-// The function doesn't follow redirects. Use Get* for following redirects.
+// The function doesn't follow redirects unless the RedirectPolicy is set.
+// Use Get* or DoRedirects for following redirects otherwise.
 //
 // Response is ignored if resp is nil.
 //
@@ -261,15 +263,19 @@ type Client struct {
 	// request is aborted with the provided error.
 	//
 	// The proxy type is determined by the URL scheme.
-	// "http" and "https" are supported. If the scheme is empty,
-	// "http" is assumed.
+	// "http", "https", "socks5" and "socks5h" are supported. If the scheme
+	// is empty, "http" is assumed.
 	//
 	// If Proxy is nil or returns a nil *URL, no proxy is used.
+	// Default is ProxyFromEnvironment.
 	Proxy protocol.Proxy
 
 	// RetryIfFunc sets the retry decision function. If nil, the client.DefaultRetryIf will be applied.
 	RetryIfFunc client.RetryIfFunc
 
+	// RedirectPolicy makes Do follow the redirects it allows, see SetRedirectPolicy.
+	RedirectPolicy RedirectPolicy
+
 	clientFactory suite.ClientFactory
 
 	mLock          sync.Mutex
@@ -295,7 +301,8 @@ func (c *Client) SetRetryIf(fn func(request *protocol.Request) bool) {
 	c.SetRetryIfFunc(f)
 }
 
-// SetProxy is used to set client proxy.
+// SetProxy is used to set client proxy, e.g. protocol.ProxyURI(protocol.ParseURI("socks5://127.0.0.1:1080")).
+// Set it to nil to disable the proxy given by the environment variables.
 //
 // Don't SetProxy twice for a client.
 // If you want to use another proxy, please create another client and set proxy to it.
@@ -455,6 +462,16 @@ func (c *Client) DoRedirects(ctx context.Context, req *protocol.Request, resp *p
 // It is recommended obtaining req and resp via AcquireRequest
 // and AcquireResponse in performance-critical code.
 func (c *Client) Do(ctx context.Context, req *protocol.Request, resp *protocol.Response) error {
+	if c.options.RequestTimeout > 0 && req.Options().RequestTimeout() <= 0 {
+		req.SetOptions(config.WithRequestTimeout(c.options.RequestTimeout))
+	}
+	if c.RedirectPolicy != nil {
+		return c.followRedirects(ctx, req, resp)
+	}
+	return c.doOnce(ctx, req, resp)
+}
+
+func (c *Client) doOnce(ctx context.Context, req *protocol.Request, resp *protocol.Response) error {
 	if c.mws == nil {
 		return c.do(ctx, req, resp)
 	}
@@ -501,18 +518,33 @@ func (c *Client) do(ctx context.Context, req *protocol.Request, resp *protocol.R
 	}
 
 	h := string(host)
-	hc := m[h]
+	key := h
+	var addrOverride string
+	if req.Options() != nil && req.Options().Addr() != "" {
+		addrOverride = req.Options().Addr()
+		key = h + "@" + addrOverride
+	}
+	if proxyURI != nil {
+		// the proxy may be chosen per request
+		key += "|" + proxyURI.String()
+	}
+	hc := m[key]
 	if hc == nil {
 		if c.clientFactory == nil {
 			// load http1 client by default
 			c.clientFactory = factory.NewClientFactory(newHttp1OptionFromClient(c))
 		}
 		hc, _ = c.clientFactory.NewHostClient()
-		hc.SetDynamicConfig(&client.DynamicConfig{
+		dc := &client.DynamicConfig{
 			Addr:     utils.AddMissingPort(h, isTLS),
 			ProxyURI: proxyURI,
 			IsTLS:    isTLS,
-		})
+		}
+		if addr := c.mappedAddr(dc.Addr, addrOverride); addr != dc.Addr {
+			dc.Addr = addr
+			dc.TLSServerName, _, _ = net.SplitHostPort(utils.AddMissingPort(h, isTLS))
+		}
+		hc.SetDynamicConfig(dc)
 
 		// re-configure hook
 		if c.options.HostClientConfigHook != nil {
@@ -523,7 +555,7 @@ func (c *Client) do(ctx context.Context, req *protocol.Request, resp *protocol.R
 			}
 		}
 
-		m[h] = hc
+		m[key] = hc
 		if len(m) == 1 {
 			startCleaner = true
 		}
@@ -538,6 +570,33 @@ func (c *Client) do(ctx context.Context, req *protocol.Request, resp *protocol.R
 	return hc.Do(ctx, req, resp)
 }
 
+// mappedAddr returns the comma-separated addresses to dial for hostPort,
+// addrOverride of the request has higher priority than the host mapping.
+func (c *Client) mappedAddr(hostPort, addrOverride string) string {
+	host, port, _ := net.SplitHostPort(hostPort)
+	var addrs []string
+	if addrOverride != "" {
+		addrs = []string{addrOverride}
+	} else if c.options.HostMapping != nil {
+		var ok bool
+		if addrs, ok = c.options.HostMapping[hostPort]; !ok {
+			addrs = c.options.HostMapping[host]
+		}
+	}
+	if len(addrs) == 0 {
+		return hostPort
+	}
+
+	withPort := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			addr = net.JoinHostPort(addr, port)
+		}
+		withPort = append(withPort, addr)
+	}
+	return strings.Join(withPort, ",")
+}
+
 // CloseIdleConnections closes any connections which were previously
 // connected from previous requests but are now sitting idle in a
 // "keep-alive" state. It does not interrupt any connections currently
@@ -616,6 +675,7 @@ func NewClient(opts ...config.ClientOption) (*Client, error) {
 	}
 	c := &Client{
 		options: opt,
+		Proxy:   ProxyFromEnvironment,
 		m:       make(map[string]client.HostClient),
 		ms:      make(map[string]client.HostClient),
 	}
@@ -623,6 +683,10 @@ func NewClient(opts ...config.ClientOption) (*Client, error) {
 	return c, nil
 }
 
+// Use appends the middlewares to the middleware chain of the client, which wraps every
+// outbound request in the order they are added, like the HandlersChain of the server,
+// e.g. to inject auth headers, retry, tracing or logging. See the interceptor package for
+// the common ones. It is not safe to call Use concurrently with the requests.
 func (c *Client) Use(mws ...Middleware) {
 	// Put the original middlewares to the first
 	middlewares := make([]Middleware, 0, 1+len(mws))
@@ -677,5 +741,6 @@ func newHttp1OptionFromClient(c *Client) *http1.ClientOptions {
 		RetryIfFunc:                   c.RetryIfFunc,
 		StateObserve:                  c.options.HostClientStateObserve,
 		ObservationInterval:           c.options.ObservationInterval,
+		RequestDumper:                 c.options.RequestDumper,
 	}
 }
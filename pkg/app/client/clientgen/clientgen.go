@@ -0,0 +1,196 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package clientgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+
+	"hertz-study/pkg/app"
+	"hertz-study/pkg/common/utils"
+	"hertz-study/pkg/route"
+)
+
+// Endpoint describes a method of the generated client.
+type Endpoint struct {
+	// Name is the method name of the generated client.
+	Name   string
+	Method string
+	// Path is the route path, e.g. "/users/:id".
+	Path string
+	// Request is a value of the binding struct (or a pointer to it), nil means no input.
+	Request interface{}
+	// Response is a value of the response type (or a pointer to it), nil means the body is ignored.
+	Response interface{}
+	// Doc is written as the comment of the method, e.g. the summary of the route.
+	Doc string
+}
+
+// Binding describes the request and response types of a handler, it is used to
+// generate the endpoints of the routes registered with the handler.
+type Binding struct {
+	Handler app.HandlerFunc
+	// Name is the method name of the generated client, the name of Handler is used if empty.
+	Name     string
+	Request  interface{}
+	Response interface{}
+}
+
+// Generator generates the source code of a typed client.
+type Generator struct {
+	pkgName   string
+	endpoints []Endpoint
+}
+
+// New creates a Generator of the client package pkgName.
+func New(pkgName string) *Generator {
+	return &Generator{pkgName: pkgName}
+}
+
+// Add adds endpoints to the generated client.
+func (g *Generator) Add(endpoints ...Endpoint) *Generator {
+	g.endpoints = append(g.endpoints, endpoints...)
+	return g
+}
+
+// AddRoutes adds the routes whose handler is described by bindings, the other routes are ignored.
+// routes are usually obtained by engine.Routes() after all routes are registered.
+func (g *Generator) AddRoutes(routes route.RoutesInfo, bindings ...Binding) *Generator {
+	byName := make(map[string]Binding, len(bindings))
+	for _, b := range bindings {
+		byName[utils.NameOfFunction(b.Handler)] = b
+	}
+	for _, r := range routes {
+		b, ok := byName[r.Handler]
+		if !ok {
+			continue
+		}
+		name := b.Name
+		if name == "" {
+			name = methodName(r.Handler)
+		}
+		g.endpoints = append(g.endpoints, Endpoint{
+			Name:     name,
+			Method:   r.Method,
+			Path:     r.Path,
+			Request:  b.Request,
+			Response: b.Response,
+			Doc:      routeDoc(r.Doc),
+		})
+	}
+	return g
+}
+
+// routeDoc joins the summary and the description of the route doc.
+func routeDoc(doc *route.RouteDoc) string {
+	if doc == nil {
+		return ""
+	}
+	if doc.Summary == "" || doc.Description == "" {
+		return doc.Summary + doc.Description
+	}
+	return doc.Summary + "\n\n" + doc.Description
+}
+
+// Generate returns the formatted source code of the client.
+func (g *Generator) Generate() ([]byte, error) {
+	endpoints := make([]Endpoint, len(g.endpoints))
+	copy(endpoints, g.endpoints)
+	sort.Slice(endpoints, func(i, j int) bool {
+		return endpoints[i].Name < endpoints[j].Name
+	})
+
+	f := newFile(g.pkgName)
+	names := make(map[string]bool, len(endpoints))
+	for _, e := range endpoints {
+		if !token(e.Name) || !unicode.IsUpper([]rune(e.Name)[0]) {
+			return nil, fmt.Errorf("clientgen: invalid method name %q of %s %s", e.Name, e.Method, e.Path)
+		}
+		if names[e.Name] {
+			return nil, fmt.Errorf("clientgen: duplicate method name %q, set the name explicitly", e.Name)
+		}
+		names[e.Name] = true
+		if err := f.writeEndpoint(e); err != nil {
+			return nil, fmt.Errorf("clientgen: %s %s: %w", e.Method, e.Path, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	f.writeTo(&buf)
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("clientgen: format generated code: %w", err)
+	}
+	return src, nil
+}
+
+// WriteFile generates the client and writes it to filename.
+func (g *Generator) WriteFile(filename string) error {
+	src, err := g.Generate()
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(filename, src, 0o644)
+}
+
+// methodName returns the exported function name of a handler name, e.g.
+// "example.com/biz/handler.GetUser" -> "GetUser", "example.com/biz/handler.(*User).Get-fm" -> "Get".
+func methodName(handler string) string {
+	name := strings.TrimSuffix(handler, "-fm")
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		name = name[i+1:]
+	}
+	if name == "" {
+		return ""
+	}
+	r := []rune(name)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+func token(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		if !unicode.IsLetter(r) && r != '_' && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return true
+}
+
+func indirect(v interface{}) reflect.Type {
+	if v == nil {
+		return nil
+	}
+	t := reflect.TypeOf(v)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
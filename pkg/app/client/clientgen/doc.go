@@ -0,0 +1,46 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package clientgen generates typed client stubs from the routes of a hertz engine and
+// the binding structs of the handlers, so the consumers of a service get compile-checked
+// clients instead of assembling requests by hand.
+//
+// The generator needs the request and response types, which only exist at runtime, so it
+// is driven by a small program of the service, e.g. gen/main.go:
+//
+//	func main() {
+//		h := server.New()
+//		router.Register(h)
+//		err := clientgen.New("userclient").
+//			AddRoutes(h.Routes(),
+//				clientgen.Binding{Handler: handler.GetUser, Request: handler.GetUserReq{}, Response: handler.User{}},
+//				clientgen.Binding{Handler: handler.CreateUser, Request: handler.CreateUserReq{}, Response: handler.User{}},
+//			).
+//			WriteFile("userclient/client.go")
+//		if err != nil {
+//			log.Fatal(err)
+//		}
+//	}
+//
+// and invoked by go generate:
+//
+//	//go:generate go run ./gen
+//
+// The fields of the request struct are mapped by the binding tags: "path" fields fill the
+// route params, "query", "header" and "cookie" fields are sent as is, "form" fields are sent
+// as url-encoded body (or query for the methods without body), and the other fields are
+// encoded as JSON body. The response body is decoded as JSON.
+package clientgen
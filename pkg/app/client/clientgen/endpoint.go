@@ -0,0 +1,272 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package clientgen
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"hertz-study/pkg/protocol/consts"
+)
+
+// the binding tags in the order of precedence
+var sourceTags = []string{"path", "query", "header", "cookie", "form", "json"}
+
+type field struct {
+	expr   string // the expression to access the field, e.g. "in.Name"
+	goName string
+	typ    reflect.Type
+	source string
+	key    string
+	tag    reflect.StructTag
+}
+
+// collectFields collects the exported fields of t, the fields of untagged embedded structs are flattened.
+func collectFields(t reflect.Type, expr string, fields []field) []field {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+		fd := field{expr: expr + "." + sf.Name, goName: sf.Name, typ: sf.Type, source: "json", tag: sf.Tag}
+		tagged := false
+		for _, tag := range sourceTags {
+			v, ok := sf.Tag.Lookup(tag)
+			if !ok {
+				continue
+			}
+			tagged = true
+			key := strings.Split(v, ",")[0]
+			if key == "-" {
+				continue
+			}
+			if key == "" {
+				key = sf.Name
+			}
+			fd.source, fd.key = tag, key
+			break
+		}
+		if sf.Anonymous && !tagged && sf.Type.Kind() == reflect.Struct {
+			fields = collectFields(sf.Type, fd.expr, fields)
+			continue
+		}
+		if tagged && fd.key == "" {
+			// all the tags are "-"
+			continue
+		}
+		fields = append(fields, fd)
+	}
+	return fields
+}
+
+func (f *file) writeEndpoint(e Endpoint) error {
+	reqType, respType := indirect(e.Request), indirect(e.Response)
+	if reqType != nil && reqType.Kind() != reflect.Struct {
+		return fmt.Errorf("request type %s is not a struct", reqType)
+	}
+
+	var fields []field
+	var params, ret string
+	if reqType != nil {
+		name, err := f.typeName(reqType)
+		if err != nil {
+			return err
+		}
+		params = ", in *" + name
+		fields = collectFields(reqType, "in", nil)
+	}
+	if respType != nil {
+		name, err := f.typeName(respType)
+		if err != nil {
+			return err
+		}
+		ret = "(*" + name + ", error)"
+	} else {
+		ret = "error"
+	}
+	errRet := "return err"
+	if respType != nil {
+		errRet = "return nil, err"
+	}
+
+	b := &f.body
+	fmt.Fprintf(b, "\n// %s sends %s %s.\n", e.Name, e.Method, e.Path)
+	if e.Doc != "" {
+		b.WriteString("//\n")
+		for _, line := range strings.Split(strings.TrimSpace(e.Doc), "\n") {
+			b.WriteString(strings.TrimRight("// "+line, " ") + "\n")
+		}
+	}
+	fmt.Fprintf(b, "func (c *Client) %s(ctx context.Context%s, opts ...%s.RequestOption) %s {\n", e.Name, params, f.use(configPkg), ret)
+	fmt.Fprintf(b, "req := %[1]s.AcquireRequest()\ndefer %[1]s.ReleaseRequest(req)\nreq.SetOptions(opts...)\n", f.use(protocolPkg))
+	fmt.Fprintf(b, "req.SetMethod(%q)\n", e.Method)
+
+	uri, err := f.pathExpr(e.Path, fields)
+	if err != nil {
+		return err
+	}
+	hasBody := e.Method == consts.MethodPost || e.Method == consts.MethodPut ||
+		e.Method == consts.MethodPatch || e.Method == consts.MethodDelete
+
+	var bodyFields, formFields []field
+	f.use("net/url")
+	b.WriteString("query := url.Values{}\n")
+	for _, fd := range fields {
+		switch {
+		case fd.source == "query" || (fd.source == "form" && !hasBody):
+			f.writeValues(fd.expr, fd.typ, func(v string) string { return fmt.Sprintf("query.Add(%q, %s)", fd.key, v) })
+		case fd.source == "header":
+			f.writeValues(fd.expr, fd.typ, func(v string) string { return fmt.Sprintf("req.Header.Add(%q, %s)", fd.key, v) })
+		case fd.source == "cookie":
+			f.writeValues(fd.expr, fd.typ, func(v string) string { return fmt.Sprintf("req.SetCookie(%q, %s)", fd.key, v) })
+		case fd.source == "form":
+			formFields = append(formFields, fd)
+		case fd.source == "json":
+			bodyFields = append(bodyFields, fd)
+		}
+	}
+	fmt.Fprintf(b, "uri := c.baseURL + %s\nif len(query) > 0 {\nuri += \"?\" + query.Encode()\n}\nreq.SetRequestURI(uri)\n", uri)
+
+	switch {
+	case hasBody && len(bodyFields) > 0:
+		if len(formFields) > 0 {
+			return errors.New("both form and json fields are defined")
+		}
+		if err = f.writeJSONBody(bodyFields, errRet); err != nil {
+			return err
+		}
+	case len(formFields) > 0:
+		b.WriteString("form := url.Values{}\n")
+		for _, fd := range formFields {
+			f.writeValues(fd.expr, fd.typ, func(v string) string { return fmt.Sprintf("form.Add(%q, %s)", fd.key, v) })
+		}
+		b.WriteString("req.Header.SetContentTypeBytes([]byte(\"application/x-www-form-urlencoded\"))\nreq.SetBodyString(form.Encode())\n")
+	}
+
+	if respType != nil {
+		name, _ := f.typeName(respType)
+		fmt.Fprintf(b, "out := new(%s)\nif err := c.do(ctx, req, out); err != nil {\nreturn nil, err\n}\nreturn out, nil\n}\n", name)
+	} else {
+		b.WriteString("return c.do(ctx, req, nil)\n}\n")
+	}
+	return nil
+}
+
+// pathExpr returns the expression building the path with the route params filled by the path fields.
+func (f *file) pathExpr(route string, fields []field) (string, error) {
+	byKey := make(map[string]field)
+	for _, fd := range fields {
+		if fd.source == "path" {
+			byKey[fd.key] = fd
+		}
+	}
+	var parts []string
+	literal := ""
+	segments := strings.Split(route, "/")
+	for i, seg := range segments {
+		if i > 0 {
+			literal += "/"
+		}
+		if seg == "" || (seg[0] != ':' && seg[0] != '*') {
+			literal += seg
+			continue
+		}
+		fd, ok := byKey[seg[1:]]
+		if !ok {
+			return "", fmt.Errorf("no field is tagged with path:%q", seg[1:])
+		}
+		if k := fd.typ.Kind(); k == reflect.Ptr || k == reflect.Slice || k == reflect.Array || k == reflect.Map || k == reflect.Struct {
+			return "", fmt.Errorf("path field %s must be a scalar", fd.goName)
+		}
+		parts = append(parts, fmt.Sprintf("%q", literal))
+		literal = ""
+		v := f.stringExpr(fd.expr, fd.typ)
+		if seg[0] == ':' {
+			v = "url.PathEscape(" + v + ")"
+		}
+		parts = append(parts, v)
+	}
+	if literal != "" || len(parts) == 0 {
+		parts = append(parts, fmt.Sprintf("%q", literal))
+	}
+	return strings.Join(parts, " + "), nil
+}
+
+// writeValues writes the statement add for every value of the field.
+func (f *file) writeValues(expr string, t reflect.Type, add func(v string) string) {
+	b := &f.body
+	switch t.Kind() {
+	case reflect.Ptr:
+		fmt.Fprintf(b, "if %s != nil {\n", expr)
+		f.writeValues("(*"+expr+")", t.Elem(), add)
+		b.WriteString("}\n")
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// []byte is sent as string
+			fmt.Fprintf(b, "%s\n", add("string("+expr+"[:])"))
+			return
+		}
+		fmt.Fprintf(b, "for _, v := range %s {\n", expr)
+		f.writeValues("v", t.Elem(), add)
+		b.WriteString("}\n")
+	default:
+		fmt.Fprintf(b, "%s\n", add(f.stringExpr(expr, t)))
+	}
+}
+
+func (f *file) stringExpr(expr string, t reflect.Type) string {
+	if t.Kind() == reflect.String {
+		if t.Name() == "string" && t.PkgPath() == "" {
+			return expr
+		}
+		return "string(" + expr + ")"
+	}
+	f.use("fmt")
+	return "fmt.Sprint(" + expr + ")"
+}
+
+// writeJSONBody writes the body with an anonymous struct holding only the body fields,
+// so the fields bound from the other sources are not duplicated in the body.
+func (f *file) writeJSONBody(fields []field, errRet string) error {
+	b := &f.body
+	names := make(map[string]bool, len(fields))
+	b.WriteString("body, err := json.Marshal(struct {\n")
+	for _, fd := range fields {
+		if names[fd.goName] {
+			return fmt.Errorf("duplicate body field %s", fd.goName)
+		}
+		names[fd.goName] = true
+		typ, err := f.typeName(fd.typ)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", fd.goName, err)
+		}
+		if v, ok := fd.tag.Lookup("json"); ok {
+			fmt.Fprintf(b, "%s %s `json:%q`\n", fd.goName, typ, v)
+		} else {
+			fmt.Fprintf(b, "%s %s\n", fd.goName, typ)
+		}
+	}
+	b.WriteString("}{\n")
+	for _, fd := range fields {
+		fmt.Fprintf(b, "%s: %s,\n", fd.goName, fd.expr)
+	}
+	fmt.Fprintf(b, "})\nif err != nil {\n%s\n}\n", errRet)
+	b.WriteString("req.Header.SetContentTypeBytes([]byte(\"application/json\"))\nreq.SetBodyRaw(body)\n")
+	return nil
+}
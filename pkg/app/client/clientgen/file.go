@@ -0,0 +1,207 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package clientgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/build"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"hertz-study/pkg/app/client"
+	"hertz-study/pkg/common/config"
+	"hertz-study/pkg/protocol"
+	"hertz-study/pkg/protocol/consts"
+)
+
+// the packages imported by every generated file
+var (
+	clientPkg   = reflect.TypeOf(client.Client{}).PkgPath()
+	configPkg   = reflect.TypeOf(config.RequestOption{}).PkgPath()
+	protocolPkg = reflect.TypeOf(protocol.Request{}).PkgPath()
+)
+
+var staticImports = []string{"context", "encoding/json", "fmt", "net/url", clientPkg, configPkg, protocolPkg}
+
+type file struct {
+	pkgName string
+	imports map[string]string // path -> name
+	used    map[string]bool
+	names   map[string]bool
+	body    bytes.Buffer
+}
+
+func newFile(pkgName string) *file {
+	f := &file{
+		pkgName: pkgName,
+		imports: make(map[string]string),
+		used:    make(map[string]bool),
+		names:   map[string]bool{pkgName: true},
+	}
+	// reserve the names of the common packages before the packages of the user types
+	for _, p := range staticImports {
+		f.reserve(p)
+	}
+	return f
+}
+
+// use imports the package p and returns the name to refer to it.
+func (f *file) use(p string) string {
+	f.used[p] = true
+	return f.reserve(p)
+}
+
+func (f *file) reserve(p string) string {
+	if name, ok := f.imports[p]; ok {
+		return name
+	}
+	base := strings.Map(func(r rune) rune {
+		if r == '-' || r == '.' {
+			return '_'
+		}
+		return r
+	}, path.Base(p))
+	name := base
+	for i := 2; f.names[name]; i++ {
+		name = base + strconv.Itoa(i)
+	}
+	f.imports[p] = name
+	f.names[name] = true
+	return name
+}
+
+// typeName returns the Go expression of t in the generated file.
+func (f *file) typeName(t reflect.Type) (string, error) {
+	if t.Name() != "" {
+		if t.PkgPath() == "" {
+			return t.Name(), nil
+		}
+		return f.use(t.PkgPath()) + "." + t.Name(), nil
+	}
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		elem, err := f.typeName(t.Elem())
+		if err != nil {
+			return "", err
+		}
+		switch t.Kind() {
+		case reflect.Ptr:
+			return "*" + elem, nil
+		case reflect.Slice:
+			return "[]" + elem, nil
+		default:
+			return "[" + strconv.Itoa(t.Len()) + "]" + elem, nil
+		}
+	case reflect.Map:
+		key, err := f.typeName(t.Key())
+		if err != nil {
+			return "", err
+		}
+		elem, err := f.typeName(t.Elem())
+		if err != nil {
+			return "", err
+		}
+		return "map[" + key + "]" + elem, nil
+	case reflect.Interface:
+		if t.NumMethod() == 0 {
+			return "interface{}", nil
+		}
+	}
+	return "", fmt.Errorf("unsupported type %s", t)
+}
+
+func (f *file) writeTo(buf *bytes.Buffer) {
+	buf.WriteString("// Code generated by clientgen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(buf, "package %s\n\n", f.pkgName)
+
+	c, proto := f.use(clientPkg), f.use(protocolPkg)
+	for _, p := range []string{"context", "encoding/json", "fmt"} {
+		f.use(p)
+	}
+
+	paths := make([]string, 0, len(f.used))
+	for p := range f.used {
+		paths = append(paths, p)
+	}
+	// standard packages first
+	sort.Slice(paths, func(i, j int) bool {
+		if si, sj := stdPkg(paths[i]), stdPkg(paths[j]); si != sj {
+			return si
+		}
+		return paths[i] < paths[j]
+	})
+	buf.WriteString("import (\n")
+	for i, p := range paths {
+		if i > 0 && stdPkg(paths[i-1]) && !stdPkg(p) {
+			buf.WriteString("\n")
+		}
+		if name := f.imports[p]; name != path.Base(p) {
+			fmt.Fprintf(buf, "\t%s %q\n", name, p)
+		} else {
+			fmt.Fprintf(buf, "\t%q\n", p)
+		}
+	}
+	buf.WriteString(")\n\n")
+
+	fmt.Fprintf(buf, `// Client is the generated typed client.
+type Client struct {
+	c       *%[1]s.Client
+	baseURL string
+}
+
+// NewClient creates a Client which sends requests to baseURL, e.g. "http://127.0.0.1:8888".
+func NewClient(c *%[1]s.Client, baseURL string) *Client {
+	return &Client{c: c, baseURL: baseURL}
+}
+
+// StatusError is returned when the server responds with a status code >= %[3]d.
+type StatusError struct {
+	StatusCode int
+	Body       []byte
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code %%d: %%s", e.StatusCode, e.Body)
+}
+
+func (c *Client) do(ctx context.Context, req *%[2]s.Request, out interface{}) error {
+	resp := %[2]s.AcquireResponse()
+	defer %[2]s.ReleaseResponse(resp)
+	if err := c.c.Do(ctx, req, resp); err != nil {
+		return err
+	}
+	if resp.StatusCode() >= %[3]d {
+		return &StatusError{StatusCode: resp.StatusCode(), Body: append([]byte(nil), resp.Body()...)}
+	}
+	if out == nil || len(resp.Body()) == 0 {
+		return nil
+	}
+	return json.Unmarshal(resp.Body(), out)
+}
+`, c, proto, consts.StatusBadRequest)
+
+	buf.Write(f.body.Bytes())
+}
+
+func stdPkg(p string) bool {
+	pkg, err := build.Import(p, "", build.FindOnly)
+	return err == nil && pkg.Goroot
+}
@@ -0,0 +1,100 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"hertz-study/pkg/app/server/registry"
+)
+
+// MemoryRegistry is an in-process registry.Registry which is also a Resolver, the servers
+// registered with it are resolved by their ServiceName, e.g. "http://user-service/users/1"
+// is sent to the instances registered with ServiceName "user-service". It is useful for
+// the tests and the single process deployments, and serves as the reference for the
+// registries backed by etcd, consul and so on.
+type MemoryRegistry struct {
+	mu       sync.RWMutex
+	services map[string][]*registry.Info
+}
+
+// NewMemoryRegistry creates an empty MemoryRegistry.
+func NewMemoryRegistry() *MemoryRegistry {
+	return &MemoryRegistry{services: make(map[string][]*registry.Info)}
+}
+
+// Register implements the registry.Registry interface.
+func (r *MemoryRegistry) Register(info *registry.Info) error {
+	if info == nil || info.ServiceName == "" || info.Addr == nil {
+		return fmt.Errorf("invalid registry info: %v", info)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	infos := r.services[info.ServiceName]
+	for i, in := range infos {
+		if in.Addr.String() == info.Addr.String() {
+			infos[i] = info
+			return nil
+		}
+	}
+	r.services[info.ServiceName] = append(infos, info)
+	return nil
+}
+
+// Deregister implements the registry.Registry interface.
+func (r *MemoryRegistry) Deregister(info *registry.Info) error {
+	if info == nil || info.Addr == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	infos := r.services[info.ServiceName]
+	for i, in := range infos {
+		if in.Addr.String() == info.Addr.String() {
+			r.services[info.ServiceName] = append(infos[:i:i], infos[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// Target implements the Resolver interface, the host of the request is the service name.
+func (r *MemoryRegistry) Target(ctx context.Context, target *TargetInfo) string {
+	return target.Host
+}
+
+// Resolve implements the Resolver interface.
+func (r *MemoryRegistry) Resolve(ctx context.Context, desc string) (Result, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	infos := r.services[desc]
+	if len(infos) == 0 {
+		return Result{}, fmt.Errorf("no instance of service %s", desc)
+	}
+	ins := make([]Instance, 0, len(infos))
+	for _, info := range infos {
+		ins = append(ins, NewInstance(info.Addr.Network(), info.Addr.String(), info.Weight, info.Tags))
+	}
+	return Result{CacheKey: desc, Instances: ins}, nil
+}
+
+// Name implements the Resolver interface.
+func (r *MemoryRegistry) Name() string {
+	return "memory"
+}
@@ -0,0 +1,257 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loadbalance
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/bytedance/gopkg/lang/fastrand"
+	"github.com/bytedance/gopkg/util/xxhash3"
+	"golang.org/x/sync/singleflight"
+	"hertz-study/pkg/app/client/discovery"
+	"hertz-study/pkg/app/server/registry"
+	"hertz-study/pkg/common/hlog"
+	"hertz-study/pkg/protocol"
+)
+
+const defaultVirtualFactor = 100
+
+// KeyFunc returns the key used to locate the instance on the hash ring.
+type KeyFunc func(ctx context.Context, req *protocol.Request) string
+
+// ConsistentHashOptions is the ring configuration of consistent hash balancer.
+type ConsistentHashOptions struct {
+	// KeyFunc is used to get the hash key of the request, it must not be nil.
+	// KeyFromHeader and KeyFromPath can be used for most cases.
+	KeyFunc KeyFunc
+
+	// VirtualFactor is the count of virtual nodes of every instance on the ring.
+	// The more virtual nodes, the more balanced the ring is, and the more memory it costs.
+	// Default is 100.
+	VirtualFactor int
+
+	// Weighted scales the virtual node count of an instance by its weight
+	// relative to registry.DefaultWeight.
+	Weighted bool
+
+	// BoundedLoadFactor enables consistent hashing with bounded loads when it is greater than 1.
+	// An instance accepts a new request only if its in-flight requests are less than
+	// ceil(BoundedLoadFactor * average in-flight requests), otherwise the next instance
+	// on the ring is tried. 1.25 is a commonly used value. Zero disables it.
+	BoundedLoadFactor float64
+}
+
+// NewConsistentHashOptions creates a default ConsistentHashOptions with the given KeyFunc.
+func NewConsistentHashOptions(keyFunc KeyFunc) ConsistentHashOptions {
+	return ConsistentHashOptions{
+		KeyFunc:       keyFunc,
+		VirtualFactor: defaultVirtualFactor,
+	}
+}
+
+// KeyFromHeader uses the value of the request header as hash key.
+func KeyFromHeader(key string) KeyFunc {
+	return func(ctx context.Context, req *protocol.Request) string {
+		return req.Header.Get(key)
+	}
+}
+
+// KeyFromPath uses the request path as hash key.
+func KeyFromPath() KeyFunc {
+	return func(ctx context.Context, req *protocol.Request) string {
+		return string(req.URI().Path())
+	}
+}
+
+type hashRing struct {
+	instances []discovery.Instance
+	// hashes of virtual nodes in ascending order
+	hashes []uint64
+	// index of the owner instance of each virtual node
+	owners []int
+}
+
+type consistentHashBalancer struct {
+	opts        ConsistentHashOptions
+	cachedRings sync.Map
+	sfg         singleflight.Group
+	// instance address -> *int64, only used when bounded load is enabled
+	loads sync.Map
+}
+
+// NewConsistentHashBalancer creates a loadbalancer using consistent hash algorithm,
+// requests with the same key are always sent to the same instance as long as it
+// is available, which is useful for cache-affinity routing to stateful upstreams.
+func NewConsistentHashBalancer(opts ConsistentHashOptions) Loadbalancer {
+	if opts.KeyFunc == nil {
+		panic("loadbalance: KeyFunc of ConsistentHashOptions must not be nil")
+	}
+	if opts.VirtualFactor <= 0 {
+		opts.VirtualFactor = defaultVirtualFactor
+	}
+	if opts.BoundedLoadFactor != 0 && opts.BoundedLoadFactor <= 1 {
+		panic("loadbalance: BoundedLoadFactor of ConsistentHashOptions must be greater than 1")
+	}
+	return &consistentHashBalancer{opts: opts}
+}
+
+func (cb *consistentHashBalancer) buildRing(e discovery.Result) *hashRing {
+	r := &hashRing{instances: make([]discovery.Instance, 0, len(e.Instances))}
+	type vnode struct {
+		hash  uint64
+		owner int
+	}
+	var vnodes []vnode
+	for _, ins := range e.Instances {
+		weight := ins.Weight()
+		if weight <= 0 {
+			hlog.SystemLogger().Warnf("Invalid weight=%d on instance address=%s", weight, ins.Address())
+			continue
+		}
+		count := cb.opts.VirtualFactor
+		if cb.opts.Weighted {
+			count = count * weight / registry.DefaultWeight
+			if count <= 0 {
+				count = 1
+			}
+		}
+		owner := len(r.instances)
+		r.instances = append(r.instances, ins)
+		addr := ins.Address().String()
+		for i := 0; i < count; i++ {
+			vnodes = append(vnodes, vnode{hash: xxhash3.HashString(addr + "#" + strconv.Itoa(i)), owner: owner})
+		}
+	}
+	sort.Slice(vnodes, func(i, j int) bool { return vnodes[i].hash < vnodes[j].hash })
+	r.hashes = make([]uint64, len(vnodes))
+	r.owners = make([]int, len(vnodes))
+	for i := range vnodes {
+		r.hashes[i] = vnodes[i].hash
+		r.owners[i] = vnodes[i].owner
+	}
+	return r
+}
+
+func (cb *consistentHashBalancer) getRing(e discovery.Result) *hashRing {
+	r, ok := cb.cachedRings.Load(e.CacheKey)
+	if !ok {
+		r, _, _ = cb.sfg.Do(e.CacheKey, func() (interface{}, error) {
+			return cb.buildRing(e), nil
+		})
+		cb.cachedRings.Store(e.CacheKey, r)
+	}
+	return r.(*hashRing)
+}
+
+func (cb *consistentHashBalancer) loadOf(ins discovery.Instance) *int64 {
+	v, _ := cb.loads.LoadOrStore(ins.Address().String(), new(int64))
+	return v.(*int64)
+}
+
+func (cb *consistentHashBalancer) pick(r *hashRing, hash uint64) discovery.Instance {
+	if len(r.hashes) == 0 {
+		return nil
+	}
+	idx := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= hash })
+	if idx == len(r.hashes) {
+		idx = 0
+	}
+	if cb.opts.BoundedLoadFactor <= 0 {
+		return r.instances[r.owners[idx]]
+	}
+
+	var total int64
+	for _, ins := range r.instances {
+		total += atomic.LoadInt64(cb.loadOf(ins))
+	}
+	limit := int64(math.Ceil(float64(total+1) * cb.opts.BoundedLoadFactor / float64(len(r.instances))))
+	for i := 0; i < len(r.hashes); i++ {
+		ins := r.instances[r.owners[(idx+i)%len(r.hashes)]]
+		if load := cb.loadOf(ins); atomic.LoadInt64(load) < limit {
+			atomic.AddInt64(load, 1)
+			return ins
+		}
+	}
+	// all instances are overloaded, fallback to the owner of the key
+	ins := r.instances[r.owners[idx]]
+	atomic.AddInt64(cb.loadOf(ins), 1)
+	return ins
+}
+
+// Pick implements the Loadbalancer interface.
+// There is no request to get key from, so a random position of the ring is used.
+func (cb *consistentHashBalancer) Pick(e discovery.Result) discovery.Instance {
+	return cb.pick(cb.getRing(e), fastrand.Uint64())
+}
+
+// PickByRequest implements the RequestLoadbalancer interface.
+func (cb *consistentHashBalancer) PickByRequest(ctx context.Context, req *protocol.Request, e discovery.Result) discovery.Instance {
+	return cb.pick(cb.getRing(e), xxhash3.HashString(cb.opts.KeyFunc(ctx, req)))
+}
+
+// Done implements the DoneNotifier interface.
+func (cb *consistentHashBalancer) Done(ins discovery.Instance, err error) {
+	if cb.opts.BoundedLoadFactor <= 0 || ins == nil {
+		return
+	}
+	atomic.AddInt64(cb.loadOf(ins), -1)
+}
+
+// pruneLoads drops the load of the addresses which are on none of the rings,
+// the ones still having requests in flight are left to a later rebuild.
+func (cb *consistentHashBalancer) pruneLoads() {
+	if cb.opts.BoundedLoadFactor <= 0 {
+		return
+	}
+	addrs := make(map[string]struct{})
+	cb.cachedRings.Range(func(_, r interface{}) bool {
+		for _, ins := range r.(*hashRing).instances {
+			addrs[ins.Address().String()] = struct{}{}
+		}
+		return true
+	})
+	cb.loads.Range(func(addr, load interface{}) bool {
+		if _, ok := addrs[addr.(string)]; !ok && atomic.LoadInt64(load.(*int64)) <= 0 {
+			cb.loads.Delete(addr)
+		}
+		return true
+	})
+}
+
+// Rebalance implements the Loadbalancer interface.
+func (cb *consistentHashBalancer) Rebalance(e discovery.Result) {
+	cb.cachedRings.Store(e.CacheKey, cb.buildRing(e))
+	cb.pruneLoads()
+}
+
+// Delete implements the Loadbalancer interface.
+func (cb *consistentHashBalancer) Delete(cacheKey string) {
+	cb.cachedRings.Delete(cacheKey)
+	cb.pruneLoads()
+}
+
+// Name implements the Loadbalancer interface.
+// The ring configuration is included since BalancerFactory is cached by balancer name.
+func (cb *consistentHashBalancer) Name() string {
+	return fmt.Sprintf("consistent_hash|%d|%t|%v", cb.opts.VirtualFactor, cb.opts.Weighted, cb.opts.BoundedLoadFactor)
+}
@@ -130,7 +130,12 @@ func (b *BalancerFactory) GetInstance(ctx context.Context, req *protocol.Request
 		return nil, err
 	}
 	atomic.StoreInt32(&cacheRes.expire, 0)
-	ins := b.balancer.Pick(cacheRes.res.Load().(discovery.Result))
+	var ins discovery.Instance
+	if rb, ok := b.balancer.(RequestLoadbalancer); ok {
+		ins = rb.PickByRequest(ctx, req, cacheRes.res.Load().(discovery.Result))
+	} else {
+		ins = b.balancer.Pick(cacheRes.res.Load().(discovery.Result))
+	}
 	if ins == nil {
 		hlog.SystemLogger().Errorf("null instance. serviceName: %s, options: %v", string(req.Host()), req.Options())
 		return nil, errors.NewPublic("instance not found")
@@ -138,6 +143,14 @@ func (b *BalancerFactory) GetInstance(ctx context.Context, req *protocol.Request
 	return ins, nil
 }
 
+// Done notifies the balancer that the request sent to ins has finished with err.
+// It is a no-op if the balancer doesn't implement DoneNotifier.
+func (b *BalancerFactory) Done(ins discovery.Instance, err error) {
+	if dn, ok := b.balancer.(DoneNotifier); ok {
+		dn.Done(ins, err)
+	}
+}
+
 func (b *BalancerFactory) getCacheResult(ctx context.Context, req *protocol.Request) (*cacheResult, error) {
 	target := b.resolver.Target(ctx, &discovery.TargetInfo{Host: string(req.Host()), Tags: req.Options().Tags()})
 	cr, existed := b.cache.Load(target)
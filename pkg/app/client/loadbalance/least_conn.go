@@ -0,0 +1,91 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loadbalance
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/bytedance/gopkg/lang/fastrand"
+	"hertz-study/pkg/app/client/discovery"
+)
+
+type leastConnBalancer struct {
+	inFlight sync.Map // address -> *int64
+}
+
+// NewLeastConnBalancer creates a loadbalancer which picks the instance with the fewest
+// in-flight requests relative to its weight, the ties are broken randomly. It relies on
+// DoneNotifier to know when the requests finish.
+func NewLeastConnBalancer() Loadbalancer {
+	return &leastConnBalancer{}
+}
+
+func (lb *leastConnBalancer) inFlightOf(ins discovery.Instance) *int64 {
+	v, _ := lb.inFlight.LoadOrStore(ins.Address().String(), new(int64))
+	return v.(*int64)
+}
+
+// Pick implements the Loadbalancer interface.
+func (lb *leastConnBalancer) Pick(e discovery.Result) discovery.Instance {
+	n := len(e.Instances)
+	if n == 0 {
+		return nil
+	}
+	var (
+		picked        discovery.Instance
+		pickedLoad    *int64
+		minN, minW    int64
+		start, offset = fastrand.Intn(n), 0
+	)
+	for ; offset < n; offset++ {
+		ins := e.Instances[(start+offset)%n]
+		w := int64(ins.Weight())
+		if w <= 0 {
+			continue
+		}
+		load := lb.inFlightOf(ins)
+		cur := atomic.LoadInt64(load)
+		// cur/w < minN/minW
+		if picked == nil || cur*minW < minN*w {
+			picked, pickedLoad, minN, minW = ins, load, cur, w
+		}
+	}
+	if picked != nil {
+		atomic.AddInt64(pickedLoad, 1)
+	}
+	return picked
+}
+
+// Done implements the DoneNotifier interface.
+func (lb *leastConnBalancer) Done(ins discovery.Instance, err error) {
+	if ins == nil {
+		return
+	}
+	atomic.AddInt64(lb.inFlightOf(ins), -1)
+}
+
+// Rebalance implements the Loadbalancer interface.
+func (lb *leastConnBalancer) Rebalance(e discovery.Result) {}
+
+// Delete implements the Loadbalancer interface.
+func (lb *leastConnBalancer) Delete(cacheKey string) {}
+
+// Name implements the Loadbalancer interface.
+func (lb *leastConnBalancer) Name() string {
+	return "least_conn"
+}
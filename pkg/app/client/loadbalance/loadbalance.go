@@ -17,9 +17,11 @@
 package loadbalance
 
 import (
+	"context"
 	"time"
 
 	"hertz-study/pkg/app/client/discovery"
+	"hertz-study/pkg/protocol"
 )
 
 // Loadbalancer picks instance for the given service discovery result.
@@ -37,6 +39,23 @@ type Loadbalancer interface {
 	Name() string
 }
 
+// RequestLoadbalancer is implemented by the Loadbalancer which picks instance according to
+// the outgoing request, such as consistent hashing. BalancerFactory prefers PickByRequest
+// over Pick when the balancer implements it.
+type RequestLoadbalancer interface {
+	Loadbalancer
+
+	// PickByRequest is used to select an instance according to the request and discovery result
+	PickByRequest(ctx context.Context, req *protocol.Request, e discovery.Result) discovery.Instance
+}
+
+// DoneNotifier is implemented by the Loadbalancer which needs to know when the request
+// sent to the picked instance finishes, e.g. for in-flight load accounting.
+type DoneNotifier interface {
+	// Done is called with the picked instance and the error of the request after it finishes
+	Done(ins discovery.Instance, err error)
+}
+
 const (
 	DefaultRefreshInterval = 5 * time.Second
 	DefaultExpireInterval  = 15 * time.Second
@@ -0,0 +1,210 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loadbalance
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+	"hertz-study/pkg/app/client/discovery"
+	"hertz-study/pkg/app/server/registry"
+	"hertz-study/pkg/protocol"
+)
+
+const (
+	defaultFailureThreshold = 3
+	defaultRecoverInterval  = 10 * time.Second
+)
+
+// LocalityOptions is the configuration of locality-aware balancer.
+type LocalityOptions struct {
+	// Zone is the zone of the caller. Instances whose zone tag equals to it are preferred.
+	Zone string
+
+	// ZoneTagKey is the tag key of the zone in instance tags.
+	// Default is registry.ZoneTagKey.
+	ZoneTagKey string
+
+	// FailureThreshold is the count of consecutive failures after which a
+	// same-zone instance is skipped, default is 3.
+	FailureThreshold int32
+
+	// RecoverInterval is the duration a failed instance is skipped before
+	// it is tried again, default is 10s.
+	RecoverInterval time.Duration
+}
+
+type localityInfo struct {
+	local  discovery.Result
+	remote discovery.Result
+}
+
+type instanceHealth struct {
+	failures int32
+	// unix nano before which the instance is considered unhealthy
+	until int64
+}
+
+type localityBalancer struct {
+	opts   LocalityOptions
+	inner  Loadbalancer
+	cached sync.Map
+	sfg    singleflight.Group
+	// instance address -> *instanceHealth
+	health sync.Map
+}
+
+// NewLocalityBalancer creates a loadbalancer which prefers the instances in the same zone
+// and spills over to the other zones when none of the same-zone instances is available.
+// The zone of instances is read from the tags of discovery result, which is usually
+// registered by the server with registry.Info.Tags[registry.ZoneTagKey].
+// The instance of each zone group is picked by inner.
+func NewLocalityBalancer(inner Loadbalancer, opts LocalityOptions) Loadbalancer {
+	if inner == nil {
+		inner = NewWeightedBalancer()
+	}
+	if opts.ZoneTagKey == "" {
+		opts.ZoneTagKey = registry.ZoneTagKey
+	}
+	if opts.FailureThreshold <= 0 {
+		opts.FailureThreshold = defaultFailureThreshold
+	}
+	if opts.RecoverInterval <= 0 {
+		opts.RecoverInterval = defaultRecoverInterval
+	}
+	return &localityBalancer{opts: opts, inner: inner}
+}
+
+func (lb *localityBalancer) split(e discovery.Result) *localityInfo {
+	li := &localityInfo{
+		local:  discovery.Result{CacheKey: e.CacheKey + "|local"},
+		remote: discovery.Result{CacheKey: e.CacheKey + "|remote"},
+	}
+	for _, ins := range e.Instances {
+		if zone, ok := ins.Tag(lb.opts.ZoneTagKey); ok && zone == lb.opts.Zone {
+			li.local.Instances = append(li.local.Instances, ins)
+		} else {
+			li.remote.Instances = append(li.remote.Instances, ins)
+		}
+	}
+	// nothing to spill over, fallback to the whole result
+	if len(li.remote.Instances) == 0 {
+		li.remote = e
+	}
+	return li
+}
+
+func (lb *localityBalancer) getInfo(e discovery.Result) *localityInfo {
+	li, ok := lb.cached.Load(e.CacheKey)
+	if !ok {
+		li, _, _ = lb.sfg.Do(e.CacheKey, func() (interface{}, error) {
+			return lb.split(e), nil
+		})
+		lb.cached.Store(e.CacheKey, li)
+	}
+	return li.(*localityInfo)
+}
+
+func (lb *localityBalancer) healthy(ins discovery.Instance) bool {
+	v, ok := lb.health.Load(ins.Address().String())
+	if !ok {
+		return true
+	}
+	return atomic.LoadInt64(&v.(*instanceHealth).until) <= time.Now().UnixNano()
+}
+
+func (lb *localityBalancer) pick(e discovery.Result, pick func(discovery.Result) discovery.Instance) discovery.Instance {
+	li := lb.getInfo(e)
+	// the inner balancer may pick an unhealthy instance, give it several chances
+	for i := 0; i < len(li.local.Instances); i++ {
+		ins := pick(li.local)
+		if ins == nil {
+			continue
+		}
+		if lb.healthy(ins) {
+			return ins
+		}
+		// the discarded pick never runs a request, release what the inner balancer counted for it
+		if dn, ok := lb.inner.(DoneNotifier); ok {
+			dn.Done(ins, nil)
+		}
+	}
+	return pick(li.remote)
+}
+
+// Pick implements the Loadbalancer interface.
+func (lb *localityBalancer) Pick(e discovery.Result) discovery.Instance {
+	return lb.pick(e, lb.inner.Pick)
+}
+
+// PickByRequest implements the RequestLoadbalancer interface.
+func (lb *localityBalancer) PickByRequest(ctx context.Context, req *protocol.Request, e discovery.Result) discovery.Instance {
+	rb, ok := lb.inner.(RequestLoadbalancer)
+	if !ok {
+		return lb.Pick(e)
+	}
+	return lb.pick(e, func(r discovery.Result) discovery.Instance {
+		return rb.PickByRequest(ctx, req, r)
+	})
+}
+
+// Done implements the DoneNotifier interface.
+func (lb *localityBalancer) Done(ins discovery.Instance, err error) {
+	if ins == nil {
+		return
+	}
+	if dn, ok := lb.inner.(DoneNotifier); ok {
+		dn.Done(ins, err)
+	}
+	v, _ := lb.health.LoadOrStore(ins.Address().String(), &instanceHealth{})
+	h := v.(*instanceHealth)
+	if err == nil {
+		atomic.StoreInt32(&h.failures, 0)
+		return
+	}
+	if atomic.AddInt32(&h.failures, 1) >= lb.opts.FailureThreshold {
+		atomic.StoreInt32(&h.failures, 0)
+		atomic.StoreInt64(&h.until, time.Now().Add(lb.opts.RecoverInterval).UnixNano())
+	}
+}
+
+// Rebalance implements the Loadbalancer interface.
+func (lb *localityBalancer) Rebalance(e discovery.Result) {
+	li := lb.split(e)
+	lb.cached.Store(e.CacheKey, li)
+	lb.inner.Rebalance(li.local)
+	lb.inner.Rebalance(li.remote)
+}
+
+// Delete implements the Loadbalancer interface.
+func (lb *localityBalancer) Delete(cacheKey string) {
+	v, ok := lb.cached.LoadAndDelete(cacheKey)
+	if !ok {
+		return
+	}
+	li := v.(*localityInfo)
+	lb.inner.Delete(li.local.CacheKey)
+	lb.inner.Delete(li.remote.CacheKey)
+}
+
+// Name implements the Loadbalancer interface.
+func (lb *localityBalancer) Name() string {
+	return "locality|" + lb.opts.Zone + "|" + lb.inner.Name()
+}
@@ -0,0 +1,63 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loadbalance
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"hertz-study/pkg/app/client/discovery"
+)
+
+type roundRobinBalancer struct {
+	counters sync.Map // cache key -> *uint64
+}
+
+// NewRoundRobinBalancer creates a loadbalancer which picks the instances in turn,
+// the instances with non-positive weight are skipped.
+func NewRoundRobinBalancer() Loadbalancer {
+	return &roundRobinBalancer{}
+}
+
+// Pick implements the Loadbalancer interface.
+func (rb *roundRobinBalancer) Pick(e discovery.Result) discovery.Instance {
+	if len(e.Instances) == 0 {
+		return nil
+	}
+	v, _ := rb.counters.LoadOrStore(e.CacheKey, new(uint64))
+	n := atomic.AddUint64(v.(*uint64), 1) - 1
+	for i := 0; i < len(e.Instances); i++ {
+		ins := e.Instances[(n+uint64(i))%uint64(len(e.Instances))]
+		if ins.Weight() > 0 {
+			return ins
+		}
+	}
+	return nil
+}
+
+// Rebalance implements the Loadbalancer interface.
+func (rb *roundRobinBalancer) Rebalance(e discovery.Result) {}
+
+// Delete implements the Loadbalancer interface.
+func (rb *roundRobinBalancer) Delete(cacheKey string) {
+	rb.counters.Delete(cacheKey)
+}
+
+// Name implements the Loadbalancer interface.
+func (rb *roundRobinBalancer) Name() string {
+	return "round_robin"
+}
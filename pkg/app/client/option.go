@@ -18,6 +18,7 @@ package client
 
 import (
 	"crypto/tls"
+	"io"
 	"time"
 
 	"hertz-study/pkg/app/client/retry"
@@ -92,6 +93,27 @@ func WithDialer(d network.Dialer) config.ClientOption {
 	}}
 }
 
+// WithHostMapping sets the static mapping from hosts to the addresses to dial, like a hosts file.
+//
+// The key is "host" or "host:port", the latter has higher priority. The value is the list of
+// "ip" or "ip:port", the port of the request is used if absent. The requests are sent to the
+// addresses in a round-robin manner, while the Host header and the TLS server name are kept.
+func WithHostMapping(m map[string][]string) config.ClientOption {
+	return config.ClientOption{F: func(o *config.ClientOptions) {
+		o.HostMapping = m
+	}}
+}
+
+// WithRequestDumper writes every request and its response to w exactly as they are on the
+// wire, e.g. os.Stderr or a file, which helps to debug the upstream calls. The dumps of the
+// concurrent requests don't interleave. The request bodies are buffered for dumping, and the
+// response body is not dumped in the body stream mode. Use it for debugging only.
+func WithRequestDumper(w io.Writer) config.ClientOption {
+	return config.ClientOption{F: func(o *config.ClientOptions) {
+		o.RequestDumper = w
+	}}
+}
+
 // WithResponseBodyStream is used to determine whether read body in stream or not.
 func WithResponseBodyStream(b bool) config.ClientOption {
 	return config.ClientOption{F: func(o *config.ClientOptions) {
@@ -134,6 +156,13 @@ func WithDisablePathNormalizing(isDisablePathNormalizing bool) config.ClientOpti
 	}}
 }
 
+// WithRetryConfig enables retrying the failed requests up to the max attempt times, with the
+// delay of the delay policy between the attempts, e.g. retry.CombineDelay(retry.BackOffDelayPolicy,
+// retry.RandomDelayPolicy) for exponential backoff with jitter. The Retry-After of the response
+// is honored within the max delay.
+//
+// Only the idempotent requests and the ones marked by config.WithReplayable are retried unless
+// the retry condition is customized by Client.SetRetryIfFunc, e.g. client.RetryOnStatus.
 func WithRetryConfig(opts ...retry.Option) config.ClientOption {
 	retryCfg := &retry.Config{
 		MaxAttemptTimes: consts.DefaultMaxRetryTimes,
@@ -156,6 +185,14 @@ func WithWriteTimeout(t time.Duration) config.ClientOption {
 	}}
 }
 
+// WithRequestTimeout sets the timeout of the whole request for the requests without their own
+// timeout set by config.WithRequestTimeout.
+func WithRequestTimeout(t time.Duration) config.ClientOption {
+	return config.ClientOption{F: func(o *config.ClientOptions) {
+		o.RequestTimeout = t
+	}}
+}
+
 // WithConnStateObserve sets the connection state observation function.
 // The first param is used to set hostclient state func.
 // The second param is used to set observation interval, default value is 5 seconds.
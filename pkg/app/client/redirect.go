@@ -0,0 +1,109 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"hertz-study/pkg/protocol"
+	"hertz-study/pkg/protocol/client"
+	"hertz-study/pkg/protocol/consts"
+)
+
+// ErrUseLastResponse can be returned by RedirectPolicy to stop following the redirects,
+// the redirect response is returned by Do without error.
+var ErrUseLastResponse = errors.New("use last response")
+
+// RedirectPolicy decides whether to follow the redirect, req is the next request to the
+// location of the redirect and via are the URLs requested so far, the first one is the original.
+// The redirect is followed if it returns nil, otherwise Do returns the error, except that
+// ErrUseLastResponse returns the redirect response. The headers of req can be modified.
+type RedirectPolicy func(req *protocol.Request, via []string) error
+
+// LimitRedirects follows at most n redirects.
+func LimitRedirects(n int) RedirectPolicy {
+	return func(req *protocol.Request, via []string) error {
+		if len(via) > n {
+			return fmt.Errorf("stopped after %d redirects", n)
+		}
+		return nil
+	}
+}
+
+// SameHostRedirects follows at most n redirects to the host of the original request,
+// the redirect to other hosts is returned as is.
+func SameHostRedirects(n int) RedirectPolicy {
+	limit := LimitRedirects(n)
+	return func(req *protocol.Request, via []string) error {
+		origin := protocol.AcquireURI()
+		defer protocol.ReleaseURI(origin)
+		origin.Update(via[0])
+		if !bytes.Equal(origin.Host(), req.URI().Host()) {
+			return ErrUseLastResponse
+		}
+		return limit(req, via)
+	}
+}
+
+// SetRedirectPolicy makes Do follow the redirects allowed by p, e.g. LimitRedirects(10).
+// Like browsers, the method of the redirected POST request is changed to GET for the status codes
+// 301, 302 and 303 (303 changes any method except HEAD), and the Authorization header is removed
+// when the host changes. The redirects of the requests with a body stream are not followed.
+func (c *Client) SetRedirectPolicy(p RedirectPolicy) {
+	c.RedirectPolicy = p
+}
+
+func (c *Client) followRedirects(ctx context.Context, req *protocol.Request, resp *protocol.Response) error {
+	var via []string
+	for {
+		if err := c.doOnce(ctx, req, resp); err != nil {
+			return err
+		}
+		statusCode := resp.StatusCode()
+		location := resp.Header.PeekLocation()
+		if !client.StatusCodeIsRedirect(statusCode) || len(location) == 0 || req.IsBodyStream() {
+			return nil
+		}
+
+		uri := req.URI()
+		via = append(via, uri.String())
+		oldHost := string(uri.Host())
+		uri.UpdateBytes(location)
+		if string(uri.Host()) != oldHost {
+			req.Header.SetHostBytes(uri.Host())
+			req.Header.Del(consts.HeaderAuthorization)
+		}
+		method := string(req.Header.Method())
+		if (statusCode == consts.StatusSeeOther && method != consts.MethodHead) ||
+			((statusCode == consts.StatusMovedPermanently || statusCode == consts.StatusFound) && method == consts.MethodPost) {
+			req.Header.SetMethod(consts.MethodGet)
+			req.ResetBody()
+			req.Header.Del(consts.HeaderContentType)
+			req.Header.SetContentLength(0)
+		}
+
+		if err := c.RedirectPolicy(req, via); err != nil {
+			if errors.Is(err, ErrUseLastResponse) {
+				return nil
+			}
+			return err
+		}
+	}
+}
@@ -0,0 +1,154 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sse
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxLineSize is the max size of a single line of the event stream.
+const maxLineSize = 1 << 20
+
+// Event is a server-sent event.
+type Event struct {
+	// ID is the last event id of the stream when this event is dispatched.
+	ID string
+	// Event is the event type, default is "message".
+	Event string
+	// Data is the data of the event, multiple data lines are joined with "\n".
+	Data string
+}
+
+// parser parses the event stream according to
+// https://html.spec.whatwg.org/multipage/server-sent-events.html#event-stream-interpretation
+type parser struct {
+	scanner *bufio.Scanner
+
+	lastID    string
+	eventType string
+	data      strings.Builder
+	hasData   bool
+
+	// retry is set when the stream contains a valid retry field
+	retry func(d time.Duration)
+}
+
+func newParser(r io.Reader, lastID string, retry func(d time.Duration)) *parser {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 4096), maxLineSize)
+	s.Split(scanLines)
+	return &parser{scanner: s, lastID: lastID, retry: retry}
+}
+
+// next returns the next event, or the error which ends the stream (io.EOF if the stream ends normally).
+func (p *parser) next() (*Event, error) {
+	for p.scanner.Scan() {
+		line := p.scanner.Bytes()
+		if len(line) == 0 {
+			if e := p.dispatch(); e != nil {
+				return e, nil
+			}
+			continue
+		}
+		if line[0] == ':' {
+			// comment, usually used as heartbeat
+			continue
+		}
+		field, value := line, []byte(nil)
+		if i := bytes.IndexByte(line, ':'); i >= 0 {
+			field, value = line[:i], line[i+1:]
+			if len(value) > 0 && value[0] == ' ' {
+				value = value[1:]
+			}
+		}
+		p.process(string(field), value)
+	}
+	if err := p.scanner.Err(); err != nil {
+		return nil, err
+	}
+	// the pending event is discarded if the stream ends without a blank line
+	return nil, io.EOF
+}
+
+func (p *parser) process(field string, value []byte) {
+	switch field {
+	case "event":
+		p.eventType = string(value)
+	case "data":
+		if p.hasData {
+			p.data.WriteByte('\n')
+		}
+		p.data.Write(value)
+		p.hasData = true
+	case "id":
+		if bytes.IndexByte(value, 0) < 0 {
+			p.lastID = string(value)
+		}
+	case "retry":
+		if ms, err := strconv.ParseUint(string(value), 10, 63); err == nil && p.retry != nil {
+			p.retry(time.Duration(ms) * time.Millisecond)
+		}
+	}
+}
+
+func (p *parser) dispatch() *Event {
+	defer func() {
+		p.eventType = ""
+		p.data.Reset()
+		p.hasData = false
+	}()
+	if !p.hasData {
+		return nil
+	}
+	e := &Event{ID: p.lastID, Event: p.eventType, Data: p.data.String()}
+	if e.Event == "" {
+		e.Event = "message"
+	}
+	return e
+}
+
+// scanLines is a bufio.SplitFunc which splits lines by "\r\n", "\n" or "\r".
+func scanLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		if data[i] == '\n' {
+			return i + 1, data[:i], nil
+		}
+		if i+1 < len(data) {
+			if data[i+1] == '\n' {
+				return i + 2, data[:i], nil
+			}
+			return i + 1, data[:i], nil
+		}
+		// "\r" is the last byte, wait for more data to see if it is followed by "\n"
+		if atEOF {
+			return i + 1, data[:i], nil
+		}
+		return 0, nil, nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
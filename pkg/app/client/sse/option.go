@@ -0,0 +1,107 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sse
+
+import (
+	"time"
+
+	"hertz-study/pkg/protocol"
+)
+
+const (
+	defaultRetry    = 3 * time.Second
+	defaultMaxRetry = 30 * time.Second
+)
+
+// Options is the config of the sse Client.
+type Options struct {
+	// LastEventID is sent with the first request, it is updated by the received events.
+	LastEventID string
+	// Retry is the reconnection delay before the server sends a retry field, default is 3s.
+	Retry time.Duration
+	// MaxRetry is the upper limit of the delay when the reconnection keeps failing, default is 30s.
+	MaxRetry time.Duration
+	// MaxAttempts is the max count of consecutive failed connections, zero means unlimited.
+	MaxAttempts int
+	// RequestHook is called before every request is sent, e.g. to set the auth header.
+	RequestHook func(req *protocol.Request)
+	// BufferSize is the size of the channel returned by Client.Events.
+	BufferSize int
+}
+
+// Option is the only struct that can be used to set Options.
+type Option struct {
+	F func(o *Options)
+}
+
+func (o *Options) Apply(opts []Option) {
+	for _, op := range opts {
+		op.F(o)
+	}
+}
+
+// NewOptions creates a default Options with opts applied.
+func NewOptions(opts []Option) *Options {
+	options := &Options{
+		Retry:    defaultRetry,
+		MaxRetry: defaultMaxRetry,
+	}
+	options.Apply(opts)
+	return options
+}
+
+// WithLastEventID sets the Last-Event-ID of the first request, which is used to resume a stream.
+func WithLastEventID(id string) Option {
+	return Option{F: func(o *Options) {
+		o.LastEventID = id
+	}}
+}
+
+// WithRetry sets the reconnection delay used until the server sends a retry field.
+func WithRetry(d time.Duration) Option {
+	return Option{F: func(o *Options) {
+		o.Retry = d
+	}}
+}
+
+// WithMaxRetry sets the upper limit of the reconnection delay.
+func WithMaxRetry(d time.Duration) Option {
+	return Option{F: func(o *Options) {
+		o.MaxRetry = d
+	}}
+}
+
+// WithMaxAttempts sets the max count of consecutive failed connections before giving up.
+func WithMaxAttempts(n int) Option {
+	return Option{F: func(o *Options) {
+		o.MaxAttempts = n
+	}}
+}
+
+// WithRequestHook sets the hook called before every request is sent.
+func WithRequestHook(hook func(req *protocol.Request)) Option {
+	return Option{F: func(o *Options) {
+		o.RequestHook = hook
+	}}
+}
+
+// WithBufferSize sets the size of the channel returned by Client.Events.
+func WithBufferSize(n int) Option {
+	return Option{F: func(o *Options) {
+		o.BufferSize = n
+	}}
+}
@@ -0,0 +1,216 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sse
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"hertz-study/pkg/app/client"
+	"hertz-study/pkg/common/errors"
+	"hertz-study/pkg/common/hlog"
+	"hertz-study/pkg/protocol"
+	"hertz-study/pkg/protocol/consts"
+)
+
+// ErrMaxAttempts is returned when the reconnection fails MaxAttempts times in a row.
+var ErrMaxAttempts = errors.NewPublic("sse: max attempts exceeded")
+
+// Client consumes a server-sent events stream, it reconnects with the Last-Event-ID header
+// when the stream is broken, waiting for the retry delay sent by the server.
+//
+// The hertz client should be created with client.WithResponseBodyStream(true), otherwise
+// the events are only delivered after the whole response is read. Cancelling the context
+// stops delivering events immediately, but the connection is released only after the server
+// sends more data or closes the stream, set a read timeout on the hertz client to bound it.
+type Client struct {
+	c       *client.Client
+	url     string
+	options *Options
+
+	mu     sync.Mutex
+	lastID string
+	retry  time.Duration
+}
+
+// NewClient creates a sse Client which subscribes url with c.
+func NewClient(c *client.Client, url string, opts ...Option) *Client {
+	o := NewOptions(opts)
+	return &Client{
+		c:       c,
+		url:     url,
+		options: o,
+		lastID:  o.LastEventID,
+		retry:   o.Retry,
+	}
+}
+
+// LastEventID returns the id of the last received event.
+func (c *Client) LastEventID() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lastID
+}
+
+// Subscribe calls handler for every received event until ctx is done, the server
+// responds 204 No Content, or the reconnection fails MaxAttempts times in a row.
+// It returns nil when the server stops the stream with 204 No Content.
+func (c *Client) Subscribe(ctx context.Context, handler func(e *Event)) error {
+	events := make(chan *Event)
+	done := make(chan error, 1)
+	go func() {
+		done <- c.run(ctx, events)
+	}()
+	for {
+		select {
+		case e := <-events:
+			handler(e)
+		case err := <-done:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Events returns a channel delivering the received events, the channel is closed when
+// the subscription ends, the reason is sent to errCh then.
+func (c *Client) Events(ctx context.Context) (events <-chan *Event, errCh <-chan error) {
+	ch := make(chan *Event, c.options.BufferSize)
+	ec := make(chan error, 1)
+	go func() {
+		defer close(ch)
+		ec <- c.run(ctx, ch)
+	}()
+	return ch, ec
+}
+
+func (c *Client) run(ctx context.Context, events chan<- *Event) error {
+	failures := 0
+	for {
+		connected, stop, err := c.connect(ctx, events)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if stop {
+			return err
+		}
+		if connected {
+			failures = 0
+		} else {
+			failures++
+		}
+		if c.options.MaxAttempts > 0 && failures >= c.options.MaxAttempts {
+			return fmt.Errorf("%w: %v", ErrMaxAttempts, err)
+		}
+		hlog.SystemLogger().Debugf("sse stream of %s is broken, reconnect later: %v", c.url, err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(c.delay(failures)):
+		}
+	}
+}
+
+// delay doubles the retry delay for every consecutive failure.
+func (c *Client) delay(failures int) time.Duration {
+	c.mu.Lock()
+	d := c.retry
+	c.mu.Unlock()
+	for i := 1; i < failures && d < c.options.MaxRetry; i++ {
+		d *= 2
+	}
+	if d > c.options.MaxRetry && c.options.MaxRetry > 0 {
+		d = c.options.MaxRetry
+	}
+	return d
+}
+
+// connect reads the stream once, it returns whether the stream was established,
+// whether the subscription should stop and the reason why the stream ends.
+func (c *Client) connect(ctx context.Context, events chan<- *Event) (connected, stop bool, err error) {
+	req := protocol.AcquireRequest()
+	resp := protocol.AcquireResponse()
+	defer func() {
+		if ctx.Err() != nil {
+			// closing the body stream reads the rest of it, which may never end
+			go release(req, resp)
+			return
+		}
+		release(req, resp)
+	}()
+
+	req.SetRequestURI(c.url)
+	req.SetMethod(consts.MethodGet)
+	req.Header.Set(consts.HeaderAccept, "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+	if id := c.LastEventID(); id != "" {
+		req.Header.Set("Last-Event-ID", id)
+	}
+	if c.options.RequestHook != nil {
+		c.options.RequestHook(req)
+	}
+
+	if err = c.c.Do(ctx, req, resp); err != nil {
+		return false, false, err
+	}
+	switch code := resp.StatusCode(); {
+	case code == consts.StatusNoContent:
+		return false, true, nil
+	case code == consts.StatusTooManyRequests || code >= consts.StatusInternalServerError:
+		return false, false, fmt.Errorf("sse: unexpected status code %d", code)
+	case code != consts.StatusOK:
+		return false, true, fmt.Errorf("sse: unexpected status code %d", code)
+	}
+
+	var body io.Reader
+	if resp.IsBodyStream() {
+		body = resp.BodyStream()
+	} else {
+		body = bytes.NewReader(resp.Body())
+	}
+	p := newParser(body, c.LastEventID(), func(d time.Duration) {
+		c.mu.Lock()
+		c.retry = d
+		c.mu.Unlock()
+	})
+	for {
+		e, err := p.next()
+		if err != nil {
+			return true, false, err
+		}
+		c.mu.Lock()
+		c.lastID = e.ID
+		c.mu.Unlock()
+		select {
+		case events <- e:
+		case <-ctx.Done():
+			return true, true, ctx.Err()
+		}
+	}
+}
+
+func release(req *protocol.Request, resp *protocol.Response) {
+	resp.CloseBodyStream() //nolint:errcheck
+	protocol.ReleaseRequest(req)
+	protocol.ReleaseResponse(resp)
+}
@@ -42,14 +42,20 @@
 package app
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"net"
+	"net/http"
 	"net/url"
 	"os"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -130,6 +136,33 @@ func ClientIPWithOption(opts ClientIPOptions) ClientIP {
 	}
 }
 
+// ParseTrustedCIDRs parses the IPs and CIDRs to be used as ClientIPOptions.TrustedCIDRs,
+// an IP is treated as a single address range.
+func ParseTrustedCIDRs(proxies []string) ([]*net.IPNet, error) {
+	cidrs := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy: %q", proxy)
+			}
+			bits := net.IPv6len * 8
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, net.IPv4len*8
+			}
+			cidrs = append(cidrs, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, cidr, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy: %q", proxy)
+		}
+		cidrs = append(cidrs, cidr)
+	}
+	return cidrs, nil
+}
+
 // isTrustedProxy will check whether the IP address is included in the trusted list according to trustedCIDRs
 func isTrustedProxy(trustedCIDRs []*net.IPNet, remoteIP net.IP) bool {
 	if trustedCIDRs == nil {
@@ -168,6 +201,10 @@ func validateHeader(trustedCIDRs []*net.IPNet, header string) (clientIP string,
 
 var defaultClientIP = ClientIPWithOption(defaultClientIPOptions)
 
+var errNoHTMLRender = errors.NewPrivate("HTML render is not set, load the templates by LoadHTMLGlob or LoadHTMLFiles first")
+
+var errFlushNotSupported = errors.NewPrivate("flush is not supported by the protocol of the request")
+
 // SetClientIPFunc sets ClientIP function implementation to get ClientIP.
 // Deprecated: Use engine.SetClientIPFunc instead of SetClientIPFunc
 func SetClientIPFunc(fn ClientIP) {
@@ -204,6 +241,7 @@ type RequestContext struct {
 	Errors errors.ErrorChain
 
 	Params     param.Params
+	paramsBuf  []byte // holds the values of Params, reused across requests
 	handlers   HandlersChain
 	fullPath   string
 	index      int8
@@ -231,22 +269,71 @@ type RequestContext struct {
 	// clientIPFunc get client ip by use custom function.
 	clientIPFunc ClientIP
 
+	// trustedCIDRs are the proxies whose forwarding headers are honored, nil means none.
+	trustedCIDRs []*net.IPNet
+
 	// clientIPFunc get form value by use custom function.
 	formValueFunc FormValueFunc
 
+	// streamRenderBufferSize is the buffer size of RenderStream.
+	streamRenderBufferSize int
+
+	// writer wraps conn to write the response if it is not nil.
+	writer network.Writer
+	// flushWriterFunc creates the writer Flush hijacks the response with, it's set by the protocol server.
+	flushWriterFunc FlushWriterFunc
+
+	ioStats IOStats
+	// countingWriter counts the bytes written by the writer returned by GetWriter
+	countingWriter countingWriter
+
 	binder    binding.Binder
 	validator binding.StructValidator
+
+	panicReporter network.PanicReporter
 }
 
-// Flush is the shortcut for ctx.Response.GetHijackWriter().Flush().
-// Will return nil if the response writer is not hijacked.
+// FlushWriterFunc creates the writer that streams the response of ctx to w, which is chosen by the
+// protocol of the request, e.g. chunked encoding for HTTP/1.1.
+type FlushWriterFunc func(ctx *RequestContext, w network.Writer) network.ExtWriter
+
+// Flush writes the response header and the body written so far to the client.
+//
+// If the response writer is not hijacked, it is hijacked by the writer of the protocol on the
+// first call, e.g. a chunked body writer for HTTP/1.1, so the following ctx.Write calls are sent
+// progressively and the handler can stream the generated content, e.g. CSV exports. Use
+// ctx.SetBodyStream instead if the content is available as an io.Reader.
+// Will return nil if there is no writer to flush, e.g. in unit tests.
 func (ctx *RequestContext) Flush() error {
 	if ctx.Response.GetHijackWriter() == nil {
-		return nil
+		w := ctx.GetWriter()
+		if w == nil || ctx.Response.IsBodyStream() {
+			return nil
+		}
+		if ctx.flushWriterFunc == nil {
+			return errFlushNotSupported
+		}
+		hw := ctx.flushWriterFunc(ctx, w)
+		ctx.Response.HijackWriter(hw)
+		if body := ctx.Response.Body(); len(body) > 0 {
+			if _, err := hw.Write(body); err != nil {
+				return err
+			}
+		}
+		// the buffered body must be valid until flushed
+		defer ctx.Response.ResetBody()
 	}
 	return ctx.Response.GetHijackWriter().Flush()
 }
 
+// SetFlushWriterFunc sets the function creating the writer Flush hijacks the response with.
+// It is reset with the connection.
+//
+// NOTE: It is an internal function. You should not use it.
+func (ctx *RequestContext) SetFlushWriterFunc(f FlushWriterFunc) {
+	ctx.flushWriterFunc = f
+}
+
 func (ctx *RequestContext) SetClientIPFunc(f ClientIP) {
 	ctx.clientIPFunc = f
 }
@@ -263,6 +350,22 @@ func (ctx *RequestContext) SetValidator(validator binding.StructValidator) {
 	ctx.validator = validator
 }
 
+// SetPanicReporter sets the reporter of the panics recovered while handling the request,
+// which is set by the engine.
+func (ctx *RequestContext) SetPanicReporter(r network.PanicReporter) {
+	ctx.panicReporter = r
+}
+
+// PanicReporter returns the reporter set by SetPanicReporter, which is nil if not set.
+func (ctx *RequestContext) PanicReporter() network.PanicReporter {
+	return ctx.panicReporter
+}
+
+// IOStats returns the sizes and the phase timings of the request.
+func (ctx *RequestContext) IOStats() *IOStats {
+	return &ctx.ioStats
+}
+
 func (ctx *RequestContext) GetTraceInfo() traceinfo.TraceInfo {
 	return ctx.traceInfo
 }
@@ -320,8 +423,24 @@ func (ctx *RequestContext) GetReader() network.Reader {
 	return ctx.conn
 }
 
+// GetWriter returns the writer of the response, which is the connection unless set by SetWriter.
+// The bytes written by it are counted by IOStats.
 func (ctx *RequestContext) GetWriter() network.Writer {
-	return ctx.conn
+	w := ctx.writer
+	if w == nil {
+		if ctx.conn == nil {
+			return nil
+		}
+		w = ctx.conn
+	}
+	ctx.countingWriter.w, ctx.countingWriter.n = w, &ctx.ioStats.bytesWritten
+	return &ctx.countingWriter
+}
+
+// SetWriter sets the writer of the response wrapping the connection, e.g. to set the deadline of
+// every flush. It is reset with the connection.
+func (ctx *RequestContext) SetWriter(w network.Writer) {
+	ctx.writer = w
 }
 
 func (ctx *RequestContext) GetIndex() int8 {
@@ -480,10 +599,8 @@ func (ctx *RequestContext) GetResponse() (dst *protocol.Response) {
 //
 // In case the Key is reset after response, Value() return nil if ctx.Key is nil.
 func (ctx *RequestContext) Value(key interface{}) interface{} {
-	// this ctx has been reset, return nil.
-	if ctx.Keys == nil {
-		return nil
-	}
+	// Get returns nil if this ctx has been reset, Keys is not read without the lock
+	// since Set may initialize it concurrently.
 	if keyString, ok := key.(string); ok {
 		val, _ := ctx.Get(keyString)
 		return val
@@ -532,6 +649,28 @@ func (ctx *RequestContext) RemoteAddr() net.Addr {
 	return addr
 }
 
+// TLSConnectionState returns the state of the tls connection the request is received from,
+// ok is false if the connection is not a tls one.
+func (ctx *RequestContext) TLSConnectionState() (state tls.ConnectionState, ok bool) {
+	tlsConn, ok := ctx.conn.(network.ConnTLSer)
+	if !ok {
+		return state, false
+	}
+	return tlsConn.ConnectionState(), true
+}
+
+// PeerCertificates returns the certificates presented by the client, the first one is the
+// leaf certificate. It is empty if the client presents none or the connection is not a tls one.
+//
+// The certificates are verified only when the server requires it, see server.WithClientAuth.
+func (ctx *RequestContext) PeerCertificates() []*x509.Certificate {
+	state, ok := ctx.TLSConnectionState()
+	if !ok {
+		return nil
+	}
+	return state.PeerCertificates
+}
+
 // WriteString appends s to response body.
 func (ctx *RequestContext) WriteString(s string) (int, error) {
 	ctx.Response.AppendBodyString(s)
@@ -683,7 +822,15 @@ func (ctx *RequestContext) multipartFormValue(key string) (string, bool) {
 	return "", false
 }
 
+// RequestBodyStream returns the reader of the request body.
+//
+// If the server enables streaming request body by server.WithStreamBody, a chunked body or a
+// body larger than the max request body size is read incrementally off the wire (chunked
+// transfer encoding is decoded) instead of being buffered. Otherwise, it reads the buffered body.
 func (ctx *RequestContext) RequestBodyStream() io.Reader {
+	if !ctx.Request.IsBodyStream() {
+		return bytes.NewReader(ctx.Request.Body())
+	}
 	return ctx.Request.BodyStream()
 }
 
@@ -696,7 +843,11 @@ func (ctx *RequestContext) RequestBodyStream() io.Reader {
 // returning from RequestHandler. Either move or copy uploaded files
 // into new place if you want retaining them.
 //
-// Use SaveMultipartFile function for permanently saving uploaded file.
+// Use SaveUploadedFile function for permanently saving uploaded file.
+//
+// The form is parsed in streaming with the limits set by server.WithMultipartFormConfig,
+// errors.ErrFileTooLarge, errors.ErrTooManyFiles or errors.ErrBodyTooLarge is returned if
+// it exceeds any of them.
 //
 // The returned form is valid until returning from RequestHandler.
 //
@@ -777,6 +928,9 @@ func getRedirectStatusCode(statusCode int) int {
 //
 // NOTE: If you want to pass requestContext to a goroutine, call this method
 // to get a copy of requestContext.
+//
+// Keys of the copy is a new map, so Set on either side is not visible to the other,
+// but the values themselves are shared.
 func (ctx *RequestContext) Copy() *RequestContext {
 	cp := &RequestContext{
 		conn:   ctx.conn,
@@ -795,11 +949,16 @@ func (ctx *RequestContext) Copy() *RequestContext {
 	paramCopy := make([]param.Param, len(cp.Params))
 	copy(paramCopy, cp.Params)
 	cp.Params = paramCopy
+	// the values may reference paramsBuf of ctx, which is reused by the next request
+	cp.paramsBuf = copyParamValues(cp.Params, nil)
 	cp.fullPath = ctx.fullPath
 	cp.clientIPFunc = ctx.clientIPFunc
+	cp.trustedCIDRs = ctx.trustedCIDRs
 	cp.formValueFunc = ctx.formValueFunc
 	cp.binder = ctx.binder
 	cp.validator = ctx.validator
+	cp.panicReporter = ctx.panicReporter
+	cp.ioStats = ctx.ioStats
 	return cp
 }
 
@@ -841,6 +1000,7 @@ func (ctx *RequestContext) ResetWithoutConn() {
 	ctx.index = -1
 	ctx.fullPath = ""
 	ctx.Keys = nil
+	ctx.ioStats = IOStats{}
 
 	if ctx.finished != nil {
 		close(ctx.finished)
@@ -854,12 +1014,36 @@ func (ctx *RequestContext) ResetWithoutConn() {
 	}
 }
 
+// Release closes the body streams, removes the multipart form files and closes the channel
+// returned by Finished without resetting the other contents, it's used instead of Reset if the
+// context isn't recycled so that it stays valid for the handlers holding it.
+//
+// NOTE: It is an internal function. You should not use it.
+func (ctx *RequestContext) Release() {
+	ctx.Request.CloseBodyStream() //nolint:errcheck
+	ctx.Request.RemoveMultipartFormFiles()
+	ctx.Response.CloseBodyStream() //nolint:errcheck
+
+	ctx.finishedMu.Lock()
+	if ctx.finished == nil {
+		ctx.finished = make(chan struct{})
+	}
+	select {
+	case <-ctx.finished:
+	default:
+		close(ctx.finished)
+	}
+	ctx.finishedMu.Unlock()
+}
+
 // Reset resets requestContext.
 //
 // NOTE: It is an internal function. You should not use it.
 func (ctx *RequestContext) Reset() {
 	ctx.ResetWithoutConn()
 	ctx.conn = nil
+	ctx.writer = nil
+	ctx.flushWriterFunc = nil
 }
 
 // Redirect returns an HTTP redirect to the specific location.
@@ -1056,10 +1240,47 @@ func (ctx *RequestContext) GetStringMapStringSlice(key string) (smss map[string]
 //	    // a GET request to /user/john
 //	    id := ctx.Param("id") // id == "john"
 //	})
+//
+// If the server reuses the buffer of the param values with WithReuseParamValues, the value is
+// valid until the request ends, use strings.Clone or Copy to keep it.
 func (ctx *RequestContext) Param(key string) string {
 	return ctx.Params.ByName(key)
 }
 
+// CopyParamValues copies the values of Params, which reference the path of the request
+// when matched by the router, into one buffer. The buffer of ctx is reused across requests
+// if reuse is true, otherwise a new one owned by the values is allocated.
+//
+// NOTE: It is an internal function. You should not use it.
+func (ctx *RequestContext) CopyParamValues(reuse bool) {
+	if !reuse {
+		copyParamValues(ctx.Params, nil)
+		return
+	}
+	ctx.paramsBuf = copyParamValues(ctx.Params, ctx.paramsBuf[:0])
+}
+
+// copyParamValues copies the values of params into buf, which is grown at most once, and
+// returns it.
+func copyParamValues(params param.Params, buf []byte) []byte {
+	n := 0
+	for i := range params {
+		n += len(params[i].Value)
+	}
+	if n == 0 {
+		return buf
+	}
+	if cap(buf) < n {
+		buf = make([]byte, 0, n)
+	}
+	for i := range params {
+		start := len(buf)
+		buf = append(buf, params[i].Value...)
+		params[i].Value = bytesconv.B2s(buf[start:])
+	}
+	return buf
+}
+
 // Abort prevents pending handlers from being called.
 //
 // Note that this will not stop the current handler.
@@ -1137,15 +1358,45 @@ func (ctx *RequestContext) IndentedJSON(code int, obj interface{}) {
 	ctx.Render(code, render.IndentedJSON{Data: obj})
 }
 
+// ProblemJSON writes the problem details of RFC 7807 as application/problem+json with p.Status,
+// which is 500 if not set. The Title is the status text of p.Status if not set.
+func (ctx *RequestContext) ProblemJSON(p render.Problem) {
+	if p.Status == 0 {
+		p.Status = consts.StatusInternalServerError
+	}
+	if p.Title == "" {
+		p.Title = http.StatusText(p.Status)
+	}
+	ctx.Render(p.Status, render.ProblemJSON{Data: p})
+}
+
 // HTML renders the HTTP template specified by its file name.
 //
 // It also updates the HTTP code and sets the Content-Type as "text/html".
 // See http://golang.org/doc/articles/wiki/
 func (ctx *RequestContext) HTML(code int, name string, obj interface{}) {
+	if ctx.HTMLRender == nil {
+		panic(errNoHTMLRender)
+	}
 	instance := ctx.HTMLRender.Instance(name, obj)
 	ctx.Render(code, instance)
 }
 
+// HTMLWithLayout renders the HTTP template specified by its file name within the given layout,
+// the page is rendered without any layout if layout is empty.
+//
+// It also updates the HTTP code and sets the Content-Type as "text/html".
+func (ctx *RequestContext) HTMLWithLayout(code int, layout, name string, obj interface{}) {
+	if ctx.HTMLRender == nil {
+		panic(errNoHTMLRender)
+	}
+	r, ok := ctx.HTMLRender.(render.HTMLLayoutRender)
+	if !ok {
+		panic(fmt.Errorf("HTML render %T does not support layouts", ctx.HTMLRender))
+	}
+	ctx.Render(code, r.InstanceWithLayout(layout, name, obj))
+}
+
 // Data writes some data into the body stream and updates the HTTP code.
 func (ctx *RequestContext) Data(code int, contentType string, data []byte) {
 	ctx.Render(code, render.Data{
@@ -1161,6 +1412,35 @@ func (ctx *RequestContext) XML(code int, obj interface{}) {
 	ctx.Render(code, render.XML{Data: obj})
 }
 
+// JSONP serializes the given struct as JSON wrapped by the callback of the "callback" query
+// parameter into the response body, it is the same as JSON if the request has no valid callback.
+//
+// It also sets the Content-Type as "application/javascript".
+func (ctx *RequestContext) JSONP(code int, obj interface{}) {
+	ctx.Render(code, render.JSONP{Callback: ctx.Query("callback"), Data: obj})
+}
+
+// YAML serializes the given struct as YAML into the response body.
+//
+// It also sets the Content-Type as "application/yaml".
+func (ctx *RequestContext) YAML(code int, obj interface{}) {
+	ctx.Render(code, render.YAML{Data: obj})
+}
+
+// Form serializes the given struct, map or url.Values as URL-encoded form into the response body.
+//
+// It also sets the Content-Type as "application/x-www-form-urlencoded".
+func (ctx *RequestContext) Form(code int, obj interface{}) {
+	ctx.Render(code, render.Form{Data: obj})
+}
+
+// MsgPack serializes the given struct as MsgPack into the response body.
+//
+// It also sets the Content-Type as "application/msgpack".
+func (ctx *RequestContext) MsgPack(code int, obj interface{}) {
+	ctx.Render(code, render.MsgPack{Data: obj})
+}
+
 // AbortWithError calls `AbortWithStatus()` and `Error()` internally.
 //
 // This method stops the chain, writes the status code and pushes the specified error to `c.Errors`.
@@ -1395,9 +1675,10 @@ func (ctx *RequestContext) getValidator() binding.StructValidator {
 }
 
 // BindAndValidate binds data from *RequestContext to obj and validates them if needed.
+// The validation error is translated by the Accept-Language header if the validator supports.
 // NOTE: obj should be a pointer.
 func (ctx *RequestContext) BindAndValidate(obj interface{}) error {
-	return ctx.getBinder().BindAndValidate(&ctx.Request, obj, ctx.Params)
+	return ctx.translateValidateError(ctx.getBinder().BindAndValidate(&ctx.Request, obj, ctx.Params))
 }
 
 // Bind binds data from *RequestContext to obj.
@@ -1407,9 +1688,69 @@ func (ctx *RequestContext) Bind(obj interface{}) error {
 }
 
 // Validate validates obj with "vd" tag
+// The validation error is translated by the Accept-Language header if the validator supports.
 // NOTE: obj should be a pointer.
 func (ctx *RequestContext) Validate(obj interface{}) error {
-	return ctx.getValidator().ValidateStruct(obj)
+	return ctx.translateValidateError(ctx.getValidator().ValidateStruct(obj))
+}
+
+func (ctx *RequestContext) translateValidateError(err error) error {
+	if err == nil {
+		return nil
+	}
+	t, ok := ctx.getValidator().(binding.ValidateTranslator)
+	if !ok {
+		return err
+	}
+	var langs []string
+	for _, lang := range acceptValues(ctx.Request.Header.Get(consts.HeaderAcceptLanguage)) {
+		if lang != "*" {
+			langs = append(langs, lang)
+		}
+	}
+	if len(langs) == 0 {
+		return err
+	}
+	return t.TranslateError(err, langs)
+}
+
+// acceptValues returns the values of the Accept family headers ordered by the quality,
+// e.g. "zh-CN,zh;q=0.9,en;q=0.8" of Accept-Language returns [zh-CN zh en].
+// The values of zero quality are dropped.
+func acceptValues(header string) []string {
+	if header == "" {
+		return nil
+	}
+	type acceptValue struct {
+		value string
+		q     float64
+	}
+	var list []acceptValue
+	for _, part := range strings.Split(header, ",") {
+		value, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if value = strings.TrimSpace(value); value == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if f, err := strconv.ParseFloat(v, 64); err == nil {
+					q = f
+				}
+			}
+		}
+		if q > 0 {
+			list = append(list, acceptValue{value: value, q: q})
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].q > list[j].q
+	})
+	values := make([]string, len(list))
+	for i := range list {
+		values[i] = list[i].value
+	}
+	return values
 }
 
 // BindQuery binds query parameters from *RequestContext to obj with 'query' tag. It will only use 'query' tag for binding.
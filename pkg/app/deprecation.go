@@ -0,0 +1,39 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"net/http"
+	"time"
+
+	"hertz-study/internal/bytesconv"
+	"hertz-study/pkg/protocol/consts"
+)
+
+// Deprecated marks the response as served by a deprecated API with the Deprecation header of
+// draft-ietf-httpapi-deprecation-header, the Sunset header of RFC 8594 if sunset is not zero, and
+// a Link header of relation "deprecation" pointing to the migration guide if link is not empty.
+func (ctx *RequestContext) Deprecated(sunset time.Time, link string) {
+	h := &ctx.Response.Header
+	h.Set(consts.HeaderDeprecation, "true")
+	if !sunset.IsZero() {
+		h.Set(consts.HeaderSunset, string(bytesconv.AppendHTTPDate(make([]byte, 0, len(http.TimeFormat)), sunset)))
+	}
+	if link != "" {
+		h.Add(consts.HeaderLink, "<"+link+">; rel=\"deprecation\"; type=\"text/html\"")
+	}
+}
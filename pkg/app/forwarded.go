@@ -0,0 +1,122 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"net"
+	"strings"
+)
+
+const (
+	headerForwarded       = "Forwarded"
+	headerXForwardedProto = "X-Forwarded-Proto"
+	headerXForwardedHost  = "X-Forwarded-Host"
+)
+
+// SetTrustedCIDRs sets the proxies whose Forwarded and X-Forwarded-* headers are honored by
+// ExternalScheme and ExternalHost, no peer is trusted if cidrs is nil.
+func (ctx *RequestContext) SetTrustedCIDRs(cidrs []*net.IPNet) {
+	ctx.trustedCIDRs = cidrs
+}
+
+// ExternalScheme returns the scheme the client requests with, "https" or "http". If the peer is
+// a trusted proxy, the proto of RFC 7239 Forwarded header or the X-Forwarded-Proto header is
+// honored, so that it is "https" behind the TLS-terminating gateways.
+func (ctx *RequestContext) ExternalScheme() string {
+	if ctx.fromTrustedProxy() {
+		if proto := forwardedParam(ctx.Request.Header.Get(headerForwarded), "proto"); proto != "" {
+			if proto, ok := httpScheme(proto); ok {
+				return proto
+			}
+		} else if proto, ok := httpScheme(lastValue(ctx.Request.Header.Get(headerXForwardedProto))); ok {
+			return proto
+		}
+	}
+	if _, ok := ctx.TLSConnectionState(); ok {
+		return "https"
+	}
+	return "http"
+}
+
+// ExternalHost returns the host the client requests, the host of RFC 7239 Forwarded header or
+// the X-Forwarded-Host header is honored if the peer is a trusted proxy.
+func (ctx *RequestContext) ExternalHost() string {
+	if ctx.fromTrustedProxy() {
+		if host := forwardedParam(ctx.Request.Header.Get(headerForwarded), "host"); host != "" {
+			return host
+		}
+		if host := lastValue(ctx.Request.Header.Get(headerXForwardedHost)); host != "" {
+			return host
+		}
+	}
+	return string(ctx.Host())
+}
+
+// FullURL returns the URL the client requests with ExternalScheme and ExternalHost,
+// e.g. "https://example.com/users?page=2", which is useful to generate the absolute links.
+func (ctx *RequestContext) FullURL() string {
+	return ctx.ExternalScheme() + "://" + ctx.ExternalHost() + string(ctx.URI().RequestURI())
+}
+
+func (ctx *RequestContext) fromTrustedProxy() bool {
+	if ctx.trustedCIDRs == nil {
+		return false
+	}
+	host, _, err := net.SplitHostPort(ctx.RemoteAddr().String())
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	return isTrustedProxy(ctx.trustedCIDRs, ip)
+}
+
+// httpScheme returns proto in lower case if it is "http" or "https".
+func httpScheme(proto string) (string, bool) {
+	proto = strings.ToLower(proto)
+	return proto, proto == "http" || proto == "https"
+}
+
+// forwardedParam returns the param of the last element of the Forwarded header, which is
+// added by the trusted proxy, e.g. "for=192.0.2.60;proto=https;host=example.com". The former
+// elements are sent by the client or the other proxies, so they are ignored.
+func forwardedParam(header, name string) string {
+	if header == "" {
+		return ""
+	}
+	if i := strings.LastIndexByte(header, ','); i >= 0 {
+		header = header[i+1:]
+	}
+	for _, pair := range strings.Split(header, ";") {
+		k, v, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if ok && strings.EqualFold(k, name) {
+			return strings.Trim(v, "\"")
+		}
+	}
+	return ""
+}
+
+// lastValue returns the last value of the comma-separated header, which is appended by the
+// trusted proxy.
+func lastValue(header string) string {
+	if i := strings.LastIndexByte(header, ','); i >= 0 {
+		header = header[i+1:]
+	}
+	return strings.TrimSpace(header)
+}
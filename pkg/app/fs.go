@@ -44,16 +44,19 @@ package app
 import (
 	"bytes"
 	"compress/gzip"
+	"container/list"
 	"context"
 	"fmt"
-	"html"
+	"html/template"
 	"io"
+	"io/fs"
 	"io/ioutil"
-	"mime"
+	"mime/multipart"
 	"net/http"
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -62,15 +65,20 @@ import (
 	"hertz-study/internal/bytestr"
 	"hertz-study/internal/nocopy"
 	"hertz-study/pkg/common/bytebufferpool"
+	"hertz-study/pkg/common/cachecontrol"
 	"hertz-study/pkg/common/compress"
 	"hertz-study/pkg/common/errors"
 	"hertz-study/pkg/common/hlog"
+	"hertz-study/pkg/common/mimetype"
 	"hertz-study/pkg/common/utils"
 	"hertz-study/pkg/network"
 	"hertz-study/pkg/protocol"
 	"hertz-study/pkg/protocol/consts"
 )
 
+// maxByteRanges limits the ranges of a request to prevent the amplification.
+const maxByteRanges = 16
+
 var (
 	errDirIndexRequired   = errors.NewPublic("directory index required")
 	errNoCreatePermission = errors.NewPublic("no 'create file' permissions")
@@ -108,6 +116,13 @@ type FS struct {
 	// Path to the root directory to serve files from.
 	Root string
 
+	// The file system to serve files from instead of Root if set, e.g. an embed.FS.
+	//
+	// The files are read into memory when opened, so it is intended for the assets
+	// compiled into the binary. Compressed responses are generated in memory too,
+	// and the ETags are always generated from the content.
+	FileSystem fs.FS
+
 	// List of index file names to try opening during directory access.
 	//
 	// For example:
@@ -129,6 +144,20 @@ type FS struct {
 	// By default index pages aren't generated.
 	GenerateIndexPages bool
 
+	// Template to render the index pages generated by GenerateIndexPages, which
+	// is executed with a *DirIndex. The entries are sorted by the "sort" and
+	// "order" query arguments, and the DirIndex is responded in JSON instead if
+	// the request accepts application/json but not text/html.
+	//
+	// DefaultDirIndexTemplate is used by default.
+	DirIndexTemplate *template.Template
+
+	// Shows the hidden files, whose names start with '.', in the generated
+	// index pages.
+	//
+	// Hidden files aren't shown by default.
+	DirIndexShowHidden bool
+
 	// Transparently compresses responses if set to true.
 	//
 	// The server tries minimizing CPU usage by caching compressed files.
@@ -143,6 +172,9 @@ type FS struct {
 
 	// Enables byte range requests if set to true.
 	//
+	// Range requests are validated by If-Range, and multiple ranges are
+	// responded as multipart/byteranges, so the downloads are resumable.
+	//
 	// Byte range requests are disabled by default.
 	AcceptByteRange bool
 
@@ -159,6 +191,15 @@ type FS struct {
 	// "Cannot open requested path"
 	PathNotFound HandlerFunc
 
+	// Serves the nearest index file of the parent directories, see IndexNames,
+	// if the requested file is not found and the last path segment has no extension,
+	// so the client-side routes of a single page application are served by its
+	// index.html. The requests of missing assets, e.g. "/app.js", still fall to
+	// PathNotFound.
+	//
+	// SPA fallback is disabled by default.
+	SPAFallback bool
+
 	// Expiration duration for inactive file handlers.
 	//
 	// FSHandlerCacheDuration is used by default.
@@ -171,6 +212,48 @@ type FS struct {
 	// FSCompressedFileSuffix is used by default.
 	CompressedFileSuffix string
 
+	// Serves the precompressed sibling files if set to true, e.g. "app.js.br"
+	// or "app.js.gz" for "app.js", when the client accepts the encoding.
+	// Brotli is preferred over gzip, and the siblings older than the original
+	// file are ignored. The siblings take precedence over Compress.
+	//
+	// Precompressed siblings are not served by default.
+	Precompressed bool
+
+	// Max total size in bytes of the file contents cached in memory, the least
+	// recently used ones are evicted once exceeded. Only the files not larger
+	// than MemoryCacheMaxFileSize are cached.
+	//
+	// Memory cache is disabled by default.
+	MemoryCacheSize int
+
+	// Max size of the files cached in memory.
+	//
+	// FSMemoryCacheMaxFileSize is used by default.
+	MemoryCacheMaxFileSize int
+
+	// Generates the ETag from the content hash instead of the modification
+	// time and size if set to true, so the ETag changes whenever the content
+	// changes, e.g. the assets are rebuilt with the original modification time.
+	// The files are read once more when opened.
+	ContentETag bool
+
+	// Cache-Control header values by file extension, for example:
+	//
+	//     {".js": "public, max-age=31536000, immutable", "": "no-cache"}
+	//
+	// The value of key "" applies to the extensions not in the map.
+	//
+	// By default no Cache-Control header is set.
+	CacheControl map[string]string
+
+	// Registry detecting the content types of the files, e.g. to override
+	// the type of an extension or to serve the unknown files as
+	// application/octet-stream without sniffing by mimetype.ModeStrict.
+	//
+	// mimetype.Default is used by default.
+	MIMETypes *mimetype.Registry
+
 	once sync.Once
 	h    HandlerFunc
 }
@@ -183,6 +266,8 @@ type fsSmallFileReader struct {
 	ff       *fsFile
 	startPos int
 	endPos   int
+	// the content cached in memory, if any
+	content []byte
 }
 
 func (r *fsSmallFileReader) Close() error {
@@ -191,6 +276,7 @@ func (r *fsSmallFileReader) Close() error {
 	r.ff = nil
 	r.startPos = 0
 	r.endPos = 0
+	r.content = nil
 	ff.h.smallFileReaderPool.Put(r)
 	return nil
 }
@@ -211,6 +297,11 @@ func (r *fsSmallFileReader) Read(p []byte) (int, error) {
 	}
 
 	ff := r.ff
+	if r.content != nil {
+		n := copy(p, r.content[r.startPos:])
+		r.startPos += n
+		return n, nil
+	}
 	if ff.f != nil {
 		n, err := ff.f.ReadAt(p, int64(r.startPos))
 		r.startPos += n
@@ -227,6 +318,10 @@ func (r *fsSmallFileReader) WriteTo(w io.Writer) (int64, error) {
 
 	var n int
 	var err error
+	if r.content != nil {
+		n, err = w.Write(r.content[r.startPos:r.endPos])
+		return int64(n), err
+	}
 	if ff.f == nil {
 		n, err = w.Write(ff.dirIndex[r.startPos:r.endPos])
 		return int64(n), err
@@ -320,6 +415,11 @@ func (fs *FS) initRequestHandler() {
 		root = root[:len(root)-1]
 	}
 
+	// the paths are relative to the root of the file system
+	if fs.FileSystem != nil {
+		root = ""
+	}
+
 	cacheDuration := fs.CacheDuration
 	if cacheDuration <= 0 {
 		cacheDuration = consts.FSHandlerCacheDuration
@@ -328,19 +428,42 @@ func (fs *FS) initRequestHandler() {
 	if len(compressedFileSuffix) == 0 {
 		compressedFileSuffix = consts.FSCompressedFileSuffix
 	}
+	dirIndexTemplate := fs.DirIndexTemplate
+	if dirIndexTemplate == nil {
+		dirIndexTemplate = DefaultDirIndexTemplate
+	}
+	memCacheMaxFileSize := fs.MemoryCacheMaxFileSize
+	if memCacheMaxFileSize <= 0 {
+		memCacheMaxFileSize = consts.FSMemoryCacheMaxFileSize
+	}
+	mimeTypes := fs.MIMETypes
+	if mimeTypes == nil {
+		mimeTypes = mimetype.Default
+	}
 
 	h := &fsHandler{
 		root:                 root,
+		fsys:                 fs.FileSystem,
 		indexNames:           fs.IndexNames,
 		pathRewrite:          fs.PathRewrite,
 		generateIndexPages:   fs.GenerateIndexPages,
+		dirIndexTemplate:     dirIndexTemplate,
+		dirIndexShowHidden:   fs.DirIndexShowHidden,
 		compress:             fs.Compress,
 		pathNotFound:         fs.PathNotFound,
+		spaFallback:          fs.SPAFallback,
 		acceptByteRange:      fs.AcceptByteRange,
 		cacheDuration:        cacheDuration,
 		compressedFileSuffix: compressedFileSuffix,
+		precompressed:        fs.Precompressed,
+		contentETag:          fs.ContentETag,
+		cacheControl:         fs.CacheControl,
+		mimeTypes:            mimeTypes,
 		cache:                make(map[string]*fsFile),
 		compressedCache:      make(map[string]*fsFile),
+		siblingCache:         make(map[string]*fsFile),
+		siblingMisses:        make(map[string]time.Time),
+		memCache:             newFSMemCache(fs.MemoryCacheSize, memCacheMaxFileSize),
 	}
 
 	go func() {
@@ -356,18 +479,31 @@ func (fs *FS) initRequestHandler() {
 
 type fsHandler struct {
 	root                 string
+	fsys                 fs.FS
 	indexNames           []string
 	pathRewrite          PathRewriteFunc
 	pathNotFound         HandlerFunc
+	spaFallback          bool
 	generateIndexPages   bool
+	dirIndexTemplate     *template.Template
+	dirIndexShowHidden   bool
 	compress             bool
 	acceptByteRange      bool
 	cacheDuration        time.Duration
 	compressedFileSuffix string
+	precompressed        bool
+	contentETag          bool
+	cacheControl         map[string]string
+	mimeTypes            *mimetype.Registry
 
 	cache           map[string]*fsFile
 	compressedCache map[string]*fsFile
-	cacheLock       sync.Mutex
+	// precompressed siblings by encoding and path
+	siblingCache  map[string]*fsFile
+	siblingMisses map[string]time.Time
+	cacheLock     sync.Mutex
+
+	memCache *fsMemCache
 
 	smallFileReaderPool sync.Pool
 }
@@ -379,12 +515,15 @@ type bigFileReader struct {
 	ff *fsFile
 	r  io.Reader
 	lr io.LimitedReader
+	// start of the byte range
+	offset int64
 }
 
 func (r *bigFileReader) UpdateByteRange(startPos, endPos int) error {
 	if _, err := r.f.Seek(int64(startPos), 0); err != nil {
 		return err
 	}
+	r.offset = int64(startPos)
 	r.r = &r.lr
 	r.lr.R = r.f
 	r.lr.N = int64(endPos - startPos + 1)
@@ -405,8 +544,17 @@ func (r *bigFileReader) WriteTo(w io.Writer) (int64, error) {
 	return utils.CopyZeroAlloc(zw, r.r)
 }
 
+// FileSection implements network.FileSection, so that the file is sent with sendfile(2).
+func (r *bigFileReader) FileSection() (f *os.File, offset, count int64) {
+	if r.r == &r.lr {
+		return r.f, r.offset, r.lr.N
+	}
+	return r.f, 0, int64(r.ff.contentLength)
+}
+
 func (r *bigFileReader) Close() error {
 	r.r = r.f
+	r.offset = 0
 	n, err := r.f.Seek(0, 0)
 	if err == nil {
 		if n != 0 {
@@ -443,6 +591,13 @@ func (h *fsHandler) cleanCache(pendingFiles []*fsFile) []*fsFile {
 
 	pendingFiles, filesToRelease = cleanCacheNolock(h.cache, pendingFiles, filesToRelease, h.cacheDuration)
 	pendingFiles, filesToRelease = cleanCacheNolock(h.compressedCache, pendingFiles, filesToRelease, h.cacheDuration)
+	pendingFiles, filesToRelease = cleanCacheNolock(h.siblingCache, pendingFiles, filesToRelease, h.cacheDuration)
+	now := time.Now()
+	for k, t := range h.siblingMisses {
+		if now.Sub(t) > h.cacheDuration {
+			delete(h.siblingMisses, k)
+		}
+	}
 
 	h.cacheLock.Unlock()
 
@@ -549,6 +704,10 @@ func (h *fsHandler) compressFileNolock(f *os.File, fileInfo os.FileInfo, filePat
 }
 
 func (h *fsHandler) openFSFile(filePath string, mustCompress bool) (*fsFile, error) {
+	if h.fsys != nil {
+		return h.openMemFSFile(filePath, mustCompress)
+	}
+
 	filePathOriginal := filePath
 	if mustCompress {
 		filePath += h.compressedFileSuffix
@@ -605,15 +764,22 @@ func (h *fsHandler) newFSFile(f *os.File, fileInfo os.FileInfo, compressed bool)
 
 	// detect content-type
 	ext := fileExtension(fileInfo.Name(), compressed, h.compressedFileSuffix)
-	contentType := mime.TypeByExtension(ext)
+	contentType := h.mimeTypes.TypeByExtension(ext)
 	if len(contentType) == 0 {
-		data, err := readFileHeader(f, compressed)
-		if err != nil {
-			return nil, fmt.Errorf("cannot read header of the file %q: %s", f.Name(), err)
+		contentType = mimetype.DefaultType
+		if h.mimeTypes.Mode() == mimetype.ModeSniff {
+			data, err := readFileHeader(f, compressed)
+			if err != nil {
+				return nil, fmt.Errorf("cannot read header of the file %q: %s", f.Name(), err)
+			}
+			contentType = h.mimeTypes.Sniff(data)
 		}
-		contentType = http.DetectContentType(data)
 	}
+	return h.newFSFileWithType(f, fileInfo, compressed, contentType), nil
+}
 
+func (h *fsHandler) newFSFileWithType(f *os.File, fileInfo os.FileInfo, compressed bool, contentType string) *fsFile {
+	contentLength := int(fileInfo.Size())
 	lastModified := fileInfo.ModTime()
 	ff := &fsFile{
 		h:               h,
@@ -623,71 +789,231 @@ func (h *fsHandler) newFSFile(f *os.File, fileInfo os.FileInfo, compressed bool)
 		compressed:      compressed,
 		lastModified:    lastModified,
 		lastModifiedStr: bytesconv.AppendHTTPDate(make([]byte, 0, len(http.TimeFormat)), lastModified),
+		etag:            []byte(cachecontrol.ModTimeETag(lastModified, int64(contentLength))),
 
 		t: time.Now(),
 	}
-	return ff, nil
+	if h.contentETag {
+		if etag, err := cachecontrol.HashETagReader(io.NewSectionReader(f, 0, int64(contentLength))); err == nil {
+			ff.etag = []byte(etag)
+		} else {
+			hlog.SystemLogger().Errorf("Cannot hash file=%q for ETag, error=%s", f.Name(), err)
+		}
+	}
+	return ff
 }
 
-func (h *fsHandler) createDirIndex(base *protocol.URI, dirPath string, mustCompress bool) (*fsFile, error) {
-	w := &bytebufferpool.ByteBuffer{}
-
-	basePathEscaped := html.EscapeString(string(base.Path()))
-	fmt.Fprintf(w, "<html><head><title>%s</title><style>.dir { font-weight: bold }</style></head><body>", basePathEscaped)
-	fmt.Fprintf(w, "<h1>%s</h1>", basePathEscaped)
-	fmt.Fprintf(w, "<ul>")
+// fsName returns the name of filePath in fsHandler.fsys.
+func fsName(filePath string) string {
+	name := strings.Trim(filePath, "/")
+	if name == "" {
+		return "."
+	}
+	return name
+}
 
-	if len(basePathEscaped) > 1 {
-		var parentURI protocol.URI
-		base.CopyTo(&parentURI)
-		parentURI.Update(string(base.Path()) + "/..")
-		parentPathEscaped := html.EscapeString(string(parentURI.Path()))
-		fmt.Fprintf(w, `<li><a href="%s" class="dir">..</a></li>`, parentPathEscaped)
+// stat returns the FileInfo of filePath in fsHandler.fsys or the local filesystem.
+func (h *fsHandler) stat(filePath string) (os.FileInfo, error) {
+	if h.fsys != nil {
+		return fs.Stat(h.fsys, fsName(filePath))
 	}
+	return os.Stat(filePath)
+}
 
-	f, err := os.Open(dirPath)
+// readMemFSFile reads the content of filePath in fsHandler.fsys.
+func (h *fsHandler) readMemFSFile(filePath string) ([]byte, os.FileInfo, error) {
+	name := fsName(filePath)
+	fileInfo, err := fs.Stat(h.fsys, name)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	if fileInfo.IsDir() {
+		return nil, nil, errDirIndexRequired
+	}
+	content, err := fs.ReadFile(h.fsys, name)
+	if err != nil {
+		return nil, nil, err
 	}
+	return content, fileInfo, nil
+}
 
-	fileinfos, err := f.Readdir(0)
-	f.Close()
+// openMemFSFile opens filePath in fsHandler.fsys, the content is gzipped in memory
+// if mustCompress and it is compressible.
+func (h *fsHandler) openMemFSFile(filePath string, mustCompress bool) (*fsFile, error) {
+	content, fileInfo, err := h.readMemFSFile(filePath)
 	if err != nil {
 		return nil, err
 	}
 
-	fm := make(map[string]os.FileInfo, len(fileinfos))
-	filenames := make([]string, 0, len(fileinfos))
-	for _, fi := range fileinfos {
-		name := fi.Name()
-		if strings.HasSuffix(name, h.compressedFileSuffix) {
-			// Do not show compressed files on index page.
+	contentType := h.mimeTypes.Detect(filePath, content)
+	compressed := false
+	if mustCompress && len(content) <= consts.FsMaxCompressibleFileSize {
+		zcontent := compress.AppendGzipBytesLevel(nil, content, compress.CompressDefaultCompression)
+		if float64(len(zcontent)) < float64(len(content))*consts.FsMinCompressRatio {
+			content, compressed = zcontent, true
+		}
+	}
+	return h.newMemFSFile(content, fileInfo.ModTime(), compressed, contentType), nil
+}
+
+func (h *fsHandler) newMemFSFile(content []byte, lastModified time.Time, compressed bool, contentType string) *fsFile {
+	etag := []byte(cachecontrol.HashETag(content))
+	return &fsFile{
+		h:               h,
+		dirIndex:        content,
+		contentType:     contentType,
+		contentLength:   len(content),
+		compressed:      compressed,
+		lastModified:    lastModified,
+		lastModifiedStr: bytesconv.AppendHTTPDate(make([]byte, 0, len(http.TimeFormat)), lastModified),
+		etag:            etag,
+
+		t: time.Now(),
+	}
+}
+
+// siblingEncodings are the encodings of the precompressed siblings in the order of preference.
+var siblingEncodings = []struct {
+	encoding []byte
+	ext      string
+}{
+	{[]byte("br"), ".br"},
+	{bytestr.StrGzip, ".gz"},
+}
+
+// acceptedSibling returns the extension and the encoding of the precompressed sibling of path
+// accepted by the client, or "" if there is none.
+func (h *fsHandler) acceptedSibling(ctx *RequestContext, path string) (string, []byte) {
+	for _, se := range siblingEncodings {
+		if !ctx.Request.Header.HasAcceptEncodingBytes(se.encoding) {
+			continue
+		}
+		key := se.ext + ":" + path
+		h.cacheLock.Lock()
+		_, ok := h.siblingCache[key]
+		_, miss := h.siblingMisses[key]
+		h.cacheLock.Unlock()
+		if ok {
+			return se.ext, se.encoding
+		}
+		if miss {
 			continue
 		}
-		fm[name] = fi
-		filenames = append(filenames, name)
+
+		filePath := h.root + path
+		sibling, err := h.stat(filePath + se.ext)
+		if err == nil && sibling.Mode().IsRegular() {
+			if original, err := h.stat(filePath); err == nil && !sibling.ModTime().Before(original.ModTime()) {
+				return se.ext, se.encoding
+			}
+		}
+		h.cacheLock.Lock()
+		h.siblingMisses[key] = time.Now()
+		h.cacheLock.Unlock()
+	}
+	return "", nil
+}
+
+func (h *fsHandler) openSiblingFSFile(filePath, ext string, encoding []byte) (*fsFile, error) {
+	// the content type of the original file
+	contentType := h.mimeTypes.Detect(filePath, nil)
+
+	var ff *fsFile
+	if h.fsys != nil {
+		content, fileInfo, err := h.readMemFSFile(filePath + ext)
+		if err != nil {
+			return nil, err
+		}
+		ff = h.newMemFSFile(content, fileInfo.ModTime(), true, contentType)
+	} else {
+		f, err := os.Open(filePath + ext)
+		if err != nil {
+			return nil, err
+		}
+		fileInfo, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("cannot obtain info for file %q: %s", filePath+ext, err)
+		}
+		if int64(int(fileInfo.Size())) != fileInfo.Size() {
+			f.Close()
+			return nil, fmt.Errorf("too big file: %d bytes", fileInfo.Size())
+		}
+		ff = h.newFSFileWithType(f, fileInfo, true, contentType)
+	}
+	ff.contentEncoding = encoding
+	return ff, nil
+}
+
+// setCacheControl sets the Cache-Control header configured for the extension of path.
+func (h *fsHandler) setCacheControl(hdr *protocol.ResponseHeader, path []byte) {
+	if len(h.cacheControl) == 0 {
+		return
+	}
+	v, ok := h.cacheControl[filepath.Ext(string(path))]
+	if !ok {
+		v = h.cacheControl[""]
+	}
+	if v != "" {
+		hdr.Set(consts.HeaderCacheControl, v)
+	}
+}
+
+func (h *fsHandler) createDirIndex(ctx *RequestContext, dirPath string, mustCompress bool) (*fsFile, error) {
+	base := ctx.URI()
+	sortBy, desc := dirIndexParams(ctx)
+	index := &DirIndex{
+		Path:    string(base.Path()),
+		Sort:    sortBy,
+		Desc:    desc,
+		Entries: []DirEntry{},
+	}
+	if len(index.Path) > 1 {
+		var parentURI protocol.URI
+		base.CopyTo(&parentURI)
+		parentURI.Update(string(base.Path()) + "/..")
+		index.Parent = string(parentURI.Path())
+	}
+
+	fileinfos, err := h.readDir(dirPath)
+	if err != nil {
+		return nil, err
 	}
 
 	var u protocol.URI
 	base.CopyTo(&u)
 	u.Update(string(u.Path()) + "/")
 
-	sort.Strings(filenames)
-	for _, name := range filenames {
+	for _, fi := range fileinfos {
+		name := fi.Name()
+		if strings.HasSuffix(name, h.compressedFileSuffix) {
+			// Do not show compressed files on index page.
+			continue
+		}
+		if !h.dirIndexShowHidden && strings.HasPrefix(name, ".") {
+			continue
+		}
 		u.Update(name)
-		pathEscaped := html.EscapeString(string(u.Path()))
-		fi := fm[name]
-		auxStr := "dir"
-		className := "dir"
+		entry := DirEntry{
+			Name:  name,
+			URL:   string(u.Path()),
+			IsDir: fi.IsDir(),
+		}
 		if !fi.IsDir() {
-			auxStr = fmt.Sprintf("file, %d bytes", fi.Size())
-			className = "file"
+			entry.Size = fi.Size()
+		}
+		if !fi.ModTime().IsZero() {
+			entry.ModTime = fsModTime(fi.ModTime())
 		}
-		fmt.Fprintf(w, `<li><a href="%s" class="%s">%s</a>, %s, last modified %s</li>`,
-			pathEscaped, className, html.EscapeString(name), auxStr, fsModTime(fi.ModTime()))
+		index.Entries = append(index.Entries, entry)
 	}
+	sortDirEntries(index.Entries, sortBy, desc)
 
-	fmt.Fprintf(w, "</ul></body></html>")
+	w := &bytebufferpool.ByteBuffer{}
+	contentType, err := renderDirIndex(w, index, h.dirIndexTemplate, acceptsJSONDirIndex(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("cannot render index of directory %q: %s", dirPath, err)
+	}
 	if mustCompress {
 		var zbuf bytebufferpool.ByteBuffer
 		zbuf.B = compress.AppendGzipBytesLevel(zbuf.B, w.B, compress.CompressDefaultCompression)
@@ -699,17 +1025,43 @@ func (h *fsHandler) createDirIndex(base *protocol.URI, dirPath string, mustCompr
 	ff := &fsFile{
 		h:               h,
 		dirIndex:        dirIndex,
-		contentType:     "text/html; charset=utf-8",
+		contentType:     contentType,
 		contentLength:   len(dirIndex),
 		compressed:      mustCompress,
 		lastModified:    lastModified,
 		lastModifiedStr: bytesconv.AppendHTTPDate(make([]byte, 0, len(http.TimeFormat)), lastModified),
+		etag:            []byte(cachecontrol.ModTimeETag(lastModified, int64(len(dirIndex)))),
 
 		t: lastModified,
 	}
 	return ff, nil
 }
 
+func (h *fsHandler) readDir(dirPath string) ([]os.FileInfo, error) {
+	if h.fsys == nil {
+		f, err := os.Open(dirPath)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return f.Readdir(0)
+	}
+
+	entries, err := fs.ReadDir(h.fsys, fsName(dirPath))
+	if err != nil {
+		return nil, err
+	}
+	fileinfos := make([]os.FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		fi, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		fileinfos = append(fileinfos, fi)
+	}
+	return fileinfos, nil
+}
+
 func (h *fsHandler) openIndexFile(ctx *RequestContext, dirPath string, mustCompress bool) (*fsFile, error) {
 	for _, indexName := range h.indexNames {
 		indexFilePath := dirPath + "/" + indexName
@@ -726,7 +1078,24 @@ func (h *fsHandler) openIndexFile(ctx *RequestContext, dirPath string, mustCompr
 		return nil, fmt.Errorf("cannot access directory without index page. Directory %q", dirPath)
 	}
 
-	return h.createDirIndex(ctx.URI(), dirPath, mustCompress)
+	return h.createDirIndex(ctx, dirPath, mustCompress)
+}
+
+// openFallbackIndex opens the nearest index file of the parent directories of path for SPAFallback.
+func (h *fsHandler) openFallbackIndex(path string, mustCompress bool) (*fsFile, bool) {
+	dir := path
+	for {
+		n := strings.LastIndexByte(dir, '/')
+		if n < 0 {
+			return nil, false
+		}
+		dir = dir[:n]
+		for _, indexName := range h.indexNames {
+			if ff, err := h.openFSFile(h.root+dir+"/"+indexName, mustCompress); err == nil {
+				return ff, true
+			}
+		}
+	}
 }
 
 func (ff *fsFile) decReadersCount() {
@@ -769,7 +1138,7 @@ func (ff *fsFile) bigFileReader() (io.Reader, error) {
 }
 
 func (ff *fsFile) NewReader() (io.Reader, error) {
-	if ff.isBig() {
+	if ff.isBig() && !ff.h.memCache.fits(ff) {
 		r, err := ff.bigFileReader()
 		if err != nil {
 			ff.decReadersCount()
@@ -787,6 +1156,7 @@ func (ff *fsFile) smallFileReader() io.Reader {
 	r := v.(*fsSmallFileReader)
 	r.ff = ff
 	r.endPos = ff.contentLength
+	r.content = ff.h.memCache.get(ff)
 	if r.startPos > 0 {
 		panic("BUG: fsSmallFileReader with non-nil startPos found in the pool")
 	}
@@ -820,24 +1190,42 @@ func (h *fsHandler) handleRequest(c context.Context, ctx *RequestContext) {
 
 	mustCompress := false
 	fileCache := h.cache
+	cacheKey := string(path)
 	byteRange := ctx.Request.Header.PeekRange()
-	if len(byteRange) == 0 && h.compress && ctx.Request.Header.HasAcceptEncodingBytes(bytestr.StrGzip) {
+	var siblingExt string
+	var siblingEncoding []byte
+	if len(byteRange) == 0 && h.precompressed {
+		if siblingExt, siblingEncoding = h.acceptedSibling(ctx, cacheKey); siblingExt != "" {
+			fileCache = h.siblingCache
+			cacheKey = siblingExt + ":" + cacheKey
+		}
+	}
+	if len(byteRange) == 0 && siblingExt == "" && h.compress && ctx.Request.Header.HasAcceptEncodingBytes(bytestr.StrGzip) {
 		mustCompress = true
 		fileCache = h.compressedCache
 	}
 
+	if h.generateIndexPages {
+		// the generated index pages vary by the sort order and the format
+		cacheKey = dirIndexVariant(ctx) + cacheKey
+	}
+
 	h.cacheLock.Lock()
-	ff, ok := fileCache[string(path)]
+	ff, ok := fileCache[cacheKey]
 	if ok {
 		ff.readersCount++
 	}
 	h.cacheLock.Unlock()
 
 	if !ok {
-		pathStr := string(path)
-		filePath := h.root + pathStr
+		pathStr := cacheKey
+		filePath := h.root + string(path)
 		var err error
-		ff, err = h.openFSFile(filePath, mustCompress)
+		if siblingExt != "" {
+			ff, err = h.openSiblingFSFile(filePath, siblingExt, siblingEncoding)
+		} else {
+			ff, err = h.openFSFile(filePath, mustCompress)
+		}
 
 		if mustCompress && err == errNoCreatePermission {
 			hlog.SystemLogger().Errorf("Insufficient permissions for saving compressed file for path=%q. Serving uncompressed file. "+
@@ -845,6 +1233,11 @@ func (h *fsHandler) handleRequest(c context.Context, ctx *RequestContext) {
 			mustCompress = false
 			ff, err = h.openFSFile(filePath, mustCompress)
 		}
+		if h.spaFallback && os.IsNotExist(err) && filepath.Ext(string(path)) == "" {
+			if indexFF, ok := h.openFallbackIndex(string(path), mustCompress); ok {
+				ff, err = indexFF, nil
+			}
+		}
 		if err == errDirIndexRequired {
 			ff, err = h.openIndexFile(ctx, filePath, mustCompress)
 			if err != nil {
@@ -882,9 +1275,11 @@ func (h *fsHandler) handleRequest(c context.Context, ctx *RequestContext) {
 		}
 	}
 
-	if !ctx.IfModifiedSince(ff.lastModified) {
+	if !ff.checkPreconditions(ctx) {
 		ff.decReadersCount()
-		ctx.NotModified()
+		if ctx.Response.StatusCode() == consts.StatusNotModified {
+			h.setCacheControl(&ctx.Response.Header, path)
+		}
 		return
 	}
 
@@ -897,36 +1292,56 @@ func (h *fsHandler) handleRequest(c context.Context, ctx *RequestContext) {
 
 	hdr := &ctx.Response.Header
 	if ff.compressed {
-		hdr.SetContentEncodingBytes(bytestr.StrGzip)
+		if len(ff.contentEncoding) > 0 {
+			hdr.SetContentEncodingBytes(ff.contentEncoding)
+		} else {
+			hdr.SetContentEncodingBytes(bytestr.StrGzip)
+		}
+	}
+	if h.compress || h.precompressed {
+		hdr.Set(consts.HeaderVary, "Accept-Encoding")
 	}
 
 	statusCode := consts.StatusOK
 	contentLength := ff.contentLength
 	if h.acceptByteRange {
 		hdr.SetCanonical(bytestr.StrAcceptRanges, bytestr.StrBytes)
-		if len(byteRange) > 0 {
-			startPos, endPos, err := ParseByteRange(byteRange, contentLength)
+		if len(byteRange) > 0 && ff.ifRange(ctx) {
+			ranges, err := ParseByteRanges(byteRange, contentLength)
 			if err != nil {
 				r.(io.Closer).Close()
 				hlog.SystemLogger().Errorf("Cannot parse byte range %q for path=%q,error=%s", byteRange, path, err)
 				ctx.AbortWithMsg("Range Not Satisfiable", consts.StatusRequestedRangeNotSatisfiable)
+				hdr.Set(consts.HeaderContentRange, "bytes */"+strconv.Itoa(contentLength))
 				return
 			}
 
-			if err = r.(byteRangeUpdater).UpdateByteRange(startPos, endPos); err != nil {
-				r.(io.Closer).Close()
-				hlog.SystemLogger().Errorf("Cannot seek byte range %q for path=%q, error=%s", byteRange, path, err)
-				ctx.AbortWithMsg("Internal Server Error", consts.StatusInternalServerError)
-				return
+			if len(ranges) == 1 {
+				startPos, endPos := ranges[0][0], ranges[0][1]
+				if err = r.(byteRangeUpdater).UpdateByteRange(startPos, endPos); err != nil {
+					r.(io.Closer).Close()
+					hlog.SystemLogger().Errorf("Cannot seek byte range %q for path=%q, error=%s", byteRange, path, err)
+					ctx.AbortWithMsg("Internal Server Error", consts.StatusInternalServerError)
+					return
+				}
+
+				hdr.SetContentRange(startPos, endPos, contentLength)
+				contentLength = endPos - startPos + 1
+			} else {
+				var contentType string
+				r, contentLength, contentType = ff.newMultiRangeReader(r.(io.Closer), ranges)
+				hdr.SetContentType(contentType)
 			}
-
-			hdr.SetContentRange(startPos, endPos, contentLength)
-			contentLength = endPos - startPos + 1
 			statusCode = consts.StatusPartialContent
 		}
 	}
 
-	hdr.SetCanonical(bytestr.StrLastModified, ff.lastModifiedStr)
+	// the files of FS.FileSystem may have no modification time, e.g. embed.FS
+	if !ff.lastModified.IsZero() {
+		hdr.SetCanonical(bytestr.StrLastModified, ff.lastModifiedStr)
+	}
+	hdr.Set(consts.HeaderETag, string(ff.etag))
+	h.setCacheControl(hdr, path)
 	if !ctx.IsHead() {
 		ctx.SetBodyStream(r, contentLength)
 	} else {
@@ -949,15 +1364,25 @@ func (h *fsHandler) handleRequest(c context.Context, ctx *RequestContext) {
 }
 
 type fsFile struct {
-	h             *fsHandler
-	f             *os.File
+	h *fsHandler
+	f *os.File
+	// the content in memory, i.e. a directory index or a file of FS.FileSystem
 	dirIndex      []byte
 	contentType   string
 	contentLength int
 	compressed    bool
 
+	// the encoding of the precompressed sibling, gzip if empty
+	contentEncoding []byte
+
 	lastModified    time.Time
 	lastModifiedStr []byte
+	etag            []byte
+
+	// the content cached by fsMemCache
+	content  []byte
+	memElem  *list.Element
+	released bool
 
 	t            time.Time
 	readersCount int
@@ -966,7 +1391,176 @@ type fsFile struct {
 	bigFilesLock sync.Mutex
 }
 
+// checkPreconditions evaluates the conditional headers of the request following
+// RFC 7232 section 6, it responds 304 or 412 and returns false if any condition fails.
+func (ff *fsFile) checkPreconditions(ctx *RequestContext) bool {
+	if ifMatch := ctx.Request.Header.Peek(consts.HeaderIfMatch); len(ifMatch) > 0 {
+		if !cachecontrol.MatchETag(bytesconv.B2s(ifMatch), bytesconv.B2s(ff.etag), false) {
+			ctx.AbortWithMsg("Precondition Failed", consts.StatusPreconditionFailed)
+			return false
+		}
+	} else if ifUnmod := ctx.Request.Header.Peek(consts.HeaderIfUnmodifiedSince); len(ifUnmod) > 0 {
+		if t, err := bytesconv.ParseHTTPDate(ifUnmod); err == nil && t.Before(ff.lastModified.Truncate(time.Second)) {
+			ctx.AbortWithMsg("Precondition Failed", consts.StatusPreconditionFailed)
+			return false
+		}
+	}
+
+	// If-None-Match takes precedence over If-Modified-Since
+	if ifNoneMatch := ctx.Request.Header.Peek(consts.HeaderIfNoneMatch); len(ifNoneMatch) > 0 {
+		if !cachecontrol.MatchETag(bytesconv.B2s(ifNoneMatch), bytesconv.B2s(ff.etag), true) {
+			return true
+		}
+	} else if ff.lastModified.IsZero() || ctx.IfModifiedSince(ff.lastModified) {
+		return true
+	}
+	ctx.NotModified()
+	ctx.Response.Header.Set(consts.HeaderETag, string(ff.etag))
+	return false
+}
+
+// ifRange returns whether the Range header should be honored according to the If-Range header,
+// which must be the ETag or the exact Last-Modified date of the file.
+func (ff *fsFile) ifRange(ctx *RequestContext) bool {
+	ifRange := ctx.Request.Header.Peek(consts.HeaderIfRange)
+	if len(ifRange) == 0 {
+		return true
+	}
+	if ifRange[0] == '"' || bytes.HasPrefix(ifRange, []byte("W/")) {
+		// weak ETags can not be used for ranges
+		return bytes.Equal(ifRange, ff.etag)
+	}
+	t, err := bytesconv.ParseHTTPDate(ifRange)
+	return err == nil && t.Equal(ff.lastModified.Truncate(time.Second))
+}
+
+type multiRangeReader struct {
+	io.Reader
+	c io.Closer
+}
+
+func (r *multiRangeReader) Close() error {
+	return r.c.Close()
+}
+
+// newMultiRangeReader returns the multipart/byteranges body of the ranges, its length and content type.
+// The file reader is only used to be closed with the returned reader.
+func (ff *fsFile) newMultiRangeReader(c io.Closer, ranges [][2]int) (io.Reader, int, string) {
+	var ra io.ReaderAt = ff.f
+	if ff.f == nil {
+		ra = bytes.NewReader(ff.dirIndex)
+	}
+	boundary := multipart.NewWriter(ioutil.Discard).Boundary()
+
+	readers := make([]io.Reader, 0, 2*len(ranges)+1)
+	n := 0
+	for i, rg := range ranges {
+		var b []byte
+		if i > 0 {
+			b = append(b, bytestr.StrCRLF...)
+		}
+		b = append(b, "--"+boundary+"\r\n"...)
+		b = append(b, consts.HeaderContentType+": "+ff.contentType+"\r\n"...)
+		b = append(b, fmt.Sprintf("%s: bytes %d-%d/%d\r\n\r\n", consts.HeaderContentRange, rg[0], rg[1], ff.contentLength)...)
+		size := rg[1] - rg[0] + 1
+		readers = append(readers, bytes.NewReader(b), io.NewSectionReader(ra, int64(rg[0]), int64(size)))
+		n += len(b) + size
+	}
+	tail := "\r\n--" + boundary + "--\r\n"
+	readers = append(readers, strings.NewReader(tail))
+	n += len(tail)
+
+	return &multiRangeReader{Reader: io.MultiReader(readers...), c: c}, n, "multipart/byteranges; boundary=" + boundary
+}
+
+// fsMemCache is a LRU cache of the file contents.
+type fsMemCache struct {
+	mu          sync.Mutex
+	size        int
+	maxFileSize int
+	used        int
+	lru         *list.List
+}
+
+func newFSMemCache(size, maxFileSize int) *fsMemCache {
+	if size <= 0 {
+		return nil
+	}
+	return &fsMemCache{size: size, maxFileSize: maxFileSize, lru: list.New()}
+}
+
+// fits returns whether the content of ff can be cached.
+func (c *fsMemCache) fits(ff *fsFile) bool {
+	return c != nil && ff.f != nil && ff.contentLength <= c.maxFileSize && ff.contentLength <= c.size
+}
+
+// get returns the content of ff, which is read and cached if it is not cached yet.
+// It returns nil if the content can not be cached.
+func (c *fsMemCache) get(ff *fsFile) []byte {
+	if !c.fits(ff) {
+		return nil
+	}
+	c.mu.Lock()
+	if ff.content != nil {
+		c.lru.MoveToFront(ff.memElem)
+		content := ff.content
+		c.mu.Unlock()
+		return content
+	}
+	released := ff.released
+	c.mu.Unlock()
+	if released {
+		return nil
+	}
+
+	content := make([]byte, ff.contentLength)
+	if n, err := ff.f.ReadAt(content, 0); n != len(content) {
+		hlog.SystemLogger().Errorf("Cannot read file=%q into memory cache, error=%v", ff.f.Name(), err)
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ff.released {
+		return content
+	}
+	if ff.content != nil {
+		// cached by another reader
+		return ff.content
+	}
+	ff.content = content
+	ff.memElem = c.lru.PushFront(ff)
+	c.used += len(content)
+	for c.used > c.size {
+		last := c.lru.Back().Value.(*fsFile)
+		c.removeNolock(last)
+	}
+	return content
+}
+
+// remove removes ff from the cache and prevents it from being cached again.
+func (c *fsMemCache) remove(ff *fsFile) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	ff.released = true
+	c.removeNolock(ff)
+	c.mu.Unlock()
+}
+
+func (c *fsMemCache) removeNolock(ff *fsFile) {
+	if ff.memElem == nil {
+		return
+	}
+	c.lru.Remove(ff.memElem)
+	c.used -= len(ff.content)
+	ff.memElem = nil
+	ff.content = nil
+}
+
 func (ff *fsFile) Release() {
+	ff.h.memCache.remove(ff)
 	if ff.f != nil {
 		ff.f.Close()
 
@@ -1147,6 +1741,65 @@ func ParseByteRange(byteRange []byte, contentLength int) (startPos, endPos int,
 	return startPos, endPos, nil
 }
 
+// ParseByteRanges parses 'Range: bytes=...' header value with one or more comma-separated ranges.
+//
+// Every range must be satisfiable, see ParseByteRange. The ranges are sorted, and the overlapping
+// and adjacent ones are merged. An error is returned if the total length of the ranges exceeds
+// contentLength, so that a request can't pull back the content many times.
+func ParseByteRanges(byteRange []byte, contentLength int) ([][2]int, error) {
+	if !bytes.HasPrefix(byteRange, bytestr.StrBytes) {
+		return nil, fmt.Errorf("unsupported range units: %q. Expecting %q", byteRange, bytestr.StrBytes)
+	}
+	b := byteRange[len(bytestr.StrBytes):]
+	if len(b) == 0 || b[0] != '=' {
+		return nil, fmt.Errorf("missing byte range in %q", byteRange)
+	}
+
+	specs := bytes.Split(b[1:], []byte(","))
+	if len(specs) > maxByteRanges {
+		return nil, fmt.Errorf("too many byte ranges in %q, at most %d", byteRange, maxByteRanges)
+	}
+	ranges := make([][2]int, 0, len(specs))
+	spec := append([]byte(nil), bytestr.StrBytes...)
+	spec = append(spec, '=')
+	total := 0
+	for _, s := range specs {
+		startPos, endPos, err := ParseByteRange(append(spec, bytes.TrimSpace(s)...), contentLength)
+		if err != nil {
+			return nil, err
+		}
+		total += endPos - startPos + 1
+		if total > contentLength {
+			return nil, fmt.Errorf("the total length of byte ranges exceeds the content length %d. byte range %q", contentLength, byteRange)
+		}
+		ranges = append(ranges, [2]int{startPos, endPos})
+	}
+	return coalesceByteRanges(ranges), nil
+}
+
+// coalesceByteRanges sorts ranges by the start position and merges the overlapping and
+// adjacent ones in place.
+func coalesceByteRanges(ranges [][2]int) [][2]int {
+	if len(ranges) < 2 {
+		return ranges
+	}
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i][0] < ranges[j][0]
+	})
+	merged := ranges[:1]
+	for _, r := range ranges[1:] {
+		last := &merged[len(merged)-1]
+		if r[0] <= last[1]+1 {
+			if r[1] > last[1] {
+				last[1] = r[1]
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
 // NewVHostPathRewriter returns path rewriter, which strips slashesCount
 // leading slashes from the path and prepends the path with request's host,
 // thus simplifying virtual hosting for static files.
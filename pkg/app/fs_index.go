@@ -0,0 +1,141 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"bytes"
+	"html/template"
+	"io"
+	"sort"
+	"time"
+
+	"hertz-study/pkg/common/json"
+	"hertz-study/pkg/protocol/consts"
+)
+
+// DefaultDirIndexTemplate is the default template of the directory index pages, see FS.DirIndexTemplate.
+var DefaultDirIndexTemplate = template.Must(template.New("dirIndex").Parse(`<html><head><title>{{.Path}}</title><style>.dir { font-weight: bold }</style></head><body>
+<h1>{{.Path}}</h1>
+<p>Sort by <a href="?sort=name">name</a> | <a href="?sort=size">size</a> | <a href="?sort=mtime">last modified</a> | <a href="?sort={{.Sort}}&order={{if .Desc}}asc{{else}}desc{{end}}">reverse</a></p>
+<ul>
+{{- if .Parent}}<li><a href="{{.Parent}}" class="dir">..</a></li>{{end}}
+{{- range .Entries}}
+<li><a href="{{.URL}}" class="{{if .IsDir}}dir{{else}}file{{end}}">{{.Name}}</a>, {{if .IsDir}}dir{{else}}file, {{.Size}} bytes{{end}}{{if not .ModTime.IsZero}}, last modified {{.ModTime.Format "2006-01-02 15:04:05 MST"}}{{end}}</li>
+{{- end}}
+</ul></body></html>`))
+
+const (
+	dirIndexSortName  = "name"
+	dirIndexSortSize  = "size"
+	dirIndexSortMTime = "mtime"
+)
+
+// DirIndex is the directory index page generated by FS.GenerateIndexPages, which is rendered
+// by FS.DirIndexTemplate or responded in JSON.
+type DirIndex struct {
+	// Path is the request path of the directory.
+	Path string `json:"path"`
+	// Parent is the request path of the parent directory, empty for the root.
+	Parent string `json:"parent,omitempty"`
+	// Sort is the key the entries are sorted by, i.e. "name", "size" or "mtime",
+	// which is given by the "sort" query argument, default is "name".
+	Sort string `json:"sort"`
+	// Desc is true if the entries are sorted in descending order, i.e. the "order"
+	// query argument is "desc".
+	Desc    bool       `json:"desc"`
+	Entries []DirEntry `json:"entries"`
+}
+
+// DirEntry is a file or a subdirectory of DirIndex.
+type DirEntry struct {
+	Name  string `json:"name"`
+	URL   string `json:"url"`
+	IsDir bool   `json:"is_dir"`
+	// Size is 0 for the directories.
+	Size int64 `json:"size"`
+	// ModTime is in UTC and truncated to seconds, it may be zero for FS.FileSystem.
+	ModTime time.Time `json:"mod_time"`
+}
+
+// dirIndexParams returns the sort key and order of the directory index requested by ctx.
+// Unknown values fall back to the defaults, which bounds the variants to be cached.
+func dirIndexParams(ctx *RequestContext) (sortBy string, desc bool) {
+	switch s := string(ctx.QueryArgs().Peek("sort")); s {
+	case dirIndexSortSize, dirIndexSortMTime:
+		sortBy = s
+	default:
+		sortBy = dirIndexSortName
+	}
+	return sortBy, string(ctx.QueryArgs().Peek("order")) == "desc"
+}
+
+// acceptsJSONDirIndex reports whether the directory index should be responded in JSON,
+// i.e. the request accepts application/json but not text/html.
+func acceptsJSONDirIndex(ctx *RequestContext) bool {
+	accept := ctx.Request.Header.Peek(consts.HeaderAccept)
+	return bytes.Contains(accept, []byte(consts.MIMEApplicationJSON)) && !bytes.Contains(accept, []byte(consts.MIMETextHtml))
+}
+
+// dirIndexVariant returns the prefix of the cache key of the directory index variant
+// requested by ctx, or "" for the default one.
+func dirIndexVariant(ctx *RequestContext) string {
+	sortBy, desc := dirIndexParams(ctx)
+	var variant string
+	if acceptsJSONDirIndex(ctx) {
+		variant = "json;"
+	}
+	if sortBy != dirIndexSortName {
+		variant += sortBy + ";"
+	}
+	if desc {
+		variant += "desc;"
+	}
+	return variant
+}
+
+func sortDirEntries(entries []DirEntry, sortBy string, desc bool) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := &entries[i], &entries[j]
+		if desc {
+			a, b = b, a
+		}
+		switch sortBy {
+		case dirIndexSortSize:
+			if a.Size != b.Size {
+				return a.Size < b.Size
+			}
+		case dirIndexSortMTime:
+			if !a.ModTime.Equal(b.ModTime) {
+				return a.ModTime.Before(b.ModTime)
+			}
+		}
+		return a.Name < b.Name
+	})
+}
+
+// renderDirIndex writes index to w in JSON or by tmpl, and returns the content type.
+func renderDirIndex(w io.Writer, index *DirIndex, tmpl *template.Template, asJSON bool) (string, error) {
+	if asJSON {
+		b, err := json.Marshal(index)
+		if err != nil {
+			return "", err
+		}
+		_, err = w.Write(b)
+		return consts.MIMEApplicationJSONUTF8, err
+	}
+	return "text/html; charset=utf-8", tmpl.Execute(w, index)
+}
@@ -0,0 +1,262 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package health
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"hertz-study/pkg/app"
+	"hertz-study/pkg/common/hlog"
+	"hertz-study/pkg/protocol/consts"
+	"hertz-study/pkg/route"
+)
+
+// The statuses of the reports and the check results.
+const (
+	StatusOK           = "ok"
+	StatusFail         = "fail"
+	StatusShuttingDown = "shutting_down"
+)
+
+// Checker checks whether a dependency or a component is healthy, it returns nil if healthy.
+type Checker func(ctx context.Context) error
+
+// CheckResult is the result of a single check.
+type CheckResult struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	// LatencyMs is the time spent by the check in milliseconds.
+	LatencyMs float64 `json:"latency_ms"`
+	// Cached is true if the result is returned from the cache.
+	Cached bool `json:"cached,omitempty"`
+}
+
+// Report is the aggregated result of the checks.
+type Report struct {
+	Status string        `json:"status"`
+	Checks []CheckResult `json:"checks,omitempty"`
+}
+
+type check struct {
+	name    string
+	checker Checker
+	opts    checkOptions
+
+	mu        sync.Mutex
+	last      CheckResult
+	checkedAt time.Time
+}
+
+// Health manages the liveness and readiness checks of a server.
+type Health struct {
+	opts *options
+
+	mu        sync.RWMutex
+	liveness  []*check
+	readiness []*check
+
+	notReady     int32
+	shuttingDown int32
+}
+
+// New creates a Health without any check, the server is considered alive and ready.
+func New(opts ...Option) *Health {
+	return &Health{opts: newOptions(opts...)}
+}
+
+// AddLivenessCheck adds a check which indicates whether the process should be restarted.
+func (h *Health) AddLivenessCheck(name string, checker Checker, opts ...CheckOption) {
+	c := h.newCheck(name, checker, opts)
+	h.mu.Lock()
+	h.liveness = append(h.liveness, c)
+	h.mu.Unlock()
+}
+
+// AddReadinessCheck adds a check which indicates whether the server can accept traffic.
+func (h *Health) AddReadinessCheck(name string, checker Checker, opts ...CheckOption) {
+	c := h.newCheck(name, checker, opts)
+	h.mu.Lock()
+	h.readiness = append(h.readiness, c)
+	h.mu.Unlock()
+}
+
+// SetReady sets whether the server is ready regardless of the readiness checks,
+// e.g. it can be set to false before the warmup finishes.
+func (h *Health) SetReady(ready bool) {
+	if ready {
+		atomic.StoreInt32(&h.notReady, 0)
+	} else {
+		atomic.StoreInt32(&h.notReady, 1)
+	}
+}
+
+// Liveness runs the liveness checks.
+func (h *Health) Liveness(ctx context.Context) *Report {
+	h.mu.RLock()
+	checks := h.liveness
+	h.mu.RUnlock()
+	return runChecks(ctx, checks)
+}
+
+// Readiness runs the readiness checks, it reports StatusShuttingDown during graceful shutdown.
+func (h *Health) Readiness(ctx context.Context) *Report {
+	if atomic.LoadInt32(&h.shuttingDown) == 1 {
+		return &Report{Status: StatusShuttingDown}
+	}
+	h.mu.RLock()
+	checks := h.readiness
+	h.mu.RUnlock()
+	r := runChecks(ctx, checks)
+	if atomic.LoadInt32(&h.notReady) == 1 {
+		r.Status = StatusFail
+	}
+	return r
+}
+
+// LivenessHandler returns the handler responding the liveness report,
+// the status code is 200 if alive, otherwise 503.
+func (h *Health) LivenessHandler() app.HandlerFunc {
+	return func(c context.Context, ctx *app.RequestContext) {
+		writeReport(ctx, h.Liveness(c))
+	}
+}
+
+// ReadinessHandler returns the handler responding the readiness report,
+// the status code is 200 if ready, otherwise 503.
+func (h *Health) ReadinessHandler() app.HandlerFunc {
+	return func(c context.Context, ctx *app.RequestContext) {
+		writeReport(ctx, h.Readiness(c))
+	}
+}
+
+// Register mounts the handlers on the engine and flips readiness to "not ready" when
+// the engine starts graceful shutdown.
+func (h *Health) Register(engine *route.Engine) {
+	engine.GET(h.opts.livenessPath, h.LivenessHandler())
+	engine.HEAD(h.opts.livenessPath, h.LivenessHandler())
+	engine.GET(h.opts.readinessPath, h.ReadinessHandler())
+	engine.HEAD(h.opts.readinessPath, h.ReadinessHandler())
+	engine.OnShutdown = append(engine.OnShutdown, h.shutdown)
+}
+
+func (h *Health) shutdown(ctx context.Context) {
+	atomic.StoreInt32(&h.shuttingDown, 1)
+	if h.opts.shutdownDelay <= 0 {
+		return
+	}
+	hlog.SystemLogger().Infof("Readiness is set to not ready, wait %s for draining", h.opts.shutdownDelay)
+	select {
+	case <-ctx.Done():
+	case <-time.After(h.opts.shutdownDelay):
+	}
+}
+
+func (h *Health) newCheck(name string, checker Checker, opts []CheckOption) *check {
+	c := &check{name: name, checker: checker, opts: checkOptions{timeout: h.opts.timeout}}
+	for _, opt := range opts {
+		opt(&c.opts)
+	}
+	return c
+}
+
+func runChecks(ctx context.Context, checks []*check) *Report {
+	r := &Report{Status: StatusOK, Checks: make([]CheckResult, len(checks))}
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c *check) {
+			defer wg.Done()
+			r.Checks[i] = c.run(ctx)
+		}(i, c)
+	}
+	wg.Wait()
+
+	for _, res := range r.Checks {
+		if res.Status != StatusOK {
+			r.Status = StatusFail
+			break
+		}
+	}
+	sort.Slice(r.Checks, func(i, j int) bool {
+		return r.Checks[i].Name < r.Checks[j].Name
+	})
+	return r
+}
+
+// run runs the check, the concurrent runs of the same check share the cached result.
+func (c *check) run(ctx context.Context) CheckResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.opts.cacheTTL > 0 && !c.checkedAt.IsZero() && time.Since(c.checkedAt) < c.opts.cacheTTL {
+		res := c.last
+		res.Cached = true
+		return res
+	}
+
+	if c.opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.opts.timeout)
+		defer cancel()
+	}
+	start := time.Now()
+	err := c.safeCheck(ctx)
+	res := CheckResult{
+		Name:      c.name,
+		Status:    StatusOK,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		res.Status = StatusFail
+		res.Error = err.Error()
+	}
+	c.last, c.checkedAt = res, time.Now()
+	return res
+}
+
+// safeCheck returns when the checker returns or ctx is done, even if the checker ignores ctx.
+func (c *check) safeCheck(ctx context.Context) error {
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		done <- c.checker(ctx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func writeReport(ctx *app.RequestContext, r *Report) {
+	code := consts.StatusOK
+	if r.Status != StatusOK {
+		code = consts.StatusServiceUnavailable
+	}
+	ctx.Response.Header.Set("Cache-Control", "no-store")
+	ctx.JSON(code, r)
+}
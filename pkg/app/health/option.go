@@ -0,0 +1,107 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package health
+
+import (
+	"time"
+)
+
+const (
+	defaultLivenessPath  = "/livez"
+	defaultReadinessPath = "/readyz"
+	defaultCheckTimeout  = 5 * time.Second
+)
+
+type (
+	options struct {
+		livenessPath  string
+		readinessPath string
+		timeout       time.Duration
+		shutdownDelay time.Duration
+	}
+
+	Option func(o *options)
+)
+
+func newOptions(opts ...Option) *options {
+	cfg := &options{
+		livenessPath:  defaultLivenessPath,
+		readinessPath: defaultReadinessPath,
+		timeout:       defaultCheckTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return cfg
+}
+
+// WithLivenessPath sets the path of liveness handler, default is "/livez".
+func WithLivenessPath(path string) Option {
+	return func(o *options) {
+		o.livenessPath = path
+	}
+}
+
+// WithReadinessPath sets the path of readiness handler, default is "/readyz".
+func WithReadinessPath(path string) Option {
+	return func(o *options) {
+		o.readinessPath = path
+	}
+}
+
+// WithTimeout sets the default timeout of every check, default is 5s.
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.timeout = timeout
+	}
+}
+
+// WithShutdownDelay sets how long the server keeps serving after readiness flips to
+// "not ready" on shutdown, so that the load balancers have time to observe it and
+// drain the traffic before the listener is closed. It should be shorter than the
+// exit wait timeout of the server.
+func WithShutdownDelay(d time.Duration) Option {
+	return func(o *options) {
+		o.shutdownDelay = d
+	}
+}
+
+type (
+	checkOptions struct {
+		timeout  time.Duration
+		cacheTTL time.Duration
+	}
+
+	CheckOption func(o *checkOptions)
+)
+
+// WithCheckTimeout sets the timeout of the check, overriding the default one.
+func WithCheckTimeout(timeout time.Duration) CheckOption {
+	return func(o *checkOptions) {
+		o.timeout = timeout
+	}
+}
+
+// WithCacheTTL caches the result of the check for ttl, which is useful for expensive
+// checks, e.g. querying a database, since the probes may be frequent.
+func WithCacheTTL(ttl time.Duration) CheckOption {
+	return func(o *checkOptions) {
+		o.cacheTTL = ttl
+	}
+}
@@ -0,0 +1,120 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"os"
+	"time"
+
+	errs "hertz-study/pkg/common/errors"
+	"hertz-study/pkg/network"
+)
+
+// IOStats is the accounting of the bytes and the phases of a request, it is filled by the server
+// as the request is served and reset with the context, see RequestContext.IOStats.
+//
+// The sizes are always recorded, and they are counted rather than taken from Content-Length, so
+// that they are accurate for the chunked and streamed bodies. The timings are only recorded if
+// trace is enabled, e.g. by server.WithTracer, to save reading the clock on every request.
+type IOStats struct {
+	// HeaderBytesRead is the size of the request line and the header.
+	HeaderBytesRead int
+	// BodyBytesRead is the size of the request body, which is the decoded size of a chunked body.
+	// A streamed body is counted as it is read, and the unread rest is counted when it is
+	// discarded after the response is written.
+	BodyBytesRead int
+
+	// ReadStart is when the server starts reading the request.
+	ReadStart time.Time
+	// ReadEnd is when the request is read, the streamed body may be read by the handlers later.
+	ReadEnd time.Time
+	// HandleEnd is when the handlers return.
+	HandleEnd time.Time
+	// WriteEnd is when the response is flushed to the connection.
+	WriteEnd time.Time
+
+	bytesWritten int
+}
+
+// BytesRead returns the size of the request read, including the header.
+func (s *IOStats) BytesRead() int {
+	return s.HeaderBytesRead + s.BodyBytesRead
+}
+
+// BytesWritten returns the size of the response written to the connection so far, including the
+// status line, the header, which is ctx.Response.Header.GetHeaderLength(), and the chunk framing.
+// The response is written after the handlers return unless it is flushed by the handlers.
+func (s *IOStats) BytesWritten() int {
+	return s.bytesWritten
+}
+
+// ReadDuration returns the time reading the request, 0 if it isn't finished.
+func (s *IOStats) ReadDuration() time.Duration {
+	return since(s.ReadStart, s.ReadEnd)
+}
+
+// HandleDuration returns the time of the handlers, 0 if they haven't returned.
+func (s *IOStats) HandleDuration() time.Duration {
+	return since(s.ReadEnd, s.HandleEnd)
+}
+
+// WriteDuration returns the time writing the response, 0 if it isn't finished.
+func (s *IOStats) WriteDuration() time.Duration {
+	return since(s.HandleEnd, s.WriteEnd)
+}
+
+func since(start, end time.Time) time.Duration {
+	if start.IsZero() || end.IsZero() {
+		return 0
+	}
+	return end.Sub(start)
+}
+
+// countingWriter counts the bytes written to the response by w.
+type countingWriter struct {
+	w network.Writer
+	n *int
+}
+
+func (c *countingWriter) Malloc(n int) ([]byte, error) {
+	buf, err := c.w.Malloc(n)
+	if err == nil {
+		*c.n += n
+	}
+	return buf, err
+}
+
+func (c *countingWriter) WriteBinary(b []byte) (int, error) {
+	n, err := c.w.WriteBinary(b)
+	*c.n += n
+	return n, err
+}
+
+func (c *countingWriter) Flush() error {
+	return c.w.Flush()
+}
+
+// SendFile implements network.FileSender if w does.
+func (c *countingWriter) SendFile(f *os.File, offset, count int64) (int64, error) {
+	fs, ok := c.w.(network.FileSender)
+	if !ok {
+		return 0, errs.ErrSendFileNotSupport
+	}
+	n, err := fs.SendFile(f, offset, count)
+	*c.n += int(n)
+	return n, err
+}
@@ -0,0 +1,83 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package canary
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bytedance/gopkg/lang/fastrand"
+	"github.com/bytedance/gopkg/util/xxhash3"
+	"hertz-study/pkg/app/client"
+	"hertz-study/pkg/protocol"
+)
+
+// Canary will construct a middleware which injects the target version header into
+// outgoing requests according to the percentage policy, the server side routes the
+// request to the instances of that version.
+func Canary(opts ...Option) client.Middleware {
+	options := &Options{
+		Header: DefaultHeader,
+	}
+	options.Apply(opts)
+
+	total := 0
+	for _, t := range options.Targets {
+		if t.Percent < 0 {
+			panic(fmt.Errorf("canary: invalid percent %d of version '%s'", t.Percent, t.Version))
+		}
+		total += t.Percent
+	}
+	if total > 100 {
+		panic(fmt.Errorf("canary: the sum of percentages %d exceeds 100", total))
+	}
+
+	return func(next client.Endpoint) client.Endpoint {
+		return func(ctx context.Context, req *protocol.Request, resp *protocol.Response) (err error) {
+			if options.Override || len(req.Header.Peek(options.Header)) == 0 {
+				if version := options.pick(ctx, req); version != "" {
+					req.Header.Set(options.Header, version)
+				}
+			}
+			return next(ctx, req, resp)
+		}
+	}
+}
+
+// pick returns the version of the request, the bucket of the request is in [0, 100).
+func (o *Options) pick(ctx context.Context, req *protocol.Request) string {
+	var bucket int
+	if key := o.stickyKey(ctx, req); key != "" {
+		bucket = int(xxhash3.HashString(key) % 100)
+	} else {
+		bucket = fastrand.Intn(100)
+	}
+	for _, t := range o.Targets {
+		if bucket < t.Percent {
+			return t.Version
+		}
+		bucket -= t.Percent
+	}
+	return o.BaseVersion
+}
+
+func (o *Options) stickyKey(ctx context.Context, req *protocol.Request) string {
+	if o.StickyKey == nil {
+		return ""
+	}
+	return o.StickyKey(ctx, req)
+}
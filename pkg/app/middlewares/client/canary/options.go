@@ -0,0 +1,106 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package canary
+
+import (
+	"context"
+
+	"hertz-study/pkg/protocol"
+)
+
+// DefaultHeader is the request header which carries the target version.
+const DefaultHeader = "X-Canary-Version"
+
+// Target is a version which receives Percent percent of the traffic.
+type Target struct {
+	Version string
+	Percent int
+}
+
+// Options canary option for client
+type Options struct {
+	// Header is the header injected with the target version, default is DefaultHeader
+	Header string
+
+	// Targets are the canary versions, the sum of their percentages should not exceed 100
+	Targets []Target
+
+	// BaseVersion is injected for the traffic not hitting any target, no header is injected if empty
+	BaseVersion string
+
+	// StickyKey returns the key of the request, requests with the same key always get the same version.
+	// The version is picked randomly if it is nil or returns an empty key
+	StickyKey func(ctx context.Context, req *protocol.Request) string
+
+	// Override forces to override the header carried by the request,
+	// otherwise the version propagated from upstream is kept
+	Override bool
+}
+
+func (o *Options) Apply(opts []Option) {
+	for _, op := range opts {
+		op.F(o)
+	}
+}
+
+type Option struct {
+	F func(o *Options)
+}
+
+// WithHeader sets the header injected with the target version.
+func WithHeader(header string) Option {
+	return Option{F: func(o *Options) {
+		o.Header = header
+	}}
+}
+
+// WithTarget adds a canary version which receives percent percent of the traffic.
+func WithTarget(version string, percent int) Option {
+	return Option{F: func(o *Options) {
+		o.Targets = append(o.Targets, Target{Version: version, Percent: percent})
+	}}
+}
+
+// WithBaseVersion sets the version injected for the traffic not hitting any canary target.
+func WithBaseVersion(version string) Option {
+	return Option{F: func(o *Options) {
+		o.BaseVersion = version
+	}}
+}
+
+// WithStickyKey sets the function to get the sticky key of the request.
+func WithStickyKey(f func(ctx context.Context, req *protocol.Request) string) Option {
+	return Option{F: func(o *Options) {
+		o.StickyKey = f
+	}}
+}
+
+// WithStickyHeader uses the value of the request header as sticky key, e.g. user id.
+func WithStickyHeader(header string) Option {
+	return Option{F: func(o *Options) {
+		o.StickyKey = func(ctx context.Context, req *protocol.Request) string {
+			return req.Header.Get(header)
+		}
+	}}
+}
+
+// WithOverride sets whether to override the version header carried by the request.
+func WithOverride(b bool) Option {
+	return Option{F: func(o *Options) {
+		o.Override = b
+	}}
+}
@@ -0,0 +1,61 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package deadline
+
+import (
+	"context"
+	"time"
+
+	"hertz-study/pkg/app/client"
+	"hertz-study/pkg/common/config"
+	"hertz-study/pkg/protocol"
+)
+
+// Deadline will construct a middleware which bounds the timeout of the outgoing requests by
+// the deadline of their context, e.g. set by the server deadline middleware, and propagates the
+// remaining budget to the server by the header. The requests whose context is done are not sent
+// and the error of the context is returned.
+func Deadline(opts ...Option) client.Middleware {
+	options := &Options{
+		Header: DefaultHeader,
+		Format: FormatMilliseconds,
+	}
+	options.Apply(opts)
+
+	return func(next client.Endpoint) client.Endpoint {
+		return func(ctx context.Context, req *protocol.Request, resp *protocol.Response) (err error) {
+			deadline, ok := ctx.Deadline()
+			if !ok {
+				return next(ctx, req, resp)
+			}
+			if err = ctx.Err(); err != nil {
+				return err
+			}
+			remaining := time.Until(deadline)
+			if remaining <= 0 {
+				return context.DeadlineExceeded
+			}
+			if t := req.Options().RequestTimeout(); t <= 0 || t > remaining {
+				req.SetOptions(config.WithRequestTimeout(remaining))
+			}
+			if options.Header != "" {
+				req.Header.Set(options.Header, options.Format(remaining))
+			}
+			return next(ctx, req, resp)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package deadline
+
+import (
+	"strconv"
+	"time"
+)
+
+const (
+	// DefaultHeader carries the remaining budget of the request in milliseconds.
+	DefaultHeader = "X-Request-Timeout-Ms"
+	// GRPCTimeoutHeader carries the remaining budget of the request in the format of gRPC.
+	GRPCTimeoutHeader = "Grpc-Timeout"
+)
+
+// Options deadline option for client
+type Options struct {
+	// Header is the header propagating the remaining budget to the server, default is
+	// DefaultHeader, no header is set if empty
+	Header string
+
+	// Format formats the remaining budget into the value of Header, default is FormatMilliseconds
+	Format func(timeout time.Duration) string
+}
+
+func (o *Options) Apply(opts []Option) {
+	for _, op := range opts {
+		op.F(o)
+	}
+}
+
+type Option struct {
+	F func(o *Options)
+}
+
+// WithHeader sets the header propagating the remaining budget and its format.
+func WithHeader(header string, format func(timeout time.Duration) string) Option {
+	return Option{F: func(o *Options) {
+		o.Header = header
+		o.Format = format
+	}}
+}
+
+// WithGRPCTimeout propagates the remaining budget by GRPCTimeoutHeader.
+func WithGRPCTimeout() Option {
+	return WithHeader(GRPCTimeoutHeader, FormatGRPCTimeout)
+}
+
+// FormatMilliseconds formats timeout in milliseconds, rounded up so that a budget left is not 0.
+func FormatMilliseconds(timeout time.Duration) string {
+	return strconv.FormatInt(int64((timeout+time.Millisecond-1)/time.Millisecond), 10)
+}
+
+// FormatGRPCTimeout formats timeout in the format of gRPC, with the finest unit making at most
+// 8 digits, rounded up.
+func FormatGRPCTimeout(timeout time.Duration) string {
+	units := []struct {
+		d    time.Duration
+		unit string
+	}{
+		{time.Nanosecond, "n"},
+		{time.Microsecond, "u"},
+		{time.Millisecond, "m"},
+		{time.Second, "S"},
+		{time.Minute, "M"},
+		{time.Hour, "H"},
+	}
+	for _, u := range units {
+		if n := (timeout + u.d - 1) / u.d; n <= 99999999 {
+			return strconv.FormatInt(int64(n), 10) + u.unit
+		}
+	}
+	return "99999999H"
+}
@@ -0,0 +1,75 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package interceptor provides the common client middlewares, which are installed by
+// client.Use and wrap every outbound request like the HandlersChain of the server.
+package interceptor
+
+import (
+	"context"
+
+	"hertz-study/pkg/app/client"
+	"hertz-study/pkg/app/middlewares/server/logfields"
+	"hertz-study/pkg/common/hlog"
+	"hertz-study/pkg/protocol"
+)
+
+// Headers returns a middleware which sets the headers of every request,
+// the headers already set by the caller are kept.
+func Headers(headers map[string]string) client.Middleware {
+	return func(next client.Endpoint) client.Endpoint {
+		return func(ctx context.Context, req *protocol.Request, resp *protocol.Response) error {
+			for k, v := range headers {
+				if len(req.Header.Peek(k)) == 0 {
+					req.Header.Set(k, v)
+				}
+			}
+			return next(ctx, req, resp)
+		}
+	}
+}
+
+// BearerAuth returns a middleware which sets the Authorization header of every request
+// with the token returned by token, e.g. from a token source refreshing it in background.
+// The request fails with the error returned by token.
+func BearerAuth(token func(ctx context.Context) (string, error)) client.Middleware {
+	return func(next client.Endpoint) client.Endpoint {
+		return func(ctx context.Context, req *protocol.Request, resp *protocol.Response) error {
+			t, err := token(ctx)
+			if err != nil {
+				return err
+			}
+			req.Header.Set("Authorization", "Bearer "+t)
+			return next(ctx, req, resp)
+		}
+	}
+}
+
+// RequestID returns a middleware which propagates the request id seeded into ctx by
+// logfields.LogFields to the downstream by header, e.g. "X-Request-ID", so that the logs
+// of the whole call chain can be correlated.
+func RequestID(header string) client.Middleware {
+	return func(next client.Endpoint) client.Endpoint {
+		return func(ctx context.Context, req *protocol.Request, resp *protocol.Response) error {
+			if id, ok := hlog.FieldsFromContext(ctx)[logfields.KeyRequestID].(string); ok && id != "" {
+				if len(req.Header.Peek(header)) == 0 {
+					req.Header.Set(header, id)
+				}
+			}
+			return next(ctx, req, resp)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interceptor
+
+import (
+	"context"
+	"time"
+
+	"hertz-study/pkg/app/client"
+	"hertz-study/pkg/common/hlog"
+	"hertz-study/pkg/protocol"
+)
+
+// Logger returns a middleware which logs the method, url, status code and latency of
+// every request by hlog.Ctx* functions, so the fields seeded into ctx are attached.
+// The failed requests are logged at error level.
+func Logger() client.Middleware {
+	return func(next client.Endpoint) client.Endpoint {
+		return func(ctx context.Context, req *protocol.Request, resp *protocol.Response) error {
+			start := time.Now()
+			err := next(ctx, req, resp)
+			latency := time.Since(start)
+			if err != nil {
+				hlog.CtxErrorf(ctx, "HERTZ: client request failed: method=%s, url=%s, latency=%v, error=%v",
+					req.Method(), req.URI().FullURI(), latency, err)
+				return err
+			}
+			status := 0
+			if resp != nil {
+				status = resp.StatusCode()
+			}
+			hlog.CtxInfof(ctx, "HERTZ: client request: method=%s, url=%s, status=%d, latency=%v",
+				req.Method(), req.URI().FullURI(), status, latency)
+			return nil
+		}
+	}
+}
@@ -0,0 +1,64 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interceptor
+
+import (
+	"context"
+	"time"
+
+	"hertz-study/pkg/app/client"
+	"hertz-study/pkg/app/client/retry"
+	"hertz-study/pkg/protocol"
+	protocolclient "hertz-study/pkg/protocol/client"
+)
+
+// Retry returns a middleware which retries the request while retryIf returns true, up to
+// the MaxAttemptTimes of the retry config including the first attempt, waiting the delay
+// of the config between the attempts. protocolclient.DefaultRetryIf is used if retryIf is nil.
+//
+// Unlike client.WithRetryConfig which retries inside the transport, the retry is visible to
+// the middlewares installed before it, e.g. the status codes like 503 can be retried.
+// The requests with body stream are never retried since the body can't be replayed.
+func Retry(retryIf protocolclient.RetryIfFunc, opts ...retry.Option) client.Middleware {
+	cfg := &retry.Config{
+		MaxAttemptTimes: 3,
+		DelayPolicy:     retry.DefaultDelayPolicy,
+	}
+	cfg.Apply(opts)
+	if retryIf == nil {
+		retryIf = protocolclient.DefaultRetryIf
+	}
+
+	return func(next client.Endpoint) client.Endpoint {
+		return func(ctx context.Context, req *protocol.Request, resp *protocol.Response) (err error) {
+			for attempts := uint(1); ; attempts++ {
+				err = next(ctx, req, resp)
+				if attempts >= cfg.MaxAttemptTimes || req.IsBodyStream() || !retryIf(req, resp, err) {
+					return err
+				}
+				select {
+				case <-ctx.Done():
+					return err
+				case <-time.After(retry.Delay(attempts, err, cfg)):
+				}
+				if resp != nil {
+					resp.Reset()
+				}
+			}
+		}
+	}
+}
@@ -25,7 +25,9 @@ import (
 	"hertz-study/pkg/protocol"
 )
 
-// Discovery will construct a middleware with BalancerFactory.
+// Discovery will construct a middleware with BalancerFactory, which resolves the host of the
+// requests tagged by config.WithSD(true) or named by WithServiceNames to the instances given
+// by resolver, and picks one by the balancer, e.g. loadbalance.NewRoundRobinBalancer.
 func Discovery(resolver discovery.Resolver, opts ...ServiceDiscoveryOption) client.Middleware {
 	options := &ServiceDiscoveryOptions{
 		Balancer: loadbalance.NewWeightedBalancer(),
@@ -43,12 +45,13 @@ func Discovery(resolver discovery.Resolver, opts ...ServiceDiscoveryOption) clie
 	f := loadbalance.NewBalancerFactory(lbConfig)
 	return func(next client.Endpoint) client.Endpoint {
 		return func(ctx context.Context, req *protocol.Request, resp *protocol.Response) (err error) {
-			if req.Options() != nil && req.Options().IsSD() {
-				ins, err := f.GetInstance(ctx, req)
-				if err != nil {
-					return err
+			if req.Options().IsSD() || options.ServiceNames[string(req.Host())] {
+				ins, pickErr := f.GetInstance(ctx, req)
+				if pickErr != nil {
+					return pickErr
 				}
 				req.SetHost(ins.Address().String())
+				defer func() { f.Done(ins, err) }()
 			}
 			return next(ctx, req, resp)
 		}
@@ -37,6 +37,10 @@ type ServiceDiscoveryOptions struct {
 
 	// LbOpts LoadBalance option
 	LbOpts loadbalance.Options
+
+	// ServiceNames are the hosts always resolved by service discovery, without
+	// tagging the requests by config.WithSD
+	ServiceNames map[string]bool
 }
 
 func (o *ServiceDiscoveryOptions) Apply(opts []ServiceDiscoveryOption) {
@@ -94,3 +98,17 @@ func WithLoadBalanceOptions(lb loadbalance.Loadbalancer, options loadbalance.Opt
 		o.Balancer = lb
 	}}
 }
+
+// WithServiceNames sets the hosts always resolved by service discovery, so the requests like
+// "http://user-service/users/1" are balanced among the instances of "user-service" without
+// tagging the requests by config.WithSD(true).
+func WithServiceNames(names ...string) ServiceDiscoveryOption {
+	return ServiceDiscoveryOption{F: func(o *ServiceDiscoveryOptions) {
+		if o.ServiceNames == nil {
+			o.ServiceNames = make(map[string]bool, len(names))
+		}
+		for _, name := range names {
+			o.ServiceNames[name] = true
+		}
+	}}
+}
@@ -0,0 +1,57 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package audit
+
+import (
+	"context"
+	"strconv"
+
+	"hertz-study/pkg/app"
+	"hertz-study/pkg/common/hlog"
+)
+
+// Audit returns a middleware which writes an audit record of every request to al after the
+// handlers, with the method and the route as the action, the path as the resource and the
+// status code as the outcome. al is also carried by the context passed to the handlers, use
+// hlog.AuditFromContext to write the records of the business actions.
+func Audit(al *hlog.AuditLogger, opts ...Option) app.HandlerFunc {
+	cfg := newOptions(opts...)
+	return func(c context.Context, ctx *app.RequestContext) {
+		c = hlog.NewAuditContext(c, al)
+		ctx.Next(c)
+
+		if cfg.skipper != nil && cfg.skipper(c, ctx) {
+			return
+		}
+		action := ctx.FullPath()
+		if action == "" {
+			action = string(ctx.Path())
+		}
+		e := &hlog.AuditEvent{
+			Actor:    cfg.actor(c, ctx),
+			Action:   string(ctx.Method()) + " " + action,
+			Resource: string(ctx.Path()),
+			Outcome:  strconv.Itoa(ctx.Response.StatusCode()),
+		}
+		if cfg.fields != nil {
+			e.Fields = cfg.fields(c, ctx)
+		}
+		if _, err := al.Log(e); err != nil {
+			hlog.SystemLogger().CtxErrorf(c, "[Audit] write record failed: err=%v", err)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package audit
+
+import (
+	"context"
+
+	"hertz-study/pkg/app"
+	"hertz-study/pkg/common/hlog"
+)
+
+const (
+	defaultActorKey = "user"
+	anonymousActor  = "anonymous"
+)
+
+type (
+	options struct {
+		actor   func(c context.Context, ctx *app.RequestContext) string
+		fields  func(c context.Context, ctx *app.RequestContext) hlog.Fields
+		skipper func(c context.Context, ctx *app.RequestContext) bool
+	}
+
+	Option func(o *options)
+)
+
+func newOptions(opts ...Option) *options {
+	cfg := &options{
+		actor: defaultActor,
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return cfg
+}
+
+// defaultActor returns the user set by the basic_auth middleware, or "anonymous".
+func defaultActor(_ context.Context, ctx *app.RequestContext) string {
+	if user := ctx.GetString(defaultActorKey); user != "" {
+		return user
+	}
+	return anonymousActor
+}
+
+// WithActor sets the function returning the actor of the request, default is the value of
+// key "user" set by the basic_auth middleware, or "anonymous" if there is none.
+func WithActor(f func(c context.Context, ctx *app.RequestContext) string) Option {
+	return func(o *options) {
+		o.actor = f
+	}
+}
+
+// WithFields sets the function returning the additional fields of the record, which is
+// called after the handlers, e.g. the tenant id.
+func WithFields(f func(c context.Context, ctx *app.RequestContext) hlog.Fields) Option {
+	return func(o *options) {
+		o.fields = f
+	}
+}
+
+// WithSkipper sets the function deciding whether the request is skipped from auditing,
+// e.g. the read-only requests. The audit logger is still available to the handlers then.
+func WithSkipper(f func(c context.Context, ctx *app.RequestContext) bool) Option {
+	return func(o *options) {
+		o.skipper = f
+	}
+}
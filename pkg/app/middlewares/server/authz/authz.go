@@ -0,0 +1,73 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package authz
+
+import (
+	"context"
+
+	"hertz-study/pkg/app"
+	"hertz-study/pkg/protocol/consts"
+)
+
+// Enforcer decides whether the subject sub is allowed to take the action act on the object obj.
+type Enforcer interface {
+	Enforce(sub, obj, act string) (bool, error)
+}
+
+// EnforcerFunc is an adapter to use a function as the Enforcer, e.g. to use a Casbin enforcer
+// whose model has the request definition "r = sub, obj, act":
+//
+//	authz.EnforcerFunc(func(sub, obj, act string) (bool, error) {
+//		return e.Enforce(sub, obj, act)
+//	})
+type EnforcerFunc func(sub, obj, act string) (bool, error)
+
+// Enforce calls f(sub, obj, act).
+func (f EnforcerFunc) Enforce(sub, obj, act string) (bool, error) {
+	return f(sub, obj, act)
+}
+
+// New returns an authorization middleware which enforces the policies of e on the subject,
+// the object and the action of the requests. It should be used after the authentication
+// middleware setting the subject, e.g.
+//
+//	rbac := authz.NewRBAC()
+//	rbac.AddPolicy("admin", "/admin/*", "*")
+//	rbac.AddRoleForUser("alice", "admin")
+//	admin := h.Group("/admin", basic_auth.New(accounts, "admin"), authz.New(rbac))
+//
+// The requests are aborted with 500 if e returns an error.
+func New(e Enforcer, opts ...Option) app.HandlerFunc {
+	o := newOptions(opts...)
+	return func(c context.Context, ctx *app.RequestContext) {
+		sub := o.subjectFunc(c, ctx)
+		if sub == "" {
+			o.unauthorized(c, ctx)
+			ctx.Abort()
+			return
+		}
+		ok, err := e.Enforce(sub, o.objectFunc(c, ctx), o.actionFunc(c, ctx))
+		if err != nil {
+			ctx.AbortWithError(consts.StatusInternalServerError, err) //nolint:errcheck
+			return
+		}
+		if !ok {
+			o.forbidden(c, ctx)
+			ctx.Abort()
+		}
+	}
+}
@@ -0,0 +1,118 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package authz
+
+import (
+	"context"
+
+	"hertz-study/pkg/app"
+	"hertz-study/pkg/protocol/consts"
+)
+
+// DefaultSubjectKey is the key of the context value used as the subject by default, which is
+// the key the authenticated users are set by basic_auth and digest_auth.
+const DefaultSubjectKey = "user"
+
+type (
+	options struct {
+		subjectFunc  RequestFunc
+		objectFunc   RequestFunc
+		actionFunc   RequestFunc
+		unauthorized app.HandlerFunc
+		forbidden    app.HandlerFunc
+	}
+
+	Option func(o *options)
+
+	// RequestFunc returns a string from the request, e.g. the subject of the request.
+	RequestFunc func(c context.Context, ctx *app.RequestContext) string
+)
+
+func newOptions(opts ...Option) *options {
+	cfg := &options{
+		subjectFunc:  defaultSubject,
+		objectFunc:   defaultObject,
+		actionFunc:   defaultAction,
+		unauthorized: defaultUnauthorized,
+		forbidden:    defaultForbidden,
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return cfg
+}
+
+func defaultSubject(c context.Context, ctx *app.RequestContext) string {
+	return ctx.GetString(DefaultSubjectKey)
+}
+
+func defaultObject(c context.Context, ctx *app.RequestContext) string {
+	return ctx.FullPath()
+}
+
+func defaultAction(c context.Context, ctx *app.RequestContext) string {
+	return string(ctx.Method())
+}
+
+func defaultUnauthorized(c context.Context, ctx *app.RequestContext) {
+	ctx.AbortWithMsg("Unauthorized", consts.StatusUnauthorized)
+}
+
+func defaultForbidden(c context.Context, ctx *app.RequestContext) {
+	ctx.AbortWithMsg("Forbidden", consts.StatusForbidden)
+}
+
+// WithSubjectFunc sets the function returning the subject of the request, default is the string
+// set to the context by DefaultSubjectKey. The requests with an empty subject are unauthorized.
+func WithSubjectFunc(f RequestFunc) Option {
+	return func(o *options) {
+		o.subjectFunc = f
+	}
+}
+
+// WithObjectFunc sets the function returning the object of the request, default is the route
+// template, e.g. "/users/:id", so that a policy covers all the requests of the route.
+func WithObjectFunc(f RequestFunc) Option {
+	return func(o *options) {
+		o.objectFunc = f
+	}
+}
+
+// WithActionFunc sets the function returning the action of the request, default is the method.
+func WithActionFunc(f RequestFunc) Option {
+	return func(o *options) {
+		o.actionFunc = f
+	}
+}
+
+// WithUnauthorized sets the handler responding the requests without a subject, which responds
+// 401 by default.
+func WithUnauthorized(h app.HandlerFunc) Option {
+	return func(o *options) {
+		o.unauthorized = h
+	}
+}
+
+// WithForbidden sets the handler responding the requests denied by the policies, which responds
+// 403 by default.
+func WithForbidden(h app.HandlerFunc) Option {
+	return func(o *options) {
+		o.forbidden = h
+	}
+}
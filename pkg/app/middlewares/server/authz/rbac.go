@@ -0,0 +1,221 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package authz
+
+import (
+	"errors"
+	"strings"
+	"sync"
+)
+
+// Wildcard matches any object or action in the policies of RBAC.
+const Wildcard = "*"
+
+type policy struct {
+	obj, act string
+}
+
+// RBAC is an in-memory Enforcer of the role based access control, it is safe for concurrent use.
+//
+// A policy allows a subject, which is a user or a role, to take an action on an object. The
+// object of a policy matches the objects equal to it, or with its prefix if it ends with "*",
+// e.g. "/admin/*" matches "/admin/users". Wildcard matches any object or action. The roles may
+// be assigned to users as well as other roles, whose policies are inherited.
+//
+// The policies follow the "p, sub, obj, act" and "g, user, role" rules of Casbin, see LoadPolicy.
+type RBAC struct {
+	mu       sync.RWMutex
+	policies map[string][]policy
+	roles    map[string][]string
+}
+
+// NewRBAC creates an empty RBAC, which denies all the requests.
+func NewRBAC() *RBAC {
+	return &RBAC{
+		policies: make(map[string][]policy),
+		roles:    make(map[string][]string),
+	}
+}
+
+// AddPolicy allows sub to take the action act on obj.
+func (r *RBAC) AddPolicy(sub, obj, act string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.addPolicy(sub, obj, act)
+}
+
+func (r *RBAC) addPolicy(sub, obj, act string) {
+	p := policy{obj: obj, act: act}
+	for _, existing := range r.policies[sub] {
+		if existing == p {
+			return
+		}
+	}
+	r.policies[sub] = append(r.policies[sub], p)
+}
+
+// RemovePolicy removes the policy added by AddPolicy.
+func (r *RBAC) RemovePolicy(sub, obj, act string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ps, p := r.policies[sub], policy{obj: obj, act: act}
+	for i := range ps {
+		if ps[i] == p {
+			r.policies[sub] = append(ps[:i], ps[i+1:]...)
+			break
+		}
+	}
+	if len(r.policies[sub]) == 0 {
+		delete(r.policies, sub)
+	}
+}
+
+// AddRoleForUser assigns role to user, which may be a role as well.
+func (r *RBAC) AddRoleForUser(user, role string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.addRoleForUser(user, role)
+}
+
+func (r *RBAC) addRoleForUser(user, role string) {
+	for _, existing := range r.roles[user] {
+		if existing == role {
+			return
+		}
+	}
+	r.roles[user] = append(r.roles[user], role)
+}
+
+// DeleteRoleForUser removes role from user.
+func (r *RBAC) DeleteRoleForUser(user, role string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	roles := r.roles[user]
+	for i := range roles {
+		if roles[i] == role {
+			r.roles[user] = append(roles[:i], roles[i+1:]...)
+			break
+		}
+	}
+	if len(r.roles[user]) == 0 {
+		delete(r.roles, user)
+	}
+}
+
+// RolesForUser returns the roles of user, including the ones inherited from its roles.
+func (r *RBAC) RolesForUser(user string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var roles []string
+	r.walkRoles(user, func(sub string) bool {
+		if sub != user {
+			roles = append(roles, sub)
+		}
+		return false
+	})
+	return roles
+}
+
+// Enforce implements Enforcer, it never returns an error.
+func (r *RBAC) Enforce(sub, obj, act string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.walkRoles(sub, func(s string) bool {
+		for _, p := range r.policies[s] {
+			if match(p.obj, obj) && (p.act == Wildcard || p.act == act) {
+				return true
+			}
+		}
+		return false
+	}), nil
+}
+
+// walkRoles calls f with sub and all its roles until f returns true, the cycles of the roles
+// are visited only once.
+func (r *RBAC) walkRoles(sub string, f func(sub string) bool) bool {
+	visited := map[string]struct{}{sub: {}}
+	queue := []string{sub}
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		if f(s) {
+			return true
+		}
+		for _, role := range r.roles[s] {
+			if _, ok := visited[role]; !ok {
+				visited[role] = struct{}{}
+				queue = append(queue, role)
+			}
+		}
+	}
+	return false
+}
+
+// LoadPolicy replaces all the policies and roles with rules, which are the lines of a Casbin
+// policy, e.g. loaded by a Casbin adapter or read from a policy.csv, in the form of
+// ["p", sub, obj, act] or ["g", user, role]. Nothing is changed if any rule is invalid.
+func (r *RBAC) LoadPolicy(rules [][]string) error {
+	loaded := NewRBAC()
+	for _, rule := range rules {
+		rule = append([]string(nil), rule...)
+		for i := range rule {
+			rule[i] = strings.TrimSpace(rule[i])
+		}
+		switch {
+		case len(rule) == 4 && rule[0] == "p":
+			loaded.addPolicy(rule[1], rule[2], rule[3])
+		case len(rule) == 3 && rule[0] == "g":
+			loaded.addRoleForUser(rule[1], rule[2])
+		default:
+			return errors.New("authz: invalid policy rule " + strings.Join(rule, ", "))
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.policies, r.roles = loaded.policies, loaded.roles
+	return nil
+}
+
+// Policy returns all the policies and roles in the form of LoadPolicy, e.g. to save them by a
+// Casbin adapter.
+func (r *RBAC) Policy() [][]string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var rules [][]string
+	for sub, ps := range r.policies {
+		for _, p := range ps {
+			rules = append(rules, []string{"p", sub, p.obj, p.act})
+		}
+	}
+	for user, roles := range r.roles {
+		for _, role := range roles {
+			rules = append(rules, []string{"g", user, role})
+		}
+	}
+	return rules
+}
+
+// match reports whether the object pattern of a policy matches obj.
+func match(pattern, obj string) bool {
+	if pattern == Wildcard || pattern == obj {
+		return true
+	}
+	if strings.HasSuffix(pattern, "*") {
+		return strings.HasPrefix(obj, pattern[:len(pattern)-1])
+	}
+	return false
+}
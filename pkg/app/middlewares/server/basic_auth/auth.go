@@ -0,0 +1,92 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package basic_auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"strconv"
+
+	"hertz-study/pkg/app"
+	"hertz-study/pkg/protocol/consts"
+)
+
+// New returns a Basic HTTP Authorization middleware for realm, the credentials are checked
+// against the accounts, which may be nil, and then the password function set by WithPasswordFunc.
+// The passwords are compared in constant time, and the authenticated user is set to the context
+// by the key set by WithUserKey, e.g.
+//
+//	admin := h.Group("/admin", basic_auth.New(basic_auth.Accounts{"admin": secret}, "admin"))
+func New(accounts Accounts, realm string, opts ...Option) app.HandlerFunc {
+	o := newOptions(opts...)
+	if realm == "" {
+		realm = "Authorization Required"
+	}
+	challenge := "Basic realm=" + strconv.Quote(realm) + `, charset="UTF-8"`
+	return func(c context.Context, ctx *app.RequestContext) {
+		user, password, ok := parseBasicAuth(ctx.Request.Header.Peek(consts.HeaderAuthorization))
+		if ok {
+			expected, found := accounts.Password(c, user, o.passwordFunc)
+			// compare even if the user is not found to not reveal whether it exists by the timing
+			ok = SecureCompare(password, expected) && found
+		}
+		if !ok {
+			ctx.Header(consts.HeaderWWWAuthenticate, challenge)
+			ctx.AbortWithStatus(consts.StatusUnauthorized)
+			return
+		}
+		ctx.Set(o.userKey, user)
+	}
+}
+
+// Password returns the password of user from the accounts, or from f if the user is not in the
+// accounts and f isn't nil.
+func (accounts Accounts) Password(c context.Context, user string, f PasswordFunc) (string, bool) {
+	if password, ok := accounts[user]; ok {
+		return password, true
+	}
+	if f != nil {
+		return f(c, user)
+	}
+	return "", false
+}
+
+// SecureCompare reports whether a and b are equal in constant time, regardless of their lengths.
+func SecureCompare(a, b string) bool {
+	ha, hb := sha256.Sum256([]byte(a)), sha256.Sum256([]byte(b))
+	return subtle.ConstantTimeCompare(ha[:], hb[:]) == 1
+}
+
+// parseBasicAuth parses the value of an Authorization header of the Basic scheme.
+func parseBasicAuth(auth []byte) (user, password string, ok bool) {
+	const prefix = "Basic "
+	if len(auth) < len(prefix) || !bytes.EqualFold(auth[:len(prefix)], []byte(prefix)) {
+		return "", "", false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(string(auth[len(prefix):]))
+	if err != nil {
+		return "", "", false
+	}
+	i := bytes.IndexByte(decoded, ':')
+	if i < 0 {
+		return "", "", false
+	}
+	return string(decoded[:i]), string(decoded[i+1:]), true
+}
@@ -40,57 +40,18 @@
 
 package basic_auth
 
-import (
-	"context"
-	"encoding/base64"
-	"net/http"
-	"strconv"
-
-	"hertz-study/internal/bytesconv"
-	"hertz-study/pkg/app"
-)
+import "hertz-study/pkg/app"
 
 // Accounts is an alias to map[string]string, construct with {"username":"password"}
 type Accounts map[string]string
 
-// pairs is an alias to map[string]string, which mean {"header":"username"}
-type pairs map[string]string
-
-func (p pairs) findValue(needle string) (v string, ok bool) {
-	v, ok = p[needle]
-	return
-}
-
-func constructPairs(accounts Accounts) pairs {
-	length := len(accounts)
-	p := make(pairs, length)
-	for user, password := range accounts {
-		value := "Basic " + base64.StdEncoding.EncodeToString(bytesconv.S2b(user+":"+password))
-		p[value] = user
-	}
-	return p
-}
-
 // BasicAuthForRealm returns a Basic HTTP Authorization middleware. It takes as arguments a map[string]string where
 // the key is the username and the value is the password, as well as the name of the Realm.
 // If the realm is empty, "Authorization Required" will be used by default.
 // (see http://tools.ietf.org/html/rfc2617#section-1.2)
+// The passwords are compared in constant time, see New.
 func BasicAuthForRealm(accounts Accounts, realm, userKey string) app.HandlerFunc {
-	realm = "Basic realm=" + strconv.Quote(realm)
-	p := constructPairs(accounts)
-	return func(ctx context.Context, c *app.RequestContext) {
-		// Search user in the slice of allowed credentials
-		user, found := p.findValue(c.Request.Header.Get("Authorization"))
-		if !found {
-			// Credentials doesn't match, we return 401 and abort handlers chain.
-			c.Header("WWW-Authenticate", realm)
-			c.AbortWithStatus(http.StatusUnauthorized)
-			return
-		}
-
-		// The user credentials was found, set user's id to key AuthUserKey in this context, the user's id can be read later using
-		c.Set(userKey, user)
-	}
+	return New(accounts, realm, WithUserKey(userKey))
 }
 
 // BasicAuth is a constructor of BasicAuth verifier to hertz middleware
@@ -0,0 +1,62 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package basic_auth
+
+import (
+	"context"
+)
+
+const defaultUserKey = "user"
+
+type (
+	options struct {
+		passwordFunc PasswordFunc
+		userKey      string
+	}
+
+	Option func(o *options)
+
+	// PasswordFunc returns the password of user, ok is false if the user doesn't exist,
+	// e.g. to load the credentials from a database or a secret manager.
+	PasswordFunc func(c context.Context, user string) (password string, ok bool)
+)
+
+func newOptions(opts ...Option) *options {
+	cfg := &options{
+		userKey: defaultUserKey,
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return cfg
+}
+
+// WithPasswordFunc sets the function to look up the passwords of the users not in the accounts.
+func WithPasswordFunc(f PasswordFunc) Option {
+	return func(o *options) {
+		o.passwordFunc = f
+	}
+}
+
+// WithUserKey sets the key of the authenticated user set to the context, default is "user".
+func WithUserKey(key string) Option {
+	return func(o *options) {
+		o.userKey = key
+	}
+}
@@ -0,0 +1,76 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bodylimit
+
+import (
+	"context"
+
+	"hertz-study/pkg/app"
+	"hertz-study/pkg/protocol/consts"
+	"hertz-study/pkg/protocol/http1/ext"
+)
+
+const tooLargeMsg = "Request Entity Too Large"
+
+// New returns a middleware which limits the request body size of the routes it is applied to
+// to n bytes, requests with a larger body are rejected with 413 and the connection is closed.
+//
+// The body exceeding the engine level limit set by server.WithMaxRequestBodySize is rejected
+// before reaching any handler, so n only tightens the limit unless server.WithStreamBody is
+// enabled. With streaming body, the size is checked with the Content-Length header before
+// calling the next handlers, and chunked bodies are limited while being read: reading beyond
+// n bytes returns errs.ErrBodyTooLarge and the response is replaced with 413 after the
+// handlers return.
+func New(n int) app.HandlerFunc {
+	return func(c context.Context, ctx *app.RequestContext) {
+		if n <= 0 {
+			ctx.Next(c)
+			return
+		}
+		if ctx.Request.Header.ContentLength() > n {
+			reject(ctx)
+			return
+		}
+		if !ctx.Request.IsBodyStream() {
+			if len(ctx.Request.Body()) > n {
+				reject(ctx)
+				return
+			}
+			ctx.Next(c)
+			return
+		}
+
+		stream := ctx.RequestBodyStream()
+		if !ext.LimitBodyStream(stream, n) {
+			// the stream is set by the user, leave it alone
+			ctx.Next(c)
+			return
+		}
+		ctx.Next(c)
+		if ext.BodyStreamExceeded(stream) {
+			reject(ctx)
+		}
+	}
+}
+
+func reject(ctx *app.RequestContext) {
+	ctx.AbortWithMsg(tooLargeMsg, consts.StatusRequestEntityTooLarge)
+	if ctx.Request.IsBodyStream() {
+		// the rest of the body is not read
+		ctx.SetConnectionClose()
+	}
+}
@@ -0,0 +1,231 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cache
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+	"hertz-study/internal/headers"
+	"hertz-study/pkg/app"
+	"hertz-study/pkg/protocol/consts"
+)
+
+const (
+	// StatusHeader is set to "HIT" on the responses served from the cache and "MISS" on the
+	// responses saved to the cache.
+	StatusHeader = "X-Cache"
+
+	headerAge = "Age"
+)
+
+// KeyFunc derives the cache key from a request, the requests with the same key share the
+// cached response.
+type KeyFunc func(c context.Context, ctx *app.RequestContext) string
+
+// New returns a middleware which caches the responses of the handlers and serves the requests
+// with the same key from the cache until the responses expire, e.g.
+//
+//	h.GET("/articles/:id", cache.New(cache.WithTTL(10*time.Second)), getArticle)
+//
+// Only the responses of the configured methods and status codes are cached, and never the ones
+// with a streamed body, a Set-Cookie header, "Vary: *" or the Cache-Control directive no-store,
+// no-cache or private. The s-maxage or max-age directive overrides the TTL. The responses varying
+// by the request headers listed in the Vary header are cached per the values of the headers.
+func New(opts ...Option) app.HandlerFunc {
+	o := newOptions(opts...)
+	var group singleflight.Group
+	return func(c context.Context, ctx *app.RequestContext) {
+		if !o.methods[string(ctx.Method())] {
+			ctx.Next(c)
+			return
+		}
+		req := Directives{MaxAge: -1, SMaxAge: -1}
+		if o.requestDirectives {
+			req = ParseCacheControl(ctx.Request.Header.Peek(consts.HeaderCacheControl))
+		}
+		key := o.keyFunc(c, ctx)
+		if !req.NoCache && req.MaxAge != 0 {
+			if e := o.lookup(ctx, key); e != nil {
+				serve(ctx, e)
+				return
+			}
+		}
+		if !o.stampede {
+			o.fill(c, ctx, key, req)
+			return
+		}
+
+		var leader bool
+		var panicked interface{}
+		group.Do(key, func() (interface{}, error) { //nolint:errcheck
+			leader = true
+			// the panic is raised again after Do, so that the waiting requests aren't failed by it
+			defer func() {
+				panicked = recover()
+			}()
+			o.fill(c, ctx, key, req)
+			return nil, nil
+		})
+		if leader {
+			if panicked != nil {
+				panic(panicked)
+			}
+			return
+		}
+		// the response may not be cached or may vary by the headers, handle the request if so
+		if e := o.lookup(ctx, key); e != nil {
+			serve(ctx, e)
+			return
+		}
+		o.fill(c, ctx, key, req)
+	}
+}
+
+// DefaultKey is the method, the host, the path and the sorted query string of the request.
+func DefaultKey(c context.Context, ctx *app.RequestContext) string {
+	var b strings.Builder
+	b.Write(ctx.Method())
+	b.WriteByte(' ')
+	b.Write(ctx.Host())
+	b.Write(ctx.Path())
+	if args := ctx.QueryArgs(); args.Len() > 0 {
+		query := make([]string, 0, args.Len())
+		args.VisitAll(func(k, v []byte) {
+			query = append(query, string(k)+"="+string(v))
+		})
+		sort.Strings(query)
+		b.WriteByte('?')
+		b.WriteString(strings.Join(query, "&"))
+	}
+	return b.String()
+}
+
+// HeaderKey returns a KeyFunc extending DefaultKey by the values of the request headers,
+// e.g. HeaderKey("X-Tenant-ID") to cache the responses per tenant.
+func HeaderKey(headers ...string) KeyFunc {
+	return func(c context.Context, ctx *app.RequestContext) string {
+		return DefaultKey(c, ctx) + headerValues(ctx, headers)
+	}
+}
+
+func headerValues(ctx *app.RequestContext, headers []string) string {
+	var b strings.Builder
+	for _, h := range headers {
+		b.WriteByte(0)
+		b.WriteString(h)
+		b.WriteByte('=')
+		b.Write(ctx.Request.Header.Peek(h))
+	}
+	return b.String()
+}
+
+// lookup returns the cached response of the request, or nil if there is none.
+func (o *options) lookup(ctx *app.RequestContext, key string) *Entry {
+	e := o.store.Get(key)
+	if e == nil || len(e.Vary) == 0 {
+		return e
+	}
+	e = o.store.Get(key + headerValues(ctx, e.Vary))
+	if e == nil || len(e.Vary) > 0 {
+		return nil
+	}
+	return e
+}
+
+// fill handles the request and saves the response if it is cacheable.
+func (o *options) fill(c context.Context, ctx *app.RequestContext, key string, req Directives) {
+	ctx.Next(c)
+
+	if req.NoStore {
+		return
+	}
+	ttl, ok := o.cacheable(ctx)
+	if !ok {
+		return
+	}
+	var vary []string
+	for _, v := range ctx.Response.Header.PeekAll(consts.HeaderVary) {
+		for _, name := range strings.Split(string(v), ",") {
+			if name = strings.TrimSpace(name); name == "*" {
+				return
+			} else if name != "" {
+				vary = append(vary, name)
+			}
+		}
+	}
+
+	ctx.Response.Header.Set(StatusHeader, "MISS")
+	e := capture(ctx)
+	if len(vary) > 0 {
+		o.store.Set(key, &Entry{Vary: vary, StoredAt: e.StoredAt}, ttl)
+		key += headerValues(ctx, vary)
+	}
+	o.store.Set(key, e, ttl)
+}
+
+// cacheable returns how long the response is cached, ok is false if it should not be cached.
+func (o *options) cacheable(ctx *app.RequestContext) (ttl time.Duration, ok bool) {
+	resp := &ctx.Response
+	if !o.statusCodes[resp.StatusCode()] || resp.IsBodyStream() || len(resp.Body()) > o.maxBodySize {
+		return 0, false
+	}
+	if len(resp.Header.Peek(consts.HeaderSetCookie)) > 0 {
+		return 0, false
+	}
+	d := ParseCacheControl(resp.Header.Peek(consts.HeaderCacheControl))
+	if d.NoStore || d.NoCache || d.Private {
+		return 0, false
+	}
+	ttl = o.ttl
+	if d.SMaxAge >= 0 {
+		ttl = time.Duration(d.SMaxAge) * time.Second
+	} else if d.MaxAge >= 0 {
+		ttl = time.Duration(d.MaxAge) * time.Second
+	}
+	return ttl, ttl > 0
+}
+
+func capture(ctx *app.RequestContext) *Entry {
+	e := &Entry{
+		StatusCode: ctx.Response.StatusCode(),
+		Body:       append([]byte(nil), ctx.Response.Body()...),
+		StoredAt:   time.Now(),
+	}
+	ctx.Response.Header.VisitAll(func(k, v []byte) {
+		switch string(k) {
+		case consts.HeaderContentLength, consts.HeaderDate, consts.HeaderServer, consts.HeaderConnection,
+			consts.HeaderTransferEncoding, StatusHeader, headerAge:
+			return
+		}
+		e.Header = append(e.Header, [2]string{string(k), string(v)})
+	})
+	return e
+}
+
+func serve(ctx *app.RequestContext, e *Entry) {
+	headers.Replay(&ctx.Response.Header, e.Header)
+	ctx.Response.Header.Set(headerAge, strconv.Itoa(int(time.Since(e.StoredAt)/time.Second)))
+	ctx.Response.Header.Set(StatusHeader, "HIT")
+	ctx.Response.SetStatusCode(e.StatusCode)
+	ctx.Response.SetBody(e.Body)
+	ctx.Abort()
+}
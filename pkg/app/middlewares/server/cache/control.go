@@ -0,0 +1,73 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cache
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// Directives are the directives of a Cache-Control header relevant to caching.
+type Directives struct {
+	NoStore bool
+	NoCache bool
+	Private bool
+	Public  bool
+	// MaxAge and SMaxAge are the seconds of max-age and s-maxage, -1 if absent or invalid.
+	MaxAge  int
+	SMaxAge int
+}
+
+// ParseCacheControl parses the value of a Cache-Control header, the unknown directives are ignored.
+func ParseCacheControl(v []byte) Directives {
+	d := Directives{MaxAge: -1, SMaxAge: -1}
+	for len(v) > 0 {
+		var item []byte
+		if i := bytes.IndexByte(v, ','); i >= 0 {
+			item, v = v[:i], v[i+1:]
+		} else {
+			item, v = v, nil
+		}
+		name, value := bytes.TrimSpace(item), []byte(nil)
+		if i := bytes.IndexByte(name, '='); i >= 0 {
+			name, value = bytes.TrimSpace(name[:i]), bytes.Trim(bytes.TrimSpace(name[i+1:]), `"`)
+		}
+		switch {
+		case bytes.EqualFold(name, []byte("no-store")):
+			d.NoStore = true
+		case bytes.EqualFold(name, []byte("no-cache")):
+			d.NoCache = true
+		case bytes.EqualFold(name, []byte("private")):
+			d.Private = true
+		case bytes.EqualFold(name, []byte("public")):
+			d.Public = true
+		case bytes.EqualFold(name, []byte("max-age")):
+			d.MaxAge = parseSeconds(value)
+		case bytes.EqualFold(name, []byte("s-maxage")):
+			d.SMaxAge = parseSeconds(value)
+		}
+	}
+	return d
+}
+
+func parseSeconds(v []byte) int {
+	n, err := strconv.Atoi(string(v))
+	if err != nil || n < 0 {
+		return -1
+	}
+	return n
+}
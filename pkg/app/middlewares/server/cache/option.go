@@ -0,0 +1,132 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cache
+
+import (
+	"time"
+
+	"hertz-study/pkg/protocol/consts"
+)
+
+const (
+	defaultTTL         = time.Minute
+	defaultMaxEntries  = 10000
+	defaultMaxBodySize = 1024 * 1024
+)
+
+type (
+	options struct {
+		store             Store
+		ttl               time.Duration
+		keyFunc           KeyFunc
+		methods           map[string]bool
+		statusCodes       map[int]bool
+		maxBodySize       int
+		requestDirectives bool
+		stampede          bool
+	}
+
+	Option func(o *options)
+)
+
+func newOptions(opts ...Option) *options {
+	cfg := &options{
+		ttl:         defaultTTL,
+		keyFunc:     DefaultKey,
+		methods:     map[string]bool{consts.MethodGet: true, consts.MethodHead: true},
+		statusCodes: map[int]bool{consts.StatusOK: true},
+		maxBodySize: defaultMaxBodySize,
+		stampede:    true,
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	if cfg.store == nil {
+		cfg.store = NewMemoryStore(defaultMaxEntries)
+	}
+	return cfg
+}
+
+// WithStore sets the store of the cached responses, default is a MemoryStore keeping at most
+// 10000 entries. Share the store between the middlewares to purge the entries by Delete.
+func WithStore(s Store) Option {
+	return func(o *options) {
+		o.store = s
+	}
+}
+
+// WithTTL sets how long the responses are cached if they have no max-age or s-maxage
+// directive, default is 1 minute.
+func WithTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.ttl = ttl
+	}
+}
+
+// WithKeyFunc sets how the cache key is derived from a request, default is DefaultKey.
+func WithKeyFunc(f KeyFunc) Option {
+	return func(o *options) {
+		o.keyFunc = f
+	}
+}
+
+// WithMethods sets the methods of the requests to cache, default is GET and HEAD.
+func WithMethods(methods ...string) Option {
+	return func(o *options) {
+		o.methods = make(map[string]bool, len(methods))
+		for _, m := range methods {
+			o.methods[m] = true
+		}
+	}
+}
+
+// WithStatusCodes sets the status codes of the responses to cache, default is 200.
+func WithStatusCodes(codes ...int) Option {
+	return func(o *options) {
+		o.statusCodes = make(map[int]bool, len(codes))
+		for _, c := range codes {
+			o.statusCodes[c] = true
+		}
+	}
+}
+
+// WithMaxBodySize sets the max size of the response body to cache, default is 1MB.
+func WithMaxBodySize(size int) Option {
+	return func(o *options) {
+		o.maxBodySize = size
+	}
+}
+
+// WithRequestDirectives sets whether the Cache-Control directives of the requests are respected:
+// no-cache and max-age=0 skip the cached response and no-store skips saving the response.
+// It is disabled by default, so that the clients can't bypass the cache to overload the server.
+func WithRequestDirectives(enable bool) Option {
+	return func(o *options) {
+		o.requestDirectives = enable
+	}
+}
+
+// WithStampedeProtection sets whether only one of the concurrent requests missing the cache
+// with the same key is handled, the others wait for it and are served from the cache.
+// It is enabled by default.
+func WithStampedeProtection(enable bool) Option {
+	return func(o *options) {
+		o.stampede = enable
+	}
+}
@@ -0,0 +1,126 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Entry is a response saved in the Store.
+type Entry struct {
+	StatusCode int
+	Header     [][2]string
+	Body       []byte
+	// Vary is the names of the request headers the response varies by. An entry with Vary
+	// has no response, the variants are saved under the keys extended by the header values.
+	Vary []string
+	// StoredAt is when the response is saved, which is used to compute the Age header.
+	StoredAt time.Time
+}
+
+// Store saves the cached responses, it must be safe for concurrent use.
+type Store interface {
+	// Get returns the entry of key, or nil if there is none or it has expired.
+	Get(key string) *Entry
+	// Set saves the entry of key for ttl.
+	Set(key string, e *Entry, ttl time.Duration)
+	// Delete removes the entry of key, e.g. to purge the response after a mutation.
+	Delete(key string)
+}
+
+type memoryEntry struct {
+	key      string
+	expireAt time.Time
+	entry    *Entry
+}
+
+// MemoryStore is a Store keeping the entries in memory until they expire, the least recently
+// used entries are evicted when the count of entries exceeds the limit.
+type MemoryStore struct {
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// the entries ordered by the time they are used, the most recently used one is at the front
+	lru *list.List
+}
+
+// NewMemoryStore creates a MemoryStore keeping at most maxEntries entries if maxEntries is positive.
+func NewMemoryStore(maxEntries int) *MemoryStore {
+	return &MemoryStore{
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+// Get implements Store.
+func (s *MemoryStore) Get(key string) *Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	el, ok := s.entries[key]
+	if !ok {
+		return nil
+	}
+	e := el.Value.(*memoryEntry)
+	if !time.Now().Before(e.expireAt) {
+		s.remove(el)
+		return nil
+	}
+	s.lru.MoveToFront(el)
+	return e.entry
+}
+
+// Set implements Store.
+func (s *MemoryStore) Set(key string, entry *Entry, ttl time.Duration) {
+	expireAt := time.Now().Add(ttl)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.entries[key]; ok {
+		e := el.Value.(*memoryEntry)
+		e.expireAt, e.entry = expireAt, entry
+		s.lru.MoveToFront(el)
+		return
+	}
+	s.entries[key] = s.lru.PushFront(&memoryEntry{key: key, expireAt: expireAt, entry: entry})
+	for s.maxEntries > 0 && s.lru.Len() > s.maxEntries {
+		s.remove(s.lru.Back())
+	}
+}
+
+// Delete implements Store.
+func (s *MemoryStore) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.entries[key]; ok {
+		s.remove(el)
+	}
+}
+
+// Len returns the count of the entries in the store, including the expired ones not evicted yet.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+func (s *MemoryStore) remove(el *list.Element) {
+	s.lru.Remove(el)
+	delete(s.entries, el.Value.(*memoryEntry).key)
+}
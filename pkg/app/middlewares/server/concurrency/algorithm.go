@@ -0,0 +1,114 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package concurrency
+
+import (
+	"math"
+	"time"
+)
+
+// Sample is the measurement of the requests completed in a window, which updates the limit.
+type Sample struct {
+	// RTT is the average latency of the requests.
+	RTT time.Duration
+	// MinRTT is the minimum latency of the requests.
+	MinRTT time.Duration
+	// MaxInFlight is the maximum count of the in-flight requests when the requests are admitted.
+	MaxInFlight int
+	// Count is the count of the requests.
+	Count int
+	// Dropped is the count of the requests failed by overload, i.e. responded 503 or 504, or
+	// their deadline is exceeded.
+	Dropped int
+}
+
+// Algorithm computes the concurrency limit from the samples of the windows, it's called
+// sequentially and the result is clamped by the range set by WithLimitRange.
+type Algorithm interface {
+	Update(limit float64, s Sample) float64
+}
+
+const (
+	// longRTTAlpha is the weight of a higher minimum latency of a window in the long-term one
+	longRTTAlpha = 0.05
+	// gradientSmoothing is the weight of the new limit, which smooths the changes of the limit
+	gradientSmoothing = 0.2
+)
+
+type gradient struct {
+	tolerance float64
+	longRTT   float64
+}
+
+// NewGradient returns the gradient algorithm, which compares the average latency of the window
+// with the long-term minimum latency of the windows, i.e. the latency without queuing: the
+// limit shrinks by at most half as the latency grows, which means the requests are queuing,
+// and grows by the square root of the limit otherwise. The latency up to tolerance times the
+// long-term one isn't regarded as queuing, tolerance is 1.5 if it's < 1.
+func NewGradient(tolerance float64) Algorithm {
+	if tolerance < 1 {
+		tolerance = 1.5
+	}
+	return &gradient{tolerance: tolerance}
+}
+
+func (g *gradient) Update(limit float64, s Sample) float64 {
+	short := float64(s.RTT)
+	if short <= 0 {
+		return limit
+	}
+	// follow a lower latency at once, which is the latency without queuing, and a higher one
+	// slowly, e.g. after the handlers become slower
+	if minRTT := float64(s.MinRTT); g.longRTT == 0 || minRTT < g.longRTT {
+		g.longRTT = minRTT
+	} else {
+		g.longRTT = g.longRTT*(1-longRTTAlpha) + minRTT*longRTTAlpha
+	}
+	// the limit isn't used up, growing it tells nothing
+	if float64(s.MaxInFlight) < limit/2 {
+		return limit
+	}
+	grad := math.Max(0.5, math.Min(1, g.tolerance*g.longRTT/short))
+	newLimit := limit*grad + math.Sqrt(limit)
+	return limit*(1-gradientSmoothing) + newLimit*gradientSmoothing
+}
+
+type aimd struct {
+	threshold time.Duration
+	backoff   float64
+}
+
+// NewAIMD returns the additive-increase/multiplicative-decrease algorithm: the limit is
+// multiplied by backoff if the average latency exceeds threshold or any request is dropped,
+// and increased by 1 otherwise if at least half of it is used. backoff is 0.9 if it's not in
+// (0, 1).
+func NewAIMD(threshold time.Duration, backoff float64) Algorithm {
+	if backoff <= 0 || backoff >= 1 {
+		backoff = 0.9
+	}
+	return &aimd{threshold: threshold, backoff: backoff}
+}
+
+func (a *aimd) Update(limit float64, s Sample) float64 {
+	if s.Dropped > 0 || (a.threshold > 0 && s.RTT > a.threshold) {
+		return limit * a.backoff
+	}
+	if float64(s.MaxInFlight) >= limit/2 {
+		return limit + 1
+	}
+	return limit
+}
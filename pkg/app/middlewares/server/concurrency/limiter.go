@@ -0,0 +1,158 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"hertz-study/pkg/app"
+	"hertz-study/pkg/app/middlewares/server/priority"
+	"hertz-study/pkg/protocol/consts"
+)
+
+// Stats is the statistics of a Limiter.
+type Stats struct {
+	Limit    int   `json:"limit"`
+	InFlight int64 `json:"in_flight"`
+	// Rejected is the count of the rejected requests.
+	Rejected uint64 `json:"rejected"`
+}
+
+// Limiter limits the count of the in-flight requests by a limit adapted to the latency, so that
+// the requests over the capacity of the server are rejected rather than queued, which protects
+// the latency of the admitted ones under overload.
+type Limiter struct {
+	opts *options
+
+	inFlight int64
+	rejected uint64
+	// limitN is the integer part of limit, which is read by every request
+	limitN int64
+
+	mu     sync.Mutex
+	limit  float64
+	window window
+}
+
+// window accumulates the sample of the requests completed since start.
+type window struct {
+	start       time.Time
+	sum         time.Duration
+	min         time.Duration
+	count       int
+	maxInFlight int
+	dropped     int
+}
+
+// New creates a Limiter, use Middleware to limit the requests. Load can be passed to
+// loadshed.WithLoadFunc to shed the requests by priority before the limit is reached.
+func New(opts ...Option) *Limiter {
+	l := &Limiter{opts: newOptions(opts...)}
+	l.setLimit(float64(l.opts.initialLimit))
+	l.window.start = time.Now()
+	return l
+}
+
+// Middleware returns a middleware which rejects the request if the in-flight requests reach the
+// limit, the requests of priority.Critical are always admitted.
+func (l *Limiter) Middleware() app.HandlerFunc {
+	return func(c context.Context, ctx *app.RequestContext) {
+		n := atomic.AddInt64(&l.inFlight, 1)
+		defer atomic.AddInt64(&l.inFlight, -1)
+		if n > atomic.LoadInt64(&l.limitN) && priority.Get(ctx) != priority.Critical {
+			atomic.AddUint64(&l.rejected, 1)
+			l.opts.onReject(c, ctx)
+			ctx.Abort()
+			return
+		}
+
+		start := time.Now()
+		ctx.Next(c)
+		now := time.Now()
+		status := ctx.Response.StatusCode()
+		dropped := status == consts.StatusServiceUnavailable || status == consts.StatusGatewayTimeout ||
+			errors.Is(c.Err(), context.DeadlineExceeded)
+		l.record(now, now.Sub(start), int(n), dropped)
+	}
+}
+
+// Limit returns the current limit.
+func (l *Limiter) Limit() int {
+	return int(atomic.LoadInt64(&l.limitN))
+}
+
+// Load returns the ratio of the in-flight requests to the limit, where 1 is the full load.
+func (l *Limiter) Load() float64 {
+	return float64(atomic.LoadInt64(&l.inFlight)) / float64(atomic.LoadInt64(&l.limitN))
+}
+
+// Stats returns the current statistics.
+func (l *Limiter) Stats() Stats {
+	return Stats{
+		Limit:    l.Limit(),
+		InFlight: atomic.LoadInt64(&l.inFlight),
+		Rejected: atomic.LoadUint64(&l.rejected),
+	}
+}
+
+func (l *Limiter) record(now time.Time, rtt time.Duration, inFlight int, dropped bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	w := &l.window
+	if w.count == 0 || rtt < w.min {
+		w.min = rtt
+	}
+	w.sum += rtt
+	w.count++
+	if inFlight > w.maxInFlight {
+		w.maxInFlight = inFlight
+	}
+	if dropped {
+		w.dropped++
+	}
+	if w.count < l.opts.minSamples || now.Sub(w.start) < l.opts.window {
+		return
+	}
+
+	l.setLimit(l.opts.algorithm.Update(l.limit, Sample{
+		RTT:         w.sum / time.Duration(w.count),
+		MinRTT:      w.min,
+		MaxInFlight: w.maxInFlight,
+		Count:       w.count,
+		Dropped:     w.dropped,
+	}))
+	*w = window{start: now}
+}
+
+// setLimit sets the limit clamped by the range, it's called with mu held.
+func (l *Limiter) setLimit(limit float64) {
+	if hi := float64(l.opts.maxLimit); l.opts.maxLimit > 0 && limit > hi {
+		limit = hi
+	}
+	if lo := float64(l.opts.minLimit); limit < lo {
+		limit = lo
+	}
+	if limit < 1 {
+		limit = 1
+	}
+	l.limit = limit
+	atomic.StoreInt64(&l.limitN, int64(limit))
+}
@@ -0,0 +1,112 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package concurrency
+
+import (
+	"context"
+	"time"
+
+	"hertz-study/pkg/app"
+	"hertz-study/pkg/protocol/consts"
+)
+
+const (
+	defaultInitialLimit = 20
+	defaultMinLimit     = 1
+	defaultMaxLimit     = 1000
+	defaultWindow       = 500 * time.Millisecond
+	defaultMinSamples   = 10
+)
+
+type (
+	options struct {
+		algorithm    Algorithm
+		initialLimit int
+		minLimit     int
+		maxLimit     int
+		window       time.Duration
+		minSamples   int
+		onReject     app.HandlerFunc
+	}
+
+	Option func(o *options)
+)
+
+func newOptions(opts ...Option) *options {
+	cfg := &options{
+		initialLimit: defaultInitialLimit,
+		minLimit:     defaultMinLimit,
+		maxLimit:     defaultMaxLimit,
+		window:       defaultWindow,
+		minSamples:   defaultMinSamples,
+		onReject:     defaultOnReject,
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	if cfg.algorithm == nil {
+		cfg.algorithm = NewGradient(0)
+	}
+
+	return cfg
+}
+
+func defaultOnReject(c context.Context, ctx *app.RequestContext) {
+	ctx.AbortWithMsg("Service Unavailable", consts.StatusServiceUnavailable)
+	ctx.Response.Header.Set(consts.HeaderRetryAfter, "1")
+}
+
+// WithAlgorithm sets the algorithm adapting the limit, default is NewGradient(1.5).
+func WithAlgorithm(a Algorithm) Option {
+	return func(o *options) {
+		o.algorithm = a
+	}
+}
+
+// WithInitialLimit sets the limit before it's adapted, default is 20.
+func WithInitialLimit(n int) Option {
+	return func(o *options) {
+		o.initialLimit = n
+	}
+}
+
+// WithLimitRange sets the range the limit is adapted within, default is [1, 1000].
+func WithLimitRange(minLimit, maxLimit int) Option {
+	return func(o *options) {
+		o.minLimit = minLimit
+		o.maxLimit = maxLimit
+	}
+}
+
+// WithWindow sets the window sampling the requests, the limit is updated when a window lasts
+// at least d and has at least minSamples requests. The default is 500ms and 10 requests.
+func WithWindow(d time.Duration, minSamples int) Option {
+	return func(o *options) {
+		o.window = d
+		o.minSamples = minSamples
+	}
+}
+
+// WithOnReject sets the handler responding the rejected requests, which responds 503 with
+// "Retry-After: 1" by default.
+func WithOnReject(h app.HandlerFunc) Option {
+	return func(o *options) {
+		o.onReject = h
+	}
+}
@@ -0,0 +1,65 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package deadline
+
+import (
+	"context"
+	"time"
+
+	"hertz-study/pkg/app"
+)
+
+// New returns a middleware which sets the deadline of the context passed to the following
+// handlers by the timeout carried by the request headers, so that the calls made with the
+// context inherit the remaining budget of the caller, e.g. by the client deadline middleware.
+// The requests whose budget is already used up are responded by the handler set by WithOnExpired.
+func New(opts ...Option) app.HandlerFunc {
+	o := newOptions(opts...)
+	return func(c context.Context, ctx *app.RequestContext) {
+		timeout, ok := o.timeout(ctx)
+		if !ok {
+			ctx.Next(c)
+			return
+		}
+		if timeout <= 0 {
+			o.onExpired(c, ctx)
+			ctx.Abort()
+			return
+		}
+		c, cancel := context.WithTimeout(c, timeout)
+		defer cancel()
+		ctx.Next(c)
+	}
+}
+
+// timeout returns the timeout of the request, ok is false if it has no timeout.
+func (o *options) timeout(ctx *app.RequestContext) (timeout time.Duration, ok bool) {
+	for _, s := range o.sources {
+		if v := ctx.Request.Header.Peek(s.header); len(v) > 0 {
+			if timeout, ok = s.parse(string(v)); ok {
+				break
+			}
+		}
+	}
+	if !ok {
+		timeout, ok = o.defaultTimeout, o.defaultTimeout > 0
+	}
+	if ok && o.maxTimeout > 0 && timeout > o.maxTimeout {
+		timeout = o.maxTimeout
+	}
+	return timeout, ok
+}
@@ -0,0 +1,152 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package deadline
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"hertz-study/pkg/app"
+	"hertz-study/pkg/protocol/consts"
+)
+
+const (
+	// DefaultHeader carries the timeout of the request in milliseconds.
+	DefaultHeader = "X-Request-Timeout-Ms"
+	// GRPCTimeoutHeader carries the timeout of the request in the format of gRPC, e.g. "100m"
+	// for 100 milliseconds.
+	GRPCTimeoutHeader = "Grpc-Timeout"
+)
+
+type (
+	options struct {
+		sources        []source
+		maxTimeout     time.Duration
+		defaultTimeout time.Duration
+		onExpired      app.HandlerFunc
+	}
+
+	Option func(o *options)
+
+	// Parser parses the timeout carried by a header, ok is false if value is invalid.
+	Parser func(value string) (timeout time.Duration, ok bool)
+
+	source struct {
+		header string
+		parse  Parser
+	}
+)
+
+func newOptions(opts ...Option) *options {
+	cfg := &options{
+		onExpired: defaultOnExpired,
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	if len(cfg.sources) == 0 {
+		cfg.sources = []source{
+			{header: DefaultHeader, parse: ParseMilliseconds},
+			{header: GRPCTimeoutHeader, parse: ParseGRPCTimeout},
+		}
+	}
+
+	return cfg
+}
+
+func defaultOnExpired(c context.Context, ctx *app.RequestContext) {
+	ctx.AbortWithMsg("Gateway Timeout", consts.StatusGatewayTimeout)
+}
+
+// WithHeader adds a header carrying the timeout and its parser, the headers are looked up in the
+// order they are added. The defaults are DefaultHeader with ParseMilliseconds and
+// GRPCTimeoutHeader with ParseGRPCTimeout, which are replaced by the headers added.
+func WithHeader(header string, parse Parser) Option {
+	return func(o *options) {
+		o.sources = append(o.sources, source{header: header, parse: parse})
+	}
+}
+
+// WithMaxTimeout caps the timeouts carried by the requests, no cap by default.
+func WithMaxTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.maxTimeout = d
+	}
+}
+
+// WithDefaultTimeout sets the timeout of the requests without a valid timeout header,
+// no timeout by default.
+func WithDefaultTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.defaultTimeout = d
+	}
+}
+
+// WithOnExpired sets the handler responding the requests whose budget is already used up by the
+// callers, e.g. a timeout of 0, which responds 504 by default.
+func WithOnExpired(h app.HandlerFunc) Option {
+	return func(o *options) {
+		o.onExpired = h
+	}
+}
+
+// ParseMilliseconds parses a timeout in milliseconds, e.g. "1500".
+func ParseMilliseconds(value string) (time.Duration, bool) {
+	ms, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || ms < 0 || ms > int64(maxDuration/time.Millisecond) {
+		return 0, false
+	}
+	return time.Duration(ms) * time.Millisecond, true
+}
+
+// ParseGRPCTimeout parses a timeout in the format of gRPC, which is at most 8 digits followed by
+// a unit of "H", "M", "S", "m", "u" or "n", e.g. "100m".
+func ParseGRPCTimeout(value string) (time.Duration, bool) {
+	if len(value) < 2 || len(value) > 9 {
+		return 0, false
+	}
+	var unit time.Duration
+	switch value[len(value)-1] {
+	case 'H':
+		unit = time.Hour
+	case 'M':
+		unit = time.Minute
+	case 'S':
+		unit = time.Second
+	case 'm':
+		unit = time.Millisecond
+	case 'u':
+		unit = time.Microsecond
+	case 'n':
+		unit = time.Nanosecond
+	default:
+		return 0, false
+	}
+	n, err := strconv.ParseUint(value[:len(value)-1], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	if time.Duration(n) > maxDuration/unit {
+		return maxDuration, true
+	}
+	return time.Duration(n) * unit, true
+}
+
+const maxDuration = time.Duration(1<<63 - 1)
@@ -0,0 +1,97 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package deprecation
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"hertz-study/pkg/app"
+)
+
+// Policy describes the deprecation of an API version.
+type Policy struct {
+	// Sunset is when the API stops working, zero if it is not scheduled yet.
+	Sunset time.Time
+	// Link points to the migration guide, e.g. the changelog of the new version.
+	Link string
+}
+
+// VersionFunc returns the API version of a request, empty if the request has no version.
+type VersionFunc func(c context.Context, ctx *app.RequestContext) string
+
+type (
+	options struct {
+		versionFunc VersionFunc
+	}
+
+	Option func(o *options)
+)
+
+// WithVersionFunc sets how the API version of a request is told, default is PathVersion.
+func WithVersionFunc(f VersionFunc) Option {
+	return func(o *options) {
+		o.versionFunc = f
+	}
+}
+
+// Deprecate marks every response of the routes as deprecated by ctx.Deprecated, e.g.
+//
+//	h.GET("/orders/list", deprecation.Deprecate(sunset, "https://example.com/migrate"), handler)
+func Deprecate(sunset time.Time, link string) app.HandlerFunc {
+	return func(c context.Context, ctx *app.RequestContext) {
+		ctx.Deprecated(sunset, link)
+		ctx.Next(c)
+	}
+}
+
+// New marks the responses as deprecated by the policy of their API version, the requests of
+// the versions not in policies are left untouched, e.g.
+//
+//	h.Use(deprecation.New(map[string]deprecation.Policy{"v1": {Sunset: sunset}}))
+func New(policies map[string]Policy, opts ...Option) app.HandlerFunc {
+	o := &options{versionFunc: PathVersion}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return func(c context.Context, ctx *app.RequestContext) {
+		if p, ok := policies[o.versionFunc(c, ctx)]; ok {
+			ctx.Deprecated(p.Sunset, p.Link)
+		}
+		ctx.Next(c)
+	}
+}
+
+// PathVersion returns the first path segment as the version if it looks like "v1" or "v2beta".
+func PathVersion(c context.Context, ctx *app.RequestContext) string {
+	path := strings.TrimPrefix(string(ctx.Path()), "/")
+	if i := strings.IndexByte(path, '/'); i >= 0 {
+		path = path[:i]
+	}
+	if len(path) < 2 || path[0] != 'v' || path[1] < '0' || path[1] > '9' {
+		return ""
+	}
+	return path
+}
+
+// HeaderVersion returns a VersionFunc reading the version from the request header.
+func HeaderVersion(header string) VersionFunc {
+	return func(c context.Context, ctx *app.RequestContext) string {
+		return string(ctx.Request.Header.Peek(header))
+	}
+}
@@ -0,0 +1,157 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package digest_auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"strconv"
+	"strings"
+
+	"hertz-study/pkg/app"
+	"hertz-study/pkg/app/middlewares/server/basic_auth"
+	"hertz-study/pkg/protocol/consts"
+)
+
+// New returns a Digest HTTP Authorization middleware of RFC 7616 with qop "auth" for realm,
+// the credentials are checked against the accounts, which may be nil, and then the password
+// function set by WithPasswordFunc. The nonces expire after the TTL set by WithNonceTTL and
+// the nonce counts must increase to prevent the replays. The authenticated user is set to the
+// context by the key set by WithUserKey, e.g.
+//
+//	debug := h.Group("/debug", digest_auth.New(basic_auth.Accounts{"admin": secret}, "debug"))
+func New(accounts basic_auth.Accounts, realm string, opts ...Option) app.HandlerFunc {
+	o := newOptions(opts...)
+	if realm == "" {
+		realm = "Authorization Required"
+	}
+	newHash := md5.New
+	if strings.EqualFold(o.algorithm, AlgorithmSHA256) {
+		o.algorithm, newHash = AlgorithmSHA256, sha256.New
+	} else {
+		o.algorithm = AlgorithmMD5
+	}
+	var b [16]byte
+	rand.Read(b[:]) //nolint:errcheck
+	opaque := hex.EncodeToString(b[:])
+	challenge := "Digest realm=" + strconv.Quote(realm) + `, qop="auth", algorithm=` + o.algorithm +
+		`, opaque="` + opaque + `", nonce="`
+	ns := newNonces(o.nonceTTL, o.maxNonces)
+
+	return func(c context.Context, ctx *app.RequestContext) {
+		params, ok := parseDigestAuth(ctx.Request.Header.Peek(consts.HeaderAuthorization))
+		stale := false
+		if ok {
+			ok, stale = o.verify(c, ctx, accounts, params, realm, opaque, newHash, ns)
+		}
+		if !ok {
+			h := challenge + ns.issue() + `"`
+			if stale {
+				h += ", stale=true"
+			}
+			ctx.Header(consts.HeaderWWWAuthenticate, h)
+			ctx.AbortWithStatus(consts.StatusUnauthorized)
+			return
+		}
+		ctx.Set(o.userKey, params["username"])
+	}
+}
+
+// verify checks the credentials of the request, stale is true if they are valid except the nonce.
+func (o *options) verify(c context.Context, ctx *app.RequestContext, accounts basic_auth.Accounts, params map[string]string,
+	realm, opaque string, newHash func() hash.Hash, ns *nonces,
+) (ok, stale bool) {
+	if params["realm"] != realm || params["opaque"] != opaque || params["qop"] != "auth" ||
+		params["uri"] != string(ctx.Request.RequestURI()) {
+		return false, false
+	}
+	if alg, found := params["algorithm"]; found && !strings.EqualFold(alg, o.algorithm) ||
+		!found && o.algorithm != AlgorithmMD5 {
+		return false, false
+	}
+	nc, err := strconv.ParseUint(params["nc"], 16, 64)
+	if err != nil || params["cnonce"] == "" {
+		return false, false
+	}
+
+	user := params["username"]
+	password, found := accounts.Password(c, user, o.passwordFunc)
+	h := func(s ...string) string {
+		d := newHash()
+		d.Write([]byte(strings.Join(s, ":"))) //nolint:errcheck
+		return hex.EncodeToString(d.Sum(nil))
+	}
+	ha1 := h(user, realm, password)
+	ha2 := h(string(ctx.Method()), params["uri"])
+	expected := h(ha1, params["nonce"], params["nc"], params["cnonce"], params["qop"], ha2)
+	// compare even if the user is not found to not reveal whether it exists by the timing
+	if !basic_auth.SecureCompare(params["response"], expected) || !found {
+		return false, false
+	}
+	// the nonce is only used after the credentials are verified, so that it can't be consumed by others
+	return ns.use(params["nonce"], nc)
+}
+
+// parseDigestAuth parses the parameters of an Authorization header of the Digest scheme.
+func parseDigestAuth(auth []byte) (map[string]string, bool) {
+	const prefix = "Digest "
+	if len(auth) < len(prefix) || !bytes.EqualFold(auth[:len(prefix)], []byte(prefix)) {
+		return nil, false
+	}
+	s := string(auth[len(prefix):])
+	params := make(map[string]string, 10)
+	for {
+		s = strings.TrimLeft(s, " \t,")
+		if s == "" {
+			break
+		}
+		i := strings.IndexByte(s, '=')
+		if i <= 0 {
+			return nil, false
+		}
+		key := strings.ToLower(strings.TrimSpace(s[:i]))
+		s = strings.TrimLeft(s[i+1:], " \t")
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			var b strings.Builder
+			j := 1
+			for ; j < len(s) && s[j] != '"'; j++ {
+				if s[j] == '\\' && j+1 < len(s) {
+					j++
+				}
+				b.WriteByte(s[j])
+			}
+			if j >= len(s) {
+				return nil, false
+			}
+			value, s = b.String(), s[j+1:]
+		} else {
+			j := strings.IndexByte(s, ',')
+			if j < 0 {
+				j = len(s)
+			}
+			value, s = strings.TrimSpace(s[:j]), s[j:]
+		}
+		params[key] = value
+	}
+	return params, true
+}
@@ -0,0 +1,97 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package digest_auth
+
+import (
+	"container/list"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+type nonceEntry struct {
+	nonce    string
+	expireAt time.Time
+	// nc is the largest nonce count used, the requests with a smaller or equal one are replays
+	nc uint64
+}
+
+// nonces issues the nonces and checks their expiration and nonce counts.
+type nonces struct {
+	ttl time.Duration
+	max int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// the entries ordered by the time they are issued, which is also the order of expiration
+	order *list.List
+}
+
+func newNonces(ttl time.Duration, max int) *nonces {
+	return &nonces{
+		ttl:     ttl,
+		max:     max,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// issue returns a new nonce.
+func (n *nonces) issue() string {
+	var b [16]byte
+	rand.Read(b[:]) //nolint:errcheck
+	nonce := hex.EncodeToString(b[:])
+
+	now := time.Now()
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for el := n.order.Front(); el != nil; el = n.order.Front() {
+		if now.Before(el.Value.(*nonceEntry).expireAt) && (n.max <= 0 || n.order.Len() < n.max) {
+			break
+		}
+		n.remove(el)
+	}
+	n.entries[nonce] = n.order.PushBack(&nonceEntry{nonce: nonce, expireAt: now.Add(n.ttl)})
+	return nonce
+}
+
+// use records the nonce count nc of nonce, ok is true if the nonce is valid and nc is larger
+// than the ones used, stale is true if the nonce is unknown or has expired.
+func (n *nonces) use(nonce string, nc uint64) (ok, stale bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	el, found := n.entries[nonce]
+	if !found {
+		return false, true
+	}
+	e := el.Value.(*nonceEntry)
+	if !time.Now().Before(e.expireAt) {
+		n.remove(el)
+		return false, true
+	}
+	if nc <= e.nc {
+		return false, false
+	}
+	e.nc = nc
+	return true, false
+}
+
+func (n *nonces) remove(el *list.Element) {
+	n.order.Remove(el)
+	delete(n.entries, el.Value.(*nonceEntry).nonce)
+}
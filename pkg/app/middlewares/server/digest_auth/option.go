@@ -0,0 +1,98 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package digest_auth
+
+import (
+	"time"
+
+	"hertz-study/pkg/app/middlewares/server/basic_auth"
+)
+
+const (
+	// AlgorithmMD5 is the MD5 algorithm, which is supported by all the clients.
+	AlgorithmMD5 = "MD5"
+	// AlgorithmSHA256 is the SHA-256 algorithm of RFC 7616.
+	AlgorithmSHA256 = "SHA-256"
+
+	defaultUserKey   = "user"
+	defaultNonceTTL  = 5 * time.Minute
+	defaultMaxNonces = 100000
+)
+
+type (
+	options struct {
+		passwordFunc basic_auth.PasswordFunc
+		userKey      string
+		algorithm    string
+		nonceTTL     time.Duration
+		maxNonces    int
+	}
+
+	Option func(o *options)
+)
+
+func newOptions(opts ...Option) *options {
+	cfg := &options{
+		userKey:   defaultUserKey,
+		algorithm: AlgorithmMD5,
+		nonceTTL:  defaultNonceTTL,
+		maxNonces: defaultMaxNonces,
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return cfg
+}
+
+// WithPasswordFunc sets the function to look up the passwords of the users not in the accounts.
+func WithPasswordFunc(f basic_auth.PasswordFunc) Option {
+	return func(o *options) {
+		o.passwordFunc = f
+	}
+}
+
+// WithUserKey sets the key of the authenticated user set to the context, default is "user".
+func WithUserKey(key string) Option {
+	return func(o *options) {
+		o.userKey = key
+	}
+}
+
+// WithAlgorithm sets the hash algorithm, AlgorithmMD5 or AlgorithmSHA256, default is AlgorithmMD5.
+func WithAlgorithm(alg string) Option {
+	return func(o *options) {
+		o.algorithm = alg
+	}
+}
+
+// WithNonceTTL sets how long a nonce is valid, default is 5 minutes. The clients are challenged
+// with a new nonce marked as stale after it expires, which they use without prompting the user.
+func WithNonceTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.nonceTTL = ttl
+	}
+}
+
+// WithMaxNonces sets the max count of the nonces kept, default is 100000. The oldest nonces
+// are dropped if it is exceeded, which bounds the memory used by the unauthenticated requests.
+func WithMaxNonces(n int) Option {
+	return func(o *options) {
+		o.maxNonces = n
+	}
+}
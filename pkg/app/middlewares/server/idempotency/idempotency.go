@@ -0,0 +1,141 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"hertz-study/internal/headers"
+	"hertz-study/pkg/app"
+	"hertz-study/pkg/protocol/consts"
+)
+
+// ReplayedHeader is set to "true" on the responses replayed from the Store.
+const ReplayedHeader = "Idempotent-Replayed"
+
+// New returns a middleware which makes the requests of the unsafe methods idempotent by the
+// Idempotency-Key header: the response of the first request with a key is saved and replayed
+// to the retries with the same key within the TTL, so that the side effects are applied once.
+//
+//   - A retry arriving while the first request is being handled is rejected with 409.
+//   - A request reusing a key with a different method, path, query or body is rejected with 422.
+//   - The key is released if the handling panics, the status code is 5xx or the body can't be
+//     saved, i.e. it's a stream or larger than WithMaxBodySize, so that it can be retried.
+//
+// The requests without a key are handled as usual unless WithRequired is set.
+func New(opts ...Option) app.HandlerFunc {
+	o := newOptions(opts...)
+	return func(c context.Context, ctx *app.RequestContext) {
+		if !o.methods[string(ctx.Method())] {
+			ctx.Next(c)
+			return
+		}
+		key := ctx.Request.Header.Peek(o.keyHeader)
+		if len(key) == 0 {
+			if o.required {
+				ctx.AbortWithMsg("Missing "+o.keyHeader+" header", consts.StatusBadRequest)
+				return
+			}
+			ctx.Next(c)
+			return
+		}
+		if len(key) > defaultMaxKeyLen {
+			ctx.AbortWithMsg("Invalid "+o.keyHeader+" header", consts.StatusBadRequest)
+			return
+		}
+
+		storeKey := o.scopeFunc(c, ctx) + " " + string(key)
+		fp := fingerprint(ctx)
+		if rec := o.store.Reserve(storeKey, fp, o.ttl); rec != nil {
+			switch {
+			case rec.Fingerprint != fp:
+				ctx.AbortWithMsg(o.keyHeader+" is already used by a different request", consts.StatusUnprocessableEntity)
+			case rec.Response == nil:
+				ctx.AbortWithMsg("A request with the same "+o.keyHeader+" is being processed", consts.StatusConflict)
+				ctx.Response.Header.Set(consts.HeaderRetryAfter, "1")
+			default:
+				replay(ctx, rec.Response)
+			}
+			return
+		}
+
+		completed := false
+		defer func() {
+			if !completed {
+				o.store.Release(storeKey)
+			}
+		}()
+		ctx.Next(c)
+		if ctx.Response.StatusCode() >= consts.StatusInternalServerError {
+			return
+		}
+		// a record without the body would replay a truncated response, release the key instead
+		if resp := o.capture(ctx); resp != nil {
+			o.store.Complete(storeKey, resp)
+			completed = true
+		}
+	}
+}
+
+// DefaultScope scopes the idempotency keys by the method, the path and the Authorization header
+// of the request, so that the keys of different users don't conflict.
+func DefaultScope(c context.Context, ctx *app.RequestContext) string {
+	scope := string(ctx.Method()) + " " + string(ctx.Path())
+	if auth := ctx.Request.Header.Peek(consts.HeaderAuthorization); len(auth) > 0 {
+		sum := sha256.Sum256(auth)
+		scope += " " + hex.EncodeToString(sum[:8])
+	}
+	return scope
+}
+
+// fingerprint identifies the request by its method, URI and body.
+func fingerprint(ctx *app.RequestContext) string {
+	h := sha256.New()
+	h.Write(ctx.Method())
+	h.Write([]byte{0})
+	h.Write(ctx.Request.RequestURI())
+	h.Write([]byte{0})
+	h.Write(ctx.Request.Body())
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// capture returns the response to save, or nil if its body is a stream or too large.
+func (o *options) capture(ctx *app.RequestContext) *Response {
+	if ctx.Response.IsBodyStream() || len(ctx.Response.Body()) > o.maxBodySize {
+		return nil
+	}
+	resp := &Response{StatusCode: ctx.Response.StatusCode(), Body: append([]byte(nil), ctx.Response.Body()...)}
+	ctx.Response.Header.VisitAll(func(k, v []byte) {
+		switch string(k) {
+		case consts.HeaderContentLength, consts.HeaderDate, consts.HeaderServer, consts.HeaderConnection,
+			consts.HeaderTransferEncoding, consts.HeaderSetCookie:
+			return
+		}
+		resp.Header = append(resp.Header, [2]string{string(k), string(v)})
+	})
+	return resp
+}
+
+func replay(ctx *app.RequestContext, resp *Response) {
+	headers.Replay(&ctx.Response.Header, resp.Header)
+	ctx.Response.Header.Set(ReplayedHeader, "true")
+	ctx.Response.SetStatusCode(resp.StatusCode)
+	ctx.Response.SetBody(resp.Body)
+	ctx.Abort()
+}
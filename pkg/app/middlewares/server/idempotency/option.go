@@ -0,0 +1,128 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package idempotency
+
+import (
+	"context"
+	"time"
+
+	"hertz-study/pkg/app"
+	"hertz-study/pkg/protocol/consts"
+)
+
+const (
+	// DefaultKeyHeader is the standard header carrying the idempotency key of a request.
+	DefaultKeyHeader = "Idempotency-Key"
+
+	defaultTTL         = 24 * time.Hour
+	defaultMaxKeys     = 100000
+	defaultMaxKeyLen   = 255
+	defaultMaxBodySize = 64 * 1024
+)
+
+// ScopeFunc returns the scope of the idempotency keys of a request, the same key in different
+// scopes are independent.
+type ScopeFunc func(c context.Context, ctx *app.RequestContext) string
+
+type (
+	options struct {
+		keyHeader   string
+		store       Store
+		ttl         time.Duration
+		methods     map[string]bool
+		scopeFunc   ScopeFunc
+		required    bool
+		maxBodySize int
+	}
+
+	Option func(o *options)
+)
+
+func newOptions(opts ...Option) *options {
+	cfg := &options{
+		keyHeader:   DefaultKeyHeader,
+		ttl:         defaultTTL,
+		methods:     map[string]bool{consts.MethodPost: true, consts.MethodPatch: true},
+		scopeFunc:   DefaultScope,
+		maxBodySize: defaultMaxBodySize,
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	if cfg.store == nil {
+		cfg.store = NewMemoryStore(defaultMaxKeys)
+	}
+	return cfg
+}
+
+// WithKeyHeader sets the header to read the idempotency key from, default is DefaultKeyHeader.
+func WithKeyHeader(header string) Option {
+	return func(o *options) {
+		o.keyHeader = header
+	}
+}
+
+// WithStore sets the store of the idempotency keys and the responses, default is a MemoryStore
+// keeping at most 100000 keys. Use a shared store, e.g. on Redis, for multiple instances.
+func WithStore(s Store) Option {
+	return func(o *options) {
+		o.store = s
+	}
+}
+
+// WithTTL sets how long a key and its response are kept, default is 24 hours.
+func WithTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.ttl = ttl
+	}
+}
+
+// WithMethods sets the methods of the requests to check the idempotency key of,
+// default is POST and PATCH.
+func WithMethods(methods ...string) Option {
+	return func(o *options) {
+		o.methods = make(map[string]bool, len(methods))
+		for _, m := range methods {
+			o.methods[m] = true
+		}
+	}
+}
+
+// WithScopeFunc sets the scope of the idempotency keys, default is DefaultScope.
+func WithScopeFunc(f ScopeFunc) Option {
+	return func(o *options) {
+		o.scopeFunc = f
+	}
+}
+
+// WithRequired rejects the requests without an idempotency key with 400.
+func WithRequired(b bool) Option {
+	return func(o *options) {
+		o.required = b
+	}
+}
+
+// WithMaxBodySize sets the max size of the response body saved for replay, default is 64KB.
+// The keys of the responses with larger or streamed bodies are released instead of saved, so
+// that the retries are handled again.
+func WithMaxBodySize(size int) Option {
+	return func(o *options) {
+		o.maxBodySize = size
+	}
+}
@@ -0,0 +1,136 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package idempotency
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Response is a response saved in the Store for replay.
+type Response struct {
+	StatusCode int
+	Header     [][2]string
+	Body       []byte
+}
+
+// Record is the state of an idempotency key in the Store.
+type Record struct {
+	// Fingerprint identifies the request which used the key first.
+	Fingerprint string
+	// Response is the response of the request, nil while the request is being handled.
+	Response *Response
+}
+
+// Store saves the idempotency keys and the responses, it must be safe for concurrent use.
+type Store interface {
+	// Reserve saves key with fingerprint for ttl and returns nil if key is absent, or returns
+	// the record of key otherwise. It must be atomic, so that only one request gets nil.
+	Reserve(key, fingerprint string, ttl time.Duration) *Record
+	// Complete saves the response of key reserved by Reserve.
+	Complete(key string, resp *Response)
+	// Release removes key reserved by Reserve, e.g. if the request failed and may be retried.
+	Release(key string)
+}
+
+type memoryEntry struct {
+	key      string
+	expireAt time.Time
+	record   Record
+}
+
+// MemoryStore is a Store keeping the keys in memory until they expire, the oldest keys are
+// evicted when the count of keys exceeds the limit.
+type MemoryStore struct {
+	maxKeys int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// the entries ordered by the time they are reserved
+	order *list.List
+}
+
+// NewMemoryStore creates a MemoryStore keeping at most maxKeys keys if maxKeys is positive.
+func NewMemoryStore(maxKeys int) *MemoryStore {
+	return &MemoryStore{
+		maxKeys: maxKeys,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// Reserve implements Store.
+func (s *MemoryStore) Reserve(key, fingerprint string, ttl time.Duration) *Record {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.entries[key]; ok {
+		if e := el.Value.(*memoryEntry); now.Before(e.expireAt) {
+			r := e.record
+			return &r
+		}
+		s.remove(el)
+	}
+	s.evict(now)
+	s.entries[key] = s.order.PushBack(&memoryEntry{
+		key:      key,
+		expireAt: now.Add(ttl),
+		record:   Record{Fingerprint: fingerprint},
+	})
+	return nil
+}
+
+// Complete implements Store.
+func (s *MemoryStore) Complete(key string, resp *Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.entries[key]; ok {
+		el.Value.(*memoryEntry).record.Response = resp
+	}
+}
+
+// Release implements Store.
+func (s *MemoryStore) Release(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.entries[key]; ok {
+		s.remove(el)
+	}
+}
+
+// Len returns the count of the keys in the store.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// evict removes the expired keys and the oldest keys exceeding the limit before adding a key.
+func (s *MemoryStore) evict(now time.Time) {
+	for el := s.order.Front(); el != nil; el = s.order.Front() {
+		if now.Before(el.Value.(*memoryEntry).expireAt) && (s.maxKeys <= 0 || s.order.Len() < s.maxKeys) {
+			return
+		}
+		s.remove(el)
+	}
+}
+
+func (s *MemoryStore) remove(el *list.Element) {
+	s.order.Remove(el)
+	delete(s.entries, el.Value.(*memoryEntry).key)
+}
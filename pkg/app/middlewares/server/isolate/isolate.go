@@ -0,0 +1,142 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package isolate runs untrusted handlers, e.g. the ones of third-party plugins, in a
+// bounded goroutine pool on a copy of the request, so that their panics, hangs and leaks
+// are contained in the pool and can't take down the server.
+package isolate
+
+import (
+	"context"
+	"runtime/debug"
+	"sync/atomic"
+
+	"hertz-study/pkg/app"
+	"hertz-study/pkg/protocol/consts"
+)
+
+// Stats is the statistics of a Pool.
+type Stats struct {
+	// Running is the count of the running handlers, including the abandoned ones.
+	Running int64 `json:"running"`
+	// Abandoned is the count of the handlers still running after their budget is used up.
+	Abandoned int64  `json:"abandoned"`
+	Panics    uint64 `json:"panics"`
+	Timeouts  uint64 `json:"timeouts"`
+	Rejected  uint64 `json:"rejected"`
+}
+
+// Pool runs the wrapped handlers in isolation. Every handler runs in its own goroutine with
+// a copy of the RequestContext and a time budget. The response of the copy is copied back
+// if the handler finishes within the budget, otherwise the request is responded by the
+// timeout handler and the handler is abandoned while still holding its slot of the pool,
+// so a runaway plugin can only exhaust its own pool.
+//
+// Go can't preempt or limit the CPU time of a goroutine, the handlers should watch the
+// done channel of the context to stop early. The handlers must not hijack the connection
+// or stream the response since they only own a copy of the request.
+type Pool struct {
+	opts *options
+	sem  chan struct{}
+
+	running   int64
+	abandoned int64
+	panics    uint64
+	timeouts  uint64
+	rejected  uint64
+}
+
+// New creates a Pool, use Wrap to isolate the handlers.
+func New(opts ...Option) *Pool {
+	o := newOptions(opts...)
+	return &Pool{
+		opts: o,
+		sem:  make(chan struct{}, o.maxConcurrency),
+	}
+}
+
+// Wrap returns a handler which runs h in the pool, e.g.
+//
+//	plugins := isolate.New(isolate.WithTimeout(3 * time.Second))
+//	h.GET("/plugins/foo", plugins.Wrap(foo.Handle))
+func (p *Pool) Wrap(h app.HandlerFunc) app.HandlerFunc {
+	return func(c context.Context, ctx *app.RequestContext) {
+		select {
+		case p.sem <- struct{}{}:
+		default:
+			atomic.AddUint64(&p.rejected, 1)
+			p.opts.onReject(c, ctx)
+			ctx.Abort()
+			return
+		}
+
+		cc, cancel := context.WithTimeout(c, p.opts.timeout)
+		defer cancel()
+		cp := ctx.Copy()
+		done := make(chan bool, 1)
+		atomic.AddInt64(&p.running, 1)
+		go p.run(cc, cp, h, done)
+
+		select {
+		case ok := <-done:
+			if ok {
+				cp.Response.CopyTo(&ctx.Response)
+			} else {
+				ctx.AbortWithStatus(consts.StatusInternalServerError)
+			}
+		case <-cc.Done():
+			atomic.AddUint64(&p.timeouts, 1)
+			atomic.AddInt64(&p.abandoned, 1)
+			go func() {
+				<-done
+				atomic.AddInt64(&p.abandoned, -1)
+			}()
+			p.opts.onTimeout(c, ctx)
+			ctx.Abort()
+		}
+	}
+}
+
+// run runs h and reports whether it returns without panic.
+func (p *Pool) run(c context.Context, ctx *app.RequestContext, h app.HandlerFunc, done chan<- bool) {
+	ok := false
+	defer func() {
+		if err := recover(); err != nil {
+			atomic.AddUint64(&p.panics, 1)
+			stack := debug.Stack()
+			if report := ctx.PanicReporter(); report != nil {
+				report(c, err, stack)
+			}
+			p.opts.onPanic(c, err, stack)
+		}
+		atomic.AddInt64(&p.running, -1)
+		<-p.sem
+		done <- ok
+	}()
+	h(c, ctx)
+	ok = true
+}
+
+// Stats returns the statistics of the pool.
+func (p *Pool) Stats() Stats {
+	return Stats{
+		Running:   atomic.LoadInt64(&p.running),
+		Abandoned: atomic.LoadInt64(&p.abandoned),
+		Panics:    atomic.LoadUint64(&p.panics),
+		Timeouts:  atomic.LoadUint64(&p.timeouts),
+		Rejected:  atomic.LoadUint64(&p.rejected),
+	}
+}
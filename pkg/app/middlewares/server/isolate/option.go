@@ -0,0 +1,105 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package isolate
+
+import (
+	"context"
+	"time"
+
+	"hertz-study/pkg/app"
+	"hertz-study/pkg/common/hlog"
+	"hertz-study/pkg/protocol/consts"
+)
+
+type (
+	options struct {
+		maxConcurrency int
+		timeout        time.Duration
+		onReject       app.HandlerFunc
+		onTimeout      app.HandlerFunc
+		onPanic        func(c context.Context, err interface{}, stack []byte)
+	}
+
+	Option func(o *options)
+)
+
+func newOptions(opts ...Option) *options {
+	cfg := &options{
+		maxConcurrency: 64,
+		timeout:        10 * time.Second,
+		onReject:       defaultOnReject,
+		onTimeout:      defaultOnTimeout,
+		onPanic:        defaultOnPanic,
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return cfg
+}
+
+func defaultOnReject(c context.Context, ctx *app.RequestContext) {
+	ctx.AbortWithMsg("Service Unavailable", consts.StatusServiceUnavailable)
+}
+
+func defaultOnTimeout(c context.Context, ctx *app.RequestContext) {
+	ctx.AbortWithMsg("Gateway Timeout", consts.StatusGatewayTimeout)
+}
+
+func defaultOnPanic(c context.Context, err interface{}, stack []byte) {
+	hlog.SystemLogger().CtxErrorf(c, "[Isolate] err=%v\nstack=%s", err, stack)
+}
+
+// WithMaxConcurrency sets the max count of the handlers running in the pool, including the
+// abandoned ones, the requests exceeding it are rejected. Default is 64.
+func WithMaxConcurrency(n int) Option {
+	return func(o *options) {
+		o.maxConcurrency = n
+	}
+}
+
+// WithTimeout sets the time budget of every handler, default is 10s.
+func WithTimeout(t time.Duration) Option {
+	return func(o *options) {
+		o.timeout = t
+	}
+}
+
+// WithOnReject sets the handler responding the requests rejected by the full pool,
+// which responds 503 by default.
+func WithOnReject(h app.HandlerFunc) Option {
+	return func(o *options) {
+		o.onReject = h
+	}
+}
+
+// WithOnTimeout sets the handler responding the requests whose handler uses up the budget,
+// which responds 504 by default.
+func WithOnTimeout(h app.HandlerFunc) Option {
+	return func(o *options) {
+		o.onTimeout = h
+	}
+}
+
+// WithOnPanic sets the function reporting the panics of the handlers, the requests are
+// responded with 500. The panic and its stack are logged by default.
+func WithOnPanic(f func(c context.Context, err interface{}, stack []byte)) Option {
+	return func(o *options) {
+		o.onPanic = f
+	}
+}
@@ -0,0 +1,120 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package keyauth
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"hertz-study/pkg/app"
+)
+
+var (
+	// ErrMissingKey is passed to the ErrorHandler if no key is found in the request.
+	ErrMissingKey = errors.New("missing API key")
+	// ErrInvalidKey is returned by the Validator if the key is unknown or revoked.
+	ErrInvalidKey = errors.New("invalid API key")
+	// ErrForbidden is returned by the Validator if the key is valid but not allowed to access
+	// the request, which is responded 403 rather than 401 by default.
+	ErrForbidden = errors.New("API key is forbidden")
+)
+
+// Validator validates key and returns its metadata, e.g. the owner and the scopes of the key,
+// which is set to the context. A non-nil error fails the authentication and is passed to the
+// ErrorHandler, return ErrInvalidKey or ErrForbidden for the default responses.
+type Validator func(c context.Context, ctx *app.RequestContext, key string) (metadata interface{}, err error)
+
+type extractor func(ctx *app.RequestContext) string
+
+// New returns an API key authentication middleware, the key is looked up as set by
+// WithKeyLookup and validated by validator, e.g.
+//
+//	h.Use(keyauth.New(func(c context.Context, ctx *app.RequestContext, key string) (interface{}, error) {
+//		client, ok := clients[key]
+//		if !ok {
+//			return nil, keyauth.ErrInvalidKey
+//		}
+//		return client, nil
+//	}))
+//
+// It panics if the key lookup is invalid.
+func New(validator Validator, opts ...Option) app.HandlerFunc {
+	o := newOptions(opts...)
+	extractors, err := parseKeyLookup(o.keyLookup, o.authScheme)
+	if err != nil {
+		panic("keyauth: " + err.Error())
+	}
+	return func(c context.Context, ctx *app.RequestContext) {
+		var key string
+		for _, extract := range extractors {
+			if key = extract(ctx); key != "" {
+				break
+			}
+		}
+		if key == "" {
+			o.errorHandler(c, ctx, ErrMissingKey)
+			ctx.Abort()
+			return
+		}
+		metadata, err := validator(c, ctx, key)
+		if err != nil {
+			o.errorHandler(c, ctx, err)
+			ctx.Abort()
+			return
+		}
+		ctx.Set(o.contextKey, metadata)
+	}
+}
+
+func parseKeyLookup(lookup, scheme string) ([]extractor, error) {
+	var extractors []extractor
+	for _, l := range strings.Split(lookup, ",") {
+		source, name, ok := strings.Cut(strings.TrimSpace(l), ":")
+		if !ok || name == "" {
+			return nil, errors.New("invalid key lookup " + l)
+		}
+		switch source {
+		case "header":
+			extractors = append(extractors, headerExtractor(name, scheme))
+		case "query":
+			extractors = append(extractors, func(ctx *app.RequestContext) string {
+				return ctx.Query(name)
+			})
+		case "cookie":
+			extractors = append(extractors, func(ctx *app.RequestContext) string {
+				return string(ctx.Cookie(name))
+			})
+		default:
+			return nil, errors.New("unknown key lookup source " + source)
+		}
+	}
+	return extractors, nil
+}
+
+func headerExtractor(name, scheme string) extractor {
+	return func(ctx *app.RequestContext) string {
+		v := strings.TrimSpace(string(ctx.Request.Header.Peek(name)))
+		if scheme == "" {
+			return v
+		}
+		if len(v) <= len(scheme) || !strings.EqualFold(v[:len(scheme)], scheme) || v[len(scheme)] != ' ' {
+			return ""
+		}
+		return strings.TrimSpace(v[len(scheme)+1:])
+	}
+}
@@ -0,0 +1,121 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package keyauth
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"hertz-study/pkg/app"
+	"hertz-study/pkg/protocol/consts"
+)
+
+const (
+	// DefaultKeyLookup looks up the key from the X-API-Key header.
+	DefaultKeyLookup = "header:X-API-Key"
+	// DefaultContextKey is the key of the metadata of the API key set to the context.
+	DefaultContextKey = "keyauth"
+)
+
+type (
+	options struct {
+		keyLookup    string
+		authScheme   string
+		contextKey   string
+		realm        string
+		errorHandler ErrorHandler
+	}
+
+	Option func(o *options)
+
+	// ErrorHandler responds the requests failing the authentication, err is ErrMissingKey,
+	// ErrForbidden or the error returned by the Validator.
+	ErrorHandler func(c context.Context, ctx *app.RequestContext, err error)
+)
+
+func newOptions(opts ...Option) *options {
+	cfg := &options{
+		keyLookup:  DefaultKeyLookup,
+		contextKey: DefaultContextKey,
+		realm:      "Restricted",
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	if cfg.errorHandler == nil {
+		cfg.errorHandler = cfg.defaultErrorHandler
+	}
+
+	return cfg
+}
+
+// defaultErrorHandler responds 403 if err is ErrForbidden, and 401 with a WWW-Authenticate
+// challenge otherwise.
+func (o *options) defaultErrorHandler(c context.Context, ctx *app.RequestContext, err error) {
+	if errors.Is(err, ErrForbidden) {
+		ctx.AbortWithMsg("Forbidden", consts.StatusForbidden)
+		return
+	}
+	ctx.AbortWithMsg("Unauthorized", consts.StatusUnauthorized)
+	scheme := o.authScheme
+	if scheme == "" {
+		scheme = "ApiKey"
+	}
+	ctx.Response.Header.Set(consts.HeaderWWWAuthenticate, scheme+" realm="+strconv.Quote(o.realm))
+}
+
+// WithKeyLookup sets where the key is looked up, default is DefaultKeyLookup. It is a comma
+// separated list of "<source>:<name>" tried in order, where the source is "header", "query"
+// or "cookie", e.g. "header:X-API-Key,query:api_key".
+func WithKeyLookup(lookup string) Option {
+	return func(o *options) {
+		o.keyLookup = lookup
+	}
+}
+
+// WithAuthScheme sets the scheme prefixing the keys looked up from the headers, e.g. "Bearer"
+// for "header:Authorization". The scheme is also used in the WWW-Authenticate challenge.
+func WithAuthScheme(scheme string) Option {
+	return func(o *options) {
+		o.authScheme = scheme
+	}
+}
+
+// WithContextKey sets the key of the metadata returned by the Validator set to the context,
+// default is DefaultContextKey.
+func WithContextKey(key string) Option {
+	return func(o *options) {
+		o.contextKey = key
+	}
+}
+
+// WithRealm sets the realm of the WWW-Authenticate challenge, default is "Restricted".
+func WithRealm(realm string) Option {
+	return func(o *options) {
+		o.realm = realm
+	}
+}
+
+// WithErrorHandler sets the handler responding the requests failing the authentication.
+func WithErrorHandler(h ErrorHandler) Option {
+	return func(o *options) {
+		o.errorHandler = h
+	}
+}
@@ -0,0 +1,120 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loadshed
+
+import (
+	"context"
+	"sync/atomic"
+
+	"hertz-study/pkg/app"
+	"hertz-study/pkg/app/middlewares/server/priority"
+)
+
+// Stats is the statistics of a Shedder.
+type Stats struct {
+	InFlight int64 `json:"in_flight"`
+	// Load is the current load, where 1 is the full load.
+	Load float64 `json:"load"`
+	// MinPriority is the priority set by SetMinPriority.
+	MinPriority string `json:"min_priority"`
+	// Shed is the count of the shed requests by priority.
+	Shed map[string]uint64 `json:"shed"`
+}
+
+// Shedder sheds the requests of low priority first when the server is overloaded,
+// the priority of a request is given by priority.Get.
+type Shedder struct {
+	opts *options
+
+	inFlight    int64
+	minPriority int32
+	shed        [priority.Critical + 1]uint64
+}
+
+// New creates a Shedder, use Middleware to shed the requests.
+func New(opts ...Option) *Shedder {
+	return &Shedder{
+		opts:        newOptions(opts...),
+		minPriority: int32(priority.Batch),
+	}
+}
+
+// Middleware returns a middleware which sheds the request if the current load reaches the
+// threshold of its priority, or its priority is lower than the one set by SetMinPriority.
+func (s *Shedder) Middleware() app.HandlerFunc {
+	return func(c context.Context, ctx *app.RequestContext) {
+		p := priority.Get(ctx)
+		if !s.admit(p) {
+			if p >= priority.Batch && p <= priority.Critical {
+				atomic.AddUint64(&s.shed[p], 1)
+			}
+			s.opts.onShed(c, ctx)
+			ctx.Abort()
+			return
+		}
+
+		atomic.AddInt64(&s.inFlight, 1)
+		defer atomic.AddInt64(&s.inFlight, -1)
+		ctx.Next(c)
+	}
+}
+
+// SetMinPriority sheds all the requests of priority lower than p regardless of the load,
+// which is the manual switch during incidents, e.g. SetMinPriority(priority.Normal) sheds
+// the prefetch and batch traffic. SetMinPriority(priority.Batch) restores the default.
+func (s *Shedder) SetMinPriority(p priority.Priority) {
+	atomic.StoreInt32(&s.minPriority, int32(p))
+}
+
+// Load returns the current load, where 1 is the full load.
+func (s *Shedder) Load() float64 {
+	var load float64
+	if s.opts.maxInFlight > 0 {
+		load = float64(atomic.LoadInt64(&s.inFlight)) / float64(s.opts.maxInFlight)
+	}
+	if s.opts.loadFunc != nil {
+		if l := s.opts.loadFunc(); l > load {
+			load = l
+		}
+	}
+	return load
+}
+
+// Stats returns the current statistics.
+func (s *Shedder) Stats() Stats {
+	st := Stats{
+		InFlight:    atomic.LoadInt64(&s.inFlight),
+		Load:        s.Load(),
+		MinPriority: priority.Priority(atomic.LoadInt32(&s.minPriority)).String(),
+		Shed:        make(map[string]uint64, len(s.shed)),
+	}
+	for p := range s.shed {
+		st.Shed[priority.Priority(p).String()] = atomic.LoadUint64(&s.shed[p])
+	}
+	return st
+}
+
+func (s *Shedder) admit(p priority.Priority) bool {
+	if p < priority.Priority(atomic.LoadInt32(&s.minPriority)) {
+		return false
+	}
+	threshold, ok := s.opts.thresholds[p]
+	if !ok {
+		return true
+	}
+	return s.Load() < threshold
+}
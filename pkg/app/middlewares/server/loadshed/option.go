@@ -0,0 +1,98 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loadshed
+
+import (
+	"context"
+
+	"hertz-study/pkg/app"
+	"hertz-study/pkg/app/middlewares/server/priority"
+	"hertz-study/pkg/protocol/consts"
+)
+
+// defaultThresholds are the loads from which the requests of each priority are shed,
+// Critical is never shed by load.
+var defaultThresholds = map[priority.Priority]float64{
+	priority.Batch:  0.5,
+	priority.Low:    0.7,
+	priority.Normal: 0.9,
+	priority.High:   1,
+}
+
+type (
+	options struct {
+		maxInFlight int64
+		loadFunc    func() float64
+		thresholds  map[priority.Priority]float64
+		onShed      app.HandlerFunc
+	}
+
+	Option func(o *options)
+)
+
+func newOptions(opts ...Option) *options {
+	cfg := &options{
+		thresholds: make(map[priority.Priority]float64, len(defaultThresholds)),
+		onShed:     defaultOnShed,
+	}
+	for p, load := range defaultThresholds {
+		cfg.thresholds[p] = load
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return cfg
+}
+
+func defaultOnShed(c context.Context, ctx *app.RequestContext) {
+	ctx.AbortWithMsg("Service Unavailable", consts.StatusServiceUnavailable)
+	ctx.Response.Header.Set(consts.HeaderRetryAfter, "1")
+}
+
+// WithMaxInFlight sets the count of in-flight requests regarded as full load, i.e. the load
+// is the ratio of the in-flight requests to n. It is disabled by default.
+func WithMaxInFlight(n int) Option {
+	return func(o *options) {
+		o.maxInFlight = int64(n)
+	}
+}
+
+// WithLoadFunc sets the function returning an extra load signal, e.g. the CPU usage, where
+// 1 is the full load. The greater of it and the in-flight ratio is the load of the server.
+func WithLoadFunc(f func() float64) Option {
+	return func(o *options) {
+		o.loadFunc = f
+	}
+}
+
+// WithThreshold sets the load from which the requests of priority p are shed. The defaults
+// are 0.5 for Batch, 0.7 for Low, 0.9 for Normal, 1 for High, and Critical is never shed by load.
+func WithThreshold(p priority.Priority, load float64) Option {
+	return func(o *options) {
+		o.thresholds[p] = load
+	}
+}
+
+// WithOnShed sets the handler responding the shed requests, which responds 503 with
+// "Retry-After: 1" by default.
+func WithOnShed(h app.HandlerFunc) Option {
+	return func(o *options) {
+		o.onShed = h
+	}
+}
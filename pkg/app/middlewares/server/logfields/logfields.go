@@ -0,0 +1,67 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logfields
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+
+	"github.com/bytedance/gopkg/lang/fastrand"
+	"hertz-study/pkg/app"
+	"hertz-study/pkg/common/hlog"
+)
+
+// Field keys seeded by LogFields.
+const (
+	KeyRequestID = "request_id"
+	KeyMethod    = "method"
+	KeyPath      = "path"
+	KeyClientIP  = "client_ip"
+)
+
+// LogFields returns a middleware which seeds the standard fields of the request into the
+// context by hlog.ContextWithFields, so that the logs of the handlers and the downstream
+// libraries output by hlog.Ctx* functions carry them.
+func LogFields(opts ...Option) app.HandlerFunc {
+	cfg := newOptions(opts...)
+	return func(c context.Context, ctx *app.RequestContext) {
+		requestID := string(ctx.Request.Header.Peek(cfg.requestIDHeader))
+		if requestID == "" {
+			requestID = newRequestID()
+		}
+		ctx.Response.Header.Set(cfg.requestIDHeader, requestID)
+
+		c = hlog.ContextWithFields(c,
+			KeyRequestID, requestID,
+			KeyMethod, string(ctx.Method()),
+			KeyPath, string(ctx.Path()),
+			KeyClientIP, ctx.ClientIP(),
+		)
+		if cfg.extraFields != nil {
+			c = hlog.ContextWithFields(c, cfg.extraFields(c, ctx)...)
+		}
+		ctx.Next(c)
+	}
+}
+
+func newRequestID() string {
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], fastrand.Uint64())
+	binary.BigEndian.PutUint64(b[8:], fastrand.Uint64())
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,61 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logfields
+
+import (
+	"context"
+
+	"hertz-study/pkg/app"
+)
+
+const defaultRequestIDHeader = "X-Request-ID"
+
+type (
+	options struct {
+		requestIDHeader string
+		extraFields     func(c context.Context, ctx *app.RequestContext) []interface{}
+	}
+
+	Option func(o *options)
+)
+
+func newOptions(opts ...Option) *options {
+	cfg := &options{
+		requestIDHeader: defaultRequestIDHeader,
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return cfg
+}
+
+// WithRequestIDHeader sets the header to read the request id from and echo it back,
+// default is "X-Request-ID". A new request id is generated if the request has none.
+func WithRequestIDHeader(header string) Option {
+	return func(o *options) {
+		o.requestIDHeader = header
+	}
+}
+
+// WithExtraFields sets the function returning the extra key-value pairs to seed, e.g. the tenant id.
+func WithExtraFields(f func(c context.Context, ctx *app.RequestContext) []interface{}) Option {
+	return func(o *options) {
+		o.extraFields = f
+	}
+}
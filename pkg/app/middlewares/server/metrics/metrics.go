@@ -0,0 +1,187 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"time"
+
+	"hertz-study/pkg/app"
+	"hertz-study/pkg/network"
+	"hertz-study/pkg/protocol/consts"
+	"hertz-study/pkg/route"
+)
+
+// unmatchedRoute is the route label of requests which match no route, which keeps
+// the cardinality of labels bounded.
+const unmatchedRoute = "<unmatched>"
+
+// otherMethod is the method label of requests with non-standard methods, for the same reason.
+const otherMethod = "OTHER"
+
+const contentTypeText = "text/plain; version=0.0.4; charset=utf-8"
+
+// Collector collects the metrics of server requests and exposes them in prometheus text format.
+type Collector struct {
+	opts *options
+
+	requests     *metric
+	duration     *metric
+	inFlight     *metric
+	requestSize  *metric
+	responseSize *metric
+	writeStalls  *metric
+	stallTime    *metric
+}
+
+// NewCollector creates a Collector, use Middleware to collect metrics and Handler to expose them.
+// Register is recommended for most cases.
+func NewCollector(opts ...Option) *Collector {
+	cfg := newOptions(opts...)
+	ns := cfg.namespace + "_server_"
+	return &Collector{
+		opts:         cfg,
+		requests:     newMetric(ns+"requests_total", "Total number of requests handled by the server.", typeCounter, nil, "method", "route", "status"),
+		duration:     newMetric(ns+"request_duration_seconds", "Latency of requests handled by the server.", typeHistogram, cfg.durationBuckets, "method", "route", "status"),
+		inFlight:     newMetric(ns+"requests_in_flight", "Number of requests being handled by the server.", typeGauge, nil),
+		requestSize:  newMetric(ns+"request_size_bytes", "Size of request bodies.", typeHistogram, cfg.sizeBuckets, "method", "route"),
+		responseSize: newMetric(ns+"response_size_bytes", "Size of response bodies.", typeHistogram, cfg.sizeBuckets, "method", "route"),
+		writeStalls:  newMetric(ns+"write_stalls_total", "Total number of stalled response writes, timed_out is true if the connection is closed.", typeCounter, nil, "route", "timed_out"),
+		stallTime:    newMetric(ns+"write_stall_duration_seconds", "Duration of stalled response writes.", typeHistogram, cfg.durationBuckets, "route"),
+	}
+}
+
+// Register creates a Collector, installs its middleware on engine and mounts the metrics
+// handler on engine or the admin router given by WithAdminRouter.
+//
+// NOTE: it should be called before registering routes, since the middleware only applies to the
+// routes registered after it.
+func Register(engine route.IRoutes, opts ...Option) *Collector {
+	c := NewCollector(opts...)
+	engine.Use(c.Middleware())
+	if c.opts.disableHandler {
+		return c
+	}
+	if c.opts.adminRouter != nil {
+		c.opts.adminRouter.GET(c.opts.path, c.Handler())
+	} else {
+		engine.GET(c.opts.path, c.Handler())
+	}
+	return c
+}
+
+// Middleware returns a middleware which collects metrics of requests labeled by
+// method, route template and status code.
+func (c *Collector) Middleware() app.HandlerFunc {
+	return func(ctx context.Context, rc *app.RequestContext) {
+		if c.isMetricsRequest(rc) {
+			rc.Next(ctx)
+			return
+		}
+
+		gauge := c.inFlight.with()
+		gauge.add(1)
+		// deferred so that a panicking handler doesn't leak the in-flight request
+		defer gauge.add(-1)
+		start := time.Now()
+
+		rc.Next(ctx)
+
+		method := methodLabel(rc.Method())
+		routePath := rc.FullPath()
+		if routePath == "" {
+			routePath = unmatchedRoute
+		}
+		status := strconv.Itoa(rc.Response.StatusCode())
+
+		c.requests.with(method, routePath, status).add(1)
+		c.duration.with(method, routePath, status).observe(c.duration.buckets, time.Since(start).Seconds())
+
+		// the streamed request body is counted as far as it is read by the handlers
+		ioStats := rc.IOStats()
+		c.requestSize.with(method, routePath).observe(c.requestSize.buckets, float64(ioStats.BodyBytesRead))
+		var respSize int
+		switch {
+		case rc.Response.GetHijackWriter() != nil:
+			// the body is flushed by the handlers
+			respSize = ioStats.BytesWritten() - rc.Response.Header.GetHeaderLength()
+		case rc.Response.IsBodyStream():
+			// the stream is written after the handlers return, only its declared size is known
+			if n := rc.Response.Header.ContentLength(); n > 0 {
+				respSize = n
+			}
+		default:
+			respSize = len(rc.Response.BodyBytes())
+		}
+		c.responseSize.with(method, routePath).observe(c.responseSize.buckets, float64(respSize))
+	}
+}
+
+// methodLabel returns the method label of the request, the methods other than the standard ones
+// are labeled otherMethod since the clients can send any method.
+func methodLabel(method []byte) string {
+	switch string(method) {
+	case consts.MethodGet:
+		return consts.MethodGet
+	case consts.MethodHead:
+		return consts.MethodHead
+	case consts.MethodPost:
+		return consts.MethodPost
+	case consts.MethodPut:
+		return consts.MethodPut
+	case consts.MethodPatch:
+		return consts.MethodPatch
+	case consts.MethodDelete:
+		return consts.MethodDelete
+	case consts.MethodConnect:
+		return consts.MethodConnect
+	case consts.MethodOptions:
+		return consts.MethodOptions
+	case consts.MethodTrace:
+		return consts.MethodTrace
+	}
+	return otherMethod
+}
+
+// ObserveWriteStall records the stalled writes of the responses, e.g. the slow consumers of the
+// streaming responses. Pass it to server.WithWriteStallObserver.
+func (c *Collector) ObserveWriteStall(stall network.WriteStall) {
+	routePath := stall.Route
+	if routePath == "" {
+		routePath = unmatchedRoute
+	}
+	c.writeStalls.with(routePath, strconv.FormatBool(stall.TimedOut)).add(1)
+	c.stallTime.with(routePath).observe(c.stallTime.buckets, stall.Duration.Seconds())
+}
+
+// Handler returns a handler which exposes the collected metrics in prometheus text format.
+func (c *Collector) Handler() app.HandlerFunc {
+	return func(ctx context.Context, rc *app.RequestContext) {
+		var buf bytes.Buffer
+		for _, m := range []*metric{c.requests, c.duration, c.inFlight, c.requestSize, c.responseSize, c.writeStalls, c.stallTime} {
+			m.writeTo(&buf)
+		}
+		rc.Data(consts.StatusOK, contentTypeText, buf.Bytes())
+	}
+}
+
+// the metrics endpoint mounted on the measured engine is not measured itself
+func (c *Collector) isMetricsRequest(rc *app.RequestContext) bool {
+	return c.opts.adminRouter == nil && !c.opts.disableHandler && rc.FullPath() == c.opts.path
+}
@@ -0,0 +1,105 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metrics
+
+import (
+	"hertz-study/pkg/route"
+)
+
+const (
+	defaultNamespace = "hertz"
+	defaultPath      = "/metrics"
+)
+
+var (
+	// DefaultDurationBuckets are the buckets of request duration histogram, in seconds.
+	DefaultDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+	// DefaultSizeBuckets are the buckets of request and response size histograms, in bytes.
+	DefaultSizeBuckets = []float64{64, 256, 1024, 4096, 16384, 65536, 262144, 1048576, 4194304}
+)
+
+type (
+	options struct {
+		namespace       string
+		path            string
+		durationBuckets []float64
+		sizeBuckets     []float64
+		adminRouter     route.IRoutes
+		disableHandler  bool
+	}
+
+	Option func(o *options)
+)
+
+func newOptions(opts ...Option) *options {
+	cfg := &options{
+		namespace:       defaultNamespace,
+		path:            defaultPath,
+		durationBuckets: DefaultDurationBuckets,
+		sizeBuckets:     DefaultSizeBuckets,
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return cfg
+}
+
+// WithNamespace sets the prefix of metric names, default is "hertz".
+func WithNamespace(namespace string) Option {
+	return func(o *options) {
+		o.namespace = namespace
+	}
+}
+
+// WithPath sets the path of metrics handler, default is "/metrics".
+func WithPath(path string) Option {
+	return func(o *options) {
+		o.path = path
+	}
+}
+
+// WithDurationBuckets sets the buckets of request duration histogram, in seconds.
+func WithDurationBuckets(buckets []float64) Option {
+	return func(o *options) {
+		o.durationBuckets = buckets
+	}
+}
+
+// WithSizeBuckets sets the buckets of request and response size histograms, in bytes.
+func WithSizeBuckets(buckets []float64) Option {
+	return func(o *options) {
+		o.sizeBuckets = buckets
+	}
+}
+
+// WithAdminRouter mounts the metrics handler on a separate router, e.g. the engine of an
+// admin server listening on another port, instead of the engine being measured.
+func WithAdminRouter(r route.IRoutes) Option {
+	return func(o *options) {
+		o.adminRouter = r
+	}
+}
+
+// WithDisableHandler disables mounting the metrics handler, use Collector.Handler to expose
+// the metrics in a customized way.
+func WithDisableHandler(disable bool) Option {
+	return func(o *options) {
+		o.disableHandler = disable
+	}
+}
@@ -0,0 +1,191 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metrics
+
+import (
+	"bytes"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const labelSep = "\xff"
+
+type metricType string
+
+const (
+	typeCounter   metricType = "counter"
+	typeGauge     metricType = "gauge"
+	typeHistogram metricType = "histogram"
+)
+
+// metric is a family of series distinguished by label values.
+type metric struct {
+	name    string
+	help    string
+	typ     metricType
+	labels  []string
+	buckets []float64
+
+	mu     sync.RWMutex
+	series map[string]*series
+}
+
+type series struct {
+	labelValues []string
+
+	// counter and gauge
+	value int64
+
+	// histogram
+	mu     sync.Mutex
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func newMetric(name, help string, typ metricType, buckets []float64, labels ...string) *metric {
+	if typ == typeHistogram {
+		buckets = append([]float64(nil), buckets...)
+		sort.Float64s(buckets)
+	}
+	return &metric{
+		name:    name,
+		help:    help,
+		typ:     typ,
+		labels:  labels,
+		buckets: buckets,
+		series:  make(map[string]*series),
+	}
+}
+
+func (m *metric) with(labelValues ...string) *series {
+	key := strings.Join(labelValues, labelSep)
+	m.mu.RLock()
+	s, ok := m.series[key]
+	m.mu.RUnlock()
+	if ok {
+		return s
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s, ok = m.series[key]; ok {
+		return s
+	}
+	s = &series{labelValues: labelValues}
+	if m.typ == typeHistogram {
+		s.counts = make([]uint64, len(m.buckets))
+	}
+	m.series[key] = s
+	return s
+}
+
+func (s *series) add(delta int64) {
+	atomic.AddInt64(&s.value, delta)
+}
+
+func (s *series) observe(buckets []float64, v float64) {
+	idx := sort.SearchFloat64s(buckets, v)
+	s.mu.Lock()
+	if idx < len(s.counts) {
+		s.counts[idx]++
+	}
+	s.sum += v
+	s.count++
+	s.mu.Unlock()
+}
+
+// writeTo writes the metric in prometheus text exposition format.
+func (m *metric) writeTo(buf *bytes.Buffer) {
+	buf.WriteString("# HELP " + m.name + " " + m.help + "\n")
+	buf.WriteString("# TYPE " + m.name + " " + string(m.typ) + "\n")
+
+	m.mu.RLock()
+	keys := make([]string, 0, len(m.series))
+	for k := range m.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	all := make([]*series, len(keys))
+	for i, k := range keys {
+		all[i] = m.series[k]
+	}
+	m.mu.RUnlock()
+
+	for _, s := range all {
+		if m.typ != typeHistogram {
+			writeSample(buf, m.name, m.labels, s.labelValues, "", "", float64(atomic.LoadInt64(&s.value)))
+			continue
+		}
+		s.mu.Lock()
+		var cumulative uint64
+		for i, upper := range m.buckets {
+			cumulative += s.counts[i]
+			writeSample(buf, m.name+"_bucket", m.labels, s.labelValues, "le", formatFloat(upper), float64(cumulative))
+		}
+		writeSample(buf, m.name+"_bucket", m.labels, s.labelValues, "le", "+Inf", float64(s.count))
+		writeSample(buf, m.name+"_sum", m.labels, s.labelValues, "", "", s.sum)
+		writeSample(buf, m.name+"_count", m.labels, s.labelValues, "", "", float64(s.count))
+		s.mu.Unlock()
+	}
+}
+
+func writeSample(buf *bytes.Buffer, name string, labels, values []string, extraLabel, extraValue string, v float64) {
+	buf.WriteString(name)
+	if len(labels) > 0 || extraLabel != "" {
+		buf.WriteByte('{')
+		for i, l := range labels {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeLabel(buf, l, values[i])
+		}
+		if extraLabel != "" {
+			if len(labels) > 0 {
+				buf.WriteByte(',')
+			}
+			writeLabel(buf, extraLabel, extraValue)
+		}
+		buf.WriteByte('}')
+	}
+	buf.WriteByte(' ')
+	buf.WriteString(formatFloat(v))
+	buf.WriteByte('\n')
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func writeLabel(buf *bytes.Buffer, name, value string) {
+	buf.WriteString(name)
+	buf.WriteString(`="`)
+	labelValueEscaper.WriteString(buf, value) //nolint:errcheck
+	buf.WriteByte('"')
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
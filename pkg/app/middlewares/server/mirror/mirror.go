@@ -0,0 +1,122 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mirror
+
+import (
+	"bytes"
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/bytedance/gopkg/lang/fastrand"
+	"hertz-study/pkg/app"
+	"hertz-study/pkg/app/client"
+	"hertz-study/pkg/protocol"
+	"hertz-study/pkg/protocol/consts"
+)
+
+// hopHeaders are the hop-by-hop headers, which apply to the connection of the client only and
+// aren't mirrored, see https://www.rfc-editor.org/rfc/rfc9110#section-7.6.1
+var hopHeaders = []string{
+	consts.HeaderConnection,
+	consts.HeaderKeepAlive,
+	consts.HeaderTE,
+	consts.HeaderTrailer,
+	consts.HeaderTransferEncoding,
+	consts.HeaderUpgrade,
+}
+
+// New creates the middleware which duplicates the sampled requests to the shadow target with cli
+// asynchronously, e.g. to test a new version of the service with the production traffic. The
+// responses of the target are discarded, and the requests to the server are never delayed or
+// failed by the mirroring.
+//
+// target is the base URL of the shadow target, e.g. "http://shadow:8080", to which the request
+// URI is appended. The requests with body streams aren't mirrored since reading the stream would
+// consume it before the handlers. It panics if target isn't an absolute URL.
+func New(cli *client.Client, target string, opts ...Option) app.HandlerFunc {
+	u, err := url.Parse(target)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		panic("mirror: invalid target: " + target)
+	}
+	target = strings.TrimSuffix(target, "/")
+	o := newOptions(opts...)
+	sem := make(chan struct{}, o.maxConcurrency)
+
+	return func(c context.Context, ctx *app.RequestContext) {
+		if o.percentage <= 0 || (o.percentage < 100 && fastrand.Float64()*100 >= o.percentage) ||
+			ctx.Request.IsBodyStream() || len(ctx.Request.Body()) > o.maxBodySize ||
+			(o.skipper != nil && o.skipper(c, ctx)) {
+			ctx.Next(c)
+			return
+		}
+
+		select {
+		case sem <- struct{}{}:
+		default:
+			ctx.Next(c)
+			return
+		}
+		// the request is copied before the handlers, which may modify it
+		req := protocol.AcquireRequest()
+		ctx.Request.CopyTo(req)
+		uri := target + string(ctx.Request.URI().RequestURI())
+		req.SetRequestURI(uri)
+		req.Header.SetHost(u.Host)
+		removeHopHeaders(&req.Header)
+		if o.header != "" {
+			req.Header.Set(o.header, "true")
+		}
+		// the request context is canceled after the response is written
+		mctx := context.WithoutCancel(c)
+		go func() {
+			resp := protocol.AcquireResponse()
+			defer func() {
+				protocol.ReleaseRequest(req)
+				protocol.ReleaseResponse(resp)
+				<-sem
+			}()
+			if err := cli.DoTimeout(mctx, req, resp, o.timeout); err != nil {
+				o.onError(mctx, uri, err)
+			}
+		}()
+
+		ctx.Next(c)
+	}
+}
+
+// removeHopHeaders removes the hop-by-hop headers, including the Proxy-* ones and the ones listed
+// by the Connection header.
+func removeHopHeaders(h *protocol.RequestHeader) {
+	var keys []string
+	for _, key := range bytes.Split(h.Peek(consts.HeaderConnection), []byte{','}) {
+		if key = bytes.TrimSpace(key); len(key) > 0 {
+			keys = append(keys, string(key))
+		}
+	}
+	h.VisitAll(func(key, value []byte) {
+		if len(key) > len("Proxy-") && strings.EqualFold(string(key[:len("Proxy-")]), "Proxy-") {
+			keys = append(keys, string(key))
+		}
+	})
+	for _, key := range keys {
+		h.Del(key)
+	}
+	for _, key := range hopHeaders {
+		h.Del(key)
+	}
+}
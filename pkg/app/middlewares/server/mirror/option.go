@@ -0,0 +1,132 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mirror
+
+import (
+	"context"
+	"time"
+
+	"hertz-study/pkg/app"
+	"hertz-study/pkg/common/hlog"
+)
+
+const (
+	// DefaultHeader is the header marking the mirrored requests, so that the shadow target can
+	// skip the side effects, e.g. sending emails.
+	DefaultHeader = "X-Mirrored"
+
+	defaultTimeout        = 5 * time.Second
+	defaultMaxConcurrency = 100
+	defaultMaxBodySize    = 1 << 20
+)
+
+type (
+	options struct {
+		percentage     float64
+		timeout        time.Duration
+		maxConcurrency int
+		maxBodySize    int
+		header         string
+		skipper        func(c context.Context, ctx *app.RequestContext) bool
+		onError        func(c context.Context, uri string, err error)
+	}
+
+	Option func(o *options)
+)
+
+func newOptions(opts ...Option) *options {
+	cfg := &options{
+		percentage:     100,
+		timeout:        defaultTimeout,
+		maxConcurrency: defaultMaxConcurrency,
+		maxBodySize:    defaultMaxBodySize,
+		header:         DefaultHeader,
+		onError:        defaultOnError,
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	if cfg.maxConcurrency <= 0 {
+		cfg.maxConcurrency = defaultMaxConcurrency
+	}
+	if cfg.onError == nil {
+		cfg.onError = defaultOnError
+	}
+	return cfg
+}
+
+func defaultOnError(c context.Context, uri string, err error) {
+	hlog.SystemLogger().CtxDebugf(c, "[Mirror] mirror request failed: uri=%s, err=%v", uri, err)
+}
+
+// WithPercentage sets the percentage of the requests to mirror, in the range of [0, 100],
+// default is 100 which mirrors all the requests.
+func WithPercentage(p float64) Option {
+	return func(o *options) {
+		o.percentage = p
+	}
+}
+
+// WithTimeout sets the timeout of every mirrored request, default is 5s.
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.timeout = d
+	}
+}
+
+// WithMaxConcurrency sets the max count of the in-flight mirrored requests, the requests beyond
+// it aren't mirrored, so that a slow shadow target never piles up goroutines. Default is 100.
+func WithMaxConcurrency(n int) Option {
+	return func(o *options) {
+		o.maxConcurrency = n
+	}
+}
+
+// WithMaxBodySize sets the max size of the request body to mirror, the requests with larger
+// bodies aren't mirrored. Default is 1MB.
+func WithMaxBodySize(n int) Option {
+	return func(o *options) {
+		o.maxBodySize = n
+	}
+}
+
+// WithHeader sets the header set to "true" on the mirrored requests, default is DefaultHeader.
+// The empty key disables the header.
+func WithHeader(key string) Option {
+	return func(o *options) {
+		o.header = key
+	}
+}
+
+// WithSkipper sets the function deciding whether the request is skipped from mirroring,
+// e.g. the non-idempotent requests.
+func WithSkipper(f func(c context.Context, ctx *app.RequestContext) bool) Option {
+	return func(o *options) {
+		o.skipper = f
+	}
+}
+
+// WithOnError sets the function called when a mirrored request fails, the default logs the
+// error at debug level. The responses of the shadow target are discarded regardless of the
+// status codes.
+func WithOnError(f func(c context.Context, uri string, err error)) Option {
+	return func(o *options) {
+		o.onError = f
+	}
+}
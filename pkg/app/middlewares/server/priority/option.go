@@ -0,0 +1,68 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package priority
+
+// DefaultHeader is the standard header carrying the priority of a request, whose value is one
+// of the names of Priority, e.g. "X-Request-Priority: batch".
+const DefaultHeader = "X-Request-Priority"
+
+type (
+	options struct {
+		header       string
+		defaultValue Priority
+		max          Priority
+	}
+
+	Option func(o *options)
+)
+
+func newOptions(opts ...Option) *options {
+	cfg := &options{
+		header:       DefaultHeader,
+		defaultValue: Normal,
+		max:          Critical,
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return cfg
+}
+
+// WithHeader sets the header to read the priority from, default is DefaultHeader.
+func WithHeader(header string) Option {
+	return func(o *options) {
+		o.header = header
+	}
+}
+
+// WithDefault sets the priority of the requests without a valid priority header, default is Normal.
+func WithDefault(p Priority) Option {
+	return func(o *options) {
+		o.defaultValue = p
+	}
+}
+
+// WithMax caps the priority claimed by the requests, default is Critical. Set it to Normal for
+// the entries exposed to the untrusted clients, so that they can lower their priority but
+// can not raise it.
+func WithMax(p Priority) Option {
+	return func(o *options) {
+		o.max = p
+	}
+}
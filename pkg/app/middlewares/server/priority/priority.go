@@ -0,0 +1,113 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package priority
+
+import (
+	"context"
+	"strings"
+
+	"hertz-study/pkg/app"
+)
+
+// Priority is the importance of a request, the requests of lower priority are shed first when
+// the server is overloaded, see the loadshed middleware.
+type Priority int
+
+const (
+	// Batch is for the offline jobs, which can be retried at any time later.
+	Batch Priority = iota
+	// Low is for the speculative requests, e.g. prefetch.
+	Low
+	// Normal is the default priority.
+	Normal
+	// High is for the requests blocking the users.
+	High
+	// Critical is for the requests the service can not work without, e.g. health checks
+	// and control plane calls.
+	Critical
+)
+
+var names = [...]string{"batch", "low", "normal", "high", "critical"}
+
+// ctxKey is the key of the priority stored in app.RequestContext.
+const ctxKey = "hertz_request_priority"
+
+// defaultOptions parses the requests not passing the middleware.
+var defaultOptions = newOptions()
+
+// String returns the name of p.
+func (p Priority) String() string {
+	if p < Batch || p > Critical {
+		return "unknown"
+	}
+	return names[p]
+}
+
+// Parse parses the name of a priority case-insensitively, "prefetch" is an alias of Low.
+func Parse(s string) (Priority, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "prefetch" {
+		return Low, true
+	}
+	for i, name := range names {
+		if s == name {
+			return Priority(i), true
+		}
+	}
+	return Normal, false
+}
+
+// New returns a middleware which parses the priority header of the request into the context,
+// which can be read by Get.
+func New(opts ...Option) app.HandlerFunc {
+	cfg := newOptions(opts...)
+	return func(c context.Context, ctx *app.RequestContext) {
+		ctx.Set(ctxKey, cfg.parse(ctx))
+		ctx.Next(c)
+	}
+}
+
+// Get returns the priority of the request parsed by the middleware, the DefaultHeader is
+// parsed with the default options if the middleware is not used.
+func Get(ctx *app.RequestContext) Priority {
+	if v, ok := ctx.Get(ctxKey); ok {
+		if p, ok := v.(Priority); ok {
+			return p
+		}
+	}
+	return defaultOptions.parse(ctx)
+}
+
+// Set overrides the priority of the request, e.g. by the authenticated identity.
+func Set(ctx *app.RequestContext, p Priority) {
+	ctx.Set(ctxKey, p)
+}
+
+func (o *options) parse(ctx *app.RequestContext) Priority {
+	v := ctx.Request.Header.Peek(o.header)
+	if len(v) == 0 {
+		return o.defaultValue
+	}
+	p, ok := Parse(string(v))
+	if !ok {
+		return o.defaultValue
+	}
+	if p > o.max {
+		p = o.max
+	}
+	return p
+}
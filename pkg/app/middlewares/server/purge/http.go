@@ -0,0 +1,76 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package purge
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"hertz-study/pkg/app/client"
+	"hertz-study/pkg/protocol"
+)
+
+// DefaultPurgeMethod is the method of the purge requests understood by Varnish and most CDNs.
+const DefaultPurgeMethod = "PURGE"
+
+// HTTPPurger purges the URLs by sending a request of Method for every URL, which fits Varnish
+// and the CDNs supporting the PURGE method. Implement Purger for the CDNs with purge APIs.
+type HTTPPurger struct {
+	Client *client.Client
+	// Method is the method of the purge requests, default is DefaultPurgeMethod.
+	Method string
+	// Endpoint is the address of the cache server, e.g. "http://varnish:6081". The purge requests
+	// are sent to it with the host of the URLs as the Host header. The URLs are requested directly
+	// if it is empty.
+	Endpoint string
+}
+
+// Purge sends the purge requests one by one and returns the first error.
+func (p *HTTPPurger) Purge(ctx context.Context, urls []string) error {
+	method := p.Method
+	if method == "" {
+		method = DefaultPurgeMethod
+	}
+	req, resp := protocol.AcquireRequest(), protocol.AcquireResponse()
+	defer func() {
+		protocol.ReleaseRequest(req)
+		protocol.ReleaseResponse(resp)
+	}()
+	for _, u := range urls {
+		req.Reset()
+		resp.Reset()
+		req.Header.SetMethod(method)
+		if p.Endpoint == "" {
+			req.SetRequestURI(u)
+		} else {
+			parsed, err := url.Parse(u)
+			if err != nil {
+				return err
+			}
+			req.SetRequestURI(p.Endpoint + parsed.RequestURI())
+			req.Header.SetHost(parsed.Host)
+		}
+		if err := p.Client.Do(ctx, req, resp); err != nil {
+			return fmt.Errorf("purge %s: %w", u, err)
+		}
+		if code := resp.StatusCode(); code >= 300 && code != 404 {
+			return fmt.Errorf("purge %s: unexpected status code %d", u, code)
+		}
+	}
+	return nil
+}
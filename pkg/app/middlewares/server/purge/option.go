@@ -0,0 +1,108 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package purge
+
+import (
+	"context"
+	"time"
+
+	"hertz-study/pkg/protocol/consts"
+)
+
+const defaultTimeout = 5 * time.Second
+
+type (
+	options struct {
+		keyFunc      KeyFunc
+		methods      map[string]bool
+		success      func(statusCode int) bool
+		async        bool
+		timeout      time.Duration
+		errorHandler func(c context.Context, urls []string, err error)
+	}
+
+	Option func(o *options)
+)
+
+func newOptions(opts ...Option) *options {
+	cfg := &options{
+		keyFunc: RequestURL,
+		methods: map[string]bool{
+			consts.MethodPost:   true,
+			consts.MethodPut:    true,
+			consts.MethodPatch:  true,
+			consts.MethodDelete: true,
+		},
+		success: func(statusCode int) bool {
+			return statusCode >= 200 && statusCode < 300
+		},
+		async:   true,
+		timeout: defaultTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return cfg
+}
+
+// WithKeyFunc sets how the URLs to purge are derived from the request, default is RequestURL.
+func WithKeyFunc(f KeyFunc) Option {
+	return func(o *options) {
+		o.keyFunc = f
+	}
+}
+
+// WithMethods sets the mutating methods triggering the purge, default is POST, PUT, PATCH and DELETE.
+func WithMethods(methods ...string) Option {
+	return func(o *options) {
+		o.methods = make(map[string]bool, len(methods))
+		for _, m := range methods {
+			o.methods[m] = true
+		}
+	}
+}
+
+// WithSuccess sets which status codes mean the mutation succeeds, default is 2xx.
+func WithSuccess(f func(statusCode int) bool) Option {
+	return func(o *options) {
+		o.success = f
+	}
+}
+
+// WithAsync sets whether purging in a new goroutine to not delay the response, default is true.
+// The response is sent after the purge completes if it is false.
+func WithAsync(b bool) Option {
+	return func(o *options) {
+		o.async = b
+	}
+}
+
+// WithTimeout sets the timeout of a purge, default is 5s.
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.timeout = d
+	}
+}
+
+// WithErrorHandler sets the function called when a purge fails, the failures are logged by default.
+func WithErrorHandler(f func(c context.Context, urls []string, err error)) Option {
+	return func(o *options) {
+		o.errorHandler = f
+	}
+}
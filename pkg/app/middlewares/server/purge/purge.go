@@ -0,0 +1,119 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package purge
+
+import (
+	"context"
+	"strings"
+
+	"hertz-study/pkg/app"
+	"hertz-study/pkg/common/hlog"
+)
+
+// Purger purges the cached responses of the URLs from the edge caches, e.g. Varnish or a CDN.
+type Purger interface {
+	Purge(ctx context.Context, urls []string) error
+}
+
+// PurgerFunc is an adapter to allow the use of ordinary functions as Purger.
+type PurgerFunc func(ctx context.Context, urls []string) error
+
+// Purge calls f(ctx, urls).
+func (f PurgerFunc) Purge(ctx context.Context, urls []string) error {
+	return f(ctx, urls)
+}
+
+// KeyFunc derives the URLs to purge from a request whose mutation succeeds.
+type KeyFunc func(c context.Context, ctx *app.RequestContext) []string
+
+// New returns a middleware which purges the URLs derived from the requests of the mutating
+// methods by p after the handlers succeed, e.g.
+//
+//	h.PUT("/users/:id", purge.New(p, purge.WithKeyFunc(purge.Paths("/users/:id", "/users"))), updateUser)
+func New(p Purger, opts ...Option) app.HandlerFunc {
+	o := newOptions(opts...)
+	return func(c context.Context, ctx *app.RequestContext) {
+		ctx.Next(c)
+
+		if !o.methods[string(ctx.Method())] || !o.success(ctx.Response.StatusCode()) {
+			return
+		}
+		urls := o.keyFunc(c, ctx)
+		if len(urls) == 0 {
+			return
+		}
+		if o.async {
+			// ctx is recycled after the response, so nothing of it is used by the goroutine
+			go o.purge(context.Background(), p, urls)
+			return
+		}
+		o.purge(c, p, urls)
+	}
+}
+
+func (o *options) purge(c context.Context, p Purger, urls []string) {
+	if o.timeout > 0 {
+		var cancel context.CancelFunc
+		c, cancel = context.WithTimeout(c, o.timeout)
+		defer cancel()
+	}
+	err := p.Purge(c, urls)
+	if err == nil {
+		return
+	}
+	if o.errorHandler != nil {
+		o.errorHandler(c, urls, err)
+		return
+	}
+	hlog.SystemLogger().CtxErrorf(c, "[Purge] purge urls=%v error=%v", urls, err)
+}
+
+// RequestURL purges the URL of the request without the query string, e.g. "http://example.com/users/1".
+func RequestURL(c context.Context, ctx *app.RequestContext) []string {
+	return []string{baseURL(ctx) + string(ctx.URI().Path())}
+}
+
+// Paths returns a KeyFunc purging the URLs of the paths on the host of the request, the params
+// like ":id" and "*filepath" of the paths are replaced by the values of the request, e.g.
+// Paths("/users/:id", "/users") purges the user and the list after the user is updated.
+func Paths(paths ...string) KeyFunc {
+	return func(c context.Context, ctx *app.RequestContext) []string {
+		base := baseURL(ctx)
+		urls := make([]string, 0, len(paths))
+		for _, path := range paths {
+			urls = append(urls, base+expandPath(path, ctx))
+		}
+		return urls
+	}
+}
+
+func baseURL(ctx *app.RequestContext) string {
+	return ctx.ExternalScheme() + "://" + ctx.ExternalHost()
+}
+
+func expandPath(path string, ctx *app.RequestContext) string {
+	if !strings.ContainsAny(path, ":*") {
+		return path
+	}
+	segments := strings.Split(path, "/")
+	for i, seg := range segments {
+		if len(seg) > 1 && (seg[0] == ':' || seg[0] == '*') {
+			segments[i] = strings.TrimPrefix(ctx.Param(seg[1:]), "/")
+		}
+	}
+	return strings.Join(segments, "/")
+}
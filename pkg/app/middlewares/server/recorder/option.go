@@ -0,0 +1,104 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package recorder
+
+import (
+	"context"
+	"strings"
+
+	"hertz-study/pkg/app"
+)
+
+const defaultMaxBodySize = 64 * 1024
+
+var (
+	defaultRedactHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie"}
+	defaultRedactFields  = []string{"password", "token", "secret"}
+)
+
+type (
+	options struct {
+		sampleRate    float64
+		redactHeaders map[string]struct{}
+		redactFields  map[string]struct{}
+		maxBodySize   int
+		skipper       func(c context.Context, ctx *app.RequestContext) bool
+	}
+
+	Option func(o *options)
+)
+
+func newOptions(opts ...Option) *options {
+	cfg := &options{
+		sampleRate:  1,
+		maxBodySize: defaultMaxBodySize,
+	}
+	WithRedactHeaders(defaultRedactHeaders...)(cfg)
+	WithRedactFields(defaultRedactFields...)(cfg)
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return cfg
+}
+
+// WithSampleRate sets the ratio of the requests to record, in the range of [0, 1],
+// default is 1 which records all the requests.
+func WithSampleRate(rate float64) Option {
+	return func(o *options) {
+		o.sampleRate = rate
+	}
+}
+
+// WithRedactHeaders sets the request headers whose values are replaced with "[REDACTED]",
+// default is Authorization, Proxy-Authorization and Cookie.
+func WithRedactHeaders(keys ...string) Option {
+	return func(o *options) {
+		o.redactHeaders = make(map[string]struct{}, len(keys))
+		for _, k := range keys {
+			o.redactHeaders[strings.ToLower(k)] = struct{}{}
+		}
+	}
+}
+
+// WithRedactFields sets the query arguments, form fields and JSON object keys whose values
+// are replaced with "[REDACTED]", default is password, token and secret.
+func WithRedactFields(keys ...string) Option {
+	return func(o *options) {
+		o.redactFields = make(map[string]struct{}, len(keys))
+		for _, k := range keys {
+			o.redactFields[k] = struct{}{}
+		}
+	}
+}
+
+// WithMaxBodySize sets the max size of the request body to record, larger bodies are
+// truncated and can not be replayed faithfully. Default is 64KB.
+func WithMaxBodySize(n int) Option {
+	return func(o *options) {
+		o.maxBodySize = n
+	}
+}
+
+// WithSkipper sets the function deciding whether the request is skipped from recording,
+// e.g. the health checks.
+func WithSkipper(f func(c context.Context, ctx *app.RequestContext) bool) Option {
+	return func(o *options) {
+		o.skipper = f
+	}
+}
@@ -0,0 +1,168 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package recorder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/bytedance/gopkg/lang/fastrand"
+	"hertz-study/pkg/app"
+	"hertz-study/pkg/common/hlog"
+	"hertz-study/pkg/protocol"
+)
+
+const redacted = "[REDACTED]"
+
+// Recorder records the sampled inbound requests to a Store, which can be replayed
+// against a local engine with Replay to reproduce the production bugs.
+type Recorder struct {
+	store Store
+	opts  *options
+	seq   uint64
+}
+
+// New creates a Recorder saving the records to store.
+func New(store Store, opts ...Option) *Recorder {
+	return &Recorder{store: store, opts: newOptions(opts...)}
+}
+
+// Middleware returns the middleware recording the requests, which should be registered
+// before the middlewares modifying the request.
+func (r *Recorder) Middleware() app.HandlerFunc {
+	return func(c context.Context, ctx *app.RequestContext) {
+		if r.opts.sampleRate <= 0 || (r.opts.sampleRate < 1 && fastrand.Float64() >= r.opts.sampleRate) ||
+			(r.opts.skipper != nil && r.opts.skipper(c, ctx)) {
+			ctx.Next(c)
+			return
+		}
+
+		rec := r.record(ctx)
+		ctx.Next(c)
+		rec.StatusCode = ctx.Response.StatusCode()
+		if err := r.store.Save(rec); err != nil {
+			hlog.SystemLogger().CtxErrorf(c, "[Recorder] save record failed: err=%v", err)
+		}
+	}
+}
+
+func (r *Recorder) record(ctx *app.RequestContext) *Record {
+	req := &ctx.Request
+	rec := &Record{
+		Seq:    atomic.AddUint64(&r.seq, 1),
+		Time:   time.Now(),
+		Method: string(req.Method()),
+		Host:   string(req.Host()),
+		URI:    r.uri(req),
+	}
+	req.Header.VisitAll(func(key, value []byte) {
+		v := string(value)
+		if _, ok := r.opts.redactHeaders[strings.ToLower(string(key))]; ok {
+			v = redacted
+		}
+		rec.Header = append(rec.Header, [2]string{string(key), v})
+	})
+
+	// reading the body stream here would consume it before the handlers
+	if req.IsBodyStream() {
+		rec.Truncated = true
+		return rec
+	}
+	body := r.body(req)
+	if len(body) > r.opts.maxBodySize {
+		body = body[:r.opts.maxBodySize]
+		rec.Truncated = true
+	}
+	rec.Body = append([]byte(nil), body...)
+	return rec
+}
+
+func (r *Recorder) uri(req *protocol.Request) string {
+	u := req.URI()
+	uri := u.RequestURI()
+	if u.QueryArgs().Len() == 0 {
+		return string(uri)
+	}
+	if i := bytes.IndexByte(uri, '?'); i >= 0 {
+		uri = uri[:i]
+	}
+	var args protocol.Args
+	r.redactArgs(&args, u.QueryArgs())
+	return string(uri) + "?" + string(args.QueryString())
+}
+
+func (r *Recorder) redactArgs(dst, src *protocol.Args) {
+	src.VisitAll(func(key, value []byte) {
+		if _, ok := r.opts.redactFields[string(key)]; ok {
+			value = []byte(redacted)
+		}
+		dst.Add(string(key), string(value))
+	})
+}
+
+// body returns the request body with the fields redacted for urlencoded form and JSON bodies.
+func (r *Recorder) body(req *protocol.Request) []byte {
+	body := req.Body()
+	if len(body) == 0 || len(r.opts.redactFields) == 0 {
+		return body
+	}
+	ct := req.Header.ContentType()
+	switch {
+	case bytes.HasPrefix(ct, []byte("application/x-www-form-urlencoded")):
+		var src, args protocol.Args
+		src.ParseBytes(body)
+		r.redactArgs(&args, &src)
+		return args.QueryString()
+	case bytes.Contains(ct, []byte("json")):
+		var v interface{}
+		if err := json.Unmarshal(body, &v); err != nil {
+			return body
+		}
+		if !r.redactJSON(v) {
+			return body
+		}
+		if b, err := json.Marshal(v); err == nil {
+			return b
+		}
+	}
+	return body
+}
+
+// redactJSON redacts v in place and returns whether anything is redacted.
+func (r *Recorder) redactJSON(v interface{}) bool {
+	changed := false
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			if _, ok := r.opts.redactFields[k]; ok {
+				v[k] = redacted
+				changed = true
+				continue
+			}
+			changed = r.redactJSON(e) || changed
+		}
+	case []interface{}:
+		for _, e := range v {
+			changed = r.redactJSON(e) || changed
+		}
+	}
+	return changed
+}
@@ -0,0 +1,65 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package recorder
+
+import (
+	"bytes"
+	"strings"
+
+	"hertz-study/pkg/common/ut"
+	"hertz-study/pkg/protocol"
+	"hertz-study/pkg/route"
+)
+
+// ReplayResult is the result of replaying a Record.
+type ReplayResult struct {
+	Record   *Record
+	Response *protocol.Response
+}
+
+// Mismatch returns whether the status code of the replayed response differs from the recorded one.
+func (r *ReplayResult) Mismatch() bool {
+	return r.Response.StatusCode() != r.Record.StatusCode
+}
+
+// Replay re-issues the records one by one in order against engine without network transporting,
+// e.g. a local engine with the same routes and a debugger attached. Use ReadRecords to load the
+// records saved by WriterStore. The redacted values are replayed as "[REDACTED]".
+func Replay(engine *route.Engine, records []*Record) []*ReplayResult {
+	results := make([]*ReplayResult, 0, len(records))
+	for _, rec := range records {
+		headers := make([]ut.Header, 0, len(rec.Header))
+		for _, h := range rec.Header {
+			// the body is set below
+			if strings.EqualFold(h[0], "Content-Length") || strings.EqualFold(h[0], "Transfer-Encoding") {
+				continue
+			}
+			headers = append(headers, ut.Header{Key: h[0], Value: h[1]})
+		}
+		var body *ut.Body
+		if len(rec.Body) > 0 {
+			body = &ut.Body{Body: bytes.NewReader(rec.Body), Len: len(rec.Body)}
+		}
+		url := rec.URI
+		if rec.Host != "" {
+			url = "http://" + rec.Host + rec.URI
+		}
+		w := ut.PerformRequest(engine, rec.Method, url, body, headers...)
+		results = append(results, &ReplayResult{Record: rec, Response: w.Result()})
+	}
+	return results
+}
@@ -0,0 +1,99 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package recorder
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Record is a sanitized inbound request, which is encoded as a line of JSON by WriterStore.
+type Record struct {
+	Seq    uint64      `json:"seq"`
+	Time   time.Time   `json:"time"`
+	Method string      `json:"method"`
+	Host   string      `json:"host"`
+	URI    string      `json:"uri"`
+	Header [][2]string `json:"header,omitempty"`
+	Body   []byte      `json:"body,omitempty"`
+	// Truncated is true if the body exceeds the max body size or is a stream which is not recorded.
+	Truncated bool `json:"truncated,omitempty"`
+	// StatusCode is the status code of the recorded response.
+	StatusCode int `json:"status_code"`
+}
+
+// Store saves the records, it must be safe for concurrent use.
+type Store interface {
+	Save(r *Record) error
+}
+
+// WriterStore is a Store writing the records to an io.Writer as JSON lines, e.g. an *os.File
+// or an *hlog.RotateFile.
+type WriterStore struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterStore creates a WriterStore writing to w.
+func NewWriterStore(w io.Writer) *WriterStore {
+	return &WriterStore{w: w}
+}
+
+// Save implements Store.
+func (s *WriterStore) Save(r *Record) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(b)
+	return err
+}
+
+// ReadRecords reads the records written by WriterStore from r, sorted by the time
+// the requests are received.
+func ReadRecords(r io.Reader) ([]*Record, error) {
+	var records []*Record
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 16*1024*1024)
+	for sc.Scan() {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		rec := &Record{}
+		if err := json.Unmarshal(sc.Bytes(), rec); err != nil {
+			return nil, err
+		}
+		records = append(records, rec)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		if !records[i].Time.Equal(records[j].Time) {
+			return records[i].Time.Before(records[j].Time)
+		}
+		return records[i].Seq < records[j].Seq
+	})
+	return records, nil
+}
@@ -27,9 +27,16 @@ import (
 type (
 	options struct {
 		recoveryHandler func(c context.Context, ctx *app.RequestContext, err interface{}, stack []byte)
+		panicHooks      []PanicHook
+		dumpRequest     bool
+		stack           bool
 	}
 
 	Option func(o *options)
+
+	// PanicHook is called with every recovered panic except the broken connections, e.g. to
+	// report it to Sentry. The stack is nil if disabled by WithStack.
+	PanicHook func(c context.Context, ctx *app.RequestContext, err interface{}, stack []byte)
 )
 
 func defaultRecoveryHandler(c context.Context, ctx *app.RequestContext, err interface{}, stack []byte) {
@@ -40,6 +47,7 @@ func defaultRecoveryHandler(c context.Context, ctx *app.RequestContext, err inte
 func newOptions(opts ...Option) *options {
 	cfg := &options{
 		recoveryHandler: defaultRecoveryHandler,
+		stack:           true,
 	}
 
 	for _, opt := range opts {
@@ -54,3 +62,26 @@ func WithRecoveryHandler(f func(c context.Context, ctx *app.RequestContext, err
 		o.recoveryHandler = f
 	}
 }
+
+// WithPanicHooks adds the hooks called before the recovery handler.
+func WithPanicHooks(hooks ...PanicHook) Option {
+	return func(o *options) {
+		o.panicHooks = append(o.panicHooks, hooks...)
+	}
+}
+
+// WithDumpRequest sets whether to log the request line and headers of the panicking request,
+// the credentials in the headers, e.g. Authorization and Cookie, are redacted. Default is false.
+func WithDumpRequest(enable bool) Option {
+	return func(o *options) {
+		o.dumpRequest = enable
+	}
+}
+
+// WithStack sets whether to collect the stack of the panics, which reads the source files and is
+// expensive. Default is true.
+func WithStack(enable bool) Option {
+	return func(o *options) {
+		o.stack = enable
+	}
+}
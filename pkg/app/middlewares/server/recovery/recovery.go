@@ -19,11 +19,17 @@ package recovery
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"runtime"
+	"strings"
+	"syscall"
 
 	"hertz-study/pkg/app"
+	errs "hertz-study/pkg/common/errors"
+	"hertz-study/pkg/common/hlog"
+	"hertz-study/pkg/protocol/consts"
 )
 
 var (
@@ -36,21 +42,83 @@ var (
 // Recovery returns a middleware that recovers from any panic.
 // By default, it will print the time, content, and stack information of the error and write a 500.
 // Overriding the Config configuration, you can customize the error printing logic.
+//
+// The panics caused by the broken connections, e.g. writing to a connection closed by the client,
+// are only logged and abort the request without writing the 500 since nobody can receive it.
+// The other panics are also reported to the PanicReporter of the server if set.
 func Recovery(opts ...Option) app.HandlerFunc {
 	cfg := newOptions(opts...)
 
 	return func(c context.Context, ctx *app.RequestContext) {
 		defer func() {
 			if err := recover(); err != nil {
-				stack := stack(3)
+				if cfg.dumpRequest {
+					hlog.SystemLogger().CtxErrorf(c, "[Recovery] request=%s", dumpRequest(ctx))
+				}
+				if isBrokenConn(err) {
+					hlog.SystemLogger().CtxErrorf(c, "[Recovery] broken connection, err=%v", err)
+					ctx.Abort()
+					return
+				}
 
-				cfg.recoveryHandler(c, ctx, err, stack)
+				var stk []byte
+				if cfg.stack {
+					stk = stack(3)
+				}
+				for _, hook := range cfg.panicHooks {
+					hook(c, ctx, err, stk)
+				}
+				if report := ctx.PanicReporter(); report != nil {
+					report(c, err, stk)
+				}
+				cfg.recoveryHandler(c, ctx, err, stk)
 			}
 		}()
 		ctx.Next(c)
 	}
 }
 
+// isBrokenConn reports whether the panic is caused by a connection closed by the peer.
+func isBrokenConn(rcv interface{}) bool {
+	err, ok := rcv.(error)
+	if !ok {
+		return false
+	}
+	if errors.Is(err, syscall.EPIPE) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, errs.ErrConnectionClosed) {
+		return true
+	}
+	// the errors of some network libraries only keep the message
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "broken pipe") || strings.Contains(msg, "connection reset by peer")
+}
+
+var redactedHeaders = map[string]bool{
+	consts.HeaderAuthorization:      true,
+	consts.HeaderProxyAuthorization: true,
+	consts.HeaderCookie:             true,
+}
+
+// dumpRequest returns the request line and headers with the credentials redacted.
+func dumpRequest(ctx *app.RequestContext) string {
+	var b strings.Builder
+	b.Write(ctx.Request.Header.Method())
+	b.WriteByte(' ')
+	b.Write(ctx.Request.Header.RequestURI())
+	b.WriteByte(' ')
+	b.WriteString(ctx.Request.Header.GetProtocol())
+	ctx.Request.Header.VisitAll(func(k, v []byte) {
+		b.WriteString("\r\n")
+		b.Write(k)
+		b.WriteString(": ")
+		if redactedHeaders[string(k)] {
+			b.WriteString("*")
+			return
+		}
+		b.Write(v)
+	})
+	return b.String()
+}
+
 // stack returns a nicely formatted stack frame, skipping skip frames.
 func stack(skip int) []byte {
 	buf := new(bytes.Buffer) // the returned data
@@ -0,0 +1,109 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package retrydetect
+
+import (
+	"hertz-study/pkg/app/middlewares/server/priority"
+)
+
+const (
+	// DefaultKeyHeader is the standard header carrying the idempotency key of a request.
+	DefaultKeyHeader = "Idempotency-Key"
+	// ReplayedHeader is set to "true" on the responses replayed from the Store.
+	ReplayedHeader = "X-Retry-Replayed"
+
+	defaultMaxBodySize = 64 * 1024
+)
+
+// DefaultCountHeaders are the headers carrying the count of the previous attempts of a request,
+// e.g. "X-Retry-Count: 1" for the first retry.
+var DefaultCountHeaders = []string{"X-Retry-Count", "X-Retry-Attempt"}
+
+type (
+	options struct {
+		keyHeader     string
+		countHeaders  []string
+		store         Store
+		replay        bool
+		retryPriority *priority.Priority
+		maxBodySize   int
+	}
+
+	Option func(o *options)
+)
+
+func newOptions(opts ...Option) *options {
+	cfg := &options{
+		keyHeader:    DefaultKeyHeader,
+		countHeaders: DefaultCountHeaders,
+		maxBodySize:  defaultMaxBodySize,
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return cfg
+}
+
+// WithKeyHeader sets the header to read the idempotency key from, default is DefaultKeyHeader.
+func WithKeyHeader(header string) Option {
+	return func(o *options) {
+		o.keyHeader = header
+	}
+}
+
+// WithCountHeaders sets the headers to read the count of the previous attempts from,
+// default is DefaultCountHeaders.
+func WithCountHeaders(headers ...string) Option {
+	return func(o *options) {
+		o.countHeaders = headers
+	}
+}
+
+// WithStore sets the store remembering the idempotency keys and the responses, the requests
+// with a key are only detected as retries by the count headers if it is not set.
+func WithStore(s Store) Option {
+	return func(o *options) {
+		o.store = s
+	}
+}
+
+// WithReplay short-circuits the retries with the response of the same idempotency key saved
+// in the store, so that the side effects are not applied twice. Only the responses with
+// a status code less than 500 and a body not exceeding the max body size are saved.
+func WithReplay(b bool) Option {
+	return func(o *options) {
+		o.replay = b
+	}
+}
+
+// WithRetryPriority sets the priority of the retries, e.g. priority.High to finish the requests
+// the clients have been waiting for, or priority.Low to shed the retry storms first.
+// The middleware should be used before the loadshed middleware.
+func WithRetryPriority(p priority.Priority) Option {
+	return func(o *options) {
+		o.retryPriority = &p
+	}
+}
+
+// WithMaxBodySize sets the max size of the response body saved for replay, default is 64KB.
+func WithMaxBodySize(size int) Option {
+	return func(o *options) {
+		o.maxBodySize = size
+	}
+}
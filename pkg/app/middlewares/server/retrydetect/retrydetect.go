@@ -0,0 +1,139 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package retrydetect
+
+import (
+	"context"
+	"strconv"
+
+	"hertz-study/internal/headers"
+	"hertz-study/pkg/app"
+	"hertz-study/pkg/app/middlewares/server/priority"
+	"hertz-study/pkg/protocol/consts"
+)
+
+// Info describes whether a request is a retry, which is stored in the context by the middleware.
+type Info struct {
+	// Attempt is the count of the previous attempts claimed by the count headers.
+	Attempt int
+	// Key is the idempotency key of the request, empty if the request has no key.
+	Key string
+	// Seen is true if a request of the same key has been received before.
+	Seen bool
+	// Replayed is true if the response is replayed from the store.
+	Replayed bool
+}
+
+// IsRetry reports whether the request is a retry of a previous request.
+func (i Info) IsRetry() bool {
+	return i.Attempt > 0 || i.Seen
+}
+
+// ctxKey is the key of the Info stored in app.RequestContext.
+const ctxKey = "hertz_retry_info"
+
+// New returns a middleware which detects the retried requests by the idempotency key and the
+// count headers. The handlers can call IsRetry to skip the side effects of the retries, the
+// retries can also be prioritized or short-circuited with the saved response by the options.
+func New(opts ...Option) app.HandlerFunc {
+	cfg := newOptions(opts...)
+	return func(c context.Context, ctx *app.RequestContext) {
+		info := Info{Attempt: cfg.attempt(ctx)}
+		var storeKey string
+		var saved *Response
+		if cfg.store != nil {
+			if key := ctx.Request.Header.Peek(cfg.keyHeader); len(key) > 0 {
+				info.Key = string(key)
+				storeKey = string(ctx.Method()) + " " + string(ctx.Path()) + " " + info.Key
+				info.Seen, saved = cfg.store.Seen(storeKey)
+			}
+		}
+
+		if info.IsRetry() && cfg.retryPriority != nil {
+			priority.Set(ctx, *cfg.retryPriority)
+		}
+		if cfg.replay && saved != nil {
+			info.Replayed = true
+			ctx.Set(ctxKey, info)
+			replay(ctx, saved)
+			return
+		}
+		ctx.Set(ctxKey, info)
+		ctx.Next(c)
+
+		if storeKey != "" && cfg.replay {
+			if resp := cfg.capture(ctx); resp != nil {
+				cfg.store.Save(storeKey, resp)
+			}
+		}
+	}
+}
+
+// Get returns the retry info of the request detected by the middleware, the zero Info is
+// returned if the middleware is not used.
+func Get(ctx *app.RequestContext) Info {
+	if v, ok := ctx.Get(ctxKey); ok {
+		if info, ok := v.(Info); ok {
+			return info
+		}
+	}
+	return Info{}
+}
+
+// IsRetry reports whether the request is detected as a retry by the middleware.
+func IsRetry(ctx *app.RequestContext) bool {
+	return Get(ctx).IsRetry()
+}
+
+func (o *options) attempt(ctx *app.RequestContext) int {
+	for _, h := range o.countHeaders {
+		if v := ctx.Request.Header.Peek(h); len(v) > 0 {
+			if n, err := strconv.Atoi(string(v)); err == nil && n > 0 {
+				return n
+			}
+		}
+	}
+	return 0
+}
+
+// capture returns the response to save, or nil if it should not be replayed.
+func (o *options) capture(ctx *app.RequestContext) *Response {
+	status := ctx.Response.StatusCode()
+	if status >= consts.StatusInternalServerError || ctx.Response.IsBodyStream() || len(ctx.Response.Body()) > o.maxBodySize {
+		return nil
+	}
+	resp := &Response{
+		StatusCode: status,
+		Body:       append([]byte(nil), ctx.Response.Body()...),
+	}
+	ctx.Response.Header.VisitAll(func(k, v []byte) {
+		switch string(k) {
+		case consts.HeaderContentLength, consts.HeaderDate, consts.HeaderServer, consts.HeaderConnection, consts.HeaderTransferEncoding:
+			return
+		}
+		resp.Header = append(resp.Header, [2]string{string(k), string(v)})
+	})
+	return resp
+}
+
+func replay(ctx *app.RequestContext, resp *Response) {
+	headers.Replay(&ctx.Response.Header, resp.Header)
+	ctx.Response.Header.Set(ReplayedHeader, "true")
+	ctx.Response.SetStatusCode(resp.StatusCode)
+	ctx.Response.SetBody(resp.Body)
+	ctx.Abort()
+}
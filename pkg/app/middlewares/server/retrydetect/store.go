@@ -0,0 +1,109 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package retrydetect
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Response is a response saved in the Store for replay.
+type Response struct {
+	StatusCode int
+	Header     [][2]string
+	Body       []byte
+}
+
+// Store remembers the idempotency keys and the responses, it must be safe for concurrent use.
+// The keys passed to the store are scoped by the method and the path of the requests.
+type Store interface {
+	// Seen marks key as received and reports whether it has been received before,
+	// resp is the saved response of key if there is one.
+	Seen(key string) (seen bool, resp *Response)
+	// Save saves the response of key.
+	Save(key string, resp *Response)
+}
+
+type memoryEntry struct {
+	key      string
+	expireAt time.Time
+	resp     *Response
+}
+
+// MemoryStore is a Store keeping the keys in memory for a fixed TTL, the oldest keys are evicted
+// when the count of keys exceeds the limit.
+type MemoryStore struct {
+	ttl     time.Duration
+	maxKeys int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// the entries ordered by the time they are received, which is also the order of expiration
+	order *list.List
+}
+
+// NewMemoryStore creates a MemoryStore remembering a key for ttl, at most maxKeys keys are kept
+// if maxKeys is positive.
+func NewMemoryStore(ttl time.Duration, maxKeys int) *MemoryStore {
+	return &MemoryStore{
+		ttl:     ttl,
+		maxKeys: maxKeys,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// Seen implements Store.
+func (s *MemoryStore) Seen(key string) (bool, *Response) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.evict(now)
+	if el, ok := s.entries[key]; ok {
+		return true, el.Value.(*memoryEntry).resp
+	}
+	s.entries[key] = s.order.PushBack(&memoryEntry{key: key, expireAt: now.Add(s.ttl)})
+	return false, nil
+}
+
+// Save implements Store.
+func (s *MemoryStore) Save(key string, resp *Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.entries[key]; ok {
+		el.Value.(*memoryEntry).resp = resp
+	}
+}
+
+// Len returns the count of the keys in the store.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+func (s *MemoryStore) evict(now time.Time) {
+	for el := s.order.Front(); el != nil; el = s.order.Front() {
+		e := el.Value.(*memoryEntry)
+		if now.Before(e.expireAt) && (s.maxKeys <= 0 || s.order.Len() < s.maxKeys) {
+			return
+		}
+		s.order.Remove(el)
+		delete(s.entries, e.key)
+	}
+}
@@ -0,0 +1,87 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package routestats
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	defaultWindow = time.Hour
+	defaultTopN   = 10
+)
+
+type (
+	options struct {
+		window   time.Duration
+		topN     int
+		excludes []string
+	}
+
+	Option func(o *options)
+)
+
+func newOptions(opts ...Option) *options {
+	cfg := &options{
+		window: defaultWindow,
+		topN:   defaultTopN,
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return cfg
+}
+
+// WithWindow sets the rolling window in which the recent hits are counted, default is 1h.
+// The hot routes are ranked by the recent hits.
+func WithWindow(window time.Duration) Option {
+	return func(o *options) {
+		if window > 0 {
+			o.window = window
+		}
+	}
+}
+
+// WithTopN sets the default count of hot routes reported by Handler, default is 10.
+// It can be overridden by the "top" query argument.
+func WithTopN(n int) Option {
+	return func(o *options) {
+		if n > 0 {
+			o.topN = n
+		}
+	}
+}
+
+// WithExcludePrefixes excludes the routes whose path has any of prefixes from counting and
+// reporting, e.g. the debug endpoints.
+func WithExcludePrefixes(prefixes ...string) Option {
+	return func(o *options) {
+		o.excludes = append(o.excludes, prefixes...)
+	}
+}
+
+func (o *options) excluded(path string) bool {
+	for _, prefix := range o.excludes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,198 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package routestats
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"hertz-study/pkg/app"
+	"hertz-study/pkg/protocol/consts"
+	"hertz-study/pkg/route"
+)
+
+// RouteStat is the usage of a registered route.
+type RouteStat struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	// Hits is the count of requests since the collector is created.
+	Hits uint64 `json:"hits"`
+	// RecentHits is the count of requests in the rolling window.
+	RecentHits uint64 `json:"recent_hits"`
+	// LastHit is nil if the route is never hit.
+	LastHit *time.Time `json:"last_hit,omitempty"`
+}
+
+// Report is the usage report of the routes of an engine.
+type Report struct {
+	Since  time.Time `json:"since"`
+	Window string    `json:"window"`
+	// Hot is the routes with the most recent hits in descending order.
+	Hot []RouteStat `json:"hot"`
+	// NeverHit is the routes which are never hit since the collector is created,
+	// the candidates of dead endpoints.
+	NeverHit []RouteStat `json:"never_hit"`
+}
+
+type counter struct {
+	hits    uint64
+	lastHit int64
+	window  *window
+}
+
+// Collector counts the hits of every route of an engine, which helps to find the hot
+// routes and the dead ones.
+type Collector struct {
+	opts   *options
+	engine *route.Engine
+	since  time.Time
+
+	mu       sync.RWMutex
+	counters map[string]*counter
+}
+
+// NewCollector creates a Collector of the routes of engine, use Middleware to count the hits
+// and Handler to expose the report. Register is recommended for most cases.
+func NewCollector(engine *route.Engine, opts ...Option) *Collector {
+	return &Collector{
+		opts:     newOptions(opts...),
+		engine:   engine,
+		since:    time.Now(),
+		counters: make(map[string]*counter),
+	}
+}
+
+// Register creates a Collector and installs its middleware on engine.
+//
+// NOTE: it should be called before registering routes, since the middleware only applies to the
+// routes registered after it, the routes registered before are always reported as never hit.
+func Register(engine *route.Engine, opts ...Option) *Collector {
+	c := NewCollector(engine, opts...)
+	engine.Use(c.Middleware())
+	return c
+}
+
+// Middleware returns a middleware which counts the hits of the matched route.
+func (c *Collector) Middleware() app.HandlerFunc {
+	return func(ctx context.Context, rc *app.RequestContext) {
+		if fullPath := rc.FullPath(); fullPath != "" && !c.opts.excluded(fullPath) {
+			now := time.Now()
+			cnt := c.counter(string(rc.Method()), fullPath)
+			atomic.AddUint64(&cnt.hits, 1)
+			atomic.StoreInt64(&cnt.lastHit, now.UnixNano())
+			cnt.window.add(now)
+		}
+		rc.Next(ctx)
+	}
+}
+
+// Stats returns the usage of all the registered routes, sorted by method and path.
+func (c *Collector) Stats() []RouteStat {
+	now := time.Now()
+	routes := c.engine.Routes()
+	stats := make([]RouteStat, 0, len(routes))
+	c.mu.RLock()
+	for _, r := range routes {
+		if c.opts.excluded(r.Path) {
+			continue
+		}
+		s := RouteStat{Method: r.Method, Path: r.Path}
+		if cnt, ok := c.counters[r.Method+" "+r.Path]; ok {
+			s.Hits = atomic.LoadUint64(&cnt.hits)
+			s.RecentHits = cnt.window.sum(now)
+			lastHit := time.Unix(0, atomic.LoadInt64(&cnt.lastHit))
+			s.LastHit = &lastHit
+		}
+		stats = append(stats, s)
+	}
+	c.mu.RUnlock()
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Path != stats[j].Path {
+			return stats[i].Path < stats[j].Path
+		}
+		return stats[i].Method < stats[j].Method
+	})
+	return stats
+}
+
+// Report returns the top n hot routes and the never hit routes.
+func (c *Collector) Report(n int) Report {
+	r := Report{
+		Since:    c.since,
+		Window:   c.opts.window.String(),
+		Hot:      []RouteStat{},
+		NeverHit: []RouteStat{},
+	}
+	stats := c.Stats()
+	for _, s := range stats {
+		if s.LastHit == nil {
+			r.NeverHit = append(r.NeverHit, s)
+		}
+	}
+	sort.SliceStable(stats, func(i, j int) bool {
+		if stats[i].RecentHits != stats[j].RecentHits {
+			return stats[i].RecentHits > stats[j].RecentHits
+		}
+		return stats[i].Hits > stats[j].Hits
+	})
+	for _, s := range stats {
+		if len(r.Hot) >= n || s.Hits == 0 {
+			break
+		}
+		r.Hot = append(r.Hot, s)
+	}
+	return r
+}
+
+// Handler returns a handler which exposes the report in JSON, the count of hot routes is
+// given by the "top" query argument or WithTopN.
+func (c *Collector) Handler() app.HandlerFunc {
+	return func(ctx context.Context, rc *app.RequestContext) {
+		n := c.opts.topN
+		if top := rc.Query("top"); top != "" {
+			v, err := strconv.Atoi(top)
+			if err != nil || v <= 0 {
+				rc.String(consts.StatusBadRequest, "invalid top: %s", top)
+				return
+			}
+			n = v
+		}
+		rc.JSON(consts.StatusOK, c.Report(n))
+	}
+}
+
+func (c *Collector) counter(method, fullPath string) *counter {
+	key := method + " " + fullPath
+	c.mu.RLock()
+	cnt, ok := c.counters[key]
+	c.mu.RUnlock()
+	if ok {
+		return cnt
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cnt, ok = c.counters[key]; !ok {
+		cnt = &counter{window: newWindow(c.opts.window)}
+		c.counters[key] = cnt
+	}
+	return cnt
+}
@@ -0,0 +1,70 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package routestats
+
+import (
+	"sync"
+	"time"
+)
+
+// windowSlots is the count of slots of a rolling window, the window slides by one slot.
+const windowSlots = 60
+
+type slot struct {
+	index int64
+	hits  uint64
+}
+
+// window counts the hits in the latest duration.
+type window struct {
+	slotSize int64
+
+	mu    sync.Mutex
+	slots [windowSlots]slot
+}
+
+func newWindow(d time.Duration) *window {
+	size := int64(d) / windowSlots
+	if size <= 0 {
+		size = 1
+	}
+	return &window{slotSize: size}
+}
+
+func (w *window) add(now time.Time) {
+	idx := now.UnixNano() / w.slotSize
+	w.mu.Lock()
+	s := &w.slots[idx%windowSlots]
+	if s.index != idx {
+		*s = slot{index: idx}
+	}
+	s.hits++
+	w.mu.Unlock()
+}
+
+// sum returns the hits of the slots in the window ending at now.
+func (w *window) sum(now time.Time) (hits uint64) {
+	idx := now.UnixNano() / w.slotSize
+	w.mu.Lock()
+	for i := range w.slots {
+		if s := &w.slots[i]; idx-s.index < windowSlots {
+			hits += s.hits
+		}
+	}
+	w.mu.Unlock()
+	return
+}
@@ -0,0 +1,78 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package schema
+
+type (
+	options struct {
+		enabled      bool
+		failOnError  bool
+		allowUnknown bool
+		statusCodes  func(code int) bool
+	}
+
+	Option func(o *options)
+)
+
+func newOptions(opts ...Option) *options {
+	cfg := &options{
+		enabled:     true,
+		statusCodes: isSuccess,
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return cfg
+}
+
+func isSuccess(code int) bool {
+	return code >= 200 && code < 300
+}
+
+// WithEnabled sets whether the validation is enabled, default is true. The validation
+// decodes every response, so it is meant for development, e.g. WithEnabled(os.Getenv("ENV") == "dev").
+// The middleware does nothing when disabled.
+func WithEnabled(b bool) Option {
+	return func(o *options) {
+		o.enabled = b
+	}
+}
+
+// WithFailOnMismatch sets whether the response is replaced with 500 listing the mismatches,
+// default is false which only logs them.
+func WithFailOnMismatch(b bool) Option {
+	return func(o *options) {
+		o.failOnError = b
+	}
+}
+
+// WithAllowUnknownFields sets whether the fields not declared in the response type are allowed,
+// default is false.
+func WithAllowUnknownFields(b bool) Option {
+	return func(o *options) {
+		o.allowUnknown = b
+	}
+}
+
+// WithStatusCodes sets the function deciding whether the response of the status code is
+// validated, default is the 2xx ones, since the error responses are usually of other types.
+func WithStatusCodes(f func(code int) bool) Option {
+	return func(o *options) {
+		o.statusCodes = f
+	}
+}
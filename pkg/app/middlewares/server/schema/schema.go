@@ -0,0 +1,79 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package schema
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+
+	"hertz-study/pkg/app"
+	"hertz-study/pkg/common/hlog"
+	"hertz-study/pkg/protocol/consts"
+)
+
+// Response returns a middleware declaring the response type of the route as the type of v,
+// e.g. schema.Response(UserResp{}), and validating the JSON responses of the handlers against
+// it to catch the contract drift in development. The mismatches, e.g. unknown or missing fields
+// and values of wrong types, are logged or fail the request according to WithFailOnMismatch.
+//
+// The fields with omitempty may be missing, and the types implementing json.Marshaler are not
+// validated. Non-JSON and streaming responses are skipped.
+func Response(v interface{}, opts ...Option) app.HandlerFunc {
+	o := newOptions(opts...)
+	t := reflect.TypeOf(v)
+	return func(c context.Context, ctx *app.RequestContext) {
+		if !o.enabled || t == nil {
+			ctx.Next(c)
+			return
+		}
+		ctx.Next(c)
+
+		if !o.statusCodes(ctx.Response.StatusCode()) || ctx.Response.IsBodyStream() ||
+			!bytes.Contains(ctx.Response.Header.ContentType(), []byte("json")) {
+			return
+		}
+		mismatches := Validate(t, ctx.Response.Body(), o.allowUnknown)
+		if len(mismatches) == 0 {
+			return
+		}
+		hlog.SystemLogger().CtxErrorf(c, "[Schema] response mismatches the declared type=%s: route=%s, mismatches=%v",
+			t, ctx.FullPath(), mismatches)
+		if o.failOnError {
+			ctx.AbortWithMsg("response mismatches the declared type "+t.String()+":\n"+strings.Join(mismatches, "\n"),
+				consts.StatusInternalServerError)
+		}
+	}
+}
+
+// Validate checks whether the JSON body can be encoded from a value of type t, and returns
+// the mismatches with the JSON paths, e.g. "$.items[0].id: expected integer, got string".
+func Validate(t reflect.Type, body []byte, allowUnknownFields bool) []string {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var val interface{}
+	if err := dec.Decode(&val); err != nil {
+		return []string{"$: invalid JSON: " + err.Error()}
+	}
+	v := &validator{allowUnknown: allowUnknownFields}
+	v.validate("$", t, val)
+	sort.Strings(v.mismatches)
+	return v.mismatches
+}
@@ -0,0 +1,265 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package schema
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	marshalerType     = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+	fieldsCache sync.Map // reflect.Type -> []field
+)
+
+// field is a JSON field of a struct.
+type field struct {
+	name      string
+	typ       reflect.Type
+	omitempty bool
+	quoted    bool
+	// the field is promoted from an embedded struct pointer, which may be nil
+	optional bool
+}
+
+type validator struct {
+	allowUnknown bool
+	mismatches   []string
+}
+
+func (v *validator) addf(path, format string, args ...interface{}) {
+	v.mismatches = append(v.mismatches, path+": "+fmt.Sprintf(format, args...))
+}
+
+// validate checks the decoded JSON value val against t, as if val is encoded from a value of t.
+func (v *validator) validate(path string, t reflect.Type, val interface{}) {
+	// the encoding of the custom marshalers is unknown
+	if t.Implements(marshalerType) || reflect.PtrTo(t).Implements(marshalerType) {
+		return
+	}
+	if t.Implements(textMarshalerType) || reflect.PtrTo(t).Implements(textMarshalerType) {
+		if _, ok := val.(string); !ok && val != nil {
+			v.addf(path, "expected string, got %s", kindOf(val))
+		}
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		if val != nil {
+			v.validate(path, t.Elem(), val)
+		}
+	case reflect.Interface:
+	case reflect.Struct:
+		obj, ok := val.(map[string]interface{})
+		if !ok {
+			v.addf(path, "expected object, got %s", kindOf(val))
+			return
+		}
+		v.validateStruct(path, t, obj)
+	case reflect.Map:
+		if val == nil {
+			return
+		}
+		obj, ok := val.(map[string]interface{})
+		if !ok {
+			v.addf(path, "expected object, got %s", kindOf(val))
+			return
+		}
+		for k, e := range obj {
+			v.validate(path+"."+k, t.Elem(), e)
+		}
+	case reflect.Slice:
+		if val == nil {
+			return
+		}
+		if t.Elem().Kind() == reflect.Uint8 && !reflect.PtrTo(t.Elem()).Implements(marshalerType) {
+			// []byte is encoded as base64 string
+			if _, ok := val.(string); !ok {
+				v.addf(path, "expected string, got %s", kindOf(val))
+			}
+			return
+		}
+		v.validateArray(path, t, val, -1)
+	case reflect.Array:
+		v.validateArray(path, t, val, t.Len())
+	case reflect.String:
+		if _, ok := val.(string); !ok {
+			v.addf(path, "expected string, got %s", kindOf(val))
+		}
+	case reflect.Bool:
+		if _, ok := val.(bool); !ok {
+			v.addf(path, "expected boolean, got %s", kindOf(val))
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, ok := val.(json.Number)
+		if !ok {
+			v.addf(path, "expected integer, got %s", kindOf(val))
+			return
+		}
+		if _, err := strconv.ParseInt(n.String(), 10, 64); err != nil {
+			if _, err = strconv.ParseUint(n.String(), 10, 64); err != nil {
+				v.addf(path, "expected integer, got %s", n)
+			}
+		}
+	case reflect.Float32, reflect.Float64:
+		if _, ok := val.(json.Number); !ok {
+			v.addf(path, "expected number, got %s", kindOf(val))
+		}
+	}
+}
+
+func (v *validator) validateArray(path string, t reflect.Type, val interface{}, length int) {
+	arr, ok := val.([]interface{})
+	if !ok {
+		v.addf(path, "expected array, got %s", kindOf(val))
+		return
+	}
+	if length >= 0 && len(arr) != length {
+		v.addf(path, "expected array of length %d, got %d", length, len(arr))
+	}
+	for i, e := range arr {
+		v.validate(path+"["+strconv.Itoa(i)+"]", t.Elem(), e)
+	}
+}
+
+func (v *validator) validateStruct(path string, t reflect.Type, obj map[string]interface{}) {
+	fields := structFields(t)
+	seen := make([]bool, len(fields))
+	for k, e := range obj {
+		i := lookupField(fields, k)
+		if i < 0 {
+			if !v.allowUnknown {
+				v.addf(path+"."+k, "unknown field")
+			}
+			continue
+		}
+		seen[i] = true
+		f := fields[i]
+		if f.quoted {
+			// the ",string" option quotes the scalar values
+			if _, ok := e.(string); !ok && e != nil {
+				v.addf(path+"."+k, "expected quoted string, got %s", kindOf(e))
+			}
+			continue
+		}
+		v.validate(path+"."+k, f.typ, e)
+	}
+	for i, f := range fields {
+		if !seen[i] && !f.omitempty && !f.optional {
+			v.addf(path+"."+f.name, "missing field")
+		}
+	}
+}
+
+func lookupField(fields []field, name string) int {
+	for i := range fields {
+		if fields[i].name == name {
+			return i
+		}
+	}
+	// encoding/json matches the names case-insensitively
+	for i := range fields {
+		if strings.EqualFold(fields[i].name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// structFields returns the JSON fields of the struct type t, including the promoted ones.
+func structFields(t reflect.Type) []field {
+	if v, ok := fieldsCache.Load(t); ok {
+		return v.([]field)
+	}
+	fields := appendFields(nil, t, false, map[reflect.Type]bool{})
+	fieldsCache.Store(t, fields)
+	return fields
+}
+
+func appendFields(fields []field, t reflect.Type, optional bool, visited map[reflect.Type]bool) []field {
+	if visited[t] {
+		return fields
+	}
+	visited[t] = true
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if sf.Anonymous && name == "" {
+			ft := sf.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				fields = appendFields(fields, ft, optional || sf.Type.Kind() == reflect.Ptr, visited)
+				continue
+			}
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		f := field{name: name, typ: sf.Type, optional: optional}
+		for _, o := range strings.Split(opts, ",") {
+			switch o {
+			case "omitempty":
+				f.omitempty = true
+			case "string":
+				switch sf.Type.Kind() {
+				case reflect.Bool, reflect.String, reflect.Float32, reflect.Float64,
+					reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+					reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+					f.quoted = true
+				}
+			}
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+func kindOf(val interface{}) string {
+	switch val.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case json.Number:
+		return "number"
+	case bool:
+		return "boolean"
+	}
+	return fmt.Sprintf("%T", val)
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package singleflight
+
+import (
+	"context"
+
+	"hertz-study/pkg/app"
+	"hertz-study/pkg/app/middlewares/server/cache"
+	"hertz-study/pkg/protocol/consts"
+)
+
+const defaultMaxBodySize = 1024 * 1024
+
+// KeyFunc derives the key of a request, the concurrent requests with the same key are coalesced.
+type KeyFunc func(c context.Context, ctx *app.RequestContext) string
+
+type (
+	options struct {
+		keyFunc     KeyFunc
+		methods     map[string]bool
+		maxBodySize int
+	}
+
+	Option func(o *options)
+)
+
+func newOptions(opts ...Option) *options {
+	cfg := &options{
+		keyFunc:     DefaultKey,
+		methods:     map[string]bool{consts.MethodGet: true, consts.MethodHead: true},
+		maxBodySize: defaultMaxBodySize,
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return cfg
+}
+
+// DefaultKey is cache.DefaultKey extended by the Authorization and Cookie headers, so that
+// the requests of different users are never coalesced.
+var DefaultKey = KeyFunc(cache.HeaderKey(consts.HeaderAuthorization, consts.HeaderCookie))
+
+// WithKeyFunc sets how the key is derived from a request, default is DefaultKey.
+// The requests with the same key must get the same response, e.g. use cache.DefaultKey to
+// coalesce the requests to a public endpoint regardless of the users.
+func WithKeyFunc(f KeyFunc) Option {
+	return func(o *options) {
+		o.keyFunc = f
+	}
+}
+
+// WithMethods sets the methods of the requests to coalesce, default is GET and HEAD.
+func WithMethods(methods ...string) Option {
+	return func(o *options) {
+		o.methods = make(map[string]bool, len(methods))
+		for _, m := range methods {
+			o.methods[m] = true
+		}
+	}
+}
+
+// WithMaxBodySize sets the max size of the response body shared with the coalesced requests,
+// default is 1MB. The coalesced requests are handled by themselves if the body is larger.
+func WithMaxBodySize(size int) Option {
+	return func(o *options) {
+		o.maxBodySize = size
+	}
+}
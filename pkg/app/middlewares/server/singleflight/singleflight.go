@@ -0,0 +1,105 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package singleflight
+
+import (
+	"context"
+
+	"golang.org/x/sync/singleflight"
+	"hertz-study/internal/headers"
+	"hertz-study/pkg/app"
+	"hertz-study/pkg/protocol/consts"
+)
+
+// SharedHeader is set to "true" on the responses shared from the request coalesced with.
+const SharedHeader = "X-Coalesced"
+
+type response struct {
+	statusCode int
+	header     [][2]string
+	body       []byte
+}
+
+// New returns a middleware which coalesces the concurrent requests with the same key: only the
+// first one is handled, the others wait for it and get a copy of its response, e.g.
+//
+//	h.GET("/report", singleflight.New(), buildReport)
+//
+// Unlike the cache middleware, nothing is kept after the handling finishes. The responses with
+// a streamed body, a Set-Cookie header or a body larger than the max body size aren't shared,
+// the waiting requests are handled by themselves instead, as well as if the handling panics.
+func New(opts ...Option) app.HandlerFunc {
+	o := newOptions(opts...)
+	var group singleflight.Group
+	return func(c context.Context, ctx *app.RequestContext) {
+		if !o.methods[string(ctx.Method())] {
+			ctx.Next(c)
+			return
+		}
+
+		var leader bool
+		var panicked interface{}
+		v, _, _ := group.Do(o.keyFunc(c, ctx), func() (interface{}, error) {
+			leader = true
+			// the panic is raised again after Do, so that the waiting requests aren't failed by it
+			defer func() {
+				panicked = recover()
+			}()
+			ctx.Next(c)
+			return o.capture(ctx), nil
+		})
+		if leader {
+			if panicked != nil {
+				panic(panicked)
+			}
+			return
+		}
+		if resp, _ := v.(*response); resp != nil {
+			resp.writeTo(ctx)
+			return
+		}
+		ctx.Next(c)
+	}
+}
+
+// capture returns the response to share, or nil if it should not be shared.
+func (o *options) capture(ctx *app.RequestContext) *response {
+	resp := &ctx.Response
+	if resp.IsBodyStream() || len(resp.Body()) > o.maxBodySize || len(resp.Header.Peek(consts.HeaderSetCookie)) > 0 {
+		return nil
+	}
+	r := &response{
+		statusCode: resp.StatusCode(),
+		body:       append([]byte(nil), resp.Body()...),
+	}
+	resp.Header.VisitAll(func(k, v []byte) {
+		switch string(k) {
+		case consts.HeaderContentLength, consts.HeaderDate, consts.HeaderServer, consts.HeaderConnection, consts.HeaderTransferEncoding:
+			return
+		}
+		r.header = append(r.header, [2]string{string(k), string(v)})
+	})
+	return r
+}
+
+func (r *response) writeTo(ctx *app.RequestContext) {
+	headers.Replay(&ctx.Response.Header, r.header)
+	ctx.Response.Header.Set(SharedHeader, "true")
+	ctx.Response.SetStatusCode(r.statusCode)
+	ctx.Response.SetBody(r.body)
+	ctx.Abort()
+}
@@ -0,0 +1,109 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package slo
+
+import (
+	"context"
+	"time"
+
+	"hertz-study/pkg/app"
+)
+
+const (
+	defaultNamespace   = "hertz"
+	defaultWindow      = time.Hour
+	defaultMinRequests = 100
+)
+
+type (
+	options struct {
+		namespace   string
+		window      time.Duration
+		minRequests uint64
+		isBad       func(c context.Context, ctx *app.RequestContext) bool
+		onExhausted func(r Report)
+		onRecovered func(r Report)
+	}
+
+	Option func(o *options)
+)
+
+func newOptions(opts ...Option) *options {
+	cfg := &options{
+		namespace:   defaultNamespace,
+		window:      defaultWindow,
+		minRequests: defaultMinRequests,
+		isBad:       defaultIsBad,
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return cfg
+}
+
+// defaultIsBad treats the requests answered with 5xx as failed.
+func defaultIsBad(_ context.Context, ctx *app.RequestContext) bool {
+	return ctx.Response.StatusCode() >= 500
+}
+
+// WithNamespace sets the prefix of metric names, default is "hertz".
+func WithNamespace(namespace string) Option {
+	return func(o *options) {
+		o.namespace = namespace
+	}
+}
+
+// WithWindow sets the default rolling window of the objectives, default is 1h.
+func WithWindow(window time.Duration) Option {
+	return func(o *options) {
+		o.window = window
+	}
+}
+
+// WithMinRequests sets the min count of requests in the window before the budget is evaluated,
+// which avoids tripping on few failures under low traffic, default is 100.
+func WithMinRequests(n uint64) Option {
+	return func(o *options) {
+		o.minRequests = n
+	}
+}
+
+// WithFailureJudge sets the function deciding whether a request counts against the availability
+// objective, default is the requests answered with 5xx.
+func WithFailureJudge(f func(c context.Context, ctx *app.RequestContext) bool) Option {
+	return func(o *options) {
+		o.isBad = f
+	}
+}
+
+// WithOnBudgetExhausted sets the function called when the error budget of an objective is
+// used up, e.g. to trip the maintenance mode or to alert. It is called once until the budget
+// recovers, in the goroutine of the request.
+func WithOnBudgetExhausted(f func(r Report)) Option {
+	return func(o *options) {
+		o.onExhausted = f
+	}
+}
+
+// WithOnBudgetRecovered sets the function called when the exhausted error budget recovers.
+func WithOnBudgetRecovered(f func(r Report)) Option {
+	return func(o *options) {
+		o.onRecovered = f
+	}
+}
@@ -0,0 +1,305 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package slo tracks the success rate and the latency of the routes against the service level
+// objectives over rolling windows, and exposes the burn rates of the error budgets.
+//
+//	tracker := slo.New([]slo.Objective{
+//		{Availability: 0.999, LatencyThreshold: 300 * time.Millisecond, LatencyTarget: 0.99},
+//	}, slo.WithOnBudgetExhausted(func(r slo.Report) { alert(r) }))
+//	h.Use(tracker.Middleware())
+//	h.GET("/slo", tracker.Handler())
+package slo
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"hertz-study/pkg/app"
+	"hertz-study/pkg/protocol/consts"
+)
+
+// the kinds of service level indicators
+const (
+	SLIAvailability = "availability"
+	SLILatency      = "latency"
+)
+
+// evaluateInterval bounds how often the budgets are evaluated on the request path.
+const evaluateInterval = time.Second
+
+const contentTypeText = "text/plain; version=0.0.4; charset=utf-8"
+
+// Objective is a service level objective of the requests matching Method and Route.
+type Objective struct {
+	// Name identifies the objective in the reports and the metrics, default is "<Method> <Route>".
+	Name string
+	// Method matches the method of requests, empty matches all methods.
+	Method string
+	// Route matches the route template of requests, e.g. "/user/:id". Empty means the objective
+	// applies to every route individually.
+	Route string
+	// Availability is the target ratio of the requests which do not fail, e.g. 0.999.
+	// Zero disables the availability objective.
+	Availability float64
+	// LatencyThreshold and LatencyTarget are the target ratio of the requests served within the
+	// threshold, e.g. 99% within 300ms. Zero disables the latency objective.
+	LatencyThreshold time.Duration
+	LatencyTarget    float64
+	// Window is the rolling window the objective is evaluated over, default is set by WithWindow.
+	Window time.Duration
+}
+
+// Report is the state of an indicator of an objective in its window.
+type Report struct {
+	Objective string
+	Method    string
+	Route     string
+	SLI       string
+	Target    float64
+	Total     uint64
+	Bad       uint64
+	// BurnRate is how fast the error budget is being spent, 1 means the budget is used up
+	// exactly at the end of the window.
+	BurnRate float64
+	// BudgetRemaining is the ratio of the error budget left in the window, it is negative
+	// when the objective is violated.
+	BudgetRemaining float64
+	Exhausted       bool
+}
+
+// Tracker tracks the objectives of the requests.
+type Tracker struct {
+	opts       *options
+	objectives []Objective
+
+	mu     sync.RWMutex
+	states map[string]*state
+
+	lastEvaluate int64
+}
+
+// state is the tracking state of an objective of a method and route pair.
+type state struct {
+	objective *Objective
+	name      string
+	method    string
+	route     string
+	window    *window
+
+	exhausted [2]int32 // availability, latency
+}
+
+// New creates a Tracker of objectives.
+func New(objectives []Objective, opts ...Option) *Tracker {
+	t := &Tracker{
+		opts:       newOptions(opts...),
+		objectives: make([]Objective, len(objectives)),
+		states:     make(map[string]*state),
+	}
+	copy(t.objectives, objectives)
+	for i := range t.objectives {
+		if t.objectives[i].Window <= 0 {
+			t.objectives[i].Window = t.opts.window
+		}
+	}
+	return t
+}
+
+// Middleware returns a middleware which records the outcome and the latency of every request
+// into the objectives it matches.
+func (t *Tracker) Middleware() app.HandlerFunc {
+	return func(c context.Context, ctx *app.RequestContext) {
+		start := time.Now()
+		ctx.Next(c)
+
+		route := ctx.FullPath()
+		if route == "" {
+			// requests matching no route are not attributed to any objective
+			return
+		}
+		now := time.Now()
+		latency := now.Sub(start)
+		method := string(ctx.Method())
+		bad := t.opts.isBad(c, ctx)
+		for i := range t.objectives {
+			o := &t.objectives[i]
+			if (o.Method != "" && o.Method != method) || (o.Route != "" && o.Route != route) {
+				continue
+			}
+			slow := o.LatencyThreshold > 0 && latency > o.LatencyThreshold
+			t.state(i, route).window.add(now, bad, slow)
+		}
+
+		if last := atomic.LoadInt64(&t.lastEvaluate); now.UnixNano()-last >= int64(evaluateInterval) &&
+			atomic.CompareAndSwapInt64(&t.lastEvaluate, last, now.UnixNano()) {
+			t.evaluate(now)
+		}
+	}
+}
+
+// Reports returns the current reports of all the tracked objectives.
+func (t *Tracker) Reports() []Report {
+	now := time.Now()
+	var reports []Report
+	for _, s := range t.allStates() {
+		reports = append(reports, s.reports(now, t.opts.minRequests)...)
+	}
+	return reports
+}
+
+// Handler returns a handler which exposes the reports in prometheus text format.
+func (t *Tracker) Handler() app.HandlerFunc {
+	return func(c context.Context, ctx *app.RequestContext) {
+		reports := t.Reports()
+		var buf bytes.Buffer
+		ns := t.opts.namespace + "_slo_"
+		writeGauge(&buf, ns+"target", "Target ratio of good requests.", reports, func(r *Report) float64 { return r.Target })
+		writeGauge(&buf, ns+"requests", "Number of requests in the window.", reports, func(r *Report) float64 { return float64(r.Total) })
+		writeGauge(&buf, ns+"bad_requests", "Number of requests violating the objective in the window.", reports, func(r *Report) float64 { return float64(r.Bad) })
+		writeGauge(&buf, ns+"burn_rate", "Burn rate of the error budget in the window.", reports, func(r *Report) float64 { return r.BurnRate })
+		writeGauge(&buf, ns+"error_budget_remaining", "Ratio of the error budget left in the window.", reports, func(r *Report) float64 { return r.BudgetRemaining })
+		ctx.Data(consts.StatusOK, contentTypeText, buf.Bytes())
+	}
+}
+
+// state returns the state of the i-th objective of route, the requests of all the methods
+// share the state if the objective matches all methods.
+func (t *Tracker) state(i int, route string) *state {
+	key := strconv.Itoa(i) + "\xff" + route
+	t.mu.RLock()
+	s, ok := t.states[key]
+	t.mu.RUnlock()
+	if ok {
+		return s
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if s, ok = t.states[key]; ok {
+		return s
+	}
+	o := &t.objectives[i]
+	name := o.Name
+	if name == "" {
+		name = strings.TrimSpace(o.Method + " " + route)
+	}
+	s = &state{objective: o, name: name, method: o.Method, route: route, window: newWindow(o.Window)}
+	t.states[key] = s
+	return s
+}
+
+func (t *Tracker) allStates() []*state {
+	t.mu.RLock()
+	keys := make([]string, 0, len(t.states))
+	for k := range t.states {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	states := make([]*state, len(keys))
+	for i, k := range keys {
+		states[i] = t.states[k]
+	}
+	t.mu.RUnlock()
+	return states
+}
+
+// evaluate calls the callbacks of the budgets whose exhaustion state changes.
+func (t *Tracker) evaluate(now time.Time) {
+	if t.opts.onExhausted == nil && t.opts.onRecovered == nil {
+		return
+	}
+	for _, s := range t.allStates() {
+		for _, r := range s.reports(now, t.opts.minRequests) {
+			flag := &s.exhausted[0]
+			if r.SLI == SLILatency {
+				flag = &s.exhausted[1]
+			}
+			switch {
+			case r.Exhausted && atomic.CompareAndSwapInt32(flag, 0, 1):
+				if t.opts.onExhausted != nil {
+					t.opts.onExhausted(r)
+				}
+			case !r.Exhausted && atomic.CompareAndSwapInt32(flag, 1, 0):
+				if t.opts.onRecovered != nil {
+					t.opts.onRecovered(r)
+				}
+			}
+		}
+	}
+}
+
+func (s *state) reports(now time.Time, minRequests uint64) []Report {
+	total, bad, slow := s.window.sum(now)
+	o := s.objective
+	var reports []Report
+	if o.Availability > 0 {
+		reports = append(reports, s.report(SLIAvailability, o.Availability, total, bad, minRequests))
+	}
+	if o.LatencyThreshold > 0 && o.LatencyTarget > 0 {
+		reports = append(reports, s.report(SLILatency, o.LatencyTarget, total, slow, minRequests))
+	}
+	return reports
+}
+
+func (s *state) report(sli string, target float64, total, bad, minRequests uint64) Report {
+	r := Report{
+		Objective:       s.name,
+		Method:          s.method,
+		Route:           s.route,
+		SLI:             sli,
+		Target:          target,
+		Total:           total,
+		Bad:             bad,
+		BudgetRemaining: 1,
+	}
+	if total == 0 {
+		return r
+	}
+	if budget := 1 - target; budget > 0 {
+		r.BurnRate = float64(bad) / float64(total) / budget
+	} else if bad > 0 {
+		// a 100% target has no budget at all
+		r.BurnRate = float64(bad)
+	}
+	r.BudgetRemaining = 1 - r.BurnRate
+	r.Exhausted = total >= minRequests && r.BudgetRemaining <= 0
+	return r
+}
+
+func writeGauge(buf *bytes.Buffer, name, help string, reports []Report, value func(r *Report) float64) {
+	buf.WriteString("# HELP " + name + " " + help + "\n")
+	buf.WriteString("# TYPE " + name + " gauge\n")
+	for i := range reports {
+		r := &reports[i]
+		buf.WriteString(name + `{slo="` + escape(r.Objective) + `",method="` + escape(r.Method) +
+			`",route="` + escape(r.Route) + `",sli="` + r.SLI + `"} `)
+		buf.WriteString(strconv.FormatFloat(value(r), 'g', -1, 64))
+		buf.WriteByte('\n')
+	}
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func escape(s string) string {
+	return labelValueEscaper.Replace(s)
+}
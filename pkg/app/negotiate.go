@@ -0,0 +1,218 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"strconv"
+	"strings"
+
+	"hertz-study/pkg/app/server/render"
+	"hertz-study/pkg/protocol/consts"
+)
+
+// Negotiate is the config of RequestContext.Negotiate.
+type Negotiate struct {
+	// The content types offered in the order of preference. All the content types registered by
+	// render.RegisterNegotiator are offered if empty, with text/html in front if HTMLName is set.
+	Offered []string
+	// The template name and the data rendering text/html.
+	HTMLName string
+	HTMLData interface{}
+	// The data rendering the JSON, XML and YAML content types, Data is rendered if not set.
+	JSONData interface{}
+	XMLData  interface{}
+	YAMLData interface{}
+	// The data rendering the content types whose specific data is not set.
+	Data interface{}
+}
+
+// data returns the data rendering the media type.
+func (n *Negotiate) data(mediaType string) interface{} {
+	var data interface{}
+	switch {
+	case mediaType == consts.MIMEApplicationJSON || strings.HasSuffix(mediaType, "+json"):
+		data = n.JSONData
+	case strings.HasSuffix(mediaType, "/xml") || strings.HasSuffix(mediaType, "+xml"):
+		data = n.XMLData
+	case strings.HasSuffix(mediaType, "yaml"):
+		data = n.YAMLData
+	}
+	if data == nil {
+		data = n.Data
+	}
+	return data
+}
+
+// NegotiateFormat returns the offered content type best matching the Accept header following
+// RFC 7231 section 5.3.2: the quality of an offered type is the one of the most specific media
+// range matching it, e.g. "text/html;level=1" over "text/html" over "text/*" over "*/*", and
+// the offered type of the highest non-zero quality is returned, the earlier offered one wins
+// if tied. The first offered type is returned if the request has no Accept header, and "" if
+// none is acceptable.
+func (ctx *RequestContext) NegotiateFormat(offered ...string) string {
+	if len(offered) == 0 {
+		return ""
+	}
+	ranges := parseAccept(ctx.Request.Header.Get(consts.HeaderAccept))
+	if len(ranges) == 0 {
+		return offered[0]
+	}
+	best, bestQ := "", 0.0
+	for _, o := range offered {
+		offer, ok := parseMediaRange(o)
+		if !ok {
+			continue
+		}
+		if q := offer.quality(ranges); q > bestQ {
+			best, bestQ = o, q
+		}
+	}
+	return best
+}
+
+// Negotiate renders the data of the content type negotiated by the Accept header among
+// config.Offered, see NegotiateFormat. It responds 406 Not Acceptable if none of them is
+// acceptable, and sets the Vary header in both cases.
+func (ctx *RequestContext) Negotiate(code int, config Negotiate) {
+	offered := config.Offered
+	if len(offered) == 0 {
+		offered = render.NegotiatedTypes()
+		if config.HTMLName != "" {
+			offered = append([]string{consts.MIMETextHtml}, offered...)
+		}
+	}
+
+	renders := make(map[string]render.Render, len(offered))
+	candidates := make([]string, 0, len(offered))
+	for _, o := range offered {
+		mediaType, _, _ := strings.Cut(strings.ToLower(o), ";")
+		mediaType = strings.TrimSpace(mediaType)
+		var r render.Render
+		if mediaType == consts.MIMETextHtml {
+			if config.HTMLName != "" && ctx.HTMLRender != nil {
+				r = ctx.HTMLRender.Instance(config.HTMLName, config.HTMLData)
+			}
+		} else if data := config.data(mediaType); data != nil {
+			r = render.Negotiate(mediaType, data)
+		}
+		if r != nil {
+			renders[o] = r
+			candidates = append(candidates, o)
+		}
+	}
+
+	format := ctx.NegotiateFormat(candidates...)
+	if format == "" {
+		ctx.AbortWithMsg("the accepted formats are not offered, offered: "+strings.Join(candidates, ", "), consts.StatusNotAcceptable)
+	} else {
+		ctx.Render(code, renders[format])
+	}
+	ctx.Response.Header.Add(consts.HeaderVary, consts.HeaderAccept)
+}
+
+// mediaRange is a media range of the Accept header or an offered content type.
+type mediaRange struct {
+	typ, subtype string
+	params       []string // "name=value" pairs except q
+	q            float64
+}
+
+// parseAccept parses the media ranges of the Accept header, the invalid ones are skipped.
+func parseAccept(header string) []mediaRange {
+	var ranges []mediaRange
+	for header != "" {
+		var part string
+		part, header, _ = strings.Cut(header, ",")
+		if r, ok := parseMediaRange(part); ok {
+			ranges = append(ranges, r)
+		}
+	}
+	return ranges
+}
+
+func parseMediaRange(s string) (mediaRange, bool) {
+	r := mediaRange{q: 1}
+	mediaType, params, _ := strings.Cut(s, ";")
+	typ, subtype, ok := strings.Cut(strings.ToLower(strings.TrimSpace(mediaType)), "/")
+	if !ok || typ == "" || subtype == "" || (typ == "*" && subtype != "*") {
+		return r, false
+	}
+	r.typ, r.subtype = typ, subtype
+	for params != "" {
+		var param string
+		param, params, _ = strings.Cut(params, ";")
+		name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		value = strings.Trim(strings.TrimSpace(value), `"`)
+		if name == "" {
+			continue
+		}
+		// the parameters following q are the accept-ext ones
+		if name == "q" {
+			q, err := strconv.ParseFloat(value, 64)
+			if err != nil || q < 0 || q > 1 {
+				return r, false
+			}
+			r.q = q
+			break
+		}
+		r.params = append(r.params, name+"="+value)
+	}
+	return r, true
+}
+
+// quality returns the quality of r as an offered type given the media ranges accepted,
+// 0 if it is not acceptable.
+func (r mediaRange) quality(accepted []mediaRange) float64 {
+	q, specificity := 0.0, -1
+	for _, a := range accepted {
+		s, ok := a.match(r)
+		if ok && s > specificity {
+			q, specificity = a.q, s
+		}
+	}
+	return q
+}
+
+// match reports whether the media range r matches the offered type, and its specificity.
+func (r mediaRange) match(offer mediaRange) (int, bool) {
+	switch {
+	case r.typ == "*":
+		return 0, true
+	case r.typ != offer.typ:
+		return 0, false
+	case r.subtype == "*":
+		return 1, true
+	case r.subtype != offer.subtype:
+		return 0, false
+	}
+	for _, p := range r.params {
+		if !containsFold(offer.params, p) {
+			return 0, false
+		}
+	}
+	return 2 + len(r.params), true
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,166 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"fmt"
+
+	"hertz-study/pkg/app/server/render"
+	"hertz-study/pkg/common/bytebufferpool"
+	"hertz-study/pkg/common/errors"
+	"hertz-study/pkg/network"
+)
+
+const defaultStreamRenderBufferSize = 64 * 1024
+
+var errStreamRenderAborted = errors.NewPrivate("stream render aborted")
+
+// SetStreamRenderBufferSize sets the buffer size of RenderStream, the default size is used if size <= 0.
+func (ctx *RequestContext) SetStreamRenderBufferSize(size int) {
+	ctx.streamRenderBufferSize = size
+}
+
+// RenderStream renders r with the HTTP code like Render, but the content is written to the client
+// progressively with chunked encoding instead of being buffered entirely, e.g. multi-megabyte HTML
+// or CSV reports.
+//
+// The content is buffered until it exceeds the stream render buffer size, so the error happening
+// before is returned with nothing sent and the handler can still respond an error page, while the
+// content not exceeding the buffer is sent with Content-Length as usual. Once the content is sent,
+// an error aborts the response by closing the connection without the last chunk, so that the client
+// can tell the response is truncated, and it is returned as well.
+//
+// The content is buffered entirely if the response can not be streamed, e.g. in unit tests.
+func (ctx *RequestContext) RenderStream(code int, r render.StreamRender) error {
+	ctx.SetStatusCode(code)
+
+	if !bodyAllowedForStatus(code) {
+		r.WriteContentType(&ctx.Response)
+		return nil
+	}
+
+	if ctx.GetWriter() == nil || ctx.flushWriterFunc == nil || ctx.Response.IsBodyStream() {
+		if err := r.Render(&ctx.Response); err != nil {
+			ctx.Response.ResetBody()
+			return err
+		}
+		return nil
+	}
+
+	r.WriteContentType(&ctx.Response)
+	size := ctx.streamRenderBufferSize
+	if size <= 0 {
+		size = defaultStreamRenderBufferSize
+	}
+	sw := &streamRenderWriter{ctx: ctx, buf: bytebufferpool.Get(), size: size}
+	defer bytebufferpool.Put(sw.buf)
+	// the response has been streamed by Flush, so nothing can be recovered
+	if hw := ctx.Response.GetHijackWriter(); hw != nil {
+		sw.w = hw
+	}
+
+	err := r.RenderTo(sw)
+	if sw.w == nil {
+		if err != nil {
+			return err
+		}
+		ctx.Response.SetBody(sw.buf.B)
+		return nil
+	}
+	if err == nil && len(sw.buf.B) > 0 {
+		err = sw.writeChunk()
+	}
+	if err != nil {
+		if w, ok := sw.w.(*streamRenderExtWriter); ok {
+			w.aborted = true
+		}
+		ctx.SetConnectionClose()
+	}
+	return err
+}
+
+// HTMLStream renders the HTTP template specified by its file name like HTML, but the template
+// is executed against the response directly, see RenderStream.
+func (ctx *RequestContext) HTMLStream(code int, name string, obj interface{}) error {
+	if ctx.HTMLRender == nil {
+		return errNoHTMLRender
+	}
+	r, ok := ctx.HTMLRender.Instance(name, obj).(render.StreamRender)
+	if !ok {
+		return fmt.Errorf("HTML render %T does not support streaming", ctx.HTMLRender)
+	}
+	return ctx.RenderStream(code, r)
+}
+
+// streamRenderWriter buffers the content of RenderStream and sends it in chunks of size.
+type streamRenderWriter struct {
+	ctx  *RequestContext
+	buf  *bytebufferpool.ByteBuffer
+	size int
+	// w is nil until the first chunk is sent
+	w network.ExtWriter
+}
+
+func (sw *streamRenderWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		room := sw.size - len(sw.buf.B)
+		if room == 0 {
+			if err := sw.writeChunk(); err != nil {
+				return n - len(p), err
+			}
+			continue
+		}
+		if room > len(p) {
+			room = len(p)
+		}
+		sw.buf.B = append(sw.buf.B, p[:room]...)
+		p = p[room:]
+	}
+	return n, nil
+}
+
+// writeChunk sends the buffered content, it is flushed at once
+// since the writer of the protocol holds the buffer until flushed.
+func (sw *streamRenderWriter) writeChunk() error {
+	if sw.w == nil {
+		sw.w = &streamRenderExtWriter{ExtWriter: sw.ctx.flushWriterFunc(sw.ctx, sw.ctx.GetWriter())}
+		sw.ctx.Response.HijackWriter(sw.w)
+	}
+	if _, err := sw.w.Write(sw.buf.B); err != nil {
+		return err
+	}
+	if err := sw.w.Flush(); err != nil {
+		return err
+	}
+	sw.buf.Reset()
+	return nil
+}
+
+// streamRenderExtWriter does not write the last chunk if the rendering is
+// aborted, so that the client can tell the response is truncated.
+type streamRenderExtWriter struct {
+	network.ExtWriter
+	aborted bool
+}
+
+func (w *streamRenderExtWriter) Finalize() error {
+	if w.aborted {
+		return errStreamRenderAborted
+	}
+	return w.ExtWriter.Finalize()
+}
@@ -0,0 +1,68 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"hertz-study/pkg/app/server/agent"
+)
+
+// EnableAgent starts a diagnostics agent with the built-in commands and the "engine" and "routes"
+// commands of h, which is closed when h shuts down. Query it with the hertz-agent CLI:
+//
+//	go run hertz-study/cmd/hertz-agent <pid> engine
+func (h *Hertz) EnableAgent(opts ...agent.Option) (*agent.Agent, error) {
+	a, err := agent.Listen(opts...)
+	if err != nil {
+		return nil, err
+	}
+	a.Register("engine", "the stats of the engine", h.writeEngineStats)
+	a.Register("routes", "the registered routes", h.writeRoutes)
+	h.OnShutdown = append(h.OnShutdown, func(ctx context.Context) {
+		a.Close() //nolint:errcheck
+	})
+	return a, nil
+}
+
+func (h *Hertz) writeEngineStats(w io.Writer) error {
+	opt := h.GetOptions()
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "running:\t%t\n", h.IsRunning())
+	fmt.Fprintf(tw, "transporter:\t%s\n", h.GetTransporterName())
+	fmt.Fprintf(tw, "address:\t%s://%s\n", opt.Network, opt.Addr)
+	fmt.Fprintf(tw, "routes:\t%d\n", len(h.Routes()))
+	fmt.Fprintf(tw, "read timeout:\t%s\n", opt.ReadTimeout)
+	fmt.Fprintf(tw, "write timeout:\t%s\n", opt.WriteTimeout)
+	fmt.Fprintf(tw, "idle timeout:\t%s\n", opt.IdleTimeout)
+	fmt.Fprintf(tw, "max request body size:\t%d\n", opt.MaxRequestBodySize)
+	fmt.Fprintf(tw, "max concurrent connections:\t%d\n", opt.MaxConcurrentConnections)
+	fmt.Fprintf(tw, "stream request body:\t%t\n", opt.StreamRequestBody)
+	fmt.Fprintf(tw, "keep-alive disabled:\t%t\n", opt.DisableKeepalive)
+	return tw.Flush()
+}
+
+func (h *Hertz) writeRoutes(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, r := range h.Routes() {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", r.Method, r.Path, r.Handler)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,326 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agent
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"runtime/metrics"
+	"runtime/pprof"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"hertz-study/pkg/common/hlog"
+	"hertz-study/pkg/network"
+)
+
+// Command writes the output of a command to w.
+type Command func(w io.Writer) error
+
+type command struct {
+	desc string
+	fn   Command
+}
+
+// Agent serves the live runtime info over a local socket, for debugging the instances whose
+// admin HTTP port is not reachable. A client sends the name of a command in a line, and the
+// agent writes the output of the command and closes the connection, see Query.
+type Agent struct {
+	opts  *options
+	ln    net.Listener
+	start time.Time
+
+	mu       sync.RWMutex
+	commands map[string]command
+
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// Listen starts an agent with the built-in commands, run "help" to list them.
+func Listen(opts ...Option) (*Agent, error) {
+	o := newOptions(opts...)
+	if o.network == "unix" && o.addr == SocketPath(os.Getpid()) {
+		if err := privateDir(filepath.Dir(o.addr)); err != nil {
+			return nil, fmt.Errorf("agent listen: %w", err)
+		}
+	}
+	ln, err := network.Listen(nil, o.network, o.addr, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("agent listen: %w", err)
+	}
+	a := &Agent{
+		opts:     o,
+		ln:       ln,
+		start:    time.Now(),
+		commands: make(map[string]command),
+	}
+	a.Register("help", "list the commands", a.help)
+	a.Register("version", "the go version, the platform and the process", version)
+	a.Register("stats", "the summary of the runtime", a.stats)
+	a.Register("memstats", "the memory statistics of the runtime", memStats)
+	a.Register("gcstats", "the statistics of the recent GCs", gcStats)
+	a.Register("goroutines", "the stacks of all the goroutines", goroutines)
+	a.Register("gc", "run a GC", runGC)
+	a.Register("freeos", "run a GC and return as much memory to the OS as possible", freeOSMemory)
+
+	a.wg.Add(1)
+	go a.serve()
+	hlog.SystemLogger().Infof("Diagnostics agent listening on %s://%s", o.network, ln.Addr().String())
+	return a, nil
+}
+
+// Addr returns the address the agent listens on.
+func (a *Agent) Addr() net.Addr {
+	return a.ln.Addr()
+}
+
+// Register adds a command, the existing command of the same name is replaced.
+func (a *Agent) Register(name, desc string, fn Command) {
+	a.mu.Lock()
+	a.commands[name] = command{desc: desc, fn: fn}
+	a.mu.Unlock()
+}
+
+// Close stops the agent and removes the unix socket file.
+func (a *Agent) Close() error {
+	var err error
+	a.closeOnce.Do(func() {
+		err = a.ln.Close()
+		a.wg.Wait()
+		network.UnlinkUdsFile(a.opts.network, a.opts.addr) //nolint:errcheck
+		if a.opts.network == "unix" && a.opts.addr == SocketPath(os.Getpid()) {
+			os.Remove(filepath.Dir(a.opts.addr)) //nolint:errcheck
+		}
+	})
+	return err
+}
+
+// privateDir creates dir only accessible by the user of the process, so that the socket is
+// never exposed between binding and chmod. An existing dir is only used if it's not a symlink
+// and not accessible by the other users, as it may be created by anyone in the temp dir.
+func privateDir(dir string) error {
+	err := os.Mkdir(dir, 0o700)
+	if err == nil || !os.IsExist(err) {
+		return err
+	}
+	fi, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() || fi.Mode().Perm() != 0o700 {
+		return fmt.Errorf("%s is not a private directory, mode=%s", dir, fi.Mode())
+	}
+	return nil
+}
+
+func (a *Agent) serve() {
+	defer a.wg.Done()
+	for {
+		conn, err := a.ln.Accept()
+		if err != nil {
+			return
+		}
+		a.wg.Add(1)
+		go func() {
+			defer a.wg.Done()
+			a.handle(conn)
+		}()
+	}
+}
+
+func (a *Agent) handle(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(a.opts.timeout)) //nolint:errcheck
+
+	line, err := bufio.NewReader(io.LimitReader(conn, 1024)).ReadString('\n')
+	if err != nil && line == "" {
+		return
+	}
+	name := strings.TrimSpace(line)
+	a.mu.RLock()
+	cmd, ok := a.commands[name]
+	a.mu.RUnlock()
+
+	w := bufio.NewWriter(conn)
+	if !ok {
+		fmt.Fprintf(w, "unknown command %q, run \"help\" to list the commands\n", name)
+	} else if err = cmd.fn(w); err != nil {
+		fmt.Fprintf(w, "command %s failed: %v\n", name, err)
+	}
+	w.Flush() //nolint:errcheck
+}
+
+func (a *Agent) help(w io.Writer) error {
+	a.mu.RLock()
+	names := make([]string, 0, len(a.commands))
+	for name := range a.commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, name := range names {
+		fmt.Fprintf(tw, "%s\t%s\n", name, a.commands[name].desc)
+	}
+	a.mu.RUnlock()
+	return tw.Flush()
+}
+
+func version(w io.Writer) error {
+	exe, _ := os.Executable()
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "go version:\t%s\n", runtime.Version())
+	fmt.Fprintf(tw, "platform:\t%s/%s\n", runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(tw, "pid:\t%d\n", os.Getpid())
+	fmt.Fprintf(tw, "executable:\t%s\n", exe)
+	if info, ok := debug.ReadBuildInfo(); ok {
+		fmt.Fprintf(tw, "main module:\t%s %s\n", info.Main.Path, info.Main.Version)
+	}
+	return tw.Flush()
+}
+
+func (a *Agent) stats(w io.Writer) error {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "uptime:\t%s\n", time.Since(a.start).Round(time.Second))
+	fmt.Fprintf(tw, "goroutines:\t%d\n", runtime.NumGoroutine())
+	fmt.Fprintf(tw, "GOMAXPROCS:\t%d\n", runtime.GOMAXPROCS(0))
+	fmt.Fprintf(tw, "num CPU:\t%d\n", runtime.NumCPU())
+	fmt.Fprintf(tw, "heap alloc:\t%s\n", formatBytes(m.HeapAlloc))
+	fmt.Fprintf(tw, "heap in use:\t%s\n", formatBytes(m.HeapInuse))
+	fmt.Fprintf(tw, "total sys:\t%s\n", formatBytes(m.Sys))
+	fmt.Fprintf(tw, "next GC:\t%s\n", formatBytes(m.NextGC))
+	fmt.Fprintf(tw, "num GC:\t%d\n", m.NumGC)
+	if m.LastGC > 0 {
+		fmt.Fprintf(tw, "last GC:\t%s ago\n", time.Since(time.Unix(0, int64(m.LastGC))).Round(time.Millisecond))
+	}
+	fmt.Fprintf(tw, "GC pause total:\t%s\n", time.Duration(m.PauseTotalNs))
+	fmt.Fprintf(tw, "GC CPU fraction:\t%.4f%%\n", m.GCCPUFraction*100)
+	fmt.Fprintf(tw, "GC percent:\t%d\n", gcPercent())
+	if limit := debug.SetMemoryLimit(-1); limit == math.MaxInt64 {
+		fmt.Fprintf(tw, "memory limit:\tunlimited\n")
+	} else {
+		fmt.Fprintf(tw, "memory limit:\t%s\n", formatBytes(uint64(limit)))
+	}
+	return tw.Flush()
+}
+
+func memStats(w io.Writer) error {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, f := range []struct {
+		name  string
+		value uint64
+	}{
+		{"alloc", m.Alloc}, {"total alloc", m.TotalAlloc}, {"sys", m.Sys},
+		{"lookups", m.Lookups}, {"mallocs", m.Mallocs}, {"frees", m.Frees},
+		{"heap alloc", m.HeapAlloc}, {"heap sys", m.HeapSys}, {"heap idle", m.HeapIdle},
+		{"heap in use", m.HeapInuse}, {"heap released", m.HeapReleased}, {"heap objects", m.HeapObjects},
+		{"stack in use", m.StackInuse}, {"stack sys", m.StackSys},
+		{"mspan in use", m.MSpanInuse}, {"mspan sys", m.MSpanSys},
+		{"mcache in use", m.MCacheInuse}, {"mcache sys", m.MCacheSys},
+		{"buck hash sys", m.BuckHashSys}, {"GC sys", m.GCSys}, {"other sys", m.OtherSys},
+		{"next GC", m.NextGC},
+	} {
+		fmt.Fprintf(tw, "%s:\t%d\n", f.name, f.value)
+	}
+	fmt.Fprintf(tw, "num GC:\t%d\n", m.NumGC)
+	fmt.Fprintf(tw, "num forced GC:\t%d\n", m.NumForcedGC)
+	fmt.Fprintf(tw, "GC pause total:\t%s\n", time.Duration(m.PauseTotalNs))
+	return tw.Flush()
+}
+
+func gcStats(w io.Writer) error {
+	stats := &debug.GCStats{PauseQuantiles: make([]time.Duration, 5)}
+	debug.ReadGCStats(stats)
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "num GC:\t%d\n", stats.NumGC)
+	if stats.NumGC > 0 {
+		fmt.Fprintf(tw, "last GC:\t%s\n", stats.LastGC.Format(time.RFC3339Nano))
+	}
+	fmt.Fprintf(tw, "pause total:\t%s\n", stats.PauseTotal)
+	q := stats.PauseQuantiles
+	fmt.Fprintf(tw, "pause min/p25/p50/p75/max:\t%s/%s/%s/%s/%s\n", q[0], q[1], q[2], q[3], q[4])
+	n := len(stats.Pause)
+	if n > 10 {
+		n = 10
+	}
+	recent := make([]string, n)
+	for i := 0; i < n; i++ {
+		recent[i] = stats.Pause[i].String()
+	}
+	fmt.Fprintf(tw, "recent pauses:\t%s\n", strings.Join(recent, " "))
+	return tw.Flush()
+}
+
+func goroutines(w io.Writer) error {
+	return pprof.Lookup("goroutine").WriteTo(w, 2)
+}
+
+func runGC(w io.Writer) error {
+	start := time.Now()
+	runtime.GC()
+	_, err := fmt.Fprintf(w, "GC done in %s\n", time.Since(start))
+	return err
+}
+
+func freeOSMemory(w io.Writer) error {
+	start := time.Now()
+	debug.FreeOSMemory()
+	_, err := fmt.Fprintf(w, "memory freed in %s\n", time.Since(start))
+	return err
+}
+
+// gcPercent returns the current GC percent, -1 if GC is off.
+func gcPercent() int {
+	sample := []metrics.Sample{{Name: "/gc/gogc:percent"}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		return -1
+	}
+	p := sample[0].Value.Uint64()
+	// the metric is math.MaxUint64 if GC is off
+	if p > math.MaxInt32 {
+		return -1
+	}
+	return int(p)
+}
+
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := uint64(unit), 0
+	for v := n / unit; v >= unit && exp < 4; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.2f%ciB", float64(n)/float64(div), "KMGTP"[exp])
+}
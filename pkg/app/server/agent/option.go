@@ -0,0 +1,84 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agent
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const defaultTimeout = 10 * time.Second
+
+type (
+	options struct {
+		network string
+		addr    string
+		timeout time.Duration
+	}
+
+	Option func(o *options)
+)
+
+func newOptions(opts ...Option) *options {
+	cfg := &options{
+		network: "unix",
+		addr:    SocketPath(os.Getpid()),
+		timeout: defaultTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return cfg
+}
+
+// SocketPath returns the default unix socket path of the agent of the process pid,
+// which is used by the CLI to find the agent by pid. The socket is created in a directory
+// only accessible by the user of the process.
+func SocketPath(pid int) string {
+	return filepath.Join(os.TempDir(), fmt.Sprintf("hertz-agent-%d", pid), "agent.sock")
+}
+
+// WithUnixSocket sets the unix socket path the agent listens on, default is SocketPath(os.Getpid()).
+// The socket is only accessible by the user of the process, and the parent directory of path
+// should not be writable by the other users.
+func WithUnixSocket(path string) Option {
+	return func(o *options) {
+		o.network = "unix"
+		o.addr = path
+	}
+}
+
+// WithTCPAddr makes the agent listen on the TCP address instead of the unix socket, e.g.
+// "127.0.0.1:0" on the platforms without unix sockets. Never expose it to the public network
+// as the commands are not authenticated.
+func WithTCPAddr(addr string) Option {
+	return func(o *options) {
+		o.network = "tcp"
+		o.addr = addr
+	}
+}
+
+// WithTimeout sets the deadline of reading a command and writing its output, default is 10s.
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.timeout = d
+	}
+}
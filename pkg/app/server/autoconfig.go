@@ -0,0 +1,75 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"math"
+	"os"
+	"runtime"
+
+	"hertz-study/pkg/common/config"
+	"hertz-study/pkg/common/hlog"
+)
+
+const (
+	// autoMemoryLimitRatio leaves the rest of the container memory for the non-heap memory
+	autoMemoryLimitRatio = 0.9
+	// autoConnMemory is the estimated memory of a connection, including the buffers,
+	// the goroutine stack and the pooled request and response
+	autoConnMemory = 64 << 10
+	// autoKeepBodyDivisor bounds the body buffers kept in the pool to 1/autoKeepBodyDivisor
+	// of the container memory
+	autoKeepBodyDivisor = 1024
+)
+
+// autoConfigure sizes the options by the CPU quota and the memory limit of the container.
+// Only the options with the default values are changed, so that the explicit options and
+// the GOMAXPROCS and GOMEMLIMIT environment variables take precedence.
+func autoConfigure(opt *config.Options) {
+	defaults := config.NewOptions(nil)
+
+	if os.Getenv("GOMAXPROCS") == "" {
+		if quota, err := containerCPUQuota(); err == nil {
+			if procs := int(math.Ceil(quota)); procs < runtime.GOMAXPROCS(0) {
+				runtime.GOMAXPROCS(procs)
+				hlog.SystemLogger().Infof("Auto config: set GOMAXPROCS=%d by cpu quota=%.2f", procs, quota)
+			}
+		} else {
+			hlog.SystemLogger().Debugf("Auto config: detect container cpu quota failed: err=%v", err)
+		}
+	}
+
+	memLimit, err := containerMemoryLimit()
+	if err != nil {
+		hlog.SystemLogger().Debugf("Auto config: detect container memory limit failed: err=%v", err)
+		return
+	}
+	if opt.MemoryLimit == 0 && opt.MemoryLimitRatio == 0 {
+		opt.MemoryLimitRatio = autoMemoryLimitRatio
+	}
+	if opt.MaxConcurrentConnections == 0 {
+		// leave half of the memory for handling the requests
+		opt.MaxConcurrentConnections = int(memLimit / 2 / autoConnMemory)
+	}
+	if opt.MaxKeepBodySize == defaults.MaxKeepBodySize {
+		if size := int(memLimit / autoKeepBodyDivisor); size < opt.MaxKeepBodySize {
+			opt.MaxKeepBodySize = size
+		}
+	}
+	hlog.SystemLogger().Infof("Auto config: memory limit=%dMiB, max concurrent connections=%d, max keep body size=%dKiB",
+		memLimit>>20, opt.MaxConcurrentConnections, opt.MaxKeepBodySize>>10)
+}
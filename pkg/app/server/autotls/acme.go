@@ -0,0 +1,435 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package autotls
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	statusPending    = "pending"
+	statusProcessing = "processing"
+	statusValid      = "valid"
+	statusInvalid    = "invalid"
+
+	challengeHTTP01    = "http-01"
+	challengeTLSALPN01 = "tls-alpn-01"
+
+	defaultPollInterval = time.Second
+	maxResponseSize     = 1 << 20
+)
+
+// acmeProblem is the error document of RFC 8555 section 6.7.
+type acmeProblem struct {
+	Type   string `json:"type"`
+	Detail string `json:"detail"`
+	Status int    `json:"status"`
+}
+
+func (p *acmeProblem) Error() string {
+	return fmt.Sprintf("acme: %s: %s (status=%d)", p.Type, p.Detail, p.Status)
+}
+
+type acmeDirectory struct {
+	NewNonce   string `json:"newNonce"`
+	NewAccount string `json:"newAccount"`
+	NewOrder   string `json:"newOrder"`
+}
+
+type acmeIdentifier struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type acmeOrder struct {
+	URL            string           `json:"-"`
+	Status         string           `json:"status"`
+	Identifiers    []acmeIdentifier `json:"identifiers"`
+	Authorizations []string         `json:"authorizations"`
+	Finalize       string           `json:"finalize"`
+	Certificate    string           `json:"certificate"`
+	Error          *acmeProblem     `json:"error"`
+}
+
+type acmeChallenge struct {
+	Type   string       `json:"type"`
+	URL    string       `json:"url"`
+	Token  string       `json:"token"`
+	Status string       `json:"status"`
+	Error  *acmeProblem `json:"error"`
+}
+
+type acmeAuthorization struct {
+	Status     string          `json:"status"`
+	Identifier acmeIdentifier  `json:"identifier"`
+	Challenges []acmeChallenge `json:"challenges"`
+}
+
+// acmeClient is a minimal ACME (RFC 8555) client which supports the operations needed to
+// issue certificates with an ES256 account key.
+type acmeClient struct {
+	directoryURL string
+	key          *ecdsa.PrivateKey
+	http         *http.Client
+
+	mu     sync.Mutex
+	dir    *acmeDirectory
+	kid    string
+	nonces []string
+}
+
+func newACMEClient(directoryURL string, key *ecdsa.PrivateKey, hc *http.Client) *acmeClient {
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	return &acmeClient{directoryURL: directoryURL, key: key, http: hc}
+}
+
+func (c *acmeClient) directory(ctx context.Context) (*acmeDirectory, error) {
+	c.mu.Lock()
+	dir := c.dir
+	c.mu.Unlock()
+	if dir != nil {
+		return dir, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.directoryURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp)
+	}
+	dir = &acmeDirectory{}
+	if err = json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(dir); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.dir = dir
+	c.mu.Unlock()
+	return dir, nil
+}
+
+// register creates the account of the key, or looks up the existing one, and remembers its url.
+func (c *acmeClient) register(ctx context.Context, email string) error {
+	dir, err := c.directory(ctx)
+	if err != nil {
+		return err
+	}
+	payload := map[string]interface{}{"termsOfServiceAgreed": true}
+	if email != "" {
+		payload["contact"] = []string{"mailto:" + email}
+	}
+	resp, err := c.post(ctx, dir.NewAccount, payload, true)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	c.mu.Lock()
+	c.kid = resp.Header.Get("Location")
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *acmeClient) newOrder(ctx context.Context, domains []string) (*acmeOrder, error) {
+	dir, err := c.directory(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]acmeIdentifier, len(domains))
+	for i, d := range domains {
+		ids[i] = acmeIdentifier{Type: "dns", Value: d}
+	}
+	order := &acmeOrder{}
+	resp, err := c.postJSON(ctx, dir.NewOrder, map[string]interface{}{"identifiers": ids}, order)
+	if err != nil {
+		return nil, err
+	}
+	order.URL = resp.Header.Get("Location")
+	return order, nil
+}
+
+func (c *acmeClient) authorization(ctx context.Context, url string) (*acmeAuthorization, error) {
+	authz := &acmeAuthorization{}
+	_, err := c.postJSON(ctx, url, nil, authz)
+	return authz, err
+}
+
+func (c *acmeClient) accept(ctx context.Context, chal *acmeChallenge) error {
+	_, err := c.postJSON(ctx, chal.URL, struct{}{}, &acmeChallenge{})
+	return err
+}
+
+// waitAuthorization polls the authorization until it is valid or invalid.
+func (c *acmeClient) waitAuthorization(ctx context.Context, url string) error {
+	for {
+		authz := &acmeAuthorization{}
+		resp, err := c.postJSON(ctx, url, nil, authz)
+		if err != nil {
+			return err
+		}
+		switch authz.Status {
+		case statusValid:
+			return nil
+		case statusInvalid:
+			for _, chal := range authz.Challenges {
+				if chal.Error != nil {
+					return chal.Error
+				}
+			}
+			return fmt.Errorf("acme: authorization of %s is invalid", authz.Identifier.Value)
+		}
+		if err = sleep(ctx, retryAfter(resp)); err != nil {
+			return err
+		}
+	}
+}
+
+// finalize submits the csr and waits until the certificate is issued.
+func (c *acmeClient) finalize(ctx context.Context, order *acmeOrder, csr []byte) (*acmeOrder, error) {
+	payload := map[string]string{"csr": base64.RawURLEncoding.EncodeToString(csr)}
+	if _, err := c.postJSON(ctx, order.Finalize, payload, order); err != nil {
+		return nil, err
+	}
+	for {
+		switch order.Status {
+		case statusValid:
+			return order, nil
+		case statusInvalid:
+			if order.Error != nil {
+				return nil, order.Error
+			}
+			return nil, errors.New("acme: order is invalid")
+		}
+		if err := sleep(ctx, defaultPollInterval); err != nil {
+			return nil, err
+		}
+		if _, err := c.postJSON(ctx, order.URL, nil, order); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// certificate downloads the PEM encoded certificate chain.
+func (c *acmeClient) certificate(ctx context.Context, url string) ([]byte, error) {
+	resp, err := c.post(ctx, url, nil, false)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+}
+
+// keyAuthorization returns the key authorization of the challenge token, see RFC 8555 section 8.1.
+func (c *acmeClient) keyAuthorization(token string) string {
+	return token + "." + jwkThumbprint(&c.key.PublicKey)
+}
+
+func (c *acmeClient) postJSON(ctx context.Context, url string, payload, v interface{}) (*http.Response, error) {
+	resp, err := c.post(ctx, url, payload, false)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return resp, json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(v)
+}
+
+// post sends a JWS signed request, a nil payload means POST-as-GET. The request is retried
+// once with a fresh nonce if the server rejects the nonce.
+func (c *acmeClient) post(ctx context.Context, url string, payload interface{}, useJWK bool) (*http.Response, error) {
+	var body []byte
+	if payload != nil {
+		var err error
+		if body, err = json.Marshal(payload); err != nil {
+			return nil, err
+		}
+	}
+
+	for retry := 0; ; retry++ {
+		nonce, err := c.nonce(ctx)
+		if err != nil {
+			return nil, err
+		}
+		jws, err := c.sign(url, nonce, body, useJWK)
+		if err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(jws))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/jose+json")
+		resp, err := c.http.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		c.addNonce(resp.Header.Get("Replay-Nonce"))
+		if resp.StatusCode < http.StatusBadRequest {
+			return resp, nil
+		}
+		err = responseError(resp)
+		resp.Body.Close()
+		var p *acmeProblem
+		if retry == 0 && errors.As(err, &p) && p.Type == "urn:ietf:params:acme:error:badNonce" {
+			continue
+		}
+		return nil, err
+	}
+}
+
+func (c *acmeClient) nonce(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	if n := len(c.nonces); n > 0 {
+		nonce := c.nonces[n-1]
+		c.nonces = c.nonces[:n-1]
+		c.mu.Unlock()
+		return nonce, nil
+	}
+	c.mu.Unlock()
+
+	dir, err := c.directory(ctx)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, dir.NewNonce, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	nonce := resp.Header.Get("Replay-Nonce")
+	if nonce == "" {
+		return "", errors.New("acme: no nonce returned by the server")
+	}
+	return nonce, nil
+}
+
+func (c *acmeClient) addNonce(nonce string) {
+	if nonce == "" {
+		return
+	}
+	c.mu.Lock()
+	c.nonces = append(c.nonces, nonce)
+	c.mu.Unlock()
+}
+
+// sign encodes the request as a flattened JWS signed by ES256, see RFC 8555 section 6.2.
+func (c *acmeClient) sign(url, nonce string, payload []byte, useJWK bool) ([]byte, error) {
+	protected := map[string]interface{}{"alg": "ES256", "nonce": nonce, "url": url}
+	c.mu.Lock()
+	kid := c.kid
+	c.mu.Unlock()
+	if useJWK || kid == "" {
+		protected["jwk"] = jwk(&c.key.PublicKey)
+	} else {
+		protected["kid"] = kid
+	}
+	header, err := json.Marshal(protected)
+	if err != nil {
+		return nil, err
+	}
+	h64 := base64.RawURLEncoding.EncodeToString(header)
+	p64 := base64.RawURLEncoding.EncodeToString(payload)
+
+	digest := sha256.Sum256([]byte(h64 + "." + p64))
+	r, s, err := ecdsa.Sign(rand.Reader, c.key, digest[:])
+	if err != nil {
+		return nil, err
+	}
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+	return json.Marshal(map[string]string{
+		"protected": h64,
+		"payload":   p64,
+		"signature": base64.RawURLEncoding.EncodeToString(sig),
+	})
+}
+
+func jwk(pub *ecdsa.PublicKey) map[string]string {
+	return map[string]string{
+		"crv": "P-256",
+		"kty": "EC",
+		"x":   encodeCoordinate(pub.X),
+		"y":   encodeCoordinate(pub.Y),
+	}
+}
+
+// jwkThumbprint returns the RFC 7638 thumbprint of the key, the members are in lexical order.
+func jwkThumbprint(pub *ecdsa.PublicKey) string {
+	b := fmt.Sprintf(`{"crv":"P-256","kty":"EC","x":"%s","y":"%s"}`, encodeCoordinate(pub.X), encodeCoordinate(pub.Y))
+	sum := sha256.Sum256([]byte(b))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func encodeCoordinate(v *big.Int) string {
+	b := make([]byte, 32)
+	v.FillBytes(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func responseError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	p := &acmeProblem{}
+	if err := json.Unmarshal(body, p); err != nil || p.Type == "" {
+		return fmt.Errorf("acme: unexpected response status=%d body=%s", resp.StatusCode, body)
+	}
+	if p.Status == 0 {
+		p.Status = resp.StatusCode
+	}
+	return p
+}
+
+func retryAfter(resp *http.Response) time.Duration {
+	if v, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && v > 0 {
+		return time.Duration(v) * time.Second
+	}
+	return defaultPollInterval
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,79 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package autotls
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// ErrCacheMiss is returned by Cache.Get when the key is not found.
+var ErrCacheMiss = errors.New("autotls: certificate cache miss")
+
+// Cache is the store of the account key and the certificates, implement it to share the
+// certificates between instances, e.g. by a database or an object storage.
+type Cache interface {
+	// Get returns the data of key, or ErrCacheMiss if it is not found.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Put stores the data of key.
+	Put(ctx context.Context, key string, data []byte) error
+	// Delete removes the data of key, it is not an error if key is not found.
+	Delete(ctx context.Context, key string) error
+}
+
+// DirCache is a Cache storing the data in the files of the directory, one file per key.
+type DirCache string
+
+// Get implements Cache.
+func (d DirCache) Get(_ context.Context, key string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(string(d), key))
+	if os.IsNotExist(err) {
+		return nil, ErrCacheMiss
+	}
+	return data, err
+}
+
+// Put implements Cache, the file is replaced by renaming so that it is never partially written.
+func (d DirCache) Put(_ context.Context, key string, data []byte) error {
+	if err := os.MkdirAll(string(d), 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(string(d), key+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(string(d), key))
+}
+
+// Delete implements Cache.
+func (d DirCache) Delete(_ context.Context, key string) error {
+	err := os.Remove(filepath.Join(string(d), key))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
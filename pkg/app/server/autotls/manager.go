@@ -0,0 +1,501 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package autotls provides automatic certificate provisioning by ACME, e.g. Let's Encrypt.
+//
+// The certificates are obtained on the first tls handshake of every domain, cached by a
+// Cache and renewed automatically before they expire. The TLS-ALPN-01 challenge is used by
+// default so that only the tls port (443) is needed, use WithHTTP01 and serve
+// Manager.HTTPHandler on port 80 to use the HTTP-01 challenge instead.
+//
+// By using it, you agree to the terms of service of the ACME server.
+package autotls
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"hertz-study/pkg/app"
+	"hertz-study/pkg/common/hlog"
+	"hertz-study/pkg/protocol/consts"
+)
+
+const (
+	// ALPNProto is the application protocol negotiated by the TLS-ALPN-01 challenge.
+	ALPNProto = "acme-tls/1"
+
+	accountKeyName     = "acme_account+key"
+	httpChallengePath  = "/.well-known/acme-challenge/"
+	obtainTimeout      = 5 * time.Minute
+	renewCheckInterval = 12 * time.Hour
+)
+
+// failureBackoffMin and failureBackoffMax bound the exponential backoff before obtaining the
+// certificate of a domain again after it failed, so that a bad domain never hits the rate
+// limits of the ACME server, e.g. 5 failed validations per hour of Let's Encrypt.
+var (
+	failureBackoffMin = 5 * time.Minute
+	failureBackoffMax = 12 * time.Hour
+)
+
+// idPeACMEIdentifier is the oid of the acmeIdentifier extension, see RFC 8737 section 6.1.
+var idPeACMEIdentifier = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 1, 31}
+
+// Manager obtains and renews the certificates of the allowed domains.
+type Manager struct {
+	domains map[string]struct{}
+	opts    *options
+
+	clientMu sync.Mutex
+	client   *acmeClient
+
+	mu       sync.RWMutex
+	certs    map[string]*tls.Certificate
+	locks    map[string]*sync.Mutex
+	failures map[string]*failure
+
+	httpTokens sync.Map // token -> key authorization
+	alpnCerts  sync.Map // domain -> *tls.Certificate
+
+	renewOnce sync.Once
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// New creates a Manager which only obtains the certificates of domains.
+func New(domains []string, opts ...Option) *Manager {
+	m := &Manager{
+		domains:  make(map[string]struct{}, len(domains)),
+		opts:     newOptions(opts...),
+		certs:    make(map[string]*tls.Certificate),
+		locks:    make(map[string]*sync.Mutex),
+		failures: make(map[string]*failure),
+		done:     make(chan struct{}),
+	}
+	for _, d := range domains {
+		m.domains[normalizeDomain(d)] = struct{}{}
+	}
+	return m
+}
+
+// TLSConfig returns a tls config which serves the certificates of m and answers the
+// TLS-ALPN-01 challenges.
+func (m *Manager) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: m.GetCertificate,
+		NextProtos:     []string{"http/1.1", ALPNProto},
+	}
+}
+
+// GetCertificate returns the certificate of the server name, it is used as tls.Config.GetCertificate.
+// The certificate is loaded from the cache or obtained from the ACME server if m has none yet.
+func (m *Manager) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	name := normalizeDomain(hello.ServerName)
+	if name == "" {
+		return nil, errors.New("autotls: missing server name")
+	}
+	if len(hello.SupportedProtos) == 1 && hello.SupportedProtos[0] == ALPNProto {
+		if cert, ok := m.alpnCerts.Load(name); ok {
+			return cert.(*tls.Certificate), nil
+		}
+		return nil, fmt.Errorf("autotls: no pending tls-alpn-01 challenge of %s", name)
+	}
+	if _, ok := m.domains[name]; !ok {
+		return nil, fmt.Errorf("autotls: domain %s is not allowed", name)
+	}
+
+	ctx := hello.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(ctx, obtainTimeout)
+	defer cancel()
+	return m.cert(ctx, name)
+}
+
+// HTTPHandler answers the HTTP-01 challenges under "/.well-known/acme-challenge/" and redirects
+// the other requests to https, serve it on port 80 when WithHTTP01 is used, e.g.
+//
+//	h80.Any("/*path", m.HTTPHandler())
+func (m *Manager) HTTPHandler() app.HandlerFunc {
+	return func(c context.Context, ctx *app.RequestContext) {
+		path := string(ctx.Path())
+		if !strings.HasPrefix(path, httpChallengePath) {
+			uri := ctx.URI()
+			ctx.Redirect(consts.StatusMovedPermanently, []byte("https://"+string(uri.Host())+string(uri.RequestURI())))
+			return
+		}
+		keyAuth, ok := m.httpTokens.Load(strings.TrimPrefix(path, httpChallengePath))
+		if !ok {
+			ctx.AbortWithStatus(consts.StatusNotFound)
+			return
+		}
+		ctx.Data(consts.StatusOK, "text/plain", []byte(keyAuth.(string)))
+	}
+}
+
+// Shutdown stops renewing the certificates, its signature matches the OnShutdown hooks of the
+// server, e.g. h.OnShutdown = append(h.OnShutdown, m.Shutdown).
+func (m *Manager) Shutdown(context.Context) {
+	m.closeOnce.Do(func() {
+		close(m.done)
+	})
+}
+
+func (m *Manager) cert(ctx context.Context, name string) (*tls.Certificate, error) {
+	if cert := m.loadCert(name); cert != nil {
+		return cert, nil
+	}
+
+	// only one handshake obtains the certificate of the domain at the same time
+	m.mu.Lock()
+	l, ok := m.locks[name]
+	if !ok {
+		l = &sync.Mutex{}
+		m.locks[name] = l
+	}
+	m.mu.Unlock()
+	l.Lock()
+	defer l.Unlock()
+	if cert := m.loadCert(name); cert != nil {
+		return cert, nil
+	}
+
+	cert, err := m.cachedCert(ctx, name)
+	if err != nil {
+		if err = m.lastFailure(name); err != nil {
+			return nil, err
+		}
+		if cert, err = m.obtain(ctx, name); err != nil {
+			m.recordFailure(name, err)
+			return nil, err
+		}
+	}
+	m.storeCert(name, cert)
+	m.mu.Lock()
+	delete(m.failures, name)
+	m.mu.Unlock()
+	m.renewOnce.Do(func() {
+		go m.renewLoop()
+	})
+	return cert, nil
+}
+
+// failure is the last failure of obtaining the certificate of a domain.
+type failure struct {
+	err     error
+	count   int
+	retryAt time.Time
+}
+
+// lastFailure returns the error of the last failure of name if it's still backing off.
+func (m *Manager) lastFailure(name string) error {
+	m.mu.RLock()
+	f := m.failures[name]
+	m.mu.RUnlock()
+	if f != nil && time.Now().Before(f.retryAt) {
+		return fmt.Errorf("autotls: obtaining certificate of %s is backed off until %s: %w",
+			name, f.retryAt.Format(time.RFC3339), f.err)
+	}
+	return nil
+}
+
+// recordFailure backs off obtaining the certificate of name, the backoff doubles on every
+// consecutive failure. The handshakes canceled by the clients aren't regarded as failures.
+func (m *Manager) recordFailure(name string, err error) {
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f := m.failures[name]
+	if f == nil {
+		f = &failure{}
+		m.failures[name] = f
+	}
+	backoff := failureBackoffMax
+	if f.count < 16 && failureBackoffMin<<f.count < failureBackoffMax {
+		backoff = failureBackoffMin << f.count
+	}
+	f.err = err
+	f.count++
+	f.retryAt = time.Now().Add(backoff)
+	hlog.SystemLogger().Errorf("[AutoTLS] obtain certificate of domain=%s failed, retry in %s: err=%v", name, backoff, err)
+}
+
+func (m *Manager) loadCert(name string) *tls.Certificate {
+	m.mu.RLock()
+	cert := m.certs[name]
+	m.mu.RUnlock()
+	if cert != nil && time.Now().Before(cert.Leaf.NotAfter) {
+		return cert
+	}
+	return nil
+}
+
+func (m *Manager) storeCert(name string, cert *tls.Certificate) {
+	m.mu.Lock()
+	m.certs[name] = cert
+	m.mu.Unlock()
+}
+
+// cachedCert returns the unexpired certificate of name in the cache.
+func (m *Manager) cachedCert(ctx context.Context, name string) (*tls.Certificate, error) {
+	data, err := m.opts.cache.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	keyBlock, rest := pem.Decode(data)
+	if keyBlock == nil {
+		return nil, errors.New("autotls: invalid cached certificate")
+	}
+	cert, err := tls.X509KeyPair(rest, pem.EncodeToMemory(keyBlock))
+	if err != nil {
+		return nil, err
+	}
+	if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+		return nil, err
+	}
+	if !time.Now().Before(cert.Leaf.NotAfter) {
+		return nil, errors.New("autotls: cached certificate is expired")
+	}
+	return &cert, nil
+}
+
+// obtain issues a new certificate of name and stores it in the cache.
+func (m *Manager) obtain(ctx context.Context, name string) (*tls.Certificate, error) {
+	client, err := m.acmeClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	order, err := client.newOrder(ctx, []string{name})
+	if err != nil {
+		return nil, err
+	}
+	for _, url := range order.Authorizations {
+		if err = m.authorize(ctx, client, url); err != nil {
+			return nil, err
+		}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: name},
+		DNSNames: []string{name},
+	}, key)
+	if err != nil {
+		return nil, err
+	}
+	if order, err = client.finalize(ctx, order, csr); err != nil {
+		return nil, err
+	}
+	chain, err := client.certificate(ctx, order.Certificate)
+	if err != nil {
+		return nil, err
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	cert, err := tls.X509KeyPair(chain, keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+		return nil, err
+	}
+	if err = m.opts.cache.Put(ctx, name, append(keyPEM, chain...)); err != nil {
+		hlog.SystemLogger().Warnf("[AutoTLS] cache certificate of domain=%s failed: err=%v", name, err)
+	}
+	hlog.SystemLogger().Infof("[AutoTLS] obtained certificate of domain=%s expiring at %s", name, cert.Leaf.NotAfter.Format(time.RFC3339))
+	return &cert, nil
+}
+
+// authorize fulfills a challenge of the authorization and waits until it is valid.
+func (m *Manager) authorize(ctx context.Context, client *acmeClient, url string) error {
+	authz, err := client.authorization(ctx, url)
+	if err != nil {
+		return err
+	}
+	if authz.Status == statusValid {
+		return nil
+	}
+
+	typ := challengeTLSALPN01
+	if m.opts.http01 {
+		typ = challengeHTTP01
+	}
+	var chal *acmeChallenge
+	for i := range authz.Challenges {
+		if authz.Challenges[i].Type == typ {
+			chal = &authz.Challenges[i]
+			break
+		}
+	}
+	if chal == nil {
+		return fmt.Errorf("autotls: %s challenge of %s is not offered by the server", typ, authz.Identifier.Value)
+	}
+
+	keyAuth := client.keyAuthorization(chal.Token)
+	domain := normalizeDomain(authz.Identifier.Value)
+	if typ == challengeHTTP01 {
+		m.httpTokens.Store(chal.Token, keyAuth)
+		defer m.httpTokens.Delete(chal.Token)
+	} else {
+		cert, err := tlsALPNCert(domain, keyAuth)
+		if err != nil {
+			return err
+		}
+		m.alpnCerts.Store(domain, cert)
+		defer m.alpnCerts.Delete(domain)
+	}
+
+	if err = client.accept(ctx, chal); err != nil {
+		return err
+	}
+	return client.waitAuthorization(ctx, url)
+}
+
+// acmeClient returns the client with the account registered, the account key is loaded from
+// the cache or generated and stored.
+func (m *Manager) acmeClient(ctx context.Context) (*acmeClient, error) {
+	m.clientMu.Lock()
+	defer m.clientMu.Unlock()
+	if m.client != nil {
+		return m.client, nil
+	}
+
+	var key *ecdsa.PrivateKey
+	data, err := m.opts.cache.Get(ctx, accountKeyName)
+	switch {
+	case err == nil:
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, errors.New("autotls: invalid cached account key")
+		}
+		if key, err = x509.ParseECPrivateKey(block.Bytes); err != nil {
+			return nil, err
+		}
+	case errors.Is(err, ErrCacheMiss):
+		if key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader); err != nil {
+			return nil, err
+		}
+		der, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			return nil, err
+		}
+		if err = m.opts.cache.Put(ctx, accountKeyName, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	client := newACMEClient(m.opts.directoryURL, key, m.opts.httpClient)
+	if err = client.register(ctx, m.opts.email); err != nil {
+		return nil, err
+	}
+	m.client = client
+	return client, nil
+}
+
+func (m *Manager) renewLoop() {
+	ticker := time.NewTicker(renewCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.done:
+			return
+		case <-ticker.C:
+			m.renew()
+		}
+	}
+}
+
+// renew renews the certificates expiring within the renew window, the current certificate
+// is kept in use if it fails, and it is retried on the next check.
+func (m *Manager) renew() {
+	m.mu.RLock()
+	var names []string
+	for name, cert := range m.certs {
+		if time.Until(cert.Leaf.NotAfter) < m.opts.renewBefore {
+			names = append(names, name)
+		}
+	}
+	m.mu.RUnlock()
+
+	for _, name := range names {
+		ctx, cancel := context.WithTimeout(context.Background(), obtainTimeout)
+		cert, err := m.obtain(ctx, name)
+		cancel()
+		if err != nil {
+			hlog.SystemLogger().Errorf("[AutoTLS] renew certificate of domain=%s failed: err=%v", name, err)
+			continue
+		}
+		m.storeCert(name, cert)
+	}
+}
+
+// tlsALPNCert creates the self-signed certificate answering the TLS-ALPN-01 challenge.
+func tlsALPNCert(domain, keyAuth string) (*tls.Certificate, error) {
+	sum := sha256.Sum256([]byte(keyAuth))
+	ext, err := asn1.Marshal(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber:    big.NewInt(now.UnixNano()),
+		Subject:         pkix.Name{CommonName: domain},
+		NotBefore:       now.Add(-time.Hour),
+		NotAfter:        now.Add(24 * time.Hour),
+		DNSNames:        []string{domain},
+		ExtraExtensions: []pkix.Extension{{Id: idPeACMEIdentifier, Critical: true, Value: ext}},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
+
+func normalizeDomain(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
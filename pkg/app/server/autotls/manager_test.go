@@ -0,0 +1,448 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package autotls
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"hertz-study/pkg/common/test/assert"
+)
+
+// fakeACME is an ACME server issuing certificates of a test CA. It verifies the JWS of every
+// request and validates the challenges by asking the manager directly.
+type fakeACME struct {
+	t   *testing.T
+	srv *httptest.Server
+	m   *Manager
+
+	caKey  *ecdsa.PrivateKey
+	caCert *x509.Certificate
+
+	mu          sync.Mutex
+	nonce       int
+	nonces      map[string]bool
+	accountKey  *ecdsa.PublicKey
+	domain      string
+	orders      int
+	authzStatus string
+	chain       []byte
+	// badNonces is the count of the requests rejected with badNonce before accepting them
+	badNonces int
+	// failValidation makes the challenges invalid
+	failValidation bool
+}
+
+func newFakeACME(t *testing.T) *fakeACME {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "fake acme ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	assert.Nil(t, err)
+	caCert, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+
+	f := &fakeACME{t: t, caKey: key, caCert: caCert, nonces: map[string]bool{}}
+	f.srv = httptest.NewServer(http.HandlerFunc(f.handle))
+	t.Cleanup(f.srv.Close)
+	return f
+}
+
+func (f *fakeACME) newManager(domains []string, opts ...Option) *Manager {
+	opts = append([]Option{
+		WithDirectoryURL(f.srv.URL + "/dir"),
+		WithHTTPClient(f.srv.Client()),
+		WithCache(DirCache(f.t.TempDir())),
+	}, opts...)
+	m := New(domains, opts...)
+	f.t.Cleanup(func() { m.Shutdown(context.Background()) })
+	f.mu.Lock()
+	f.m = m
+	f.mu.Unlock()
+	return m
+}
+
+func (f *fakeACME) handle(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/dir":
+		json.NewEncoder(w).Encode(map[string]string{ //nolint:errcheck
+			"newNonce":   f.srv.URL + "/nonce",
+			"newAccount": f.srv.URL + "/account",
+			"newOrder":   f.srv.URL + "/order",
+		})
+		return
+	case r.URL.Path == "/nonce":
+		w.Header().Set("Replay-Nonce", f.newNonce())
+		return
+	}
+
+	payload, ok := f.verify(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Replay-Nonce", f.newNonce())
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/account":
+		w.Header().Set("Location", f.srv.URL+"/account/1")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"status":"valid"}`)) //nolint:errcheck
+	case "/order":
+		var req struct {
+			Identifiers []acmeIdentifier `json:"identifiers"`
+		}
+		json.Unmarshal(payload, &req) //nolint:errcheck
+		f.domain = req.Identifiers[0].Value
+		f.orders++
+		f.authzStatus = statusPending
+		f.chain = nil
+		w.Header().Set("Location", f.srv.URL+"/order/1")
+		w.WriteHeader(http.StatusCreated)
+		f.writeOrder(w)
+	case "/order/1":
+		f.writeOrder(w)
+	case "/authz/1":
+		f.writeJSON(w, map[string]interface{}{
+			"status":     f.authzStatus,
+			"identifier": acmeIdentifier{Type: "dns", Value: f.domain},
+			"challenges": []acmeChallenge{
+				{Type: challengeHTTP01, URL: f.srv.URL + "/chal/http", Token: "http-token", Status: statusPending},
+				{Type: challengeTLSALPN01, URL: f.srv.URL + "/chal/alpn", Token: "alpn-token", Status: statusPending},
+			},
+		})
+	case "/chal/http", "/chal/alpn":
+		f.authzStatus = statusInvalid
+		if !f.failValidation && f.validate(r.URL.Path == "/chal/http") {
+			f.authzStatus = statusValid
+		}
+		f.writeJSON(w, map[string]string{"status": statusProcessing})
+	case "/finalize/1":
+		var req struct {
+			CSR string `json:"csr"`
+		}
+		json.Unmarshal(payload, &req) //nolint:errcheck
+		der, _ := base64.RawURLEncoding.DecodeString(req.CSR)
+		f.issue(der)
+		f.writeOrder(w)
+	case "/cert/1":
+		w.Header().Set("Content-Type", "application/pem-certificate-chain")
+		w.Write(f.chain) //nolint:errcheck
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeACME) newNonce() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.nonce++
+	n := fmt.Sprintf("nonce-%d", f.nonce)
+	f.nonces[n] = true
+	return n
+}
+
+// verify checks the JWS of the request and returns its payload.
+func (f *fakeACME) verify(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	var jws struct {
+		Protected string `json:"protected"`
+		Payload   string `json:"payload"`
+		Signature string `json:"signature"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&jws); err != nil {
+		f.t.Errorf("invalid jws: %v", err)
+		return nil, false
+	}
+	var protected struct {
+		Alg   string            `json:"alg"`
+		Nonce string            `json:"nonce"`
+		URL   string            `json:"url"`
+		JWK   map[string]string `json:"jwk"`
+		KID   string            `json:"kid"`
+	}
+	h, _ := base64.RawURLEncoding.DecodeString(jws.Protected)
+	if err := json.Unmarshal(h, &protected); err != nil {
+		f.t.Errorf("invalid protected header: %v", err)
+		return nil, false
+	}
+	assert.DeepEqual(f.t, "ES256", protected.Alg)
+	assert.DeepEqual(f.t, f.srv.URL+r.URL.Path, protected.URL)
+
+	f.mu.Lock()
+	validNonce := f.nonces[protected.Nonce]
+	delete(f.nonces, protected.Nonce)
+	reject := f.badNonces > 0
+	if reject {
+		f.badNonces--
+	}
+	pub := f.accountKey
+	if protected.JWK != nil {
+		x, _ := base64.RawURLEncoding.DecodeString(protected.JWK["x"])
+		y, _ := base64.RawURLEncoding.DecodeString(protected.JWK["y"])
+		pub = &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		f.accountKey = pub
+	} else {
+		assert.DeepEqual(f.t, f.srv.URL+"/account/1", protected.KID)
+	}
+	f.mu.Unlock()
+
+	if !validNonce || reject {
+		w.Header().Set("Replay-Nonce", f.newNonce())
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"type":"urn:ietf:params:acme:error:badNonce","detail":"bad nonce"}`)) //nolint:errcheck
+		return nil, false
+	}
+
+	sig, _ := base64.RawURLEncoding.DecodeString(jws.Signature)
+	digest := sha256.Sum256([]byte(jws.Protected + "." + jws.Payload))
+	if pub == nil || len(sig) != 64 ||
+		!ecdsa.Verify(pub, digest[:], new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])) {
+		f.t.Errorf("invalid signature of %s", r.URL.Path)
+		w.WriteHeader(http.StatusUnauthorized)
+		return nil, false
+	}
+	payload, _ := base64.RawURLEncoding.DecodeString(jws.Payload)
+	return payload, true
+}
+
+// validate fetches the answer of the challenge from the manager like the ACME server does.
+func (f *fakeACME) validate(http01 bool) bool {
+	jwk, _ := json.Marshal(map[string]string{
+		"crv": "P-256",
+		"kty": "EC",
+		"x":   encodeCoordinate(f.accountKey.X),
+		"y":   encodeCoordinate(f.accountKey.Y),
+	})
+	thumb := sha256.Sum256(jwk)
+	token := "alpn-token"
+	if http01 {
+		token = "http-token"
+	}
+	keyAuth := token + "." + base64.RawURLEncoding.EncodeToString(thumb[:])
+
+	if http01 {
+		v, ok := f.m.httpTokens.Load(token)
+		return ok && v.(string) == keyAuth
+	}
+	cert, err := f.m.GetCertificate(&tls.ClientHelloInfo{ServerName: f.domain, SupportedProtos: []string{ALPNProto}})
+	if err != nil {
+		return false
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return false
+	}
+	sum := sha256.Sum256([]byte(keyAuth))
+	want, _ := asn1.Marshal(sum[:])
+	for _, ext := range leaf.Extensions {
+		if ext.Id.Equal(idPeACMEIdentifier) {
+			return ext.Critical && string(ext.Value) == string(want)
+		}
+	}
+	return false
+}
+
+func (f *fakeACME) issue(csrDER []byte) {
+	csr, err := x509.ParseCertificateRequest(csrDER)
+	assert.Nil(f.t, err)
+	assert.Nil(f.t, csr.CheckSignature())
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      csr.Subject,
+		DNSNames:     csr.DNSNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(90 * 24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, f.caCert, csr.PublicKey, f.caKey)
+	assert.Nil(f.t, err)
+	f.chain = append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: f.caCert.Raw})...)
+}
+
+func (f *fakeACME) writeOrder(w http.ResponseWriter) {
+	order := map[string]interface{}{
+		"status":         statusPending,
+		"identifiers":    []acmeIdentifier{{Type: "dns", Value: f.domain}},
+		"authorizations": []string{f.srv.URL + "/authz/1"},
+		"finalize":       f.srv.URL + "/finalize/1",
+	}
+	switch {
+	case f.chain != nil:
+		order["status"] = statusValid
+		order["certificate"] = f.srv.URL + "/cert/1"
+	case f.authzStatus == statusValid:
+		order["status"] = "ready"
+	}
+	f.writeJSON(w, order)
+}
+
+func (f *fakeACME) writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v) //nolint:errcheck
+}
+
+func (f *fakeACME) orderCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.orders
+}
+
+func hello(name string) *tls.ClientHelloInfo {
+	return &tls.ClientHelloInfo{ServerName: name}
+}
+
+func TestManagerObtain(t *testing.T) {
+	for _, http01 := range []bool{false, true} {
+		t.Run(fmt.Sprintf("http01=%v", http01), func(t *testing.T) {
+			f := newFakeACME(t)
+			cache := DirCache(t.TempDir())
+			m := f.newManager([]string{"Example.com."}, WithHTTP01(http01), WithCache(cache))
+
+			cert, err := m.GetCertificate(hello("example.com"))
+			assert.Nil(t, err)
+			assert.DeepEqual(t, []string{"example.com"}, cert.Leaf.DNSNames)
+			assert.DeepEqual(t, 1, f.orderCount())
+			// the challenges are cleaned up
+			_, pending := m.alpnCerts.Load("example.com")
+			assert.False(t, pending)
+
+			// served from memory
+			cert2, err := m.GetCertificate(hello("EXAMPLE.com"))
+			assert.Nil(t, err)
+			assert.Assert(t, cert == cert2)
+
+			// served from the cache by another manager
+			m2 := f.newManager([]string{"example.com"}, WithCache(cache))
+			cert3, err := m2.GetCertificate(hello("example.com"))
+			assert.Nil(t, err)
+			assert.DeepEqual(t, cert.Certificate, cert3.Certificate)
+			assert.DeepEqual(t, 1, f.orderCount())
+		})
+	}
+}
+
+func TestManagerRetryBadNonce(t *testing.T) {
+	f := newFakeACME(t)
+	m := f.newManager([]string{"example.com"})
+	f.mu.Lock()
+	f.badNonces = 1
+	f.mu.Unlock()
+
+	_, err := m.GetCertificate(hello("example.com"))
+	assert.Nil(t, err)
+}
+
+func TestManagerDomainNotAllowed(t *testing.T) {
+	f := newFakeACME(t)
+	m := f.newManager([]string{"example.com"})
+
+	_, err := m.GetCertificate(hello("evil.com"))
+	assert.NotNil(t, err)
+	_, err = m.GetCertificate(hello(""))
+	assert.NotNil(t, err)
+	assert.DeepEqual(t, 0, f.orderCount())
+}
+
+func TestManagerFailureBackoff(t *testing.T) {
+	f := newFakeACME(t)
+	m := f.newManager([]string{"example.com"})
+	f.mu.Lock()
+	f.failValidation = true
+	f.mu.Unlock()
+
+	_, err := m.GetCertificate(hello("example.com"))
+	assert.NotNil(t, err)
+	assert.DeepEqual(t, 1, f.orderCount())
+
+	// the handshakes are rejected without asking the ACME server during the backoff
+	for i := 0; i < 3; i++ {
+		_, err = m.GetCertificate(hello("example.com"))
+		assert.NotNil(t, err)
+		assert.Assert(t, strings.Contains(err.Error(), "backed off"), err)
+	}
+	assert.DeepEqual(t, 1, f.orderCount())
+
+	// the backoff doubles on the consecutive failures
+	m.mu.Lock()
+	first := time.Until(m.failures["example.com"].retryAt)
+	m.failures["example.com"].retryAt = time.Now()
+	m.mu.Unlock()
+	_, err = m.GetCertificate(hello("example.com"))
+	assert.NotNil(t, err)
+	assert.DeepEqual(t, 2, f.orderCount())
+	m.mu.RLock()
+	second := time.Until(m.failures["example.com"].retryAt)
+	m.mu.RUnlock()
+	assert.Assert(t, first > failureBackoffMin-time.Minute && first <= failureBackoffMin, first)
+	assert.Assert(t, second > first, first, second)
+
+	// the failure is forgotten once the certificate is obtained
+	f.mu.Lock()
+	f.failValidation = false
+	f.mu.Unlock()
+	m.mu.Lock()
+	m.failures["example.com"].retryAt = time.Now()
+	m.mu.Unlock()
+	_, err = m.GetCertificate(hello("example.com"))
+	assert.Nil(t, err)
+	m.mu.RLock()
+	_, failed := m.failures["example.com"]
+	m.mu.RUnlock()
+	assert.False(t, failed)
+}
+
+func TestManagerCanceledIsNotFailure(t *testing.T) {
+	f := newFakeACME(t)
+	m := f.newManager([]string{"example.com"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := m.cert(ctx, "example.com")
+	assert.NotNil(t, err)
+	m.mu.RLock()
+	_, failed := m.failures["example.com"]
+	m.mu.RUnlock()
+	assert.False(t, failed)
+}
@@ -0,0 +1,105 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package autotls
+
+import (
+	"net/http"
+	"time"
+)
+
+const (
+	// LetsEncryptURL is the directory url of the Let's Encrypt production environment.
+	LetsEncryptURL = "https://acme-v02.api.letsencrypt.org/directory"
+	// LetsEncryptStagingURL is the directory url of the Let's Encrypt staging environment, which
+	// has much higher rate limits and issues untrusted certificates, use it for testing.
+	LetsEncryptStagingURL = "https://acme-staging-v02.api.letsencrypt.org/directory"
+
+	defaultCacheDir    = "autotls-cache"
+	defaultRenewBefore = 30 * 24 * time.Hour
+)
+
+type (
+	options struct {
+		cache        Cache
+		email        string
+		directoryURL string
+		renewBefore  time.Duration
+		httpClient   *http.Client
+		http01       bool
+	}
+
+	Option func(o *options)
+)
+
+func newOptions(opts ...Option) *options {
+	cfg := &options{
+		cache:        DirCache(defaultCacheDir),
+		directoryURL: LetsEncryptURL,
+		renewBefore:  defaultRenewBefore,
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return cfg
+}
+
+// WithCache sets the store of the account key and the certificates, default is
+// DirCache("autotls-cache") under the working directory.
+func WithCache(cache Cache) Option {
+	return func(o *options) {
+		o.cache = cache
+	}
+}
+
+// WithEmail sets the contact email of the ACME account, which is notified of the problems
+// with the certificates.
+func WithEmail(email string) Option {
+	return func(o *options) {
+		o.email = email
+	}
+}
+
+// WithDirectoryURL sets the directory url of the ACME server, default is LetsEncryptURL.
+func WithDirectoryURL(url string) Option {
+	return func(o *options) {
+		o.directoryURL = url
+	}
+}
+
+// WithRenewBefore sets how long before the expiry the certificates are renewed, default is 30 days.
+func WithRenewBefore(d time.Duration) Option {
+	return func(o *options) {
+		o.renewBefore = d
+	}
+}
+
+// WithHTTPClient sets the client used to talk to the ACME server, default is http.DefaultClient.
+func WithHTTPClient(c *http.Client) Option {
+	return func(o *options) {
+		o.httpClient = c
+	}
+}
+
+// WithHTTP01 uses the HTTP-01 challenge instead of the TLS-ALPN-01 one, Manager.HTTPHandler
+// must be served on port 80 of the domains then.
+func WithHTTP01(enable bool) Option {
+	return func(o *options) {
+		o.http01 = enable
+	}
+}
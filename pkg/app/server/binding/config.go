@@ -20,6 +20,7 @@ import (
 	stdJson "encoding/json"
 	"fmt"
 	"reflect"
+	"strings"
 	"time"
 
 	exprValidator "github.com/bytedance/go-tagexpr/v2/validator"
@@ -67,6 +68,11 @@ type BindConfig struct {
 	// NOTE:
 	// time.Time is registered by default
 	TypeUnmarshalFuncs map[reflect.Type]inDecoder.CustomizeDecodeFunc
+	// BodyDecoders registers body decoders by the lower-case content type,
+	// which take precedence over the built-in JSON and protobuf decoders.
+	// NOTE:
+	// It is used for Bind() and BindAndValidate().
+	BodyDecoders map[string]BodyDecodeFunc
 	// Validator is used to validate for BindAndValidate()
 	Validator StructValidator
 }
@@ -79,6 +85,7 @@ func NewBindConfig() *BindConfig {
 		EnableDecoderUseNumber:             false,
 		EnableDecoderDisallowUnknownFields: false,
 		TypeUnmarshalFuncs:                 make(map[reflect.Type]inDecoder.CustomizeDecodeFunc),
+		BodyDecoders:                       make(map[string]BodyDecodeFunc),
 		Validator:                          defaultValidate,
 	}
 }
@@ -110,6 +117,17 @@ func (config *BindConfig) MustRegTypeUnmarshal(t reflect.Type, fn func(req *prot
 	}
 }
 
+// BodyDecodeFunc decodes the request body into obj.
+type BodyDecodeFunc func(body []byte, obj interface{}) error
+
+// RegBodyDecoder registers the body decoder of the content type, e.g. "application/xml" with xml.Unmarshal.
+func (config *BindConfig) RegBodyDecoder(contentType string, fn BodyDecodeFunc) {
+	if config.BodyDecoders == nil {
+		config.BodyDecoders = make(map[string]BodyDecodeFunc)
+	}
+	config.BodyDecoders[strings.ToLower(contentType)] = fn
+}
+
 func (config *BindConfig) initTypeUnmarshal() {
 	config.MustRegTypeUnmarshal(reflect.TypeOf(time.Time{}), func(req *protocol.Request, params param.Params, text string) (reflect.Value, error) {
 		if text == "" {
@@ -145,8 +163,15 @@ type ValidateErrFactory func(fieldSelector, msg string) error
 type ValidateConfig struct {
 	ValidateTag string
 	ErrFactory  ValidateErrFactory
+	// Translations holds the messages of the validation errors by the lower-case language.
+	Translations map[string]ValidateMessages
 }
 
+// ValidateMessages maps the selector of the failed field (e.g. "User.Name") to the message,
+// "*" matches all the fields. "{field}" and "{msg}" in the message are replaced by the
+// selector and the original message.
+type ValidateMessages map[string]string
+
 func NewValidateConfig() *ValidateConfig {
 	return &ValidateConfig{}
 }
@@ -165,6 +190,18 @@ func (config *ValidateConfig) SetValidatorErrorFactory(errFactory ValidateErrFac
 	config.ErrFactory = errFactory
 }
 
+// RegTranslation registers the messages of the validation errors in the language, e.g. "zh" or "zh-CN",
+// which are chosen by the Accept-Language header of the request in RequestContext.BindAndValidate.
+// NOTE:
+//
+//	It only works with the default error factory.
+func (config *ValidateConfig) RegTranslation(lang string, msgs ValidateMessages) {
+	if config.Translations == nil {
+		config.Translations = make(map[string]ValidateMessages)
+	}
+	config.Translations[strings.ToLower(lang)] = msgs
+}
+
 // SetValidatorTag customizes the factory of validation error.
 func (config *ValidateConfig) SetValidatorTag(tag string) {
 	config.ValidateTag = tag
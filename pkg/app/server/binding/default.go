@@ -63,6 +63,7 @@ package binding
 import (
 	"bytes"
 	stdJson "encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/url"
@@ -179,7 +180,7 @@ func (b *defaultBinder) bindTag(req *protocol.Request, v interface{}, params par
 	if len(tag) == 0 {
 		err := b.preBindBody(req, v)
 		if err != nil {
-			return fmt.Errorf("bind body failed, err=%v", err)
+			return fmt.Errorf("bind body failed, err=%w", err)
 		}
 	}
 	cache := b.tagCache(tag)
@@ -285,6 +286,10 @@ func (b *defaultBinder) BindForm(req *protocol.Request, v interface{}) error {
 }
 
 func (b *defaultBinder) BindJSON(req *protocol.Request, v interface{}) error {
+	if !b.config.EnableDecoderUseNumber && !b.config.EnableDecoderDisallowUnknownFields {
+		// respect the unmarshaler replaced by json.SetCodec
+		return hJson.Unmarshal(req.Body(), v)
+	}
 	return b.decodeJSON(bytes.NewReader(req.Body()), v)
 }
 
@@ -325,8 +330,11 @@ func (b *defaultBinder) preBindBody(req *protocol.Request, v interface{}) error
 	if req.Header.ContentLength() <= 0 {
 		return nil
 	}
-	ct := bytesconv.B2s(req.Header.ContentType())
-	switch strings.ToLower(utils.FilterContentType(ct)) {
+	ct := strings.ToLower(utils.FilterContentType(bytesconv.B2s(req.Header.ContentType())))
+	if decode, ok := b.config.BodyDecoders[ct]; ok {
+		return decode(req.Body(), v)
+	}
+	switch ct {
 	case consts.MIMEApplicationJSON:
 		return hJson.Unmarshal(req.Body(), v)
 	case consts.MIMEPROTOBUF:
@@ -341,8 +349,11 @@ func (b *defaultBinder) preBindBody(req *protocol.Request, v interface{}) error
 }
 
 func (b *defaultBinder) bindNonStruct(req *protocol.Request, v interface{}) (err error) {
-	ct := bytesconv.B2s(req.Header.ContentType())
-	switch strings.ToLower(utils.FilterContentType(ct)) {
+	ct := strings.ToLower(utils.FilterContentType(bytesconv.B2s(req.Header.ContentType())))
+	if decode, ok := b.config.BodyDecoders[ct]; ok {
+		return decode(req.Body(), v)
+	}
+	switch ct {
 	case consts.MIMEApplicationJSON:
 		err = hJson.Unmarshal(req.Body(), v)
 	case consts.MIMEPROTOBUF:
@@ -382,11 +393,15 @@ func (b *defaultBinder) bindNonStruct(req *protocol.Request, v interface{}) (err
 	return
 }
 
-var _ StructValidator = (*validator)(nil)
+var (
+	_ StructValidator    = (*validator)(nil)
+	_ ValidateTranslator = (*validator)(nil)
+)
 
 type validator struct {
-	validateTag string
-	validate    *exprValidator.Validator
+	validateTag  string
+	validate     *exprValidator.Validator
+	translations map[string]ValidateMessages
 }
 
 func NewValidator(config *ValidateConfig) StructValidator {
@@ -398,19 +413,23 @@ func NewValidator(config *ValidateConfig) StructValidator {
 	if config != nil && config.ErrFactory != nil {
 		vd.SetErrorFactory(config.ErrFactory)
 	}
-	return &validator{
+	v := &validator{
 		validateTag: validateTag,
 		validate:    vd,
 	}
+	if config != nil {
+		v.translations = config.Translations
+	}
+	return v
 }
 
-// Error validate error
-type validateError struct {
+// ValidateError is returned by the default validator when a field fails to be validated.
+type ValidateError struct {
 	FailPath, Msg string
 }
 
 // Error implements error interface.
-func (e *validateError) Error() string {
+func (e *ValidateError) Error() string {
 	if e.Msg != "" {
 		return e.Msg
 	}
@@ -418,7 +437,7 @@ func (e *validateError) Error() string {
 }
 
 func defaultValidateErrorFactory(failPath, msg string) error {
-	return &validateError{
+	return &ValidateError{
 		FailPath: failPath,
 		Msg:      msg,
 	}
@@ -441,6 +460,38 @@ func (v *validator) ValidateTag() string {
 	return v.validateTag
 }
 
+// TranslateError looks up the message of the failed field in the translations of langs in order,
+// the primary language (e.g. "zh" of "zh-CN") is also tried for every language.
+func (v *validator) TranslateError(err error, langs []string) error {
+	var ve *ValidateError
+	if len(v.translations) == 0 || !errors.As(err, &ve) {
+		return err
+	}
+	for _, lang := range langs {
+		lang = strings.ToLower(lang)
+		msgs, ok := v.translations[lang]
+		if !ok {
+			if i := strings.IndexByte(lang, '-'); i > 0 {
+				msgs, ok = v.translations[lang[:i]]
+			}
+		}
+		if !ok {
+			continue
+		}
+		msg, ok := msgs[ve.FailPath]
+		if !ok {
+			if msg, ok = msgs["*"]; !ok {
+				return err
+			}
+		}
+		return &ValidateError{
+			FailPath: ve.FailPath,
+			Msg:      strings.NewReplacer("{field}", ve.FailPath, "{msg}", ve.Error()).Replace(msg),
+		}
+	}
+	return err
+}
+
 var defaultValidate = NewValidator(NewValidateConfig())
 
 func DefaultValidator() StructValidator {
@@ -0,0 +1,29 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package binding
+
+import (
+	inDecoder "hertz-study/pkg/app/server/binding/internal/decoder"
+)
+
+// FieldError is returned by the binding when the parameter of a field is missing or
+// can not be decoded, use errors.As to get the field and the value.
+type FieldError = inDecoder.FieldError
+
+// ErrMissingRequired is wrapped by the FieldError of a missing required parameter,
+// which can be checked by errors.Is.
+var ErrMissingRequired = inDecoder.ErrMissingRequired
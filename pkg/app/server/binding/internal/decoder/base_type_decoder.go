@@ -41,7 +41,6 @@
 package decoder
 
 import (
-	"fmt"
 	"reflect"
 
 	"hertz-study/pkg/protocol"
@@ -75,7 +74,7 @@ func (d *baseTypeFieldTextDecoder) Decode(req *protocol.Request, params param.Pa
 				if found {
 					err = nil
 				} else {
-					err = fmt.Errorf("'%s' field is a 'required' parameter, but the request body does not have this parameter '%s'", d.fieldName, tagInfo.JSONName)
+					err = missingRequiredError(d.fieldName)
 				}
 			}
 			continue
@@ -87,7 +86,7 @@ func (d *baseTypeFieldTextDecoder) Decode(req *protocol.Request, params param.Pa
 			break
 		}
 		if tagInfo.Required {
-			err = fmt.Errorf("'%s' field is a 'required' parameter, but the request does not have this parameter", d.fieldName)
+			err = missingRequiredError(d.fieldName)
 		}
 	}
 	if err != nil {
@@ -113,7 +112,7 @@ func (d *baseTypeFieldTextDecoder) Decode(req *protocol.Request, params param.Pa
 		var vv reflect.Value
 		vv, err := stringToValue(t, text, req, params, d.config)
 		if err != nil {
-			return err
+			return decodeError(d.fieldName, text, d.fieldType.Name(), err)
 		}
 		field.Set(ReferenceValue(vv, ptrDepth))
 		return nil
@@ -122,7 +121,7 @@ func (d *baseTypeFieldTextDecoder) Decode(req *protocol.Request, params param.Pa
 	// Non-pointer elems
 	err = d.decoder.UnmarshalString(text, field, d.config.LooseZeroMode)
 	if err != nil {
-		return fmt.Errorf("unable to decode '%s' as %s: %w", text, d.fieldType.Name(), err)
+		return decodeError(d.fieldName, text, d.fieldType.Name(), err)
 	}
 
 	return nil
@@ -135,8 +134,7 @@ func getBaseTypeTextDecoder(field reflect.StructField, index int, tagInfos []Tag
 			tagInfos[idx].SliceGetter = pathSlice
 			tagInfos[idx].Getter = path
 		case formTag:
-			tagInfos[idx].SliceGetter = postFormSlice
-			tagInfos[idx].Getter = postForm
+			setFormGetters(&tagInfos[idx])
 		case queryTag:
 			tagInfos[idx].SliceGetter = querySlice
 			tagInfos[idx].Getter = query
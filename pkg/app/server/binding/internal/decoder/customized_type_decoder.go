@@ -78,7 +78,7 @@ func (d *customizedFieldTextDecoder) Decode(req *protocol.Request, params param.
 
 	v, err := d.decodeFunc(req, params, text)
 	if err != nil {
-		return err
+		return decodeError(d.fieldName, text, d.fieldType.Name(), err)
 	}
 	if !v.IsValid() {
 		return nil
@@ -108,8 +108,7 @@ func getCustomizedFieldDecoder(field reflect.StructField, index int, tagInfos []
 			tagInfos[idx].SliceGetter = pathSlice
 			tagInfos[idx].Getter = path
 		case formTag:
-			tagInfos[idx].SliceGetter = postFormSlice
-			tagInfos[idx].Getter = postForm
+			setFormGetters(&tagInfos[idx])
 		case queryTag:
 			tagInfos[idx].SliceGetter = querySlice
 			tagInfos[idx].Getter = query
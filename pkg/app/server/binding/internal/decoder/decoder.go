@@ -80,7 +80,7 @@ func GetReqDecoder(rt reflect.Type, byTag string, config *DecodeConfig) (Decoder
 			continue
 		}
 
-		dec, needValidate2, err := getFieldDecoder(parentInfos{[]reflect.Type{el}, []int{}, ""}, el.Field(i), i, byTag, config)
+		dec, needValidate2, err := getFieldDecoder(parentInfos{[]reflect.Type{el}, []int{}, "", ""}, el.Field(i), i, byTag, config)
 		if err != nil {
 			return nil, false, err
 		}
@@ -107,6 +107,7 @@ type parentInfos struct {
 	Types    []reflect.Type
 	Indexes  []int
 	JSONName string
+	FormName string
 }
 
 func getFieldDecoder(pInfo parentInfos, field reflect.StructField, index int, byTag string, config *DecodeConfig) ([]fieldDecoder, bool, error) {
@@ -129,6 +130,11 @@ func getFieldDecoder(pInfo parentInfos, field reflect.StructField, index int, by
 	if len(byTag) != 0 {
 		fieldTagInfos = getFieldTagInfoByTag(field, byTag)
 	}
+	for i := range fieldTagInfos {
+		if fieldTagInfos[i].Key == formTag {
+			fieldTagInfos[i].FormParent = pInfo.FormName
+		}
+	}
 
 	// customized type decoder has the highest priority
 	if customizedFunc, exist := config.TypeUnmarshalFuncs[field.Type]; exist {
@@ -174,6 +180,7 @@ func getFieldDecoder(pInfo parentInfos, field reflect.StructField, index int, by
 		}
 
 		pIdx := pInfo.Indexes
+		newParentFormName := formName(field, pInfo.FormName)
 		for i := 0; i < el.NumField(); i++ {
 			if el.Field(i).PkgPath != "" && !el.Field(i).Anonymous {
 				// ignore unexported field
@@ -187,6 +194,7 @@ func getFieldDecoder(pInfo parentInfos, field reflect.StructField, index int, by
 			pInfo.Indexes = idxes
 			pInfo.Types = append(pInfo.Types, el)
 			pInfo.JSONName = newParentJSONName
+			pInfo.FormName = newParentFormName
 			dec, needValidate2, err := getFieldDecoder(pInfo, el.Field(i), i, byTag, config)
 			needValidate = needValidate || needValidate2
 			if err != nil {
@@ -0,0 +1,56 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package decoder
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrMissingRequired is wrapped by the FieldError of a missing required parameter.
+var ErrMissingRequired = errors.New("missing required parameter")
+
+// FieldError is returned when the parameter of a field is missing or can not be decoded.
+type FieldError struct {
+	// Field is the name of the struct field.
+	Field string
+	// Value is the text which fails to be decoded, it is empty for missing parameters.
+	Value string
+	// Type is the type of the field.
+	Type string
+	// Err is ErrMissingRequired or the error of decoding.
+	Err error
+}
+
+func (e *FieldError) Error() string {
+	if e.Err == ErrMissingRequired {
+		return fmt.Sprintf("'%s' field is a 'required' parameter, but the request does not have this parameter", e.Field)
+	}
+	return fmt.Sprintf("unable to decode '%s' as %s: %v", e.Value, e.Type, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+func missingRequiredError(field string) error {
+	return &FieldError{Field: field, Err: ErrMissingRequired}
+}
+
+func decodeError(field, value, typ string, err error) error {
+	return &FieldError{Field: field, Value: value, Type: typ, Err: err}
+}
@@ -41,6 +41,8 @@
 package decoder
 
 import (
+	"strings"
+
 	"hertz-study/pkg/protocol"
 	"hertz-study/pkg/route/param"
 )
@@ -86,6 +88,43 @@ func postForm(req *protocol.Request, params param.Params, key string, defaultVal
 	return ret, false
 }
 
+// setFormGetters sets the getters of the form tag, which look up the nested keys "a.b" and "a[b]"
+// before the key "b" if the field is nested in the struct field "a".
+func setFormGetters(tagInfo *TagInfo) {
+	if tagInfo.FormParent == "" {
+		tagInfo.SliceGetter = postFormSlice
+		tagInfo.Getter = postForm
+		return
+	}
+	keys := nestedFormKeys(tagInfo.FormParent, tagInfo.Value)
+	tagInfo.Getter = func(req *protocol.Request, params param.Params, key string, defaultValue ...string) (string, bool) {
+		for _, k := range keys {
+			if ret, exist := postForm(req, params, k); exist {
+				return ret, exist
+			}
+		}
+		return postForm(req, params, key, defaultValue...)
+	}
+	tagInfo.SliceGetter = func(req *protocol.Request, params param.Params, key string, defaultValue ...string) []string {
+		for _, k := range keys {
+			if ret := postFormSlice(req, params, k); len(ret) > 0 {
+				return ret
+			}
+		}
+		return postFormSlice(req, params, key, defaultValue...)
+	}
+}
+
+// nestedFormKeys returns the keys of the field named name nested in the dotted parent,
+// e.g. "a.b.c" and "a[b][c]" for the parent "a.b" and the name "c".
+func nestedFormKeys(parent, name string) []string {
+	bracket := strings.Replace(parent, ".", "[", 1)
+	if bracket != parent {
+		bracket = strings.ReplaceAll(bracket, ".", "][") + "]"
+	}
+	return []string{parent + "." + name, bracket + "[" + name + "]"}
+}
+
 func query(req *protocol.Request, params param.Params, key string, defaultValue ...string) (ret string, exist bool) {
 	if ret, exist = req.URI().QueryArgs().PeekExists(key); exist {
 		return
@@ -41,7 +41,6 @@
 package decoder
 
 import (
-	"fmt"
 	"reflect"
 
 	"hertz-study/internal/bytesconv"
@@ -67,7 +66,7 @@ func (d *mapTypeFieldTextDecoder) Decode(req *protocol.Request, params param.Par
 				if found {
 					err = nil
 				} else {
-					err = fmt.Errorf("'%s' field is a 'required' parameter, but the request does not have this parameter", d.fieldName)
+					err = missingRequiredError(d.fieldName)
 				}
 			}
 			continue
@@ -79,7 +78,7 @@ func (d *mapTypeFieldTextDecoder) Decode(req *protocol.Request, params param.Par
 			break
 		}
 		if tagInfo.Required {
-			err = fmt.Errorf("'%s' field is a 'required' parameter, but the request does not have this parameter", d.fieldName)
+			err = missingRequiredError(d.fieldName)
 		}
 	}
 	if err != nil {
@@ -104,7 +103,7 @@ func (d *mapTypeFieldTextDecoder) Decode(req *protocol.Request, params param.Par
 		var vv reflect.Value
 		vv, err := stringToValue(t, text, req, params, d.config)
 		if err != nil {
-			return fmt.Errorf("unable to decode '%s' as %s: %w", text, d.fieldType.Name(), err)
+			return decodeError(d.fieldName, text, d.fieldType.Name(), err)
 		}
 		field.Set(ReferenceValue(vv, ptrDepth))
 		return nil
@@ -112,7 +111,7 @@ func (d *mapTypeFieldTextDecoder) Decode(req *protocol.Request, params param.Par
 
 	err = hJson.Unmarshal(bytesconv.S2b(text), field.Addr().Interface())
 	if err != nil {
-		return fmt.Errorf("unable to decode '%s' as %s: %w", text, d.fieldType.Name(), err)
+		return decodeError(d.fieldName, text, d.fieldType.Name(), err)
 	}
 
 	return nil
@@ -125,8 +124,7 @@ func getMapTypeTextDecoder(field reflect.StructField, index int, tagInfos []TagI
 			tagInfos[idx].SliceGetter = pathSlice
 			tagInfos[idx].Getter = path
 		case formTag:
-			tagInfos[idx].SliceGetter = postFormSlice
-			tagInfos[idx].Getter = postForm
+			setFormGetters(&tagInfos[idx])
 		case queryTag:
 			tagInfos[idx].SliceGetter = querySlice
 			tagInfos[idx].Getter = query
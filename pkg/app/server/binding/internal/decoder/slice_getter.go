@@ -41,6 +41,8 @@
 package decoder
 
 import (
+	"strings"
+
 	"hertz-study/internal/bytesconv"
 	"hertz-study/pkg/protocol"
 	"hertz-study/pkg/route/param"
@@ -86,6 +88,11 @@ func postFormSlice(req *protocol.Request, params param.Params, key string, defau
 		return
 	}
 
+	// the multiple values may be sent as "key[]=a&key[]=b"
+	if !strings.HasSuffix(key, "[]") {
+		return postFormSlice(req, params, key+"[]", defaultValue...)
+	}
+
 	if len(ret) == 0 && len(defaultValue) != 0 {
 		ret = append(ret, defaultValue...)
 	}
@@ -69,7 +69,7 @@ func (d *sliceTypeFieldTextDecoder) Decode(req *protocol.Request, params param.P
 				if found {
 					err = nil
 				} else {
-					err = fmt.Errorf("'%s' field is a 'required' parameter, but the request does not have this parameter", d.fieldName)
+					err = missingRequiredError(d.fieldName)
 				}
 			}
 			continue
@@ -84,7 +84,7 @@ func (d *sliceTypeFieldTextDecoder) Decode(req *protocol.Request, params param.P
 			break
 		}
 		if tagInfo.Required {
-			err = fmt.Errorf("'%s' field is a 'required' parameter, but the request does not have this parameter", d.fieldName)
+			err = missingRequiredError(d.fieldName)
 		}
 	}
 	if err != nil {
@@ -151,7 +151,7 @@ func (d *sliceTypeFieldTextDecoder) Decode(req *protocol.Request, params param.P
 		// text[0] can be a complete json content for []Type.
 		err = hJson.Unmarshal(bytesconv.S2b(texts[0]), reqValue.Field(d.index).Addr().Interface())
 		if err != nil {
-			return fmt.Errorf("using '%s' to unmarshal field '%s: %s' failed, %v", texts[0], d.fieldName, d.fieldType.String(), err)
+			return decodeError(d.fieldName, texts[0], d.fieldType.String(), err)
 		}
 	} else {
 		reqValue.Field(d.index).Set(ReferenceValue(field, parentPtrDepth))
@@ -174,8 +174,7 @@ func getSliceFieldDecoder(field reflect.StructField, index int, tagInfos []TagIn
 			tagInfos[idx].SliceGetter = pathSlice
 			tagInfos[idx].Getter = path
 		case formTag:
-			tagInfos[idx].SliceGetter = postFormSlice
-			tagInfos[idx].Getter = postForm
+			setFormGetters(&tagInfos[idx])
 		case queryTag:
 			tagInfos[idx].SliceGetter = querySlice
 			tagInfos[idx].Getter = query
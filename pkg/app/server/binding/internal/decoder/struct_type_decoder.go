@@ -17,7 +17,6 @@
 package decoder
 
 import (
-	"fmt"
 	"reflect"
 
 	"hertz-study/internal/bytesconv"
@@ -44,7 +43,7 @@ func (d *structTypeFieldTextDecoder) Decode(req *protocol.Request, params param.
 				if found {
 					err = nil
 				} else {
-					err = fmt.Errorf("'%s' field is a 'required' parameter, but the request does not have this parameter", d.fieldName)
+					err = missingRequiredError(d.fieldName)
 				}
 			}
 			continue
@@ -56,7 +55,7 @@ func (d *structTypeFieldTextDecoder) Decode(req *protocol.Request, params param.
 			break
 		}
 		if tagInfo.Required {
-			err = fmt.Errorf("'%s' field is a 'required' parameter, but the request does not have this parameter", d.fieldName)
+			err = missingRequiredError(d.fieldName)
 		}
 	}
 	if err != nil {
@@ -102,8 +101,7 @@ func getStructTypeFieldDecoder(field reflect.StructField, index int, tagInfos []
 			tagInfos[idx].SliceGetter = pathSlice
 			tagInfos[idx].Getter = path
 		case formTag:
-			tagInfos[idx].SliceGetter = postFormSlice
-			tagInfos[idx].Getter = postForm
+			setFormGetters(&tagInfos[idx])
 		case queryTag:
 			tagInfos[idx].SliceGetter = querySlice
 			tagInfos[idx].Getter = query
@@ -44,6 +44,7 @@ type TagInfo struct {
 	Key         string
 	Value       string
 	JSONName    string
+	FormParent  string
 	Required    bool
 	Skip        bool
 	Default     string
@@ -120,6 +121,22 @@ func lookupFieldTags(field reflect.StructField, parentJSONName string, config *D
 	return tagInfos, newParentJSONName, needValidate
 }
 
+// formName returns the form key of the nested fields of field, which is
+// parentFormName followed by the form tag of field, e.g. "a.b".
+func formName(field reflect.StructField, parentFormName string) string {
+	if field.Anonymous {
+		return parentFormName
+	}
+	name := field.Name
+	if tagValue, _ := head(field.Tag.Get(formTag), ","); tagValue != "" && tagValue != "-" {
+		name = tagValue
+	}
+	if parentFormName == "" {
+		return name
+	}
+	return parentFormName + "." + name
+}
+
 func getDefaultFieldTags(field reflect.StructField) (tagInfos []TagInfo) {
 	defaultVal := ""
 	if val, ok := field.Tag.Lookup(defaultTag); ok {
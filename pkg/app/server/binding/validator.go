@@ -45,3 +45,10 @@ type StructValidator interface {
 	Engine() interface{}
 	ValidateTag() string
 }
+
+// ValidateTranslator is implemented by the validators supporting translated error messages.
+type ValidateTranslator interface {
+	// TranslateError translates err into the first supported language of langs,
+	// err is returned as is if there is no translation.
+	TranslateError(err error, langs []string) error
+}
@@ -0,0 +1,203 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package certmanager provides a certificate manager which reloads the certificate of the tls
+// server when its files change, without restarting the server.
+//
+//	m, err := certmanager.New("server.crt", "server.key")
+//	if err != nil {
+//		panic(err)
+//	}
+//	h := server.New(server.WithTLS(m.TLSConfig()))
+//	h.OnShutdown = append(h.OnShutdown, func(ctx context.Context) { m.Close() })
+package certmanager
+
+import (
+	"context"
+	"crypto/tls"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"hertz-study/pkg/common/hlog"
+)
+
+// Manager holds the certificate loaded from the cert and key files and reloads it atomically
+// when the files change, the connections established before keep using the old certificate.
+type Manager struct {
+	certFile string
+	keyFile  string
+	opts     *options
+
+	cert      atomic.Value // *tls.Certificate
+	watcher   *fsnotify.Watcher
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// New loads the certificate from the PEM encoded cert and key files and starts watching them.
+func New(certFile, keyFile string, opts ...Option) (*Manager, error) {
+	m := &Manager{
+		certFile: certFile,
+		keyFile:  keyFile,
+		opts:     newOptions(opts...),
+		done:     make(chan struct{}),
+	}
+	if err := m.Reload(); err != nil {
+		return nil, err
+	}
+
+	if m.opts.pollInterval > 0 {
+		go m.poll()
+		return m, nil
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	// watch the directories rather than the files, since the files are usually replaced by
+	// renaming, e.g. the kubernetes secret volumes swap a symlink to update the files
+	dirs := map[string]struct{}{filepath.Dir(certFile): {}, filepath.Dir(keyFile): {}}
+	for dir := range dirs {
+		if err = watcher.Add(dir); err != nil {
+			watcher.Close()
+			return nil, err
+		}
+	}
+	m.watcher = watcher
+	go m.watch()
+	return m, nil
+}
+
+// GetCertificate returns the current certificate, it is used as tls.Config.GetCertificate.
+func (m *Manager) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return m.cert.Load().(*tls.Certificate), nil
+}
+
+// TLSConfig returns a tls config serving the certificate of m, which requires TLS 1.2 at least.
+func (m *Manager) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: m.GetCertificate,
+	}
+}
+
+// Reload loads the certificate from the files, the current one is kept if it fails.
+func (m *Manager) Reload() error {
+	commit, err := m.PrepareReload(context.Background())
+	if err != nil {
+		return err
+	}
+	commit()
+	return nil
+}
+
+// PrepareReload loads the certificate from the files and returns the function to apply it,
+// which is a step of the reload pipeline of the server:
+//
+//	h.AddReloader("certificate", m.PrepareReload)
+func (m *Manager) PrepareReload(_ context.Context) (func(), error) {
+	cert, err := tls.LoadX509KeyPair(m.certFile, m.keyFile)
+	if err != nil {
+		return nil, err
+	}
+	return func() {
+		m.cert.Store(&cert)
+	}, nil
+}
+
+// Close stops watching the files.
+func (m *Manager) Close() error {
+	var err error
+	m.closeOnce.Do(func() {
+		close(m.done)
+		if m.watcher != nil {
+			err = m.watcher.Close()
+		}
+	})
+	return err
+}
+
+func (m *Manager) watch() {
+	var delay <-chan time.Time
+	for {
+		select {
+		case <-m.done:
+			return
+		case event, ok := <-m.watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+			if delay == nil {
+				delay = time.After(m.opts.reloadDelay)
+			}
+		case err, ok := <-m.watcher.Errors:
+			if !ok {
+				return
+			}
+			hlog.SystemLogger().Errorf("[CertManager] watch certificate files failed: err=%v", err)
+		case <-delay:
+			delay = nil
+			m.reload()
+		}
+	}
+}
+
+func (m *Manager) poll() {
+	ticker := time.NewTicker(m.opts.pollInterval)
+	defer ticker.Stop()
+	last := m.modTime()
+	for {
+		select {
+		case <-m.done:
+			return
+		case <-ticker.C:
+			if t := m.modTime(); !t.Equal(last) {
+				last = t
+				m.reload()
+			}
+		}
+	}
+}
+
+// modTime returns the latest modification time of the files.
+func (m *Manager) modTime() time.Time {
+	var t time.Time
+	for _, f := range []string{m.certFile, m.keyFile} {
+		if info, err := os.Stat(f); err == nil && info.ModTime().After(t) {
+			t = info.ModTime()
+		}
+	}
+	return t
+}
+
+func (m *Manager) reload() {
+	err := m.Reload()
+	if err != nil {
+		hlog.SystemLogger().Errorf("[CertManager] reload certificate failed, keep using the current one: err=%v", err)
+	} else {
+		hlog.SystemLogger().Infof("[CertManager] reloaded certificate from file=%s", m.certFile)
+	}
+	if m.opts.onReload != nil {
+		m.opts.onReload(err)
+	}
+}
@@ -0,0 +1,67 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package certmanager
+
+import "time"
+
+const defaultReloadDelay = 100 * time.Millisecond
+
+type (
+	options struct {
+		pollInterval time.Duration
+		reloadDelay  time.Duration
+		onReload     func(err error)
+	}
+
+	Option func(o *options)
+)
+
+func newOptions(opts ...Option) *options {
+	cfg := &options{
+		reloadDelay: defaultReloadDelay,
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return cfg
+}
+
+// WithPollInterval checks the modification time of the files every interval instead of
+// watching the file events, which is useful on the file systems without inotify support.
+func WithPollInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.pollInterval = interval
+	}
+}
+
+// WithReloadDelay sets how long to wait after a file event before reloading, so that the
+// certificate and the key being replaced one after the other are loaded together, default is 100ms.
+func WithReloadDelay(delay time.Duration) Option {
+	return func(o *options) {
+		o.reloadDelay = delay
+	}
+}
+
+// WithOnReload sets the function called after every reload triggered by the file changes,
+// err is nil if the new certificate is in use.
+func WithOnReload(f func(err error)) Option {
+	return func(o *options) {
+		o.onReload = f
+	}
+}
@@ -0,0 +1,172 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	cgroupRoot = "/sys/fs/cgroup"
+	// cgroup v1 reports a huge page-aligned number instead of "max" if there is no limit
+	cgroupV1Unlimited = 1 << 62
+)
+
+var (
+	errNoMemoryLimit = errors.New("no container memory limit")
+	errNoCPUQuota    = errors.New("no container cpu quota")
+)
+
+// containerMemoryLimit returns the memory limit of the cgroup the process belongs to.
+func containerMemoryLimit() (int64, error) {
+	// cgroup v2
+	if path, err := cgroupPath("", "/proc/self/cgroup"); err == nil {
+		if limit, err := readCgroupLimit(cgroupRoot, path, "memory.max"); !os.IsNotExist(err) {
+			return limit, err
+		}
+	}
+	// cgroup v1
+	path, err := cgroupPath("memory", "/proc/self/cgroup")
+	if err != nil {
+		return 0, err
+	}
+	return readCgroupLimit(filepath.Join(cgroupRoot, "memory"), path, "memory.limit_in_bytes")
+}
+
+// cgroupPath returns the path of the cgroup of the controller in the cgroup file,
+// the empty controller stands for the unified hierarchy of cgroup v2.
+func cgroupPath(controller, file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		// hierarchy-ID:controller-list:cgroup-path
+		parts := strings.SplitN(s.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if controller == "" {
+			if parts[0] == "0" && parts[1] == "" {
+				return parts[2], nil
+			}
+			continue
+		}
+		for _, c := range strings.Split(parts[1], ",") {
+			if c == controller {
+				return parts[2], nil
+			}
+		}
+	}
+	if err = s.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("cgroup of controller=%q not found", controller)
+}
+
+// readCgroupFile reads the file of the cgroup path under root, the file under root is read
+// instead if the path is not visible, e.g. the cgroup is mounted as the root in the container.
+func readCgroupFile(root, path, file string) ([]byte, error) {
+	b, err := os.ReadFile(filepath.Join(root, path, file))
+	if os.IsNotExist(err) && path != "/" {
+		b, err = os.ReadFile(filepath.Join(root, file))
+	}
+	return b, err
+}
+
+func readCgroupLimit(root, path, file string) (int64, error) {
+	b, err := readCgroupFile(root, path, file)
+	if err != nil {
+		return 0, err
+	}
+	v := strings.TrimSpace(string(b))
+	if v == "max" {
+		return 0, errNoMemoryLimit
+	}
+	limit, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid memory limit=%s in %s", v, file)
+	}
+	if limit <= 0 || limit >= cgroupV1Unlimited {
+		return 0, errNoMemoryLimit
+	}
+	return limit, nil
+}
+
+// containerCPUQuota returns the count of CPUs the cgroup the process belongs to is allowed
+// to use, which may be fractional, e.g. 0.5 for the 500m CPU limit of kubernetes.
+func containerCPUQuota() (float64, error) {
+	// cgroup v2, "$MAX $PERIOD" in cpu.max
+	if path, err := cgroupPath("", "/proc/self/cgroup"); err == nil {
+		b, err := readCgroupFile(cgroupRoot, path, "cpu.max")
+		if err == nil {
+			fields := strings.Fields(string(b))
+			if len(fields) != 2 {
+				return 0, fmt.Errorf("invalid cpu.max=%s", b)
+			}
+			if fields[0] == "max" {
+				return 0, errNoCPUQuota
+			}
+			return parseCPUQuota(fields[0], fields[1])
+		}
+		if !os.IsNotExist(err) {
+			return 0, err
+		}
+	}
+	// cgroup v1
+	path, err := cgroupPath("cpu", "/proc/self/cgroup")
+	if err != nil {
+		return 0, err
+	}
+	root := filepath.Join(cgroupRoot, "cpu")
+	quota, err := readCgroupFile(root, path, "cpu.cfs_quota_us")
+	if err != nil {
+		return 0, err
+	}
+	period, err := readCgroupFile(root, path, "cpu.cfs_period_us")
+	if err != nil {
+		return 0, err
+	}
+	if strings.TrimSpace(string(quota)) == "-1" {
+		return 0, errNoCPUQuota
+	}
+	return parseCPUQuota(strings.TrimSpace(string(quota)), strings.TrimSpace(string(period)))
+}
+
+func parseCPUQuota(quota, period string) (float64, error) {
+	q, err := strconv.ParseFloat(quota, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cpu quota=%s", quota)
+	}
+	p, err := strconv.ParseFloat(period, 64)
+	if err != nil || p <= 0 {
+		return 0, fmt.Errorf("invalid cpu period=%s", period)
+	}
+	if q <= 0 {
+		return 0, errNoCPUQuota
+	}
+	return q / p, nil
+}
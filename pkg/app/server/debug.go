@@ -0,0 +1,237 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"context"
+	"expvar"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"strings"
+
+	"hertz-study/pkg/app"
+	"hertz-study/pkg/app/middlewares/server/basic_auth"
+	"hertz-study/pkg/app/middlewares/server/routestats"
+	"hertz-study/pkg/common/adaptor"
+	"hertz-study/pkg/common/utils"
+	"hertz-study/pkg/protocol/consts"
+	"hertz-study/pkg/route"
+)
+
+// DefaultDebugPrefix is the default path prefix of the debug endpoints.
+const DefaultDebugPrefix = "/debug"
+
+type debugOptions struct {
+	accounts    basic_auth.Accounts
+	allowRemote bool
+	policies    []routePolicy
+	routeStats  *routestats.Collector
+}
+
+// RoutePolicy evaluates a policy decision of the request in ctx, e.g. whether auth is
+// required or which rate-limit bucket it falls in, for the route dry-run endpoint. The
+// params, the full path and the handlers of the matched route are set to ctx, but none of
+// the handlers is executed.
+type RoutePolicy func(c context.Context, ctx *app.RequestContext) interface{}
+
+type routePolicy struct {
+	name string
+	f    RoutePolicy
+}
+
+// DebugOption is the option of EnableDebug.
+type DebugOption func(o *debugOptions)
+
+// WithDebugBasicAuth protects the debug endpoints with basic auth.
+func WithDebugBasicAuth(accounts basic_auth.Accounts) DebugOption {
+	return func(o *debugOptions) {
+		o.accounts = accounts
+	}
+}
+
+// WithDebugAllowRemote allows the debug endpoints to be accessed from non-loopback addresses.
+// By default only the requests from loopback addresses are served, consider using it
+// together with WithDebugBasicAuth.
+func WithDebugAllowRemote() DebugOption {
+	return func(o *debugOptions) {
+		o.allowRemote = true
+	}
+}
+
+// WithDebugRoutePolicy adds a policy reported by the route dry-run endpoint with name.
+func WithDebugRoutePolicy(name string, f RoutePolicy) DebugOption {
+	return func(o *debugOptions) {
+		o.policies = append(o.policies, routePolicy{name: name, f: f})
+	}
+}
+
+// WithDebugRouteStats exposes the route usage report of c, see routestats.Register.
+func WithDebugRouteStats(c *routestats.Collector) DebugOption {
+	return func(o *debugOptions) {
+		o.routeStats = c
+	}
+}
+
+// EnableDebug registers the runtime diagnostic endpoints under prefix:
+//
+//	<prefix>/pprof/        pprof index, profile, heap, goroutine, trace, etc.
+//	<prefix>/vars          expvar
+//	<prefix>/routes        registered routes of the engine
+//	<prefix>/route         dry-run route evaluation, see routeDryRun
+//	<prefix>/routes/stats  hot and never hit routes, if WithDebugRouteStats is set
+//
+// DefaultDebugPrefix is used if prefix is empty.
+func (h *Hertz) EnableDebug(prefix string, opts ...DebugOption) {
+	o := &debugOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if prefix == "" {
+		prefix = DefaultDebugPrefix
+	}
+
+	var handlers []app.HandlerFunc
+	if !o.allowRemote {
+		handlers = append(handlers, loopbackOnly)
+	}
+	if len(o.accounts) > 0 {
+		handlers = append(handlers, basic_auth.BasicAuth(o.accounts))
+	}
+	g := h.Group(strings.TrimSuffix(prefix, "/"), handlers...)
+
+	g.GET("/pprof/", adaptor.HertzHandler(http.HandlerFunc(pprof.Index)))
+	g.GET("/pprof/cmdline", adaptor.HertzHandler(http.HandlerFunc(pprof.Cmdline)))
+	g.GET("/pprof/profile", adaptor.HertzHandler(http.HandlerFunc(pprof.Profile)))
+	g.GET("/pprof/symbol", adaptor.HertzHandler(http.HandlerFunc(pprof.Symbol)))
+	g.POST("/pprof/symbol", adaptor.HertzHandler(http.HandlerFunc(pprof.Symbol)))
+	g.GET("/pprof/trace", adaptor.HertzHandler(http.HandlerFunc(pprof.Trace)))
+	// pprof.Index resolves the profile name from "/debug/pprof/<name>" only, so register them one by one
+	for _, name := range []string{"allocs", "block", "goroutine", "heap", "mutex", "threadcreate"} {
+		g.GET("/pprof/"+name, adaptor.HertzHandler(pprof.Handler(name)))
+	}
+
+	g.GET("/vars", adaptor.HertzHandler(expvar.Handler()))
+
+	g.GET("/routes", func(c context.Context, ctx *app.RequestContext) {
+		type routeInfo struct {
+			Method  string          `json:"method"`
+			Path    string          `json:"path"`
+			Handler string          `json:"handler"`
+			Doc     *route.RouteDoc `json:"doc,omitempty"`
+		}
+		routes := h.Routes()
+		res := make([]routeInfo, 0, len(routes))
+		for _, r := range routes {
+			res = append(res, routeInfo{Method: r.Method, Path: r.Path, Handler: r.Handler, Doc: r.Doc})
+		}
+		ctx.JSON(consts.StatusOK, res)
+	})
+
+	g.GET("/route", h.routeDryRun(o.policies))
+
+	if o.routeStats != nil {
+		g.GET("/routes/stats", o.routeStats.Handler())
+	}
+}
+
+// routeDryRun reports how the request described by the query arguments would be routed,
+// without executing any handler:
+//
+//	method   the method of the request, default is GET
+//	path     the request URI, required
+//	host     the host of the request, default is the host of the debug request
+//	header   a "Key: Value" header of the request, repeatable
+//
+// The report contains the status code the engine would respond with, the matched route and
+// params, the handler chain, the redirection location and the decisions of the policies
+// added by WithDebugRoutePolicy.
+func (h *Hertz) routeDryRun(policies []routePolicy) app.HandlerFunc {
+	type report struct {
+		Method   string                 `json:"method"`
+		Path     string                 `json:"path"`
+		Status   int                    `json:"status"`
+		Route    string                 `json:"route,omitempty"`
+		Params   map[string]string      `json:"params,omitempty"`
+		Handlers []string               `json:"handlers"`
+		Redirect string                 `json:"redirect,omitempty"`
+		Policies map[string]interface{} `json:"policies,omitempty"`
+	}
+	return func(c context.Context, ctx *app.RequestContext) {
+		path := ctx.Query("path")
+		if path == "" {
+			ctx.String(consts.StatusBadRequest, "path is required")
+			return
+		}
+
+		dry := h.NewContext()
+		dry.Request.Header.SetMethod(ctx.DefaultQuery("method", consts.MethodGet))
+		dry.Request.SetRequestURI(path)
+		dry.Request.SetHost(ctx.DefaultQuery("host", string(ctx.Host())))
+		for _, v := range ctx.QueryArgs().PeekAll("header") {
+			key, value, ok := strings.Cut(string(v), ":")
+			if !ok {
+				ctx.String(consts.StatusBadRequest, "invalid header: %s", v)
+				return
+			}
+			dry.Request.Header.Add(strings.TrimSpace(key), strings.TrimSpace(value))
+		}
+
+		r := report{
+			Method:   string(dry.Request.Method()),
+			Path:     path,
+			Status:   h.Match(dry),
+			Route:    dry.FullPath(),
+			Handlers: []string{},
+		}
+		for _, handler := range dry.Handlers() {
+			r.Handlers = append(r.Handlers, utils.NameOfFunction(handler))
+		}
+		if r.Status == consts.StatusOK {
+			for _, p := range dry.Params {
+				if r.Params == nil {
+					r.Params = make(map[string]string, len(dry.Params))
+				}
+				r.Params[p.Key] = p.Value
+			}
+			for _, p := range policies {
+				if r.Policies == nil {
+					r.Policies = make(map[string]interface{}, len(policies))
+				}
+				r.Policies[p.name] = p.f(c, dry)
+			}
+		} else {
+			r.Redirect = string(dry.Response.Header.Peek(consts.HeaderLocation))
+		}
+		ctx.JSON(consts.StatusOK, r)
+	}
+}
+
+func loopbackOnly(c context.Context, ctx *app.RequestContext) {
+	var ip net.IP
+	switch addr := ctx.RemoteAddr().(type) {
+	case *net.TCPAddr:
+		ip = addr.IP
+	default:
+		if host, _, err := net.SplitHostPort(addr.String()); err == nil {
+			ip = net.ParseIP(host)
+		}
+	}
+	if ip == nil || !ip.IsLoopback() {
+		ctx.AbortWithStatus(consts.StatusForbidden)
+	}
+}
@@ -0,0 +1,242 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package dynconfig provides the configuration loaded from the providers, e.g. files, the
+// environment variables and remote servers, which is reloaded when they change so that the
+// selected options can be changed without restarting the server.
+//
+//	c := dynconfig.New(dynconfig.NewFileProvider("conf/app.conf"), dynconfig.NewEnvProvider("APP_"))
+//	// the middlewares subscribe to their own keys
+//	c.OnChange(func(ch *dynconfig.Change) {
+//		v, _ := ch.Get("quota.limit")
+//		limiter.SetLimit(v)
+//	}, "quota")
+//	h := server.Default()
+//	// the built-in keys, e.g. KeyLogLevel, are applied to h
+//	if err := h.UseDynamicConfig(c); err != nil {
+//		panic(err)
+//	}
+//
+// The configuration is a flat map of keys like "log.level" to string values, the values of
+// the later providers override the ones of the former providers.
+package dynconfig
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Change is a change of the configuration passed to the hooks registered by OnChange.
+type Change struct {
+	// Old and New are the whole configuration before and after the change, they must not be modified.
+	Old, New map[string]string
+	// Keys are the sorted keys added, removed or modified by the change.
+	Keys []string
+}
+
+// Changed reports whether key or any key under it, i.e. prefixed with key + ".", is changed.
+func (ch *Change) Changed(key string) bool {
+	for _, k := range ch.Keys {
+		if matchKey(k, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// Get returns the new value of key.
+func (ch *Change) Get(key string) (string, bool) {
+	v, ok := ch.New[key]
+	return v, ok
+}
+
+type hook struct {
+	keys []string
+	f    func(ch *Change)
+}
+
+// Config is the configuration merged from the providers, it is safe for concurrent use.
+type Config struct {
+	providers []Provider
+
+	mu     sync.RWMutex
+	values map[string]string
+
+	// serializes Load
+	loadMu sync.Mutex
+
+	hookMu     sync.RWMutex
+	validators map[string][]func(value string) error
+	hooks      []hook
+}
+
+// New creates a Config loading from providers in order, call Load or Hertz.UseDynamicConfig to
+// load it.
+func New(providers ...Provider) *Config {
+	return &Config{
+		providers:  providers,
+		values:     map[string]string{},
+		validators: map[string][]func(value string) error{},
+	}
+}
+
+// Get returns the current value of key.
+func (c *Config) Get(key string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	v, ok := c.values[key]
+	return v, ok
+}
+
+// Values returns a copy of the current configuration.
+func (c *Config) Values() map[string]string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	values := make(map[string]string, len(c.values))
+	for k, v := range c.values {
+		values[k] = v
+	}
+	return values
+}
+
+// Validate adds a check of the new value of key, a reload changing key to a value failing any
+// check is rejected as a whole, and the current configuration is kept.
+func (c *Config) Validate(key string, f func(value string) error) {
+	c.hookMu.Lock()
+	c.validators[key] = append(c.validators[key], f)
+	c.hookMu.Unlock()
+}
+
+// OnChange adds a hook called after the configuration is changed, if any of keys or the keys
+// under them is changed, or on every change if no key is given. The hooks are called in the
+// order they are added and should not block, use Validate to reject the invalid values.
+func (c *Config) OnChange(f func(ch *Change), keys ...string) {
+	c.hookMu.Lock()
+	c.hooks = append(c.hooks, hook{keys: keys, f: f})
+	c.hookMu.Unlock()
+}
+
+// Load loads the configuration from the providers and applies it.
+func (c *Config) Load(ctx context.Context) error {
+	c.loadMu.Lock()
+	defer c.loadMu.Unlock()
+	commit, err := c.PrepareReload(ctx)
+	if err != nil {
+		return err
+	}
+	if commit != nil {
+		commit()
+	}
+	return nil
+}
+
+// PrepareReload loads and validates the configuration from the providers, and returns the
+// function to apply it and call the hooks, which is a step of the reload pipeline of the server:
+//
+//	h.AddReloader("dynconfig", c.PrepareReload)
+//
+// The returned function is nil if nothing changes.
+func (c *Config) PrepareReload(ctx context.Context) (func(), error) {
+	values := map[string]string{}
+	for _, p := range c.providers {
+		v, err := p.Load(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("load config from %s: %w", p.Name(), err)
+		}
+		for k, val := range v {
+			values[k] = val
+		}
+	}
+
+	c.mu.RLock()
+	ch := &Change{Old: c.values, New: values, Keys: diff(c.values, values)}
+	c.mu.RUnlock()
+	if len(ch.Keys) == 0 {
+		return nil, nil
+	}
+
+	c.hookMu.RLock()
+	defer c.hookMu.RUnlock()
+	for _, k := range ch.Keys {
+		v, ok := values[k]
+		if !ok {
+			continue
+		}
+		for _, f := range c.validators[k] {
+			if err := f(v); err != nil {
+				return nil, fmt.Errorf("invalid config %s=%q: %w", k, v, err)
+			}
+		}
+	}
+	return func() {
+		c.mu.Lock()
+		c.values = values
+		c.mu.Unlock()
+		c.notify(ch)
+	}, nil
+}
+
+// Watch watches the providers until ctx is done, and calls reload when any of them changes.
+// reload is usually Hertz.Reload, which runs PrepareReload with the other reload steps.
+func (c *Config) Watch(ctx context.Context, reload func(ctx context.Context)) {
+	for _, p := range c.providers {
+		go p.Watch(ctx, func() {
+			reload(ctx)
+		})
+	}
+}
+
+func (c *Config) notify(ch *Change) {
+	c.hookMu.RLock()
+	hooks := c.hooks
+	c.hookMu.RUnlock()
+	for _, h := range hooks {
+		if len(h.keys) == 0 {
+			h.f(ch)
+			continue
+		}
+		for _, key := range h.keys {
+			if ch.Changed(key) {
+				h.f(ch)
+				break
+			}
+		}
+	}
+}
+
+// matchKey reports whether k is key or under key.
+func matchKey(k, key string) bool {
+	return k == key || (strings.HasPrefix(k, key) && len(k) > len(key) && k[len(key)] == '.')
+}
+
+func diff(old, new map[string]string) []string {
+	var keys []string
+	for k, v := range new {
+		if ov, ok := old[k]; !ok || ov != v {
+			keys = append(keys, k)
+		}
+	}
+	for k := range old {
+		if _, ok := new[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,133 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dynconfig
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"hertz-study/pkg/app"
+	"hertz-study/pkg/common/hlog"
+	"hertz-study/pkg/route"
+)
+
+// The keys of the options applied by BindEngine.
+const (
+	// KeyLogLevel is the level of the default logger and the system logger, e.g. "info".
+	KeyLogLevel = "log.level"
+	// KeyReadTimeout is the read timeout of the connections, e.g. "3s", see server.WithReadTimeout.
+	KeyReadTimeout = "server.read_timeout"
+	// KeyIdleTimeout is the idle timeout of the keep-alive connections, see server.WithIdleTimeout.
+	KeyIdleTimeout = "server.idle_timeout"
+	// KeyConnectionThrottle is the rate of new connections per second, see server.WithConnectionThrottle.
+	KeyConnectionThrottle = "server.connection_throttle"
+	// KeyConnectionThrottleBurst is the burst of new connections, see server.WithConnectionThrottle.
+	KeyConnectionThrottleBurst = "server.connection_throttle_burst"
+	// KeyTrustedProxies is the comma separated CIDRs of the trusted proxies, see server.WithTrustedProxies.
+	KeyTrustedProxies = "server.trusted_proxies"
+)
+
+// BindEngine validates the built-in keys and applies them to engine when they change, it's
+// called by Hertz.UseDynamicConfig. A removed key restores the value of the option the engine is
+// created with, except KeyLogLevel which keeps the last level.
+func BindEngine(c *Config, engine *route.Engine) {
+	opt := engine.GetOptions()
+
+	c.Validate(KeyLogLevel, func(v string) error {
+		_, err := hlog.ParseLevel(v)
+		return err
+	})
+	c.Validate(KeyReadTimeout, validateDuration)
+	c.Validate(KeyIdleTimeout, validateDuration)
+	c.Validate(KeyConnectionThrottle, validateCount)
+	c.Validate(KeyConnectionThrottleBurst, validateCount)
+	c.Validate(KeyTrustedProxies, func(v string) error {
+		_, err := app.ParseTrustedCIDRs(splitList(v))
+		return err
+	})
+
+	c.OnChange(func(ch *Change) {
+		if v, ok := ch.Get(KeyLogLevel); ok {
+			lv, _ := hlog.ParseLevel(v)
+			hlog.SetLevel(lv)
+		}
+	}, KeyLogLevel)
+	c.OnChange(func(ch *Change) {
+		engine.SetTimeouts(durationOf(ch, KeyReadTimeout, opt.ReadTimeout), durationOf(ch, KeyIdleTimeout, opt.IdleTimeout))
+	}, KeyReadTimeout, KeyIdleTimeout)
+	c.OnChange(func(ch *Change) {
+		perSecond := countOf(ch, KeyConnectionThrottle, opt.ConnectionThrottle)
+		burst := countOf(ch, KeyConnectionThrottleBurst, opt.ConnectionThrottleBurst)
+		if err := engine.SetConnectionThrottle(perSecond, burst); err != nil {
+			hlog.SystemLogger().Errorf("[DynConfig] apply %s failed: err=%v", KeyConnectionThrottle, err)
+		}
+	}, KeyConnectionThrottle, KeyConnectionThrottleBurst)
+	c.OnChange(func(ch *Change) {
+		proxies := opt.TrustedProxies
+		if v, ok := ch.Get(KeyTrustedProxies); ok {
+			proxies = splitList(v)
+		}
+		if err := engine.SetTrustedProxies(proxies); err != nil {
+			hlog.SystemLogger().Errorf("[DynConfig] apply %s failed: err=%v", KeyTrustedProxies, err)
+		}
+	}, KeyTrustedProxies)
+}
+
+func validateDuration(v string) error {
+	d, err := time.ParseDuration(v)
+	if err == nil && d < 0 {
+		return errors.New("negative duration")
+	}
+	return err
+}
+
+func validateCount(v string) error {
+	n, err := strconv.Atoi(v)
+	if err == nil && n < 0 {
+		return errors.New("negative count")
+	}
+	return err
+}
+
+func durationOf(ch *Change, key string, def time.Duration) time.Duration {
+	if v, ok := ch.Get(key); ok {
+		d, _ := time.ParseDuration(v)
+		return d
+	}
+	return def
+}
+
+func countOf(ch *Change, key string, def int) int {
+	if v, ok := ch.Get(key); ok {
+		n, _ := strconv.Atoi(v)
+		return n
+	}
+	return def
+}
+
+// splitList splits the comma separated list, the result is empty but not nil for an empty list.
+func splitList(v string) []string {
+	list := []string{}
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			list = append(list, s)
+		}
+	}
+	return list
+}
@@ -0,0 +1,123 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dynconfig
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"hertz-study/pkg/common/hlog"
+)
+
+type fileProvider struct {
+	path string
+	opts *options
+}
+
+// NewFileProvider creates a Provider loading the file at path, which is a JSON object if its
+// extension is ".json", whose nested objects are flattened with the keys joined by ".", or the
+// lines of "key = value" otherwise. The directory of the file is watched for the changes, since
+// the file is usually replaced by renaming, e.g. the kubernetes config map volumes swap a symlink.
+func NewFileProvider(path string, opts ...Option) Provider {
+	return &fileProvider{path: path, opts: newOptions(opts...)}
+}
+
+func (p *fileProvider) Name() string {
+	return "file:" + p.path
+}
+
+func (p *fileProvider) Load(_ context.Context) (map[string]string, error) {
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(filepath.Ext(p.path), ".json") {
+		return parseJSON(data)
+	}
+	return parseProperties(data)
+}
+
+func (p *fileProvider) Watch(ctx context.Context, changed func()) {
+	if p.opts.pollInterval > 0 {
+		p.poll(ctx, changed)
+		return
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		hlog.SystemLogger().Errorf("[DynConfig] watch file=%s failed: err=%v", p.path, err)
+		return
+	}
+	defer watcher.Close()
+	if err = watcher.Add(filepath.Dir(p.path)); err != nil {
+		hlog.SystemLogger().Errorf("[DynConfig] watch file=%s failed: err=%v", p.path, err)
+		return
+	}
+
+	var delay <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+			if delay == nil {
+				delay = time.After(p.opts.reloadDelay)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			hlog.SystemLogger().Errorf("[DynConfig] watch file=%s failed: err=%v", p.path, err)
+		case <-delay:
+			delay = nil
+			changed()
+		}
+	}
+}
+
+func (p *fileProvider) poll(ctx context.Context, changed func()) {
+	ticker := time.NewTicker(p.opts.pollInterval)
+	defer ticker.Stop()
+	last := p.modTime()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if t := p.modTime(); !t.Equal(last) {
+				last = t
+				changed()
+			}
+		}
+	}
+}
+
+func (p *fileProvider) modTime() time.Time {
+	if info, err := os.Stat(p.path); err == nil {
+		return info.ModTime()
+	}
+	return time.Time{}
+}
@@ -0,0 +1,84 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dynconfig
+
+import (
+	"net/http"
+	"time"
+)
+
+const (
+	defaultReloadDelay        = 100 * time.Millisecond
+	defaultRemotePollInterval = 10 * time.Second
+)
+
+type (
+	options struct {
+		pollInterval time.Duration
+		reloadDelay  time.Duration
+		client       *http.Client
+		header       http.Header
+	}
+
+	// Option is the option of the providers.
+	Option func(o *options)
+)
+
+func newOptions(opts ...Option) *options {
+	cfg := &options{
+		reloadDelay: defaultReloadDelay,
+		client:      http.DefaultClient,
+		header:      http.Header{},
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return cfg
+}
+
+// WithPollInterval sets how often to check the changes. The file provider checks the
+// modification time of the file instead of watching the file events if it's set, which is
+// useful on the file systems without inotify support. The remote provider polls every 10s by default.
+func WithPollInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.pollInterval = interval
+	}
+}
+
+// WithReloadDelay sets how long the file provider waits after a file event before reporting
+// the change, so that the events of a single write are reported once, default is 100ms.
+func WithReloadDelay(delay time.Duration) Option {
+	return func(o *options) {
+		o.reloadDelay = delay
+	}
+}
+
+// WithHTTPClient sets the client of the remote provider, default is http.DefaultClient.
+func WithHTTPClient(c *http.Client) Option {
+	return func(o *options) {
+		o.client = c
+	}
+}
+
+// WithHeader adds a header to the requests of the remote provider, e.g. the authorization.
+func WithHeader(key, value string) Option {
+	return func(o *options) {
+		o.header.Add(key, value)
+	}
+}
@@ -0,0 +1,150 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dynconfig
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Provider is a source of the configuration.
+type Provider interface {
+	// Name returns the name of the provider used in the errors, e.g. "file:conf/app.conf".
+	Name() string
+	// Load returns the current configuration of the provider.
+	Load(ctx context.Context) (map[string]string, error)
+	// Watch blocks until ctx is done, and calls changed when the configuration may change.
+	Watch(ctx context.Context, changed func())
+}
+
+type envProvider struct {
+	prefix string
+}
+
+// NewEnvProvider creates a Provider loading the environment variables with prefix, the key is
+// the rest of the name in lower case with "__" replaced by ".", e.g. APP_SERVER__READ_TIMEOUT is
+// "server.read_timeout" with the prefix "APP_". The environment variables are never watched,
+// since they are not changed from outside the process.
+func NewEnvProvider(prefix string) Provider {
+	return &envProvider{prefix: prefix}
+}
+
+func (p *envProvider) Name() string {
+	return "env:" + p.prefix
+}
+
+func (p *envProvider) Load(_ context.Context) (map[string]string, error) {
+	values := map[string]string{}
+	for _, kv := range os.Environ() {
+		name, value, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(name, p.prefix) || len(name) == len(p.prefix) {
+			continue
+		}
+		key := strings.ToLower(strings.ReplaceAll(name[len(p.prefix):], "__", "."))
+		values[key] = value
+	}
+	return values, nil
+}
+
+func (p *envProvider) Watch(ctx context.Context, _ func()) {
+	<-ctx.Done()
+}
+
+// parseJSON parses a JSON object, the nested objects are flattened with the keys joined by ".",
+// and the arrays are joined by ",".
+func parseJSON(data []byte) (map[string]string, error) {
+	var obj map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&obj); err != nil {
+		return nil, err
+	}
+	values := map[string]string{}
+	if err := flatten(values, "", obj); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func flatten(values map[string]string, prefix string, obj map[string]interface{}) error {
+	for k, v := range obj {
+		key := prefix + k
+		switch v := v.(type) {
+		case map[string]interface{}:
+			if err := flatten(values, key+".", v); err != nil {
+				return err
+			}
+		case []interface{}:
+			elems := make([]string, 0, len(v))
+			for _, e := range v {
+				s, err := scalar(key, e)
+				if err != nil {
+					return err
+				}
+				elems = append(elems, s)
+			}
+			values[key] = strings.Join(elems, ",")
+		default:
+			s, err := scalar(key, v)
+			if err != nil {
+				return err
+			}
+			values[key] = s
+		}
+	}
+	return nil
+}
+
+func scalar(key string, v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case nil:
+		return "", nil
+	default:
+		return "", fmt.Errorf("unsupported value of %s", key)
+	}
+}
+
+// parseProperties parses the lines of "key = value", the empty lines and the lines starting
+// with "#" are skipped.
+func parseProperties(data []byte) (map[string]string, error) {
+	values := map[string]string{}
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		k, v, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: missing '='", n)
+		}
+		values[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return values, sc.Err()
+}
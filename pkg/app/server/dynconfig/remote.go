@@ -0,0 +1,126 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dynconfig
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"hertz-study/pkg/common/hlog"
+)
+
+// maxRemoteSize limits the size of the remote configuration.
+const maxRemoteSize = 1 << 20
+
+type remoteProvider struct {
+	url  string
+	opts *options
+
+	mu   sync.Mutex
+	etag string
+	last []byte
+}
+
+// NewRemoteProvider creates a Provider loading the JSON object served at url by GET, whose nested
+// objects are flattened with the keys joined by ".". It's polled every 10s by default, the ETag
+// of the response is sent back by If-None-Match to skip the unchanged ones.
+func NewRemoteProvider(url string, opts ...Option) Provider {
+	o := newOptions(opts...)
+	if o.pollInterval <= 0 {
+		o.pollInterval = defaultRemotePollInterval
+	}
+	return &remoteProvider{url: url, opts: o}
+}
+
+func (p *remoteProvider) Name() string {
+	return "remote:" + p.url
+}
+
+func (p *remoteProvider) Load(ctx context.Context) (map[string]string, error) {
+	data, _, err := p.fetch(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	return parseJSON(data)
+}
+
+func (p *remoteProvider) Watch(ctx context.Context, changed func()) {
+	ticker := time.NewTicker(p.opts.pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, modified, err := p.fetch(ctx, true)
+			if err != nil {
+				if ctx.Err() == nil {
+					hlog.SystemLogger().Warnf("[DynConfig] poll url=%s failed: err=%v", p.url, err)
+				}
+				continue
+			}
+			if modified {
+				changed()
+			}
+		}
+	}
+}
+
+// fetch gets the configuration, conditionally by the last ETag if conditional is true, and
+// reports whether it differs from the last one.
+func (p *remoteProvider) fetch(ctx context.Context, conditional bool) ([]byte, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.url, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	for k, v := range p.opts.header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Accept", "application/json")
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if conditional && p.etag != "" {
+		req.Header.Set("If-None-Match", p.etag)
+	}
+	resp, err := p.opts.client.Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified {
+		return p.last, false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRemoteSize+1))
+	if err != nil {
+		return nil, false, err
+	}
+	if len(data) > maxRemoteSize {
+		return nil, false, fmt.Errorf("config is larger than %d bytes", maxRemoteSize)
+	}
+	modified := !bytes.Equal(data, p.last)
+	p.etag, p.last = resp.Header.Get("ETag"), data
+	return data, modified, nil
+}
@@ -0,0 +1,97 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"sync"
+
+	"hertz-study/pkg/common/config"
+	"hertz-study/pkg/common/hlog"
+)
+
+// initGCHooks applies the GC related options when h starts running and restores the previous
+// settings after it shuts down, so that creating a server doesn't change the process globally.
+func (h *Hertz) initGCHooks(opt *config.Options) {
+	if opt.GCPercent == nil && opt.MemoryLimit <= 0 && opt.MemoryLimitRatio <= 0 && opt.MemoryBallast <= 0 {
+		return
+	}
+	var (
+		mu      sync.Mutex
+		restore func()
+	)
+	h.OnRun = append(h.OnRun, func(ctx context.Context) error {
+		mu.Lock()
+		restore = tuneGC(opt)
+		mu.Unlock()
+		return nil
+	})
+	h.OnShutdownHook(func(ctx context.Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if restore != nil {
+			restore()
+			restore = nil
+		}
+		return nil
+	}, 0)
+}
+
+// tuneGC applies the GC related options, the GOGC and GOMEMLIMIT environment variables
+// take precedence so that the deployments are able to override the values in code.
+// It returns the function restoring the previous settings, which keeps the memory ballast
+// alive until it is called.
+func tuneGC(opt *config.Options) (restore func()) {
+	prevPercent, prevLimit := -2, int64(-1)
+	if opt.GCPercent != nil && os.Getenv("GOGC") == "" {
+		prevPercent = debug.SetGCPercent(*opt.GCPercent)
+		hlog.SystemLogger().Infof("Set GC percent=%d", *opt.GCPercent)
+	}
+
+	if os.Getenv("GOMEMLIMIT") == "" {
+		limit := opt.MemoryLimit
+		if opt.MemoryLimitRatio > 0 {
+			if cl, err := containerMemoryLimit(); err == nil {
+				limit = int64(float64(cl) * opt.MemoryLimitRatio)
+			} else {
+				hlog.SystemLogger().Warnf("Detect container memory limit failed: err=%v", err)
+			}
+		}
+		if limit > 0 {
+			prevLimit = debug.SetMemoryLimit(limit)
+			hlog.SystemLogger().Infof("Set memory limit=%dMiB", limit>>20)
+		}
+	}
+
+	var ballast []byte
+	if opt.MemoryBallast > 0 {
+		ballast = make([]byte, opt.MemoryBallast)
+	}
+	return func() {
+		// a negative percent is a valid setting, -2 marks it unchanged
+		if prevPercent != -2 {
+			debug.SetGCPercent(prevPercent)
+		}
+		if prevLimit >= 0 {
+			debug.SetMemoryLimit(prevLimit)
+		}
+		runtime.KeepAlive(ballast)
+	}
+}
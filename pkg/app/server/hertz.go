@@ -4,6 +4,7 @@ import (
 	"context"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
@@ -19,17 +20,89 @@ import (
 type Hertz struct {
 	*route.Engine
 	signalWaiter func(err chan error) error
+
+	// Hook functions get triggered sequentially in the old process before starting the new
+	// process for graceful restart, the restart is cancelled if any of them returns an error.
+	OnPreRestart []route.CtxErrCallback
+	// Hook functions get triggered sequentially in the old process after the new process is
+	// started, before the old process shuts down.
+	OnPostRestart []route.CtxCallback
+
+	// Hook functions get triggered sequentially after every reload with its result, the error
+	// is nil if the reload succeeds or a *ReloadError otherwise, e.g. to publish an event.
+	OnReload []func(ctx context.Context, err error)
+
+	reloadMu  sync.Mutex
+	reloaders []reloader
 }
 
 // 创建一个新引擎
 // New creates a hertz instance without any default config.
+// The issues of the options found by config.Options.Validate are logged, use NewChecked to
+// reject the invalid options.
 func New(opts ...config.Option) *Hertz {
 	// 生成可选项
+	options := newOptions(opts)
+	logOptionIssues(options.Validate())
+	h, err := newHertz(options)
+	if err != nil {
+		panic(err.Error())
+	}
+	return h
+}
+
+// NewChecked creates a hertz instance as New does, but returns a *config.OptionsError if any
+// issue of the options is config.SeverityError, and returns the error instead of panicking if
+// the listener can't be created. The warnings are logged.
+func NewChecked(opts ...config.Option) (*Hertz, error) {
+	options := newOptions(opts)
+	issues := options.Validate()
+	if err := config.IssuesError(issues); err != nil {
+		return nil, err
+	}
+	logOptionIssues(issues)
+	return newHertz(options)
+}
+
+func newOptions(opts []config.Option) *config.Options {
 	options := config.NewOptions(opts)
+	if options.AutoConfig {
+		autoConfigure(options)
+	}
+	return options
+}
+
+func logOptionIssues(issues []config.OptionIssue) {
+	for _, i := range issues {
+		if i.Severity == config.SeverityError {
+			hlog.SystemLogger().Errorf("Options %s", i)
+		} else {
+			hlog.SystemLogger().Warnf("Options %s", i)
+		}
+	}
+}
+
+func newHertz(options *config.Options) (*Hertz, error) {
+	if options.SocketActivation && options.Listener == nil {
+		ln, err := activatedListener()
+		if err != nil {
+			return nil, errors.NewPrivate("create socket activation listener fail: " + err.Error())
+		}
+		options.Listener = ln
+	}
+	if options.GracefulRestart && options.Listener == nil && len(restartSignals) > 0 {
+		// the listener is created in advance to be passed to the new process on restart
+		ln, err := restartListener(options)
+		if err != nil {
+			return nil, errors.NewPrivate("create graceful restart listener fail: " + err.Error())
+		}
+		options.Listener = ln
+	}
 	h := &Hertz{
 		Engine: route.NewEngine(options),
 	}
-	return h
+	h.initGCHooks(options)
+	return h, nil
 }
 
 // Default creates a hertz instance with default middlewares.
@@ -51,6 +124,20 @@ func (h *Hertz) Spin() {
 	}()
 	// 关机信号量
 	signalWaiter := waitSignal
+	var restart, reload func() error
+	if h.GetOptions().GracefulRestart && len(restartSignals) > 0 {
+		restart = h.restart
+	}
+	if h.GetOptions().ReloadOnSIGHUP {
+		reload = func() error {
+			return h.Reload(context.Background())
+		}
+	}
+	if restart != nil || reload != nil {
+		signalWaiter = func(errCh chan error) error {
+			return waitSignalOrRestart(errCh, restart, reload)
+		}
+	}
 	if h.signalWaiter != nil {
 		signalWaiter = h.signalWaiter
 	}
@@ -84,31 +171,75 @@ func (h *Hertz) SetCustomSignalWaiter(f func(err chan error) error) {
 // SIGTERM triggers immediately close.
 // SIGHUP|SIGINT triggers graceful shutdown.
 func waitSignal(errCh chan error) error {
+	return waitSignalOrRestart(errCh, nil, nil)
+}
+
+// waitSignalOrRestart is waitSignal which also calls restart on restartSignals,
+// and triggers graceful shutdown if restart succeeds. SIGHUP calls reload instead of
+// triggering graceful shutdown if reload is not nil, which runs in the background so that
+// the signals are still handled during a slow reload, and the SIGHUPs received meanwhile
+// are merged into one more reload.
+func waitSignalOrRestart(errCh chan error, restart, reload func() error) error {
 	signalToNotify := []os.Signal{syscall.SIGINT, syscall.SIGHUP, syscall.SIGTERM}
-	if signal.Ignored(syscall.SIGHUP) {
+	if signal.Ignored(syscall.SIGHUP) && reload == nil {
 		signalToNotify = []os.Signal{syscall.SIGINT, syscall.SIGTERM}
 	}
+	if restart != nil {
+		signalToNotify = append(signalToNotify, restartSignals...)
+	}
 
 	signals := make(chan os.Signal, 1)
 	signal.Notify(signals, signalToNotify...)
+	defer signal.Stop(signals)
+
+	var reloadCh chan struct{}
+	if reload != nil {
+		reloadCh = make(chan struct{}, 1)
+		defer close(reloadCh)
+		go func() {
+			for range reloadCh {
+				// the outcome is reported by reload
+				reload() //nolint:errcheck
+			}
+		}()
+	}
 	// 开启监听
-	select {
-	case sig := <-signals:
-		switch sig {
-		case syscall.SIGTERM:
-			// force exit
-			return errors.NewPublic(sig.String()) // nolint
-		case syscall.SIGHUP, syscall.SIGINT:
-			hlog.SystemLogger().Infof("Received signal: %s\n", sig)
-			// graceful shutdown
-			return nil
+	for {
+		select {
+		case sig := <-signals:
+			switch sig {
+			case syscall.SIGTERM:
+				// force exit
+				return errors.NewPublic(sig.String()) // nolint
+			case syscall.SIGHUP:
+				if reload != nil {
+					select {
+					case reloadCh <- struct{}{}:
+						hlog.SystemLogger().Infof("Received signal: %s, begin reload", sig)
+					default:
+						hlog.SystemLogger().Infof("Received signal: %s, reload is pending", sig)
+					}
+					continue
+				}
+				fallthrough
+			case syscall.SIGINT:
+				hlog.SystemLogger().Infof("Received signal: %s\n", sig)
+				// graceful shutdown
+				return nil
+			default:
+				hlog.SystemLogger().Infof("Received signal: %s, begin graceful restart", sig)
+				if err := restart(); err != nil {
+					hlog.SystemLogger().Errorf("Graceful restart error=%v", err)
+					continue
+				}
+				// graceful shutdown, the new process takes over the listener
+				return nil
+			}
+		case err := <-errCh:
+			// error occurs, exit immediately
+			return err
 		}
-	case err := <-errCh:
-		// error occurs, exit immediately
-		return err
 	}
-
-	return nil
 }
 
 // 初始化运行回调函数
@@ -119,7 +250,7 @@ func (h *Hertz) initOnRunHooks(errChan chan error) {
 		go func() {
 			// delay register 1s
 			time.Sleep(1 * time.Second)
-			if err := opt.Registry.Register(opt.RegistryInfo); err != nil {
+			if err := h.register(opt); err != nil {
 				hlog.SystemLogger().Errorf("Register error=%v", err)
 				// pass err to errChan
 				errChan <- err
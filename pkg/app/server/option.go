@@ -19,10 +19,14 @@ package server
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"net"
+	"os"
 	"strings"
 	"time"
 
+	"hertz-study/pkg/app/client/retry"
+	"hertz-study/pkg/app/server/autotls"
 	"hertz-study/pkg/app/server/binding"
 	"hertz-study/pkg/app/server/registry"
 	"hertz-study/pkg/common/config"
@@ -30,6 +34,7 @@ import (
 	"hertz-study/pkg/common/tracer/stats"
 	"hertz-study/pkg/network"
 	"hertz-study/pkg/network/standard"
+	"hertz-study/pkg/protocol"
 )
 
 // WithKeepAliveTimeout sets keep-alive timeout.
@@ -43,7 +48,8 @@ func WithKeepAliveTimeout(t time.Duration) config.Option {
 
 // WithReadTimeout sets read timeout.
 //
-// Close the connection when read request timeout.
+// The request is answered with 408 and the connection is closed when reading the request
+// exceeds the timeout, so that the clients sending slowly can't hold the connections.
 func WithReadTimeout(t time.Duration) config.Option {
 	return config.Option{F: func(o *config.Options) {
 		o.ReadTimeout = t
@@ -52,13 +58,71 @@ func WithReadTimeout(t time.Duration) config.Option {
 
 // WithWriteTimeout sets write timeout.
 //
-// Connection will be closed when write request timeout.
+// Connection will be closed when writing a response exceeds the timeout, so that the clients
+// not reading can't hold the connections. It bounds writing the whole response, use
+// WithStreamWriteTimeout for the long streams.
 func WithWriteTimeout(t time.Duration) config.Option {
 	return config.Option{F: func(o *config.Options) {
 		o.WriteTimeout = t
 	}}
 }
 
+// WithReadHeaderTimeout sets the time allowed to read the request line and the header of every
+// request, so that the clients sending the header slowly, e.g. slowloris, are answered with 408
+// and disconnected. The read timeout applies to the body afterwards. Zero means only the read
+// timeout applies.
+func WithReadHeaderTimeout(t time.Duration) config.Option {
+	return config.Option{F: func(o *config.Options) {
+		o.ReadHeaderTimeout = t
+	}}
+}
+
+// WithMaxHeaderBytes sets the max size of the request line and the header, the requests exceeding
+// it are answered with 431 and disconnected. Default is no limit.
+func WithMaxHeaderBytes(n int) config.Option {
+	return config.Option{F: func(o *config.Options) {
+		o.MaxHeaderBytes = n
+	}}
+}
+
+// WithMaxHeaderCount sets the max count of the request header fields, the requests exceeding it
+// are answered with 431 and disconnected. Default is no limit.
+func WithMaxHeaderCount(n int) config.Option {
+	return config.Option{F: func(o *config.Options) {
+		o.MaxHeaderCount = n
+	}}
+}
+
+// WithDisablePool sets whether to allocate a new RequestContext for every request instead of
+// recycling them, the contexts, requests and responses then stay valid after the handlers return,
+// e.g. for the goroutines holding them, at the cost of allocations and GC. The body streams are
+// still closed after the response is written.
+func WithDisablePool(disable bool) config.Option {
+	return config.Option{F: func(o *config.Options) {
+		o.DisablePool = disable
+	}}
+}
+
+// WithReuseParamValues sets whether to keep the route param values in a buffer reused across the
+// requests, which saves an allocation per request with params. The values returned by Param are
+// then only valid until the request ends, so they must be copied to be kept. Default is false.
+func WithReuseParamValues(reuse bool) config.Option {
+	return config.Option{F: func(o *config.Options) {
+		o.ReuseParamValues = reuse
+	}}
+}
+
+// WithSenseFile sets whether to send the big files, e.g. the ones served by FS, with sendfile(2)
+// instead of copying them through the user space, which cuts the CPU of static-heavy workloads.
+// The files are copied as usual on the TLS connections, on the platforms other than Linux with
+// netpoll, or if the body is not a plain file, e.g. compressed on the fly. The write timeout
+// bounds every wait for the peer to receive more data.
+func WithSenseFile(enable bool) config.Option {
+	return config.Option{F: func(o *config.Options) {
+		o.SenseFile = enable
+	}}
+}
+
 // WithIdleTimeout sets idle timeout.
 //
 // Close the connection when the successive request timeout (in keepalive mode).
@@ -178,7 +242,9 @@ func WithBasePath(basePath string) config.Option {
 
 // WithMaxRequestBodySize sets the limitation of request body size. Unit: byte
 //
-// Body buffer which larger than this size will be put back into buffer poll.
+// Requests with a larger body are rejected with 413 without buffering it. If WithStreamBody
+// is enabled, larger bodies are passed to the handlers as a stream instead, use bodylimit.New
+// to limit them. Body buffer which larger than this size will be put back into buffer poll.
 func WithMaxRequestBodySize(bs int) config.Option {
 	return config.Option{F: func(o *config.Options) {
 		o.MaxRequestBodySize = bs
@@ -236,6 +302,14 @@ func WithExitWaitTime(timeout time.Duration) config.Option {
 	}}
 }
 
+// WithStartupCheckTimeout sets the timeout of every startup check added by AddStartupCheck,
+// default is 30s. Zero means no timeout.
+func WithStartupCheckTimeout(timeout time.Duration) config.Option {
+	return config.Option{F: func(o *config.Options) {
+		o.StartupCheckTimeout = timeout
+	}}
+}
+
 // WithTLS sets TLS config to start a tls server.
 //
 // NOTE: If a tls server is started, it won't accept non-tls request.
@@ -249,6 +323,36 @@ func WithTLS(cfg *tls.Config) config.Option {
 	}}
 }
 
+// WithAutoTLS starts a tls server whose certificates of domains are obtained from Let's Encrypt
+// and renewed automatically, they are cached in the "autotls-cache" directory under the working
+// directory. The server should listen on port 443 for the TLS-ALPN-01 challenge to succeed.
+//
+// Use WithAutoTLSManager to customize the cache, the ACME server or the challenge type.
+func WithAutoTLS(domains ...string) config.Option {
+	return WithAutoTLSManager(autotls.New(domains))
+}
+
+// WithAutoTLSManager starts a tls server whose certificates are provisioned by m.
+func WithAutoTLSManager(m *autotls.Manager) config.Option {
+	return WithTLS(m.TLSConfig())
+}
+
+// WithClientAuth sets the policy of verifying the client certificates (mTLS) of the tls server,
+// the certificates are verified against clientCAs, or the system roots if it is nil. Use
+// RequestContext.PeerCertificates to get the client certificates in the handlers.
+//
+// NOTE: It must be set after WithTLS since it modifies the tls config.
+func WithClientAuth(mode tls.ClientAuthType, clientCAs *x509.CertPool) config.Option {
+	return config.Option{F: func(o *config.Options) {
+		if o.TLS == nil {
+			panic("WithClientAuth must be set after WithTLS")
+		}
+		o.TLS = o.TLS.Clone()
+		o.TLS.ClientAuth = mode
+		o.TLS.ClientCAs = clientCAs
+	}}
+}
+
 // WithListenConfig sets listener config.
 func WithListenConfig(l *net.ListenConfig) config.Option {
 	return config.Option{F: func(o *config.Options) {
@@ -256,7 +360,197 @@ func WithListenConfig(l *net.ListenConfig) config.Option {
 	}}
 }
 
+// WithListener sets the listener to serve on, Network, Addr and ListenConfig are ignored then.
+//
+// The listener is not unlinked when it is a unix domain socket, since it is not created by hertz.
+func WithListener(ln net.Listener) config.Option {
+	return config.Option{F: func(o *config.Options) {
+		o.Listener = ln
+	}}
+}
+
+// WithUnixSocket serves on the unix domain socket file path, whose permission is set to perm
+// if it is not zero, e.g. 0o660 to allow the local proxy of the same group to connect.
+func WithUnixSocket(path string, perm os.FileMode) config.Option {
+	return config.Option{F: func(o *config.Options) {
+		o.Network = "unix"
+		o.Addr = path
+		o.UnixSocketPerm = perm
+	}}
+}
+
+// WithSocketActivation serves on the socket passed by systemd socket activation (LISTEN_FDS)
+// instead of listening by itself, Network and Addr are only used when the process is not
+// socket activated. The first socket is used if several ones are passed.
+func WithSocketActivation(b bool) config.Option {
+	return config.Option{F: func(o *config.Options) {
+		o.SocketActivation = b
+	}}
+}
+
+// WithGracefulRestart enables zero-downtime restart.
+//
+// On SIGUSR2 the server starts a new process of the same binary and arguments, which inherits
+// the listener, then the old process shuts down gracefully after the in-flight requests finish.
+// Use Hertz.OnPreRestart and Hertz.OnPostRestart to hook the restart. Not supported on windows.
+func WithGracefulRestart(b bool) config.Option {
+	return config.Option{F: func(o *config.Options) {
+		o.GracefulRestart = b
+	}}
+}
+
+// WithReloadOnSIGHUP makes SIGHUP trigger Hertz.Reload instead of the graceful shutdown,
+// which reloads the states registered by Hertz.AddReloader without restarting the server,
+// e.g. the config files and the hot-reloadable options by Hertz.UseDynamicConfig, and the
+// TLS certificates by certmanager.Manager.PrepareReload.
+func WithReloadOnSIGHUP(b bool) config.Option {
+	return config.Option{F: func(o *config.Options) {
+		o.ReloadOnSIGHUP = b
+	}}
+}
+
+// WithMemoryBallast allocates a ballast of size bytes which is never touched, so it costs no
+// physical memory but raises the heap size triggering GC, reducing the GC frequency of
+// the servers with a small live heap and a high allocation rate.
+func WithMemoryBallast(size int64) config.Option {
+	return config.Option{F: func(o *config.Options) {
+		o.MemoryBallast = size
+	}}
+}
+
+// WithGCPercent sets the GC percent as GOGC does, a negative percent disables GC which should
+// be used with a memory limit. It is ignored if the GOGC environment variable is set.
+// Like the other GC options, it is applied when the server runs and restored after shutdown.
+func WithGCPercent(percent int) config.Option {
+	return config.Option{F: func(o *config.Options) {
+		o.GCPercent = &percent
+	}}
+}
+
+// WithMemoryLimit sets the soft memory limit in bytes as GOMEMLIMIT does.
+// It is ignored if the GOMEMLIMIT environment variable is set.
+func WithMemoryLimit(limit int64) config.Option {
+	return config.Option{F: func(o *config.Options) {
+		o.MemoryLimit = limit
+	}}
+}
+
+// WithAutoMemoryLimit sets the soft memory limit to ratio (e.g. 0.9) of the memory limit of
+// the container detected from cgroup v1 or v2, leaving the rest for the non-heap memory.
+// It takes precedence over WithMemoryLimit when the container limit is detected, and is
+// ignored if the GOMEMLIMIT environment variable is set.
+func WithAutoMemoryLimit(ratio float64) config.Option {
+	return config.Option{F: func(o *config.Options) {
+		o.MemoryLimitRatio = ratio
+	}}
+}
+
+// WithAutoConfig sizes the server by the CPU quota and the memory limit of the container
+// detected from cgroup at startup, so that the defaults behave sensibly in kubernetes:
+//
+//   - GOMAXPROCS is set to the CPU quota rounded up.
+//   - The soft memory limit is set to 90% of the container memory, as WithAutoMemoryLimit(0.9) does.
+//   - MaxConcurrentConnections is set to allow the connections to use half of the container memory.
+//   - MaxKeepBodySize is bounded to 1/1024 of the container memory to limit the pooled buffers.
+//
+// Only the options left as default are changed, so the explicit options and the GOMAXPROCS
+// and GOMEMLIMIT environment variables override the detected values.
+func WithAutoConfig(enable bool) config.Option {
+	return config.Option{F: func(o *config.Options) {
+		o.AutoConfig = enable
+	}}
+}
+
+// WithStreamRenderBufferSize sets the size of the buffer of ctx.RenderStream, the content is sent in
+// chunks of the size and the errors happening before the first chunk is sent can still be responded
+// as an error page. Unit: byte, default is 64KB.
+func WithStreamRenderBufferSize(size int) config.Option {
+	return config.Option{F: func(o *config.Options) {
+		o.StreamRenderBufferSize = size
+	}}
+}
+
+// WithTrustedProxies sets the proxies whose forwarding headers are trusted by ctx.ClientIP,
+// each item is an IP or a CIDR, e.g. "10.0.0.0/8". For the multi-hop proxies, X-Forwarded-For
+// is walked from right to left and the first IP not in the ranges is the client IP.
+// Empty list means the forwarding headers are never trusted, default is trusting all.
+// The forwarded scheme and host used by ctx.ExternalScheme and ctx.ExternalHost are only
+// honored from the listed proxies, never by default.
+func WithTrustedProxies(cidrs []string) config.Option {
+	return config.Option{F: func(o *config.Options) {
+		o.TrustedProxies = append([]string{}, cidrs...)
+	}}
+}
+
+// WithRemoteIPHeaders sets the forwarding headers ctx.ClientIP looks up in order when the peer is
+// a trusted proxy, default is "X-Forwarded-For", "X-Real-IP".
+func WithRemoteIPHeaders(headers ...string) config.Option {
+	return config.Option{F: func(o *config.Options) {
+		o.RemoteIPHeaders = headers
+	}}
+}
+
+// WithProxyProtocol sets whether the accepted connections start with the HAProxy PROXY protocol
+// v1 or v2 header, then ctx.RemoteAddr and ctx.ClientIP report the original client address.
+// The connections without a valid header are closed, so enable it only if the server can only be
+// reached through the TCP load balancers. In go net, the OnAccept and OnConnect callbacks are
+// then called in the goroutine of the connection, for its addresses wait for the header.
+func WithProxyProtocol(enable bool) config.Option {
+	return config.Option{F: func(o *config.Options) {
+		o.ProxyProtocol = enable
+	}}
+}
+
+// WithStreamWriteTimeout sets the deadline of writing every flushed part of the responses, e.g.
+// a chunk of the SSE or download streams, so that a consumer too slow to receive a chunk in time
+// is disconnected instead of holding the buffers. The zero-copy sendfile of files is disabled.
+func WithStreamWriteTimeout(t time.Duration) config.Option {
+	return config.Option{F: func(o *config.Options) {
+		o.StreamWriteTimeout = t
+	}}
+}
+
+// WithWriteStallObserver sets the function called when flushing a part of the response takes
+// longer than threshold or exceeds the deadline set by WithStreamWriteTimeout, e.g.
+// metrics.Collector.ObserveWriteStall. It is called synchronously, so keep it fast.
+func WithWriteStallObserver(threshold time.Duration, f func(stall network.WriteStall)) config.Option {
+	return config.Option{F: func(o *config.Options) {
+		o.WriteStallThreshold = threshold
+		o.OnWriteStall = f
+	}}
+}
+
+// WithRouteSharding sets whether partition the route tree of every method by the first path
+// segment, the lookup only walks the shard of the segment and the routes whose first segment
+// is a wildcard, which keeps the lookup latency flat for the gateways with a huge route table.
+// It costs another copy of the tree nodes, so it is only worth enabling for 10k+ routes.
+func WithRouteSharding(b bool) config.Option {
+	return config.Option{F: func(o *config.Options) {
+		o.RouteSharding = b
+	}}
+}
+
+// WithPanicReporter sets the function receiving the panics of the server, e.g. to report them
+// to an error tracker without wrapping every handler. It receives the panics recovered by the
+// recovery middleware and the engine, and the panics escaping from the connection goroutines,
+// which are recovered with the connection closed instead of crashing the process.
+func WithPanicReporter(f network.PanicReporter) config.Option {
+	return config.Option{F: func(o *config.Options) {
+		o.PanicReporter = f
+	}}
+}
+
+// WithShutdownProgress sets the function called with the count of the remaining connections
+// whenever it changes during graceful shutdown, e.g. to log the progress of draining.
+func WithShutdownProgress(f func(remaining int)) config.Option {
+	return config.Option{F: func(o *config.Options) {
+		o.OnShutdownProgress = f
+	}}
+}
+
 // WithTransport sets which network library to use.
+// netpoll is used by default on linux, darwin and freebsd, and standard.NewTransporter which
+// is based on the net package on the other platforms. standard is also used if TLS is set.
 func WithTransport(transporter func(options *config.Options) network.Transporter) config.Option {
 	return config.Option{F: func(o *config.Options) {
 		o.TransporterNewer = transporter
@@ -298,7 +592,10 @@ func WithTracer(t tracer.Tracer) config.Option {
 	}}
 }
 
-// WithTraceLevel sets the level trace.
+// WithTraceLevel sets the level of the events recorded to the trace info of the requests for
+// the tracers, default is stats.LevelDetailed. stats.LevelBase only records stats.HTTPStart and
+// stats.HTTPFinish, and stats.LevelDisabled records no events. See traceinfo.LatencyOf for
+// the breakdown of the latency by the recorded events.
 func WithTraceLevel(level stats.Level) config.Option {
 	return config.Option{F: func(o *config.Options) {
 		o.TraceLevel = level
@@ -313,6 +610,49 @@ func WithRegistry(r registry.Registry, info *registry.Info) config.Option {
 	}}
 }
 
+// WithRegistryRetry sets how to retry the failed registration to the registry, the server exits
+// only if all the attempts fail. The default is 5 attempts with the delays from 1s backing off
+// to 30s, which is changed by opts.
+func WithRegistryRetry(opts ...retry.Option) config.Option {
+	retryCfg := &retry.Config{
+		MaxAttemptTimes: 5,
+		Delay:           time.Second,
+		MaxDelay:        30 * time.Second,
+		DelayPolicy:     retry.BackOffDelayPolicy,
+	}
+	retryCfg.Apply(opts)
+
+	return config.Option{F: func(o *config.Options) {
+		o.RegistryRetry = retryCfg
+	}}
+}
+
+// WithRegistryReadiness gates the registration to the registry on check, the server is registered
+// only after check returns nil, which is polled every second, e.g. the readiness checks of the
+// health package:
+//
+//	server.WithRegistryReadiness(func(ctx context.Context) error {
+//		if r := h.Readiness(ctx); r.Status != health.StatusOK {
+//			return errors.New("not ready")
+//		}
+//		return nil
+//	})
+func WithRegistryReadiness(check func(ctx context.Context) error) config.Option {
+	return config.Option{F: func(o *config.Options) {
+		o.RegistryReadiness = check
+	}}
+}
+
+// WithRegistrySlowStart registers the server with a low weight first and ramps it up linearly to
+// the weight of the registry info over window, so that a new instance isn't hammered before its
+// caches warm up. The weight is updated by registering again, which the registries in the
+// registry package handle as an update.
+func WithRegistrySlowStart(window time.Duration) config.Option {
+	return config.Option{F: func(o *config.Options) {
+		o.RegistrySlowStart = window
+	}}
+}
+
 // WithAutoReloadRender sets the config of auto reload render.
 // If auto reload render is enabled:
 // 1. interval = 0 means reload render according to file watch mechanism.(recommended)
@@ -332,6 +672,39 @@ func WithDisablePrintRoute(b bool) config.Option {
 	}}
 }
 
+// WithMaxConcurrentConnections limits the count of concurrent connections, the connections
+// accepted beyond the limit are answered with 503 (if not tls) and closed immediately.
+func WithMaxConcurrentConnections(n int) config.Option {
+	return config.Option{F: func(o *config.Options) {
+		o.MaxConcurrentConnections = n
+	}}
+}
+
+// WithMaxRequestsPerConnection closes the keep-alive connection after serving n requests, so that
+// the clients reconnect and the load is rebalanced among the instances behind a load balancer.
+func WithMaxRequestsPerConnection(n int) config.Option {
+	return config.Option{F: func(o *config.Options) {
+		o.MaxRequestsPerConnection = n
+	}}
+}
+
+// WithConnectionThrottle limits the rate of new connections to perSecond with bursts of burst
+// connections, the connections beyond the rate are closed immediately.
+func WithConnectionThrottle(perSecond, burst int) config.Option {
+	return config.Option{F: func(o *config.Options) {
+		o.ConnectionThrottle = perSecond
+		o.ConnectionThrottleBurst = burst
+	}}
+}
+
+// WithOnConnectionRejected sets the function called when a connection is rejected by the limits
+// of WithMaxConcurrentConnections or WithConnectionThrottle, e.g. to count the rejections.
+func WithOnConnectionRejected(fn func(reason network.RejectReason)) config.Option {
+	return config.Option{F: func(o *config.Options) {
+		o.OnConnectionRejected = fn
+	}}
+}
+
 // WithOnAccept sets the callback function when a new connection is accepted but cannot
 // receive data in netpoll. In go net, it will be called before converting tls connection
 func WithOnAccept(fn func(conn net.Conn) context.Context) config.Option {
@@ -362,6 +735,15 @@ func WithValidateConfig(vc *binding.ValidateConfig) config.Option {
 	}}
 }
 
+// WithMultipartFormConfig sets the limits of parsing the multipart/form-data request bodies,
+// including the pre-parsed ones and the ones parsed by RequestContext.MultipartForm. The
+// requests exceeding the limits while being pre-parsed are responded with 413.
+func WithMultipartFormConfig(cfg *protocol.MultipartFormConfig) config.Option {
+	return config.Option{F: func(o *config.Options) {
+		o.MultipartFormConfig = cfg
+	}}
+}
+
 // WithCustomBinder sets customized Binder.
 func WithCustomBinder(b binding.Binder) config.Option {
 	return config.Option{F: func(o *config.Options) {
@@ -0,0 +1,133 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"context"
+	"time"
+
+	"hertz-study/pkg/app/client/retry"
+	"hertz-study/pkg/app/server/registry"
+	"hertz-study/pkg/common/config"
+	"hertz-study/pkg/common/hlog"
+)
+
+// readinessPollInterval is how often the readiness gating the registration is checked.
+var readinessPollInterval = time.Second
+
+// slowStartSteps is how many times the weight is raised during the slow start.
+var slowStartSteps = 10
+
+// register registers the server to the registry after it's ready, with the failed attempts retried
+// by RegistryRetry. It gives up if the server stops running meanwhile, so that the server is never
+// registered after it's deregistered on shutdown.
+func (h *Hertz) register(opt *config.Options) error {
+	ctx := context.Background()
+	if opt.RegistryReadiness != nil {
+		for {
+			if !h.IsRunning() {
+				return nil
+			}
+			err := opt.RegistryReadiness(ctx)
+			if err == nil {
+				break
+			}
+			hlog.SystemLogger().Infof("Wait for readiness before register: error=%v", err)
+			time.Sleep(readinessPollInterval)
+		}
+	}
+
+	attempts := uint(1)
+	if opt.RegistryRetry != nil && opt.RegistryRetry.MaxAttemptTimes > 1 {
+		attempts = opt.RegistryRetry.MaxAttemptTimes
+	}
+	info := opt.RegistryInfo
+	slowStart := opt.RegistrySlowStart > 0 && info != nil
+	if slowStart {
+		info = slowStartInfo(info, 0)
+	}
+	var err error
+	for i := uint(0); i < attempts; i++ {
+		if i > 0 {
+			delay := retry.Delay(i-1, err, opt.RegistryRetry)
+			hlog.SystemLogger().Warnf("Register error=%v, retry in %v", err, delay)
+			time.Sleep(delay)
+		}
+		if !h.IsRunning() {
+			return nil
+		}
+		if err = opt.Registry.Register(info); err == nil {
+			if !h.IsRunning() {
+				// the shutdown may deregister before the registration finishes
+				return opt.Registry.Deregister(opt.RegistryInfo)
+			}
+			if slowStart {
+				return h.slowStart(opt)
+			}
+			return nil
+		}
+	}
+	return err
+}
+
+// slowStart raises the registered weight step by step to the weight of RegistryInfo over
+// RegistrySlowStart. A failed update is skipped since the next step raises the weight anyway,
+// except that the full weight is retried until it's registered.
+func (h *Hertz) slowStart(opt *config.Options) error {
+	interval := opt.RegistrySlowStart / time.Duration(slowStartSteps)
+	last := slowStartInfo(opt.RegistryInfo, 0).Weight
+	for step := 1; step <= slowStartSteps; {
+		time.Sleep(interval)
+		if !h.IsRunning() {
+			return nil
+		}
+		info := slowStartInfo(opt.RegistryInfo, step)
+		if info.Weight == last {
+			step++
+			continue
+		}
+		if err := opt.Registry.Register(info); err != nil {
+			hlog.SystemLogger().Warnf("Register weight=%d error=%v", info.Weight, err)
+			if step < slowStartSteps {
+				step++
+			}
+			continue
+		}
+		if !h.IsRunning() {
+			return opt.Registry.Deregister(opt.RegistryInfo)
+		}
+		last = info.Weight
+		step++
+	}
+	return nil
+}
+
+// slowStartInfo returns a copy of info with the weight of the step of the slow start, which is
+// at least 1.
+func slowStartInfo(info *registry.Info, step int) *registry.Info {
+	weight := info.Weight
+	if weight <= 0 {
+		weight = registry.DefaultWeight
+	}
+	weight = weight * step / slowStartSteps
+	if weight < 1 {
+		weight = 1
+	}
+	ri := *info
+	ri.Weight = weight
+	return &ri
+}
@@ -0,0 +1,259 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package consul provides the registry.Registry and the discovery.Resolver backed by the HTTP API
+// of the Consul agent, e.g.
+//
+//	r := consul.NewRegistry("127.0.0.1:8500")
+//	h := server.New(server.WithRegistry(r, &registry.Info{ServiceName: "user", Addr: addr}))
+//	cli.Use(sd.Discovery(r))
+//
+// The service is registered with a TTL check which is passed periodically until Deregister,
+// Info.Tags are registered as the service meta and the "k=v" service tags, and Info.Weight as
+// the passing weight.
+package consul
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"hertz-study/pkg/app/client/discovery"
+	"hertz-study/pkg/app/server/registry"
+	"hertz-study/pkg/common/hlog"
+)
+
+// Registry registers the servers to Consul and resolves them by the service name.
+type Registry struct {
+	addr string
+	opts *options
+
+	mu         sync.Mutex
+	heartbeats map[string]chan struct{} // service id -> stop
+}
+
+var (
+	_ registry.Registry  = (*Registry)(nil)
+	_ discovery.Resolver = (*Registry)(nil)
+)
+
+// NewRegistry creates a Registry with the address of the Consul agent, e.g. "127.0.0.1:8500"
+// or "https://consul.example.com".
+func NewRegistry(addr string, opts ...Option) *Registry {
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	return &Registry{
+		addr:       strings.TrimSuffix(addr, "/"),
+		opts:       newOptions(opts...),
+		heartbeats: make(map[string]chan struct{}),
+	}
+}
+
+type agentService struct {
+	ID      string            `json:"ID"`
+	Name    string            `json:"Name"`
+	Address string            `json:"Address"`
+	Port    int               `json:"Port"`
+	Tags    []string          `json:"Tags,omitempty"`
+	Meta    map[string]string `json:"Meta,omitempty"`
+	Weights *weights          `json:"Weights,omitempty"`
+	Check   *agentCheck       `json:"Check,omitempty"`
+}
+
+type weights struct {
+	Passing int `json:"Passing"`
+	Warning int `json:"Warning"`
+}
+
+type agentCheck struct {
+	CheckID                        string `json:"CheckID"`
+	TTL                            string `json:"TTL"`
+	DeregisterCriticalServiceAfter string `json:"DeregisterCriticalServiceAfter"`
+}
+
+type serviceEntry struct {
+	Node struct {
+		Address string `json:"Address"`
+	} `json:"Node"`
+	Service agentService `json:"Service"`
+}
+
+func serviceID(info *registry.Info, host string, port int) string {
+	return info.ServiceName + "-" + host + "-" + strconv.Itoa(port)
+}
+
+// Register implements the registry.Registry interface.
+func (r *Registry) Register(info *registry.Info) error {
+	if info == nil || info.ServiceName == "" || info.Addr == nil {
+		return fmt.Errorf("invalid registry info: %v", info)
+	}
+	host, port, err := info.HostPort()
+	if err != nil {
+		return err
+	}
+	weight := info.Weight
+	if weight <= 0 {
+		weight = registry.DefaultWeight
+	}
+	id := serviceID(info, host, port)
+	svc := &agentService{
+		ID:      id,
+		Name:    info.ServiceName,
+		Address: host,
+		Port:    port,
+		Meta:    info.Tags,
+		Weights: &weights{Passing: weight, Warning: 1},
+		Check: &agentCheck{
+			CheckID:                        "service:" + id,
+			TTL:                            r.opts.ttl.String(),
+			DeregisterCriticalServiceAfter: r.opts.deregisterCriticalAfter.String(),
+		},
+	}
+	for k, v := range info.Tags {
+		svc.Tags = append(svc.Tags, k+"="+v)
+	}
+	body, err := json.Marshal(svc)
+	if err != nil {
+		return err
+	}
+	if err = r.do(context.Background(), http.MethodPut, "/v1/agent/service/register", body, nil); err != nil {
+		return err
+	}
+	if err = r.passCheck(id); err != nil {
+		return err
+	}
+
+	stop := make(chan struct{})
+	r.mu.Lock()
+	if old, ok := r.heartbeats[id]; ok {
+		close(old)
+	}
+	r.heartbeats[id] = stop
+	r.mu.Unlock()
+	go r.heartbeat(id, stop)
+	return nil
+}
+
+func (r *Registry) passCheck(id string) error {
+	return r.do(context.Background(), http.MethodPut, "/v1/agent/check/pass/service:"+url.PathEscape(id), nil, nil)
+}
+
+func (r *Registry) heartbeat(id string, stop chan struct{}) {
+	ticker := time.NewTicker(r.opts.ttl / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := r.passCheck(id); err != nil {
+				hlog.SystemLogger().Warnf("Consul pass check of service=%s error=%v", id, err)
+			}
+		}
+	}
+}
+
+// Deregister implements the registry.Registry interface.
+func (r *Registry) Deregister(info *registry.Info) error {
+	if info == nil || info.Addr == nil {
+		return nil
+	}
+	host, port, err := info.HostPort()
+	if err != nil {
+		return err
+	}
+	id := serviceID(info, host, port)
+	r.mu.Lock()
+	if stop, ok := r.heartbeats[id]; ok {
+		close(stop)
+		delete(r.heartbeats, id)
+	}
+	r.mu.Unlock()
+	return r.do(context.Background(), http.MethodPut, "/v1/agent/service/deregister/"+url.PathEscape(id), nil, nil)
+}
+
+// Target implements the discovery.Resolver interface, the host of the request is the service name.
+func (r *Registry) Target(ctx context.Context, target *discovery.TargetInfo) string {
+	return target.Host
+}
+
+// Resolve implements the discovery.Resolver interface, only the instances passing the health
+// checks are returned.
+func (r *Registry) Resolve(ctx context.Context, desc string) (discovery.Result, error) {
+	var entries []serviceEntry
+	if err := r.do(ctx, http.MethodGet, "/v1/health/service/"+url.PathEscape(desc)+"?passing=true", nil, &entries); err != nil {
+		return discovery.Result{}, err
+	}
+	if len(entries) == 0 {
+		return discovery.Result{}, fmt.Errorf("no instance of service %s", desc)
+	}
+	ins := make([]discovery.Instance, 0, len(entries))
+	for _, e := range entries {
+		host := e.Service.Address
+		if host == "" {
+			host = e.Node.Address
+		}
+		weight := registry.DefaultWeight
+		if e.Service.Weights != nil && e.Service.Weights.Passing > 0 {
+			weight = e.Service.Weights.Passing
+		}
+		addr := net.JoinHostPort(host, strconv.Itoa(e.Service.Port))
+		ins = append(ins, discovery.NewInstance("tcp", addr, weight, e.Service.Meta))
+	}
+	return discovery.Result{CacheKey: desc, Instances: ins}, nil
+}
+
+// Name implements the discovery.Resolver interface.
+func (r *Registry) Name() string {
+	return "consul"
+}
+
+func (r *Registry) do(ctx context.Context, method, path string, body []byte, out interface{}) error {
+	var rd io.Reader
+	if body != nil {
+		rd = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, r.addr+path, rd)
+	if err != nil {
+		return err
+	}
+	if r.opts.token != "" {
+		req.Header.Set("X-Consul-Token", r.opts.token)
+	}
+	resp, err := r.opts.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("consul %s %s: status=%d body=%s", method, path, resp.StatusCode, msg)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
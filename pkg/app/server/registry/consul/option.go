@@ -0,0 +1,75 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package consul
+
+import (
+	"net/http"
+	"time"
+)
+
+type (
+	options struct {
+		token                   string
+		ttl                     time.Duration
+		deregisterCriticalAfter time.Duration
+		httpClient              *http.Client
+	}
+
+	Option func(o *options)
+)
+
+func newOptions(opts ...Option) *options {
+	cfg := &options{
+		ttl:                     10 * time.Second,
+		deregisterCriticalAfter: time.Minute,
+		httpClient:              &http.Client{Timeout: 5 * time.Second},
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// WithToken sets the ACL token sent as the X-Consul-Token header.
+func WithToken(token string) Option {
+	return func(o *options) {
+		o.token = token
+	}
+}
+
+// WithTTL sets the TTL of the health check of the registered service, the check is passed
+// every TTL/3. Default is 10s.
+func WithTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.ttl = ttl
+	}
+}
+
+// WithDeregisterCriticalAfter sets after how long Consul deregisters the service whose check
+// stays critical, e.g. the process is killed without deregistering. Default is 1m.
+func WithDeregisterCriticalAfter(d time.Duration) Option {
+	return func(o *options) {
+		o.deregisterCriticalAfter = d
+	}
+}
+
+// WithHTTPClient sets the client calling the HTTP API of Consul, default has 5s timeout.
+func WithHTTPClient(c *http.Client) Option {
+	return func(o *options) {
+		o.httpClient = c
+	}
+}
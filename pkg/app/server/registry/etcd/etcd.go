@@ -0,0 +1,277 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package etcd provides the registry.Registry and the discovery.Resolver backed by the v3 JSON
+// gateway of etcd, e.g.
+//
+//	r := etcd.NewRegistry("127.0.0.1:2379")
+//	h := server.New(server.WithRegistry(r, &registry.Info{ServiceName: "user", Addr: addr}))
+//	cli.Use(sd.Discovery(r))
+//
+// Every instance is stored as a json value under a key attached to a lease, which is kept alive
+// until Deregister, so the key disappears once the process is gone.
+package etcd
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"hertz-study/pkg/app/client/discovery"
+	"hertz-study/pkg/app/server/registry"
+	"hertz-study/pkg/common/hlog"
+)
+
+// Registry registers the servers to etcd and resolves them by the service name.
+type Registry struct {
+	addr string
+	opts *options
+
+	mu         sync.Mutex
+	heartbeats map[string]chan struct{} // key -> stop
+}
+
+var (
+	_ registry.Registry  = (*Registry)(nil)
+	_ discovery.Resolver = (*Registry)(nil)
+)
+
+// NewRegistry creates a Registry with the address of an etcd endpoint, e.g. "127.0.0.1:2379"
+// or "https://etcd.example.com:2379".
+func NewRegistry(addr string, opts ...Option) *Registry {
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	return &Registry{
+		addr:       strings.TrimSuffix(addr, "/"),
+		opts:       newOptions(opts...),
+		heartbeats: make(map[string]chan struct{}),
+	}
+}
+
+// instanceInfo is the value of the key of an instance.
+type instanceInfo struct {
+	Network string            `json:"network"`
+	Address string            `json:"address"`
+	Weight  int               `json:"weight"`
+	Tags    map[string]string `json:"tags,omitempty"`
+}
+
+func (r *Registry) key(serviceName, addr string) string {
+	return r.opts.prefix + serviceName + "/" + addr
+}
+
+// Register implements the registry.Registry interface.
+func (r *Registry) Register(info *registry.Info) error {
+	if info == nil || info.ServiceName == "" || info.Addr == nil {
+		return fmt.Errorf("invalid registry info: %v", info)
+	}
+	host, port, err := info.HostPort()
+	if err != nil {
+		return err
+	}
+	weight := info.Weight
+	if weight <= 0 {
+		weight = registry.DefaultWeight
+	}
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+	value, err := json.Marshal(&instanceInfo{Network: "tcp", Address: addr, Weight: weight, Tags: info.Tags})
+	if err != nil {
+		return err
+	}
+	key := r.key(info.ServiceName, addr)
+	lease, err := r.put(key, value)
+	if err != nil {
+		return err
+	}
+
+	stop := make(chan struct{})
+	r.mu.Lock()
+	if old, ok := r.heartbeats[key]; ok {
+		close(old)
+	}
+	r.heartbeats[key] = stop
+	r.mu.Unlock()
+	go r.keepAlive(key, value, lease, stop)
+	return nil
+}
+
+// put grants a lease and puts the key attached to it.
+func (r *Registry) put(key string, value []byte) (lease string, err error) {
+	ttl := int64(r.opts.ttl / time.Second)
+	if ttl < 1 {
+		ttl = 1
+	}
+	var grant struct {
+		ID string `json:"ID"`
+	}
+	if err = r.call(context.Background(), "/v3/lease/grant", map[string]interface{}{"TTL": ttl}, &grant); err != nil {
+		return "", err
+	}
+	err = r.call(context.Background(), "/v3/kv/put", map[string]interface{}{
+		"key":   encode(key),
+		"value": base64.StdEncoding.EncodeToString(value),
+		"lease": grant.ID,
+	}, nil)
+	return grant.ID, err
+}
+
+func (r *Registry) keepAlive(key string, value []byte, lease string, stop chan struct{}) {
+	interval := r.opts.ttl / 3
+	if interval < 300*time.Millisecond {
+		interval = 300 * time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			r.call(context.Background(), "/v3/lease/revoke", map[string]interface{}{"ID": lease}, nil) //nolint:errcheck
+			return
+		case <-ticker.C:
+		}
+		var resp struct {
+			Result struct {
+				TTL string `json:"TTL"`
+			} `json:"result"`
+		}
+		err := r.call(context.Background(), "/v3/lease/keepalive", map[string]interface{}{"ID": lease}, &resp)
+		if err == nil && resp.Result.TTL != "" && resp.Result.TTL != "0" {
+			continue
+		}
+		if err != nil {
+			hlog.SystemLogger().Warnf("Etcd keep alive lease of key=%s error=%v", key, err)
+			continue
+		}
+		// the lease expired, e.g. etcd was unreachable longer than the TTL
+		if newLease, err := r.put(key, value); err != nil {
+			hlog.SystemLogger().Warnf("Etcd register key=%s again error=%v", key, err)
+		} else {
+			lease = newLease
+		}
+	}
+}
+
+// Deregister implements the registry.Registry interface.
+func (r *Registry) Deregister(info *registry.Info) error {
+	if info == nil || info.Addr == nil {
+		return nil
+	}
+	host, port, err := info.HostPort()
+	if err != nil {
+		return err
+	}
+	key := r.key(info.ServiceName, net.JoinHostPort(host, strconv.Itoa(port)))
+	r.mu.Lock()
+	if stop, ok := r.heartbeats[key]; ok {
+		close(stop)
+		delete(r.heartbeats, key)
+	}
+	r.mu.Unlock()
+	return r.call(context.Background(), "/v3/kv/deleterange", map[string]interface{}{"key": encode(key)}, nil)
+}
+
+// Target implements the discovery.Resolver interface, the host of the request is the service name.
+func (r *Registry) Target(ctx context.Context, target *discovery.TargetInfo) string {
+	return target.Host
+}
+
+// Resolve implements the discovery.Resolver interface.
+func (r *Registry) Resolve(ctx context.Context, desc string) (discovery.Result, error) {
+	prefix := r.opts.prefix + desc + "/"
+	var resp struct {
+		Kvs []struct {
+			Value string `json:"value"`
+		} `json:"kvs"`
+	}
+	if err := r.call(ctx, "/v3/kv/range", map[string]interface{}{
+		"key":       encode(prefix),
+		"range_end": encode(prefixEnd(prefix)),
+	}, &resp); err != nil {
+		return discovery.Result{}, err
+	}
+	ins := make([]discovery.Instance, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		b, err := base64.StdEncoding.DecodeString(kv.Value)
+		if err != nil {
+			continue
+		}
+		var in instanceInfo
+		if err = json.Unmarshal(b, &in); err != nil {
+			continue
+		}
+		ins = append(ins, discovery.NewInstance(in.Network, in.Address, in.Weight, in.Tags))
+	}
+	if len(ins) == 0 {
+		return discovery.Result{}, fmt.Errorf("no instance of service %s", desc)
+	}
+	return discovery.Result{CacheKey: desc, Instances: ins}, nil
+}
+
+// Name implements the discovery.Resolver interface.
+func (r *Registry) Name() string {
+	return "etcd"
+}
+
+func encode(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
+
+// prefixEnd returns the range end covering all the keys with the prefix.
+func prefixEnd(prefix string) string {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return string(end[:i+1])
+		}
+	}
+	return "\x00"
+}
+
+func (r *Registry) call(ctx context.Context, path string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.addr+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := r.opts.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("etcd %s: status=%d body=%s", path, resp.StatusCode, msg)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
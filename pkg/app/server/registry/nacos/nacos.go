@@ -0,0 +1,250 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nacos provides the registry.Registry and the discovery.Resolver backed by the v1 open
+// API of Nacos, e.g.
+//
+//	r := nacos.NewRegistry("127.0.0.1:8848")
+//	h := server.New(server.WithRegistry(r, &registry.Info{ServiceName: "user", Addr: addr}))
+//	cli.Use(sd.Discovery(r))
+//
+// The instances are registered as ephemeral instances whose heartbeats are sent until Deregister,
+// Info.Tags are registered as the metadata and Info.Weight as the weight.
+package nacos
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"hertz-study/pkg/app/client/discovery"
+	"hertz-study/pkg/app/server/registry"
+	"hertz-study/pkg/common/hlog"
+)
+
+// Registry registers the servers to Nacos and resolves them by the service name.
+type Registry struct {
+	addr string
+	opts *options
+
+	mu         sync.Mutex
+	heartbeats map[string]chan struct{} // service name + address -> stop
+}
+
+var (
+	_ registry.Registry  = (*Registry)(nil)
+	_ discovery.Resolver = (*Registry)(nil)
+)
+
+// NewRegistry creates a Registry with the address of the Nacos server, e.g. "127.0.0.1:8848"
+// or "https://nacos.example.com".
+func NewRegistry(addr string, opts ...Option) *Registry {
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	return &Registry{
+		addr:       strings.TrimSuffix(addr, "/"),
+		opts:       newOptions(opts...),
+		heartbeats: make(map[string]chan struct{}),
+	}
+}
+
+// instance is the instance of the open API.
+type instance struct {
+	IP       string            `json:"ip"`
+	Port     int               `json:"port"`
+	Weight   float64           `json:"weight"`
+	Healthy  bool              `json:"healthy"`
+	Enabled  bool              `json:"enabled"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// params returns the query parameters identifying the instance.
+func (r *Registry) params(serviceName, host string, port int) url.Values {
+	v := url.Values{}
+	v.Set("serviceName", serviceName)
+	v.Set("groupName", r.opts.group)
+	v.Set("clusterName", r.opts.cluster)
+	v.Set("ip", host)
+	v.Set("port", strconv.Itoa(port))
+	v.Set("ephemeral", "true")
+	if r.opts.namespace != "" {
+		v.Set("namespaceId", r.opts.namespace)
+	}
+	return v
+}
+
+// Register implements the registry.Registry interface.
+func (r *Registry) Register(info *registry.Info) error {
+	if info == nil || info.ServiceName == "" || info.Addr == nil {
+		return fmt.Errorf("invalid registry info: %v", info)
+	}
+	host, port, err := info.HostPort()
+	if err != nil {
+		return err
+	}
+	weight := info.Weight
+	if weight <= 0 {
+		weight = registry.DefaultWeight
+	}
+	metadata, err := json.Marshal(info.Tags)
+	if err != nil {
+		return err
+	}
+	params := r.params(info.ServiceName, host, port)
+	params.Set("weight", strconv.Itoa(weight))
+	params.Set("metadata", string(metadata))
+	params.Set("enabled", "true")
+	params.Set("healthy", "true")
+	if err = r.do(context.Background(), http.MethodPost, "/nacos/v1/ns/instance", params, nil); err != nil {
+		return err
+	}
+
+	beat, err := json.Marshal(map[string]interface{}{
+		"serviceName": r.opts.group + "@@" + info.ServiceName,
+		"ip":          host,
+		"port":        port,
+		"weight":      weight,
+		"cluster":     r.opts.cluster,
+		"metadata":    info.Tags,
+		"ephemeral":   true,
+	})
+	if err != nil {
+		return err
+	}
+	beatParams := r.params(info.ServiceName, host, port)
+	beatParams.Set("beat", string(beat))
+
+	key := info.ServiceName + "/" + net.JoinHostPort(host, strconv.Itoa(port))
+	stop := make(chan struct{})
+	r.mu.Lock()
+	if old, ok := r.heartbeats[key]; ok {
+		close(old)
+	}
+	r.heartbeats[key] = stop
+	r.mu.Unlock()
+	go r.heartbeat(key, beatParams, stop)
+	return nil
+}
+
+func (r *Registry) heartbeat(key string, params url.Values, stop chan struct{}) {
+	ticker := time.NewTicker(r.opts.heartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := r.do(context.Background(), http.MethodPut, "/nacos/v1/ns/instance/beat", params, nil); err != nil {
+				hlog.SystemLogger().Warnf("Nacos heartbeat of instance=%s error=%v", key, err)
+			}
+		}
+	}
+}
+
+// Deregister implements the registry.Registry interface.
+func (r *Registry) Deregister(info *registry.Info) error {
+	if info == nil || info.Addr == nil {
+		return nil
+	}
+	host, port, err := info.HostPort()
+	if err != nil {
+		return err
+	}
+	key := info.ServiceName + "/" + net.JoinHostPort(host, strconv.Itoa(port))
+	r.mu.Lock()
+	if stop, ok := r.heartbeats[key]; ok {
+		close(stop)
+		delete(r.heartbeats, key)
+	}
+	r.mu.Unlock()
+	return r.do(context.Background(), http.MethodDelete, "/nacos/v1/ns/instance", r.params(info.ServiceName, host, port), nil)
+}
+
+// Target implements the discovery.Resolver interface, the host of the request is the service name.
+func (r *Registry) Target(ctx context.Context, target *discovery.TargetInfo) string {
+	return target.Host
+}
+
+// Resolve implements the discovery.Resolver interface, only the healthy and enabled instances
+// are returned.
+func (r *Registry) Resolve(ctx context.Context, desc string) (discovery.Result, error) {
+	params := url.Values{}
+	params.Set("serviceName", desc)
+	params.Set("groupName", r.opts.group)
+	params.Set("clusters", r.opts.cluster)
+	params.Set("healthyOnly", "true")
+	if r.opts.namespace != "" {
+		params.Set("namespaceId", r.opts.namespace)
+	}
+	var resp struct {
+		Hosts []instance `json:"hosts"`
+	}
+	if err := r.do(ctx, http.MethodGet, "/nacos/v1/ns/instance/list", params, &resp); err != nil {
+		return discovery.Result{}, err
+	}
+	ins := make([]discovery.Instance, 0, len(resp.Hosts))
+	for _, h := range resp.Hosts {
+		if !h.Healthy || !h.Enabled {
+			continue
+		}
+		weight := int(h.Weight)
+		if weight <= 0 {
+			weight = registry.DefaultWeight
+		}
+		ins = append(ins, discovery.NewInstance("tcp", net.JoinHostPort(h.IP, strconv.Itoa(h.Port)), weight, h.Metadata))
+	}
+	if len(ins) == 0 {
+		return discovery.Result{}, fmt.Errorf("no instance of service %s", desc)
+	}
+	return discovery.Result{CacheKey: desc, Instances: ins}, nil
+}
+
+// Name implements the discovery.Resolver interface.
+func (r *Registry) Name() string {
+	return "nacos"
+}
+
+func (r *Registry) do(ctx context.Context, method, path string, params url.Values, out interface{}) error {
+	if r.opts.accessToken != "" {
+		params.Set("accessToken", r.opts.accessToken)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, r.addr+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := r.opts.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("nacos %s %s: status=%d body=%s", method, path, resp.StatusCode, msg)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,91 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package nacos
+
+import (
+	"net/http"
+	"time"
+)
+
+type (
+	options struct {
+		namespace         string
+		group             string
+		cluster           string
+		heartbeatInterval time.Duration
+		accessToken       string
+		httpClient        *http.Client
+	}
+
+	Option func(o *options)
+)
+
+func newOptions(opts ...Option) *options {
+	cfg := &options{
+		group:             "DEFAULT_GROUP",
+		cluster:           "DEFAULT",
+		heartbeatInterval: 5 * time.Second,
+		httpClient:        &http.Client{Timeout: 5 * time.Second},
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// WithNamespace sets the namespace id of the instances, default is the public namespace.
+func WithNamespace(namespace string) Option {
+	return func(o *options) {
+		o.namespace = namespace
+	}
+}
+
+// WithGroup sets the group of the services, default is "DEFAULT_GROUP".
+func WithGroup(group string) Option {
+	return func(o *options) {
+		o.group = group
+	}
+}
+
+// WithCluster sets the cluster of the instances, default is "DEFAULT".
+func WithCluster(cluster string) Option {
+	return func(o *options) {
+		o.cluster = cluster
+	}
+}
+
+// WithHeartbeatInterval sets how often the heartbeat of the ephemeral instances is sent,
+// Nacos marks an instance unhealthy after 15s without heartbeat by default. Default is 5s.
+func WithHeartbeatInterval(d time.Duration) Option {
+	return func(o *options) {
+		o.heartbeatInterval = d
+	}
+}
+
+// WithAccessToken sets the access token of Nacos with the auth enabled.
+func WithAccessToken(token string) Option {
+	return func(o *options) {
+		o.accessToken = token
+	}
+}
+
+// WithHTTPClient sets the client calling the open API of Nacos, default has 5s timeout.
+func WithHTTPClient(c *http.Client) Option {
+	return func(o *options) {
+		o.httpClient = c
+	}
+}
@@ -1,9 +1,18 @@
 package registry
 
-import "net"
+import (
+	"net"
+	"strconv"
+
+	"hertz-study/pkg/common/utils"
+)
 
 const (
 	DefaultWeight = 10
+
+	// ZoneTagKey is the tag key of Info.Tags which carries the zone of the instance,
+	// it is used by locality-aware load balancing of client.
+	ZoneTagKey = "zone"
 )
 
 // Registry is extension interface of service registry.
@@ -25,6 +34,22 @@ type Info struct {
 	Tags map[string]string
 }
 
+// HostPort returns the host and the port of Addr to be registered, the unspecified host,
+// e.g. of ":8888" or "0.0.0.0:8888", is replaced by the local IP.
+func (i *Info) HostPort() (host string, port int, err error) {
+	host, p, err := net.SplitHostPort(i.Addr.String())
+	if err != nil {
+		return "", 0, err
+	}
+	if port, err = strconv.Atoi(p); err != nil {
+		return "", 0, err
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = utils.LocalIP()
+	}
+	return host, port, nil
+}
+
 // NoopRegistry is an empty implement of Registry
 var NoopRegistry Registry = &noopRegistry{}
 
@@ -0,0 +1,117 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"hertz-study/pkg/app/server/dynconfig"
+	"hertz-study/pkg/common/hlog"
+)
+
+// ReloadFunc is a step of the reload pipeline, see Hertz.AddReloader. It loads and validates
+// the new state without applying it, and returns the function applying it, which should not
+// fail. The returned function is discarded if any step of the pipeline fails.
+type ReloadFunc func(ctx context.Context) (commit func(), err error)
+
+// ReloadError is the error of a failed reload, none of the new states is applied.
+type ReloadError struct {
+	Step string
+	Err  error
+}
+
+func (e *ReloadError) Error() string {
+	return fmt.Sprintf("reload step %s failed: %v", e.Step, e.Err)
+}
+
+func (e *ReloadError) Unwrap() error {
+	return e.Err
+}
+
+type reloader struct {
+	name string
+	f    ReloadFunc
+}
+
+// AddReloader adds a step named name to the reload pipeline run by Reload, e.g. re-reading
+// the config files or the TLS certificates by certmanager.Manager.PrepareReload.
+func (h *Hertz) AddReloader(name string, f ReloadFunc) {
+	h.reloadMu.Lock()
+	h.reloaders = append(h.reloaders, reloader{name: name, f: f})
+	h.reloadMu.Unlock()
+}
+
+// Reload runs the steps added by AddReloader in order, and applies their new states only if
+// all of them succeed, so either all or none of the new states take effect. The outcome is
+// logged and passed to the OnReload hooks. It is called on SIGHUP if WithReloadOnSIGHUP is set.
+func (h *Hertz) Reload(ctx context.Context) error {
+	h.reloadMu.Lock()
+	defer h.reloadMu.Unlock()
+
+	start := time.Now()
+	commits := make([]func(), 0, len(h.reloaders))
+	var err error
+	for _, r := range h.reloaders {
+		commit, e := r.f(ctx)
+		if e != nil {
+			err = &ReloadError{Step: r.name, Err: e}
+			break
+		}
+		if commit != nil {
+			commits = append(commits, commit)
+		}
+	}
+
+	if err != nil {
+		hlog.SystemLogger().Errorf("[Reload] reload failed, keep the current states: err=%v", err)
+	} else {
+		for _, commit := range commits {
+			commit()
+		}
+		hlog.SystemLogger().Infof("[Reload] reloaded steps=%d, cost=%v", len(h.reloaders), time.Since(start))
+	}
+	for _, hook := range h.OnReload {
+		hook(ctx, err)
+	}
+	return err
+}
+
+// UseDynamicConfig loads c and applies its built-in keys to h, see dynconfig.BindEngine. c is
+// added to the reload pipeline as the step "dynconfig", which runs whenever the providers of c
+// change while h is running, so that c is applied together with the other steps or not at all.
+func (h *Hertz) UseDynamicConfig(c *dynconfig.Config) error {
+	dynconfig.BindEngine(c, h.Engine)
+	if err := c.Load(context.Background()); err != nil {
+		return err
+	}
+	h.AddReloader("dynconfig", c.PrepareReload)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	h.OnRun = append(h.OnRun, func(context.Context) error {
+		c.Watch(ctx, func(ctx context.Context) {
+			// the outcome is reported by Reload
+			h.Reload(ctx) //nolint:errcheck
+		})
+		return nil
+	})
+	h.OnShutdown = append(h.OnShutdown, func(context.Context) {
+		cancel()
+	})
+	return nil
+}
@@ -41,9 +41,13 @@
 
 package render
 
-import "hertz-study/pkg/protocol"
+import (
+	"hertz-study/pkg/common/mimetype"
+	"hertz-study/pkg/protocol"
+)
 
-// Data contains ContentType and bytes data.
+// Data contains ContentType and bytes data, the ContentType is
+// detected by mimetype.Default from the data if it is empty.
 type Data struct {
 	ContentType string
 	Data        []byte
@@ -58,5 +62,9 @@ func (r Data) Render(resp *protocol.Response) (err error) {
 
 // WriteContentType (Data) writes custom ContentType.
 func (r Data) WriteContentType(resp *protocol.Response) {
+	if r.ContentType == "" {
+		writeContentType(resp, mimetype.Detect("", r.Data))
+		return
+	}
 	writeContentType(resp, r.ContentType)
 }
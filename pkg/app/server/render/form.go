@@ -0,0 +1,155 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package render
+
+import (
+	"encoding"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"hertz-study/pkg/protocol"
+	"hertz-study/pkg/protocol/consts"
+)
+
+// Form contains the given interface object encoded as application/x-www-form-urlencoded, e.g. for
+// the token responses of some OAuth servers. Data can be url.Values, a map of string keys or a
+// struct whose fields are named by the form tags as the binding does, the fields of the nested
+// structs are named like "a.b" and the slices are encoded as the repeated keys.
+type Form struct {
+	Data interface{}
+}
+
+var formContentType = consts.MIMEApplicationHTMLFormUTF8
+
+// Render (Form) encodes the given interface object and writes data with custom ContentType.
+func (r Form) Render(resp *protocol.Response) error {
+	r.WriteContentType(resp)
+	values, ok := r.Data.(url.Values)
+	if !ok {
+		values = make(url.Values)
+		v := reflect.ValueOf(r.Data)
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct && v.Kind() != reflect.Map {
+			return fmt.Errorf("form: unsupported type %T", r.Data)
+		}
+		if err := encodeForm(values, "", v); err != nil {
+			return err
+		}
+	}
+	resp.AppendBodyString(values.Encode())
+	return nil
+}
+
+// WriteContentType (Form) writes form ContentType.
+func (r Form) WriteContentType(resp *protocol.Response) {
+	writeContentType(resp, formContentType)
+}
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+func encodeForm(values url.Values, key string, v reflect.Value) error {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Type().Implements(textMarshalerType) {
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return err
+		}
+		values.Add(key, string(text))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" && !f.Anonymous {
+				continue
+			}
+			name, opts, _ := strings.Cut(f.Tag.Get("form"), ",")
+			if name == "-" {
+				continue
+			}
+			fv := v.Field(i)
+			if opts == "omitempty" && fv.IsZero() {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			fieldKey := joinFormKey(key, name)
+			if f.Anonymous && name == f.Name {
+				fieldKey = key
+			}
+			if err := encodeForm(values, fieldKey, fv); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("form: unsupported map key type %s", v.Type().Key())
+		}
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		for _, k := range keys {
+			if err := encodeForm(values, joinFormKey(key, k.String()), v.MapIndex(k)); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			values.Add(key, string(v.Bytes()))
+			return nil
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := encodeForm(values, key, v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.String:
+		values.Add(key, v.String())
+	case reflect.Bool:
+		values.Add(key, strconv.FormatBool(v.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		values.Add(key, strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		values.Add(key, strconv.FormatUint(v.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		values.Add(key, strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()))
+	default:
+		return fmt.Errorf("form: unsupported type %s of %q", v.Type(), key)
+	}
+	return nil
+}
+
+func joinFormKey(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
@@ -42,7 +42,9 @@
 package render
 
 import (
+	"fmt"
 	"html/template"
+	"io"
 	"log"
 	"sync"
 	"time"
@@ -67,9 +69,35 @@ type HTMLRender interface {
 	Close() error
 }
 
+// HTMLLayoutRender is implemented by the HTMLRender which is able to render a page within a layout.
+type HTMLLayoutRender interface {
+	// InstanceWithLayout returns an HTML instance which renders the template name within
+	// the template layout, the page is not rendered within any layout if layout is empty.
+	InstanceWithLayout(layout, name string, data interface{}) Render
+}
+
+// LayoutYield is the name of the template by which a layout renders the page within it,
+// e.g. {{ template "yield" . }}.
+const LayoutYield = "yield"
+
 // HTMLProduction contains template reference and its delims.
 type HTMLProduction struct {
 	Template *template.Template
+	// Layout is the default layout the pages are rendered within, it only works
+	// if HTMLProduction is created by NewHTMLProduction.
+	Layout string
+
+	layouts *htmlLayouts
+}
+
+// NewHTMLProduction creates an HTMLProduction which renders the pages within layout by default.
+// It must be called before tmpl is executed.
+func NewHTMLProduction(tmpl *template.Template, layout string) HTMLProduction {
+	return HTMLProduction{
+		Template: tmpl,
+		Layout:   layout,
+		layouts:  newHTMLLayouts(tmpl),
+	}
 }
 
 // HTML contains template reference and its name with given interface object.
@@ -83,11 +111,12 @@ var htmlContentType = "text/html; charset=utf-8"
 
 // Instance (HTMLProduction) returns an HTML instance which it realizes Render interface.
 func (r HTMLProduction) Instance(name string, data interface{}) Render {
-	return HTML{
-		Template: r.Template,
-		Name:     name,
-		Data:     data,
-	}
+	return r.InstanceWithLayout(r.Layout, name, data)
+}
+
+// InstanceWithLayout (HTMLProduction) returns an HTML instance which renders name within layout.
+func (r HTMLProduction) InstanceWithLayout(layout, name string, data interface{}) Render {
+	return newHTML(r.Template, r.layouts, layout, name, data)
 }
 
 func (r HTMLProduction) Close() error {
@@ -97,11 +126,15 @@ func (r HTMLProduction) Close() error {
 // Render (HTML) executes template and writes its result with custom ContentType for response.
 func (r HTML) Render(resp *protocol.Response) error {
 	r.WriteContentType(resp)
+	return r.RenderTo(resp.BodyWriter())
+}
 
+// RenderTo (HTML) executes template and writes its result to w.
+func (r HTML) RenderTo(w io.Writer) error {
 	if r.Name == "" {
-		return r.Template.Execute(resp.BodyWriter(), r.Data)
+		return r.Template.Execute(w, r.Data)
 	}
-	return r.Template.ExecuteTemplate(resp.BodyWriter(), r.Name, r.Data)
+	return r.Template.ExecuteTemplate(w, r.Name, r.Data)
 }
 
 // WriteContentType (HTML) writes HTML ContentType.
@@ -109,21 +142,112 @@ func (r HTML) WriteContentType(resp *protocol.Response) {
 	writeContentType(resp, htmlContentType)
 }
 
+func newHTML(tmpl *template.Template, layouts *htmlLayouts, layout, name string, data interface{}) Render {
+	if layout == "" {
+		return HTML{
+			Template: tmpl,
+			Name:     name,
+			Data:     data,
+		}
+	}
+	if layouts == nil {
+		return htmlError{err: fmt.Errorf("html/template: layout %q is not supported by the HTML render", layout)}
+	}
+	t, err := layouts.lookup(layout, name)
+	if err != nil {
+		return htmlError{err: err}
+	}
+	return HTML{
+		Template: t,
+		Data:     data,
+	}
+}
+
+// htmlError is the HTML instance which fails to be created.
+type htmlError struct {
+	err error
+}
+
+func (r htmlError) Render(resp *protocol.Response) error {
+	return r.err
+}
+
+func (r htmlError) RenderTo(w io.Writer) error {
+	return r.err
+}
+
+func (r htmlError) WriteContentType(resp *protocol.Response) {
+	writeContentType(resp, htmlContentType)
+}
+
+// htmlLayouts renders the pages within the layouts. Since a template can not be cloned after
+// being executed, it keeps an unexecuted copy of the templates, from which every combination
+// of a layout and a page is cloned once and cached.
+type htmlLayouts struct {
+	base  *template.Template
+	err   error
+	cache sync.Map // layout + "\x00" + page -> *template.Template
+}
+
+func newHTMLLayouts(tmpl *template.Template) *htmlLayouts {
+	base, err := tmpl.Clone()
+	return &htmlLayouts{base: base, err: err}
+}
+
+func (l *htmlLayouts) lookup(layout, name string) (*template.Template, error) {
+	key := layout + "\x00" + name
+	if t, ok := l.cache.Load(key); ok {
+		return t.(*template.Template), nil
+	}
+	if l.err != nil {
+		return nil, l.err
+	}
+
+	t, err := l.base.Clone()
+	if err != nil {
+		return nil, err
+	}
+	lt := t.Lookup(layout)
+	if lt == nil || lt.Tree == nil {
+		return nil, fmt.Errorf("html/template: layout %q is undefined", layout)
+	}
+	page := t.Lookup(name)
+	if page == nil || page.Tree == nil {
+		return nil, fmt.Errorf("html/template: %q is undefined", name)
+	}
+	// the tree is copied since it is escaped in place on execution
+	if _, err = t.AddParseTree(LayoutYield, page.Tree.Copy()); err != nil {
+		return nil, err
+	}
+	actual, _ := l.cache.LoadOrStore(key, lt)
+	return actual.(*template.Template), nil
+}
+
 type HTMLDebug struct {
 	sync.Once
 	Template        *template.Template
 	RefreshInterval time.Duration
+	// Layout is the default layout the pages are rendered within.
+	Layout string
 
 	Files   []string
 	FuncMap template.FuncMap
 	Delims  Delims
 
+	// mu protects Template and layouts which are replaced on reload
+	mu       sync.RWMutex
+	layouts  *htmlLayouts
 	reloadCh chan struct{}
 	watcher  *fsnotify.Watcher
 }
 
 func (h *HTMLDebug) Instance(name string, data interface{}) Render {
+	return h.InstanceWithLayout(h.Layout, name, data)
+}
+
+func (h *HTMLDebug) InstanceWithLayout(layout, name string, data interface{}) Render {
 	h.Do(func() {
+		h.layouts = newHTMLLayouts(h.Template)
 		h.startChecker()
 	})
 
@@ -133,11 +257,10 @@ func (h *HTMLDebug) Instance(name string, data interface{}) Render {
 	default:
 	}
 
-	return HTML{
-		Template: h.Template,
-		Name:     name,
-		Data:     data,
-	}
+	h.mu.RLock()
+	tmpl, layouts := h.Template, h.layouts
+	h.mu.RUnlock()
+	return newHTML(tmpl, layouts, layout, name, data)
 }
 
 func (h *HTMLDebug) Close() error {
@@ -148,10 +271,20 @@ func (h *HTMLDebug) Close() error {
 }
 
 func (h *HTMLDebug) reload() {
-	h.Template = template.Must(template.New("").
+	tmpl, err := template.New("").
 		Delims(h.Delims.Left, h.Delims.Right).
 		Funcs(h.FuncMap).
-		ParseFiles(h.Files...))
+		ParseFiles(h.Files...)
+	if err != nil {
+		// keep serving with the previous templates until the files are fixed
+		hlog.SystemLogger().Errorf("[HTMLDebug] reload HTML template failed: %v", err)
+		return
+	}
+	layouts := newHTMLLayouts(tmpl)
+
+	h.mu.Lock()
+	h.Template, h.layouts = tmpl, layouts
+	h.mu.Unlock()
 }
 
 func (h *HTMLDebug) startChecker() {
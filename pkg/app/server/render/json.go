@@ -52,12 +52,11 @@ import (
 // JSONMarshaler customize json.Marshal as you like
 type JSONMarshaler func(v interface{}) ([]byte, error)
 
+// jsonMarshalFunc replaces the json marshaler of pkg/common/json for rendering only if it isn't nil
 var jsonMarshalFunc JSONMarshaler
 
-func init() {
-	ResetJSONMarshal(hjson.Marshal)
-}
-
+// ResetJSONMarshal replaces the json marshaler for rendering only, use json.SetCodec of
+// pkg/common/json to replace it for binding as well.
 func ResetJSONMarshal(fn JSONMarshaler) {
 	jsonMarshalFunc = fn
 }
@@ -66,6 +65,33 @@ func ResetStdJSONMarshal() {
 	ResetJSONMarshal(json.Marshal)
 }
 
+// JSONUnmarshaler customize json.Unmarshal as you like
+type JSONUnmarshaler func(data []byte, v interface{}) error
+
+// SetJSONMarshaler replaces the json marshaler of pkg/common/json globally, which affects
+// ctx.JSON, ctx.IndentedJSON and the error rendering. Use json.SetCodec to replace both the
+// marshaler and the unmarshaler.
+// NOTE: It should be called before the server starts.
+func SetJSONMarshaler(fn JSONMarshaler) {
+	hjson.Marshal = fn
+}
+
+// SetJSONUnmarshaler replaces the json unmarshaler of pkg/common/json globally, which affects
+// binding.
+// NOTE: It should be called before the server starts.
+func SetJSONUnmarshaler(fn JSONUnmarshaler) {
+	hjson.Unmarshal = fn
+}
+
+// marshalJSON marshals v with the marshaler set by ResetJSONMarshal, or the one of
+// pkg/common/json, which is read on every call to follow json.SetCodec.
+func marshalJSON(v interface{}) ([]byte, error) {
+	if jsonMarshalFunc != nil {
+		return jsonMarshalFunc(v)
+	}
+	return hjson.Marshal(v)
+}
+
 // JSONRender JSON contains the given interface object.
 type JSONRender struct {
 	Data interface{}
@@ -76,7 +102,7 @@ var jsonContentType = "application/json; charset=utf-8"
 // Render (JSON) writes data with custom ContentType.
 func (r JSONRender) Render(resp *protocol.Response) error {
 	writeContentType(resp, jsonContentType)
-	jsonBytes, err := jsonMarshalFunc(r.Data)
+	jsonBytes, err := marshalJSON(r.Data)
 	if err != nil {
 		return err
 	}
@@ -122,7 +148,7 @@ type IndentedJSON struct {
 // Render (IndentedJSON) marshals the given interface object and writes it with custom ContentType.
 func (r IndentedJSON) Render(resp *protocol.Response) (err error) {
 	writeContentType(resp, jsonContentType)
-	jsonBytes, err := jsonMarshalFunc(r.Data)
+	jsonBytes, err := marshalJSON(r.Data)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,78 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package render
+
+import (
+	"hertz-study/pkg/protocol"
+)
+
+// JSONP contains the given interface object and the callback.
+type JSONP struct {
+	Callback string
+	Data     interface{}
+}
+
+var jsonpContentType = "application/javascript; charset=utf-8"
+
+// Render (JSONP) writes data wrapped by the callback with JavaScript ContentType,
+// data is written as JSON if the callback is empty or not a valid JavaScript identifier path.
+func (r JSONP) Render(resp *protocol.Response) error {
+	jsonBytes, err := marshalJSON(r.Data)
+	if err != nil {
+		return err
+	}
+	if !validCallback(r.Callback) {
+		writeContentType(resp, jsonContentType)
+		resp.AppendBody(jsonBytes)
+		return nil
+	}
+
+	writeContentType(resp, jsonpContentType)
+	// the leading comment prevents the response from being sniffed as other content, e.g. flash
+	resp.AppendBodyString("/**/")
+	resp.AppendBodyString(r.Callback)
+	resp.AppendBodyString("(")
+	resp.AppendBody(jsonBytes)
+	resp.AppendBodyString(");")
+	return nil
+}
+
+// WriteContentType (JSONP) writes JavaScript ContentType.
+func (r JSONP) WriteContentType(resp *protocol.Response) {
+	writeContentType(resp, jsonpContentType)
+}
+
+// validCallback reports whether s is like "cb", "$.cb" or "jQuery_1.cb", so that it can not
+// inject scripts.
+func validCallback(s string) bool {
+	if s == "" || len(s) > 128 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '_', c == '$':
+		case c >= '0' && c <= '9', c == '.':
+			if i == 0 || (c == '.' && s[i-1] == '.') {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return s[len(s)-1] != '.'
+}
@@ -0,0 +1,406 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package render
+
+import (
+	"encoding"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"hertz-study/pkg/protocol"
+)
+
+// MsgPackMarshaler customize msgpack.Marshal as you like
+type MsgPackMarshaler func(v interface{}) ([]byte, error)
+
+var msgPackMarshalFunc MsgPackMarshaler = marshalMsgPack
+
+// ResetMsgPackMarshal replaces the built-in MsgPack marshaler, e.g. with msgpack.Marshal of
+// github.com/vmihailenco/msgpack.
+//
+// The built-in one encodes the struct fields named by the msgpack tags, or the json tags if
+// absent, []byte as bin, time.Time as the timestamp extension, and respects the
+// MarshalMsgpack() ([]byte, error) method, encoding.BinaryMarshaler and encoding.TextMarshaler
+// in that order. The map keys are sorted if they are strings, so the output is stable.
+func ResetMsgPackMarshal(fn MsgPackMarshaler) {
+	msgPackMarshalFunc = fn
+}
+
+// MsgPack contains the given interface object.
+type MsgPack struct {
+	Data interface{}
+}
+
+var msgPackContentType = "application/msgpack"
+
+// Render (MsgPack) marshals the given interface object and writes data with custom ContentType.
+func (r MsgPack) Render(resp *protocol.Response) error {
+	writeContentType(resp, msgPackContentType)
+	msgPackBytes, err := msgPackMarshalFunc(r.Data)
+	if err != nil {
+		return err
+	}
+
+	resp.AppendBody(msgPackBytes)
+	return nil
+}
+
+// WriteContentType (MsgPack) writes MsgPack ContentType.
+func (r MsgPack) WriteContentType(resp *protocol.Response) {
+	writeContentType(resp, msgPackContentType)
+}
+
+// msgPackRawMarshaler is implemented by the types encoding themselves to MsgPack, it's the
+// same method as github.com/vmihailenco/msgpack uses.
+type msgPackRawMarshaler interface {
+	MarshalMsgpack() ([]byte, error)
+}
+
+// msgPackTimestampExt is the extension type of the timestamps.
+const msgPackTimestampExt = -1
+
+// maxMsgPackDepth bounds the nesting to fail on the cyclic data instead of overflowing the stack.
+const maxMsgPackDepth = 1000
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	rawMarshalerType    = reflect.TypeOf((*msgPackRawMarshaler)(nil)).Elem()
+	binaryMarshalerType = reflect.TypeOf((*encoding.BinaryMarshaler)(nil)).Elem()
+	msgPackStructFields sync.Map // map[reflect.Type][]msgPackField
+	errMsgPackTooDeep   = fmt.Errorf("msgpack: exceeded max depth %d, the data may be cyclic", maxMsgPackDepth)
+)
+
+type msgPackField struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+func marshalMsgPack(v interface{}) ([]byte, error) {
+	if v == nil {
+		return []byte{0xc0}, nil
+	}
+	return appendMsgPackValue(nil, reflect.ValueOf(v), 0)
+}
+
+func appendMsgPackValue(b []byte, v reflect.Value, depth int) ([]byte, error) {
+	if depth > maxMsgPackDepth {
+		return nil, errMsgPackTooDeep
+	}
+	if !v.IsValid() {
+		return append(b, 0xc0), nil
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+		if v.IsNil() {
+			return append(b, 0xc0), nil
+		}
+	}
+
+	if v.Type() == timeType {
+		return appendMsgPackTime(b, v.Interface().(time.Time)), nil
+	}
+	// the pointers are dereferenced first, the methods of the pointer receivers are
+	// found by marshalerOf as the elements are addressable
+	if v.Kind() != reflect.Interface && v.Kind() != reflect.Ptr {
+		if m, ok := marshalerOf(v, rawMarshalerType); ok {
+			data, err := m.(msgPackRawMarshaler).MarshalMsgpack()
+			if err != nil {
+				return nil, err
+			}
+			return append(b, data...), nil
+		}
+		if m, ok := marshalerOf(v, binaryMarshalerType); ok {
+			data, err := m.(encoding.BinaryMarshaler).MarshalBinary()
+			if err != nil {
+				return nil, err
+			}
+			return appendMsgPackBin(b, data), nil
+		}
+		if m, ok := marshalerOf(v, textMarshalerType); ok {
+			data, err := m.(encoding.TextMarshaler).MarshalText()
+			if err != nil {
+				return nil, err
+			}
+			return appendMsgPackString(b, string(data)), nil
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			return append(b, 0xc3), nil
+		}
+		return append(b, 0xc2), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return appendMsgPackInt(b, v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return appendMsgPackUint(b, v.Uint()), nil
+	case reflect.Float32:
+		return binary.BigEndian.AppendUint32(append(b, 0xca), math.Float32bits(float32(v.Float()))), nil
+	case reflect.Float64:
+		return binary.BigEndian.AppendUint64(append(b, 0xcb), math.Float64bits(v.Float())), nil
+	case reflect.String:
+		return appendMsgPackString(b, v.String()), nil
+	case reflect.Ptr, reflect.Interface:
+		return appendMsgPackValue(b, v.Elem(), depth+1)
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return appendMsgPackBytes(b, v), nil
+		}
+		var err error
+		b = appendMsgPackLen(b, v.Len(), 0x90, 0xdc)
+		for i := 0; i < v.Len(); i++ {
+			if b, err = appendMsgPackValue(b, v.Index(i), depth+1); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case reflect.Map:
+		return appendMsgPackMap(b, v, depth)
+	case reflect.Struct:
+		return appendMsgPackStruct(b, v, depth)
+	}
+	return nil, fmt.Errorf("msgpack: unsupported type %s", v.Type())
+}
+
+// marshalerOf returns v, or its address if the method is declared on the pointer receiver,
+// as the interface typ if it implements typ.
+func marshalerOf(v reflect.Value, typ reflect.Type) (interface{}, bool) {
+	if v.Type().Implements(typ) {
+		return v.Interface(), true
+	}
+	if v.CanAddr() && reflect.PtrTo(v.Type()).Implements(typ) {
+		return v.Addr().Interface(), true
+	}
+	return nil, false
+}
+
+func appendMsgPackMap(b []byte, v reflect.Value, depth int) ([]byte, error) {
+	keys := v.MapKeys()
+	if v.Type().Key().Kind() == reflect.String {
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].String() < keys[j].String()
+		})
+	}
+	var err error
+	b = appendMsgPackLen(b, len(keys), 0x80, 0xde)
+	for _, k := range keys {
+		if b, err = appendMsgPackValue(b, k, depth+1); err != nil {
+			return nil, err
+		}
+		if b, err = appendMsgPackValue(b, v.MapIndex(k), depth+1); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+func appendMsgPackStruct(b []byte, v reflect.Value, depth int) ([]byte, error) {
+	fields := msgPackFieldsOf(v.Type())
+	values := make([]reflect.Value, 0, len(fields))
+	names := make([]string, 0, len(fields))
+	for _, f := range fields {
+		fv, ok := fieldByIndex(v, f.index)
+		if !ok || (f.omitEmpty && fv.IsZero()) {
+			continue
+		}
+		values = append(values, fv)
+		names = append(names, f.name)
+	}
+	var err error
+	b = appendMsgPackLen(b, len(values), 0x80, 0xde)
+	for i, fv := range values {
+		b = appendMsgPackString(b, names[i])
+		if b, err = appendMsgPackValue(b, fv, depth+1); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// fieldByIndex is v.FieldByIndex which reports false instead of panicking on a nil embedded pointer.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+func msgPackFieldsOf(t reflect.Type) []msgPackField {
+	if fields, ok := msgPackStructFields.Load(t); ok {
+		return fields.([]msgPackField)
+	}
+	fields := appendMsgPackFields(nil, t, nil)
+	msgPackStructFields.Store(t, fields)
+	return fields
+}
+
+// appendMsgPackFields appends the encoded fields of the struct t, the fields of the untagged
+// embedded structs are promoted as encoding/json does, without resolving the name conflicts.
+func appendMsgPackFields(fields []msgPackField, t reflect.Type, index []int) []msgPackField {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, ok := sf.Tag.Lookup("msgpack")
+		if !ok {
+			tag = sf.Tag.Get("json")
+		}
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		fieldIndex := append(append([]int(nil), index...), i)
+
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if sf.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			fields = appendMsgPackFields(fields, ft, fieldIndex)
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		fields = append(fields, msgPackField{
+			name:      name,
+			index:     fieldIndex,
+			omitEmpty: strings.Contains(","+opts+",", ",omitempty,"),
+		})
+	}
+	return fields
+}
+
+// appendMsgPackTime appends t as the timestamp extension, in the 32-bit format if possible.
+func appendMsgPackTime(b []byte, t time.Time) []byte {
+	sec, nsec := t.Unix(), t.Nanosecond()
+	switch {
+	case sec >= 0 && sec <= math.MaxUint32 && nsec == 0:
+		b = append(b, 0xd6, byte(0xff&msgPackTimestampExt))
+		return binary.BigEndian.AppendUint32(b, uint32(sec))
+	case sec >= 0 && sec < 1<<34:
+		b = append(b, 0xd7, byte(0xff&msgPackTimestampExt))
+		return binary.BigEndian.AppendUint64(b, uint64(nsec)<<34|uint64(sec))
+	default:
+		b = append(b, 0xc7, 12, byte(0xff&msgPackTimestampExt))
+		b = binary.BigEndian.AppendUint32(b, uint32(nsec))
+		return binary.BigEndian.AppendUint64(b, uint64(sec))
+	}
+}
+
+func appendMsgPackBytes(b []byte, v reflect.Value) []byte {
+	if v.Kind() == reflect.Slice {
+		return appendMsgPackBin(b, v.Bytes())
+	}
+	b = appendMsgPackBinLen(b, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		b = append(b, byte(v.Index(i).Uint()))
+	}
+	return b
+}
+
+func appendMsgPackBin(b, data []byte) []byte {
+	return append(appendMsgPackBinLen(b, len(data)), data...)
+}
+
+func appendMsgPackBinLen(b []byte, l int) []byte {
+	switch {
+	case l <= math.MaxUint8:
+		return append(b, 0xc4, byte(l))
+	case l <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xc5), uint16(l))
+	default:
+		return binary.BigEndian.AppendUint32(append(b, 0xc6), uint32(l))
+	}
+}
+
+// appendMsgPackLen appends the header of an array or a map, fix is the fixarray or the fixmap
+// prefix, and the 32-bit prefix follows the 16-bit prefix.
+func appendMsgPackLen(b []byte, l int, fix, prefix16 byte) []byte {
+	switch {
+	case l < 16:
+		return append(b, fix|byte(l))
+	case l <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, prefix16), uint16(l))
+	default:
+		return binary.BigEndian.AppendUint32(append(b, prefix16+1), uint32(l))
+	}
+}
+
+func appendMsgPackString(b []byte, s string) []byte {
+	l := len(s)
+	switch {
+	case l < 32:
+		b = append(b, 0xa0|byte(l))
+	case l <= math.MaxUint8:
+		b = append(b, 0xd9, byte(l))
+	case l <= math.MaxUint16:
+		b = binary.BigEndian.AppendUint16(append(b, 0xda), uint16(l))
+	default:
+		b = binary.BigEndian.AppendUint32(append(b, 0xdb), uint32(l))
+	}
+	return append(b, s...)
+}
+
+func appendMsgPackUint(b []byte, u uint64) []byte {
+	if u <= math.MaxInt64 {
+		return appendMsgPackInt(b, int64(u))
+	}
+	return binary.BigEndian.AppendUint64(append(b, 0xcf), u)
+}
+
+func appendMsgPackInt(b []byte, i int64) []byte {
+	switch {
+	case i >= 0 && i <= math.MaxInt8:
+		// positive fixint
+		return append(b, byte(i))
+	case i < 0 && i >= -32:
+		// negative fixint
+		return append(b, byte(int8(i)))
+	case i >= 0 && i <= math.MaxUint8:
+		return append(b, 0xcc, byte(i))
+	case i >= 0 && i <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xcd), uint16(i))
+	case i >= 0 && i <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, 0xce), uint32(i))
+	case i >= math.MinInt8:
+		return append(b, 0xd0, byte(int8(i)))
+	case i >= math.MinInt16:
+		return binary.BigEndian.AppendUint16(append(b, 0xd1), uint16(int16(i)))
+	case i >= math.MinInt32:
+		return binary.BigEndian.AppendUint32(append(b, 0xd2), uint32(int32(i)))
+	case i >= 0:
+		return binary.BigEndian.AppendUint64(append(b, 0xcf), uint64(i))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(i))
+	}
+}
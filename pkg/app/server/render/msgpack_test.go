@@ -0,0 +1,109 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package render
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"hertz-study/pkg/common/test/assert"
+	"hertz-study/pkg/protocol"
+)
+
+type rawMsgPack struct{}
+
+func (rawMsgPack) MarshalMsgpack() ([]byte, error) {
+	return []byte{0xc3}, nil
+}
+
+type msgPackEmbedded struct {
+	E int `msgpack:"e"`
+}
+
+type msgPackStruct struct {
+	msgPackEmbedded
+	A    int    `msgpack:"a"`
+	B    string `json:"b,omitempty"`
+	C    []byte
+	Skip string `msgpack:"-"`
+	skip string
+}
+
+func TestMarshalMsgPack(t *testing.T) {
+	nsecTime := make([]byte, 0, 10)
+	nsecTime = append(nsecTime, 0xd7, 0xff)
+	nsecTime = binary.BigEndian.AppendUint64(nsecTime, 5<<34|1)
+
+	cases := []struct {
+		name string
+		v    interface{}
+		want []byte
+	}{
+		{"nil", nil, []byte{0xc0}},
+		{"bool", true, []byte{0xc3}},
+		{"fixint", 1, []byte{0x01}},
+		{"negative fixint", -1, []byte{0xff}},
+		{"int16", -200, []byte{0xd1, 0xff, 0x38}},
+		{"uint64", uint64(1<<64 - 1), []byte{0xcf, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{"float32", float32(1.5), []byte{0xca, 0x3f, 0xc0, 0x00, 0x00}},
+		{"float64", 1.5, []byte{0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0}},
+		{"string", "ab", []byte{0xa2, 'a', 'b'}},
+		{"bin", []byte{1, 2, 3}, []byte{0xc4, 0x03, 1, 2, 3}},
+		{"byte array", [2]byte{1, 2}, []byte{0xc4, 0x02, 1, 2}},
+		{"nil slice", []int(nil), []byte{0xc0}},
+		{"array", []interface{}{1, "a"}, []byte{0x92, 0x01, 0xa1, 'a'}},
+		{"sorted map", map[string]int{"b": 2, "a": 1}, []byte{0x82, 0xa1, 'a', 0x01, 0xa1, 'b', 0x02}},
+		{"timestamp32", time.Unix(1, 0), []byte{0xd6, 0xff, 0, 0, 0, 1}},
+		{"timestamp64", time.Unix(1, 5), nsecTime},
+		{"time pointer", func() *time.Time { t := time.Unix(1, 0); return &t }(), []byte{0xd6, 0xff, 0, 0, 0, 1}},
+		{"raw marshaler", rawMsgPack{}, []byte{0xc3}},
+		{"text marshaler", net.IPv4(127, 0, 0, 1), append([]byte{0xa9}, "127.0.0.1"...)},
+		{"struct", msgPackStruct{msgPackEmbedded: msgPackEmbedded{E: 3}, A: 1, C: []byte{7}, Skip: "x", skip: "y"}, []byte{
+			0x83,
+			0xa1, 'e', 0x03,
+			0xa1, 'a', 0x01,
+			0xa1, 'C', 0xc4, 0x01, 7,
+		}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			b, err := marshalMsgPack(c.v)
+			assert.Nil(t, err)
+			assert.DeepEqual(t, c.want, b)
+		})
+	}
+}
+
+type msgPackCycle struct {
+	Next *msgPackCycle
+}
+
+func TestMarshalMsgPackCycle(t *testing.T) {
+	c := &msgPackCycle{}
+	c.Next = c
+	_, err := marshalMsgPack(c)
+	assert.DeepEqual(t, errMsgPackTooDeep, err)
+}
+
+func TestMsgPackRender(t *testing.T) {
+	resp := &protocol.Response{}
+	assert.Nil(t, MsgPack{Data: map[string][]byte{"k": {1}}}.Render(resp))
+	assert.DeepEqual(t, msgPackContentType, string(resp.Header.ContentType()))
+	assert.DeepEqual(t, []byte{0x81, 0xa1, 'k', 0xc4, 0x01, 1}, resp.Body())
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package render
+
+import (
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"hertz-study/pkg/protocol/consts"
+)
+
+// Negotiator creates the Render of data for a negotiated content type, it returns nil if data
+// can not be rendered in the content type, e.g. data is not a proto.Message for protobuf.
+type Negotiator func(data interface{}) Render
+
+var (
+	negotiators     = make(map[string]Negotiator)
+	negotiatedTypes []string
+)
+
+func init() {
+	jsonNegotiator := func(data interface{}) Render { return JSONRender{Data: data} }
+	xmlNegotiator := func(data interface{}) Render { return XML{Data: data} }
+	yamlNegotiator := func(data interface{}) Render { return YAML{Data: data} }
+	msgPackNegotiator := func(data interface{}) Render { return MsgPack{Data: data} }
+
+	RegisterNegotiator(consts.MIMEApplicationJSON, jsonNegotiator)
+	RegisterNegotiator(consts.MIMEApplicationXML, xmlNegotiator)
+	RegisterNegotiator(consts.MIMETextXML, xmlNegotiator)
+	RegisterNegotiator(consts.MIMEApplicationYAML, yamlNegotiator)
+	RegisterNegotiator(consts.MIMEApplicationXYAML, yamlNegotiator)
+	RegisterNegotiator(consts.MIMETextYAML, yamlNegotiator)
+	RegisterNegotiator(consts.MIMEApplicationMsgPack, msgPackNegotiator)
+	RegisterNegotiator(consts.MIMEApplicationXMsgPack, msgPackNegotiator)
+	RegisterNegotiator(consts.MIMEApplicationHTMLForm, func(data interface{}) Render { return Form{Data: data} })
+	RegisterNegotiator(consts.MIMEPROTOBUF, func(data interface{}) Render {
+		if _, ok := data.(proto.Message); !ok {
+			return nil
+		}
+		return ProtoBuf{Data: data}
+	})
+}
+
+// RegisterNegotiator registers the Negotiator of the content type (e.g. "text/csv") used by
+// RequestContext.Negotiate, the registered one of the same content type is replaced.
+// NOTE: It should be called before the server starts as it's not concurrent safe.
+func RegisterNegotiator(contentType string, fn Negotiator) {
+	contentType = strings.ToLower(contentType)
+	if _, ok := negotiators[contentType]; !ok {
+		negotiatedTypes = append(negotiatedTypes, contentType)
+	}
+	negotiators[contentType] = fn
+}
+
+// NegotiatedTypes returns the content types with a registered Negotiator in the order of registration.
+func NegotiatedTypes() []string {
+	return append([]string(nil), negotiatedTypes...)
+}
+
+// Negotiate returns the Render of data for the content type, or nil if there is no registered
+// Negotiator of the content type or data can not be rendered in the content type.
+func Negotiate(contentType string, data interface{}) Render {
+	fn, ok := negotiators[strings.ToLower(contentType)]
+	if !ok {
+		return nil
+	}
+	return fn(data)
+}
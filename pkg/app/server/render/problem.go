@@ -0,0 +1,84 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package render
+
+import (
+	"hertz-study/pkg/protocol"
+)
+
+var problemJSONContentType = "application/problem+json; charset=utf-8"
+
+// Problem is the problem details of RFC 7807 describing an error of the http api.
+type Problem struct {
+	// Type is a URI reference identifying the problem type, "about:blank" if empty.
+	Type string
+	// Title is a short summary of the problem type, e.g. "Not Found".
+	Title string
+	// Status is the http status code.
+	Status int
+	// Detail is the explanation specific to this occurrence of the problem.
+	Detail string
+	// Instance is a URI reference identifying this occurrence of the problem.
+	Instance string
+	// Extensions are the additional members, which can't override the members above.
+	Extensions map[string]interface{}
+}
+
+// MarshalJSON flattens the extensions into the members of the problem object.
+func (p Problem) MarshalJSON() ([]byte, error) {
+	m := make(map[string]interface{}, len(p.Extensions)+5)
+	for k, v := range p.Extensions {
+		m[k] = v
+	}
+	if p.Type != "" {
+		m["type"] = p.Type
+	}
+	if p.Title != "" {
+		m["title"] = p.Title
+	}
+	if p.Status != 0 {
+		m["status"] = p.Status
+	}
+	if p.Detail != "" {
+		m["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		m["instance"] = p.Instance
+	}
+	return marshalJSON(m)
+}
+
+// ProblemJSON renders the Problem as application/problem+json.
+type ProblemJSON struct {
+	Data Problem
+}
+
+// Render (ProblemJSON) writes the problem with the application/problem+json ContentType.
+func (r ProblemJSON) Render(resp *protocol.Response) error {
+	writeContentType(resp, problemJSONContentType)
+	b, err := r.Data.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	resp.AppendBody(b)
+	return nil
+}
+
+// WriteContentType (ProblemJSON) writes application/problem+json ContentType.
+func (r ProblemJSON) WriteContentType(resp *protocol.Response) {
+	writeContentType(resp, problemJSONContentType)
+}
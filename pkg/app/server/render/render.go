@@ -41,7 +41,11 @@
 
 package render
 
-import "hertz-study/pkg/protocol"
+import (
+	"io"
+
+	"hertz-study/pkg/protocol"
+)
 
 // Render interface is to be implemented by JSON, XML, HTML, YAML and so on.
 type Render interface {
@@ -52,10 +56,30 @@ type Render interface {
 	WriteContentType(resp *protocol.Response)
 }
 
+// StreamRender is implemented by the Render which is able to write the content to an io.Writer
+// directly, so that the content can be streamed without being buffered entirely, see ctx.RenderStream.
+type StreamRender interface {
+	Render
+	// RenderTo writes the content to w.
+	RenderTo(w io.Writer) error
+}
+
 var (
 	_ Render = JSONRender{}
 	_ Render = String{}
 	_ Render = Data{}
+	_ Render = JSONP{}
+	_ Render = YAML{}
+	_ Render = MsgPack{}
+	_ Render = HTML{}
+	_ Render = Form{}
+
+	_ StreamRender = HTML{}
+	_ StreamRender = htmlError{}
+	_ StreamRender = TextTemplate{}
+
+	_ HTMLLayoutRender = HTMLProduction{}
+	_ HTMLLayoutRender = (*HTMLDebug)(nil)
 )
 
 func writeContentType(resp *protocol.Response, value string) {
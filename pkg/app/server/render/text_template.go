@@ -0,0 +1,56 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package render
+
+import (
+	"io"
+	"text/template"
+
+	"hertz-study/pkg/protocol"
+)
+
+// TextTemplate contains the text/template reference and its name with given interface object,
+// e.g. to render CSV reports. The ContentType is "text/plain; charset=utf-8" if empty.
+type TextTemplate struct {
+	ContentType string
+	Template    *template.Template
+	Name        string
+	Data        interface{}
+}
+
+// Render (TextTemplate) executes template and writes its result with custom ContentType for response.
+func (r TextTemplate) Render(resp *protocol.Response) error {
+	r.WriteContentType(resp)
+	return r.RenderTo(resp.BodyWriter())
+}
+
+// RenderTo (TextTemplate) executes template and writes its result to w.
+func (r TextTemplate) RenderTo(w io.Writer) error {
+	if r.Name == "" {
+		return r.Template.Execute(w, r.Data)
+	}
+	return r.Template.ExecuteTemplate(w, r.Name, r.Data)
+}
+
+// WriteContentType (TextTemplate) writes custom ContentType.
+func (r TextTemplate) WriteContentType(resp *protocol.Response) {
+	if r.ContentType == "" {
+		writeContentType(resp, plainContentType)
+		return
+	}
+	writeContentType(resp, r.ContentType)
+}
@@ -0,0 +1,57 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package render
+
+import (
+	"gopkg.in/yaml.v3"
+	"hertz-study/pkg/protocol"
+)
+
+// YAMLMarshaler customize yaml.Marshal as you like
+type YAMLMarshaler func(v interface{}) ([]byte, error)
+
+var yamlMarshalFunc YAMLMarshaler = yaml.Marshal
+
+// ResetYAMLMarshal replaces the YAML marshaler, default is yaml.Marshal of gopkg.in/yaml.v3,
+// which respects the yaml tags and the yaml.Marshaler implementations.
+func ResetYAMLMarshal(fn YAMLMarshaler) {
+	yamlMarshalFunc = fn
+}
+
+// YAML contains the given interface object.
+type YAML struct {
+	Data interface{}
+}
+
+var yamlContentType = "application/yaml; charset=utf-8"
+
+// Render (YAML) marshals the given interface object and writes data with custom ContentType.
+func (r YAML) Render(resp *protocol.Response) error {
+	writeContentType(resp, yamlContentType)
+	yamlBytes, err := yamlMarshalFunc(r.Data)
+	if err != nil {
+		return err
+	}
+
+	resp.AppendBody(yamlBytes)
+	return nil
+}
+
+// WriteContentType (YAML) writes YAML ContentType.
+func (r YAML) WriteContentType(resp *protocol.Response) {
+	writeContentType(resp, yamlContentType)
+}
@@ -0,0 +1,59 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package render
+
+import (
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	"hertz-study/pkg/common/test/assert"
+	"hertz-study/pkg/protocol"
+)
+
+type yamlLevel int
+
+func (l yamlLevel) MarshalYAML() (interface{}, error) {
+	return []string{"debug", "info"}[l], nil
+}
+
+type yamlStruct struct {
+	Name    string    `yaml:"name"`
+	Data    []byte    `yaml:"data"`
+	Created time.Time `yaml:"created"`
+	Level   yamlLevel `yaml:"level"`
+}
+
+func TestYAMLRender(t *testing.T) {
+	created := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+	resp := &protocol.Response{}
+	err := YAML{Data: yamlStruct{Name: "hertz", Data: []byte{0xff, 0x00}, Created: created, Level: 1}}.Render(resp)
+	assert.Nil(t, err)
+	assert.DeepEqual(t, yamlContentType, string(resp.Header.ContentType()))
+	assert.DeepEqual(t, "name: hertz\ndata:\n    - 255\n    - 0\ncreated: 2022-01-02T03:04:05Z\nlevel: info\n", string(resp.Body()))
+
+	var got struct {
+		Name    string    `yaml:"name"`
+		Data    []byte    `yaml:"data"`
+		Created time.Time `yaml:"created"`
+		Level   string    `yaml:"level"`
+	}
+	assert.Nil(t, yaml.Unmarshal(resp.Body(), &got))
+	assert.DeepEqual(t, []byte{0xff, 0x00}, got.Data)
+	assert.True(t, created.Equal(got.Created))
+	assert.DeepEqual(t, "info", got.Level)
+}
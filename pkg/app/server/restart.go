@@ -0,0 +1,126 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"hertz-study/pkg/common/config"
+	"hertz-study/pkg/common/hlog"
+	"hertz-study/pkg/network"
+)
+
+// restartListenerFDEnv passes the fd of the inherited listener to the new process.
+const restartListenerFDEnv = "HERTZ_RESTART_LISTENER_FD"
+
+// inheritedFD is the fd of the inherited listener in the new process,
+// which is the first one after stdin, stdout and stderr.
+const inheritedFD = 3
+
+// restartListener returns the listener inherited from the old process if there is one,
+// otherwise it listens on the address of the options.
+func restartListener(opt *config.Options) (net.Listener, error) {
+	if v := os.Getenv(restartListenerFDEnv); v != "" {
+		// avoid passing it to the processes started by the application
+		os.Unsetenv(restartListenerFDEnv) //nolint:errcheck
+		fd, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s=%s", restartListenerFDEnv, v)
+		}
+		f := os.NewFile(uintptr(fd), "listener")
+		defer f.Close()
+		ln, err := net.FileListener(f)
+		if err != nil {
+			return nil, fmt.Errorf("inherit listener: %w", err)
+		}
+		hlog.SystemLogger().Infof("Inherited listener on address=%s from the old process", ln.Addr().String())
+		return ln, nil
+	}
+
+	return network.Listen(opt.ListenConfig, opt.Network, opt.Addr, opt.UnixSocketPerm)
+}
+
+// activatedListener returns the first listener passed by systemd socket activation,
+// the others are closed since a hertz instance serves on one listener only.
+func activatedListener() (net.Listener, error) {
+	lns, err := network.ActivatedListeners()
+	if err != nil {
+		return nil, err
+	}
+	if len(lns) == 0 {
+		return nil, nil
+	}
+	for _, ln := range lns[1:] {
+		hlog.SystemLogger().Warnf("Ignored activated listener on address=%s", ln.Addr().String())
+		ln.Close()
+	}
+	hlog.SystemLogger().Infof("Activated listener on address=%s by systemd", lns[0].Addr().String())
+	return lns[0], nil
+}
+
+// restart starts a new process inheriting the listener, the old process should shut down
+// gracefully after it returns nil.
+func (h *Hertz) restart() error {
+	ctx := context.Background()
+	for _, hook := range h.OnPreRestart {
+		if err := hook(ctx); err != nil {
+			return fmt.Errorf("pre restart hook: %w", err)
+		}
+	}
+
+	ln, ok := h.GetOptions().Listener.(interface{ File() (*os.File, error) })
+	if !ok {
+		return fmt.Errorf("listener %T does not support restart", h.GetOptions().Listener)
+	}
+	f, err := ln.File()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	env := make([]string, 0, len(os.Environ())+1)
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, restartListenerFDEnv+"=") {
+			env = append(env, kv)
+		}
+	}
+	env = append(env, restartListenerFDEnv+"="+strconv.Itoa(inheritedFD))
+
+	p, err := os.StartProcess(exe, os.Args, &os.ProcAttr{
+		Env:   env,
+		Files: []*os.File{os.Stdin, os.Stdout, os.Stderr, f},
+	})
+	if err != nil {
+		return err
+	}
+	hlog.SystemLogger().Infof("Started new process pid=%d for graceful restart", p.Pid)
+	p.Release() //nolint:errcheck
+
+	for _, hook := range h.OnPostRestart {
+		hook(ctx)
+	}
+	return nil
+}
@@ -14,12 +14,9 @@
  * limitations under the License.
  */
 
-package dialer
+package server
 
-import (
-	"hertz-study/pkg/network/standard"
-)
+import "os"
 
-func init() {
-	defaultDialer = standard.NewDialer()
-}
+// graceful restart is not supported on windows
+var restartSignals []os.Signal
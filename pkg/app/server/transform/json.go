@@ -0,0 +1,127 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transform
+
+import (
+	"context"
+	"io"
+	"sort"
+
+	"hertz-study/pkg/app"
+	"hertz-study/pkg/common/json"
+)
+
+// InjectJSON returns the Transformer adding the fields returned by fields to the top-level object
+// of the JSON body, e.g. the request id. The bodies which are not JSON objects are left unchanged.
+// The fields are added in front, so the existing fields of the same names take precedence for the
+// decoders keeping the last one of the duplicated names.
+func InjectJSON(fields func(c context.Context, ctx *app.RequestContext) map[string]interface{}) Transformer {
+	return Func(func(c context.Context, ctx *app.RequestContext, w io.Writer) io.WriteCloser {
+		m := fields(c, ctx)
+		if len(m) == 0 {
+			return nil
+		}
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		var injected []byte
+		for _, k := range keys {
+			key, err := json.Marshal(k)
+			if err != nil {
+				return nil
+			}
+			value, err := json.Marshal(m[k])
+			if err != nil {
+				return nil
+			}
+			if len(injected) > 0 {
+				injected = append(injected, ',')
+			}
+			injected = append(injected, key...)
+			injected = append(injected, ':')
+			injected = append(injected, value...)
+		}
+		return &injectWriter{w: w, injected: injected}
+	})
+}
+
+const (
+	injectBeforeObject = iota
+	injectInObject
+	injectDone
+)
+
+type injectWriter struct {
+	w        io.Writer
+	injected []byte
+	state    int
+}
+
+func (iw *injectWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 && iw.state != injectDone {
+		i := 0
+		for i < len(p) && isJSONSpace(p[i]) {
+			i++
+		}
+		if i == len(p) {
+			break
+		}
+		switch iw.state {
+		case injectBeforeObject:
+			if p[i] != '{' {
+				iw.state = injectDone
+				break
+			}
+			if _, err := iw.w.Write(p[:i+1]); err != nil {
+				return 0, err
+			}
+			if _, err := iw.w.Write(iw.injected); err != nil {
+				return 0, err
+			}
+			p = p[i+1:]
+			iw.state = injectInObject
+			continue
+		case injectInObject:
+			if _, err := iw.w.Write(p[:i]); err != nil {
+				return 0, err
+			}
+			if p[i] != '}' {
+				if _, err := iw.w.Write([]byte{','}); err != nil {
+					return 0, err
+				}
+			}
+			p = p[i:]
+			iw.state = injectDone
+		}
+	}
+	if _, err := iw.w.Write(p); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+func (iw *injectWriter) Close() error {
+	return nil
+}
+
+func isJSONSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
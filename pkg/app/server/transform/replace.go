@@ -0,0 +1,123 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transform
+
+import (
+	"bytes"
+	"context"
+	"io"
+
+	"hertz-study/pkg/app"
+)
+
+// NewReplacer returns the Transformer replacing the old strings with the new ones in the body like
+// strings.NewReplacer, e.g. to rewrite the links of the proxied HTML pages. At most the length of
+// the longest old string is buffered. It panics if given an odd number of oldnew arguments.
+func NewReplacer(oldnew ...string) Transformer {
+	if len(oldnew)%2 == 1 {
+		panic("transform.NewReplacer: odd argument count")
+	}
+	var olds, news [][]byte
+	keep := 0
+	for i := 0; i < len(oldnew); i += 2 {
+		if oldnew[i] == "" {
+			continue
+		}
+		olds = append(olds, []byte(oldnew[i]))
+		news = append(news, []byte(oldnew[i+1]))
+		if len(oldnew[i])-1 > keep {
+			keep = len(oldnew[i]) - 1
+		}
+	}
+	return Func(func(c context.Context, ctx *app.RequestContext, w io.Writer) io.WriteCloser {
+		if len(olds) == 0 {
+			return nil
+		}
+		return &replaceWriter{w: w, olds: olds, news: news, keep: keep, next: make([]int, len(olds))}
+	})
+}
+
+type replaceWriter struct {
+	w          io.Writer
+	olds, news [][]byte
+	// keep is the count of the trailing bytes which may be the beginning of an old string
+	keep    int
+	pending []byte
+	next    []int
+}
+
+func (rw *replaceWriter) Write(p []byte) (int, error) {
+	rw.pending = append(rw.pending, p...)
+	if err := rw.replace(false); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (rw *replaceWriter) Close() error {
+	return rw.replace(true)
+}
+
+// replace writes the pending bytes with the old strings replaced, except
+// the trailing ones which may be the beginning of an old string.
+func (rw *replaceWriter) replace(final bool) error {
+	b := rw.pending
+	for i, old := range rw.olds {
+		rw.next[i] = bytes.Index(b, old)
+	}
+	pos := 0
+	for {
+		k := -1
+		for i, n := range rw.next {
+			if n >= 0 && (k < 0 || n < rw.next[k]) {
+				k = i
+			}
+		}
+		if k < 0 {
+			break
+		}
+		if _, err := rw.w.Write(b[pos:rw.next[k]]); err != nil {
+			return err
+		}
+		if _, err := rw.w.Write(rw.news[k]); err != nil {
+			return err
+		}
+		pos = rw.next[k] + len(rw.olds[k])
+		// search again the old strings overlapping the replaced one
+		for i, n := range rw.next {
+			if n >= 0 && n < pos {
+				if j := bytes.Index(b[pos:], rw.olds[i]); j >= 0 {
+					rw.next[i] = pos + j
+				} else {
+					rw.next[i] = -1
+				}
+			}
+		}
+	}
+
+	end := len(b)
+	if !final && end-rw.keep > pos {
+		end -= rw.keep
+	} else if !final {
+		end = pos
+	}
+	if _, err := rw.w.Write(b[pos:end]); err != nil {
+		return err
+	}
+	rw.pending = append(rw.pending[:0], b[end:]...)
+	return nil
+}
@@ -0,0 +1,217 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package transform provides the response rewriting stage of the engine. The transformers are
+// registered by content type and rewrite the response body in a streaming way with bounded
+// buffering, e.g. rewriting the links of the proxied HTML pages or injecting fields to JSON, so
+// the middlewares do not have to buffer and rewrite the body one by one.
+package transform
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+
+	"hertz-study/internal/bytesconv"
+	"hertz-study/pkg/app"
+	"hertz-study/pkg/common/bytebufferpool"
+	"hertz-study/pkg/common/cachecontrol"
+	"hertz-study/pkg/protocol/consts"
+)
+
+// Transformer rewrites the response bodies of the content types it is registered for.
+type Transformer interface {
+	// Transform returns the writer which writes the transformed body written to it to w, it is
+	// closed after the whole body is written to flush the buffered content. It returns nil to
+	// leave the body unchanged, e.g. depending on the request.
+	Transform(c context.Context, ctx *app.RequestContext, w io.Writer) io.WriteCloser
+}
+
+// Func is an adapter to allow the use of ordinary functions as Transformer.
+type Func func(c context.Context, ctx *app.RequestContext, w io.Writer) io.WriteCloser
+
+// Transform calls f(c, ctx, w).
+func (f Func) Transform(c context.Context, ctx *app.RequestContext, w io.Writer) io.WriteCloser {
+	return f(c, ctx, w)
+}
+
+type entry struct {
+	contentType string
+	transformer Transformer
+}
+
+// Pipeline is the list of transformers by content type, it is not safe to register
+// the transformers concurrently with Apply.
+type Pipeline struct {
+	entries []entry
+}
+
+// Register adds the transformers for the content type, which is a media type like "text/html",
+// or a wildcard like "text/*" and "*/*". The transformers matching a response are applied in
+// the registration order.
+func (p *Pipeline) Register(contentType string, transformers ...Transformer) {
+	contentType = strings.ToLower(strings.TrimSpace(contentType))
+	for _, t := range transformers {
+		p.entries = append(p.entries, entry{contentType: contentType, transformer: t})
+	}
+}
+
+// Len returns the count of the registered transformers.
+func (p *Pipeline) Len() int {
+	return len(p.entries)
+}
+
+// Apply transforms the response body of ctx by the transformers matching its content type. The
+// body stream is transformed while it is read, and the responses already written to the client,
+// e.g. by ctx.Flush, or encoded, e.g. gzipped, are left unchanged. The ETag becomes weak since
+// the body is no longer the same byte by byte.
+func (p *Pipeline) Apply(c context.Context, ctx *app.RequestContext) error {
+	if len(p.entries) == 0 || ctx.Response.GetHijackWriter() != nil || ctx.IsHead() || ctx.Response.MustSkipBody() {
+		return nil
+	}
+	if ce := ctx.Response.Header.ContentEncoding(); len(ce) > 0 && !bytes.EqualFold(ce, []byte("identity")) {
+		return nil
+	}
+	var matched []Transformer
+	mediaType := mediaTypeOf(ctx.Response.Header.ContentType())
+	for _, e := range p.entries {
+		if matchContentType(e.contentType, mediaType) {
+			matched = append(matched, e.transformer)
+		}
+	}
+	if len(matched) == 0 {
+		return nil
+	}
+
+	if ctx.Response.IsBodyStream() {
+		r := &transformReader{src: ctx.Response.BodyStream(), buf: make([]byte, 4096)}
+		if r.chain = newChain(c, ctx, matched, &r.out); r.chain == nil {
+			return nil
+		}
+		// the original stream is closed by transformReader
+		ctx.Response.SetBodyStreamNoReset(r, -1)
+		weakenETag(ctx)
+		return nil
+	}
+
+	out := bytebufferpool.Get()
+	defer bytebufferpool.Put(out)
+	ch := newChain(c, ctx, matched, out)
+	if ch == nil {
+		return nil
+	}
+	if _, err := ch[0].Write(ctx.Response.BodyBytes()); err != nil {
+		return err
+	}
+	if err := ch.close(); err != nil {
+		return err
+	}
+	ctx.Response.SetBody(out.B)
+	weakenETag(ctx)
+	return nil
+}
+
+// chain is the writers of the transformers, the body is written to the first one.
+type chain []io.WriteCloser
+
+func newChain(c context.Context, ctx *app.RequestContext, transformers []Transformer, w io.Writer) chain {
+	var ch chain
+	for i := len(transformers) - 1; i >= 0; i-- {
+		if tw := transformers[i].Transform(c, ctx, w); tw != nil {
+			ch = append(ch, tw)
+			w = tw
+		}
+	}
+	// reverse to the order of writing
+	for i, j := 0, len(ch)-1; i < j; i, j = i+1, j-1 {
+		ch[i], ch[j] = ch[j], ch[i]
+	}
+	return ch
+}
+
+// close closes the writers in the order of writing, so every writer flushes to the next one.
+func (ch chain) close() error {
+	for _, w := range ch {
+		if err := w.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// transformReader transforms the body stream chunk by chunk while it is read.
+type transformReader struct {
+	src   io.Reader
+	chain chain
+	buf   []byte
+	out   bytes.Buffer
+	err   error
+}
+
+func (r *transformReader) Read(p []byte) (int, error) {
+	for r.out.Len() == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		n, err := r.src.Read(r.buf)
+		if n > 0 {
+			if _, werr := r.chain[0].Write(r.buf[:n]); werr != nil {
+				r.err = werr
+				continue
+			}
+		}
+		if err == io.EOF {
+			if r.err = r.chain.close(); r.err == nil {
+				r.err = io.EOF
+			}
+		} else if err != nil {
+			r.err = err
+		}
+	}
+	return r.out.Read(p)
+}
+
+func (r *transformReader) Close() error {
+	if c, ok := r.src.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+func mediaTypeOf(contentType []byte) string {
+	if i := bytes.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	return strings.ToLower(strings.TrimSpace(bytesconv.B2s(contentType)))
+}
+
+func matchContentType(pattern, mediaType string) bool {
+	if pattern == "*/*" || pattern == mediaType {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(pattern, "/*"); ok {
+		typ, _, _ := strings.Cut(mediaType, "/")
+		return typ == prefix
+	}
+	return false
+}
+
+func weakenETag(ctx *app.RequestContext) {
+	if etag := ctx.Response.Header.Get(consts.HeaderETag); etag != "" {
+		ctx.Response.Header.Set(consts.HeaderETag, cachecontrol.Weak(etag))
+	}
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package adaptor
+
+import (
+	"io"
+	"net/http"
+
+	"hertz-study/pkg/protocol/consts"
+	"hertz-study/pkg/route"
+)
+
+// HTTPHandler converts a hertz engine to a net/http handler, which is useful to serve the
+// hertz routes by net/http servers, e.g. httptest.Server, or behind net/http middlewares
+// during the migration.
+//
+// The request body is read entirely before the handlers run, the response is written after
+// they return. The connection related functions of app.RequestContext are not supported,
+// e.g. RemoteAddr returns a zero address and Hijack fails.
+func HTTPHandler(engine *route.Engine) http.Handler {
+	return &httpHandler{engine: engine}
+}
+
+type httpHandler struct {
+	engine *route.Engine
+}
+
+func (h *httpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	maxBodySize := int64(h.engine.GetOptions().MaxRequestBodySize)
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize+1))
+	if err != nil {
+		http.Error(w, err.Error(), consts.StatusBadRequest)
+		return
+	}
+	if int64(len(body)) > maxBodySize {
+		http.Error(w, "request body too large", consts.StatusRequestEntityTooLarge)
+		return
+	}
+
+	ctx := h.engine.NewContext()
+	req := &ctx.Request
+	uri := r.RequestURI
+	if uri == "" {
+		uri = r.URL.RequestURI()
+	}
+	req.Header.SetRequestURI(uri)
+	req.Header.SetHost(r.Host)
+	req.Header.SetMethod(r.Method)
+	req.Header.SetProtocol(r.Proto)
+	for k, v := range r.Header {
+		for _, vv := range v {
+			req.Header.Add(k, vv)
+		}
+	}
+	req.SetIsTLS(r.TLS != nil)
+	req.SetBody(body)
+	req.Header.SetContentLength(len(body))
+
+	h.engine.ServeHTTP(r.Context(), ctx)
+
+	resp := &ctx.Response
+	header := w.Header()
+	resp.Header.VisitAll(func(k, v []byte) {
+		switch string(k) {
+		case consts.HeaderContentLength, consts.HeaderConnection:
+			// set by net/http
+			return
+		}
+		header.Add(string(k), string(v))
+	})
+	w.WriteHeader(resp.StatusCode())
+	if resp.IsBodyStream() {
+		io.Copy(w, resp.BodyStream()) //nolint:errcheck
+		resp.CloseBodyStream()        //nolint:errcheck
+		return
+	}
+	w.Write(resp.Body()) //nolint:errcheck
+}
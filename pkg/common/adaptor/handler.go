@@ -0,0 +1,42 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package adaptor
+
+import (
+	"context"
+	"net/http"
+
+	"hertz-study/pkg/app"
+	"hertz-study/pkg/protocol/consts"
+)
+
+// HertzHandler converts a net/http handler to a hertz handler, which is useful to reuse
+// the handlers of the standard library, e.g. net/http/pprof and expvar.
+//
+// It is based on GetCompatRequest and GetCompatResponseWriter, so only the basic
+// functions of http.Request and http.ResponseWriter are supported.
+func HertzHandler(h http.Handler) app.HandlerFunc {
+	return func(c context.Context, ctx *app.RequestContext) {
+		req, err := GetCompatRequest(&ctx.Request)
+		if err != nil {
+			ctx.AbortWithError(consts.StatusInternalServerError, err) //nolint:errcheck
+			return
+		}
+		req.RemoteAddr = ctx.RemoteAddr().String()
+		h.ServeHTTP(GetCompatResponseWriter(&ctx.Response), req.WithContext(c))
+	}
+}
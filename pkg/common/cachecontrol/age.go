@@ -0,0 +1,50 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cachecontrol
+
+import (
+	"strconv"
+	"time"
+)
+
+// ParseAge parses the value of the Age header, it returns false if the value is invalid.
+func ParseAge(header string) (time.Duration, bool) {
+	return parseSeconds(header)
+}
+
+// FormatAge formats the age as the value of the Age header.
+func FormatAge(age time.Duration) string {
+	if age < 0 {
+		age = 0
+	}
+	return strconv.FormatInt(int64(age/time.Second), 10)
+}
+
+// Age returns the current age of a cached response as RFC 9111 section 4.2.3 calculates, date is
+// the value of its Date header, age is the value of its Age header, requestTime and responseTime
+// are when the request was sent and the response was received by the cache.
+func Age(date time.Time, age time.Duration, requestTime, responseTime, now time.Time) time.Duration {
+	apparentAge := responseTime.Sub(date)
+	if apparentAge < 0 || date.IsZero() {
+		apparentAge = 0
+	}
+	correctedAge := age + responseTime.Sub(requestTime)
+	if correctedAge < apparentAge {
+		correctedAge = apparentAge
+	}
+	return correctedAge + now.Sub(responseTime)
+}
@@ -0,0 +1,214 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package cachecontrol provides the HTTP caching utilities shared by the static file serving,
+// the cache middlewares and the client: parsing and formatting of the Cache-Control directives,
+// ETag generation and matching, and the Age calculation of RFC 9111.
+package cachecontrol
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Names of the common directives.
+const (
+	MaxAge               = "max-age"
+	SMaxAge              = "s-maxage"
+	MaxStale             = "max-stale"
+	MinFresh             = "min-fresh"
+	NoCache              = "no-cache"
+	NoStore              = "no-store"
+	NoTransform          = "no-transform"
+	OnlyIfCached         = "only-if-cached"
+	MustRevalidate       = "must-revalidate"
+	ProxyRevalidate      = "proxy-revalidate"
+	MustUnderstand       = "must-understand"
+	Private              = "private"
+	Public               = "public"
+	Immutable            = "immutable"
+	StaleWhileRevalidate = "stale-while-revalidate"
+	StaleIfError         = "stale-if-error"
+)
+
+// Directive is a directive of the Cache-Control header, Value is empty if it has no argument.
+type Directive struct {
+	Name  string
+	Value string
+}
+
+// Directives is the ordered list of the directives of a Cache-Control header.
+type Directives []Directive
+
+// Parse parses the Cache-Control header value, e.g. `max-age=60, no-cache="Set-Cookie"`.
+// The names are lower-cased and the quoted values are unquoted, the malformed directives are skipped.
+func Parse(header string) Directives {
+	var d Directives
+	for len(header) > 0 {
+		var part string
+		part, header = nextDirective(header)
+		name, value, _ := strings.Cut(part, "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+			if v, err := strconv.Unquote(value); err == nil {
+				value = v
+			} else {
+				value = value[1 : len(value)-1]
+			}
+		}
+		d = append(d, Directive{Name: name, Value: value})
+	}
+	return d
+}
+
+// nextDirective splits s at the first comma which is not quoted.
+func nextDirective(s string) (string, string) {
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if quoted {
+				i++
+			}
+		case '"':
+			quoted = !quoted
+		case ',':
+			if !quoted {
+				return s[:i], s[i+1:]
+			}
+		}
+	}
+	return s, ""
+}
+
+// String formats the directives as the Cache-Control header value.
+func (d Directives) String() string {
+	var b strings.Builder
+	for i, v := range d {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(v.Name)
+		if v.Value == "" {
+			continue
+		}
+		b.WriteByte('=')
+		if isToken(v.Value) {
+			b.WriteString(v.Value)
+		} else {
+			b.WriteString(strconv.Quote(v.Value))
+		}
+	}
+	return b.String()
+}
+
+// Has reports whether the directive is present.
+func (d Directives) Has(name string) bool {
+	_, ok := d.Get(name)
+	return ok
+}
+
+// Get returns the value of the directive and whether it is present.
+func (d Directives) Get(name string) (string, bool) {
+	for _, v := range d {
+		if v.Name == name {
+			return v.Value, true
+		}
+	}
+	return "", false
+}
+
+// Seconds returns the value of the directive in delta-seconds, e.g. max-age, and whether it is present
+// and valid. The values overflowing are capped as RFC 9111 requires.
+func (d Directives) Seconds(name string) (time.Duration, bool) {
+	v, ok := d.Get(name)
+	if !ok {
+		return 0, false
+	}
+	return parseSeconds(v)
+}
+
+// TTL returns the freshness lifetime set by the directives, s-maxage takes precedence
+// over max-age for the shared caches. It returns false if neither is present.
+func (d Directives) TTL(shared bool) (time.Duration, bool) {
+	if shared {
+		if ttl, ok := d.Seconds(SMaxAge); ok {
+			return ttl, true
+		}
+	}
+	return d.Seconds(MaxAge)
+}
+
+// Set sets the directive, replacing the existing one if any.
+func (d *Directives) Set(name, value string) {
+	for i, v := range *d {
+		if v.Name == name {
+			(*d)[i].Value = value
+			return
+		}
+	}
+	*d = append(*d, Directive{Name: name, Value: value})
+}
+
+// SetSeconds sets the directive to the value in delta-seconds, e.g. SetSeconds(MaxAge, time.Minute).
+func (d *Directives) SetSeconds(name string, v time.Duration) {
+	d.Set(name, strconv.FormatInt(int64(v/time.Second), 10))
+}
+
+// Del deletes the directive.
+func (d *Directives) Del(name string) {
+	s := (*d)[:0]
+	for _, v := range *d {
+		if v.Name != name {
+			s = append(s, v)
+		}
+	}
+	*d = s
+}
+
+// maxSeconds is the value the overflowing delta-seconds are capped to, see RFC 9111 section 1.2.2.
+const maxSeconds = 1<<31 - 1
+
+func parseSeconds(s string) (time.Duration, bool) {
+	if s == "" {
+		return 0, false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return 0, false
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n > maxSeconds {
+		n = maxSeconds
+	}
+	return time.Duration(n) * time.Second, true
+}
+
+func isToken(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c <= ' ' || c >= 0x7f || strings.IndexByte(`"(),/:;<=>?@[\]{}`, c) >= 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,107 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cachecontrol
+
+import (
+	"hash/fnv"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HashETag returns the strong ETag generated from the hash of the content,
+// which changes whenever the content changes.
+func HashETag(content []byte) string {
+	h := fnv.New64a()
+	h.Write(content) //nolint:errcheck
+	return formatETag(h.Sum64())
+}
+
+// HashETagReader is HashETag of the content read from r.
+func HashETagReader(r io.Reader) (string, error) {
+	h := fnv.New64a()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return formatETag(h.Sum64()), nil
+}
+
+// ModTimeETag returns the strong ETag generated from the modification time and the size,
+// which is cheap but changes whenever the file is modified even if the content is the same.
+func ModTimeETag(modTime time.Time, size int64) string {
+	b := append([]byte(nil), '"')
+	b = strconv.AppendInt(b, modTime.UnixNano(), 16)
+	b = append(b, '-')
+	b = strconv.AppendInt(b, size, 16)
+	return string(append(b, '"'))
+}
+
+// WeakModTimeETag returns the weak ETag generated from the modification time in seconds and the size,
+// e.g. for the representations which are semantically equivalent but may differ byte by byte.
+func WeakModTimeETag(modTime time.Time, size int64) string {
+	return Weak(ModTimeETag(modTime.Truncate(time.Second), size))
+}
+
+// Weak returns the weak form of the ETag.
+func Weak(etag string) string {
+	if IsWeak(etag) {
+		return etag
+	}
+	return "W/" + etag
+}
+
+// IsWeak reports whether the ETag is weak.
+func IsWeak(etag string) bool {
+	return strings.HasPrefix(etag, "W/")
+}
+
+// MatchETag reports whether etag matches any of the comma-separated list in header, or "*", e.g. the
+// value of If-None-Match. Weak comparison ignores the "W/" prefixes, while strong comparison never
+// matches weak ETags.
+func MatchETag(header, etag string, weak bool) bool {
+	if IsWeak(etag) {
+		if !weak {
+			return false
+		}
+		etag = etag[2:]
+	}
+	for header != "" {
+		var v string
+		v, header, _ = strings.Cut(header, ",")
+		v = strings.TrimSpace(v)
+		if v == "*" {
+			return true
+		}
+		if IsWeak(v) {
+			if !weak {
+				continue
+			}
+			v = v[2:]
+		}
+		if v == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func formatETag(sum uint64) string {
+	b := append([]byte(nil), '"')
+	b = strconv.AppendUint(b, sum, 16)
+	return string(append(b, '"'))
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"crypto/tls"
+	"io"
 	"time"
 
 	"hertz-study/pkg/app/client/retry"
@@ -72,6 +73,12 @@ type ClientOptions struct {
 	// By default request write timeout is unlimited.
 	WriteTimeout time.Duration
 
+	// Maximum duration of the whole request, including dialing, writing the request
+	// and reading the response, for the requests without their own request timeout.
+	//
+	// By default request timeout is unlimited.
+	RequestTimeout time.Duration
+
 	// Maximum response body size.
 	//
 	// The client returns ErrBodyTooLarge if this limit is greater than 0
@@ -116,6 +123,13 @@ type ClientOptions struct {
 	// Callback hook for re-configuring host client
 	// If an error is returned, the request will be terminated.
 	HostClientConfigHook func(hc interface{}) error
+
+	// HostMapping overrides the addresses to dial for the hosts like a hosts file,
+	// the key is "host" or "host:port", the value is the list of "ip" or "ip:port".
+	HostMapping map[string][]string
+
+	// RequestDumper receives the requests and responses as they are on the wire.
+	RequestDumper io.Writer
 }
 
 func NewClientOptions(opts []ClientOption) *ClientOptions {
@@ -4,8 +4,10 @@ import (
 	"context"
 	"crypto/tls"
 	"net"
+	"os"
 	"time"
 
+	"hertz-study/pkg/app/client/retry"
 	"hertz-study/pkg/app/server/registry"
 	"hertz-study/pkg/network"
 )
@@ -17,14 +19,15 @@ type Option struct {
 }
 
 const (
-	defaultKeepAliveTimeout   = 1 * time.Minute
-	defaultReadTimeout        = 3 * time.Minute
-	defaultAddr               = ":8888"
-	defaultNetwork            = "tcp"
-	defaultBasePath           = "/"
-	defaultMaxRequestBodySize = 4 * 1024 * 1024
-	defaultWaitExitTimeout    = time.Second * 5
-	defaultReadBufferSize     = 4 * 1024
+	defaultKeepAliveTimeout    = 1 * time.Minute
+	defaultReadTimeout         = 3 * time.Minute
+	defaultAddr                = ":8888"
+	defaultNetwork             = "tcp"
+	defaultBasePath            = "/"
+	defaultMaxRequestBodySize  = 4 * 1024 * 1024
+	defaultWaitExitTimeout     = time.Second * 5
+	defaultReadBufferSize      = 4 * 1024
+	defaultStartupCheckTimeout = time.Second * 30
 )
 
 type Options struct {
@@ -52,6 +55,7 @@ type Options struct {
 	Addr                         string
 	BasePath                     string
 	ExitWaitTimeout              time.Duration
+	StartupCheckTimeout          time.Duration
 	TLS                          *tls.Config
 	H2C                          bool
 	ReadBufferSize               int
@@ -59,10 +63,41 @@ type Options struct {
 	Tracers                      []interface{}
 	TraceLevel                   interface{}
 	ListenConfig                 *net.ListenConfig
+	Listener                     net.Listener
+	GracefulRestart              bool
+	ReloadOnSIGHUP               bool
+	UnixSocketPerm               os.FileMode
+	SocketActivation             bool
+	MaxConcurrentConnections     int
+	MaxRequestsPerConnection     int
+	ConnectionThrottle           int
+	ConnectionThrottleBurst      int
 	BindConfig                   interface{}
 	ValidateConfig               interface{}
+	MultipartFormConfig          interface{}
 	CustomBinder                 interface{}
 	CustomValidator              interface{}
+	MemoryBallast                int64
+	GCPercent                    *int
+	MemoryLimit                  int64
+	MemoryLimitRatio             float64
+	AutoConfig                   bool
+	StreamRenderBufferSize       int
+	TrustedProxies               []string
+	RemoteIPHeaders              []string
+	ProxyProtocol                bool
+	StreamWriteTimeout           time.Duration
+	WriteStallThreshold          time.Duration
+	OnWriteStall                 func(stall network.WriteStall)
+	RouteSharding                bool
+	PanicReporter                network.PanicReporter
+	OnShutdownProgress           func(remaining int)
+	ReadHeaderTimeout            time.Duration
+	MaxHeaderBytes               int
+	MaxHeaderCount               int
+	SenseFile                    bool
+	DisablePool                  bool
+	ReuseParamValues             bool
 
 	// TransporterNewer is the function to create a transporter.
 	TransporterNewer    func(opt *Options) network.Transporter
@@ -77,10 +112,21 @@ type Options struct {
 	OnAccept  func(conn net.Conn) context.Context
 	OnConnect func(ctx context.Context, conn network.Conn) context.Context
 
+	// OnConnectionRejected is called when a connection is rejected by the limits of
+	// MaxConcurrentConnections or ConnectionThrottle.
+	OnConnectionRejected func(reason network.RejectReason)
+
 	// Registry is used for service registry.
 	Registry registry.Registry
 	// RegistryInfo is base info used for service registry.
 	RegistryInfo *registry.Info
+	// RegistryRetry is how to retry the failed registration, nil means no retry.
+	RegistryRetry *retry.Config
+	// RegistryReadiness gates the registration, which waits until it returns nil.
+	RegistryReadiness func(ctx context.Context) error
+	// RegistrySlowStart is how long the registered weight ramps up to the weight of RegistryInfo,
+	// 0 means the full weight is registered at once.
+	RegistrySlowStart time.Duration
 	// Enable automatically HTML template reloading mechanism.
 
 	AutoReloadRender bool
@@ -220,6 +266,9 @@ func NewOptions(opts []Option) *Options {
 		// graceful shutdown wait time
 		ExitWaitTimeout: defaultWaitExitTimeout,
 
+		// timeout of every startup check
+		StartupCheckTimeout: defaultStartupCheckTimeout,
+
 		// tls config
 		TLS: nil,
 
@@ -7,6 +7,9 @@ var preDefinedOpts []RequestOption
 type RequestOptions struct {
 	tags map[string]string
 	isSD bool
+	addr string
+	// replayable marks a non-idempotent request safe to retry
+	replayable bool
 
 	dialTimeout  time.Duration
 	readTimeout  time.Duration
@@ -49,6 +52,16 @@ func WithSD(b bool) RequestOption {
 	}}
 }
 
+// WithAddr sends the request to addr ("ip:port" or "host:port") instead of the host of
+// the request URI, the Host header and the TLS server name are kept as the host of the URI.
+//
+// It is useful for canary targeting or testing against the staging instances.
+func WithAddr(addr string) RequestOption {
+	return RequestOption{F: func(o *RequestOptions) {
+		o.addr = addr
+	}}
+}
+
 // WithDialTimeout sets dial timeout.
 //
 // This is the request level configuration. It has a higher
@@ -93,6 +106,15 @@ func WithRequestTimeout(t time.Duration) RequestOption {
 	}}
 }
 
+// WithReplayable marks the request safe to retry even if its method is not idempotent,
+// e.g. a POST request carrying an idempotency key. Only the idempotent requests are
+// retried by default.
+func WithReplayable(b bool) RequestOption {
+	return RequestOption{F: func(o *RequestOptions) {
+		o.replayable = b
+	}}
+}
+
 func (o *RequestOptions) Apply(opts []RequestOption) {
 	for _, op := range opts {
 		op.F(o)
@@ -111,6 +133,14 @@ func (o *RequestOptions) IsSD() bool {
 	return o.isSD
 }
 
+func (o *RequestOptions) Addr() string {
+	return o.addr
+}
+
+func (o *RequestOptions) Replayable() bool {
+	return o.replayable
+}
+
 func (o *RequestOptions) DialTimeout() time.Duration {
 	return o.dialTimeout
 }
@@ -150,6 +180,8 @@ func (o *RequestOptions) CopyTo(dst *RequestOptions) {
 	}
 
 	dst.isSD = o.isSD
+	dst.addr = o.addr
+	dst.replayable = o.replayable
 	dst.readTimeout = o.readTimeout
 	dst.writeTimeout = o.writeTimeout
 	dst.dialTimeout = o.dialTimeout
@@ -0,0 +1,155 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+	"time"
+
+	"hertz-study/pkg/app/server/registry"
+	"hertz-study/pkg/network"
+)
+
+// Severity is the severity of an OptionIssue.
+type Severity int
+
+const (
+	// SeverityWarning means the option is ignored or behaves unexpectedly, e.g. the connections
+	// are closed immediately on shutdown because ExitWaitTimeout is zero.
+	SeverityWarning Severity = iota
+	// SeverityError means the server misbehaves with the options, e.g. HTTP/2 is advertised by
+	// the TLS config but can't be served.
+	SeverityError
+)
+
+func (s Severity) String() string {
+	if s == SeverityError {
+		return "error"
+	}
+	return "warning"
+}
+
+// OptionIssue is an invalid value or a conflict of the options found by Options.Validate.
+type OptionIssue struct {
+	Severity Severity
+	// Fields are the names of the involved fields of Options, e.g. "ALPN" and "TLS".
+	Fields  []string
+	Message string
+}
+
+func (i OptionIssue) String() string {
+	return fmt.Sprintf("[%s] %s: %s", i.Severity, strings.Join(i.Fields, "+"), i.Message)
+}
+
+// OptionsError is the error of the options with the issues of SeverityError.
+type OptionsError struct {
+	Issues []OptionIssue
+}
+
+func (e *OptionsError) Error() string {
+	msgs := make([]string, 0, len(e.Issues))
+	for _, i := range e.Issues {
+		msgs = append(msgs, i.String())
+	}
+	return "invalid options: " + strings.Join(msgs, "; ")
+}
+
+// IssuesError returns an *OptionsError of the issues of SeverityError, nil if there is none.
+func IssuesError(issues []OptionIssue) error {
+	var errs []OptionIssue
+	for _, i := range issues {
+		if i.Severity == SeverityError {
+			errs = append(errs, i)
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return &OptionsError{Issues: errs}
+}
+
+// Validate checks the values and the combinations of the options, and returns all the issues
+// found in the order of the checks.
+func (o *Options) Validate() []OptionIssue {
+	var issues []OptionIssue
+	add := func(s Severity, msg string, fields ...string) {
+		issues = append(issues, OptionIssue{Severity: s, Fields: fields, Message: msg})
+	}
+
+	for _, d := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"KeepAliveTimeout", o.KeepAliveTimeout},
+		{"ReadTimeout", o.ReadTimeout},
+		{"WriteTimeout", o.WriteTimeout},
+		{"IdleTimeout", o.IdleTimeout},
+		{"ExitWaitTimeout", o.ExitWaitTimeout},
+		{"StreamWriteTimeout", o.StreamWriteTimeout},
+		{"ReadHeaderTimeout", o.ReadHeaderTimeout},
+	} {
+		if d.value < 0 {
+			add(SeverityError, "must not be negative", d.name)
+		}
+	}
+	if o.ExitWaitTimeout == 0 {
+		add(SeverityWarning, "the connections are closed without waiting on graceful shutdown", "ExitWaitTimeout")
+	}
+
+	if o.TLS != nil && !o.ALPN {
+		for _, proto := range o.TLS.NextProtos {
+			if proto == "h2" {
+				add(SeverityError, "h2 is advertised by TLS.NextProtos but ALPN is disabled, HTTP/2 clients are served by HTTP/1.1", "TLS", "ALPN")
+				break
+			}
+		}
+	}
+	if o.ALPN && o.TLS == nil {
+		add(SeverityWarning, "ALPN is ignored without TLS", "ALPN", "TLS")
+	}
+	if o.TLS != nil && transporterName(o.TransporterNewer) == "netpoll" {
+		add(SeverityError, "netpoll doesn't support TLS, the connections would be served in plain text", "TLS", "TransporterNewer")
+	}
+
+	if o.MemoryLimitRatio < 0 || o.MemoryLimitRatio > 1 {
+		add(SeverityError, "must be in [0, 1]", "MemoryLimitRatio")
+	}
+	if o.ConnectionThrottleBurst > 0 && o.ConnectionThrottle <= 0 {
+		add(SeverityWarning, "the burst is ignored without the rate", "ConnectionThrottleBurst", "ConnectionThrottle")
+	}
+	if o.WriteStallThreshold > 0 && o.OnWriteStall == nil {
+		add(SeverityWarning, "the threshold is ignored without the observer", "WriteStallThreshold", "OnWriteStall")
+	}
+	if o.SocketActivation && o.Listener != nil {
+		add(SeverityWarning, "the activated socket is ignored since the listener is set", "SocketActivation", "Listener")
+	}
+	if o.AutoReloadInterval > 0 && !o.AutoReloadRender {
+		add(SeverityWarning, "the interval is ignored since the auto reload is disabled", "AutoReloadInterval", "AutoReloadRender")
+	}
+
+	if o.Registry != nil && o.Registry != registry.NoopRegistry && o.RegistryInfo == nil {
+		add(SeverityError, "the registry info is required by the registry", "RegistryInfo", "Registry")
+	}
+	if o.RegistryInfo != nil && (o.Registry == nil || o.Registry == registry.NoopRegistry) {
+		add(SeverityWarning, "the registry info is ignored without a registry", "RegistryInfo", "Registry")
+	}
+	if o.RegistrySlowStart > 0 && (o.Registry == nil || o.Registry == registry.NoopRegistry) {
+		add(SeverityWarning, "the slow start is ignored without a registry", "RegistrySlowStart", "Registry")
+	}
+	return issues
+}
+
+// transporterName returns the package name of the transporter created by newer, e.g. "netpoll".
+func transporterName(newer func(opt *Options) network.Transporter) string {
+	if newer == nil {
+		return ""
+	}
+	fn := runtime.FuncForPC(reflect.ValueOf(newer).Pointer())
+	if fn == nil {
+		return ""
+	}
+	name := fn.Name()
+	name = name[strings.LastIndex(name, "/")+1:]
+	return strings.Split(name, ".")[0]
+}
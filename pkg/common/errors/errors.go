@@ -61,7 +61,12 @@ var (
 	ErrConnectionClosed   = errors.New("connection closed")
 	ErrNotSupportProtocol = errors.New("not support protocol")
 	ErrNoMultipartForm    = errors.New("request has no multipart/form-data Content-Type")
+	ErrFileTooLarge       = errors.New("multipart file size exceeds the given limit")
+	ErrTooManyFiles       = errors.New("multipart file count exceeds the given limit")
 	ErrBadPoolConn        = errors.New("connection is closed by peer while being in the connection pool")
+	ErrHeaderTooLarge     = errors.New("request header size exceeds the given limit")
+	ErrTooManyHeaders     = errors.New("request header count exceeds the given limit")
+	ErrSendFileNotSupport = errors.New("sendfile is not supported by the connection")
 )
 
 // ErrorType is an unsigned 64-bit error code as defined in the hertz spec.
@@ -0,0 +1,211 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hlog
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+const defaultAsyncQueueSize = 4096
+
+// ErrAsyncWriterClosed is returned by AsyncWriter.Write after the writer is closed.
+var ErrAsyncWriterClosed = errors.New("hlog: async writer is closed")
+
+// AsyncPolicy decides what AsyncWriter does when its queue is full.
+type AsyncPolicy int
+
+const (
+	// AsyncDrop drops the entry and counts it in AsyncWriter.Dropped, the caller is never blocked.
+	AsyncDrop AsyncPolicy = iota
+	// AsyncBlock blocks the caller until there is room in the queue, no entry is lost.
+	AsyncBlock
+)
+
+type asyncOptions struct {
+	queueSize int
+	policy    AsyncPolicy
+	onError   func(err error)
+}
+
+// AsyncOption is the option of NewAsyncWriter.
+type AsyncOption func(o *asyncOptions)
+
+// WithQueueSize sets the max count of entries buffered in the queue, default is 4096.
+func WithQueueSize(size int) AsyncOption {
+	return func(o *asyncOptions) {
+		o.queueSize = size
+	}
+}
+
+// WithAsyncPolicy sets what to do when the queue is full, default is AsyncDrop.
+func WithAsyncPolicy(policy AsyncPolicy) AsyncOption {
+	return func(o *asyncOptions) {
+		o.policy = policy
+	}
+}
+
+// WithAsyncErrorHandler sets the function called with the errors of the underlying writer,
+// the errors are ignored by default.
+func WithAsyncErrorHandler(f func(err error)) AsyncOption {
+	return func(o *asyncOptions) {
+		o.onError = f
+	}
+}
+
+type asyncItem struct {
+	p     []byte
+	flush chan struct{}
+}
+
+// AsyncWriter is a log sink which queues the entries and writes them to the underlying
+// writer in a background goroutine, so that slow disk or network sinks don't add latency
+// to the callers. Use it as the output of a logger, e.g. SetOutput(NewAsyncWriter(rf)), and
+// register Shutdown as an OnShutdown hook of the server to flush the queue before exiting.
+//
+// It is safe for concurrent use.
+type AsyncWriter struct {
+	w       io.Writer
+	policy  AsyncPolicy
+	onError func(err error)
+
+	mu      sync.RWMutex
+	closed  bool
+	queue   chan asyncItem
+	done    chan struct{}
+	dropped uint64
+}
+
+// NewAsyncWriter creates an AsyncWriter writing to w and starts its background goroutine.
+func NewAsyncWriter(w io.Writer, opts ...AsyncOption) *AsyncWriter {
+	o := &asyncOptions{
+		queueSize: defaultAsyncQueueSize,
+		policy:    AsyncDrop,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.queueSize <= 0 {
+		o.queueSize = defaultAsyncQueueSize
+	}
+	aw := &AsyncWriter{
+		w:       w,
+		policy:  o.policy,
+		onError: o.onError,
+		queue:   make(chan asyncItem, o.queueSize),
+		done:    make(chan struct{}),
+	}
+	go aw.run()
+	return aw
+}
+
+// Write implements io.Writer. p is copied since loggers may reuse it after Write returns.
+func (aw *AsyncWriter) Write(p []byte) (n int, err error) {
+	aw.mu.RLock()
+	defer aw.mu.RUnlock()
+	if aw.closed {
+		return 0, ErrAsyncWriterClosed
+	}
+	item := asyncItem{p: append([]byte(nil), p...)}
+	if aw.policy == AsyncBlock {
+		aw.queue <- item
+		return len(p), nil
+	}
+	select {
+	case aw.queue <- item:
+	default:
+		atomic.AddUint64(&aw.dropped, 1)
+	}
+	return len(p), nil
+}
+
+// Dropped returns the count of entries dropped because the queue was full.
+func (aw *AsyncWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&aw.dropped)
+}
+
+// Flush waits until all the entries queued before it are written, or ctx is done.
+func (aw *AsyncWriter) Flush(ctx context.Context) error {
+	aw.mu.RLock()
+	if aw.closed {
+		aw.mu.RUnlock()
+		return ErrAsyncWriterClosed
+	}
+	flush := make(chan struct{})
+	select {
+	case aw.queue <- asyncItem{flush: flush}:
+		aw.mu.RUnlock()
+	case <-ctx.Done():
+		aw.mu.RUnlock()
+		return ctx.Err()
+	}
+	select {
+	case <-flush:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops accepting entries, writes the queued ones and closes the underlying writer
+// if it implements io.Closer.
+func (aw *AsyncWriter) Close() error {
+	aw.mu.Lock()
+	if aw.closed {
+		aw.mu.Unlock()
+		return nil
+	}
+	aw.closed = true
+	close(aw.queue)
+	aw.mu.Unlock()
+
+	<-aw.done
+	if c, ok := aw.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// Shutdown flushes the queue until ctx is done, its signature matches the OnShutdown
+// hooks of the server, e.g. h.OnShutdown = append(h.OnShutdown, aw.Shutdown).
+func (aw *AsyncWriter) Shutdown(ctx context.Context) {
+	aw.Flush(ctx) //nolint:errcheck
+}
+
+func (aw *AsyncWriter) run() {
+	defer close(aw.done)
+	for item := range aw.queue {
+		if item.flush != nil {
+			if f, ok := aw.w.(interface{ Sync() error }); ok {
+				aw.handleError(f.Sync())
+			}
+			close(item.flush)
+			continue
+		}
+		_, err := aw.w.Write(item.p)
+		aw.handleError(err)
+	}
+}
+
+func (aw *AsyncWriter) handleError(err error) {
+	if err != nil && aw.onError != nil {
+		aw.onError(err)
+	}
+}
@@ -0,0 +1,226 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hlog
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// ErrAuditFieldMissing is returned by AuditLogger.Log when a mandatory field of the event is empty.
+var ErrAuditFieldMissing = errors.New("hlog: audit field missing")
+
+// AuditEvent is an auditable action. Actor, Action, Resource and Outcome are mandatory.
+type AuditEvent struct {
+	// Actor is who performs the action, e.g. the user id.
+	Actor string
+	// Action is what is performed, e.g. "order.cancel".
+	Action string
+	// Resource is what the action is performed on, e.g. "order/42".
+	Resource string
+	// Outcome is the result of the action, e.g. "success", "denied" or the status code.
+	Outcome string
+	// Fields are the additional details of the event.
+	Fields Fields
+}
+
+// AuditRecord is an AuditEvent as it is written to the sink, one JSON object per line.
+type AuditRecord struct {
+	Seq      uint64    `json:"seq"`
+	Time     time.Time `json:"time"`
+	Actor    string    `json:"actor"`
+	Action   string    `json:"action"`
+	Resource string    `json:"resource"`
+	Outcome  string    `json:"outcome"`
+	Fields   Fields    `json:"fields,omitempty"`
+	// PrevHash and Hash are only set when the hash chain is enabled, Hash is the hex
+	// sha256 of the record encoded with an empty Hash, which covers PrevHash.
+	PrevHash string `json:"prev_hash,omitempty"`
+	Hash     string `json:"hash,omitempty"`
+}
+
+type auditOptions struct {
+	requiredFields []string
+	hashChain      bool
+	seq            uint64
+	prevHash       string
+}
+
+// AuditOption is the option of NewAuditLogger.
+type AuditOption func(o *auditOptions)
+
+// WithAuditRequiredFields makes keys of AuditEvent.Fields mandatory in addition to
+// Actor, Action, Resource and Outcome.
+func WithAuditRequiredFields(keys ...string) AuditOption {
+	return func(o *auditOptions) {
+		o.requiredFields = keys
+	}
+}
+
+// WithAuditHashChain enables chaining every record to the previous one by hash, so that
+// modifying, removing or reordering records can be detected by VerifyAuditChain.
+func WithAuditHashChain(enable bool) AuditOption {
+	return func(o *auditOptions) {
+		o.hashChain = enable
+	}
+}
+
+// WithAuditChainState continues the sequence and the hash chain from the last record
+// written before, e.g. by the previous process writing to the same sink.
+func WithAuditChainState(lastSeq uint64, lastHash string) AuditOption {
+	return func(o *auditOptions) {
+		o.seq = lastSeq
+		o.prevHash = lastHash
+	}
+}
+
+// AuditLogger writes audit records to a dedicated sink, separate from the application logs.
+// Every record carries a sequence number increasing by one, so gaps reveal removed records.
+//
+// It is safe for concurrent use.
+type AuditLogger struct {
+	opts auditOptions
+
+	mu       sync.Mutex
+	w        io.Writer
+	seq      uint64
+	prevHash string
+}
+
+// NewAuditLogger creates an AuditLogger writing to w, which is usually a *RotateFile.
+// Wrapping w by an AsyncWriter with the AsyncDrop policy is not recommended since records
+// may be lost silently.
+func NewAuditLogger(w io.Writer, opts ...AuditOption) *AuditLogger {
+	o := auditOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &AuditLogger{opts: o, w: w, seq: o.seq, prevHash: o.prevHash}
+}
+
+// Log validates e and writes it as a record, the record is returned on success.
+func (al *AuditLogger) Log(e *AuditEvent) (*AuditRecord, error) {
+	if err := al.validate(e); err != nil {
+		return nil, err
+	}
+
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	r := &AuditRecord{
+		Seq:      al.seq + 1,
+		Time:     time.Now(),
+		Actor:    e.Actor,
+		Action:   e.Action,
+		Resource: e.Resource,
+		Outcome:  e.Outcome,
+		Fields:   e.Fields,
+	}
+	if al.opts.hashChain {
+		r.PrevHash = al.prevHash
+		h, err := hashAuditRecord(r)
+		if err != nil {
+			return nil, err
+		}
+		r.Hash = h
+	}
+	b, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = al.w.Write(append(b, '\n')); err != nil {
+		return nil, err
+	}
+	// the sequence only moves forward once the record is written
+	al.seq = r.Seq
+	al.prevHash = r.Hash
+	return r, nil
+}
+
+func (al *AuditLogger) validate(e *AuditEvent) error {
+	switch {
+	case e.Actor == "":
+		return fmt.Errorf("%w: actor", ErrAuditFieldMissing)
+	case e.Action == "":
+		return fmt.Errorf("%w: action", ErrAuditFieldMissing)
+	case e.Resource == "":
+		return fmt.Errorf("%w: resource", ErrAuditFieldMissing)
+	case e.Outcome == "":
+		return fmt.Errorf("%w: outcome", ErrAuditFieldMissing)
+	}
+	for _, k := range al.opts.requiredFields {
+		if _, ok := e.Fields[k]; !ok {
+			return fmt.Errorf("%w: %s", ErrAuditFieldMissing, k)
+		}
+	}
+	return nil
+}
+
+func hashAuditRecord(r *AuditRecord) (string, error) {
+	cp := *r
+	cp.Hash = ""
+	b, err := json.Marshal(&cp)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// VerifyAuditChain checks that records are consecutive and correctly chained by hash,
+// it returns the error of the first broken record. The records must be decoded from
+// a sink written with WithAuditHashChain enabled.
+func VerifyAuditChain(records []*AuditRecord) error {
+	for i, r := range records {
+		h, err := hashAuditRecord(r)
+		if err != nil {
+			return err
+		}
+		if h != r.Hash {
+			return fmt.Errorf("hlog: audit record seq=%d is modified", r.Seq)
+		}
+		if i == 0 {
+			continue
+		}
+		if prev := records[i-1]; r.Seq != prev.Seq+1 || r.PrevHash != prev.Hash {
+			return fmt.Errorf("hlog: audit chain is broken between seq=%d and seq=%d", prev.Seq, r.Seq)
+		}
+	}
+	return nil
+}
+
+type auditCtxKey struct{}
+
+// NewAuditContext returns a copy of ctx which carries the audit logger al.
+func NewAuditContext(ctx context.Context, al *AuditLogger) context.Context {
+	return context.WithValue(ctx, auditCtxKey{}, al)
+}
+
+// AuditFromContext returns the audit logger carried by ctx, or nil if there is none.
+func AuditFromContext(ctx context.Context) *AuditLogger {
+	if ctx == nil {
+		return nil
+	}
+	al, _ := ctx.Value(auditCtxKey{}).(*AuditLogger)
+	return al
+}
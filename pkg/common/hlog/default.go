@@ -22,6 +22,7 @@ import (
 	"io"
 	"log"
 	"os"
+	"sync/atomic"
 )
 
 // Fatal calls the default logger's Fatal method and then os.Exit(1).
@@ -94,44 +95,58 @@ func Tracef(format string, v ...interface{}) {
 	logger.Tracef(format, v...)
 }
 
-// CtxFatalf calls the default logger's CtxFatalf method and then os.Exit(1).
+// CtxFatalf calls the CtxFatalf method of the logger carried by ctx, or the default logger
+// if there is none, and then os.Exit(1). The fields carried by ctx are attached to the entry.
 func CtxFatalf(ctx context.Context, format string, v ...interface{}) {
-	logger.CtxFatalf(ctx, format, v...)
+	l, format, v := contextLogger(ctx, format, v)
+	l.CtxFatalf(ctx, format, v...)
 }
 
-// CtxErrorf calls the default logger's CtxErrorf method.
+// CtxErrorf calls the CtxErrorf method of the logger carried by ctx, or the default logger if there is none.
+// The fields carried by ctx are attached to the entry.
 func CtxErrorf(ctx context.Context, format string, v ...interface{}) {
-	logger.CtxErrorf(ctx, format, v...)
+	l, format, v := contextLogger(ctx, format, v)
+	l.CtxErrorf(ctx, format, v...)
 }
 
-// CtxWarnf calls the default logger's CtxWarnf method.
+// CtxWarnf calls the CtxWarnf method of the logger carried by ctx, or the default logger if there is none.
+// The fields carried by ctx are attached to the entry.
 func CtxWarnf(ctx context.Context, format string, v ...interface{}) {
-	logger.CtxWarnf(ctx, format, v...)
+	l, format, v := contextLogger(ctx, format, v)
+	l.CtxWarnf(ctx, format, v...)
 }
 
-// CtxNoticef calls the default logger's CtxNoticef method.
+// CtxNoticef calls the CtxNoticef method of the logger carried by ctx, or the default logger if there is none.
+// The fields carried by ctx are attached to the entry.
 func CtxNoticef(ctx context.Context, format string, v ...interface{}) {
-	logger.CtxNoticef(ctx, format, v...)
+	l, format, v := contextLogger(ctx, format, v)
+	l.CtxNoticef(ctx, format, v...)
 }
 
-// CtxInfof calls the default logger's CtxInfof method.
+// CtxInfof calls the CtxInfof method of the logger carried by ctx, or the default logger if there is none.
+// The fields carried by ctx are attached to the entry.
 func CtxInfof(ctx context.Context, format string, v ...interface{}) {
-	logger.CtxInfof(ctx, format, v...)
+	l, format, v := contextLogger(ctx, format, v)
+	l.CtxInfof(ctx, format, v...)
 }
 
-// CtxDebugf calls the default logger's CtxDebugf method.
+// CtxDebugf calls the CtxDebugf method of the logger carried by ctx, or the default logger if there is none.
+// The fields carried by ctx are attached to the entry.
 func CtxDebugf(ctx context.Context, format string, v ...interface{}) {
-	logger.CtxDebugf(ctx, format, v...)
+	l, format, v := contextLogger(ctx, format, v)
+	l.CtxDebugf(ctx, format, v...)
 }
 
-// CtxTracef calls the default logger's CtxTracef method.
+// CtxTracef calls the CtxTracef method of the logger carried by ctx, or the default logger if there is none.
+// The fields carried by ctx are attached to the entry.
 func CtxTracef(ctx context.Context, format string, v ...interface{}) {
-	logger.CtxTracef(ctx, format, v...)
+	l, format, v := contextLogger(ctx, format, v)
+	l.CtxTracef(ctx, format, v...)
 }
 
 type defaultLogger struct {
 	stdlog *log.Logger
-	level  Level
+	level  int32 // Level, accessed atomically to be changed at runtime
 	depth  int
 }
 
@@ -140,11 +155,11 @@ func (ll *defaultLogger) SetOutput(w io.Writer) {
 }
 
 func (ll *defaultLogger) SetLevel(lv Level) {
-	ll.level = lv
+	atomic.StoreInt32(&ll.level, int32(lv))
 }
 
 func (ll *defaultLogger) logf(lv Level, format *string, v ...interface{}) {
-	if ll.level > lv {
+	if Level(atomic.LoadInt32(&ll.level)) > lv {
 		return
 	}
 	msg := lv.toString()
@@ -160,6 +175,7 @@ func (ll *defaultLogger) logf(lv Level, format *string, v ...interface{}) {
 
 	ll.stdlog.Output(ll.depth, msg)
 	if lv == LevelFatal {
+		flushSink(ll.stdlog.Writer())
 		os.Exit(1)
 	}
 }
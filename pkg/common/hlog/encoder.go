@@ -0,0 +1,133 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Entry is a log entry to be encoded.
+type Entry struct {
+	Time    time.Time
+	Level   Level
+	Message string
+	// Caller is "file:line" of the log call, empty if caller is disabled.
+	Caller string
+	Fields Fields
+}
+
+// Encoder encodes a log entry into buf, a trailing newline is expected.
+type Encoder interface {
+	Encode(buf *bytes.Buffer, e *Entry) error
+}
+
+// NewTextEncoder creates an Encoder which outputs human-readable lines like:
+//
+//	2006/01/02 15:04:05.000000 main.go:10: [Info] message key=value
+func NewTextEncoder() Encoder {
+	return textEncoder{}
+}
+
+// NewJSONEncoder creates an Encoder which outputs one JSON object per line, fields
+// are merged into the object next to "time", "level", "caller" and "msg".
+func NewJSONEncoder() Encoder {
+	return jsonEncoder{}
+}
+
+type textEncoder struct{}
+
+func (textEncoder) Encode(buf *bytes.Buffer, e *Entry) error {
+	buf.WriteString(e.Time.Format("2006/01/02 15:04:05.000000"))
+	buf.WriteByte(' ')
+	if e.Caller != "" {
+		buf.WriteString(e.Caller)
+		buf.WriteString(": ")
+	}
+	buf.WriteString(e.Level.toString())
+	buf.WriteString(e.Message)
+	writeTextFields(buf, e.Fields)
+	buf.WriteByte('\n')
+	return nil
+}
+
+func writeTextFields(buf *bytes.Buffer, fields Fields) {
+	for _, k := range fields.sortedKeys() {
+		buf.WriteByte(' ')
+		buf.WriteString(k)
+		buf.WriteByte('=')
+		switch v := fields[k].(type) {
+		case string:
+			buf.WriteString(strconv.Quote(v))
+		case error:
+			buf.WriteString(strconv.Quote(v.Error()))
+		default:
+			fmt.Fprint(buf, v)
+		}
+	}
+}
+
+type jsonEncoder struct{}
+
+func (jsonEncoder) Encode(buf *bytes.Buffer, e *Entry) error {
+	buf.WriteString(`{"time":`)
+	buf.WriteString(strconv.Quote(e.Time.Format(time.RFC3339Nano)))
+	buf.WriteString(`,"level":`)
+	buf.WriteString(strconv.Quote(e.Level.name()))
+	if e.Caller != "" {
+		buf.WriteString(`,"caller":`)
+		buf.WriteString(strconv.Quote(e.Caller))
+	}
+	buf.WriteString(`,"msg":`)
+	writeJSONValue(buf, e.Message)
+	for _, k := range e.Fields.sortedKeys() {
+		buf.WriteByte(',')
+		writeJSONValue(buf, k)
+		buf.WriteByte(':')
+		v := e.Fields[k]
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		writeJSONValue(buf, v)
+	}
+	buf.WriteString("}\n")
+	return nil
+}
+
+func writeJSONValue(buf *bytes.Buffer, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		b, _ = json.Marshal(fmt.Sprint(v))
+	}
+	buf.Write(b)
+}
+
+func (f Fields) sortedKeys() []string {
+	if len(f) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(f))
+	for k := range f {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
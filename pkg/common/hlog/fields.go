@@ -0,0 +1,227 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hlog
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+)
+
+// Fields is the structured key-value pairs attached to log entries.
+type Fields map[string]interface{}
+
+// FieldLogger is a FullLogger which carries structured fields.
+type FieldLogger interface {
+	FullLogger
+
+	// WithFields returns a child logger with fields merged into the fields of the parent.
+	WithFields(fields Fields) FieldLogger
+}
+
+type (
+	loggerCtxKey struct{}
+	fieldsCtxKey struct{}
+)
+
+// WithFields returns a logger which attaches fields to every log entry of the default logger.
+// If the default logger is not a FieldLogger, fields are appended to the message as text.
+func WithFields(fields Fields) FieldLogger {
+	if fl, ok := logger.(FieldLogger); ok {
+		return fl.WithFields(fields)
+	}
+	return newFieldsLogger(logger, fields)
+}
+
+// NewContext returns a copy of ctx which carries logger l, the Ctx* functions of this
+// package output logs with the logger carried by ctx.
+func NewContext(ctx context.Context, l FullLogger) context.Context {
+	return context.WithValue(ctx, loggerCtxKey{}, l)
+}
+
+// ContextWithFields returns a copy of ctx which carries the key-value pairs kv merged into the
+// fields carried by ctx, the Ctx* functions of this package attach them to the log entries
+// automatically. kv is alternate keys and values, e.g.
+// ContextWithFields(ctx, "user_id", 1, "order_id", "x").
+func ContextWithFields(ctx context.Context, kv ...interface{}) context.Context {
+	if len(kv) == 0 {
+		return ctx
+	}
+	fields := make(Fields, len(kv)/2+1)
+	for i := 0; i < len(kv); i += 2 {
+		key, ok := kv[i].(string)
+		if !ok {
+			key = fmt.Sprint(kv[i])
+		}
+		if i+1 < len(kv) {
+			fields[key] = kv[i+1]
+		} else {
+			fields[key] = "(MISSING)"
+		}
+	}
+	return context.WithValue(ctx, fieldsCtxKey{}, FieldsFromContext(ctx).merge(fields))
+}
+
+// FieldsFromContext returns the fields carried by ctx, the returned Fields must not be modified.
+func FieldsFromContext(ctx context.Context) Fields {
+	if ctx == nil {
+		return nil
+	}
+	fields, _ := ctx.Value(fieldsCtxKey{}).(Fields)
+	return fields
+}
+
+// contextLogger returns the logger carried by ctx with the fields carried by ctx attached.
+// The fields are formatted into the message if the logger is not a FieldLogger, instead of
+// wrapping the logger, to keep the call depth for the loggers reporting the caller.
+func contextLogger(ctx context.Context, format string, v []interface{}) (FullLogger, string, []interface{}) {
+	l := FromContext(ctx)
+	fields := FieldsFromContext(ctx)
+	if len(fields) == 0 {
+		return l, format, v
+	}
+	switch fl := l.(type) {
+	case *structuredLogger:
+		// it reads the fields from ctx by itself
+		return l, format, v
+	case FieldLogger:
+		return fl.WithFields(fields), format, v
+	}
+	var buf bytes.Buffer
+	if len(v) > 0 {
+		fmt.Fprintf(&buf, format, v...)
+	} else {
+		buf.WriteString(format)
+	}
+	writeTextFields(&buf, fields)
+	return l, "%s", []interface{}{buf.String()}
+}
+
+// FromContext returns the logger carried by ctx, or the default logger if there is none.
+func FromContext(ctx context.Context) FullLogger {
+	if ctx != nil {
+		if l, ok := ctx.Value(loggerCtxKey{}).(FullLogger); ok {
+			return l
+		}
+	}
+	return logger
+}
+
+func (f Fields) merge(other Fields) Fields {
+	merged := make(Fields, len(f)+len(other))
+	for k, v := range f {
+		merged[k] = v
+	}
+	for k, v := range other {
+		merged[k] = v
+	}
+	return merged
+}
+
+// fieldsLogger adapts a FullLogger which knows nothing about fields.
+type fieldsLogger struct {
+	FullLogger
+	fields Fields
+	suffix string
+}
+
+func newFieldsLogger(l FullLogger, fields Fields) *fieldsLogger {
+	var buf bytes.Buffer
+	writeTextFields(&buf, fields)
+	return &fieldsLogger{FullLogger: l, fields: fields, suffix: buf.String()}
+}
+
+func (l *fieldsLogger) WithFields(fields Fields) FieldLogger {
+	return newFieldsLogger(l.FullLogger, l.fields.merge(fields))
+}
+
+func (l *fieldsLogger) msg(format string, v []interface{}) string {
+	if len(v) > 0 {
+		return fmt.Sprintf(format, v...) + l.suffix
+	}
+	return format + l.suffix
+}
+
+func (l *fieldsLogger) Trace(v ...interface{}) { l.FullLogger.Trace(fmt.Sprint(v...) + l.suffix) }
+
+func (l *fieldsLogger) Debug(v ...interface{}) { l.FullLogger.Debug(fmt.Sprint(v...) + l.suffix) }
+
+func (l *fieldsLogger) Info(v ...interface{}) { l.FullLogger.Info(fmt.Sprint(v...) + l.suffix) }
+
+func (l *fieldsLogger) Notice(v ...interface{}) { l.FullLogger.Notice(fmt.Sprint(v...) + l.suffix) }
+
+func (l *fieldsLogger) Warn(v ...interface{}) { l.FullLogger.Warn(fmt.Sprint(v...) + l.suffix) }
+
+func (l *fieldsLogger) Error(v ...interface{}) { l.FullLogger.Error(fmt.Sprint(v...) + l.suffix) }
+
+func (l *fieldsLogger) Fatal(v ...interface{}) { l.FullLogger.Fatal(fmt.Sprint(v...) + l.suffix) }
+
+func (l *fieldsLogger) Tracef(format string, v ...interface{}) {
+	l.FullLogger.Trace(l.msg(format, v))
+}
+
+func (l *fieldsLogger) Debugf(format string, v ...interface{}) {
+	l.FullLogger.Debug(l.msg(format, v))
+}
+
+func (l *fieldsLogger) Infof(format string, v ...interface{}) {
+	l.FullLogger.Info(l.msg(format, v))
+}
+
+func (l *fieldsLogger) Noticef(format string, v ...interface{}) {
+	l.FullLogger.Notice(l.msg(format, v))
+}
+
+func (l *fieldsLogger) Warnf(format string, v ...interface{}) {
+	l.FullLogger.Warn(l.msg(format, v))
+}
+
+func (l *fieldsLogger) Errorf(format string, v ...interface{}) {
+	l.FullLogger.Error(l.msg(format, v))
+}
+
+func (l *fieldsLogger) Fatalf(format string, v ...interface{}) {
+	l.FullLogger.Fatal(l.msg(format, v))
+}
+
+func (l *fieldsLogger) CtxTracef(ctx context.Context, format string, v ...interface{}) {
+	l.FullLogger.CtxTracef(ctx, "%s", l.msg(format, v))
+}
+
+func (l *fieldsLogger) CtxDebugf(ctx context.Context, format string, v ...interface{}) {
+	l.FullLogger.CtxDebugf(ctx, "%s", l.msg(format, v))
+}
+
+func (l *fieldsLogger) CtxInfof(ctx context.Context, format string, v ...interface{}) {
+	l.FullLogger.CtxInfof(ctx, "%s", l.msg(format, v))
+}
+
+func (l *fieldsLogger) CtxNoticef(ctx context.Context, format string, v ...interface{}) {
+	l.FullLogger.CtxNoticef(ctx, "%s", l.msg(format, v))
+}
+
+func (l *fieldsLogger) CtxWarnf(ctx context.Context, format string, v ...interface{}) {
+	l.FullLogger.CtxWarnf(ctx, "%s", l.msg(format, v))
+}
+
+func (l *fieldsLogger) CtxErrorf(ctx context.Context, format string, v ...interface{}) {
+	l.FullLogger.CtxErrorf(ctx, "%s", l.msg(format, v))
+}
+
+func (l *fieldsLogger) CtxFatalf(ctx context.Context, format string, v ...interface{}) {
+	l.FullLogger.CtxFatalf(ctx, "%s", l.msg(format, v))
+}
@@ -47,7 +47,7 @@ func SetOutput(w io.Writer) {
 
 // SetLevel sets the level of logs below which logs will not be output.
 // The default logger and system logger level is LevelTrace.
-// Note that this method is not concurrent-safe.
+// It's safe to be called at runtime for the built-in loggers.
 func SetLevel(lv Level) {
 	logger.SetLevel(lv)
 	sysLogger.SetLevel(lv)
@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"strings"
 )
 
 // FormatLogger is a logger interface that output logs with a format.
@@ -96,9 +97,38 @@ var strs = []string{
 	"[Fatal] ",
 }
 
+var names = []string{
+	"trace",
+	"debug",
+	"info",
+	"notice",
+	"warn",
+	"error",
+	"fatal",
+}
+
 func (lv Level) toString() string {
 	if lv >= LevelTrace && lv <= LevelFatal {
 		return strs[lv]
 	}
 	return fmt.Sprintf("[?%d] ", lv)
 }
+
+// ParseLevel returns the level of the lower-case name used by structured encoders, e.g. "info",
+// the name is case-insensitive.
+func ParseLevel(name string) (Level, error) {
+	for i, n := range names {
+		if strings.EqualFold(n, name) {
+			return Level(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown log level: %s", name)
+}
+
+// name returns the lower-case name of the level used by structured encoders.
+func (lv Level) name() string {
+	if lv >= LevelTrace && lv <= LevelFatal {
+		return names[lv]
+	}
+	return fmt.Sprintf("?%d", lv)
+}
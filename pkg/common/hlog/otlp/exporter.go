@@ -0,0 +1,405 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package otlp provides an hlog entry sink exporting the logs to an OpenTelemetry collector
+// by OTLP/HTTP with the JSON encoding, so that the logs flow to the same collector as the
+// traces and the metrics.
+//
+//	exp := otlp.NewExporter(otlp.WithServiceName("order"))
+//	hlog.SetLogger(hlog.NewStructuredLogger(hlog.WithEntrySinks(exp)))
+//	h.OnShutdown = append(h.OnShutdown, exp.Shutdown)
+//
+// The fields "trace_id" and "span_id" of the entries, in hex, are exported as the trace
+// context of the records rather than the attributes.
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"hertz-study/pkg/common/hlog"
+)
+
+const (
+	scopeName = "hertz-study/hlog"
+
+	fieldTraceID = "trace_id"
+	fieldSpanID  = "span_id"
+	fieldCaller  = "code.caller"
+
+	maxRetries = 3
+)
+
+// ErrExporterShutdown is returned by Exporter.WriteEntry after the exporter is shut down.
+var ErrExporterShutdown = errors.New("hlog/otlp: exporter is shut down")
+
+// Exporter is an hlog.EntrySink which batches the entries and exports them in the background.
+//
+// It is safe for concurrent use.
+type Exporter struct {
+	opts     *options
+	resource []keyValue
+
+	mu       sync.Mutex
+	queue    []logRecord
+	shutdown bool
+	dropped  uint64
+
+	flushCh chan chan struct{}
+	wakeCh  chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+// NewExporter creates an Exporter and starts its background goroutine.
+func NewExporter(opts ...Option) *Exporter {
+	o := newOptions(opts...)
+	if o.batchSize <= 0 {
+		o.batchSize = defaultBatchSize
+	}
+	if o.queueSize < o.batchSize {
+		o.queueSize = o.batchSize
+	}
+	e := &Exporter{
+		opts:     o,
+		resource: attributes(o.resource),
+		flushCh:  make(chan chan struct{}),
+		wakeCh:   make(chan struct{}, 1),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	go e.run()
+	return e
+}
+
+// WriteEntry implements hlog.EntrySink, the entry is dropped if the queue is full.
+func (e *Exporter) WriteEntry(entry *hlog.Entry) error {
+	r := newLogRecord(entry)
+	e.mu.Lock()
+	if e.shutdown {
+		e.mu.Unlock()
+		return ErrExporterShutdown
+	}
+	if len(e.queue) >= e.opts.queueSize {
+		e.mu.Unlock()
+		atomic.AddUint64(&e.dropped, 1)
+		return nil
+	}
+	e.queue = append(e.queue, r)
+	full := len(e.queue) >= e.opts.batchSize
+	e.mu.Unlock()
+
+	if full {
+		select {
+		case e.wakeCh <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Dropped returns the count of records dropped because the queue was full.
+func (e *Exporter) Dropped() uint64 {
+	return atomic.LoadUint64(&e.dropped)
+}
+
+// Flush exports the queued records and waits until it finishes or ctx is done.
+func (e *Exporter) Flush(ctx context.Context) error {
+	ch := make(chan struct{})
+	select {
+	case e.flushCh <- ch:
+	case <-e.stopped:
+		return ErrExporterShutdown
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown exports the queued records and stops the exporter, the entries written after it
+// are discarded. Its signature matches the OnShutdown hooks of the server.
+func (e *Exporter) Shutdown(ctx context.Context) {
+	e.mu.Lock()
+	if e.shutdown {
+		e.mu.Unlock()
+		return
+	}
+	e.shutdown = true
+	e.mu.Unlock()
+
+	e.Flush(ctx) //nolint:errcheck
+	close(e.done)
+	<-e.stopped
+}
+
+func (e *Exporter) run() {
+	defer close(e.stopped)
+	ticker := time.NewTicker(e.opts.flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-e.done:
+			return
+		case <-ticker.C:
+			e.exportAll()
+		case <-e.wakeCh:
+			e.exportAll()
+		case ch := <-e.flushCh:
+			e.exportAll()
+			close(ch)
+		}
+	}
+}
+
+// exportAll exports the queued records batch by batch.
+func (e *Exporter) exportAll() {
+	for {
+		e.mu.Lock()
+		n := len(e.queue)
+		if n > e.opts.batchSize {
+			n = e.opts.batchSize
+		}
+		batch := make([]logRecord, n)
+		copy(batch, e.queue)
+		e.queue = e.queue[:copy(e.queue, e.queue[n:])]
+		e.mu.Unlock()
+		if n == 0 {
+			return
+		}
+		if err := e.export(batch); err != nil {
+			e.opts.onError(fmt.Errorf("export %d records failed: %w", n, err))
+		}
+	}
+}
+
+func (e *Exporter) export(batch []logRecord) error {
+	body, err := json.Marshal(&exportRequest{ResourceLogs: []resourceLogs{{
+		Resource:  resource{Attributes: e.resource},
+		ScopeLogs: []scopeLogs{{Scope: scope{Name: scopeName}, LogRecords: batch}},
+	}}})
+	if err != nil {
+		return err
+	}
+
+	backoff := time.Second
+	for retry := 0; ; retry++ {
+		retryable, err := e.post(body)
+		if err == nil || !retryable || retry == maxRetries {
+			return err
+		}
+		select {
+		case <-e.done:
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// post sends the request, it returns whether the failure is transient and worth retrying.
+func (e *Exporter) post(body []byte) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), e.opts.timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.opts.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.opts.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := e.opts.httpClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body) //nolint:errcheck
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode == http.StatusBadGateway,
+		resp.StatusCode == http.StatusServiceUnavailable, resp.StatusCode == http.StatusGatewayTimeout:
+		return true, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	default:
+		return false, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+}
+
+// The types below are the JSON encoding of the OTLP logs data model, see
+// https://github.com/open-telemetry/opentelemetry-proto/blob/main/opentelemetry/proto/logs/v1/logs.proto
+
+type exportRequest struct {
+	ResourceLogs []resourceLogs `json:"resourceLogs"`
+}
+
+type resourceLogs struct {
+	Resource  resource    `json:"resource"`
+	ScopeLogs []scopeLogs `json:"scopeLogs"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes,omitempty"`
+}
+
+type scopeLogs struct {
+	Scope      scope       `json:"scope"`
+	LogRecords []logRecord `json:"logRecords"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+type logRecord struct {
+	TimeUnixNano         string     `json:"timeUnixNano"`
+	ObservedTimeUnixNano string     `json:"observedTimeUnixNano"`
+	SeverityNumber       int        `json:"severityNumber"`
+	SeverityText         string     `json:"severityText"`
+	Body                 anyValue   `json:"body"`
+	Attributes           []keyValue `json:"attributes,omitempty"`
+	TraceID              string     `json:"traceId,omitempty"`
+	SpanID               string     `json:"spanId,omitempty"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+func newLogRecord(entry *hlog.Entry) logRecord {
+	number, text := severity(entry.Level)
+	ts := strconv.FormatInt(entry.Time.UnixNano(), 10)
+	r := logRecord{
+		TimeUnixNano:         ts,
+		ObservedTimeUnixNano: ts,
+		SeverityNumber:       number,
+		SeverityText:         text,
+		Body:                 stringValue(entry.Message),
+	}
+	attrs := make(hlog.Fields, len(entry.Fields)+1)
+	for k, v := range entry.Fields {
+		switch k {
+		case fieldTraceID:
+			r.TraceID = fmt.Sprint(v)
+		case fieldSpanID:
+			r.SpanID = fmt.Sprint(v)
+		default:
+			attrs[k] = v
+		}
+	}
+	if entry.Caller != "" {
+		attrs[fieldCaller] = entry.Caller
+	}
+	r.Attributes = attributes(attrs)
+	return r
+}
+
+// severity maps the level to the severity number and text of the OTLP data model.
+func severity(lv hlog.Level) (int, string) {
+	switch lv {
+	case hlog.LevelTrace:
+		return 1, "TRACE"
+	case hlog.LevelDebug:
+		return 5, "DEBUG"
+	case hlog.LevelInfo:
+		return 9, "INFO"
+	case hlog.LevelNotice:
+		return 10, "NOTICE"
+	case hlog.LevelWarn:
+		return 13, "WARN"
+	case hlog.LevelError:
+		return 17, "ERROR"
+	default:
+		return 21, "FATAL"
+	}
+}
+
+func attributes(fields hlog.Fields) []keyValue {
+	if len(fields) == 0 {
+		return nil
+	}
+	kvs := make([]keyValue, 0, len(fields))
+	for k, v := range fields {
+		kvs = append(kvs, keyValue{Key: k, Value: toAnyValue(v)})
+	}
+	return kvs
+}
+
+func toAnyValue(v interface{}) anyValue {
+	switch x := v.(type) {
+	case string:
+		return stringValue(x)
+	case bool:
+		return anyValue{BoolValue: &x}
+	case int:
+		return intValue(int64(x))
+	case int8:
+		return intValue(int64(x))
+	case int16:
+		return intValue(int64(x))
+	case int32:
+		return intValue(int64(x))
+	case int64:
+		return intValue(x)
+	case uint8:
+		return intValue(int64(x))
+	case uint16:
+		return intValue(int64(x))
+	case uint32:
+		return intValue(int64(x))
+	case float32:
+		f := float64(x)
+		return anyValue{DoubleValue: &f}
+	case float64:
+		return anyValue{DoubleValue: &x}
+	case time.Duration:
+		return stringValue(x.String())
+	case error:
+		return stringValue(x.Error())
+	default:
+		return stringValue(fmt.Sprint(x))
+	}
+}
+
+func stringValue(s string) anyValue {
+	return anyValue{StringValue: &s}
+}
+
+func intValue(i int64) anyValue {
+	s := strconv.FormatInt(i, 10)
+	return anyValue{IntValue: &s}
+}
@@ -0,0 +1,149 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package otlp
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"hertz-study/pkg/common/hlog"
+)
+
+const (
+	defaultEndpoint      = "http://localhost:4318/v1/logs"
+	defaultBatchSize     = 512
+	defaultQueueSize     = 2048
+	defaultFlushInterval = 5 * time.Second
+	defaultTimeout       = 10 * time.Second
+)
+
+type (
+	options struct {
+		endpoint      string
+		headers       map[string]string
+		resource      hlog.Fields
+		batchSize     int
+		queueSize     int
+		flushInterval time.Duration
+		timeout       time.Duration
+		httpClient    *http.Client
+		onError       func(err error)
+	}
+
+	Option func(o *options)
+)
+
+func newOptions(opts ...Option) *options {
+	cfg := &options{
+		endpoint:      defaultEndpoint,
+		resource:      hlog.Fields{},
+		batchSize:     defaultBatchSize,
+		queueSize:     defaultQueueSize,
+		flushInterval: defaultFlushInterval,
+		timeout:       defaultTimeout,
+		httpClient:    http.DefaultClient,
+		onError:       defaultOnError,
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return cfg
+}
+
+// defaultOnError writes the error to stderr rather than hlog, since exporting the error log
+// may fail again.
+func defaultOnError(err error) {
+	fmt.Fprintf(os.Stderr, "hlog/otlp: %v\n", err)
+}
+
+// WithEndpoint sets the url of the OTLP/HTTP logs endpoint, default is "http://localhost:4318/v1/logs".
+func WithEndpoint(url string) Option {
+	return func(o *options) {
+		o.endpoint = url
+	}
+}
+
+// WithHeaders sets the extra headers of the export requests, e.g. the authorization header.
+func WithHeaders(headers map[string]string) Option {
+	return func(o *options) {
+		o.headers = headers
+	}
+}
+
+// WithServiceName sets the "service.name" resource attribute.
+func WithServiceName(name string) Option {
+	return func(o *options) {
+		o.resource["service.name"] = name
+	}
+}
+
+// WithResourceAttributes sets the attributes describing the source of the logs, e.g.
+// "deployment.environment" and "host.name", they are merged with the service name.
+func WithResourceAttributes(attrs hlog.Fields) Option {
+	return func(o *options) {
+		for k, v := range attrs {
+			o.resource[k] = v
+		}
+	}
+}
+
+// WithBatchSize sets the max count of records exported by one request, default is 512.
+func WithBatchSize(size int) Option {
+	return func(o *options) {
+		o.batchSize = size
+	}
+}
+
+// WithQueueSize sets the max count of records waiting to be exported, the records are dropped
+// when the queue is full, default is 2048.
+func WithQueueSize(size int) Option {
+	return func(o *options) {
+		o.queueSize = size
+	}
+}
+
+// WithFlushInterval sets the max interval between exports, default is 5s.
+func WithFlushInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.flushInterval = interval
+	}
+}
+
+// WithTimeout sets the timeout of every export request, default is 10s.
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.timeout = timeout
+	}
+}
+
+// WithHTTPClient sets the client sending the export requests, default is http.DefaultClient.
+func WithHTTPClient(c *http.Client) Option {
+	return func(o *options) {
+		o.httpClient = c
+	}
+}
+
+// WithErrorHandler sets the function called when the export fails, default writes the error to stderr.
+func WithErrorHandler(f func(err error)) Option {
+	return func(o *options) {
+		o.onError = f
+	}
+}
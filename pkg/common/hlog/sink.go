@@ -0,0 +1,183 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hlog
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultRotateMaxSize = 100 * 1024 * 1024
+	backupTimeFormat     = "20060102T150405.000"
+)
+
+// EntrySink is a log sink receiving the entries rather than the encoded bytes, which is
+// useful for exporting the entries in their own format, e.g. OTLP. The entry must not be
+// retained after WriteEntry returns.
+type EntrySink interface {
+	WriteEntry(e *Entry) error
+}
+
+// fatalFlushTimeout bounds how long the sinks are flushed before exiting on the fatal entries.
+const fatalFlushTimeout = 5 * time.Second
+
+// flusher is implemented by the sinks buffering the entries, e.g. AsyncWriter and the OTLP exporter.
+type flusher interface {
+	Flush(ctx context.Context) error
+}
+
+// flushSink writes the entries buffered by sink before the process exits, and syncs the files.
+func flushSink(sink interface{}) {
+	switch f := sink.(type) {
+	case flusher:
+		ctx, cancel := context.WithTimeout(context.Background(), fatalFlushTimeout)
+		f.Flush(ctx) //nolint:errcheck
+		cancel()
+	case interface{ Sync() error }:
+		f.Sync() //nolint:errcheck
+	}
+}
+
+func multiWriter(sinks []io.Writer) io.Writer {
+	if len(sinks) == 1 {
+		return sinks[0]
+	}
+	return io.MultiWriter(sinks...)
+}
+
+// RotateFile is a log sink which writes to a file and rotates it when its size exceeds MaxSize.
+// The rotated files are renamed to "<Filename>.<timestamp>" in the same directory.
+//
+// It is safe for concurrent use.
+type RotateFile struct {
+	// Filename is the file to write logs to.
+	Filename string
+	// MaxSize is the max size of the file in bytes before it gets rotated, default is 100MB.
+	MaxSize int64
+	// MaxBackups is the max count of rotated files to retain, zero means retaining all.
+	MaxBackups int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// NewRotateFile creates a RotateFile, the file is opened lazily on the first write.
+func NewRotateFile(filename string, maxSize int64, maxBackups int) *RotateFile {
+	return &RotateFile{Filename: filename, MaxSize: maxSize, MaxBackups: maxBackups}
+}
+
+// Write implements io.Writer.
+func (rf *RotateFile) Write(p []byte) (n int, err error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.file == nil {
+		if err = rf.open(); err != nil {
+			return 0, err
+		}
+	}
+	if rf.size+int64(len(p)) > rf.maxSize() && rf.size > 0 {
+		if err = rf.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err = rf.file.Write(p)
+	rf.size += int64(n)
+	return n, err
+}
+
+// Close implements io.Closer.
+func (rf *RotateFile) Close() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.file == nil {
+		return nil
+	}
+	err := rf.file.Close()
+	rf.file = nil
+	return err
+}
+
+// Sync commits the written logs to stable storage.
+func (rf *RotateFile) Sync() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.file == nil {
+		return nil
+	}
+	return rf.file.Sync()
+}
+
+func (rf *RotateFile) maxSize() int64 {
+	if rf.MaxSize <= 0 {
+		return defaultRotateMaxSize
+	}
+	return rf.MaxSize
+}
+
+func (rf *RotateFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(rf.Filename), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(rf.Filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	rf.file = f
+	rf.size = info.Size()
+	return nil
+}
+
+func (rf *RotateFile) rotate() error {
+	if err := rf.file.Close(); err != nil {
+		return err
+	}
+	rf.file = nil
+	backup := rf.Filename + "." + time.Now().Format(backupTimeFormat)
+	if err := os.Rename(rf.Filename, backup); err != nil {
+		return err
+	}
+	rf.removeStaleBackups()
+	return rf.open()
+}
+
+func (rf *RotateFile) removeStaleBackups() {
+	if rf.MaxBackups <= 0 {
+		return
+	}
+	backups, err := filepath.Glob(rf.Filename + ".*")
+	if err != nil || len(backups) <= rf.MaxBackups {
+		return
+	}
+	// timestamp suffix keeps the lexical order the same as the time order
+	sort.Strings(backups)
+	for _, f := range backups[:len(backups)-rf.MaxBackups] {
+		os.Remove(f) //nolint:errcheck
+	}
+}
@@ -0,0 +1,406 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hlog
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// pkgPath is used to skip the frames of this package when looking for the caller.
+var pkgPath = reflect.TypeOf(structuredLogger{}).PkgPath()
+
+// SamplingConfig limits the count of the entries of the same format in every Tick:
+// the First entries are output and after that only every Thereafter-th entry is output.
+// Entries with LevelError or higher are never sampled.
+type SamplingConfig struct {
+	Tick       time.Duration
+	First      int
+	Thereafter int
+}
+
+type structuredOptions struct {
+	level      Level
+	encoder    Encoder
+	sinks      []io.Writer
+	sampling   *SamplingConfig
+	caller     bool
+	callerSkip int
+	fields     Fields
+	entrySinks []EntrySink
+}
+
+// StructuredOption is the option of NewStructuredLogger.
+type StructuredOption func(o *structuredOptions)
+
+// WithLevel sets the level of the structured logger, default is LevelTrace.
+func WithLevel(lv Level) StructuredOption {
+	return func(o *structuredOptions) {
+		o.level = lv
+	}
+}
+
+// WithEncoder sets the encoder of the structured logger, default is NewTextEncoder().
+func WithEncoder(enc Encoder) StructuredOption {
+	return func(o *structuredOptions) {
+		o.encoder = enc
+	}
+}
+
+// WithSinks sets where the logs are written to, default is os.Stderr.
+// Logs are written to all the sinks, e.g. os.Stderr and a *RotateFile.
+func WithSinks(sinks ...io.Writer) StructuredOption {
+	return func(o *structuredOptions) {
+		o.sinks = sinks
+	}
+}
+
+// WithSampling enables sampling of the structured logger.
+func WithSampling(cfg SamplingConfig) StructuredOption {
+	return func(o *structuredOptions) {
+		o.sampling = &cfg
+	}
+}
+
+// WithCaller sets whether record the caller of log calls and how many extra stack frames
+// outside this package to skip, which is useful when the logger is wrapped by other functions.
+func WithCaller(enable bool, extraSkip int) StructuredOption {
+	return func(o *structuredOptions) {
+		o.caller = enable
+		o.callerSkip = extraSkip
+	}
+}
+
+// WithInitialFields sets the fields attached to all entries of the structured logger.
+func WithInitialFields(fields Fields) StructuredOption {
+	return func(o *structuredOptions) {
+		o.fields = fields
+	}
+}
+
+// WithEntrySinks sets the sinks receiving the entries before they are encoded, in addition to
+// the sinks set by WithSinks. Use WithSinks(io.Discard) to only output to the entry sinks.
+func WithEntrySinks(sinks ...EntrySink) StructuredOption {
+	return func(o *structuredOptions) {
+		o.entrySinks = sinks
+	}
+}
+
+type structuredCore struct {
+	mu         sync.Mutex
+	level      int32
+	encoder    Encoder
+	output     io.Writer
+	sinks      []io.Writer
+	entrySinks []EntrySink
+	sampler    *sampler
+	caller     bool
+	callerSkip int
+}
+
+type structuredLogger struct {
+	core   *structuredCore
+	fields Fields
+}
+
+// NewStructuredLogger creates a FieldLogger which encodes every entry with the configured
+// Encoder and writes it to the configured sinks. Use SetLogger to make it the default logger.
+func NewStructuredLogger(opts ...StructuredOption) FieldLogger {
+	o := &structuredOptions{
+		level:   LevelTrace,
+		encoder: NewTextEncoder(),
+		sinks:   []io.Writer{os.Stderr},
+		caller:  true,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	core := &structuredCore{
+		level:      int32(o.level),
+		encoder:    o.encoder,
+		sinks:      o.sinks,
+		entrySinks: o.entrySinks,
+		caller:     o.caller,
+		callerSkip: o.callerSkip,
+	}
+	core.output = multiWriter(o.sinks)
+	if o.sampling != nil {
+		core.sampler = newSampler(*o.sampling)
+	}
+	return &structuredLogger{core: core, fields: o.fields}
+}
+
+var bufferPool = sync.Pool{New: func() interface{} {
+	return &bytes.Buffer{}
+}}
+
+func (sl *structuredLogger) log(lv Level, format *string, v ...interface{}) {
+	c := sl.core
+	if Level(atomic.LoadInt32(&c.level)) > lv {
+		return
+	}
+	if c.sampler != nil && lv < LevelError && !c.sampler.allow(lv, sampleKey(format, v)) {
+		return
+	}
+	var msg string
+	if format != nil {
+		if len(v) > 0 {
+			msg = fmt.Sprintf(*format, v...)
+		} else {
+			msg = *format
+		}
+	} else {
+		msg = fmt.Sprint(v...)
+	}
+
+	e := &Entry{Time: time.Now(), Level: lv, Message: msg, Fields: sl.fields}
+	if c.caller {
+		e.Caller = caller(c.callerSkip)
+	}
+
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	if err := c.encoder.Encode(buf, e); err == nil {
+		c.mu.Lock()
+		c.output.Write(buf.Bytes()) //nolint:errcheck
+		c.mu.Unlock()
+	}
+	bufferPool.Put(buf)
+	for _, sink := range c.entrySinks {
+		sink.WriteEntry(e) //nolint:errcheck
+	}
+
+	if lv == LevelFatal {
+		c.mu.Lock()
+		sinks := c.sinks
+		c.mu.Unlock()
+		for _, sink := range sinks {
+			flushSink(sink)
+		}
+		for _, sink := range c.entrySinks {
+			flushSink(sink)
+		}
+		os.Exit(1)
+	}
+}
+
+func (sl *structuredLogger) WithFields(fields Fields) FieldLogger {
+	return &structuredLogger{core: sl.core, fields: sl.fields.merge(fields)}
+}
+
+// withContext returns the logger with the fields carried by ctx attached.
+func (sl *structuredLogger) withContext(ctx context.Context) *structuredLogger {
+	fields := FieldsFromContext(ctx)
+	if len(fields) == 0 {
+		return sl
+	}
+	return &structuredLogger{core: sl.core, fields: sl.fields.merge(fields)}
+}
+
+func (sl *structuredLogger) SetLevel(lv Level) {
+	atomic.StoreInt32(&sl.core.level, int32(lv))
+}
+
+func (sl *structuredLogger) SetOutput(w io.Writer) {
+	sl.core.mu.Lock()
+	sl.core.output = w
+	sl.core.sinks = []io.Writer{w}
+	sl.core.mu.Unlock()
+}
+
+func (sl *structuredLogger) Fatal(v ...interface{}) {
+	sl.log(LevelFatal, nil, v...)
+}
+
+func (sl *structuredLogger) Error(v ...interface{}) {
+	sl.log(LevelError, nil, v...)
+}
+
+func (sl *structuredLogger) Warn(v ...interface{}) {
+	sl.log(LevelWarn, nil, v...)
+}
+
+func (sl *structuredLogger) Notice(v ...interface{}) {
+	sl.log(LevelNotice, nil, v...)
+}
+
+func (sl *structuredLogger) Info(v ...interface{}) {
+	sl.log(LevelInfo, nil, v...)
+}
+
+func (sl *structuredLogger) Debug(v ...interface{}) {
+	sl.log(LevelDebug, nil, v...)
+}
+
+func (sl *structuredLogger) Trace(v ...interface{}) {
+	sl.log(LevelTrace, nil, v...)
+}
+
+func (sl *structuredLogger) Fatalf(format string, v ...interface{}) {
+	sl.log(LevelFatal, &format, v...)
+}
+
+func (sl *structuredLogger) Errorf(format string, v ...interface{}) {
+	sl.log(LevelError, &format, v...)
+}
+
+func (sl *structuredLogger) Warnf(format string, v ...interface{}) {
+	sl.log(LevelWarn, &format, v...)
+}
+
+func (sl *structuredLogger) Noticef(format string, v ...interface{}) {
+	sl.log(LevelNotice, &format, v...)
+}
+
+func (sl *structuredLogger) Infof(format string, v ...interface{}) {
+	sl.log(LevelInfo, &format, v...)
+}
+
+func (sl *structuredLogger) Debugf(format string, v ...interface{}) {
+	sl.log(LevelDebug, &format, v...)
+}
+
+func (sl *structuredLogger) Tracef(format string, v ...interface{}) {
+	sl.log(LevelTrace, &format, v...)
+}
+
+func (sl *structuredLogger) CtxFatalf(ctx context.Context, format string, v ...interface{}) {
+	sl.withContext(ctx).log(LevelFatal, &format, v...)
+}
+
+func (sl *structuredLogger) CtxErrorf(ctx context.Context, format string, v ...interface{}) {
+	sl.withContext(ctx).log(LevelError, &format, v...)
+}
+
+func (sl *structuredLogger) CtxWarnf(ctx context.Context, format string, v ...interface{}) {
+	sl.withContext(ctx).log(LevelWarn, &format, v...)
+}
+
+func (sl *structuredLogger) CtxNoticef(ctx context.Context, format string, v ...interface{}) {
+	sl.withContext(ctx).log(LevelNotice, &format, v...)
+}
+
+func (sl *structuredLogger) CtxInfof(ctx context.Context, format string, v ...interface{}) {
+	sl.withContext(ctx).log(LevelInfo, &format, v...)
+}
+
+func (sl *structuredLogger) CtxDebugf(ctx context.Context, format string, v ...interface{}) {
+	sl.withContext(ctx).log(LevelDebug, &format, v...)
+}
+
+func (sl *structuredLogger) CtxTracef(ctx context.Context, format string, v ...interface{}) {
+	sl.withContext(ctx).log(LevelTrace, &format, v...)
+}
+
+// caller returns "file:line" of the first frame outside this package after skipping skip frames.
+func caller(skip int) string {
+	var pcs [16]uintptr
+	n := runtime.Callers(3, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		f, more := frames.Next()
+		if !strings.HasPrefix(f.Function, pkgPath+".") {
+			if skip <= 0 {
+				return filepath.Base(f.File) + ":" + strconv.Itoa(f.Line)
+			}
+			skip--
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// sampleCounterSize is the count of the counters of every level, the keys are hashed into them,
+// so that the memory of the sampler is bounded however many messages are logged.
+const sampleCounterSize = 1024
+
+type sampleCounter struct {
+	resetAt int64
+	count   int64
+}
+
+type sampler struct {
+	cfg      SamplingConfig
+	counters [LevelFatal + 1][sampleCounterSize]sampleCounter
+}
+
+func newSampler(cfg SamplingConfig) *sampler {
+	if cfg.Tick <= 0 {
+		cfg.Tick = time.Second
+	}
+	if cfg.Thereafter <= 0 {
+		cfg.Thereafter = 1
+	}
+	return &sampler{cfg: cfg}
+}
+
+// sampleKey returns the key sampling the entry: the format string of the formatted calls, and
+// the first argument of the others if it's a string, e.g. "user login" of
+// Info("user login", id), or its type otherwise. The formatted message isn't used so that the
+// entries of the same call site share the counter.
+func sampleKey(format *string, v []interface{}) string {
+	if format != nil {
+		return *format
+	}
+	if len(v) == 0 {
+		return ""
+	}
+	if v[0] == nil {
+		return "<nil>"
+	}
+	if s, ok := v[0].(string); ok {
+		return s
+	}
+	return reflect.TypeOf(v[0]).String()
+}
+
+func (s *sampler) allow(lv Level, key string) bool {
+	if lv < LevelTrace || lv > LevelFatal {
+		return true
+	}
+	// FNV-1a
+	h := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= 16777619
+	}
+	c := &s.counters[lv][h%sampleCounterSize]
+
+	now := time.Now().UnixNano()
+	if resetAt := atomic.LoadInt64(&c.resetAt); now > resetAt {
+		if atomic.CompareAndSwapInt64(&c.resetAt, resetAt, now+int64(s.cfg.Tick)) {
+			atomic.StoreInt64(&c.count, 0)
+		}
+	}
+	n := atomic.AddInt64(&c.count, 1)
+	if n <= int64(s.cfg.First) {
+		return true
+	}
+	return (n-int64(s.cfg.First))%int64(s.cfg.Thereafter) == 0
+}
@@ -0,0 +1,31 @@
+// Copyright 2022 CloudWeGo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package json
+
+// Codec is a json implementation, e.g. sonic, jsoniter or encoding/json.
+// The built-in one is sonic on the supported platforms and encoding/json otherwise,
+// use the stdjson build tag to force encoding/json.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// SetCodec replaces Marshal and Unmarshal with c globally, which affects rendering, binding
+// and the error rendering. It is not concurrent safe and should be called before the server starts.
+func SetCodec(c Codec) {
+	Marshal = c.Marshal
+	Unmarshal = c.Unmarshal
+}
@@ -0,0 +1,174 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package mimetype provides the registry of MIME types used by the static file serving, the render
+// helpers and the client to detect the content type from the file extension and the content.
+//
+// The types of the extensions can be overridden, and the content sniffing, which is the fallback of
+// the unknown extensions, can be extended by custom sniffers or disabled by ModeStrict.
+package mimetype
+
+import (
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// DefaultType is the content type of the data whose type is unknown.
+const DefaultType = "application/octet-stream"
+
+// Mode decides how the content type is detected if the extension is unknown.
+type Mode int
+
+const (
+	// ModeSniff sniffs the content if the extension is unknown, it is the default mode.
+	ModeSniff Mode = iota
+	// ModeStrict only trusts the extensions, DefaultType is used if the extension is unknown.
+	ModeStrict
+)
+
+// Sniffer detects the content type from the leading bytes (at most 512) of the content,
+// it returns "" if the type is unknown to it.
+type Sniffer func(data []byte) string
+
+// Registry maps the extensions to the content types and sniffs the content, it is safe for concurrent use.
+type Registry struct {
+	mu       sync.RWMutex
+	types    map[string]string
+	sniffers []Sniffer
+	mode     Mode
+}
+
+// NewRegistry creates a Registry in ModeSniff backed by the extension map of the mime package.
+func NewRegistry() *Registry {
+	return &Registry{types: make(map[string]string)}
+}
+
+// Default is the Registry used by hertz if not specified.
+var Default = NewRegistry()
+
+// Register sets the content type of the extension, e.g. Register(".mjs", "text/javascript"), which
+// overrides the mime package. An empty contentType removes the override.
+func (r *Registry) Register(ext, contentType string) {
+	ext = normalizeExt(ext)
+	r.mu.Lock()
+	if contentType == "" {
+		delete(r.types, ext)
+	} else {
+		r.types[ext] = contentType
+	}
+	r.mu.Unlock()
+}
+
+// RegisterSniffer adds a sniffer, which is tried in the registration order before http.DetectContentType.
+func (r *Registry) RegisterSniffer(s Sniffer) {
+	r.mu.Lock()
+	r.sniffers = append(r.sniffers, s)
+	r.mu.Unlock()
+}
+
+// SetMode sets how the content type is detected if the extension is unknown.
+func (r *Registry) SetMode(mode Mode) {
+	r.mu.Lock()
+	r.mode = mode
+	r.mu.Unlock()
+}
+
+// Mode returns how the content type is detected if the extension is unknown.
+func (r *Registry) Mode() Mode {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.mode
+}
+
+// TypeByExtension returns the content type of the extension, e.g. ".html", or "" if it is unknown.
+func (r *Registry) TypeByExtension(ext string) string {
+	if ext == "" {
+		return ""
+	}
+	ext = normalizeExt(ext)
+	r.mu.RLock()
+	t, ok := r.types[ext]
+	r.mu.RUnlock()
+	if ok {
+		return t
+	}
+	return mime.TypeByExtension(ext)
+}
+
+// Sniff detects the content type from the leading bytes of the content by the custom sniffers
+// and then http.DetectContentType, it never returns "".
+func (r *Registry) Sniff(data []byte) string {
+	if len(data) > 512 {
+		data = data[:512]
+	}
+	r.mu.RLock()
+	sniffers := r.sniffers
+	r.mu.RUnlock()
+	for _, s := range sniffers {
+		if t := s(data); t != "" {
+			return t
+		}
+	}
+	return http.DetectContentType(data)
+}
+
+// Detect returns the content type of the file name, the content is sniffed in ModeSniff if the
+// extension is unknown. The data may be nil if the content is not available.
+func (r *Registry) Detect(name string, data []byte) string {
+	if t := r.TypeByExtension(filepath.Ext(name)); t != "" {
+		return t
+	}
+	if data == nil || r.Mode() == ModeStrict {
+		return DefaultType
+	}
+	return r.Sniff(data)
+}
+
+// Register sets the content type of the extension in the Default registry.
+func Register(ext, contentType string) {
+	Default.Register(ext, contentType)
+}
+
+// RegisterSniffer adds a sniffer to the Default registry.
+func RegisterSniffer(s Sniffer) {
+	Default.RegisterSniffer(s)
+}
+
+// SetMode sets the mode of the Default registry.
+func SetMode(mode Mode) {
+	Default.SetMode(mode)
+}
+
+// TypeByExtension returns the content type of the extension by the Default registry.
+func TypeByExtension(ext string) string {
+	return Default.TypeByExtension(ext)
+}
+
+// Detect returns the content type of the file name and its content by the Default registry.
+func Detect(name string, data []byte) string {
+	return Default.Detect(name, data)
+}
+
+func normalizeExt(ext string) string {
+	ext = strings.ToLower(ext)
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return ext
+}
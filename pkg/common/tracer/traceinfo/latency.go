@@ -0,0 +1,70 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package traceinfo
+
+import (
+	"time"
+
+	"hertz-study/pkg/common/tracer/stats"
+)
+
+// Latency is the breakdown of the latency of a request by phases, computed from the events
+// recorded to HTTPStats. A phase is 0 if its events aren't recorded, e.g. the phases of the
+// detailed events at stats.LevelBase, or the request fails before the phase.
+type Latency struct {
+	// Total is from stats.HTTPStart to stats.HTTPFinish.
+	Total time.Duration
+	// ReadHeader is from stats.ReadHeaderStart to stats.ReadHeaderFinish.
+	ReadHeader time.Duration
+	// ReadBody is from stats.ReadBodyStart to stats.ReadBodyFinish, the streamed body is read
+	// by the handlers and counted in Handle.
+	ReadBody time.Duration
+	// Handle is from stats.ServerHandleStart to stats.ServerHandleFinish.
+	Handle time.Duration
+	// Write is from stats.WriteStart to stats.WriteFinish.
+	Write time.Duration
+}
+
+// EventDuration returns the duration between the events start and end recorded to s, 0 if
+// either of them isn't recorded.
+func EventDuration(s HTTPStats, start, end stats.Event) time.Duration {
+	if s == nil {
+		return 0
+	}
+	se, ee := s.GetEvent(start), s.GetEvent(end)
+	if se == nil || ee == nil {
+		return 0
+	}
+	return ee.Time().Sub(se.Time())
+}
+
+// LatencyOf returns the Latency of the request recorded to s, it's usually called in the
+// Finish of a tracer, e.g.
+//
+//	func (t *myTracer) Finish(ctx context.Context, c *app.RequestContext) {
+//		l := traceinfo.LatencyOf(c.GetTraceInfo().Stats())
+//		t.handleHistogram.Observe(l.Handle.Seconds())
+//	}
+func LatencyOf(s HTTPStats) Latency {
+	return Latency{
+		Total:      EventDuration(s, stats.HTTPStart, stats.HTTPFinish),
+		ReadHeader: EventDuration(s, stats.ReadHeaderStart, stats.ReadHeaderFinish),
+		ReadBody:   EventDuration(s, stats.ReadBodyStart, stats.ReadBodyFinish),
+		Handle:     EventDuration(s, stats.ServerHandleStart, stats.ServerHandleFinish),
+		Write:      EventDuration(s, stats.WriteStart, stats.WriteFinish),
+	}
+}
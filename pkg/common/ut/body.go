@@ -0,0 +1,81 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ut
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/url"
+	"sort"
+
+	"hertz-study/pkg/common/json"
+	"hertz-study/pkg/protocol/consts"
+)
+
+// File is a file part of the multipart body built by MultipartBody.
+type File struct {
+	Field   string
+	Name    string
+	Content []byte
+}
+
+// typedReader is the body built by the helpers below, carrying the Content-Type which is set
+// unless a Content-Type header is given to PerformRequest.
+type typedReader struct {
+	*bytes.Reader
+	contentType string
+}
+
+func newBody(b []byte, contentType string) *Body {
+	return &Body{Body: &typedReader{Reader: bytes.NewReader(b), contentType: contentType}, Len: len(b)}
+}
+
+// JSONBody returns the Body with v encoded as json, it panics if v can't be encoded.
+func JSONBody(v interface{}) *Body {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic("ut: marshal json body fail: " + err.Error())
+	}
+	return newBody(b, consts.MIMEApplicationJSON)
+}
+
+// FormBody returns the Body with values url-encoded.
+func FormBody(values url.Values) *Body {
+	return newBody([]byte(values.Encode()), consts.MIMEApplicationHTMLForm)
+}
+
+// MultipartBody returns the multipart/form-data Body with the fields and files,
+// fields are written in the order of their names.
+func MultipartBody(fields map[string]string, files ...File) *Body {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		// writing to bytes.Buffer never fails
+		w.WriteField(name, fields[name]) //nolint:errcheck
+	}
+	for _, f := range files {
+		fw, _ := w.CreateFormFile(f.Field, f.Name)
+		fw.Write(f.Content) //nolint:errcheck
+	}
+	w.Close() //nolint:errcheck
+	return newBody(buf.Bytes(), w.FormDataContentType())
+}
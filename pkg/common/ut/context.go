@@ -60,6 +60,14 @@ func createUtRequestContext(engine *route.Engine, method, url string, body *Body
 			ctx.Request.Header.Set(v.Key, v.Value)
 		}
 	}
+	if body != nil {
+		if r, ok := body.Body.(*typedReader); ok {
+			ctx.Request.Header.SetContentLength(body.Len)
+			if len(ctx.Request.Header.ContentType()) == 0 {
+				ctx.Request.Header.SetContentTypeBytes([]byte(r.contentType))
+			}
+		}
+	}
 
 	return ctx
 }
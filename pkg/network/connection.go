@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"os"
 	"time"
 )
 
@@ -89,6 +90,27 @@ type ErrorNormalization interface {
 	ToHertzError(err error) error
 }
 
+// IdleTracker is implemented by the connections tracking whether they are idle between the
+// requests, so that the transporter is able to close the idle ones on graceful shutdown.
+type IdleTracker interface {
+	SetIdle(idle bool)
+}
+
+// FileSender is implemented by the connections able to send a file to the peer without copying
+// it through the user space, e.g. with sendfile(2) on Linux.
+type FileSender interface {
+	// SendFile flushes the buffered data and sends count bytes of f starting at offset.
+	// errors.ErrSendFileNotSupport is returned if nothing is sent, e.g. the zero-copy is
+	// disabled or it is a TLS connection, then the file should be copied as usual.
+	SendFile(f *os.File, offset, count int64) (int64, error)
+}
+
+// FileSection is implemented by the readers of a section of a file, e.g. the big files served
+// by app.FS, so that the section can be sent by FileSender.
+type FileSection interface {
+	FileSection() (f *os.File, offset, count int64)
+}
+
 type DialFunc func(addr string) (Conn, error)
 
 /****************** Stream-based connection *******************/
@@ -0,0 +1,191 @@
+// Copyright 2022 CloudWeGo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+//go:build linux
+// +build linux
+
+package iouring
+
+import (
+	"io"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// conn is a net.Conn whose reads and writes are operations of the ring. The deadlines apply to
+// the operations started after they are set.
+type conn struct {
+	r       *ring
+	fd      int
+	network string
+	laddr   net.Addr
+	raddr   net.Addr
+
+	// guards fd against being closed while an operation is being submitted
+	mu     sync.RWMutex
+	closed bool
+
+	readDeadline  int64
+	writeDeadline int64
+}
+
+func newConn(r *ring, fd int, network string) *conn {
+	c := &conn{r: r, fd: fd, network: network}
+	if sa, err := syscall.Getsockname(fd); err == nil {
+		c.laddr = sockaddrToAddr(sa, network)
+	}
+	if sa, err := syscall.Getpeername(fd); err == nil {
+		c.raddr = sockaddrToAddr(sa, network)
+	}
+	return c
+}
+
+func (c *conn) Read(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+	res, err := c.do("read", b, &c.readDeadline, func(e *sqe) {
+		e.opcode = opRecv
+		e.fd = int32(c.fd)
+		e.addr = uint64(uintptr(unsafe.Pointer(&b[0])))
+		e.len = uint32(len(b))
+	})
+	if err != nil {
+		return 0, err
+	}
+	if res == 0 {
+		return 0, io.EOF
+	}
+	return res, nil
+}
+
+func (c *conn) Write(b []byte) (n int, err error) {
+	for n < len(b) {
+		p := b[n:]
+		var res int
+		res, err = c.do("write", p, &c.writeDeadline, func(e *sqe) {
+			e.opcode = opSend
+			e.fd = int32(c.fd)
+			e.addr = uint64(uintptr(unsafe.Pointer(&p[0])))
+			e.len = uint32(len(p))
+			e.opFlags = syscall.MSG_NOSIGNAL
+		})
+		if err != nil {
+			return n, err
+		}
+		n += res
+	}
+	return n, nil
+}
+
+// do runs the operation prepared by prep on buf, the result is the count of bytes.
+func (c *conn) do(opName string, buf []byte, deadline *int64, prep func(e *sqe)) (int, error) {
+	var d time.Time
+	if ns := atomic.LoadInt64(deadline); ns != 0 {
+		if d = time.Unix(0, ns); !time.Now().Before(d) {
+			return 0, c.opError(opName, os.ErrDeadlineExceeded)
+		}
+	}
+	c.mu.RLock()
+	if c.closed {
+		c.mu.RUnlock()
+		return 0, c.opError(opName, net.ErrClosed)
+	}
+	o, id, err := c.r.start(buf, prep)
+	c.mu.RUnlock()
+	if err != nil {
+		return 0, c.opError(opName, err)
+	}
+	res, err := c.r.wait(o, id, d)
+	if err != nil {
+		return 0, c.opError(opName, err)
+	}
+	if res < 0 {
+		return 0, c.opError(opName, os.NewSyscallError(syscallName[opName], syscall.Errno(-res)))
+	}
+	return int(res), nil
+}
+
+var syscallName = map[string]string{"read": "recv", "write": "send"}
+
+func (c *conn) opError(opName string, err error) error {
+	return &net.OpError{Op: opName, Net: c.network, Source: c.laddr, Addr: c.raddr, Err: err}
+}
+
+// Close shuts the socket down before closing it, since the operations in flight keep it open.
+func (c *conn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return c.opError("close", net.ErrClosed)
+	}
+	c.closed = true
+	syscall.Shutdown(c.fd, syscall.SHUT_RDWR) //nolint:errcheck
+	return syscall.Close(c.fd)
+}
+
+func (c *conn) LocalAddr() net.Addr {
+	return c.laddr
+}
+
+func (c *conn) RemoteAddr() net.Addr {
+	return c.raddr
+}
+
+func (c *conn) SetDeadline(t time.Time) error {
+	c.SetReadDeadline(t)  //nolint:errcheck
+	c.SetWriteDeadline(t) //nolint:errcheck
+	return nil
+}
+
+func (c *conn) SetReadDeadline(t time.Time) error {
+	atomic.StoreInt64(&c.readDeadline, unixNano(t))
+	return nil
+}
+
+func (c *conn) SetWriteDeadline(t time.Time) error {
+	atomic.StoreInt64(&c.writeDeadline, unixNano(t))
+	return nil
+}
+
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func sockaddrToAddr(sa syscall.Sockaddr, network string) net.Addr {
+	switch sa := sa.(type) {
+	case *syscall.SockaddrInet4:
+		return &net.TCPAddr{IP: append(net.IP(nil), sa.Addr[:]...), Port: sa.Port}
+	case *syscall.SockaddrInet6:
+		var zone string
+		if sa.ZoneId != 0 {
+			if ifi, err := net.InterfaceByIndex(int(sa.ZoneId)); err == nil {
+				zone = ifi.Name
+			}
+		}
+		return &net.TCPAddr{IP: append(net.IP(nil), sa.Addr[:]...), Port: sa.Port, Zone: zone}
+	case *syscall.SockaddrUnix:
+		return &net.UnixAddr{Name: sa.Name, Net: network}
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package iouring is an experimental transporter doing the accepts, reads and writes of the
+// connections with io_uring, which requires Linux 5.7 or later. Use it by
+//
+//	server.New(server.WithTransport(iouring.NewTransporter))
+//
+// ListenAndServe fails if io_uring is not available, e.g. on the other platforms or disabled by
+// the kernel.io_uring_disabled sysctl or the seccomp profile of the container.
+package iouring
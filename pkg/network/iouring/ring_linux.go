@@ -0,0 +1,385 @@
+// Copyright 2022 CloudWeGo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+//go:build linux
+// +build linux
+
+package iouring
+
+import (
+	"math"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+	errs "hertz-study/pkg/common/errors"
+)
+
+const (
+	opNop         = 0
+	opAccept      = 13
+	opAsyncCancel = 14
+	opSend        = 26
+	opRecv        = 27
+
+	setupCQSize     = 1 << 3
+	registerEventFd = 4
+	enterGetEvents  = 1 << 0
+	sqCQOverflow    = 1 << 1
+
+	offSQRing = 0
+	offCQRing = 0x8000000
+	offSQEs   = 0x10000000
+
+	sqeSize = 64
+	cqeSize = 16
+
+	// stopID is the user data of the operation stopping the reaper.
+	stopID uint64 = math.MaxUint64
+)
+
+var (
+	errRingClosed = errs.NewPrivate("io_uring is closed")
+
+	opPool = sync.Pool{New: func() interface{} {
+		return &op{done: make(chan struct{}, 1)}
+	}}
+	// cancelOp is shared by the cancellations, whose results are not waited for
+	cancelOp = &op{}
+)
+
+// sqe is struct io_uring_sqe.
+type sqe struct {
+	opcode      uint8
+	flags       uint8
+	ioprio      uint16
+	fd          int32
+	off         uint64
+	addr        uint64
+	len         uint32
+	opFlags     uint32
+	userData    uint64
+	bufIndex    uint16
+	personality uint16
+	spliceFdIn  int32
+	addr3       uint64
+	_           uint64
+}
+
+// cqe is struct io_uring_cqe.
+type cqe struct {
+	userData uint64
+	res      int32
+	flags    uint32
+}
+
+type sqringOffsets struct {
+	head, tail, ringMask, ringEntries, flags, dropped, array, resv1 uint32
+	userAddr                                                        uint64
+}
+
+type cqringOffsets struct {
+	head, tail, ringMask, ringEntries, overflow, cqes, flags, resv1 uint32
+	userAddr                                                        uint64
+}
+
+// params is struct io_uring_params.
+type params struct {
+	sqEntries, cqEntries, flags, sqThreadCPU, sqThreadIdle, features, wqFd uint32
+	resv                                                                   [3]uint32
+	sqOff                                                                  sqringOffsets
+	cqOff                                                                  cqringOffsets
+}
+
+// op is an operation submitted to the ring, it is signaled on done once it completes.
+type op struct {
+	res  int32
+	done chan struct{}
+	// keeps the buffer used by the kernel alive until the operation completes
+	buf []byte
+}
+
+// ring is an io_uring instance shared by the goroutines. The submissions are serialized by mu
+// and submitted immediately, the completions are reaped by a dedicated goroutine which is woken
+// by the eventfd registered to the ring, so that it is parked by the Go netpoller instead of
+// blocking a thread in io_uring_enter. The submissions reap the completions by themselves when
+// the completion queue is full.
+type ring struct {
+	fd     int
+	event  *os.File
+	sqRing []byte
+	cqRing []byte
+	sqeMem []byte
+
+	sqHead  *uint32
+	sqTail  *uint32
+	sqMask  uint32
+	sqFlags *uint32
+	sqArray unsafe.Pointer
+	sqes    unsafe.Pointer
+	cqHead  *uint32
+	cqTail  *uint32
+	cqMask  uint32
+	cqes    unsafe.Pointer
+
+	mu      sync.Mutex
+	ops     map[uint64]*op
+	nextID  uint64
+	closed  bool
+	stopped bool
+	done    chan struct{}
+}
+
+func newRing(entries uint32) (*ring, error) {
+	p := params{flags: setupCQSize, cqEntries: entries * 4}
+	fd, _, errno := unix.Syscall(unix.SYS_IO_URING_SETUP, uintptr(entries), uintptr(unsafe.Pointer(&p)), 0)
+	if errno != 0 {
+		return nil, os.NewSyscallError("io_uring_setup", errno)
+	}
+	r := &ring{fd: int(fd), ops: make(map[uint64]*op), done: make(chan struct{})}
+	if err := r.mmap(&p); err != nil {
+		r.unmap()
+		return nil, err
+	}
+	if err := r.registerEventFd(); err != nil {
+		r.unmap()
+		return nil, err
+	}
+	go r.reap()
+	return r, nil
+}
+
+func (r *ring) mmap(p *params) (err error) {
+	prot, flags := unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED|unix.MAP_POPULATE
+	if r.sqRing, err = unix.Mmap(r.fd, offSQRing, int(p.sqOff.array+p.sqEntries*4), prot, flags); err != nil {
+		return os.NewSyscallError("mmap", err)
+	}
+	if r.cqRing, err = unix.Mmap(r.fd, offCQRing, int(p.cqOff.cqes+p.cqEntries*cqeSize), prot, flags); err != nil {
+		return os.NewSyscallError("mmap", err)
+	}
+	if r.sqeMem, err = unix.Mmap(r.fd, offSQEs, int(p.sqEntries*sqeSize), prot, flags); err != nil {
+		return os.NewSyscallError("mmap", err)
+	}
+	r.sqHead = (*uint32)(unsafe.Pointer(&r.sqRing[p.sqOff.head]))
+	r.sqTail = (*uint32)(unsafe.Pointer(&r.sqRing[p.sqOff.tail]))
+	r.sqMask = *(*uint32)(unsafe.Pointer(&r.sqRing[p.sqOff.ringMask]))
+	r.sqFlags = (*uint32)(unsafe.Pointer(&r.sqRing[p.sqOff.flags]))
+	r.sqArray = unsafe.Pointer(&r.sqRing[p.sqOff.array])
+	r.sqes = unsafe.Pointer(&r.sqeMem[0])
+	r.cqHead = (*uint32)(unsafe.Pointer(&r.cqRing[p.cqOff.head]))
+	r.cqTail = (*uint32)(unsafe.Pointer(&r.cqRing[p.cqOff.tail]))
+	r.cqMask = *(*uint32)(unsafe.Pointer(&r.cqRing[p.cqOff.ringMask]))
+	r.cqes = unsafe.Pointer(&r.cqRing[p.cqOff.cqes])
+	return nil
+}
+
+func (r *ring) registerEventFd() error {
+	efd, err := unix.Eventfd(0, unix.EFD_NONBLOCK|unix.EFD_CLOEXEC)
+	if err != nil {
+		return os.NewSyscallError("eventfd", err)
+	}
+	// the eventfd is nonblocking, so reading it parks the goroutine in the netpoller
+	r.event = os.NewFile(uintptr(efd), "io_uring-eventfd")
+	fd := int32(efd)
+	if _, _, errno := unix.Syscall6(unix.SYS_IO_URING_REGISTER, uintptr(r.fd), registerEventFd, uintptr(unsafe.Pointer(&fd)), 1, 0, 0); errno != 0 {
+		return os.NewSyscallError("io_uring_register", errno)
+	}
+	return nil
+}
+
+func (r *ring) unmap() {
+	if r.event != nil {
+		r.event.Close() //nolint:errcheck
+	}
+	for _, b := range [][]byte{r.sqRing, r.cqRing, r.sqeMem} {
+		if b != nil {
+			unix.Munmap(b) //nolint:errcheck
+		}
+	}
+	syscall.Close(r.fd) //nolint:errcheck
+}
+
+// start submits the operation prepared by prep, buf is kept alive until it completes.
+func (r *ring) start(buf []byte, prep func(e *sqe)) (*op, uint64, error) {
+	o := opPool.Get().(*op)
+	o.buf = buf
+	id, err := r.submit(o, prep)
+	if err == errRingClosed {
+		releaseOp(o)
+	}
+	return o, id, err
+}
+
+// wait waits for the result of the operation, which is cancelled at deadline if it is not zero.
+// The result is a negative errno if the operation fails. The operation must not be used after.
+func (r *ring) wait(o *op, id uint64, deadline time.Time) (int32, error) {
+	if deadline.IsZero() {
+		<-o.done
+		res := o.res
+		releaseOp(o)
+		return res, nil
+	}
+	var fired int32
+	t := time.AfterFunc(time.Until(deadline), func() {
+		atomic.StoreInt32(&fired, 1)
+		r.cancel(id)
+	})
+	<-o.done
+	t.Stop()
+	res := o.res
+	releaseOp(o)
+	if atomic.LoadInt32(&fired) == 1 && (res == -int32(syscall.ECANCELED) || res == -int32(syscall.EINTR)) {
+		return res, os.ErrDeadlineExceeded
+	}
+	return res, nil
+}
+
+func releaseOp(o *op) {
+	o.res = 0
+	o.buf = nil
+	opPool.Put(o)
+}
+
+// cancel cancels the operation of id if it is still in flight.
+func (r *ring) cancel(id uint64) {
+	r.submit(cancelOp, func(e *sqe) { //nolint:errcheck
+		e.opcode = opAsyncCancel
+		e.fd = -1
+		e.addr = id
+	})
+}
+
+func (r *ring) submit(o *op, prep func(e *sqe)) (uint64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return 0, errRingClosed
+	}
+	id := stopID
+	if o != nil {
+		r.nextID++
+		id = r.nextID
+		r.ops[id] = o
+	}
+	// the queue is drained by every submission, so it always has room
+	tail := *r.sqTail
+	idx := tail & r.sqMask
+	e := (*sqe)(unsafe.Add(r.sqes, uintptr(idx)*sqeSize))
+	*e = sqe{}
+	prep(e)
+	e.userData = id
+	*(*uint32)(unsafe.Add(r.sqArray, uintptr(idx)*4)) = idx
+	atomic.StoreUint32(r.sqTail, tail+1)
+	for {
+		pending := tail + 1 - atomic.LoadUint32(r.sqHead)
+		if pending == 0 {
+			return id, nil
+		}
+		err := enter(r.fd, pending)
+		switch err {
+		case nil, syscall.EINTR:
+		case syscall.EAGAIN, syscall.EBUSY:
+			// the completion queue is full, reap it here since the reaper waits for mu
+			if r.dispatch() == 0 {
+				runtime.Gosched()
+			}
+		default:
+			// the operation is kept to not release its buffer, it is submitted by the next call
+			return 0, os.NewSyscallError("io_uring_enter", err)
+		}
+	}
+}
+
+// reap dispatches the completions to the waiting goroutines until the ring is closed.
+func (r *ring) reap() {
+	defer close(r.done)
+	var counter [8]byte
+	for {
+		r.mu.Lock()
+		r.dispatch()
+		stopped := r.stopped
+		r.mu.Unlock()
+		if stopped {
+			return
+		}
+		// the completions posted after the dispatch signal the eventfd as well
+		if _, err := r.event.Read(counter[:]); err != nil {
+			return
+		}
+	}
+}
+
+// dispatch dispatches the completions posted so far and returns the count of them, mu must be held.
+func (r *ring) dispatch() (n int) {
+	for {
+		head := atomic.LoadUint32(r.cqHead)
+		tail := atomic.LoadUint32(r.cqTail)
+		n += int(tail - head)
+		for ; head != tail; head++ {
+			c := (*cqe)(unsafe.Add(r.cqes, uintptr(head&r.cqMask)*cqeSize))
+			if c.userData == stopID {
+				r.stopped = true
+				continue
+			}
+			if o, ok := r.ops[c.userData]; ok {
+				delete(r.ops, c.userData)
+				o.res = c.res
+				if o.done != nil {
+					o.done <- struct{}{}
+				}
+			}
+		}
+		atomic.StoreUint32(r.cqHead, head)
+		// the completions not fitting in the queue are kept by the kernel until they are flushed
+		if atomic.LoadUint32(r.sqFlags)&sqCQOverflow == 0 || flushOverflow(r.fd) != nil {
+			return n
+		}
+	}
+}
+
+// close stops the reaper and releases the ring, there must be no operations in flight.
+func (r *ring) close() {
+	if _, err := r.submit(nil, func(e *sqe) { e.opcode = opNop }); err != nil {
+		return
+	}
+	r.mu.Lock()
+	r.closed = true
+	r.mu.Unlock()
+	<-r.done
+	r.unmap()
+}
+
+// enter submits toSubmit operations without waiting for the completions.
+func enter(fd int, toSubmit uint32) error {
+	_, _, errno := unix.Syscall6(unix.SYS_IO_URING_ENTER, uintptr(fd), uintptr(toSubmit), 0, 0, 0, 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// flushOverflow moves the overflowed completions into the completion queue.
+func flushOverflow(fd int) error {
+	_, _, errno := unix.Syscall6(unix.SYS_IO_URING_ENTER, uintptr(fd), 0, 0, enterGetEvents, 0, 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
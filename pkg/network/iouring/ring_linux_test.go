@@ -0,0 +1,60 @@
+// Copyright 2022 CloudWeGo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+//go:build linux
+// +build linux
+
+package iouring
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"hertz-study/pkg/common/test/assert"
+)
+
+func TestRingFullCompletionQueue(t *testing.T) {
+	r, err := newRing(2)
+	if err != nil {
+		t.Skipf("io_uring is not available: %v", err)
+	}
+	// the goroutines submit far more operations than the completion queue holds
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				o, id, err := r.start(nil, func(e *sqe) { e.opcode = opNop })
+				assert.Nil(t, err)
+				res, err := r.wait(o, id, time.Time{})
+				assert.Nil(t, err)
+				assert.DeepEqual(t, int32(0), res)
+			}
+		}()
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		r.close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("the submissions are stuck")
+	}
+}
@@ -0,0 +1,326 @@
+// Copyright 2022 CloudWeGo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+//go:build linux
+// +build linux
+
+package iouring
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"hertz-study/pkg/common/config"
+	errs "hertz-study/pkg/common/errors"
+	"hertz-study/pkg/common/hlog"
+	"hertz-study/pkg/network"
+	"hertz-study/pkg/network/standard"
+)
+
+const (
+	// ringEntries is the size of the submission queue of the ring.
+	ringEntries = 1024
+	// shutdownPollInterval is how often Shutdown checks the remaining connections.
+	shutdownPollInterval = 50 * time.Millisecond
+	// acceptRetryDelay is how long to wait before accepting again if the fds are exhausted.
+	acceptRetryDelay = 100 * time.Millisecond
+)
+
+var errListenerFd = errs.NewPrivate("the listener doesn't expose its file descriptor")
+
+type transport struct {
+	readBufferSize     int
+	network            string
+	addr               string
+	keepAliveTimeout   time.Duration
+	ln                 net.Listener
+	customListener     bool
+	tls                *tls.Config
+	listenConfig       *net.ListenConfig
+	unixSocketPerm     os.FileMode
+	limiter            *network.ConnLimiter
+	handler            network.OnData
+	OnAccept           func(conn net.Conn) context.Context
+	OnConnect          func(ctx context.Context, conn network.Conn) context.Context
+	panicReporter      network.PanicReporter
+	onShutdownProgress func(remaining int)
+
+	lock     sync.Mutex
+	ring     *ring
+	closing  bool
+	acceptID uint64 // the accept in flight, 0 if none
+
+	connsMu sync.Mutex
+	conns   map[network.Conn]struct{}
+}
+
+// NewTransporter creates the io_uring transporter, the PROXY protocol and the zero-copy
+// sendfile are not supported.
+func NewTransporter(options *config.Options) network.Transporter {
+	return &transport{
+		readBufferSize:     options.ReadBufferSize,
+		network:            options.Network,
+		addr:               options.Addr,
+		keepAliveTimeout:   options.KeepAliveTimeout,
+		ln:                 options.Listener,
+		customListener:     options.Listener != nil,
+		tls:                options.TLS,
+		listenConfig:       options.ListenConfig,
+		unixSocketPerm:     options.UnixSocketPerm,
+		limiter:            network.NewConnLimiter(options.MaxConcurrentConnections, options.ConnectionThrottle, options.ConnectionThrottleBurst, options.OnConnectionRejected),
+		OnAccept:           options.OnAccept,
+		OnConnect:          options.OnConnect,
+		panicReporter:      options.PanicReporter,
+		onShutdownProgress: options.OnShutdownProgress,
+	}
+}
+
+func (t *transport) ListenAndServe(onData network.OnData) (err error) {
+	if t.panicReporter != nil {
+		onData = network.RecoverOnData(onData, t.panicReporter)
+	}
+	t.handler = onData
+
+	r, err := newRing(ringEntries)
+	if err != nil {
+		return fmt.Errorf("io_uring is not available: %w", err)
+	}
+	t.lock.Lock()
+	t.ring = r
+	if !t.customListener {
+		t.ln, err = network.Listen(t.listenConfig, t.network, t.addr, t.unixSocketPerm)
+	}
+	t.lock.Unlock()
+	if err != nil {
+		return err
+	}
+	lfd, err := listenerFd(t.ln)
+	if err != nil {
+		return err
+	}
+	hlog.SystemLogger().Infof("HTTP server listening on address=%s", t.ln.Addr().String())
+	return t.serve(lfd)
+}
+
+func (t *transport) serve(lfd int) error {
+	for {
+		fd, err := t.accept(lfd)
+		if err != nil {
+			if err == errRingClosed || t.isClosing() {
+				return nil
+			}
+			if err == syscall.EMFILE || err == syscall.ENFILE || err == syscall.ECONNABORTED {
+				hlog.SystemLogger().Errorf("Accept error=%s, retrying", err)
+				time.Sleep(acceptRetryDelay)
+				continue
+			}
+			hlog.SystemLogger().Errorf("Error=%s", err.Error())
+			return os.NewSyscallError("accept", err)
+		}
+		t.setSockopts(fd)
+
+		var conn net.Conn = newConn(t.ring, fd, t.ln.Addr().Network())
+		if t.limiter != nil {
+			reason, ok := t.limiter.Acquire()
+			if !ok {
+				go t.limiter.Reject(conn, reason, t.tls != nil)
+				continue
+			}
+			conn = t.limiter.WrapConn(conn)
+		}
+
+		ctx := context.Background()
+		if t.OnAccept != nil {
+			ctx = t.OnAccept(conn)
+		}
+
+		var c network.Conn
+		if t.tls != nil {
+			c = standard.NewTLSConn(tls.Server(conn, t.tls), t.readBufferSize)
+		} else {
+			c = standard.NewConn(conn, t.readBufferSize)
+		}
+
+		if t.OnConnect != nil {
+			ctx = t.OnConnect(ctx, c)
+		}
+		t.trackConn(c, true)
+		go func() {
+			t.handler(ctx, c) //nolint:errcheck
+			t.trackConn(c, false)
+		}()
+	}
+}
+
+// accept returns the fd of the next connection, or the errno if the accept fails.
+func (t *transport) accept(lfd int) (int, error) {
+	t.lock.Lock()
+	if t.closing {
+		t.lock.Unlock()
+		return 0, errRingClosed
+	}
+	o, id, err := t.ring.start(nil, func(e *sqe) {
+		e.opcode = opAccept
+		e.fd = int32(lfd)
+		e.opFlags = syscall.SOCK_CLOEXEC
+	})
+	if err != nil {
+		t.lock.Unlock()
+		return 0, err
+	}
+	t.acceptID = id
+	t.lock.Unlock()
+
+	res, _ := t.ring.wait(o, id, time.Time{})
+	t.lock.Lock()
+	t.acceptID = 0
+	t.lock.Unlock()
+	if res < 0 {
+		return 0, syscall.Errno(-res)
+	}
+	return int(res), nil
+}
+
+func (t *transport) setSockopts(fd int) {
+	if t.ln.Addr().Network() != "tcp" {
+		return
+	}
+	// the same as the connections accepted by the net package
+	syscall.SetsockoptInt(fd, syscall.IPPROTO_TCP, syscall.TCP_NODELAY, 1) //nolint:errcheck
+	if t.keepAliveTimeout > 0 {
+		secs := int(t.keepAliveTimeout / time.Second)
+		if secs < 1 {
+			secs = 1
+		}
+		syscall.SetsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_KEEPALIVE, 1)      //nolint:errcheck
+		syscall.SetsockoptInt(fd, syscall.IPPROTO_TCP, syscall.TCP_KEEPIDLE, secs)  //nolint:errcheck
+		syscall.SetsockoptInt(fd, syscall.IPPROTO_TCP, syscall.TCP_KEEPINTVL, secs) //nolint:errcheck
+	}
+}
+
+func (t *transport) isClosing() bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.closing
+}
+
+func (t *transport) trackConn(c network.Conn, add bool) {
+	t.connsMu.Lock()
+	if add {
+		if t.conns == nil {
+			t.conns = make(map[network.Conn]struct{})
+		}
+		t.conns[c] = struct{}{}
+	} else {
+		delete(t.conns, c)
+	}
+	t.connsMu.Unlock()
+}
+
+// closeIdleConns closes the connections waiting for the next request and returns the count of
+// the remaining connections.
+func (t *transport) closeIdleConns() int {
+	t.connsMu.Lock()
+	defer t.connsMu.Unlock()
+	for c := range t.conns {
+		if ic, ok := c.(interface{ IsIdle() bool }); ok && ic.IsIdle() {
+			c.Close() //nolint:errcheck
+			delete(t.conns, c)
+		}
+	}
+	return len(t.conns)
+}
+
+// ConnLimiter implements network.LimitedTransporter.
+func (t *transport) ConnLimiter() *network.ConnLimiter {
+	return t.limiter
+}
+
+func (t *transport) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	return t.Shutdown(ctx)
+}
+
+// Shutdown stops accepting and waits for the connections to be closed, the ring is released
+// then. The busy connections are closed after their in-flight requests since the server
+// responds with "Connection: close" during shutdown.
+func (t *transport) Shutdown(ctx context.Context) error {
+	defer func() {
+		if !t.customListener {
+			network.UnlinkUdsFile(t.network, t.addr) //nolint:errcheck
+		}
+	}()
+	t.lock.Lock()
+	t.closing = true
+	if t.acceptID != 0 {
+		t.ring.cancel(t.acceptID)
+	}
+	if t.ln != nil {
+		_ = t.ln.Close()
+	}
+	r := t.ring
+	t.lock.Unlock()
+
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
+	last := -1
+	for {
+		n := t.closeIdleConns()
+		if n != last && t.onShutdownProgress != nil {
+			t.onShutdownProgress(n)
+		}
+		last = n
+		if n == 0 && !t.accepting() {
+			if r != nil {
+				r.close()
+			}
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (t *transport) accepting() bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.acceptID != 0
+}
+
+func listenerFd(ln net.Listener) (int, error) {
+	sc, ok := ln.(syscall.Conn)
+	if !ok {
+		return 0, errListenerFd
+	}
+	rc, err := sc.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+	var fd int
+	if err = rc.Control(func(s uintptr) { fd = int(s) }); err != nil {
+		return 0, err
+	}
+	return fd, nil
+}
@@ -0,0 +1,102 @@
+// Copyright 2022 CloudWeGo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+//go:build linux
+// +build linux
+
+package iouring_test
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"hertz-study/pkg/app"
+	"hertz-study/pkg/app/server"
+	"hertz-study/pkg/common/config"
+	"hertz-study/pkg/common/hlog"
+	"hertz-study/pkg/network"
+	"hertz-study/pkg/network/iouring"
+	"hertz-study/pkg/network/netpoll"
+	"hertz-study/pkg/network/standard"
+)
+
+func BenchmarkTransporter(b *testing.B) {
+	hlog.SetOutput(io.Discard)
+	for _, bc := range []struct {
+		name  string
+		newer func(options *config.Options) network.Transporter
+	}{
+		{"netpoll", netpoll.NewTransporter},
+		{"standard", standard.NewTransporter},
+		{"iouring", iouring.NewTransporter},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			benchmarkTransporter(b, bc.newer)
+		})
+	}
+}
+
+func benchmarkTransporter(b *testing.B, newer func(options *config.Options) network.Transporter) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		b.Fatal(err)
+	}
+	h := server.New(server.WithListener(ln), server.WithTransport(newer), server.WithExitWaitTime(time.Second))
+	h.GET("/", func(c context.Context, ctx *app.RequestContext) {
+		ctx.SetBodyString("hello")
+	})
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- h.Run()
+	}()
+	select {
+	case err = <-errCh:
+		b.Skip(err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	defer h.Shutdown(context.Background()) //nolint:errcheck
+
+	addr := ln.Addr().String()
+	req := []byte("GET / HTTP/1.1\r\nHost: bench\r\n\r\n")
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		c, err := net.Dial("tcp", addr)
+		if err != nil {
+			b.Error(err)
+			return
+		}
+		defer c.Close()
+		br := bufio.NewReader(c)
+		for pb.Next() {
+			if _, err = c.Write(req); err != nil {
+				b.Error(err)
+				return
+			}
+			resp, err := http.ReadResponse(br, nil)
+			if err != nil {
+				b.Error(err)
+				return
+			}
+			io.Copy(io.Discard, resp.Body) //nolint:errcheck
+			resp.Body.Close()
+		}
+	})
+}
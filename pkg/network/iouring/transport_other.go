@@ -0,0 +1,48 @@
+// Copyright 2022 CloudWeGo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+//go:build !linux
+// +build !linux
+
+package iouring
+
+import (
+	"context"
+
+	"hertz-study/pkg/common/config"
+	errs "hertz-study/pkg/common/errors"
+	"hertz-study/pkg/network"
+)
+
+var errNotSupported = errs.NewPrivate("io_uring is only supported on Linux")
+
+type transport struct{}
+
+// NewTransporter creates the io_uring transporter, whose ListenAndServe fails on this platform.
+func NewTransporter(options *config.Options) network.Transporter {
+	return &transport{}
+}
+
+func (t *transport) ListenAndServe(onData network.OnData) error {
+	return errNotSupported
+}
+
+func (t *transport) Close() error {
+	return nil
+}
+
+func (t *transport) Shutdown(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,198 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package network
+
+import (
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RejectReason is the reason why a connection is rejected by ConnLimiter.
+type RejectReason int
+
+const (
+	// RejectMaxConnections means the count of concurrent connections reaches the limit,
+	// the connection is answered with 503 and closed.
+	RejectMaxConnections RejectReason = iota
+	// RejectThrottled means the rate of new connections exceeds the limit, the connection is
+	// closed immediately.
+	RejectThrottled
+)
+
+func (r RejectReason) String() string {
+	switch r {
+	case RejectMaxConnections:
+		return "max_connections"
+	case RejectThrottled:
+		return "throttled"
+	default:
+		return "unknown"
+	}
+}
+
+// ServiceUnavailableResponse is written to the connections rejected by RejectMaxConnections
+// before closing them, if the connections are not tls ones.
+var ServiceUnavailableResponse = []byte("HTTP/1.1 503 Service Unavailable\r\nConnection: close\r\nContent-Length: 0\r\n\r\n")
+
+// ConnLimiter limits the count of concurrent connections and the rate of new connections
+// of a transporter. It is safe for concurrent use.
+type ConnLimiter struct {
+	maxConns int64
+	active   int64
+	onReject func(reason RejectReason)
+	rejected [RejectThrottled + 1]uint64
+
+	// token bucket of the new connections
+	mu       sync.Mutex
+	rate     float64
+	burst    float64
+	tokens   float64
+	lastFill time.Time
+}
+
+// NewConnLimiter creates a ConnLimiter which allows at most maxConns concurrent connections
+// and ratePerSecond new connections per second with bursts of burst connections, zero means
+// no limit. It returns nil if there is no limit at all. onReject is called for every rejected
+// connection if it is not nil.
+func NewConnLimiter(maxConns, ratePerSecond, burst int, onReject func(reason RejectReason)) *ConnLimiter {
+	if maxConns <= 0 && ratePerSecond <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &ConnLimiter{
+		maxConns: int64(maxConns),
+		onReject: onReject,
+		rate:     float64(ratePerSecond),
+		burst:    float64(burst),
+		tokens:   float64(burst),
+		lastFill: time.Now(),
+	}
+}
+
+// Acquire admits a new connection, Release must be called when the admitted connection is closed.
+func (l *ConnLimiter) Acquire() (RejectReason, bool) {
+	if !l.take() {
+		return l.reject(RejectThrottled)
+	}
+	if l.maxConns > 0 {
+		if atomic.AddInt64(&l.active, 1) > l.maxConns {
+			atomic.AddInt64(&l.active, -1)
+			return l.reject(RejectMaxConnections)
+		}
+		return 0, true
+	}
+	atomic.AddInt64(&l.active, 1)
+	return 0, true
+}
+
+// SetThrottle changes the rate of new connections to ratePerSecond with bursts of burst
+// connections at runtime, zero means no limit.
+func (l *ConnLimiter) SetThrottle(ratePerSecond, burst int) {
+	if burst < 1 {
+		burst = 1
+	}
+	l.mu.Lock()
+	l.rate = float64(ratePerSecond)
+	l.burst = float64(burst)
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.mu.Unlock()
+}
+
+// Release releases the slot of a closed connection.
+func (l *ConnLimiter) Release() {
+	atomic.AddInt64(&l.active, -1)
+}
+
+// Active returns the count of the admitted connections which are not closed yet.
+func (l *ConnLimiter) Active() int64 {
+	return atomic.LoadInt64(&l.active)
+}
+
+// Rejected returns the count of the connections rejected for reason.
+func (l *ConnLimiter) Rejected(reason RejectReason) uint64 {
+	if reason < 0 || int(reason) >= len(l.rejected) {
+		return 0
+	}
+	return atomic.LoadUint64(&l.rejected[reason])
+}
+
+// WrapConn returns a net.Conn which releases the slot of c when it is closed.
+func (l *ConnLimiter) WrapConn(c net.Conn) net.Conn {
+	return &limitedConn{Conn: c, limiter: l}
+}
+
+// Reject answers the rejected connection according to reason and closes it.
+func (l *ConnLimiter) Reject(c net.Conn, reason RejectReason, isTLS bool) {
+	if reason == RejectMaxConnections && !isTLS {
+		c.SetWriteDeadline(time.Now().Add(time.Second)) //nolint:errcheck
+		c.Write(ServiceUnavailableResponse)             //nolint:errcheck
+	}
+	c.Close()
+}
+
+func (l *ConnLimiter) reject(reason RejectReason) (RejectReason, bool) {
+	atomic.AddUint64(&l.rejected[reason], 1)
+	if l.onReject != nil {
+		l.onReject(reason)
+	}
+	return reason, false
+}
+
+func (l *ConnLimiter) take() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.rate <= 0 {
+		return true
+	}
+	now := time.Now()
+	l.tokens += now.Sub(l.lastFill).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.lastFill = now
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+type limitedConn struct {
+	net.Conn
+	limiter *ConnLimiter
+	once    sync.Once
+}
+
+func (c *limitedConn) Close() error {
+	c.once.Do(c.limiter.Release)
+	return c.Conn.Close()
+}
+
+// ReadFrom keeps the io.ReaderFrom optimization, e.g. sendfile, of the underlying connection.
+func (c *limitedConn) ReadFrom(r io.Reader) (int64, error) {
+	if rf, ok := c.Conn.(io.ReaderFrom); ok {
+		return rf.ReadFrom(r)
+	}
+	return io.Copy(struct{ io.Writer }{c.Conn}, r)
+}
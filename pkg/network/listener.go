@@ -0,0 +1,95 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package network
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+)
+
+const (
+	// environment variables of the systemd socket activation protocol, see sd_listen_fds(3)
+	listenPIDEnv     = "LISTEN_PID"
+	listenFDsEnv     = "LISTEN_FDS"
+	listenFDNamesEnv = "LISTEN_FDNAMES"
+
+	// listenFDsStart is the first fd passed by systemd, after stdin, stdout and stderr.
+	listenFDsStart = 3
+)
+
+// Listen listens on the address with lc if it is not nil. The stale unix domain socket file
+// is removed before listening, and its permission is set to perm if perm is not zero.
+func Listen(lc *net.ListenConfig, network, addr string, perm os.FileMode) (ln net.Listener, err error) {
+	UnlinkUdsFile(network, addr) //nolint:errcheck
+	if lc != nil {
+		ln, err = lc.Listen(context.Background(), network, addr)
+	} else {
+		ln, err = net.Listen(network, addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if network == "unix" && perm != 0 {
+		if err = os.Chmod(addr, perm); err != nil {
+			ln.Close()
+			return nil, fmt.Errorf("chmod unix socket: %w", err)
+		}
+	}
+	return ln, nil
+}
+
+// ActivatedListeners returns the listeners passed by systemd socket activation, the
+// environment variables are unset so that they are not inherited by the child processes.
+// It returns nil if the process is not socket activated.
+func ActivatedListeners() ([]net.Listener, error) {
+	pid, fds := os.Getenv(listenPIDEnv), os.Getenv(listenFDsEnv)
+	if pid == "" || fds == "" {
+		return nil, nil
+	}
+	defer func() {
+		os.Unsetenv(listenPIDEnv)     //nolint:errcheck
+		os.Unsetenv(listenFDsEnv)     //nolint:errcheck
+		os.Unsetenv(listenFDNamesEnv) //nolint:errcheck
+	}()
+	if p, err := strconv.Atoi(pid); err != nil || p != os.Getpid() {
+		// the fds are passed to another process
+		return nil, nil
+	}
+	n, err := strconv.Atoi(fds)
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("invalid %s=%s", listenFDsEnv, fds)
+	}
+
+	lns := make([]net.Listener, 0, n)
+	for fd := listenFDsStart; fd < listenFDsStart+n; fd++ {
+		f := os.NewFile(uintptr(fd), "LISTEN_FD_"+strconv.Itoa(fd))
+		// FileListener dups the fd, so the original one is always closed
+		ln, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			for _, l := range lns {
+				l.Close()
+			}
+			return nil, fmt.Errorf("activated listener of fd=%d: %w", fd, err)
+		}
+		lns = append(lns, ln)
+	}
+	return lns, nil
+}
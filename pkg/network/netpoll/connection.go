@@ -13,11 +13,15 @@
 // limitations under the License.
 //
 
+//go:build linux || darwin || freebsd
+// +build linux darwin freebsd
+
 package netpoll
 
 import (
 	"errors"
 	"io"
+	"net"
 	"strings"
 	"syscall"
 
@@ -29,6 +33,17 @@ import (
 
 type Conn struct {
 	network.Conn
+	// set by the transporter to track the idle connections
+	onIdle func(idle bool)
+	// set by the transporter if WithSenseFile is enabled
+	sendFile bool
+}
+
+// SetIdle marks whether the connection is waiting for the next request.
+func (c *Conn) SetIdle(idle bool) {
+	if c.onIdle != nil {
+		c.onIdle(idle)
+	}
 }
 
 func (c *Conn) ToHertzError(err error) error {
@@ -36,8 +51,7 @@ func (c *Conn) ToHertzError(err error) error {
 		return errs.ErrConnectionClosed
 	}
 
-	// only unify read timeout for now
-	if errors.Is(err, netpoll.ErrReadTimeout) {
+	if errors.Is(err, netpoll.ErrReadTimeout) || errors.Is(err, netpoll.ErrWriteTimeout) {
 		return errs.ErrTimeout
 	}
 	return err
@@ -111,6 +125,26 @@ func normalizeErr(err error) error {
 	return err
 }
 
+// proxiedConn reports the addresses carried by the PROXY protocol header.
+type proxiedConn struct {
+	network.Conn
+	src, dst net.Addr
+}
+
+func (c *proxiedConn) RemoteAddr() net.Addr {
+	if c.src != nil {
+		return c.src
+	}
+	return c.Conn.RemoteAddr()
+}
+
+func (c *proxiedConn) LocalAddr() net.Addr {
+	if c.dst != nil {
+		return c.dst
+	}
+	return c.Conn.LocalAddr()
+}
+
 func newConn(c netpoll.Connection) network.Conn {
 	return &Conn{Conn: c.(network.Conn)}
 }
@@ -13,6 +13,9 @@
 // limitations under the License.
 //
 
+//go:build linux || darwin || freebsd
+// +build linux darwin freebsd
+
 package netpoll
 
 import (
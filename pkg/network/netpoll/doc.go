@@ -14,12 +14,7 @@
  * limitations under the License.
  */
 
-package route
-
-import (
-	"hertz-study/pkg/network/standard"
-)
-
-func init() {
-	defaultTransporter = standard.NewTransporter
-}
+// Package netpoll is the transporter and dialer based on github.com/cloudwego/netpoll, which
+// are the defaults on linux, darwin and freebsd. The package is empty on the other platforms,
+// where netpoll is unavailable and the standard package is used instead.
+package netpoll
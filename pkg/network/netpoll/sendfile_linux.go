@@ -0,0 +1,112 @@
+// Copyright 2022 CloudWeGo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+//go:build linux
+// +build linux
+
+package netpoll
+
+import (
+	"io"
+	"os"
+	"syscall"
+
+	"github.com/cloudwego/netpoll"
+	errs "hertz-study/pkg/common/errors"
+)
+
+const (
+	// maxSendFileSize is the max count of bytes sent by a single sendfile(2).
+	maxSendFileSize = 4 << 20
+	// sendFileChunkSize is the count of bytes written through the connection when the socket is full.
+	sendFileChunkSize = 64 << 10
+)
+
+// SendFile implements network.FileSender with sendfile(2) on the socket of the connection.
+func (c *Conn) SendFile(f *os.File, offset, count int64) (int64, error) {
+	fd, ok := c.fd()
+	if !c.sendFile || !ok {
+		return 0, errs.ErrSendFileNotSupport
+	}
+	if err := c.Flush(); err != nil {
+		return 0, err
+	}
+	src := int(f.Fd())
+	var written int64
+	for written < count {
+		size := count - written
+		if size > maxSendFileSize {
+			size = maxSendFileSize
+		}
+		n, err := syscall.Sendfile(fd, src, &offset, int(size))
+		if n > 0 {
+			written += int64(n)
+		}
+		switch {
+		case err == syscall.EAGAIN:
+			// the socket is full, write a chunk through the connection instead, whose Flush parks
+			// on the poller until the socket drains or the write timeout fires
+			m, err := c.writeChunk(f, offset, count-written)
+			written += m
+			offset += m
+			if err != nil {
+				return written, err
+			}
+		case err == syscall.EINTR:
+		case err != nil:
+			return written, err
+		case n == 0:
+			// the file is truncated
+			return written, io.ErrUnexpectedEOF
+		}
+	}
+	return written, nil
+}
+
+// writeChunk writes at most sendFileChunkSize bytes of f from offset through the buffer of the
+// connection and flushes them.
+func (c *Conn) writeChunk(f *os.File, offset, count int64) (int64, error) {
+	if count > sendFileChunkSize {
+		count = sendFileChunkSize
+	}
+	buf, err := c.Malloc(int(count))
+	if err != nil {
+		return 0, err
+	}
+	n, err := f.ReadAt(buf, offset)
+	if n < len(buf) {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		// the connection is closed by the caller, buf is never sent
+		return 0, err
+	}
+	if err = c.Flush(); err != nil {
+		return 0, err
+	}
+	return int64(n), nil
+}
+
+// fd returns the socket of the connection.
+func (c *Conn) fd() (int, bool) {
+	conn := c.Conn
+	if pc, ok := conn.(*proxiedConn); ok {
+		conn = pc.Conn
+	}
+	if nc, ok := conn.(netpoll.Conn); ok {
+		return nc.Fd(), true
+	}
+	return 0, false
+}
@@ -0,0 +1,30 @@
+// Copyright 2022 CloudWeGo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+//go:build darwin || freebsd
+// +build darwin freebsd
+
+package netpoll
+
+import (
+	"os"
+
+	errs "hertz-study/pkg/common/errors"
+)
+
+// SendFile implements network.FileSender, the files are copied as usual on this platform.
+func (c *Conn) SendFile(f *os.File, offset, count int64) (int64, error) {
+	return 0, errs.ErrSendFileNotSupport
+}
@@ -13,8 +13,8 @@
 // limitations under the License.
 //
 
-//go:build !windows
-// +build !windows
+//go:build linux || darwin || freebsd
+// +build linux darwin freebsd
 
 package netpoll
 
@@ -22,7 +22,9 @@ import (
 	"context"
 	"io"
 	"net"
+	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/cloudwego/netpoll"
@@ -38,56 +40,100 @@ func init() {
 
 type transporter struct {
 	sync.RWMutex
-	network          string
-	addr             string
-	keepAliveTimeout time.Duration
-	readTimeout      time.Duration
-	writeTimeout     time.Duration
-	listener         net.Listener
-	eventLoop        netpoll.EventLoop
-	listenConfig     *net.ListenConfig
-	OnAccept         func(conn net.Conn) context.Context
-	OnConnect        func(ctx context.Context, conn network.Conn) context.Context
+	network            string
+	addr               string
+	keepAliveTimeout   time.Duration
+	readTimeout        time.Duration
+	writeTimeout       time.Duration
+	listener           net.Listener
+	customListener     bool
+	eventLoop          netpoll.EventLoop
+	listenConfig       *net.ListenConfig
+	unixSocketPerm     os.FileMode
+	limiter            *network.ConnLimiter
+	proxyProtocol      bool
+	senseFile          bool
+	proxyConns         sync.Map // netpoll.Connection -> *proxiedConn
+	OnAccept           func(conn net.Conn) context.Context
+	OnConnect          func(ctx context.Context, conn network.Conn) context.Context
+	panicReporter      network.PanicReporter
+	onShutdownProgress func(remaining int)
+	// count of the open connections
+	conns int64
+	// netpoll.Connection waiting for the next request -> struct{}
+	idleConns sync.Map
 }
 
+// shutdownPollInterval is how often Shutdown reports the remaining connections.
+const shutdownPollInterval = 50 * time.Millisecond
+
 // For transporter switch
 func NewTransporter(options *config.Options) network.Transporter {
 	return &transporter{
-		network:          options.Network,
-		addr:             options.Addr,
-		keepAliveTimeout: options.KeepAliveTimeout,
-		readTimeout:      options.ReadTimeout,
-		writeTimeout:     options.WriteTimeout,
-		listener:         nil,
-		eventLoop:        nil,
-		listenConfig:     options.ListenConfig,
-		OnAccept:         options.OnAccept,
-		OnConnect:        options.OnConnect,
+		network:            options.Network,
+		addr:               options.Addr,
+		keepAliveTimeout:   options.KeepAliveTimeout,
+		readTimeout:        options.ReadTimeout,
+		writeTimeout:       options.WriteTimeout,
+		listener:           options.Listener,
+		customListener:     options.Listener != nil,
+		eventLoop:          nil,
+		listenConfig:       options.ListenConfig,
+		unixSocketPerm:     options.UnixSocketPerm,
+		limiter:            network.NewConnLimiter(options.MaxConcurrentConnections, options.ConnectionThrottle, options.ConnectionThrottleBurst, options.OnConnectionRejected),
+		proxyProtocol:      options.ProxyProtocol,
+		senseFile:          options.SenseFile,
+		OnAccept:           options.OnAccept,
+		OnConnect:          options.OnConnect,
+		panicReporter:      options.PanicReporter,
+		onShutdownProgress: options.OnShutdownProgress,
 	}
 }
 
 // ListenAndServe binds listen address and keep serving, until an error occurs
 // or the transport shutdowns
 func (t *transporter) ListenAndServe(onReq network.OnData) (err error) {
-	network.UnlinkUdsFile(t.network, t.addr) //nolint:errcheck
-	if t.listenConfig != nil {
-		t.listener, err = t.listenConfig.Listen(context.Background(), t.network, t.addr)
-	} else {
-		t.listener, err = net.Listen(t.network, t.addr)
+	if t.panicReporter != nil {
+		onReq = network.RecoverOnData(onReq, t.panicReporter)
 	}
-
-	if err != nil {
-		panic("create netpoll listener fail: " + err.Error())
+	if !t.customListener {
+		t.listener, err = network.Listen(t.listenConfig, t.network, t.addr, t.unixSocketPerm)
+		if err != nil {
+			panic("create netpoll listener fail: " + err.Error())
+		}
 	}
 
 	// Initialize custom option for EventLoop
 	opts := []netpoll.Option{
 		netpoll.WithIdleTimeout(t.keepAliveTimeout),
 		netpoll.WithOnPrepare(func(conn netpoll.Connection) context.Context {
+			if t.limiter != nil {
+				reason, ok := t.limiter.Acquire()
+				if !ok {
+					t.limiter.Reject(conn, reason, false)
+					return context.Background()
+				}
+				conn.AddCloseCallback(func(netpoll.Connection) error {
+					t.limiter.Release()
+					return nil
+				})
+			}
+			atomic.AddInt64(&t.conns, 1)
+			conn.AddCloseCallback(func(c netpoll.Connection) error {
+				atomic.AddInt64(&t.conns, -1)
+				t.idleConns.Delete(c)
+				return nil
+			})
 			conn.SetReadTimeout(t.readTimeout) // nolint:errcheck
 			if t.writeTimeout > 0 {
 				conn.SetWriteTimeout(t.writeTimeout)
 			}
+			if t.proxyProtocol {
+				conn.AddCloseCallback(func(c netpoll.Connection) error {
+					t.proxyConns.Delete(c)
+					return nil
+				})
+			}
 			if t.OnAccept != nil {
 				return t.OnAccept(newConn(conn))
 			}
@@ -104,7 +150,18 @@ func (t *transporter) ListenAndServe(onReq network.OnData) (err error) {
 	// Create EventLoop
 	t.Lock()
 	t.eventLoop, err = netpoll.NewEventLoop(func(ctx context.Context, connection netpoll.Connection) error {
-		return onReq(ctx, newConn(connection))
+		if t.proxyProtocol {
+			c, err := t.proxiedConn(connection)
+			if err != nil {
+				connection.Close()
+				return err
+			}
+			t.initConn(c, connection)
+			return onReq(ctx, c)
+		}
+		c := newConn(connection).(*Conn)
+		t.initConn(c, connection)
+		return onReq(ctx, c)
 	}, opts...)
 	t.Unlock()
 	if err != nil {
@@ -123,7 +180,53 @@ func (t *transporter) ListenAndServe(onReq network.OnData) (err error) {
 	return nil
 }
 
+func (t *transporter) initConn(c *Conn, connection netpoll.Connection) {
+	c.onIdle = t.idleTracker(connection)
+	c.sendFile = t.senseFile
+}
+
+// idleTracker returns the function tracking whether the connection is idle, the idle connections
+// are closed on Shutdown since netpoll regards them as busy while the server waits for the next
+// request in OnRequest.
+func (t *transporter) idleTracker(connection netpoll.Connection) func(idle bool) {
+	return func(idle bool) {
+		if idle {
+			t.idleConns.Store(connection, struct{}{})
+		} else {
+			t.idleConns.Delete(connection)
+		}
+	}
+}
+
+// closeIdleConns closes the connections waiting for the next request.
+func (t *transporter) closeIdleConns() {
+	t.idleConns.Range(func(k, _ interface{}) bool {
+		k.(netpoll.Connection).Close() //nolint:errcheck
+		t.idleConns.Delete(k)
+		return true
+	})
+}
+
+// proxiedConn reads the PROXY protocol header on the first request of the connection.
+func (t *transporter) proxiedConn(connection netpoll.Connection) (*Conn, error) {
+	if c, ok := t.proxyConns.Load(connection); ok {
+		return &Conn{Conn: c.(*proxiedConn)}, nil
+	}
+	c := &proxiedConn{Conn: connection.(network.Conn)}
+	var err error
+	if c.src, c.dst, err = network.ReadProxyHeader(c.Conn); err != nil {
+		return nil, err
+	}
+	t.proxyConns.Store(connection, c)
+	return &Conn{Conn: c}, nil
+}
+
 // Close forces transport to close immediately (no wait timeout)
+// ConnLimiter implements network.LimitedTransporter.
+func (t *transporter) ConnLimiter() *network.ConnLimiter {
+	return t.limiter
+}
+
 func (t *transporter) Close() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 0)
 	defer cancel()
@@ -134,12 +237,44 @@ func (t *transporter) Close() error {
 // It will wait all connections close until reaching ctx.Deadline()
 func (t *transporter) Shutdown(ctx context.Context) error {
 	defer func() {
-		network.UnlinkUdsFile(t.network, t.addr) //nolint:errcheck
+		if !t.customListener {
+			network.UnlinkUdsFile(t.network, t.addr) //nolint:errcheck
+		}
 		t.RUnlock()
 	}()
 	t.RLock()
 	if t.eventLoop == nil {
 		return nil
 	}
-	return t.eventLoop.Shutdown(ctx)
+
+	// netpoll closes the idle connections and waits for the busy ones, the connections waiting
+	// for the next request are busy to netpoll so they are closed here
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(shutdownPollInterval)
+		defer ticker.Stop()
+		last := int64(-1)
+		report := func() {
+			if n := atomic.LoadInt64(&t.conns); n != last && t.onShutdownProgress != nil {
+				t.onShutdownProgress(int(n))
+				last = n
+			}
+		}
+		for {
+			t.closeIdleConns()
+			report()
+			select {
+			case <-done:
+				report()
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	err := t.eventLoop.Shutdown(ctx)
+	close(done)
+	<-stopped
+	return err
 }
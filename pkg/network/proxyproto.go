@@ -0,0 +1,253 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package network
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// proxyV1MaxLen is the max length of a v1 header including the CRLF.
+	proxyV1MaxLen = 107
+	// proxyV2HeaderLen is the length of the fixed part of a v2 header.
+	proxyV2HeaderLen = 16
+
+	// proxyReaderSize limits the length of a v2 header including the TLVs.
+	proxyReaderSize = 4096
+
+	defaultProxyHeaderTimeout = 5 * time.Second
+)
+
+var proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// ErrProxyProtocolHeader is returned by the reads of a ProxyProtocolConn whose peer does not
+// send a valid PROXY protocol header.
+var ErrProxyProtocolHeader = errors.New("invalid PROXY protocol header")
+
+// ProxyProtocolConn is a net.Conn accepted from a load balancer speaking the HAProxy PROXY
+// protocol v1 or v2. The header is read on the first Read or the first call of RemoteAddr and
+// LocalAddr, which report the addresses of the original connection carried by the header.
+// The connection is unusable if the header is invalid, so it must be used only for listeners
+// which can only be reached through the load balancers.
+type ProxyProtocolConn struct {
+	net.Conn
+
+	timeout time.Duration
+	once    sync.Once
+	r       *bufio.Reader
+	err     error
+	src     net.Addr
+	dst     net.Addr
+}
+
+// NewProxyProtocolConn wraps conn to read the PROXY protocol header within timeout,
+// default is 5s if timeout is not positive.
+func NewProxyProtocolConn(conn net.Conn, timeout time.Duration) *ProxyProtocolConn {
+	if timeout <= 0 {
+		timeout = defaultProxyHeaderTimeout
+	}
+	return &ProxyProtocolConn{Conn: conn, timeout: timeout}
+}
+
+func (c *ProxyProtocolConn) Read(b []byte) (int, error) {
+	c.once.Do(c.readHeader)
+	if c.err != nil {
+		return 0, c.err
+	}
+	if c.r != nil {
+		if c.r.Buffered() > 0 {
+			return c.r.Read(b)
+		}
+		// the bytes read ahead are drained
+		c.r = nil
+	}
+	return c.Conn.Read(b)
+}
+
+// RemoteAddr returns the source address carried by the header, or the address of the peer
+// if the header is "UNKNOWN" or of the LOCAL command, e.g. the health checks.
+func (c *ProxyProtocolConn) RemoteAddr() net.Addr {
+	c.once.Do(c.readHeader)
+	if c.src != nil {
+		return c.src
+	}
+	return c.Conn.RemoteAddr()
+}
+
+// ReadFrom keeps the io.ReaderFrom optimization, e.g. sendfile, of the underlying connection.
+func (c *ProxyProtocolConn) ReadFrom(r io.Reader) (int64, error) {
+	if rf, ok := c.Conn.(io.ReaderFrom); ok {
+		return rf.ReadFrom(r)
+	}
+	return io.Copy(struct{ io.Writer }{c.Conn}, r)
+}
+
+// LocalAddr returns the destination address carried by the header, or the local address.
+func (c *ProxyProtocolConn) LocalAddr() net.Addr {
+	c.once.Do(c.readHeader)
+	if c.dst != nil {
+		return c.dst
+	}
+	return c.Conn.LocalAddr()
+}
+
+func (c *ProxyProtocolConn) readHeader() {
+	c.Conn.SetReadDeadline(time.Now().Add(c.timeout)) //nolint:errcheck
+	c.r = bufio.NewReaderSize(c.Conn, proxyReaderSize)
+	var n int
+	c.src, c.dst, n, c.err = parseProxyHeader(c.r.Peek)
+	if c.err == nil {
+		c.r.Discard(n) //nolint:errcheck
+	}
+	// the deadline of the protocol takes over
+	c.Conn.SetReadDeadline(time.Time{}) //nolint:errcheck
+	if c.err != nil {
+		c.Conn.Close()
+	}
+}
+
+// ReadProxyHeader reads the PROXY protocol v1 or v2 header from r and returns the addresses of
+// the original connection carried by it, which are nil if the header is "UNKNOWN" or of the
+// LOCAL command.
+func ReadProxyHeader(r Reader) (src, dst net.Addr, err error) {
+	src, dst, n, err := parseProxyHeader(r.Peek)
+	if err != nil {
+		return nil, nil, err
+	}
+	return src, dst, r.Skip(n)
+}
+
+// parseProxyHeader parses the header by peek and returns the length of it.
+func parseProxyHeader(peek func(n int) ([]byte, error)) (src, dst net.Addr, n int, err error) {
+	prefix, err := peek(6)
+	if err != nil {
+		return nil, nil, 0, ErrProxyProtocolHeader
+	}
+	if string(prefix) == "PROXY " {
+		return parseProxyV1(peek)
+	}
+	if !bytes.Equal(prefix, proxyV2Signature[:len(prefix)]) {
+		return nil, nil, 0, ErrProxyProtocolHeader
+	}
+	if prefix, err = peek(proxyV2HeaderLen); err == nil && bytes.Equal(prefix[:len(proxyV2Signature)], proxyV2Signature) {
+		return parseProxyV2(peek)
+	}
+	return nil, nil, 0, ErrProxyProtocolHeader
+}
+
+// parseProxyV1 parses the header like "PROXY TCP4 192.168.0.1 192.168.0.11 56324 443\r\n".
+func parseProxyV1(peek func(n int) ([]byte, error)) (src, dst net.Addr, n int, err error) {
+	var line []byte
+	// peek byte by byte to not wait for the bytes after the header
+	for n = 7; ; n++ {
+		if n > proxyV1MaxLen {
+			return nil, nil, 0, ErrProxyProtocolHeader
+		}
+		if line, err = peek(n); err != nil {
+			return nil, nil, 0, ErrProxyProtocolHeader
+		}
+		if line[n-1] == '\n' {
+			break
+		}
+	}
+	if line[n-2] != '\r' {
+		return nil, nil, 0, ErrProxyProtocolHeader
+	}
+	fields := strings.Split(string(line[:n-2]), " ")
+	if fields[1] == "UNKNOWN" {
+		return nil, nil, n, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, nil, 0, ErrProxyProtocolHeader
+	}
+	if src, err = parseProxyV1Addr(fields[2], fields[4]); err != nil {
+		return nil, nil, 0, err
+	}
+	if dst, err = parseProxyV1Addr(fields[3], fields[5]); err != nil {
+		return nil, nil, 0, err
+	}
+	return src, dst, n, nil
+}
+
+func parseProxyV1Addr(host, port string) (net.Addr, error) {
+	ip := net.ParseIP(host)
+	p, err := strconv.ParseUint(port, 10, 16)
+	if ip == nil || err != nil {
+		return nil, ErrProxyProtocolHeader
+	}
+	return &net.TCPAddr{IP: ip, Port: int(p)}, nil
+}
+
+func parseProxyV2(peek func(n int) ([]byte, error)) (src, dst net.Addr, n int, err error) {
+	header, _ := peek(proxyV2HeaderLen)
+	verCmd, famProto := header[12], header[13]
+	if verCmd>>4 != 2 {
+		return nil, nil, 0, fmt.Errorf("%w: unsupported version %d", ErrProxyProtocolHeader, verCmd>>4)
+	}
+	n = proxyV2HeaderLen + int(binary.BigEndian.Uint16(header[14:]))
+	b, err := peek(n)
+	if err != nil {
+		return nil, nil, 0, ErrProxyProtocolHeader
+	}
+	payload := b[proxyV2HeaderLen:]
+
+	switch verCmd & 0xf {
+	case 0x0:
+		// LOCAL, the connection is established by the proxy itself
+		return nil, nil, n, nil
+	case 0x1:
+	default:
+		return nil, nil, 0, fmt.Errorf("%w: unsupported command %d", ErrProxyProtocolHeader, verCmd&0xf)
+	}
+
+	// the TLVs following the addresses are ignored
+	switch famProto >> 4 {
+	case 0x1:
+		if len(payload) < 12 {
+			return nil, nil, 0, ErrProxyProtocolHeader
+		}
+		return proxyV2Addr(famProto, payload[0:4], payload[8:10]), proxyV2Addr(famProto, payload[4:8], payload[10:12]), n, nil
+	case 0x2:
+		if len(payload) < 36 {
+			return nil, nil, 0, ErrProxyProtocolHeader
+		}
+		return proxyV2Addr(famProto, payload[0:16], payload[32:34]), proxyV2Addr(famProto, payload[16:32], payload[34:36]), n, nil
+	default:
+		// AF_UNSPEC or AF_UNIX, keep the addresses of the connection
+		return nil, nil, n, nil
+	}
+}
+
+func proxyV2Addr(famProto byte, ip, port []byte) net.Addr {
+	addrIP := make(net.IP, len(ip))
+	copy(addrIP, ip)
+	p := int(binary.BigEndian.Uint16(port))
+	if famProto&0xf == 0x2 {
+		return &net.UDPAddr{IP: addrIP, Port: p}
+	}
+	return &net.TCPAddr{IP: addrIP, Port: p}
+}
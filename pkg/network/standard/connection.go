@@ -21,8 +21,10 @@ import (
 	"errors"
 	"io"
 	"net"
+	"os"
 	"runtime"
 	"strconv"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -46,10 +48,26 @@ type Conn struct {
 	outputBuffer *linkBuffer
 	caches       [][]byte // buf allocated by Next when cross-package, which should be freed when release
 	maxSize      int      // history max malloc size
+	idle         int32
+	sendFile     bool // set by the transport if WithSenseFile is enabled
 
 	err error
 }
 
+// SetIdle marks whether the connection is waiting for the next request.
+func (c *Conn) SetIdle(idle bool) {
+	var v int32
+	if idle {
+		v = 1
+	}
+	atomic.StoreInt32(&c.idle, v)
+}
+
+// IsIdle reports whether the connection is waiting for the next request.
+func (c *Conn) IsIdle() bool {
+	return atomic.LoadInt32(&c.idle) == 1
+}
+
 func (c *Conn) ToHertzError(err error) error {
 	if errors.Is(err, syscall.EPIPE) || errors.Is(err, syscall.ENOTCONN) {
 		return errs.ErrConnectionClosed
@@ -181,6 +199,22 @@ func (c *Conn) ReadFrom(r io.Reader) (n int64, err error) {
 	return
 }
 
+// SendFile implements network.FileSender with the io.ReaderFrom of the underlying connection,
+// which sends the file with sendfile(2) if it is a *net.TCPConn.
+func (c *Conn) SendFile(f *os.File, offset, count int64) (int64, error) {
+	rf, ok := c.c.(io.ReaderFrom)
+	if !c.sendFile || !ok {
+		return 0, errs.ErrSendFileNotSupport
+	}
+	if err := c.Flush(); err != nil {
+		return 0, err
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return rf.ReadFrom(&io.LimitedReader{R: f, N: count})
+}
+
 // Close closes the connection
 func (c *Conn) Close() error {
 	return c.c.Close()
@@ -579,6 +613,17 @@ func (c *TLSConn) ConnectionState() tls.ConnectionState {
 	return c.c.(network.ConnTLSer).ConnectionState()
 }
 
+// NewConn returns the buffered connection of c used by this transport, e.g. for the transporters
+// implementing net.Conn with other I/O interfaces. size is the initial size of the read buffer.
+func NewConn(c net.Conn, size int) network.Conn {
+	return newConn(c, size)
+}
+
+// NewTLSConn is NewConn for the TLS connections.
+func NewTLSConn(c net.Conn, size int) network.Conn {
+	return newTLSConn(c, size)
+}
+
 func newConn(c net.Conn, size int) network.Conn {
 	maxSize := defaultMallocSize
 	if size > maxSize {
@@ -19,8 +19,11 @@ package standard
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"net"
+	"os"
 	"sync"
+	"syscall"
 	"time"
 
 	"hertz-study/pkg/common/config"
@@ -36,32 +39,47 @@ type transport struct {
 	// and/or multi-KB headers (for example, BIG cookies).
 	//
 	// Default buffer size is used if not set.
-	readBufferSize   int
-	network          string
-	addr             string
-	keepAliveTimeout time.Duration
-	readTimeout      time.Duration
-	handler          network.OnData
-	ln               net.Listener
-	tls              *tls.Config
-	listenConfig     *net.ListenConfig
-	lock             sync.Mutex
-	OnAccept         func(conn net.Conn) context.Context
-	OnConnect        func(ctx context.Context, conn network.Conn) context.Context
+	readBufferSize     int
+	network            string
+	addr               string
+	keepAliveTimeout   time.Duration
+	readTimeout        time.Duration
+	handler            network.OnData
+	ln                 net.Listener
+	customListener     bool
+	tls                *tls.Config
+	listenConfig       *net.ListenConfig
+	unixSocketPerm     os.FileMode
+	limiter            *network.ConnLimiter
+	proxyProtocol      bool
+	senseFile          bool
+	lock               sync.Mutex
+	closing            bool // guarded by lock
+	OnAccept           func(conn net.Conn) context.Context
+	OnConnect          func(ctx context.Context, conn network.Conn) context.Context
+	panicReporter      network.PanicReporter
+	onShutdownProgress func(remaining int)
+
+	connsMu sync.Mutex
+	conns   map[network.Conn]struct{}
 }
 
+const (
+	// shutdownPollInterval is how often Shutdown checks the remaining connections.
+	shutdownPollInterval = 50 * time.Millisecond
+	// acceptRetryDelay is how long to wait before accepting again if the fds are exhausted.
+	acceptRetryDelay = 100 * time.Millisecond
+)
+
 // 开启服务
 func (t *transport) serve() (err error) {
-	network.UnlinkUdsFile(t.network, t.addr) //nolint:errcheck
-	t.lock.Lock()
-	if t.listenConfig != nil {
-		t.ln, err = t.listenConfig.Listen(context.Background(), t.network, t.addr)
-	} else {
-		t.ln, err = net.Listen(t.network, t.addr)
-	}
-	t.lock.Unlock()
-	if err != nil {
-		return err
+	if !t.customListener {
+		t.lock.Lock()
+		t.ln, err = network.Listen(t.listenConfig, t.network, t.addr, t.unixSocketPerm)
+		t.lock.Unlock()
+		if err != nil {
+			return err
+		}
 	}
 	hlog.SystemLogger().Infof("HTTP server listening on address=%s", t.ln.Addr().String())
 	for {
@@ -69,32 +87,112 @@ func (t *transport) serve() (err error) {
 		conn, err := t.ln.Accept()
 		var c network.Conn
 		if err != nil {
+			if t.isClosing() {
+				// the listener is closed by Shutdown, the same as netpoll stops serving
+				return nil
+			}
+			if errors.Is(err, syscall.EMFILE) || errors.Is(err, syscall.ENFILE) || errors.Is(err, syscall.ECONNABORTED) {
+				hlog.SystemLogger().Errorf("Accept error=%s, retrying", err)
+				time.Sleep(acceptRetryDelay)
+				continue
+			}
 			hlog.SystemLogger().Errorf("Error=%s", err.Error())
 			return err
 		}
 
-		if t.OnAccept != nil {
-			ctx = t.OnAccept(conn)
+		if t.limiter != nil {
+			reason, ok := t.limiter.Acquire()
+			if !ok {
+				go t.limiter.Reject(conn, reason, t.tls != nil)
+				continue
+			}
+			conn = t.limiter.WrapConn(conn)
+		}
+
+		if t.proxyProtocol {
+			// the header is read lazily to not block the loop
+			conn = network.NewProxyProtocolConn(conn, t.readTimeout)
 		}
 
 		if t.tls != nil {
 			c = newTLSConn(tls.Server(conn, t.tls), t.readBufferSize)
 		} else {
 			c = newConn(conn, t.readBufferSize)
+			c.(*Conn).sendFile = t.senseFile
+		}
+
+		// the addresses of a PROXY protocol conn wait for its header, so are the callbacks using them
+		if !t.proxyProtocol {
+			ctx = t.connContext(ctx, conn, c)
+		}
+		t.trackConn(c, true)
+		go func() {
+			if t.proxyProtocol {
+				ctx = t.connContext(ctx, conn, c)
+			}
+			t.handler(ctx, c) //nolint:errcheck
+			t.trackConn(c, false)
+		}()
+	}
+}
+
+// connContext runs the OnAccept and OnConnect callbacks of a new connection.
+func (t *transport) connContext(ctx context.Context, conn net.Conn, c network.Conn) context.Context {
+	if t.OnAccept != nil {
+		ctx = t.OnAccept(conn)
+	}
+	if t.OnConnect != nil {
+		ctx = t.OnConnect(ctx, c)
+	}
+	return ctx
+}
+
+func (t *transport) isClosing() bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.closing
+}
+
+func (t *transport) trackConn(c network.Conn, add bool) {
+	t.connsMu.Lock()
+	if add {
+		if t.conns == nil {
+			t.conns = make(map[network.Conn]struct{})
 		}
+		t.conns[c] = struct{}{}
+	} else {
+		delete(t.conns, c)
+	}
+	t.connsMu.Unlock()
+}
 
-		if t.OnConnect != nil {
-			ctx = t.OnConnect(ctx, c)
+// closeIdleConns closes the connections waiting for the next request and returns the count of
+// the remaining connections.
+func (t *transport) closeIdleConns() int {
+	t.connsMu.Lock()
+	defer t.connsMu.Unlock()
+	for c := range t.conns {
+		if ic, ok := c.(interface{ IsIdle() bool }); ok && ic.IsIdle() {
+			c.Close() //nolint:errcheck
+			delete(t.conns, c)
 		}
-		go t.handler(ctx, c)
 	}
+	return len(t.conns)
 }
 
 func (t *transport) ListenAndServe(onData network.OnData) (err error) {
+	if t.panicReporter != nil {
+		onData = network.RecoverOnData(onData, t.panicReporter)
+	}
 	t.handler = onData
 	return t.serve()
 }
 
+// ConnLimiter implements network.LimitedTransporter.
+func (t *transport) ConnLimiter() *network.ConnLimiter {
+	return t.limiter
+}
+
 func (t *transport) Close() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 0)
 	defer cancel()
@@ -103,28 +201,59 @@ func (t *transport) Close() error {
 
 func (t *transport) Shutdown(ctx context.Context) error {
 	defer func() {
-		network.UnlinkUdsFile(t.network, t.addr) //nolint:errcheck
+		if !t.customListener {
+			network.UnlinkUdsFile(t.network, t.addr) //nolint:errcheck
+		}
 	}()
 	t.lock.Lock()
+	t.closing = true
 	if t.ln != nil {
 		_ = t.ln.Close()
 	}
 	t.lock.Unlock()
-	<-ctx.Done()
-	return nil
+
+	// the busy connections are closed after their in-flight requests since the server
+	// responds with "Connection: close" during shutdown
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
+	last := -1
+	for {
+		n := t.closeIdleConns()
+		if n != last && t.onShutdownProgress != nil {
+			t.onShutdownProgress(n)
+		}
+		last = n
+		if n == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
 }
 
-// For transporter switch
+// NewTransporter creates the transporter based on the net package, which works on all platforms
+// and is the default one where netpoll is unavailable, e.g. windows, netbsd and openbsd.
 func NewTransporter(options *config.Options) network.Transporter {
 	return &transport{
-		readBufferSize:   options.ReadBufferSize,
-		network:          options.Network,
-		addr:             options.Addr,
-		keepAliveTimeout: options.KeepAliveTimeout,
-		readTimeout:      options.ReadTimeout,
-		tls:              options.TLS,
-		listenConfig:     options.ListenConfig,
-		OnAccept:         options.OnAccept,
-		OnConnect:        options.OnConnect,
+		readBufferSize:     options.ReadBufferSize,
+		network:            options.Network,
+		addr:               options.Addr,
+		keepAliveTimeout:   options.KeepAliveTimeout,
+		readTimeout:        options.ReadTimeout,
+		tls:                options.TLS,
+		listenConfig:       options.ListenConfig,
+		unixSocketPerm:     options.UnixSocketPerm,
+		limiter:            network.NewConnLimiter(options.MaxConcurrentConnections, options.ConnectionThrottle, options.ConnectionThrottleBurst, options.OnConnectionRejected),
+		proxyProtocol:      options.ProxyProtocol,
+		senseFile:          options.SenseFile,
+		ln:                 options.Listener,
+		customListener:     options.Listener != nil,
+		OnAccept:           options.OnAccept,
+		OnConnect:          options.OnConnect,
+		panicReporter:      options.PanicReporter,
+		onShutdownProgress: options.OnShutdownProgress,
 	}
 }
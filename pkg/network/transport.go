@@ -18,6 +18,10 @@ package network
 
 import (
 	"context"
+	"fmt"
+	"runtime/debug"
+
+	"hertz-study/pkg/common/hlog"
 )
 
 type Transporter interface {
@@ -31,5 +35,34 @@ type Transporter interface {
 	ListenAndServe(onData OnData) error
 }
 
+// LimitedTransporter is implemented by the transporters limiting the connections by ConnLimiter.
+type LimitedTransporter interface {
+	// ConnLimiter returns the limiter of the transporter, nil if the connections are not limited.
+	ConnLimiter() *ConnLimiter
+}
+
 // Callback when data is ready on the connection
 type OnData func(ctx context.Context, conn interface{}) error
+
+// PanicReporter receives the recovered panics with their stacks, e.g. to report them to an
+// error tracker.
+type PanicReporter func(c context.Context, recovered interface{}, stack []byte)
+
+// RecoverOnData wraps onData to recover the panics escaping from it, the panic is reported and
+// the connection is closed instead of crashing the process.
+func RecoverOnData(onData OnData, report PanicReporter) OnData {
+	return func(ctx context.Context, conn interface{}) (err error) {
+		defer func() {
+			if rcv := recover(); rcv != nil {
+				stack := debug.Stack()
+				hlog.SystemLogger().CtxErrorf(ctx, "Recovered panic on connection, err=%v\nstack=%s", rcv, stack)
+				report(ctx, rcv, stack)
+				if c, ok := conn.(interface{ Close() error }); ok {
+					c.Close() //nolint:errcheck
+				}
+				err = fmt.Errorf("panic: %v", rcv)
+			}
+		}()
+		return onData(ctx, conn)
+	}
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package network
+
+import (
+	"errors"
+	"time"
+
+	errs "hertz-study/pkg/common/errors"
+)
+
+// WriteStall describes a flush of the response to the peer which is slower than the threshold
+// or exceeds the write deadline, usually caused by a slow consumer of a streaming response.
+type WriteStall struct {
+	// Route is the matched route of the request, e.g. "/events/:id".
+	Route string
+	// Duration is how long the flush is blocked.
+	Duration time.Duration
+	// TimedOut is true if the flush exceeds the write deadline, the connection is closed then.
+	TimedOut bool
+}
+
+// FlushDeadlineWriter is a Writer which sets the write deadline of conn before every Flush, so
+// that every flushed part of the response, e.g. a chunk of the SSE or download streams, must be
+// sent within the timeout. The flushes slower than the threshold are reported to OnStall.
+type FlushDeadlineWriter struct {
+	Conn      Conn
+	Timeout   time.Duration
+	Threshold time.Duration
+	OnStall   func(d time.Duration, timedOut bool)
+}
+
+func (w *FlushDeadlineWriter) Malloc(n int) (buf []byte, err error) {
+	return w.Conn.Malloc(n)
+}
+
+func (w *FlushDeadlineWriter) WriteBinary(b []byte) (n int, err error) {
+	return w.Conn.WriteBinary(b)
+}
+
+func (w *FlushDeadlineWriter) Flush() error {
+	if w.Timeout > 0 {
+		if err := w.Conn.SetWriteTimeout(w.Timeout); err != nil {
+			return err
+		}
+	}
+	start := time.Now()
+	err := w.Conn.Flush()
+	if w.OnStall != nil {
+		d := time.Since(start)
+		timedOut := w.isTimeout(err)
+		if timedOut || (w.Threshold > 0 && d >= w.Threshold) {
+			w.OnStall(d, timedOut)
+		}
+	}
+	return err
+}
+
+func (w *FlushDeadlineWriter) isTimeout(err error) bool {
+	if err == nil {
+		return false
+	}
+	if en, ok := w.Conn.(ErrorNormalization); ok && errors.Is(en.ToHertzError(err), errs.ErrTimeout) {
+		return true
+	}
+	var te interface{ Timeout() bool }
+	return errors.As(err, &te) && te.Timeout()
+}
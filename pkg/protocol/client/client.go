@@ -43,9 +43,11 @@ package client
 
 import (
 	"context"
+	"strconv"
 	"sync"
 	"time"
 
+	"hertz-study/internal/bytesconv"
 	"hertz-study/internal/bytestr"
 	"hertz-study/pkg/common/config"
 	"hertz-study/pkg/common/errors"
@@ -84,13 +86,59 @@ func DefaultRetryIf(req *protocol.Request, resp *protocol.Response, err error) b
 		return false
 	}
 
-	if isIdempotent(req, resp, err) {
+	if isIdempotent(req, resp, err) || req.Options().Replayable() {
 		return true
 	}
 
 	return false
 }
 
+// RetryOnStatus returns a RetryIfFunc which retries the requests satisfying DefaultRetryIf
+// when they fail or the response status code is one of codes, e.g. 429, 502, 503 and 504.
+func RetryOnStatus(codes ...int) RetryIfFunc {
+	return func(req *protocol.Request, resp *protocol.Response, err error) bool {
+		if !DefaultRetryIf(req, resp, err) {
+			return false
+		}
+		if err != nil {
+			return true
+		}
+		if resp == nil {
+			return false
+		}
+		for _, code := range codes {
+			if resp.StatusCode() == code {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// RetryAfter returns the delay required by the Retry-After header of resp, which is either
+// seconds or an HTTP-date, zero is returned if resp has no valid Retry-After header.
+func RetryAfter(resp *protocol.Response) time.Duration {
+	if resp == nil {
+		return 0
+	}
+	v := resp.Header.Peek(consts.HeaderRetryAfter)
+	if len(v) == 0 {
+		return 0
+	}
+	if secs, err := strconv.Atoi(string(v)); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := bytesconv.ParseHTTPDate(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
 func isIdempotent(req *protocol.Request, resp *protocol.Response, err error) bool {
 	return req.Header.IsGet() ||
 		req.Header.IsHead() ||
@@ -105,6 +153,9 @@ type DynamicConfig struct {
 	Addr     string
 	ProxyURI *protocol.URI
 	IsTLS    bool
+	// TLSServerName is the host to verify the certificate with when Addr is not the host
+	// of the requests, e.g. Addr is overridden by host mapping.
+	TLSServerName string
 }
 
 // RetryIfFunc signature of retry if function
@@ -0,0 +1,61 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"time"
+)
+
+type traceCtxKey struct{}
+
+// Trace records where the time of a request is spent, like the httptrace of the standard
+// library, which helps to debug the slow upstream calls. Only the last attempt is recorded
+// if the request is retried.
+type Trace struct {
+	// Reused is true if the request is sent over a pooled connection, the DNS, Connect
+	// and TLSHandshake are zero then.
+	Reused bool
+	// DNS is the time resolving the host, zero if the host is an ip or a proxy is used.
+	DNS time.Duration
+	// Connect is the time establishing the TCP connection, including the proxy handshake.
+	Connect time.Duration
+	// TLSHandshake is the time of the TLS handshake.
+	TLSHandshake time.Duration
+	// TTFB is the time from starting writing the request to reading the first byte of the response.
+	TTFB time.Duration
+	// Total is the time of the whole request, including the retries.
+	Total time.Duration
+}
+
+// WithTrace returns a copy of ctx carrying t, the requests sent with the returned context
+// fill t, e.g.
+//
+//	t := &client.Trace{}
+//	c.Get(client.WithTrace(ctx, t), nil, url)
+func WithTrace(ctx context.Context, t *Trace) context.Context {
+	return context.WithValue(ctx, traceCtxKey{}, t)
+}
+
+// TraceFromContext returns the Trace carried by ctx, or nil if there is none.
+func TraceFromContext(ctx context.Context) *Trace {
+	if ctx == nil {
+		return nil
+	}
+	t, _ := ctx.Value(traceCtxKey{}).(*Trace)
+	return t
+}
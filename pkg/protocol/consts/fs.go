@@ -47,6 +47,9 @@ const (
 	// files bigger than this size are sent with sendfile
 	MaxSmallFileSize = 2 * 4096
 
+	// FSMemoryCacheMaxFileSize is the default max size of the files cached in memory by FS.
+	FSMemoryCacheMaxFileSize = 64 * 1024
+
 	// FSHandlerCacheDuration is the default expiration duration for inactive
 	// file handlers opened by FS.
 	FSHandlerCacheDuration = 10 * time.Second
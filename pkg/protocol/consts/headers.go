@@ -22,6 +22,16 @@ const (
 	HeaderIfModifiedSince = "If-Modified-Since"
 	HeaderLastModified    = "Last-Modified"
 
+	// Caching
+	HeaderCacheControl = "Cache-Control"
+	HeaderVary         = "Vary"
+
+	// Conditionals
+	HeaderETag              = "ETag"
+	HeaderIfMatch           = "If-Match"
+	HeaderIfNoneMatch       = "If-None-Match"
+	HeaderIfUnmodifiedSince = "If-Unmodified-Since"
+
 	// Redirects
 	HeaderLocation = "Location"
 
@@ -42,6 +52,7 @@ const (
 	HeaderConnection      = "Connection"
 	HeaderKeepAlive       = "Keep-Alive"
 	HeaderProxyConnection = "Proxy-Connection"
+	HeaderUpgrade         = "Upgrade"
 
 	// Authentication
 	HeaderAuthorization      = "Authorization"
@@ -57,8 +68,12 @@ const (
 
 	// Response context
 	HeaderAllow       = "Allow"
+	HeaderDeprecation = "Deprecation"
+	HeaderLink        = "Link"
+	HeaderRetryAfter  = "Retry-After"
 	HeaderServer      = "Server"
 	HeaderServerLower = "server"
+	HeaderSunset      = "Sunset"
 
 	// Request context
 	HeaderFrom           = "From"
@@ -96,6 +111,8 @@ const (
 	MIMETextHtml              = "text/html"
 	MIMETextCss               = "text/css"
 	MIMETextJavascript        = "text/javascript"
+	MIMETextXML               = "text/xml"
+	MIMETextYAML              = "text/yaml"
 	MIMEMultipartPOSTForm     = "multipart/form-data"
 
 	// MIME application
@@ -123,6 +140,10 @@ const (
 	MIMEApplicationOpenXMLExcel = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
 	MIMEApplicationOpenXMLPPT   = "application/vnd.openxmlformats-officedocument.presentationml.presentation"
 	MIMEPROTOBUF                = "application/x-protobuf"
+	MIMEApplicationYAML         = "application/yaml"
+	MIMEApplicationXYAML        = "application/x-yaml"
+	MIMEApplicationMsgPack      = "application/msgpack"
+	MIMEApplicationXMsgPack     = "application/x-msgpack"
 
 	// MIME image
 	MIMEImageJPEG         = "image/jpeg"
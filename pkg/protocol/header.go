@@ -94,12 +94,24 @@ type RequestHeader struct {
 	// stores an immutable copy of headers as they were received from the
 	// wire.
 	rawHeaders []byte
+
+	headerLength int
 }
 
 func (h *RequestHeader) SetRawHeaders(r []byte) {
 	h.rawHeaders = r
 }
 
+// SetHeaderLength sets the size of the header read from the wire, including the request line.
+func (h *RequestHeader) SetHeaderLength(length int) {
+	h.headerLength = length
+}
+
+// GetHeaderLength gets the size of the header read from the wire, including the request line.
+func (h *RequestHeader) GetHeaderLength() int {
+	return h.headerLength
+}
+
 // ResponseHeader represents HTTP response header.
 //
 // It is forbidden copying ResponseHeader instances.
@@ -556,6 +568,26 @@ func (h *ResponseHeader) Add(key, value string) {
 	h.h = appendArg(h.h, bytesconv.B2s(k), value, ArgsHasValue)
 }
 
+// SetBytesKV sets the given 'key: value' header.
+//
+// Use AddBytesKV for setting multiple header values under the same key.
+func (h *ResponseHeader) SetBytesKV(key, value []byte) {
+	h.SetCanonical(normalizedHeaderKey(&h.bufKV, key, h.disableNormalizing), value)
+}
+
+// AddBytesKV adds the given 'key: value' header.
+//
+// Multiple headers with the same key may be added with this function.
+// Use SetBytesKV for setting a single header for the given key.
+func (h *ResponseHeader) AddBytesKV(key, value []byte) {
+	if h.setSpecialHeader(key, value) {
+		return
+	}
+
+	k := normalizedHeaderKey(&h.bufKV, key, h.disableNormalizing)
+	h.h = appendArgBytes(h.h, k, value, ArgsHasValue)
+}
+
 // SetContentLength sets Content-Length header value.
 //
 // Content-Length may be negative:
@@ -684,8 +716,16 @@ func (h *ResponseHeader) DelClientCookieBytes(key []byte) {
 // Returned value is valid until the next call to ResponseHeader.
 // Do not store references to returned value. Make copies instead.
 func (h *ResponseHeader) Peek(key string) []byte {
-	k := getHeaderKeyBytes(&h.bufKV, key, h.disableNormalizing)
-	return h.peek(k)
+	return h.PeekBytes(bytesconv.S2b(key))
+}
+
+// PeekBytes returns header value for the given key without converting it to string.
+// The key is case-insensitive unless the normalizing is disabled.
+//
+// Returned value is valid until the next call to ResponseHeader.
+// Do not store references to returned value. Make copies instead.
+func (h *ResponseHeader) PeekBytes(key []byte) []byte {
+	return h.peek(normalizedHeaderKey(&h.bufKV, key, h.disableNormalizing))
 }
 
 func (h *ResponseHeader) IsDisableNormalizing() bool {
@@ -1120,6 +1160,7 @@ func (h *RequestHeader) CopyTo(dst *RequestHeader) {
 	dst.cookies = copyArgs(dst.cookies, h.cookies)
 	dst.cookiesCollected = h.cookiesCollected
 	dst.rawHeaders = append(dst.rawHeaders[:0], h.rawHeaders...)
+	dst.headerLength = h.headerLength
 	dst.protocol = h.protocol
 }
 
@@ -1128,8 +1169,16 @@ func (h *RequestHeader) CopyTo(dst *RequestHeader) {
 // Returned value is valid until the next call to RequestHeader.
 // Do not store references to returned value. Make copies instead.
 func (h *RequestHeader) Peek(key string) []byte {
-	k := getHeaderKeyBytes(&h.bufKV, key, h.disableNormalizing)
-	return h.peek(k)
+	return h.PeekBytes(bytesconv.S2b(key))
+}
+
+// PeekBytes returns header value for the given key without converting it to string.
+// The key is case-insensitive unless the normalizing is disabled.
+//
+// Returned value is valid until the next call to RequestHeader.
+// Do not store references to returned value. Make copies instead.
+func (h *RequestHeader) PeekBytes(key []byte) []byte {
+	return h.peek(normalizedHeaderKey(&h.bufKV, key, h.disableNormalizing))
 }
 
 // SetMultipartFormBoundary sets the following Content-Type:
@@ -1277,9 +1326,20 @@ func (h *RequestHeader) SetRequestURIBytes(requestURI []byte) {
 //
 // Use AddBytesKV for setting multiple header values under the same key.
 func (h *RequestHeader) SetBytesKV(key, value []byte) {
-	h.bufKV.key = append(h.bufKV.key[:0], key...)
-	utils.NormalizeHeaderKey(h.bufKV.key, h.disableNormalizing)
-	h.SetCanonical(h.bufKV.key, value)
+	h.SetCanonical(normalizedHeaderKey(&h.bufKV, key, h.disableNormalizing), value)
+}
+
+// AddBytesKV adds the given 'key: value' header.
+//
+// Multiple headers with the same key may be added with this function.
+// Use SetBytesKV for setting a single header for the given key.
+func (h *RequestHeader) AddBytesKV(key, value []byte) {
+	if h.setSpecialHeader(key, value) {
+		return
+	}
+
+	k := normalizedHeaderKey(&h.bufKV, key, h.disableNormalizing)
+	h.h = appendArgBytes(h.h, k, value, ArgsHasValue)
 }
 
 func (h *RequestHeader) AddArgBytes(key, value []byte, noValue bool) {
@@ -1442,6 +1502,7 @@ func (h *RequestHeader) ResetSkipNormalize() {
 	h.cookiesCollected = false
 
 	h.rawHeaders = h.rawHeaders[:0]
+	h.headerLength = 0
 	h.mulHeader = h.mulHeader[:0]
 	h.Trailer().ResetSkipNormalize()
 }
@@ -0,0 +1,64 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package protocol
+
+import (
+	"bytes"
+
+	"hertz-study/pkg/common/utils"
+	"hertz-study/pkg/protocol/consts"
+)
+
+// commonHeaderKeys maps the normalized and the lower case forms of the common header names to
+// the normalized form, so that they are looked up by []byte without copying and normalizing.
+// The values are shared and must not be modified.
+var commonHeaderKeys = func() map[string][]byte {
+	names := []string{
+		consts.HeaderAccept, consts.HeaderAcceptEncoding, consts.HeaderAcceptLanguage,
+		consts.HeaderAuthorization, consts.HeaderCacheControl, consts.HeaderConnection,
+		consts.HeaderContentEncoding, consts.HeaderContentLength, consts.HeaderContentType,
+		consts.HeaderCookie, consts.HeaderDate, consts.HeaderETag, consts.HeaderExpect,
+		consts.HeaderHost, consts.HeaderIfModifiedSince, consts.HeaderIfNoneMatch,
+		consts.HeaderLocation, consts.HeaderRange, consts.HeaderReferer, consts.HeaderServer,
+		consts.HeaderSetCookie, consts.HeaderTrailer, consts.HeaderTransferEncoding,
+		consts.HeaderUserAgent, "Origin", "X-Forwarded-For", "X-Real-IP", "X-Request-ID",
+	}
+	m := make(map[string][]byte, 2*len(names))
+	for _, name := range names {
+		k := []byte(name)
+		utils.NormalizeHeaderKey(k, false)
+		k = k[:len(k):len(k)]
+		m[string(k)] = k
+		m[string(bytes.ToLower(k))] = k
+	}
+	return m
+}()
+
+// normalizedHeaderKey returns the normalized form of key: key itself if disableNormalizing is
+// set, the shared one of commonHeaderKeys, or the one normalized into kv.key otherwise.
+// The returned value must not be modified.
+func normalizedHeaderKey(kv *argsKV, key []byte, disableNormalizing bool) []byte {
+	if disableNormalizing {
+		return key
+	}
+	if k, ok := commonHeaderKeys[string(key)]; ok {
+		return k
+	}
+	kv.key = append(kv.key[:0], key...)
+	utils.NormalizeHeaderKey(kv.key, false)
+	return kv.key
+}
@@ -107,6 +107,9 @@ type HostClient struct {
 	IsTLS    bool
 	ProxyURI *protocol.URI
 
+	// TLSServerName is used to verify the certificate instead of the host of Addr if set.
+	TLSServerName string
+
 	clientName  atomic.Value
 	lastUseTime uint32
 
@@ -133,6 +136,7 @@ func (c *HostClient) SetDynamicConfig(dc *client.DynamicConfig) {
 	c.Addr = dc.Addr
 	c.ProxyURI = dc.ProxyURI
 	c.IsTLS = dc.IsTLS
+	c.TLSServerName = dc.TLSServerName
 
 	// start observation after setting addr to avoid race
 	if c.StateObserve != nil {
@@ -382,6 +386,12 @@ func (c *HostClient) Do(ctx context.Context, req *protocol.Request, resp *protoc
 		isDefaultRetryFunc = false
 	}
 
+	trace := client.TraceFromContext(ctx)
+	if trace != nil {
+		start := time.Now()
+		defer func() { trace.Total = time.Since(start) }()
+	}
+
 	atomic.AddInt32(&c.pendingRequests, 1)
 	req.Options().StartRequest()
 	for {
@@ -392,7 +402,7 @@ func (c *HostClient) Do(ctx context.Context, req *protocol.Request, resp *protoc
 		default:
 		}
 
-		canIdempotentRetry, err = c.do(req, resp)
+		canIdempotentRetry, err = c.do(req, resp, trace)
 		// If there is no custom retry and err is equal to nil, the loop simply exits.
 		if err == nil && isDefaultRetryFunc {
 			if connAttempts != 0 {
@@ -415,23 +425,33 @@ func (c *HostClient) Do(ctx context.Context, req *protocol.Request, resp *protoc
 			continue
 		}
 
-		if isDefaultRetryFunc {
-			break
-		}
-
 		attempts++
 		if attempts >= maxAttempts {
 			break
 		}
 
-		// Check whether this request should be retried
+		// Check whether this request should be retried, the default one only retries
+		// the failed idempotent or replayable requests
 		if !isRequestRetryable(req, resp, err) {
 			break
 		}
 
 		wait := retry.Delay(attempts, err, retryCfg)
+		// honor the Retry-After of the response, e.g. 429 and 503, within the max delay
+		if retryAfter := client.RetryAfter(resp); retryAfter > wait {
+			wait = retryAfter
+			if retryCfg.MaxDelay > 0 && wait > retryCfg.MaxDelay {
+				wait = retryCfg.MaxDelay
+			}
+		}
 		// Retry after wait time
-		time.Sleep(wait)
+		select {
+		case <-ctx.Done():
+			atomic.AddInt32(&c.pendingRequests, -1)
+			req.CloseBodyStream() //nolint:errcheck
+			return ctx.Err()
+		case <-time.After(wait):
+		}
 	}
 	atomic.AddInt32(&c.pendingRequests, -1)
 
@@ -450,14 +470,14 @@ func (c *HostClient) PendingRequests() int {
 	return int(atomic.LoadInt32(&c.pendingRequests))
 }
 
-func (c *HostClient) do(req *protocol.Request, resp *protocol.Response) (bool, error) {
+func (c *HostClient) do(req *protocol.Request, resp *protocol.Response, trace *client.Trace) (bool, error) {
 	nilResp := false
 	if resp == nil {
 		nilResp = true
 		resp = protocol.AcquireResponse()
 	}
 
-	canIdempotentRetry, err := c.doNonNilReqResp(req, resp)
+	canIdempotentRetry, err := c.doNonNilReqResp(req, resp, trace)
 
 	if nilResp {
 		protocol.ReleaseResponse(resp)
@@ -513,7 +533,7 @@ func updateReqTimeout(reqTimeout, compareTimeout time.Duration, before time.Time
 	return false, left
 }
 
-func (c *HostClient) doNonNilReqResp(req *protocol.Request, resp *protocol.Response) (bool, error) {
+func (c *HostClient) doNonNilReqResp(req *protocol.Request, resp *protocol.Response, trace *client.Trace) (bool, error) {
 	if req == nil {
 		panic("BUG: req cannot be nil")
 	}
@@ -542,15 +562,21 @@ func (c *HostClient) doNonNilReqResp(req *protocol.Request, resp *protocol.Respo
 	if (reqTimeout > 0 && reqTimeout < dialTimeout) || dialTimeout == 0 {
 		dialTimeout = reqTimeout
 	}
-	cc, inPool, err := c.acquireConn(dialTimeout)
+	if trace != nil {
+		*trace = client.Trace{}
+	}
+	cc, inPool, err := c.acquireConn(dialTimeout, trace)
 	// if getting connection error, fast fail
 	if err != nil {
 		return false, err
 	}
 	conn := cc.c
+	if trace != nil && inPool {
+		*trace = client.Trace{Reused: true}
+	}
 
 	usingProxy := false
-	if c.ProxyURI != nil && bytes.Equal(req.Scheme(), bytestr.StrHTTP) {
+	if c.ProxyURI != nil && bytes.Equal(req.Scheme(), bytestr.StrHTTP) && !proxy.IsSOCKS5(c.ProxyURI) {
 		usingProxy = true
 		proxy.SetProxyAuthHeader(&req.Header, c.ProxyURI)
 	}
@@ -580,6 +606,12 @@ func (c *HostClient) doNonNilReqResp(req *protocol.Request, resp *protocol.Respo
 		req.Header.SetUserAgentBytes(c.getClientName())
 	}
 	zw := c.acquireWriter(conn)
+	var dw *dumpWriter
+	if c.RequestDumper != nil {
+		dw = &dumpWriter{Writer: zw}
+		zw = dw
+	}
+	writeStart := time.Now()
 
 	if !usingProxy {
 		err = reqI.Write(req, zw)
@@ -648,6 +680,12 @@ func (c *HostClient) doNonNilReqResp(req *protocol.Request, resp *protocol.Respo
 		resp.Header.DisableNormalizing()
 	}
 	zr := c.acquireReader(conn)
+	var dr *dumpReader
+	if dw != nil {
+		dr = &dumpReader{Reader: zr}
+		zr = dr
+		defer dump(c.RequestDumper, dw, dr)
+	}
 
 	// errs.ErrBadPoolConn error are returned when the
 	// 1 byte peek read fails, and we're actually anticipating a response.
@@ -670,6 +708,9 @@ func (c *HostClient) doNonNilReqResp(req *protocol.Request, resp *protocol.Respo
 		}
 		return false, err
 	}
+	if trace != nil {
+		trace.TTFB = time.Since(writeStart)
+	}
 
 	// init here for passing in ReadBodyStream's closure
 	// and this value will be assigned after reading Response's Header
@@ -700,6 +741,17 @@ func (c *HostClient) doNonNilReqResp(req *protocol.Request, resp *protocol.Respo
 
 	zr.Release() //nolint:errcheck
 
+	if resp.StatusCode() == consts.StatusSwitchingProtocols && len(req.Header.Peek(consts.HeaderUpgrade)) > 0 {
+		// the connection speaks the upgraded protocol from now on, it's handed over to the response
+		// without the timeouts of the request
+		conn.SetReadTimeout(0)  //nolint:errcheck
+		conn.SetWriteTimeout(0) //nolint:errcheck
+		resp.SetHijackConn(conn)
+		c.decConnsCount()
+		releaseClientConn(cc)
+		return false, nil
+	}
+
 	shouldCloseConn = resetConnection || req.ConnectionClose() || resp.ConnectionClose()
 
 	// In stream mode, we still can close/release the connection immediately if there is no content on the wire.
@@ -728,7 +780,7 @@ func (c *HostClient) SetMaxConns(newMaxConns int) {
 	c.connsLock.Unlock()
 }
 
-func (c *HostClient) acquireConn(dialTimeout time.Duration) (cc *clientConn, inPool bool, err error) {
+func (c *HostClient) acquireConn(dialTimeout time.Duration, trace *client.Trace) (cc *clientConn, inPool bool, err error) {
 	createConn := false
 	startCleaner := false
 
@@ -798,7 +850,7 @@ func (c *HostClient) acquireConn(dialTimeout time.Duration) (cc *clientConn, inP
 		go c.connsCleaner()
 	}
 
-	conn, err := c.dialHostHard(dialTimeout)
+	conn, err := c.dialHostHard(dialTimeout, trace)
 	if err != nil {
 		c.decConnsCount()
 		return nil, false, err
@@ -819,7 +871,7 @@ func (c *HostClient) queueForIdle(w *wantConn) {
 }
 
 func (c *HostClient) dialConnFor(w *wantConn) {
-	conn, err := c.dialHostHard(c.DialTimeout)
+	conn, err := c.dialHostHard(c.DialTimeout, nil)
 	if err != nil {
 		w.tryDeliver(nil, err)
 		c.decConnsCount()
@@ -1048,7 +1100,7 @@ func (c *HostClient) nextAddr() string {
 	return addr
 }
 
-func (c *HostClient) dialHostHard(dialTimeout time.Duration) (conn network.Conn, err error) {
+func (c *HostClient) dialHostHard(dialTimeout time.Duration, trace *client.Trace) (conn network.Conn, err error) {
 	// attempt to dial all the available hosts before giving up.
 
 	c.addrsLock.Lock()
@@ -1064,7 +1116,7 @@ func (c *HostClient) dialHostHard(dialTimeout time.Duration) (conn network.Conn,
 	for n > 0 {
 		addr := c.nextAddr()
 		tlsConfig := c.cachedTLSConfig(addr)
-		conn, err = dialAddr(addr, c.Dialer, c.DialDualStack, tlsConfig, dialTimeout, c.ProxyURI, c.IsTLS)
+		conn, err = dialAddr(addr, c.Dialer, c.DialDualStack, tlsConfig, dialTimeout, c.ProxyURI, c.IsTLS, trace)
 		if err == nil {
 			return conn, nil
 		}
@@ -1084,6 +1136,10 @@ func (c *HostClient) cachedTLSConfig(addr string) *tls.Config {
 
 	if c.IsTLS && cfgAddr == "" {
 		cfgAddr = addr
+		if c.TLSServerName != "" {
+			// only the host is used as the server name, the port doesn't matter
+			cfgAddr = net.JoinHostPort(c.TLSServerName, "443")
+		}
 	}
 
 	if cfgAddr == "" {
@@ -1104,13 +1160,20 @@ func (c *HostClient) cachedTLSConfig(addr string) *tls.Config {
 	return cfg
 }
 
-func dialAddr(addr string, dial network.Dialer, dialDualStack bool, tlsConfig *tls.Config, timeout time.Duration, proxyURI *protocol.URI, isTLS bool) (network.Conn, error) {
+func dialAddr(addr string, dial network.Dialer, dialDualStack bool, tlsConfig *tls.Config, timeout time.Duration, proxyURI *protocol.URI, isTLS bool, trace *client.Trace) (network.Conn, error) {
 	var conn network.Conn
 	var err error
 	if dial == nil {
 		hlog.SystemLogger().Warn("HostClient: no dialer specified, trying to use default dialer")
 		dial = dialer.DefaultDialer()
 	}
+	if trace != nil && proxyURI == nil {
+		return dialAddrTraced(addr, dial, tlsConfig, timeout, trace)
+	}
+	if trace != nil {
+		start := time.Now()
+		defer func() { trace.Connect = time.Since(start) }()
+	}
 	dialFunc := dial.DialConnection
 
 	// addr has already been added port, no need to do it here
@@ -1139,6 +1202,46 @@ func dialAddr(addr string, dial network.Dialer, dialDualStack bool, tlsConfig *t
 	return conn, nil
 }
 
+// dialAddrTraced is dialAddr without proxy which resolves the host, connects and does the
+// TLS handshake step by step to record their timings in trace.
+func dialAddrTraced(addr string, dial network.Dialer, tlsConfig *tls.Config, timeout time.Duration, trace *client.Trace) (network.Conn, error) {
+	deadline := time.Now().Add(timeout)
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if net.ParseIP(host) == nil {
+		start := time.Now()
+		ctx, cancel := context.WithDeadline(context.Background(), deadline)
+		ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		cancel()
+		trace.DNS = time.Since(start)
+		if err != nil {
+			return nil, err
+		}
+		addr = net.JoinHostPort(ips[0].IP.String(), port)
+	}
+
+	start := time.Now()
+	conn, err := dial.DialConnection("tcp", addr, time.Until(deadline), nil)
+	trace.Connect = time.Since(start)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig == nil {
+		return conn, nil
+	}
+
+	start = time.Now()
+	tlsConn, err := dial.AddTLS(conn, tlsConfig)
+	trace.TLSHandshake = time.Since(start)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
 func (c *HostClient) getClientName() []byte {
 	v := c.clientName.Load()
 	var clientName []byte
@@ -1369,6 +1472,9 @@ type ClientOptions struct {
 	// Observe hostclient state
 	StateObserve config.HostClientStateFunc
 
+	// RequestDumper receives the requests and responses as they are on the wire.
+	RequestDumper io.Writer
+
 	// StateObserve execution interval
 	ObservationInterval time.Duration
 }
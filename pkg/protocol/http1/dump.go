@@ -0,0 +1,103 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package http1
+
+import (
+	"bytes"
+	"io"
+	"sync"
+
+	"hertz-study/pkg/network"
+)
+
+// dumpLock serializes the dumps of the concurrent requests sharing a writer.
+var dumpLock sync.Mutex
+
+// dumpWriter records the bytes written to the connection, the slices given by Malloc and
+// WriteBinary are valid until Flush, so they are copied when flushing.
+type dumpWriter struct {
+	network.Writer
+	pending [][]byte
+	buf     bytes.Buffer
+}
+
+func (w *dumpWriter) Malloc(n int) ([]byte, error) {
+	b, err := w.Writer.Malloc(n)
+	if err == nil {
+		w.pending = append(w.pending, b)
+	}
+	return b, err
+}
+
+func (w *dumpWriter) WriteBinary(b []byte) (int, error) {
+	n, err := w.Writer.WriteBinary(b)
+	if n > 0 {
+		w.pending = append(w.pending, b[:n])
+	}
+	return n, err
+}
+
+func (w *dumpWriter) Flush() error {
+	for _, b := range w.pending {
+		w.buf.Write(b)
+	}
+	w.pending = w.pending[:0]
+	return w.Writer.Flush()
+}
+
+// dumpReader records the bytes consumed from the connection until the response is dumped,
+// the body stream read after that is not recorded.
+type dumpReader struct {
+	network.Reader
+	buf    bytes.Buffer
+	dumped bool
+}
+
+func (r *dumpReader) Skip(n int) error {
+	if !r.dumped {
+		if b, err := r.Reader.Peek(n); err == nil {
+			r.buf.Write(b)
+		}
+	}
+	return r.Reader.Skip(n)
+}
+
+func (r *dumpReader) ReadByte() (byte, error) {
+	b, err := r.Reader.ReadByte()
+	if err == nil && !r.dumped {
+		r.buf.WriteByte(b)
+	}
+	return b, err
+}
+
+func (r *dumpReader) ReadBinary(n int) ([]byte, error) {
+	b, err := r.Reader.ReadBinary(n)
+	if !r.dumped {
+		r.buf.Write(b)
+	}
+	return b, err
+}
+
+// dump writes the request and the response as they are on the wire to w.
+func dump(w io.Writer, dw *dumpWriter, dr *dumpReader) {
+	dumpLock.Lock()
+	w.Write(dw.buf.Bytes()) //nolint:errcheck
+	w.Write(dr.buf.Bytes()) //nolint:errcheck
+	dumpLock.Unlock()
+	dr.dumped = true
+	dr.buf = bytes.Buffer{}
+}
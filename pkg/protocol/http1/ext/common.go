@@ -145,6 +145,12 @@ func WriteBodyFixedSize(w network.Writer, r io.Reader, size int64) error {
 		return nil
 	}
 	if size > consts.MaxSmallFileSize {
+		if n, err := sendFile(w, r, size); !errors.Is(err, errs.ErrSendFileNotSupport) {
+			if n != size && err == nil {
+				err = fmt.Errorf("sent %d bytes of file instead of %d bytes", n, size)
+			}
+			return err
+		}
 		if err := w.Flush(); err != nil {
 			return err
 		}
@@ -162,6 +168,24 @@ func WriteBodyFixedSize(w network.Writer, r io.Reader, size int64) error {
 	return err
 }
 
+// sendFile sends the body with network.FileSender if w supports it and r is exactly a section of
+// size bytes of a file.
+func sendFile(w network.Writer, r io.Reader, size int64) (int64, error) {
+	fs, ok := w.(network.FileSender)
+	if !ok {
+		return 0, errs.ErrSendFileNotSupport
+	}
+	section, ok := r.(network.FileSection)
+	if !ok {
+		return 0, errs.ErrSendFileNotSupport
+	}
+	f, offset, count := section.FileSection()
+	if f == nil || count != size {
+		return 0, errs.ErrSendFileNotSupport
+	}
+	return fs.SendFile(f, offset, count)
+}
+
 func appendBodyFixedSize(r network.Reader, dst []byte, n int) ([]byte, error) {
 	if n == 0 {
 		return dst, nil
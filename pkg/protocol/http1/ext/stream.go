@@ -76,6 +76,12 @@ type bodyStream struct {
 	chunkLeft       int
 	// whether the chunk has reached the EOF
 	chunkEOF bool
+	// limit of the body size set by LimitBodyStream, no limit if <= 0
+	limit     int
+	readBytes int
+	exceeded  bool
+	// counter set by CountBodyStream, which is added by the size of the body consumed
+	counter *int
 }
 
 func ReadBodyWithStreaming(zr network.Reader, contentLength, maxBodySize int, dst []byte) (b []byte, err error) {
@@ -122,7 +128,69 @@ func AcquireBodyStream(b *bytebufferpool.ByteBuffer, r network.Reader, t *protoc
 	return rs
 }
 
+// LimitBodyStream limits the size of the request body stream r to n bytes, Read returns
+// errs.ErrBodyTooLarge once more than n bytes are read. The rest of an oversize body is not
+// skipped on release, so the response must close the connection. It returns false if r is not
+// a body stream created by AcquireBodyStream.
+func LimitBodyStream(r io.Reader, n int) bool {
+	rs, ok := r.(*bodyStream)
+	if !ok {
+		return false
+	}
+	rs.limit = n
+	if n > 0 && rs.contentLength > n {
+		rs.exceeded = true
+	}
+	return true
+}
+
+// CountBodyStream adds the size of the body read from the request body stream r to *n, including
+// the unread rest skipped on release. It returns false if r is not a body stream created by
+// AcquireBodyStream.
+func CountBodyStream(r io.Reader, n *int) bool {
+	rs, ok := r.(*bodyStream)
+	if ok {
+		rs.counter = n
+	}
+	return ok
+}
+
+func (rs *bodyStream) count(n int) {
+	if rs.counter != nil {
+		*rs.counter += n
+	}
+}
+
+// BodyStreamExceeded returns whether the limit set by LimitBodyStream is exceeded by r.
+func BodyStreamExceeded(r io.Reader) bool {
+	rs, ok := r.(*bodyStream)
+	return ok && rs.exceeded
+}
+
 func (rs *bodyStream) Read(p []byte) (int, error) {
+	if rs.limit <= 0 {
+		n, err := rs.read(p)
+		rs.count(n)
+		return n, err
+	}
+	if rs.exceeded {
+		return 0, errBodyTooLarge
+	}
+	// read one more byte to find out whether the body exceeds the limit
+	if remain := rs.limit - rs.readBytes + 1; len(p) > remain {
+		p = p[:remain]
+	}
+	n, err := rs.read(p)
+	rs.readBytes += n
+	rs.count(n)
+	if rs.readBytes > rs.limit {
+		rs.exceeded = true
+		return n - (rs.readBytes - rs.limit), errBodyTooLarge
+	}
+	return n, err
+}
+
+func (rs *bodyStream) read(p []byte) (int, error) {
 	defer func() {
 		if rs.reader != nil {
 			rs.reader.Release() //nolint:errcheck
@@ -230,6 +298,10 @@ func (rs *bodyStream) Read(p []byte) (int, error) {
 }
 
 func (rs *bodyStream) skipRest() error {
+	// the rest of an oversize body is left to the closed connection
+	if rs.exceeded {
+		return nil
+	}
 	// The body length doesn't exceed the maxContentLengthInStream or
 	// the bodyStream has been skip rest
 	if rs.prefetchedBytes == nil {
@@ -243,21 +315,24 @@ func (rs *bodyStream) skipRest() error {
 		}
 
 		strCRLFLen := len(bytestr.StrCRLF)
-		for {
-			chunkSize, err := utils.ParseChunkSize(rs.reader)
-			if err != nil {
-				return err
-			}
-
+		// start with the rest of the chunk being read
+		for chunkSize := rs.chunkLeft; ; chunkSize = 0 {
+			var err error
 			if chunkSize == 0 {
-				rs.chunkEOF = true
-				return SkipTrailer(rs.reader)
+				if chunkSize, err = utils.ParseChunkSize(rs.reader); err != nil {
+					return err
+				}
+				if chunkSize == 0 {
+					rs.chunkEOF = true
+					return SkipTrailer(rs.reader)
+				}
 			}
 
 			err = rs.reader.Skip(chunkSize)
 			if err != nil {
 				return err
 			}
+			rs.count(chunkSize)
 
 			crlf, err := rs.reader.Peek(strCRLFLen)
 			if err != nil {
@@ -276,6 +351,10 @@ func (rs *bodyStream) skipRest() error {
 	}
 	// max value of pSize is 8193, it's safe.
 	pSize := int(rs.prefetchedBytes.Size())
+	if rs.contentLength > rs.offset {
+		// the unread prefetched bytes and the rest on the wire
+		rs.count(rs.contentLength - rs.offset)
+	}
 	if rs.contentLength <= pSize || rs.offset == rs.contentLength {
 		return nil
 	}
@@ -329,4 +408,8 @@ func (rs *bodyStream) reset() {
 	rs.chunkEOF = false
 	rs.chunkLeft = 0
 	rs.contentLength = 0
+	rs.limit = 0
+	rs.readBytes = 0
+	rs.exceeded = false
+	rs.counter = nil
 }
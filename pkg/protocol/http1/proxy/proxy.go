@@ -52,6 +52,11 @@ func SetupProxy(conn network.Conn, addr string, proxyURI *protocol.URI, tlsConfi
 	switch {
 	case proxyURI == nil:
 		// Do nothing. Not using a proxy.
+	case IsSOCKS5(proxyURI):
+		if err = socks5Connect(conn, addr, proxyURI); err != nil {
+			conn.Close()
+			return nil, err
+		}
 	case isTLS: // target addr is https
 		connectReq, connectResp := protocol.AcquireRequest(), protocol.AcquireResponse()
 		defer func() {
@@ -0,0 +1,181 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"hertz-study/pkg/network"
+	"hertz-study/pkg/protocol"
+)
+
+const (
+	socks5Version       = 0x05
+	socks5AuthNone      = 0x00
+	socks5AuthPassword  = 0x02
+	socks5AuthNoAccept  = 0xff
+	socks5CmdConnect    = 0x01
+	socks5AddrIPv4      = 0x01
+	socks5AddrDomain    = 0x03
+	socks5AddrIPv6      = 0x04
+	socks5HandshakeTime = time.Minute
+)
+
+var socks5Replies = []string{
+	1: "general SOCKS server failure",
+	2: "connection not allowed by ruleset",
+	3: "network unreachable",
+	4: "host unreachable",
+	5: "connection refused",
+	6: "TTL expired",
+	7: "command not supported",
+	8: "address type not supported",
+}
+
+// IsSOCKS5 returns whether proxyURI is a SOCKS5 proxy, i.e. the scheme is "socks5" or "socks5h".
+// The target host is resolved locally for "socks5" and by the proxy for "socks5h".
+func IsSOCKS5(proxyURI *protocol.URI) bool {
+	if proxyURI == nil {
+		return false
+	}
+	s := string(proxyURI.Scheme())
+	return s == "socks5" || s == "socks5h"
+}
+
+// socks5Connect asks the SOCKS5 proxy connected by conn to connect to addr (RFC 1928),
+// authenticating by the username and password of proxyURI if any (RFC 1929).
+func socks5Connect(conn network.Conn, addr string, proxyURI *protocol.URI) error {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port <= 0 || port > 0xffff {
+		return fmt.Errorf("socks5: invalid port of address %s", addr)
+	}
+	if string(proxyURI.Scheme()) == "socks5" && net.ParseIP(host) == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), socks5HandshakeTime)
+		ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		cancel()
+		if err != nil {
+			return err
+		}
+		host = ips[0].IP.String()
+	}
+
+	conn.SetDeadline(time.Now().Add(socks5HandshakeTime)) //nolint:errcheck
+	defer conn.SetDeadline(time.Time{})                   //nolint:errcheck
+
+	// greeting
+	username, password := proxyURI.Username(), proxyURI.Password()
+	method := byte(socks5AuthNone)
+	if len(username) > 0 {
+		method = socks5AuthPassword
+	}
+	if err = socks5Write(conn, []byte{socks5Version, 1, method}); err != nil {
+		return err
+	}
+	b, err := conn.ReadBinary(2)
+	if err != nil {
+		return err
+	}
+	if b[0] != socks5Version {
+		return fmt.Errorf("socks5: unexpected protocol version %d", b[0])
+	}
+	if b[1] == socks5AuthNoAccept || b[1] != method {
+		return errors.New("socks5: no acceptable authentication methods")
+	}
+
+	if method == socks5AuthPassword {
+		if len(username) > 255 || len(password) > 255 {
+			return errors.New("socks5: username or password too long")
+		}
+		auth := make([]byte, 0, 3+len(username)+len(password))
+		auth = append(auth, 1, byte(len(username)))
+		auth = append(auth, username...)
+		auth = append(auth, byte(len(password)))
+		auth = append(auth, password...)
+		if err = socks5Write(conn, auth); err != nil {
+			return err
+		}
+		if b, err = conn.ReadBinary(2); err != nil {
+			return err
+		}
+		if b[1] != 0 {
+			return errors.New("socks5: username/password authentication failed")
+		}
+	}
+
+	// connect
+	req := []byte{socks5Version, socks5CmdConnect, 0}
+	if ip := net.ParseIP(host); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			req = append(append(req, socks5AddrIPv4), ip4...)
+		} else {
+			req = append(append(req, socks5AddrIPv6), ip.To16()...)
+		}
+	} else {
+		if len(host) > 255 {
+			return errors.New("socks5: host name too long")
+		}
+		req = append(append(req, socks5AddrDomain, byte(len(host))), host...)
+	}
+	req = append(req, byte(port>>8), byte(port))
+	if err = socks5Write(conn, req); err != nil {
+		return err
+	}
+
+	if b, err = conn.ReadBinary(4); err != nil {
+		return err
+	}
+	if b[1] != 0 {
+		if int(b[1]) < len(socks5Replies) {
+			return errors.New("socks5: " + socks5Replies[b[1]])
+		}
+		return fmt.Errorf("socks5: unknown reply code %d", b[1])
+	}
+	// skip the bound address
+	var n int
+	switch b[3] {
+	case socks5AddrIPv4:
+		n = net.IPv4len
+	case socks5AddrIPv6:
+		n = net.IPv6len
+	case socks5AddrDomain:
+		l, err := conn.ReadByte()
+		if err != nil {
+			return err
+		}
+		n = int(l)
+	default:
+		return fmt.Errorf("socks5: unknown address type %d", b[3])
+	}
+	_, err = conn.ReadBinary(n + 2)
+	return err
+}
+
+func socks5Write(conn network.Conn, b []byte) error {
+	if _, err := conn.WriteBinary(b); err != nil {
+		return err
+	}
+	return conn.Flush()
+}
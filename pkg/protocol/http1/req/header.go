@@ -46,6 +46,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"time"
 
 	"hertz-study/internal/bytesconv"
 	"hertz-study/internal/bytestr"
@@ -57,7 +58,25 @@ import (
 	"hertz-study/pkg/protocol/http1/ext"
 )
 
-var errEOFReadHeader = errs.NewPublic("error when reading request headers: EOF")
+var (
+	errEOFReadHeader  = errs.NewPublic("error when reading request headers: EOF")
+	errHeaderTimeout  = errs.New(errs.ErrTimeout, errs.ErrorTypePublic, "http1/req")
+	errHeaderTooLarge = errs.New(errs.ErrHeaderTooLarge, errs.ErrorTypePublic, "http1/req")
+	errTooManyHeaders = errs.New(errs.ErrTooManyHeaders, errs.ErrorTypePublic, "http1/req")
+)
+
+// HeaderLimits bounds reading the request header, the zero values mean no limit.
+type HeaderLimits struct {
+	// Deadline bounds reading the request line and the header, errors.ErrTimeout is returned
+	// after it. The read timeout of r is set to the time left before every read if r supports it.
+	Deadline time.Time
+	// MaxBytes bounds the size of the request line and the header, errors.ErrHeaderTooLarge
+	// is returned if it's exceeded.
+	MaxBytes int
+	// MaxCount bounds the count of the header fields, errors.ErrTooManyHeaders is returned if
+	// it's exceeded.
+	MaxCount int
+}
 
 // Write writes request header to w.
 func WriteHeader(h *protocol.RequestHeader, w network.Writer) error {
@@ -67,9 +86,25 @@ func WriteHeader(h *protocol.RequestHeader, w network.Writer) error {
 }
 
 func ReadHeader(h *protocol.RequestHeader, r network.Reader) error {
+	return ReadHeaderWithLimits(h, r, HeaderLimits{})
+}
+
+// ReadHeaderWithLimits reads request header from r as ReadHeader does within limits.
+func ReadHeaderWithLimits(h *protocol.RequestHeader, r network.Reader, limits HeaderLimits) error {
+	timeoutSetter, _ := r.(interface{ SetReadTimeout(t time.Duration) error })
 	n := 1
 	for {
-		err := tryRead(h, r, n)
+		if !limits.Deadline.IsZero() {
+			left := time.Until(limits.Deadline)
+			if left <= 0 {
+				h.ResetSkipNormalize()
+				return errHeaderTimeout
+			}
+			if timeoutSetter != nil {
+				timeoutSetter.SetReadTimeout(left) //nolint:errcheck
+			}
+		}
+		err := tryRead(h, r, n, limits)
 		if err == nil {
 			return nil
 		}
@@ -77,6 +112,10 @@ func ReadHeader(h *protocol.RequestHeader, r network.Reader) error {
 			h.ResetSkipNormalize()
 			return err
 		}
+		if limits.MaxBytes > 0 && r.Len() >= limits.MaxBytes {
+			h.ResetSkipNormalize()
+			return errHeaderTooLarge
+		}
 
 		// No more data available on the wire, try block peek
 		if n == r.Len() {
@@ -87,7 +126,7 @@ func ReadHeader(h *protocol.RequestHeader, r network.Reader) error {
 	}
 }
 
-func tryRead(h *protocol.RequestHeader, r network.Reader, n int) error {
+func tryRead(h *protocol.RequestHeader, r network.Reader, n int, limits HeaderLimits) error {
 	h.ResetSkipNormalize()
 	b, err := r.Peek(n)
 	if len(b) == 0 {
@@ -104,15 +143,24 @@ func tryRead(h *protocol.RequestHeader, r network.Reader, n int) error {
 		return errEOFReadHeader
 	}
 	b = ext.MustPeekBuffered(r)
-	headersLen, errParse := parse(h, b)
+	headersLen, errParse := parse(h, b, limits.MaxCount)
+	if errParse == errTooManyHeaders {
+		return errParse
+	}
 	if errParse != nil {
 		return ext.HeaderError("request", err, errParse, b)
 	}
+	if limits.MaxBytes > 0 && headersLen > limits.MaxBytes {
+		return errHeaderTooLarge
+	}
+	h.SetHeaderLength(headersLen)
 	ext.MustDiscard(r, headersLen)
 	return nil
 }
 
-func parse(h *protocol.RequestHeader, buf []byte) (int, error) {
+// parse parses the request line and the header in buf, errTooManyHeaders is returned if
+// there are more than maxCount header fields, zero means no limit.
+func parse(h *protocol.RequestHeader, buf []byte, maxCount int) (int, error) {
 	m, err := parseFirstLine(h, buf)
 	if err != nil {
 		return 0, err
@@ -124,7 +172,7 @@ func parse(h *protocol.RequestHeader, buf []byte) (int, error) {
 		return 0, err
 	}
 	var n int
-	n, err = parseHeaders(h, buf[m:])
+	n, err = parseHeaders(h, buf[m:], maxCount)
 	if err != nil {
 		return 0, err
 	}
@@ -176,14 +224,19 @@ func validHeaderFieldValue(val []byte) bool {
 	return true
 }
 
-func parseHeaders(h *protocol.RequestHeader, buf []byte) (int, error) {
+func parseHeaders(h *protocol.RequestHeader, buf []byte, maxCount int) (int, error) {
 	h.InitContentLengthWithValue(-2)
 
 	var s ext.HeaderScanner
 	s.B = buf
 	s.DisableNormalizing = h.IsDisableNormalizing()
 	var err error
+	count := 0
 	for s.Next() {
+		if count++; maxCount > 0 && count > maxCount {
+			h.SetConnectionClose(true)
+			return 0, errTooManyHeaders
+		}
 		if len(s.Key) > 0 {
 			// Spaces between the header key and colon are not allowed.
 			// See RFC 7230, Section 3.2.4.
@@ -22,17 +22,25 @@ import (
 
 	"hertz-study/pkg/network"
 	"hertz-study/pkg/protocol"
+	"hertz-study/pkg/protocol/consts"
 	"hertz-study/pkg/protocol/http1/ext"
 )
 
 var chunkReaderPool sync.Pool
 
+var closeDelimitedWriterPool sync.Pool
+
 func init() {
 	chunkReaderPool = sync.Pool{
 		New: func() interface{} {
 			return &chunkedBodyWriter{}
 		},
 	}
+	closeDelimitedWriterPool = sync.Pool{
+		New: func() interface{} {
+			return &closeDelimitedBodyWriter{}
+		},
+	}
 }
 
 type chunkedBodyWriter struct {
@@ -103,3 +111,76 @@ func NewChunkedBodyWriter(r *protocol.Response, w network.Writer) network.ExtWri
 	runtime.SetFinalizer(extWriter, (*chunkedBodyWriter).release)
 	return extWriter
 }
+
+// closeDelimitedBodyWriter writes the body as is and delimits it by closing the connection,
+// which is used for HTTP/1.0 requests as they don't support chunked encoding.
+type closeDelimitedBodyWriter struct {
+	sync.Once
+	finalizeErr error
+	wroteHeader bool
+	r           *protocol.Response
+	w           network.Writer
+}
+
+func (c *closeDelimitedBodyWriter) writeHeader() error {
+	// the body is read until the connection is closed, so neither Content-Length
+	// nor Transfer-Encoding is sent
+	c.r.Header.SetContentLength(-2)
+	c.r.Header.Del(consts.HeaderTransferEncoding)
+	if err := WriteHeader(&c.r.Header, c.w); err != nil {
+		return err
+	}
+	c.wroteHeader = true
+	return nil
+}
+
+// Write writes p after the header without encoding.
+//
+// NOTE: Write will use the user buffer to flush.
+// Before flush successfully, the buffer b should be valid.
+func (c *closeDelimitedBodyWriter) Write(p []byte) (n int, err error) {
+	if !c.wroteHeader {
+		if err = c.writeHeader(); err != nil {
+			return
+		}
+	}
+	if _, err = c.w.WriteBinary(p); err != nil {
+		return
+	}
+	return len(p), nil
+}
+
+func (c *closeDelimitedBodyWriter) Flush() error {
+	return c.w.Flush()
+}
+
+// Finalize writes the header if no data is written, the end of the body is marked
+// by closing the connection afterwards.
+// Warning: do not call this method by yourself, unless you know what you are doing.
+func (c *closeDelimitedBodyWriter) Finalize() error {
+	c.Do(func() {
+		if !c.wroteHeader {
+			c.finalizeErr = c.writeHeader()
+		}
+	})
+	return c.finalizeErr
+}
+
+func (c *closeDelimitedBodyWriter) release() {
+	c.r = nil
+	c.w = nil
+	c.finalizeErr = nil
+	c.wroteHeader = false
+	closeDelimitedWriterPool.Put(c)
+}
+
+// NewCloseDelimitedBodyWriter returns a writer that writes the body of r as is and marks
+// the connection to be closed to delimit it.
+func NewCloseDelimitedBodyWriter(r *protocol.Response, w network.Writer) network.ExtWriter {
+	extWriter := closeDelimitedWriterPool.Get().(*closeDelimitedBodyWriter)
+	extWriter.r = r
+	extWriter.w = w
+	extWriter.Once = sync.Once{}
+	runtime.SetFinalizer(extWriter, (*closeDelimitedBodyWriter).release)
+	return extWriter
+}
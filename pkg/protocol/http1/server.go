@@ -30,6 +30,7 @@ import (
 	"hertz-study/pkg/app"
 	"hertz-study/pkg/app/server/render"
 	errs "hertz-study/pkg/common/errors"
+	"hertz-study/pkg/common/hlog"
 	"hertz-study/pkg/common/tracer/stats"
 	"hertz-study/pkg/common/tracer/traceinfo"
 	"hertz-study/pkg/network"
@@ -63,14 +64,26 @@ type Option struct {
 	NoDefaultServerHeader         bool
 	DisableHeaderNamesNormalizing bool
 	MaxRequestBodySize            int
+	MaxRequestsPerConn            int
 	IdleTimeout                   time.Duration
 	ReadTimeout                   time.Duration
+	WriteTimeout                  time.Duration
+	ReadHeaderTimeout             time.Duration
+	MaxHeaderBytes                int
+	MaxHeaderCount                int
+	DisablePool                   bool
 	ServerName                    []byte
 	TLS                           *tls.Config
 	HTMLRender                    render.HTMLRender
 	EnableTrace                   bool
 	ContinueHandler               func(header *protocol.RequestHeader) bool
 	HijackConnHandle              func(c network.Conn, h app.HijackHandler)
+	StreamWriteTimeout            time.Duration
+	WriteStallThreshold           time.Duration
+	OnWriteStall                  func(stall network.WriteStall)
+	// Timeouts returns the current read and idle timeouts overriding ReadTimeout and IdleTimeout
+	// if it is not nil, so that they can be changed at runtime. It's checked before every request.
+	Timeouts func() (read, idle time.Duration)
 }
 
 type Server struct {
@@ -133,20 +146,22 @@ func (s Server) Serve(c context.Context, conn network.Conn) (err error) {
 			zr.Release() //nolint:errcheck
 			zr = nil
 		}
+		if s.DisablePool {
+			ctx.Release()
+			return
+		}
 		ctx.Reset()
 		s.Core.GetCtxPool().Put(ctx)
 	}()
 
-	ctx.HTMLRender = s.HTMLRender
-	ctx.SetConn(conn)
-	ctx.Request.SetIsTLS(s.TLS != nil)
-	ctx.SetEnableTrace(s.EnableTrace)
+	s.prepareCtx(ctx, conn)
 
 	if !s.NoDefaultServerHeader {
 		serverName = s.ServerName
 	}
 
 	connRequestNum := uint64(0)
+	readTimeout, idleTimeout := s.ReadTimeout, s.IdleTimeout
 
 	for {
 		connRequestNum++
@@ -155,12 +170,26 @@ func (s Server) Serve(c context.Context, conn network.Conn) (err error) {
 			zr = ctx.GetReader()
 		}
 
+		if s.Timeouts != nil {
+			readTimeout, idleTimeout = s.Timeouts()
+			if connRequestNum == 1 {
+				ctx.GetConn().SetReadTimeout(readTimeout) //nolint:errcheck
+			}
+		}
+
 		// If this is a keep-alive connection we want to try and read the first bytes
 		// within the idle time.
 		if connRequestNum > 1 {
-			ctx.GetConn().SetReadTimeout(s.IdleTimeout) //nolint:errcheck
+			ctx.GetConn().SetReadTimeout(idleTimeout) //nolint:errcheck
 
+			idleTracker, _ := ctx.GetConn().(network.IdleTracker)
+			if idleTracker != nil {
+				idleTracker.SetIdle(true)
+			}
 			_, err = zr.Peek(4)
+			if idleTracker != nil {
+				idleTracker.SetIdle(false)
+			}
 			// This is not the first request, and we haven't read a single byte
 			// of a new request yet. This means it's just a keep-alive connection
 			// closing down either because the remote closed it or because
@@ -172,10 +201,11 @@ func (s Server) Serve(c context.Context, conn network.Conn) (err error) {
 			}
 
 			// Reset the real read timeout for the coming request
-			ctx.GetConn().SetReadTimeout(s.ReadTimeout) //nolint:errcheck
+			ctx.GetConn().SetReadTimeout(readTimeout) //nolint:errcheck
 		}
 
 		if s.EnableTrace {
+			ctx.IOStats().ReadStart = time.Now()
 			cc = traceCtl.DoStart(c, ctx)
 			internalStats.Record(ctx.GetTraceInfo(), stats.ReadHeaderStart, err)
 			eventsToTrigger.push(func(ti traceinfo.TraceInfo, err error) {
@@ -192,7 +222,19 @@ func (s Server) Serve(c context.Context, conn network.Conn) (err error) {
 		}
 
 		// Read Headers
-		if err = req.ReadHeader(&ctx.Request.Header, zr); err == nil {
+		limits := req.HeaderLimits{MaxBytes: s.MaxHeaderBytes, MaxCount: s.MaxHeaderCount}
+		headerRejected := false
+		if s.ReadHeaderTimeout > 0 {
+			limits.Deadline = time.Now().Add(s.ReadHeaderTimeout)
+		}
+		if err = req.ReadHeaderWithLimits(&ctx.Request.Header, zr, limits); err != nil {
+			headerRejected = s.ReadHeaderTimeout > 0 && isTimeout(normalizeErr(ctx.GetConn(), err)) ||
+				errors.Is(err, errs.ErrHeaderTooLarge) || errors.Is(err, errs.ErrTooManyHeaders)
+		} else {
+			if s.ReadHeaderTimeout > 0 {
+				// the read timeout applies to the body
+				ctx.GetConn().SetReadTimeout(readTimeout) //nolint:errcheck
+			}
 			if s.EnableTrace {
 				// read header finished
 				if last := eventsToTrigger.pop(); last != nil {
@@ -211,12 +253,9 @@ func (s Server) Serve(c context.Context, conn network.Conn) (err error) {
 			}
 		}
 
+		countRequest(ctx)
 		if s.EnableTrace {
-			if ctx.Request.Header.ContentLength() >= 0 {
-				ctx.GetTraceInfo().Stats().SetRecvSize(len(ctx.Request.Header.RawHeaders()) + ctx.Request.Header.ContentLength())
-			} else {
-				ctx.GetTraceInfo().Stats().SetRecvSize(0)
-			}
+			ctx.GetTraceInfo().Stats().SetRecvSize(ctx.IOStats().BytesRead())
 			// read body finished
 			if last := eventsToTrigger.pop(); last != nil {
 				last(ctx.GetTraceInfo(), err)
@@ -231,7 +270,12 @@ func (s Server) Serve(c context.Context, conn network.Conn) (err error) {
 			if err == io.EOF {
 				return errUnexpectedEOF
 			}
-			writeErrorResponse(zw, ctx, serverName, err)
+			writeErrorResponse(zw, ctx, serverName, normalizeErr(ctx.GetConn(), err))
+			if headerRejected {
+				hlog.SystemLogger().Warnf("Reject the request header from remote=%s: error=%v", ctx.GetConn().RemoteAddr(), err)
+				// the rejection is logged above, close the connection quietly
+				return errShortConnection
+			}
 			return
 		}
 
@@ -247,6 +291,9 @@ func (s Server) Serve(c context.Context, conn network.Conn) (err error) {
 
 			if continueReadingRequest {
 				zw = ctx.GetWriter()
+				if s.WriteTimeout > 0 {
+					ctx.GetConn().SetWriteTimeout(s.WriteTimeout) //nolint:errcheck
+				}
 				// Send 'HTTP/1.1 100 Continue' response.
 				_, err = zw.WriteBinary(bytestr.StrResponseContinue)
 				if err != nil {
@@ -256,6 +303,11 @@ func (s Server) Serve(c context.Context, conn network.Conn) (err error) {
 				if err != nil {
 					return
 				}
+				if s.WriteTimeout > 0 {
+					if err = ctx.GetConn().SetWriteTimeout(0); err != nil {
+						return
+					}
+				}
 
 				// Read body.
 				if zr == nil {
@@ -267,13 +319,18 @@ func (s Server) Serve(c context.Context, conn network.Conn) (err error) {
 					err = req.ContinueReadBody(&ctx.Request, zr, s.MaxRequestBodySize, !s.DisablePreParseMultipartForm)
 				}
 				if err != nil {
-					writeErrorResponse(zw, ctx, serverName, err)
+					writeErrorResponse(zw, ctx, serverName, normalizeErr(ctx.GetConn(), err))
 					return
 				}
+				countRequest(ctx)
 			}
 		}
+		if s.EnableTrace {
+			ctx.IOStats().ReadEnd = time.Now()
+		}
 
-		connectionClose = s.DisableKeepalive || ctx.Request.Header.ConnectionClose()
+		connectionClose = s.DisableKeepalive || ctx.Request.Header.ConnectionClose() ||
+			(s.MaxRequestsPerConn > 0 && connRequestNum >= uint64(s.MaxRequestsPerConn))
 		isHTTP11 = ctx.Request.Header.IsHTTP11()
 
 		if serverName != nil {
@@ -291,6 +348,7 @@ func (s Server) Serve(c context.Context, conn network.Conn) (err error) {
 		// and the route has been matched.
 		s.Core.ServeHTTP(cc, ctx)
 		if s.EnableTrace {
+			ctx.IOStats().HandleEnd = time.Now()
 			// application layer handle finished
 			if last := eventsToTrigger.pop(); last != nil {
 				last(ctx.GetTraceInfo(), err)
@@ -325,16 +383,16 @@ func (s Server) Serve(c context.Context, conn network.Conn) (err error) {
 				internalStats.Record(ti, stats.WriteFinish, err)
 			})
 		}
+		if s.WriteTimeout > 0 {
+			// bound writing the whole response, so that a client not reading can't pin the connection
+			ctx.GetConn().SetWriteTimeout(s.WriteTimeout) //nolint:errcheck
+		}
 		if err = writeResponse(ctx, zw); err != nil {
 			return
 		}
 
 		if s.EnableTrace {
-			if ctx.Response.Header.ContentLength() > 0 {
-				ctx.GetTraceInfo().Stats().SetSendSize(ctx.Response.Header.GetHeaderLength() + ctx.Response.Header.ContentLength())
-			} else {
-				ctx.GetTraceInfo().Stats().SetSendSize(0)
-			}
+			ctx.GetTraceInfo().Stats().SetSendSize(ctx.IOStats().BytesWritten())
 		}
 
 		// Release the zeroCopyReader before flush to prevent data race
@@ -346,7 +404,15 @@ func (s Server) Serve(c context.Context, conn network.Conn) (err error) {
 		if err = zw.Flush(); err != nil {
 			return
 		}
+		if s.WriteTimeout > 0 {
+			// the deadline is absolute on the standard transport, it must not outlive the response
+			// to fail the writes of the next request, e.g. ctx.Flush after an idle period
+			if err = ctx.GetConn().SetWriteTimeout(0); err != nil {
+				return
+			}
+		}
 		if s.EnableTrace {
+			ctx.IOStats().WriteEnd = time.Now()
 			// write finished
 			if last := eventsToTrigger.pop(); last != nil {
 				last(ctx.GetTraceInfo(), err)
@@ -356,6 +422,10 @@ func (s Server) Serve(c context.Context, conn network.Conn) (err error) {
 		// Release request body stream
 		if ctx.Request.IsBodyStream() {
 			err = ext.ReleaseBodyStream(ctx.RequestBodyStream())
+			if s.EnableTrace {
+				// the unread rest of the body is counted on release
+				ctx.GetTraceInfo().Stats().SetRecvSize(ctx.IOStats().BytesRead())
+			}
 			if err != nil {
 				return
 			}
@@ -379,7 +449,7 @@ func (s Server) Serve(c context.Context, conn network.Conn) (err error) {
 		}
 		// Back to network layer to trigger.
 		// For now, only netpoll network mode has this feature.
-		if s.IdleTimeout == 0 {
+		if idleTimeout == 0 {
 			return
 		}
 		// general case
@@ -387,10 +457,46 @@ func (s Server) Serve(c context.Context, conn network.Conn) (err error) {
 			traceCtl.DoFinish(cc, ctx, err)
 		}
 
+		if s.DisablePool {
+			// the handlers may hold the context, serve the next request with a new one
+			ctx.Release()
+			ctx = s.Core.GetCtxPool().Get().(*app.RequestContext)
+			s.prepareCtx(ctx, conn)
+			continue
+		}
 		ctx.ResetWithoutConn()
 	}
 }
 
+// countRequest records the size of the request read to the IOStats of ctx, the streamed body is
+// counted as it is read.
+func countRequest(ctx *app.RequestContext) {
+	ioStats := ctx.IOStats()
+	ioStats.HeaderBytesRead = ctx.Request.Header.GetHeaderLength()
+	if ctx.Request.IsBodyStream() {
+		ioStats.BodyBytesRead = 0
+		ext.CountBodyStream(ctx.Request.BodyStream(), &ioStats.BodyBytesRead)
+		return
+	}
+	ioStats.BodyBytesRead = len(ctx.Request.BodyBytes())
+	if ioStats.BodyBytesRead == 0 && ctx.Request.Header.ContentLength() > 0 {
+		// the multipart form may be parsed from the connection without buffering the body
+		ioStats.BodyBytesRead = ctx.Request.Header.ContentLength()
+	}
+}
+
+// prepareCtx sets the connection related contents of ctx.
+func (s Server) prepareCtx(ctx *app.RequestContext, conn network.Conn) {
+	ctx.HTMLRender = s.HTMLRender
+	ctx.SetConn(conn)
+	ctx.SetFlushWriterFunc(newFlushWriter)
+	if s.StreamWriteTimeout > 0 || s.OnWriteStall != nil {
+		ctx.SetWriter(s.flushDeadlineWriter(ctx, conn))
+	}
+	ctx.Request.SetIsTLS(s.TLS != nil)
+	ctx.SetEnableTrace(s.EnableTrace)
+}
+
 func NewServer() *Server {
 	return &Server{
 		eventStackPool: &sync.Pool{
@@ -401,6 +507,20 @@ func NewServer() *Server {
 	}
 }
 
+func (s Server) flushDeadlineWriter(ctx *app.RequestContext, conn network.Conn) network.Writer {
+	w := &network.FlushDeadlineWriter{
+		Conn:      conn,
+		Timeout:   s.StreamWriteTimeout,
+		Threshold: s.WriteStallThreshold,
+	}
+	if s.OnWriteStall != nil {
+		w.OnStall = func(d time.Duration, timedOut bool) {
+			s.OnWriteStall(network.WriteStall{Route: ctx.FullPath(), Duration: d, TimedOut: timedOut})
+		}
+	}
+	return w
+}
+
 func writeErrorResponse(zw network.Writer, ctx *app.RequestContext, serverName []byte, err error) network.Writer {
 	errorHandler := defaultErrorHandler
 
@@ -418,6 +538,16 @@ func writeErrorResponse(zw network.Writer, ctx *app.RequestContext, serverName [
 	return zw
 }
 
+// newFlushWriter returns the writer ctx.Flush streams the response with: chunked encoding for
+// HTTP/1.1 requests, otherwise the body is delimited by closing the connection as HTTP/1.0
+// doesn't support chunked encoding.
+func newFlushWriter(ctx *app.RequestContext, w network.Writer) network.ExtWriter {
+	if ctx.Request.Header.IsHTTP11() {
+		return resp.NewChunkedBodyWriter(&ctx.Response, w)
+	}
+	return resp.NewCloseDelimitedBodyWriter(&ctx.Response, w)
+}
+
 func writeResponse(ctx *app.RequestContext, w network.Writer) error {
 	// Skip default response writing logic if it has been hijacked
 	if ctx.Response.GetHijackWriter() != nil {
@@ -433,15 +563,35 @@ func writeResponse(ctx *app.RequestContext, w network.Writer) error {
 }
 
 func defaultErrorHandler(ctx *app.RequestContext, err error) {
-	if netErr, ok := err.(*net.OpError); ok && netErr.Timeout() {
+	if isTimeout(err) {
 		ctx.AbortWithMsg("Request timeout", consts.StatusRequestTimeout)
-	} else if errors.Is(err, errs.ErrBodyTooLarge) {
+	} else if errors.Is(err, errs.ErrHeaderTooLarge) || errors.Is(err, errs.ErrTooManyHeaders) {
+		ctx.AbortWithMsg("Request Header Fields Too Large", consts.StatusRequestHeaderFieldsTooLarge)
+	} else if errors.Is(err, errs.ErrBodyTooLarge) || errors.Is(err, errs.ErrFileTooLarge) ||
+		errors.Is(err, errs.ErrTooManyFiles) {
 		ctx.AbortWithMsg("Request Entity Too Large", consts.StatusRequestEntityTooLarge)
 	} else {
 		ctx.AbortWithMsg("Error when parsing request", consts.StatusBadRequest)
 	}
 }
 
+// normalizeErr converts the error of the network library to the one of hertz, e.g. errs.ErrTimeout.
+func normalizeErr(conn network.Conn, err error) error {
+	if en, ok := conn.(network.ErrorNormalization); ok {
+		return en.ToHertzError(err)
+	}
+	return err
+}
+
+// isTimeout reports whether err is caused by the read timeout of the connection.
+func isTimeout(err error) bool {
+	if errors.Is(err, errs.ErrTimeout) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
 type eventStack []func(ti traceinfo.TraceInfo, err error)
 
 func (e *eventStack) isEmpty() bool {
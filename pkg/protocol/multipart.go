@@ -45,18 +45,139 @@ import (
 	"fmt"
 	"io"
 	"mime/multipart"
-	"net/http"
 	"net/textproto"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"hertz-study/pkg/common/bytebufferpool"
+	"hertz-study/pkg/common/errors"
+	"hertz-study/pkg/common/mimetype"
 	"hertz-study/pkg/common/utils"
 	"hertz-study/pkg/network"
 	"hertz-study/pkg/protocol/consts"
 )
 
+// MultipartFormConfig limits the parsing of multipart/form-data request bodies, which are
+// parsed in streaming, so the memory usage is bounded by MaxMemory.
+type MultipartFormConfig struct {
+	// MaxMemory is the max bytes of the file contents kept in memory, the rest are spilled
+	// to the temporary files in os.TempDir(), which can be changed by the TMPDIR environment
+	// variable. consts.DefaultMaxInMemoryFileSize is used if it is not positive.
+	MaxMemory int64
+
+	// MaxFileSize limits the size of every file, errors.ErrFileTooLarge is returned if
+	// any file exceeds it. Unlimited if it is not positive.
+	MaxFileSize int64
+
+	// MaxTotalSize limits the size of the whole body, errors.ErrBodyTooLarge is returned if
+	// the body exceeds it. Unlimited if it is not positive.
+	MaxTotalSize int64
+
+	// MaxFiles limits the count of files, errors.ErrTooManyFiles is returned if the form
+	// has more files. Unlimited if it is not positive.
+	MaxFiles int
+}
+
+// ReadMultipartFormWithConfig reads the multipart form of the given size from r with the
+// limits of cfg. The temporary files are removed if it fails.
+func ReadMultipartFormWithConfig(r io.Reader, boundary string, size int, cfg *MultipartFormConfig) (*multipart.Form, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("form size must be greater than 0. Given %d", size)
+	}
+	f, err := readMultipartForm(io.LimitReader(r, int64(size)), boundary, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read multipart/form-data body: %w", err)
+	}
+	return f, nil
+}
+
+func readMultipartForm(r io.Reader, boundary string, cfg *MultipartFormConfig) (*multipart.Form, error) {
+	maxMemory := cfg.MaxMemory
+	if maxMemory <= 0 {
+		maxMemory = consts.DefaultMaxInMemoryFileSize
+	}
+	if cfg.MaxTotalSize > 0 {
+		r = &totalLimitReader{r: r, n: cfg.MaxTotalSize}
+	}
+	if cfg.MaxFileSize <= 0 && cfg.MaxFiles <= 0 {
+		return multipart.NewReader(r, boundary).ReadForm(maxMemory)
+	}
+
+	// The form files can only be created by multipart.Reader.ReadForm, so the parts are
+	// checked and re-encoded in streaming before it.
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(copyMultipartParts(multipart.NewReader(r, boundary), pw, boundary, cfg)) //nolint:errcheck
+	}()
+	f, err := multipart.NewReader(pr, boundary).ReadForm(maxMemory)
+	// unblock the writer if ReadForm stops early
+	pr.Close()
+	return f, err
+}
+
+func copyMultipartParts(mr *multipart.Reader, w io.Writer, boundary string, cfg *MultipartFormConfig) error {
+	mw := multipart.NewWriter(w)
+	if err := mw.SetBoundary(boundary); err != nil {
+		return err
+	}
+	files := 0
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			return mw.Close()
+		}
+		if err != nil {
+			return err
+		}
+		pw, err := mw.CreatePart(p.Header)
+		if err != nil {
+			return err
+		}
+		if p.FileName() == "" {
+			if _, err = io.Copy(pw, p); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if files++; cfg.MaxFiles > 0 && files > cfg.MaxFiles {
+			return errors.ErrTooManyFiles
+		}
+		var src io.Reader = p
+		if cfg.MaxFileSize > 0 {
+			src = io.LimitReader(p, cfg.MaxFileSize+1)
+		}
+		n, err := io.Copy(pw, src)
+		if err != nil {
+			return err
+		}
+		if cfg.MaxFileSize > 0 && n > cfg.MaxFileSize {
+			return errors.ErrFileTooLarge
+		}
+	}
+}
+
+// totalLimitReader returns errors.ErrBodyTooLarge once more than n bytes are read.
+type totalLimitReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *totalLimitReader) Read(p []byte) (int, error) {
+	if r.n < 0 {
+		return 0, errors.ErrBodyTooLarge
+	}
+	if int64(len(p)) > r.n+1 {
+		p = p[:r.n+1]
+	}
+	n, err := r.r.Read(p)
+	if r.n -= int64(n); r.n < 0 {
+		return n, errors.ErrBodyTooLarge
+	}
+	return n, err
+}
+
 func ReadMultipartForm(r io.Reader, boundary string, size, maxInMemoryFileSize int) (*multipart.Form, error) {
 	// Do not care about memory allocations here, since they are tiny
 	// compared to multipart data (aka multi-MB files) usually sent
@@ -141,7 +262,7 @@ func WriteMultipartFormFile(w *multipart.Writer, fieldName, fileName string, r i
 		return err
 	}
 
-	partWriter, err := w.CreatePart(CreateMultipartHeader(fieldName, fileName, http.DetectContentType(cbuf[:size])))
+	partWriter, err := w.CreatePart(CreateMultipartHeader(fieldName, fileName, mimetype.Detect(fileName, cbuf[:size])))
 	if err != nil {
 		return err
 	}
@@ -181,8 +302,16 @@ func AddFile(w *multipart.Writer, fieldName, path string) error {
 	return WriteMultipartFormFile(w, fieldName, filepath.Base(path), file)
 }
 
+// ParseMultipartForm reads the multipart form of request from r, with the limits set by
+// Request.SetMultipartFormConfig if any.
 func ParseMultipartForm(r io.Reader, request *Request, size, maxInMemoryFileSize int) error {
-	m, err := ReadMultipartForm(r, request.multipartFormBoundary, size, maxInMemoryFileSize)
+	var m *multipart.Form
+	var err error
+	if cfg := request.multipartFormConfig; cfg != nil {
+		m, err = ReadMultipartFormWithConfig(r, request.multipartFormBoundary, size, cfg)
+	} else {
+		m, err = ReadMultipartForm(r, request.multipartFormBoundary, size, maxInMemoryFileSize)
+	}
 	if err != nil {
 		return err
 	}
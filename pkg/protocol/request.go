@@ -49,6 +49,7 @@ import (
 	"io"
 	"mime/multipart"
 	"net/url"
+	"path/filepath"
 	"strings"
 	"sync"
 
@@ -59,6 +60,7 @@ import (
 	"hertz-study/pkg/common/compress"
 	"hertz-study/pkg/common/config"
 	"hertz-study/pkg/common/errors"
+	"hertz-study/pkg/common/mimetype"
 	"hertz-study/pkg/common/utils"
 	"hertz-study/pkg/network"
 	"hertz-study/pkg/protocol/consts"
@@ -98,6 +100,7 @@ type Request struct {
 	maxKeepBodySize int
 
 	multipartForm         *multipart.Form
+	multipartFormConfig   *MultipartFormConfig
 	multipartFormBoundary string
 
 	// Group bool members in order to reduce Request object size.
@@ -253,7 +256,11 @@ func (req *Request) MultipartForm() (*multipart.Form, error) {
 		} else if len(ce) > 0 {
 			return nil, fmt.Errorf("unsupported Content-Encoding: %q", ce)
 		}
-		f, err = ReadMultipartForm(bytes.NewReader(body), req.multipartFormBoundary, len(body), len(body))
+		if req.multipartFormConfig != nil {
+			f, err = ReadMultipartFormWithConfig(bytes.NewReader(body), req.multipartFormBoundary, len(body), req.multipartFormConfig)
+		} else {
+			f, err = ReadMultipartForm(bytes.NewReader(body), req.multipartFormBoundary, len(body), len(body))
+		}
 	} else {
 		bodyStream := req.bodyStream
 		if req.Header.contentLength > 0 {
@@ -268,9 +275,12 @@ func (req *Request) MultipartForm() (*multipart.Form, error) {
 			return nil, fmt.Errorf("unsupported Content-Encoding: %q", ce)
 		}
 
-		mr := multipart.NewReader(bodyStream, req.multipartFormBoundary)
-
-		f, err = mr.ReadForm(8 * 1024)
+		if req.multipartFormConfig != nil {
+			f, err = readMultipartForm(bodyStream, req.multipartFormBoundary, req.multipartFormConfig)
+		} else {
+			mr := multipart.NewReader(bodyStream, req.multipartFormBoundary)
+			f, err = mr.ReadForm(8 * 1024)
+		}
 	}
 
 	if err != nil {
@@ -297,6 +307,12 @@ func (req *Request) SetMaxKeepBodySize(n int) {
 	req.maxKeepBodySize = n
 }
 
+// SetMultipartFormConfig sets the limits of parsing the multipart form, which are kept
+// after Reset. The form is parsed without limits if cfg is nil.
+func (req *Request) SetMultipartFormConfig(cfg *MultipartFormConfig) {
+	req.multipartFormConfig = cfg
+}
+
 // RequestURI returns the RequestURI for the given request.
 func (req *Request) RequestURI() []byte {
 	return req.Header.RequestURI()
@@ -782,7 +798,11 @@ func (req *Request) RemoveMultipartFormFiles() {
 }
 
 func AddMultipartFormField(w *multipart.Writer, mf *MultipartField) error {
-	partWriter, err := w.CreatePart(CreateMultipartHeader(mf.Param, mf.FileName, mf.ContentType))
+	contentType := mf.ContentType
+	if len(contentType) == 0 && len(mf.FileName) > 0 {
+		contentType = mimetype.TypeByExtension(filepath.Ext(mf.FileName))
+	}
+	partWriter, err := w.CreatePart(CreateMultipartHeader(mf.Param, mf.FileName, contentType))
 	if err != nil {
 		return err
 	}
@@ -50,6 +50,7 @@ import (
 	"hertz-study/internal/nocopy"
 	"hertz-study/pkg/common/bytebufferpool"
 	"hertz-study/pkg/common/compress"
+	"hertz-study/pkg/common/errors"
 	"hertz-study/pkg/common/utils"
 	"hertz-study/pkg/network"
 )
@@ -60,6 +61,8 @@ var (
 	// and Close always returns nil. It can be used in an ingoing client
 	// response to explicitly signal that a response has zero bytes.
 	NoResponseBody = noBody{}
+
+	errNoHijackConn = errors.NewPublic("no connection to hijack")
 )
 
 // Response represents HTTP response.
@@ -100,6 +103,9 @@ type Response struct {
 
 	// If set a hijackWriter, hertz will skip the default header/body writer process.
 	hijackWriter network.ExtWriter
+
+	// the connection of the response switching protocols, taken by Hijack
+	hijackConn network.Conn
 }
 
 func (resp *Response) GetHijackWriter() network.ExtWriter {
@@ -110,6 +116,25 @@ func (resp *Response) HijackWriter(writer network.ExtWriter) {
 	resp.hijackWriter = writer
 }
 
+// Hijack returns the connection of the client response switching protocols, i.e. of status 101
+// to a request with the Upgrade header, e.g. to speak websocket over it. The connection is no
+// longer used by the client and the caller must close it. It returns errNoHijackConn if there is
+// no such connection or it has been taken.
+func (resp *Response) Hijack() (network.Conn, error) {
+	conn := resp.hijackConn
+	if conn == nil {
+		return nil, errNoHijackConn
+	}
+	resp.hijackConn = nil
+	return conn, nil
+}
+
+// SetHijackConn sets the connection returned by Hijack, it is used by the client
+// implementations. The connection not taken by Hijack is closed when resp is reset.
+func (resp *Response) SetHijackConn(conn network.Conn) {
+	resp.hijackConn = conn
+}
+
 type responseBodyWriter struct {
 	r *Response
 }
@@ -284,6 +309,10 @@ func (resp *Response) Reset() {
 	resp.laddr = nil
 	resp.ImmediateHeaderFlush = false
 	resp.hijackWriter = nil
+	if resp.hijackConn != nil {
+		resp.hijackConn.Close() //nolint:errcheck
+		resp.hijackConn = nil
+	}
 }
 
 func (resp *Response) resetSkipHeader() {
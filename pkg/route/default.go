@@ -13,8 +13,8 @@
 // limitations under the License.
 //
 
-//go:build !windows
-// +build !windows
+//go:build linux || darwin || freebsd
+// +build linux darwin freebsd
 
 package route
 
@@ -13,6 +13,7 @@ import (
 	"hertz-study/pkg/app"
 	"hertz-study/pkg/app/server/binding"
 	"hertz-study/pkg/app/server/render"
+	"hertz-study/pkg/app/server/transform"
 	"hertz-study/pkg/common/config"
 	errs "hertz-study/pkg/common/errors"
 	"hertz-study/pkg/common/hlog"
@@ -29,12 +30,15 @@ import (
 	"hertz-study/pkg/protocol/suite"
 	"html/template"
 	"io"
+	"net"
 	"path/filepath"
 	"reflect"
 	"runtime"
+	"runtime/debug"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 const unknownTransporterName = "unknown"
@@ -46,6 +50,7 @@ var (
 	errInitFailed       = errs.NewPrivate("engine has been init already")
 	errAlreadyRunning   = errs.NewPrivate("engine is already running")
 	errStatusNotRunning = errs.NewPrivate("engine is not running")
+	errConnNotLimited   = errs.NewPrivate("connections are not limited, set WithConnectionThrottle or WithMaxConcurrentConnections")
 
 	default404Body = []byte("404 page not found")
 	default405Body = []byte("405 method not allowed")
@@ -71,6 +76,16 @@ type RouteInfo struct {
 	Path        string
 	Handler     string
 	HandlerFunc app.HandlerFunc
+	// Doc is set by RouterGroup.WithDoc, nil if the route is not documented.
+	Doc *RouteDoc
+}
+
+// RouteDoc is the human-readable documentation of a route.
+type RouteDoc struct {
+	Summary     string `json:"summary,omitempty"`
+	Description string `json:"description,omitempty"`
+	// Examples are the sample usages of the route, e.g. a curl command line or a request body.
+	Examples []string `json:"examples,omitempty"`
 }
 
 // RoutesInfo defines a RouteInfo array.
@@ -101,6 +116,13 @@ type Engine struct {
 	delims     render.Delims
 	funcMap    template.FuncMap
 	htmlRender render.HTMLRender
+	htmlLayout string
+
+	// routeDocs are the docs of the routes keyed by "method path"
+	routeDocs map[string]*RouteDoc
+
+	// transformers rewrite the response bodies after the handlers
+	transformers transform.Pipeline
 
 	// NoHijackConnPool will control whether invite pool to acquire/release the hijackConn or not.
 	// If it is difficult to guarantee that hijackConn will not be closed repeatedly, set it to true.
@@ -135,6 +157,9 @@ type Engine struct {
 	// unrecovered panics.
 	PanicHandler app.HandlerFunc
 
+	// set by SetErrorHandler
+	errorHandler ErrorHandlerFunc
+
 	// ContinueHandler is called after receiving the Expect 100 Continue Header
 	//
 	// https://www.w3.org/Protocols/rfc2616/rfc2616-sec8.html#sec8.2.3
@@ -154,14 +179,28 @@ type Engine struct {
 	// engine 关机时调用的函数
 	// Hook functions get triggered simultaneously when engine shutdown
 	OnShutdown []CtxCallback
+	// hooks registered by OnShutdownHook
+	orderedShutdownHooks []CtxErrCallback
+
+	// checks run by Run before the OnRun hooks
+	startupChecks []startupCheck
 
 	// Custom Functions
 	clientIPFunc  app.ClientIP
+	trustedCIDRs  []*net.IPNet
 	formValueFunc app.FormValueFunc
 
+	// set by SetTrustedProxies, *clientIPConfig
+	clientIP atomic.Value
+	// set by SetTimeouts, *timeouts
+	timeouts atomic.Value
+
 	// Custom Binder and Validator
 	binder    binding.Binder
 	validator binding.StructValidator
+
+	// limits of parsing multipart forms
+	multipartFormConfig *protocol.MultipartFormConfig
 }
 
 func (engine *Engine) IsTraceEnable() bool {
@@ -186,6 +225,14 @@ func (engine *Engine) GetTransporterName() (tName string) {
 	return getTransporterName(engine.transport)
 }
 
+// waitsNextRequest reports whether the server waits for the next request of the keep-alive
+// connections by itself, since the transporter serves every connection with a goroutine
+// blocking on reads, unlike netpoll which triggers the server on the next request.
+func (engine *Engine) waitsNextRequest() bool {
+	name := engine.GetTransporterName()
+	return name == "standard" || name == "iouring"
+}
+
 func getTransporterName(transporter network.Transporter) (tName string) {
 	defer func() {
 		err := recover()
@@ -253,7 +300,8 @@ func (engine *Engine) NewContext() *app.RequestContext {
 //  3. Wait all connections get closed:
 //     One connection gets closed after reaching out the shorter time of processing
 //     one request (in hand or next incoming), idleTimeout or ExitWaitTime
-//  4. Exit
+//  4. Trigger the hooks registered by OnShutdownHook sequentially
+//  5. Exit
 func (engine *Engine) Shutdown(ctx context.Context) (err error) {
 	if atomic.LoadUint32(&engine.status) != statusRunning {
 		return errStatusNotRunning
@@ -286,11 +334,61 @@ func (engine *Engine) Shutdown(ctx context.Context) (err error) {
 	}
 
 	// call transport shutdown
-	if err := engine.transport.Shutdown(ctx); err != ctx.Err() {
+	err = engine.transport.Shutdown(ctx)
+	engine.executeOrderedShutdownHooks(ctx)
+	if err != ctx.Err() {
 		return err
 	}
 
-	return
+	return nil
+}
+
+// OnRunHook registers f to be called when the engine starts, after the hooks registered before,
+// Run fails with the error returned by f. f is abandoned after timeout with the error
+// context.DeadlineExceeded if timeout > 0.
+func (engine *Engine) OnRunHook(f CtxErrCallback, timeout time.Duration) {
+	engine.OnRun = append(engine.OnRun, func(ctx context.Context) error {
+		return runHook(ctx, f, timeout)
+	})
+}
+
+// OnShutdownHook registers f to be called on Shutdown, which is useful to release the resources
+// used by the handlers, e.g. DB pools and consumers. Unlike the OnShutdown hooks, the hooks
+// registered by OnShutdownHook are called sequentially in the order of registration after the
+// listener is closed and the connections are closed, or the ctx of Shutdown is done.
+// The errors are logged, f is abandoned after timeout if timeout > 0, and after the ctx of
+// Shutdown is done anyway.
+func (engine *Engine) OnShutdownHook(f CtxErrCallback, timeout time.Duration) {
+	engine.orderedShutdownHooks = append(engine.orderedShutdownHooks, func(ctx context.Context) error {
+		return runHook(ctx, f, timeout)
+	})
+}
+
+func (engine *Engine) executeOrderedShutdownHooks(ctx context.Context) {
+	for i, f := range engine.orderedShutdownHooks {
+		if err := f(ctx); err != nil {
+			hlog.SystemLogger().Errorf("Execute OnShutdownHook index=%d error=%v", i, err)
+		}
+	}
+}
+
+// runHook calls f and waits until it returns or the ctx, limited by timeout if timeout > 0, is done.
+func runHook(ctx context.Context, f CtxErrCallback, timeout time.Duration) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- f(ctx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 func (engine *Engine) executeOnShutdownHooks(ctx context.Context, ch chan struct{}) {
@@ -307,6 +405,10 @@ func (engine *Engine) executeOnShutdownHooks(ctx context.Context, ch chan struct
 }
 
 func (engine *Engine) Run() (err error) {
+	if err = engine.runStartupChecks(context.Background()); err != nil {
+		return err
+	}
+
 	if err = engine.Init(); err != nil {
 		return err
 	}
@@ -582,6 +684,14 @@ func NewEngine(opt *config.Options) *Engine {
 		options:               opt,
 	}
 	engine.initBinderAndValidator(opt)
+	engine.initClientIP(opt)
+	if opt.MultipartFormConfig != nil {
+		cfg, ok := opt.MultipartFormConfig.(*protocol.MultipartFormConfig)
+		if !ok {
+			panic("opt.MultipartFormConfig is not the '*protocol.MultipartFormConfig' type")
+		}
+		engine.multipartFormConfig = cfg
+	}
 	if opt.TransporterNewer != nil {
 		engine.transport = opt.TransporterNewer(opt)
 	}
@@ -606,6 +716,87 @@ func NewEngine(opt *config.Options) *Engine {
 	return engine
 }
 
+// initClientIP builds the ClientIP function from the trusted proxies and the remote IP headers,
+// the default one of app package is kept if neither is configured. The trusted proxies are also
+// used to honor the forwarding headers of the scheme and host.
+func (engine *Engine) initClientIP(opt *config.Options) {
+	if opt.TrustedProxies == nil && opt.RemoteIPHeaders == nil {
+		return
+	}
+	f, cidrs, err := newClientIP(opt.RemoteIPHeaders, opt.TrustedProxies)
+	if err != nil {
+		panic(err.Error())
+	}
+	engine.clientIPFunc = f
+	engine.trustedCIDRs = cidrs
+}
+
+// newClientIP builds the ClientIP function honoring the remote IP headers set by the proxies,
+// nil proxies means all. The returned CIDRs of the proxies are nil if all of them are trusted,
+// so that the forwarded scheme and host are only honored from the listed proxies.
+func newClientIP(headers, proxies []string) (app.ClientIP, []*net.IPNet, error) {
+	ipOpts := app.ClientIPOptions{
+		RemoteIPHeaders: headers,
+		TrustedCIDRs:    []*net.IPNet{},
+	}
+	if ipOpts.RemoteIPHeaders == nil {
+		ipOpts.RemoteIPHeaders = []string{"X-Forwarded-For", "X-Real-IP"}
+	}
+	if proxies == nil {
+		ipOpts.TrustedCIDRs, _ = app.ParseTrustedCIDRs([]string{"0.0.0.0/0", "::/0"})
+		return app.ClientIPWithOption(ipOpts), nil, nil
+	}
+	cidrs, err := app.ParseTrustedCIDRs(proxies)
+	if err != nil {
+		return nil, nil, err
+	}
+	ipOpts.TrustedCIDRs = cidrs
+	return app.ClientIPWithOption(ipOpts), cidrs, nil
+}
+
+type clientIPConfig struct {
+	f     app.ClientIP
+	cidrs []*net.IPNet
+}
+
+// SetTrustedProxies changes the proxies whose forwarding headers are trusted at runtime, see
+// server.WithTrustedProxies. It takes effect from the next request, nil trusts all the proxies.
+func (engine *Engine) SetTrustedProxies(proxies []string) error {
+	f, cidrs, err := newClientIP(engine.options.RemoteIPHeaders, proxies)
+	if err != nil {
+		return err
+	}
+	engine.clientIP.Store(&clientIPConfig{f: f, cidrs: cidrs})
+	return nil
+}
+
+type timeouts struct {
+	read, idle time.Duration
+}
+
+// SetTimeouts changes the read timeout and the idle timeout of the connections at runtime,
+// see server.WithReadTimeout and server.WithIdleTimeout. It takes effect from the next request
+// of every connection. Netpoll handles the keep-alive connections by itself if the idle timeout
+// is zero at start, which can't be changed afterwards.
+func (engine *Engine) SetTimeouts(read, idle time.Duration) {
+	// the same as newHttp1OptionFromEngine
+	if idle == 0 && engine.waitsNextRequest() {
+		idle = -1
+	}
+	engine.timeouts.Store(&timeouts{read: read, idle: idle})
+}
+
+// SetConnectionThrottle changes the rate of new connections at runtime, see
+// server.WithConnectionThrottle. It fails if the transporter doesn't limit the connections.
+func (engine *Engine) SetConnectionThrottle(perSecond, burst int) error {
+	lt, ok := engine.transport.(network.LimitedTransporter)
+	if !ok || lt.ConnLimiter() == nil {
+		return errConnNotLimited
+	}
+	lt.ConnLimiter().SetThrottle(perSecond, burst)
+	return nil
+}
+
 func initTrace(engine *Engine) stats.Level {
 	for _, ti := range engine.options.Tracers {
 		if tracer, ok := ti.(tracer.Tracer); ok {
@@ -648,10 +839,16 @@ func (engine *Engine) addRoute(method, path string, handlers app.HandlersChain)
 	methodRouter := engine.trees.get(method)
 	if methodRouter == nil {
 		methodRouter = &router{method: method, root: &node{}, hasTsrHandler: make(map[string]bool)}
+		if engine.options.RouteSharding {
+			methodRouter.shards = newRouteShards(method)
+		}
 		engine.trees = append(engine.trees, methodRouter)
 	}
 	// 添加路由
 	methodRouter.addRoute(path, handlers)
+	if methodRouter.shards != nil {
+		methodRouter.shards.addRoute(path, handlers)
+	}
 
 	// Update maxParams
 	if paramsCount := countParams(path); paramsCount > engine.maxParams {
@@ -674,9 +871,18 @@ func printNode(node *node, level int) {
 	}
 }
 
-func (engine *Engine) recv(ctx *app.RequestContext) {
+func (engine *Engine) recv(c context.Context, ctx *app.RequestContext) {
 	if rcv := recover(); rcv != nil {
-		engine.PanicHandler(context.Background(), ctx)
+		if report := engine.options.PanicReporter; report != nil {
+			report(c, rcv, debug.Stack())
+		}
+		if engine.PanicHandler != nil {
+			engine.PanicHandler(c, ctx)
+			return
+		}
+		hlog.SystemLogger().Errorf("Recovered panic=%v\nstack=%s", rcv, debug.Stack())
+		ctx.SetStatusCode(consts.StatusInternalServerError)
+		engine.errorHandler(c, ctx, panicError(rcv))
 	}
 }
 
@@ -684,22 +890,76 @@ func (engine *Engine) recv(ctx *app.RequestContext) {
 func (engine *Engine) ServeHTTP(c context.Context, ctx *app.RequestContext) {
 	ctx.SetBinder(engine.binder)
 	ctx.SetValidator(engine.validator)
-	if engine.PanicHandler != nil {
-		defer engine.recv(ctx)
+	ctx.SetPanicReporter(engine.options.PanicReporter)
+	if ip, _ := engine.clientIP.Load().(*clientIPConfig); ip != nil {
+		ctx.SetClientIPFunc(ip.f)
+		ctx.SetTrustedCIDRs(ip.cidrs)
+	}
+	ctx.HTMLRender = engine.htmlRender
+	if engine.PanicHandler != nil || engine.errorHandler != nil {
+		defer engine.recv(c, ctx)
+	}
+
+	switch code, body := engine.match(ctx); code {
+	case consts.StatusOK:
+		ctx.Next(c)
+		if engine.errorHandler != nil && len(ctx.Errors) > 0 {
+			engine.errorHandler(c, ctx, ctx.Errors.Last())
+		}
+	case consts.StatusBadRequest, consts.StatusNotFound, consts.StatusMethodNotAllowed:
+		serveError(c, ctx, code, body, engine.errorHandler)
+	}
+
+	if engine.transformers.Len() > 0 {
+		if err := engine.transformers.Apply(c, ctx); err != nil {
+			hlog.SystemLogger().Errorf("Transform response body error=%v", err)
+		}
 	}
+}
+
+// RegisterTransformer registers the transformers rewriting the response bodies of the content type,
+// e.g. "text/html" or "text/*", after the handlers, see transform.Pipeline for details. It is not
+// safe to be called concurrently with serving the requests.
+func (engine *Engine) RegisterTransformer(contentType string, transformers ...transform.Transformer) {
+	engine.transformers.Register(contentType, transformers...)
+}
+
+// Match finds the route of the request in ctx without executing any handler, which is
+// useful for debugging the routes. It sets the params, the full path and the handlers of
+// the matched route to ctx, and returns the status code the engine would respond with:
+//
+//   - consts.StatusOK if a route matches.
+//   - consts.StatusBadRequest if the request is invalid, e.g. missing Host.
+//   - consts.StatusNotFound or consts.StatusMethodNotAllowed with the NoRoute or NoMethod
+//     handlers set to ctx.
+//   - the redirection status code with the Location header set to ctx.Response if the path
+//     is redirected by RedirectTrailingSlash or RedirectFixedPath.
+func (engine *Engine) Match(ctx *app.RequestContext) int {
+	code, _ := engine.match(ctx)
+	return code
+}
+
+// match returns the status code and the default body of the error response.
+func (engine *Engine) match(ctx *app.RequestContext) (int, []byte) {
+	code, body := engine.matchPath(ctx)
+	// keep the params valid even if the path is modified, e.g. by a middleware
+	ctx.CopyParamValues(engine.options.ReuseParamValues)
+	return code, body
+}
 
-	rPath := string(ctx.Request.URI().Path())
+// matchPath is match without copying the path of the request, the params found reference it.
+func (engine *Engine) matchPath(ctx *app.RequestContext) (int, []byte) {
+	rPath := bytesconv.B2s(ctx.Request.URI().Path())
 
 	// align with https://datatracker.ietf.org/doc/html/rfc2616#section-5.2
 	if len(ctx.Request.Host()) == 0 && ctx.Request.Header.IsHTTP11() && bytesconv.B2s(ctx.Request.Method()) != consts.MethodConnect {
-		serveError(c, ctx, consts.StatusBadRequest, requiredHostBody)
-		return
+		return consts.StatusBadRequest, requiredHostBody
 	}
 
 	httpMethod := bytesconv.B2s(ctx.Request.Header.Method())
 	unescape := false
 	if engine.options.UseRawPath {
-		rPath = string(ctx.Request.URI().PathOriginal())
+		rPath = bytesconv.B2s(ctx.Request.URI().PathOriginal())
 		unescape = engine.options.UnescapePathValues
 	}
 
@@ -709,8 +969,7 @@ func (engine *Engine) ServeHTTP(c context.Context, ctx *app.RequestContext) {
 
 	// Follow RFC7230#section-5.3
 	if rPath == "" || rPath[0] != '/' {
-		serveError(c, ctx, consts.StatusBadRequest, default400Body)
-		return
+		return consts.StatusBadRequest, default400Body
 	}
 
 	// Find root of the tree for the given HTTP method
@@ -726,16 +985,15 @@ func (engine *Engine) ServeHTTP(c context.Context, ctx *app.RequestContext) {
 		if value.handlers != nil {
 			ctx.SetHandlers(value.handlers)
 			ctx.SetFullPath(value.fullPath)
-			ctx.Next(c)
-			return
+			return consts.StatusOK, nil
 		}
 		if httpMethod != consts.MethodConnect && rPath != "/" {
 			if value.tsr && engine.options.RedirectTrailingSlash {
 				redirectTrailingSlash(ctx)
-				return
+				return ctx.Response.StatusCode(), nil
 			}
 			if engine.options.RedirectFixedPath && redirectFixedPath(ctx, t[i].root, engine.options.RedirectFixedPath) {
-				return
+				return ctx.Response.StatusCode(), nil
 			}
 		}
 		break
@@ -748,25 +1006,27 @@ func (engine *Engine) ServeHTTP(c context.Context, ctx *app.RequestContext) {
 			}
 			if value := tree.find(rPath, paramsPointer, unescape); value.handlers != nil {
 				ctx.SetHandlers(engine.allNoMethod)
-				serveError(c, ctx, consts.StatusMethodNotAllowed, default405Body)
-				return
+				return consts.StatusMethodNotAllowed, default405Body
 			}
 		}
 	}
 	ctx.SetHandlers(engine.allNoRoute)
-	serveError(c, ctx, consts.StatusNotFound, default404Body)
+	return consts.StatusNotFound, default404Body
 }
 
 func (engine *Engine) allocateContext() *app.RequestContext {
 	ctx := engine.NewContext()
 	ctx.Request.SetMaxKeepBodySize(engine.options.MaxKeepBodySize)
 	ctx.Response.SetMaxKeepBodySize(engine.options.MaxKeepBodySize)
+	ctx.Request.SetMultipartFormConfig(engine.multipartFormConfig)
 	ctx.SetClientIPFunc(engine.clientIPFunc)
+	ctx.SetTrustedCIDRs(engine.trustedCIDRs)
 	ctx.SetFormValueFunc(engine.formValueFunc)
+	ctx.SetStreamRenderBufferSize(engine.options.StreamRenderBufferSize)
 	return ctx
 }
 
-func serveError(c context.Context, ctx *app.RequestContext, code int, defaultMessage []byte) {
+func serveError(c context.Context, ctx *app.RequestContext, code int, defaultMessage []byte, errorHandler ErrorHandlerFunc) {
 	ctx.SetStatusCode(code)
 	ctx.Next(c)
 	if ctx.Response.StatusCode() == code {
@@ -774,6 +1034,10 @@ func serveError(c context.Context, ctx *app.RequestContext, code int, defaultMes
 		if ctx.Response.HasBodyBytes() || ctx.Response.IsBodyStream() {
 			return
 		}
+		if errorHandler != nil {
+			errorHandler(c, ctx, engineError(defaultMessage))
+			return
+		}
 		ctx.Response.Header.Set("Content-Type", "text/plain")
 		ctx.Response.SetBody(defaultMessage)
 	}
@@ -894,7 +1158,7 @@ func (engine *Engine) LoadHTMLFiles(files ...string) {
 
 // SetHTMLTemplate associate a template with HTML renderer.
 func (engine *Engine) SetHTMLTemplate(tmpl *template.Template) {
-	engine.htmlRender = render.HTMLProduction{Template: tmpl.Funcs(engine.funcMap)}
+	engine.htmlRender = render.NewHTMLProduction(tmpl.Funcs(engine.funcMap), engine.htmlLayout)
 }
 
 // SetAutoReloadHTMLTemplate associate a template with HTML renderer.
@@ -905,9 +1169,16 @@ func (engine *Engine) SetAutoReloadHTMLTemplate(tmpl *template.Template, files [
 		FuncMap:         engine.funcMap,
 		Delims:          engine.delims,
 		RefreshInterval: engine.options.AutoReloadInterval,
+		Layout:          engine.htmlLayout,
 	}
 }
 
+// SetHTMLLayout sets the layout which the pages rendered by ctx.HTML are rendered within by default,
+// the layout renders the page by {{ template "yield" . }}. It must be called before loading the templates.
+func (engine *Engine) SetHTMLLayout(layout string) {
+	engine.htmlLayout = layout
+}
+
 // SetFuncMap sets the funcMap used for template.funcMap.
 func (engine *Engine) SetFuncMap(funcMap template.FuncMap) {
 	engine.funcMap = funcMap
@@ -970,10 +1241,22 @@ func (engine *Engine) Routes() (routes RoutesInfo) {
 	for _, tree := range engine.trees {
 		routes = iterate(tree.method, routes, tree.root)
 	}
+	if len(engine.routeDocs) > 0 {
+		for i := range routes {
+			routes[i].Doc = engine.routeDocs[routes[i].Method+" "+routes[i].Path]
+		}
+	}
 
 	return routes
 }
 
+func (engine *Engine) setRouteDoc(method, path string, doc *RouteDoc) {
+	if engine.routeDocs == nil {
+		engine.routeDocs = make(map[string]*RouteDoc)
+	}
+	engine.routeDocs[method+" "+path] = doc
+}
+
 func (engine *Engine) AddProtocol(protocol string, factory interface{}) {
 	engine.protocolSuite.Add(protocol, factory)
 }
@@ -1022,8 +1305,14 @@ func newHttp1OptionFromEngine(engine *Engine) *http1.Option {
 		DisableKeepalive:              engine.options.DisableKeepalive,
 		NoDefaultServerHeader:         engine.options.NoDefaultServerHeader,
 		MaxRequestBodySize:            engine.options.MaxRequestBodySize,
+		MaxRequestsPerConn:            engine.options.MaxRequestsPerConnection,
 		IdleTimeout:                   engine.options.IdleTimeout,
 		ReadTimeout:                   engine.options.ReadTimeout,
+		WriteTimeout:                  engine.options.WriteTimeout,
+		ReadHeaderTimeout:             engine.options.ReadHeaderTimeout,
+		MaxHeaderBytes:                engine.options.MaxHeaderBytes,
+		MaxHeaderCount:                engine.options.MaxHeaderCount,
+		DisablePool:                   engine.options.DisablePool,
 		ServerName:                    engine.GetServerName(),
 		ContinueHandler:               engine.ContinueHandler,
 		TLS:                           engine.options.TLS,
@@ -1033,12 +1322,21 @@ func newHttp1OptionFromEngine(engine *Engine) *http1.Option {
 		DisableHeaderNamesNormalizing: engine.options.DisableHeaderNamesNormalizing,
 		NoDefaultDate:                 engine.options.NoDefaultDate,
 		NoDefaultContentType:          engine.options.NoDefaultContentType,
+		StreamWriteTimeout:            engine.options.StreamWriteTimeout,
+		WriteStallThreshold:           engine.options.WriteStallThreshold,
+		OnWriteStall:                  engine.options.OnWriteStall,
 	}
 	// Idle timeout of standard network must not be zero. Set it to -1 seconds if it is zero.
 	// Due to the different triggering ways of the network library, see the actual use of this value for the detailed reasons.
-	if opt.IdleTimeout == 0 && engine.GetTransporterName() == "standard" {
+	if opt.IdleTimeout == 0 && engine.waitsNextRequest() {
 		opt.IdleTimeout = -1
 	}
+	opt.Timeouts = func() (read, idle time.Duration) {
+		if t, _ := engine.timeouts.Load().(*timeouts); t != nil {
+			return t.read, t.idle
+		}
+		return opt.ReadTimeout, opt.IdleTimeout
+	}
 	return opt
 }
 
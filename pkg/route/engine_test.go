@@ -0,0 +1,260 @@
+// Copyright 2022 CloudWeGo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package route_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"hertz-study/pkg/app"
+	"hertz-study/pkg/app/server"
+	"hertz-study/pkg/common/config"
+	"hertz-study/pkg/common/hlog"
+	"hertz-study/pkg/common/test/assert"
+	"hertz-study/pkg/network/standard"
+)
+
+// replayConn serves the same request again and again over a keep-alive connection and
+// discards the responses, to measure the serving path without the network.
+type replayConn struct {
+	req       []byte
+	remaining int
+	off       int
+}
+
+func (c *replayConn) Read(p []byte) (int, error) {
+	if c.off == len(c.req) {
+		if c.remaining == 0 {
+			return 0, io.EOF
+		}
+		c.remaining--
+		c.off = 0
+	}
+	n := copy(p, c.req[c.off:])
+	c.off += n
+	return n, nil
+}
+
+func (c *replayConn) Write(p []byte) (int, error)        { return len(p), nil }
+func (c *replayConn) Close() error                       { return nil }
+func (c *replayConn) LocalAddr() net.Addr                { return replayAddr }
+func (c *replayConn) RemoteAddr() net.Addr               { return replayAddr }
+func (c *replayConn) SetDeadline(t time.Time) error      { return nil }
+func (c *replayConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *replayConn) SetWriteDeadline(t time.Time) error { return nil }
+
+var replayAddr = &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 8888}
+
+var serveRequests = []struct {
+	name string
+	req  string
+}{
+	{"get", "GET /user/hertz?q=1 HTTP/1.1\r\nHost: example.com\r\nUser-Agent: bench\r\nAccept: */*\r\n\r\n"},
+	{"form", "POST /user/hertz?q=1 HTTP/1.1\r\nHost: example.com\r\nCookie: sid=abc; t=1\r\n" +
+		"Content-Type: application/x-www-form-urlencoded\r\nContent-Length: 7\r\n\r\na=1&b=2"},
+}
+
+func benchmarkServe(b *testing.B, req string, opts ...config.Option) {
+	hlog.SetOutput(io.Discard)
+	h := server.New(opts...)
+	h.GET("/user/:name", func(c context.Context, ctx *app.RequestContext) {
+		ctx.SetContentType("text/plain")
+		ctx.Response.SetBodyString(ctx.Param("name"))
+	})
+	h.POST("/user/:name", func(c context.Context, ctx *app.RequestContext) {
+		ctx.Header("X-Query", ctx.Query("q"))
+		ctx.Header("X-Session", string(ctx.Cookie("sid")))
+		ctx.SetContentType("text/plain")
+		ctx.Response.SetBodyString(ctx.Param("name") + ctx.PostForm("a"))
+	})
+	if err := h.Init(); err != nil {
+		b.Fatal(err)
+	}
+	if err := h.MarkAsRunning(); err != nil {
+		b.Fatal(err)
+	}
+	conn := standard.NewConn(&replayConn{
+		req:       []byte(req),
+		remaining: b.N - 1,
+	}, 4096)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	h.Serve(context.Background(), conn) //nolint:errcheck
+}
+
+// BenchmarkServe measures the allocations per request of the serving path, the contexts with
+// the requests, responses, headers and URIs embedded are recycled unless the pool is disabled.
+func BenchmarkServe(b *testing.B) {
+	for _, r := range serveRequests {
+		b.Run(r.name+"/pool", func(b *testing.B) {
+			benchmarkServe(b, r.req)
+		})
+		b.Run(r.name+"/nopool", func(b *testing.B) {
+			benchmarkServe(b, r.req, server.WithDisablePool(true))
+		})
+	}
+}
+
+// BenchmarkMatch matches the routes with the param values owned by every request, and with them
+// kept in the buffer reused across requests.
+func BenchmarkMatch(b *testing.B) {
+	routes := []struct {
+		name, path, uri string
+	}{
+		{"static", "/api/v1/ping", "/api/v1/ping"},
+		{"param", "/user/:name", "/user/hertz"},
+		{"params", "/repos/:owner/:repo/issues/:number", "/repos/cloudwego/hertz/issues/42"},
+		{"catchall", "/static/*filepath", "/static/css/main.css"},
+	}
+	hlog.SetOutput(io.Discard)
+	handler := func(c context.Context, ctx *app.RequestContext) {}
+	for _, reuse := range []bool{false, true} {
+		h := server.New(server.WithReuseParamValues(reuse))
+		for _, r := range routes {
+			h.GET(r.path, handler)
+		}
+		mode := "owned"
+		if reuse {
+			mode = "reuse"
+		}
+		for _, r := range routes {
+			b.Run(r.name+"/"+mode, func(b *testing.B) {
+				ctx := h.NewContext()
+				ctx.Request.SetRequestURI(r.uri)
+				ctx.Request.SetHost("example.com")
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					ctx.Params = ctx.Params[:0]
+					if code := h.Match(ctx); code != 200 {
+						b.Fatalf("unexpected status code=%d", code)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestParamKeptAfterRequest(t *testing.T) {
+	hlog.SetOutput(io.Discard)
+	h := server.New()
+	var kept []string
+	h.GET("/user/:name", func(c context.Context, ctx *app.RequestContext) {
+		kept = append(kept, ctx.Param("name"))
+	})
+	if err := h.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.MarkAsRunning(); err != nil {
+		t.Fatal(err)
+	}
+	// both requests are served by the same pooled context
+	conn := standard.NewConn(&replayConn{
+		req: []byte("GET /user/alice HTTP/1.1\r\nHost: example.com\r\n\r\n" +
+			"GET /user/bob HTTP/1.1\r\nHost: example.com\r\n\r\n"),
+	}, 4096)
+	h.Serve(context.Background(), conn) //nolint:errcheck
+	assert.DeepEqual(t, []string{"alice", "bob"}, kept)
+}
+
+func TestCopyReadAfterReuse(t *testing.T) {
+	hlog.SetOutput(io.Discard)
+	for _, reuse := range []bool{false, true} {
+		h := server.New(server.WithReuseParamValues(reuse))
+		const n = 50
+		var wg sync.WaitGroup
+		got := make([]string, n)
+		i := 0
+		h.GET("/user/:name", func(c context.Context, ctx *app.RequestContext) {
+			ctx.Set("index", i)
+			cp := ctx.Copy()
+			i++
+			wg.Add(1)
+			// read the copy while the next requests are served by the same pooled context
+			go func() {
+				defer wg.Done()
+				time.Sleep(time.Millisecond)
+				got[cp.MustGet("index").(int)] = cp.Param("name") + "," + cp.Query("q") + "," +
+					string(cp.GetHeader("X-Id"))
+			}()
+		})
+		if err := h.Init(); err != nil {
+			t.Fatal(err)
+		}
+		if err := h.MarkAsRunning(); err != nil {
+			t.Fatal(err)
+		}
+		var req []byte
+		for j := 0; j < n; j++ {
+			req = append(req, fmt.Sprintf("GET /user/u%d?q=%d HTTP/1.1\r\nHost: example.com\r\nX-Id: %d\r\n\r\n", j, j, j)...)
+		}
+		h.Serve(context.Background(), standard.NewConn(&replayConn{req: req}, 4096)) //nolint:errcheck
+		wg.Wait()
+		for j := 0; j < n; j++ {
+			assert.DeepEqual(t, fmt.Sprintf("u%d,%d,%d", j, j, j), got[j])
+		}
+	}
+}
+
+// BenchmarkMatchSharding matches a route of a gateway-style table, every service has the routes
+// under its own first segment. The heap size of the route table is reported as B/route, which is
+// about doubled by sharding since the full trees are kept besides the shards.
+func BenchmarkMatchSharding(b *testing.B) {
+	hlog.SetOutput(io.Discard)
+	handler := func(c context.Context, ctx *app.RequestContext) {}
+	for _, services := range []int{100, 10000, 100000} {
+		for _, sharding := range []bool{false, true} {
+			var before, after runtime.MemStats
+			runtime.GC()
+			runtime.ReadMemStats(&before)
+			h := server.New(server.WithRouteSharding(sharding))
+			for i := 0; i < services; i++ {
+				svc := "/svc" + strconv.Itoa(i)
+				h.GET(svc+"/api/:id/items", handler)
+				h.GET(svc+"/api/:id", handler)
+				h.GET(svc+"/health", handler)
+			}
+			runtime.GC()
+			runtime.ReadMemStats(&after)
+			bytesPerRoute := float64(after.HeapAlloc-before.HeapAlloc) / float64(3*services)
+
+			name := fmt.Sprintf("services=%d/sharding=%t", services, sharding)
+			b.Run(name, func(b *testing.B) {
+				ctx := h.NewContext()
+				ctx.Request.SetRequestURI("/svc" + strconv.Itoa(services/2) + "/api/42/items")
+				ctx.Request.SetHost("example.com")
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					ctx.Params = ctx.Params[:0]
+					if code := h.Match(ctx); code != 200 {
+						b.Fatalf("unexpected status code=%d", code)
+					}
+				}
+				b.ReportMetric(bytesPerRoute, "B/route")
+			})
+			runtime.KeepAlive(h)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package route
+
+import (
+	"context"
+	"fmt"
+
+	"hertz-study/pkg/app"
+	"hertz-study/pkg/app/server/render"
+	"hertz-study/pkg/common/errors"
+	"hertz-study/pkg/protocol/consts"
+)
+
+// ErrorHandlerFunc handles the error of a request, see Engine.SetErrorHandler.
+type ErrorHandlerFunc func(c context.Context, ctx *app.RequestContext, err error)
+
+// SetErrorHandler sets the function converting the failures of the requests into the responses:
+//
+//   - the last error attached by ctx.Error or ctx.AbortWithError after the handlers, e.g. to
+//     report the failures of all the handlers uniformly.
+//   - the recovered panics with the status code 500, unless PanicHandler is set.
+//   - the errors of the engine, e.g. no route matches, if the NoRoute or NoMethod handlers
+//     don't write a body.
+//
+// The status code of the response is set before h is called, h may use it, e.g. ProblemErrorHandler.
+// Binding failures reach h if the handlers attach them, e.g. by
+// ctx.AbortWithError(consts.StatusBadRequest, err).SetType(errors.ErrorTypeBind).
+func (engine *Engine) SetErrorHandler(h ErrorHandlerFunc) {
+	engine.errorHandler = h
+}
+
+// ProblemErrorHandler is an ErrorHandlerFunc writing the error as the problem details of RFC 7807,
+// the status is the status code of the response, or 500 (400 for binding errors) if it's not an
+// error status. The error message is only written as the detail for the public and binding errors
+// to avoid leaking internals.
+func ProblemErrorHandler(c context.Context, ctx *app.RequestContext, err error) {
+	status := ctx.Response.StatusCode()
+	var detail string
+	if e, ok := err.(*errors.Error); ok && e.IsType(errors.ErrorTypePublic|errors.ErrorTypeBind) {
+		detail = e.Error()
+		if status < consts.StatusBadRequest && e.IsType(errors.ErrorTypeBind) {
+			status = consts.StatusBadRequest
+		}
+	}
+	if status < consts.StatusBadRequest {
+		status = consts.StatusInternalServerError
+	}
+	ctx.Response.ResetBody()
+	ctx.ProblemJSON(render.Problem{Status: status, Detail: detail})
+}
+
+func panicError(rcv interface{}) error {
+	return errors.New(fmt.Errorf("panic: %v", rcv), errors.ErrorTypePrivate, rcv)
+}
+
+func engineError(msg []byte) error {
+	return errors.NewPublic(string(msg))
+}
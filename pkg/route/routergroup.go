@@ -2,6 +2,7 @@ package route
 
 import (
 	"context"
+	"io/fs"
 	"path"
 	"regexp"
 	"strings"
@@ -32,6 +33,7 @@ type IRoutes interface {
 	StaticFile(string, string) IRoutes
 	Static(string, string) IRoutes
 	StaticFS(string, *app.FS) IRoutes
+	StaticEmbedFS(string, fs.FS) IRoutes
 }
 
 // 路由管理器
@@ -46,6 +48,8 @@ type RouterGroup struct {
 	engine *Engine
 	// 是否为根路由
 	root bool
+	// doc of the routes registered by the group, see WithDoc
+	doc *RouteDoc
 }
 
 var _ IRouter = (*RouterGroup)(nil)
@@ -82,9 +86,22 @@ func (group *RouterGroup) handle(httpMethod, relativePath string, handlers app.H
 	handlers = group.combineHandlers(handlers)
 	// 在engine添加路由
 	group.engine.addRoute(httpMethod, absolutePath, handlers)
+	if group.doc != nil {
+		group.engine.setRouteDoc(httpMethod, absolutePath, group.doc)
+	}
 	return group.returnObj()
 }
 
+// WithDoc returns a copy of the group whose routes registered by it are documented by doc,
+// the doc can be read from engine.Routes() at runtime, e.g.
+//
+//	h.WithDoc(route.RouteDoc{Summary: "Get the user by id"}).GET("/users/:id", getUser)
+func (group *RouterGroup) WithDoc(doc RouteDoc) *RouterGroup {
+	g := *group
+	g.doc = &doc
+	return &g
+}
+
 var upperLetterReg = regexp.MustCompile("^[A-Z]+$")
 
 // Handle registers a new request handle and middleware with the given path and method.
@@ -191,6 +208,21 @@ func (group *RouterGroup) StaticFS(relativePath string, fs *app.FS) IRoutes {
 	return group.returnObj()
 }
 
+// StaticEmbedFS serves files from fsys, e.g. the assets compiled into the binary by go:embed.
+// Like Static, the request path is used as the file name, and "index.html" is served for
+// the directories:
+//
+//	//go:embed static
+//	var assets embed.FS
+//
+//	router.StaticEmbedFS("/static", assets)
+//
+// Use StaticFS with FS.FileSystem to enable directory listing, SPA fallback or a custom
+// PathNotFound handler.
+func (group *RouterGroup) StaticEmbedFS(relativePath string, fsys fs.FS) IRoutes {
+	return group.StaticFS(relativePath, &app.FS{FileSystem: fsys, IndexNames: []string{"index.html"}})
+}
+
 func (group *RouterGroup) combineHandlers(handlers app.HandlersChain) app.HandlersChain {
 	finalSize := len(group.Handlers) + len(handlers)
 	if finalSize >= int(rConsts.AbortIndex) {
@@ -0,0 +1,75 @@
+package route
+
+import (
+	"strings"
+
+	"hertz-study/pkg/app"
+	"hertz-study/pkg/route/param"
+)
+
+// routeShards partitions the routes of a method by the first path segment, e.g. "/users/:id"
+// goes to the shard "users", while the routes whose first segment contains a wildcard, e.g.
+// "/:tenant/info", and the root path go to the fallback tree. The lookup walks the shard of
+// the segment first and then the fallback tree, which keeps the static > param > any priority
+// of the whole tree.
+//
+// The shards are only written when adding routes before the engine runs, so the lookup
+// is lock-free like the lookup of the method trees.
+//
+// The full tree of the method is still built for Routes, the fixed path redirection and the
+// 405 detection, so every route has the nodes in both trees and the memory of the nodes is
+// about doubled, see BenchmarkMatchSharding. The handler chains are shared by the trees.
+type routeShards struct {
+	method   string
+	shards   map[string]*router
+	fallback *router
+}
+
+func newRouteShards(method string) *routeShards {
+	return &routeShards{
+		method:   method,
+		shards:   make(map[string]*router),
+		fallback: newShardRouter(method),
+	}
+}
+
+func newShardRouter(method string) *router {
+	return &router{method: method, root: &node{}, hasTsrHandler: make(map[string]bool)}
+}
+
+// firstSegment returns the first segment of path without the leading slash.
+func firstSegment(path string) string {
+	seg := path[1:]
+	if i := strings.IndexByte(seg, '/'); i >= 0 {
+		seg = seg[:i]
+	}
+	return seg
+}
+
+func (s *routeShards) addRoute(path string, h app.HandlersChain) {
+	seg := firstSegment(path)
+	if seg == nilString || strings.ContainsAny(seg, ":*") {
+		s.fallback.addRoute(path, h)
+		return
+	}
+	shard := s.shards[seg]
+	if shard == nil {
+		shard = newShardRouter(s.method)
+		s.shards[seg] = shard
+	}
+	shard.addRoute(path, h)
+}
+
+func (s *routeShards) find(path string, paramsPointer *param.Params, unescape bool) (res nodeValue) {
+	if shard := s.shards[firstSegment(path)]; shard != nil {
+		res = shard.find(path, paramsPointer, unescape)
+		if res.handlers != nil {
+			return res
+		}
+		*paramsPointer = (*paramsPointer)[:0]
+	}
+	tsr := res.tsr
+	res = s.fallback.find(path, paramsPointer, unescape)
+	res.tsr = res.tsr || tsr
+	return res
+}
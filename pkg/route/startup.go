@@ -0,0 +1,114 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package route
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"hertz-study/pkg/common/hlog"
+)
+
+type startupCheck struct {
+	name     string
+	check    CtxErrCallback
+	required bool
+}
+
+// StartupCheckFailure is a failed startup check.
+type StartupCheckFailure struct {
+	Name string
+	Err  error
+}
+
+// StartupCheckError is returned by Run if any required startup check fails.
+type StartupCheckError struct {
+	Failures []StartupCheckFailure
+}
+
+func (e *StartupCheckError) Error() string {
+	var sb strings.Builder
+	sb.WriteString("startup checks failed: ")
+	for i, f := range e.Failures {
+		if i > 0 {
+			sb.WriteString("; ")
+		}
+		sb.WriteString(f.Name)
+		sb.WriteString(": ")
+		sb.WriteString(f.Err.Error())
+	}
+	return sb.String()
+}
+
+// AddStartupCheck adds a check executed by Run before listening, e.g. verifying the database
+// connectivity, the migrations or the config sanity. The checks are executed sequentially in
+// the order they are added, each with the timeout set by server.WithStartupCheckTimeout.
+//
+// All the checks are executed and reported even if some of them fail. The startup is aborted
+// with a *StartupCheckError aggregating the failures if any required check fails, while the
+// failures of the optional checks are only logged.
+func (engine *Engine) AddStartupCheck(name string, check CtxErrCallback, required bool) {
+	engine.startupChecks = append(engine.startupChecks, startupCheck{name: name, check: check, required: required})
+}
+
+func (engine *Engine) runStartupChecks(ctx context.Context) error {
+	var failures []StartupCheckFailure
+	for _, c := range engine.startupChecks {
+		start := time.Now()
+		err := engine.runStartupCheck(ctx, c.check)
+		cost := time.Since(start)
+		switch {
+		case err == nil:
+			hlog.SystemLogger().Infof("[StartupCheck] name=%s passed, cost=%v", c.name, cost)
+		case c.required:
+			hlog.SystemLogger().Errorf("[StartupCheck] name=%s failed, cost=%v, err=%v", c.name, cost, err)
+			failures = append(failures, StartupCheckFailure{Name: c.name, Err: err})
+		default:
+			hlog.SystemLogger().Warnf("[StartupCheck] name=%s failed but not required, cost=%v, err=%v", c.name, cost, err)
+		}
+	}
+	if len(failures) > 0 {
+		return &StartupCheckError{Failures: failures}
+	}
+	return nil
+}
+
+// runStartupCheck returns when the check returns or times out, even if it ignores ctx.
+func (engine *Engine) runStartupCheck(ctx context.Context, check CtxErrCallback) error {
+	if timeout := engine.options.StartupCheckTimeout; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		done <- check(ctx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
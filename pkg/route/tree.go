@@ -17,6 +17,8 @@ type router struct {
 	method        string
 	root          *node
 	hasTsrHandler map[string]bool
+	// shards is not nil if the routes are also partitioned by the first path segment
+	shards *routeShards
 }
 
 type MethodTrees []*router
@@ -274,11 +276,13 @@ func (r *router) insert(path string, h app.HandlersChain, t kind, ppath string,
 
 // find finds registered handler by method and path, parses URL params and puts params to context
 func (r *router) find(path string, paramsPointer *param.Params, unescape bool) (res nodeValue) {
+	if r.shards != nil {
+		return r.shards.find(path, paramsPointer, unescape)
+	}
 	var (
 		cn          = r.root // current node
 		search      = path   // current path
 		searchIndex = 0
-		buf         []byte
 		paramIndex  int
 	)
 
@@ -401,7 +405,7 @@ func (r *router) find(path string, paramsPointer *param.Params, unescape bool) (
 				}
 			}
 
-			(*paramsPointer)[index].Value = bytesconv.B2s(append(buf, val...))
+			(*paramsPointer)[index].Value = val
 			// update indexes/search in case we need to backtrack when no handler match is found
 			paramIndex++
 			searchIndex += len(search)